@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/opd-ai/go-gamelaunch-client/pkg/dgclient"
+	"golang.org/x/crypto/ssh"
+)
+
+// certAuth authenticates with a private key and the OpenSSH user
+// certificate an SSH CA issued for it
+type certAuth struct {
+	keyPath    string
+	certPath   string
+	passphrase string
+}
+
+// newKeyAuth returns key authentication for keyPath, presenting the user
+// certificate at certPath, or at <keyPath>-cert.pub when certPath is empty
+// and that file exists, as OpenSSH does
+func newKeyAuth(keyPath, certPath, passphrase string) dgclient.AuthMethod {
+	if certPath == "" {
+		if _, err := os.Stat(keyPath + "-cert.pub"); err != nil {
+			return dgclient.NewKeyAuth(keyPath, passphrase)
+		}
+		certPath = keyPath + "-cert.pub"
+	}
+	return &certAuth{keyPath: keyPath, certPath: certPath, passphrase: passphrase}
+}
+
+// GetSSHAuthMethod offers the certificate, then the plain key for servers
+// that do not trust the CA
+func (c *certAuth) GetSSHAuthMethod() (ssh.AuthMethod, error) {
+	key, err := os.ReadFile(c.keyPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key file: %w", err)
+	}
+	var signer ssh.Signer
+	if c.passphrase != "" {
+		signer, err = ssh.ParsePrivateKeyWithPassphrase(key, []byte(c.passphrase))
+	} else {
+		signer, err = ssh.ParsePrivateKey(key)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse private key: %w", err)
+	}
+
+	cert, err := loadUserCertificate(c.certPath)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(cert.Key.Marshal(), signer.PublicKey().Marshal()) {
+		return nil, fmt.Errorf("certificate %s was not issued for key %s", c.certPath, c.keyPath)
+	}
+	certSigner, err := ssh.NewCertSigner(cert, signer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to use certificate %s: %w", c.certPath, err)
+	}
+	return ssh.PublicKeys(certSigner, signer), nil
+}
+
+// Name returns the name of the auth method
+func (c *certAuth) Name() string {
+	return "certificate"
+}
+
+// loadUserCertificate reads an OpenSSH user certificate and checks that it
+// is currently valid
+func loadUserCertificate(path string) (*ssh.Certificate, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read certificate: %w", err)
+	}
+	pub, _, _, _, err := ssh.ParseAuthorizedKey(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse certificate %s: %w", path, err)
+	}
+	cert, ok := pub.(*ssh.Certificate)
+	if !ok {
+		return nil, fmt.Errorf("%s is a public key, not a certificate", path)
+	}
+	if cert.CertType != ssh.UserCert {
+		return nil, fmt.Errorf("%s is a host certificate, not a user certificate", path)
+	}
+
+	now := uint64(time.Now().Unix())
+	if now < cert.ValidAfter {
+		return nil, fmt.Errorf("certificate %s is not valid before %s", path, time.Unix(int64(cert.ValidAfter), 0).Format(time.RFC3339))
+	}
+	if cert.ValidBefore != ssh.CertTimeInfinity && now >= cert.ValidBefore {
+		return nil, fmt.Errorf("certificate %s expired at %s", path, time.Unix(int64(cert.ValidBefore), 0).Format(time.RFC3339))
+	}
+	return cert, nil
+}
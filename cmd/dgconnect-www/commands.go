@@ -1,32 +1,52 @@
 package main
 
 import (
+	"bufio"
 	"context"
-	"encoding/base64"
 	"fmt"
 	"log"
 	"net"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"strconv"
 	"strings"
 	"syscall"
 	"time"
 
 	"github.com/opd-ai/go-gamelaunch-client/pkg/dgclient"
+	"github.com/opd-ai/go-gamelaunch-www/pkg/announce"
+	"github.com/opd-ai/go-gamelaunch-www/pkg/chardump"
+	"github.com/opd-ai/go-gamelaunch-www/pkg/fanout"
+	"github.com/opd-ai/go-gamelaunch-www/pkg/recording"
+	"github.com/opd-ai/go-gamelaunch-www/pkg/rtc"
+	"github.com/opd-ai/go-gamelaunch-www/pkg/sshmux"
 	"github.com/opd-ai/go-gamelaunch-www/pkg/webui"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 	"golang.org/x/crypto/ssh"
-	"golang.org/x/crypto/ssh/knownhosts"
 	"golang.org/x/term"
 )
 
 func runConnect(cmd *cobra.Command, args []string) error {
-	var host, user string
-	var actualPort int
+	if demoMode {
+		return runDemo(cmd, args)
+	}
 
-	// Parse connection string or use config
-	if len(args) > 0 {
+	var host, user, serverName string
+	var actualPort int
+	var serverConfig *ServerConfig
+
+	// Use a configured server named by the argument, the connection string,
+	// or the default server
+	if len(args) > 0 && !strings.Contains(args[0], "@") && viper.IsSet("servers."+args[0]) {
+		var err error
+		serverName = args[0]
+		serverConfig, err = GetServerConfig(serverName)
+		if err != nil {
+			return err
+		}
+	} else if len(args) > 0 {
 		if err := parseConnectionString(args[0], &user, &host); err != nil {
 			return err
 		}
@@ -38,12 +58,17 @@ func runConnect(cmd *cobra.Command, args []string) error {
 			return fmt.Errorf("no server specified and no default_server in config")
 		}
 
-		serverConfig, err := GetServerConfig(defaultServer)
+		var err error
+		serverName = defaultServer
+		serverConfig, err = GetServerConfig(serverName)
 		if err != nil {
 			return err
 		}
-
+	}
+	var serverView *ViewConfig
+	if serverConfig != nil {
 		host = serverConfig.Host
+		serverView = serverConfig.View
 		user = serverConfig.Username
 		actualPort = serverConfig.Port
 		if actualPort == 0 {
@@ -59,14 +84,51 @@ func runConnect(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("username is required")
 	}
 
+	// Report this build through the web API
+	webui.SetBuildInfo(version, commit, date)
+
+	fileConfig, err := loadActiveConfig()
+	if err != nil {
+		return err
+	}
+
+	// Publish state through Redis when replicas or other instances share
+	// this session
+	var store *fanout.RedisStore
+	if fileConfig.Web.Redis != nil {
+		store, err = fanout.NewRedisStore(context.Background(), *fileConfig.Web.Redis)
+		if err != nil {
+			return err
+		}
+		defer store.Close()
+	}
+
 	// Create WebView for the web interface
-	viewOpts := dgclient.DefaultViewOptions()
-	webView, err := webui.NewWebView(viewOpts)
+	viewOpts, err := resolveViewOptions(fileConfig, serverView)
+	if err != nil {
+		return err
+	}
+	var stateStore webui.StateStore
+	if store != nil {
+		stateStore = store
+	}
+	webView, err := webui.NewWebViewWithStore(viewOpts, stateStore)
 	if err != nil {
 		return fmt.Errorf("failed to create web view: %w", err)
 	}
 
+	env, err := resolveEnv(fileConfig, serverName)
+	if err != nil {
+		return err
+	}
+
+	addr, err := resolveListenAddr(cmd, fileConfig)
+	if err != nil {
+		return err
+	}
+
 	// Load tileset if specified
+	tilesetPath := resolveTilesetPath(fileConfig, serverConfig)
 	var tilesetConfig *webui.TilesetConfig
 	if tilesetPath != "" {
 		tilesetConfig, err = webui.LoadTilesetConfig(tilesetPath)
@@ -75,32 +137,216 @@ func runConnect(cmd *cobra.Command, args []string) error {
 		}
 	}
 
+	// Track final scores in tournament mode
+	var tournament *webui.Tournament
+	if fileConfig.Web.Tournament != nil {
+		tournament, err = webui.NewTournament(*fileConfig.Web.Tournament)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Announce game events to IRC or Discord
+	var announcer *announce.Announcer
+	if fileConfig.Web.Announce != nil {
+		announcer, err = announce.New(*fileConfig.Web.Announce)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Archive character dumps when games end
+	var dumps *chardump.Archive
+	if fileConfig.Web.Dumps != nil {
+		dumpsConfig := *fileConfig.Web.Dumps
+		dumpsConfig.Dir = expandPath(dumpsConfig.Dir)
+		dumps, err = chardump.NewArchive(dumpsConfig)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Record games for later browsing
+	var recordings *recording.Store
+	if fileConfig.Web.Recordings != nil {
+		recordingsConfig := *fileConfig.Web.Recordings
+		recordingsConfig.Dir = expandPath(recordingsConfig.Dir)
+		recordingsConfig.Index = expandPath(recordingsConfig.Index)
+		recordings, err = recording.Open(recordingsConfig)
+		if err != nil {
+			return err
+		}
+		defer recordings.Close()
+	}
+
+	// Relay keyboard-interactive SSH prompts to web clients
+	challenges := webui.NewChallengeRelay(0)
+
+	// Ask web clients about unknown and changed host keys
+	hostKeys, err := hostKeyStore(fileConfig)
+	if err != nil {
+		return err
+	}
+
+	// Reconnects and dump fetches share one transport per server
+	sshPool := sshmux.NewPool(time.Minute)
+	defer sshPool.Close()
+
+	// Tell clients when the game stops answering
+	watchdog, err := streamWatchdog(fileConfig)
+	if err != nil {
+		return err
+	}
+
+	// Offer WebRTC data channels besides the WebSocket
+	negotiator, err := rtcNegotiator(fileConfig)
+	if err != nil {
+		return err
+	}
+
 	// Create WebUI server
 	webUIOptions := webui.WebUIOptions{
-		View:         webView,
-		TilesetPath:  tilesetPath,
-		Tileset:      tilesetConfig,
-		ListenAddr:   fmt.Sprintf(":%d", webPort),
-		PollTimeout:  30 * time.Second,
-		AllowOrigins: []string{}, // Allow all origins for simplicity
+		View:          webView,
+		TilesetPath:   tilesetPath,
+		Tileset:       tilesetConfig,
+		TilesetSubset: fileConfig.Web.TilesetSubset,
+		ListenAddr:    addr,
+		PollTimeout:   30 * time.Second,
+		AllowOrigins:  []string{}, // Allow all origins for simplicity
+
+		MaxClients:      maxClients,
+		MaxClientsPerIP: maxClientsPerIP,
+
+		HTTP3: fileConfig.Web.HTTP3,
+
+		OIDC:          fileConfig.Web.Auth.OIDC,
+		ProxyAuth:     fileConfig.Web.Auth.Proxy,
+		StaticTokens:  fileConfig.Web.Auth.Tokens,
+		Htpasswd:      fileConfig.Web.Auth.Htpasswd,
+		Authorization: fileConfig.Web.Auth.Policy,
+		RateLimit:     fileConfig.Web.RateLimit,
+		AccessLog:     fileConfig.Web.AccessLog,
+		Bandwidth:     fileConfig.Web.Bandwidth,
+		DiffBudget:    fileConfig.Web.DiffBudget,
+		Keyframes:     fileConfig.Web.Keyframes,
+
+		HistoryCompaction: fileConfig.Web.HistoryCompaction,
+
+		EscapePolicy:     fileConfig.Web.EscapePolicy,
+		ProtectedRegions: fileConfig.Web.ProtectedRegions,
+
+		WithholdConcealed: fileConfig.Web.WithholdConcealed,
+
+		Tournament:        tournament,
+		TournamentSession: fmt.Sprintf("%s@%s", user, host),
+		Announcer:         announcer,
+		Dumps:             dumps,
+		Recordings:        recordings,
+		Player:            user,
+		InstantReplay:     fileConfig.Web.InstantReplay,
+		Thumbnails:        fileConfig.Web.Thumbnails,
+		Minimap:           fileConfig.Web.Minimap,
+		Scrollback:        fileConfig.Web.Scrollback,
+		DamageMap:         fileConfig.Web.DamageMap,
+		Progress:          fileConfig.Web.Progress,
+		Landmarks:         fileConfig.Web.Landmarks,
+		Snapshots:         fileConfig.Web.Snapshots,
+		Lobby:             fileConfig.lobbyConfig(),
+		Keyboards:         fileConfig.Web.Keyboards,
+		Cache:             fileConfig.Web.Cache,
+		Expiry:            fileConfig.Web.Expiry,
+		Teardown:          fileConfig.Web.Teardown,
+		Detach:            fileConfig.Web.Detach,
+		Quota:             fileConfig.Web.Quota,
+		InputFilter:       fileConfig.Web.InputFilter,
+		OutputFilter:      fileConfig.Web.OutputFilter,
+		Themes:            fileConfig.Web.Themes,
+		Font:              fileConfig.Web.Font,
+		Messages:          fileConfig.Web.Messages,
+		SSHChallenges:     challenges,
+		HostKeys:          hostKeys,
+		RTC:               negotiator,
+	}
+	if serverConfig != nil {
+		webUIOptions.InputPreset = serverConfig.InputPreset
+	}
+	if record || noRecord {
+		webUIOptions.RecordSession = &record
+	}
+	var webServer *webui.WebUI
+	if store != nil {
+		// Forward input to the owner while another instance owns the session
+		webUIOptions.InputSink = func(data []byte) error {
+			if store.Owned() {
+				webServer.GetView().SendInput(data)
+				return nil
+			}
+			return store.PublishInput(data)
+		}
 	}
 
-	webServer, err := webui.NewWebUI(webUIOptions)
+	webServer, err = webui.NewWebUI(webUIOptions)
 	if err != nil {
 		return fmt.Errorf("failed to create web server: %w", err)
 	}
 
-	// Create dgclient in a separate goroutine
-	go func() {
-		if err := runDGClient(host, user, actualPort, webView); err != nil {
-			log.Printf("dgclient error: %v", err)
-		}
-	}()
-
 	// Set up signal handling for graceful shutdown
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
+	if announcer != nil {
+		go announcer.Run(ctx)
+	}
+	if recordings != nil {
+		go recordings.RunRetention(ctx)
+	}
+
+	// Create dgclient in a separate goroutine
+	connect := func(ctx context.Context) {
+		auth, err := getAuthMethod(serverConfig, user, host, challenges)
+		if err != nil {
+			log.Printf("dgclient error: failed to get authentication method: %v", err)
+			return
+		}
+		reconnect := fileConfig.reconnectPolicy(func() (*webui.WebView, error) {
+			return webui.NewWebViewWithStore(viewOpts, stateStore)
+		})
+		if err := runDGClient(ctx, sshPool, host, user, actualPort, viewOpts.TerminalType, gameName, env, auth, webServer, reconnect, watchdog, announcer, dumps, challenges, hostKeys); err != nil {
+			log.Printf("dgclient error: %v", err)
+		}
+	}
+	released := make(chan struct{})
+	if store == nil {
+		close(released)
+		go connect(ctx)
+	} else {
+		// Only the instance owning the session connects; the others serve
+		// its screen and take over when the owner fails
+		go func() {
+			defer close(released)
+			for ctx.Err() == nil {
+				owned, release, err := store.Acquire(ctx)
+				if err != nil {
+					return
+				}
+				fmt.Println("This instance owns the game session")
+				connect(owned)
+				// Keep a finished session until shutdown rather than
+				// letting another instance reconnect
+				<-owned.Done()
+				release()
+				if ctx.Err() == nil {
+					fmt.Println("Lost ownership of the game session, waiting to take over")
+				}
+			}
+		}()
+		go func() {
+			if err := store.Follow(ctx, followedView{webServer}); err != nil && err != context.Canceled {
+				log.Printf("redis follow error: %v", err)
+			}
+		}()
+	}
+
 	sigCh := make(chan os.Signal, 1)
 	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
 	go func() {
@@ -109,24 +355,120 @@ func runConnect(cmd *cobra.Command, args []string) error {
 		cancel()
 	}()
 
+	// End the session once it expires, like an interrupt
+	go func() {
+		select {
+		case <-webServer.Expired():
+			fmt.Println("\nSession expired, shutting down...")
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	// Accept input forwarded by replicas
+	if store != nil {
+		go func() {
+			sendInput := func(data []byte) {
+				if store.Owned() {
+					webServer.GetView().SendInput(data)
+				}
+			}
+			if err := store.SubscribeInput(ctx, sendInput); err != nil && err != context.Canceled {
+				log.Printf("redis input subscription error: %v", err)
+			}
+		}()
+	}
+
 	// Start the web server
-	fmt.Printf("Starting web server on :%d\n", webPort)
-	fmt.Printf("Connect to http://localhost:%d to play games\n", webPort)
+	_, webPortStr, _ := net.SplitHostPort(addr)
+	fmt.Printf("Starting web server on %s\n", addr)
+	fmt.Printf("Connect to http://localhost:%s to play games\n", webPortStr)
 	fmt.Printf("Game server: %s@%s:%d\n", user, host, actualPort)
 
-	return webServer.StartWithContext(ctx, fmt.Sprintf(":%d", webPort))
+	err = webServer.StartWithContext(ctx, addr)
+
+	// Hand the session over to another instance before exiting
+	cancel()
+	<-released
+	return err
+}
+
+// followedView wakes the pollers of the web server's current view, which
+// changes when the session reconnects, for states saved by other instances
+type followedView struct {
+	ui *webui.WebUI
 }
 
-// runDGClient handles the dgclient connection in a separate goroutine
-func runDGClient(host, user string, actualPort int, view *webui.WebView) error {
+// NotifyExternalUpdate implements fanout.UpdateListener
+func (f followedView) NotifyExternalUpdate(fromVersion uint64) {
+	f.ui.GetView().GetStateManager().NotifyExternalUpdate(fromVersion)
+}
+
+// reconnectPolicy tells runDGClient how to reconnect a dropped game
+// connection; see Config.reconnectPolicy
+type reconnectPolicy struct {
+	attempts int           // failed attempts in a row before giving up
+	delay    time.Duration // pause before the first attempt, doubled after each
+
+	// newView creates the view of the next connection, as the game client
+	// closes its view when it ends
+	newView func() (*webui.WebView, error)
+}
+
+// runDGClient handles the dgclient connection in a separate goroutine,
+// requesting a PTY of type term with the environment variables env and
+// launching game when set, until ctx is cancelled. The session runs on the
+// transport sshPool holds for the server, logs in with auth and renders to
+// the view of ui. When the connection drops, it reconnects as reconnect
+// says, rendering to a new view that replaces the old one in ui. A non-nil
+// watchdog watches the session while connected.
+func runDGClient(ctx context.Context, sshPool *sshmux.Pool, host, user string, actualPort int, term, game string, env map[string]string, auth dgclient.AuthMethod, ui *webui.WebUI, reconnect reconnectPolicy, watchdog *webui.StreamWatchdog, announcer *announce.Announcer, dumps *chardump.Archive, challenges *webui.ChallengeRelay, hostKeys *webui.HostKeyStore) error {
+	view := ui.GetView()
+	failures, delay := 0, reconnect.delay
+	for {
+		connected, err := runDGSession(ctx, sshPool, host, user, actualPort, term, game, env, auth, view, watchdog, announcer, dumps, challenges, hostKeys)
+		if err == nil || ctx.Err() != nil {
+			return nil
+		}
+		if connected {
+			failures, delay = 0, reconnect.delay
+		}
+		if failures++; failures > reconnect.attempts {
+			return err
+		}
+
+		log.Printf("dgclient error: %v; reconnecting in %s", err, delay)
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(delay):
+		}
+		delay *= 2
+
+		next, err := reconnect.newView()
+		if err != nil {
+			return fmt.Errorf("failed to create web view: %w", err)
+		}
+		if err := ui.ReplaceView(next); err != nil {
+			next.Close()
+			return fmt.Errorf("failed to replace web view: %w", err)
+		}
+		view = next
+	}
+}
+
+// runDGSession runs one connection of runDGClient, rendering to view, and
+// reports whether it got connected before it ended
+func runDGSession(ctx context.Context, sshPool *sshmux.Pool, host, user string, actualPort int, term, game string, env map[string]string, auth dgclient.AuthMethod, view *webui.WebView, watchdog *webui.StreamWatchdog, announcer *announce.Announcer, dumps *chardump.Archive, challenges *webui.ChallengeRelay, hostKeys *webui.HostKeyStore) (bool, error) {
 	// Create client configuration
 	clientConfig := dgclient.DefaultClientConfig()
 	clientConfig.Debug = debug
+	clientConfig.DefaultTerminal = term
 
 	// Set up SSH client config
 	sshConfig := &ssh.ClientConfig{
 		User:            user,
-		HostKeyCallback: getHostKeyCallback(),
+		HostKeyCallback: hostKeys.Check,
 		Timeout:         clientConfig.ConnectTimeout,
 	}
 	clientConfig.SSHConfig = sshConfig
@@ -137,40 +479,159 @@ func runDGClient(host, user string, actualPort int, view *webui.WebView) error {
 
 	// Set the WebView on the client
 	if err := client.SetView(view); err != nil {
-		return fmt.Errorf("failed to set view: %w", err)
+		return false, fmt.Errorf("failed to set view: %w", err)
 	}
 
-	// Get authentication method
-	auth, err := getAuthMethod(user, host)
-	if err != nil {
-		return fmt.Errorf("failed to get authentication method: %w", err)
-	}
+	// Set up context for client management
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
 
-	// Connect to game server
+	// Connect to game server. dgclient dials and handshakes on its own, so
+	// it talks to a relay running its session on the pooled transport and
+	// adding env, which dgclient cannot send.
 	fmt.Printf("Connecting to %s@%s:%d...\n", user, host, actualPort)
-	if err := client.Connect(host, actualPort, auth); err != nil {
-		return fmt.Errorf("connection failed: %w", err)
+	sshAuth, err := auth.GetSSHAuthMethod()
+	if err != nil {
+		return false, fmt.Errorf("failed to get authentication method: %w", err)
+	}
+	addr := net.JoinHostPort(host, strconv.Itoa(actualPort))
+	config := &ssh.ClientConfig{
+		User:            user,
+		Auth:            []ssh.AuthMethod{sshAuth},
+		HostKeyCallback: hostKeys.Check,
+		Timeout:         clientConfig.ConnectTimeout,
+	}
+	relay := &sshmux.Relay{Pool: sshPool, Addr: addr, Config: config, Env: env}
+	conn, relayKey, err := relay.Dial(ctx)
+	if err != nil {
+		return false, fmt.Errorf("connection failed: %w", err)
+	}
+	sshConfig.HostKeyCallback = relayKey
+	if err := client.ConnectWithConn(conn, auth); err != nil {
+		return false, fmt.Errorf("connection failed: %w", err)
 	}
 
 	fmt.Println("Connected to game server successfully!")
+	if watchdog != nil {
+		go watchdog.Run(ctx, view, client.IsConnected)
+	}
 
-	// Set up context for client management
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
+	// Fetch dumps with the credentials that just worked
+	if dumps != nil {
+		dumps.SetFetcher(&chardump.SFTPFetcher{Pool: sshPool, Addr: addr, Config: config})
+	}
+	if announcer != nil {
+		announcer.Announce(announce.Event{
+			Type:    announce.EventSessionStarted,
+			Player:  user,
+			Session: fmt.Sprintf("%s@%s", user, host),
+			Game:    game,
+		})
+	}
 
 	// Launch game if specified
-	if gameName != "" {
-		if err := client.SelectGame(gameName); err != nil {
-			fmt.Printf("Warning: failed to select game %s: %v\n", gameName, err)
+	if game != "" {
+		if err := client.SelectGame(game); err != nil {
+			fmt.Printf("Warning: failed to select game %s: %v\n", game, err)
 		}
 	}
 
 	// Run the client
 	if err := client.Run(ctx); err != nil {
-		return fmt.Errorf("client error: %w", err)
+		return true, fmt.Errorf("client error: %w", err)
 	}
 
-	return nil
+	return true, nil
+}
+
+// resolveListenAddr returns the web server address: --listen, then
+// --web-port, then web.listen, then port 8080 on all interfaces
+func resolveListenAddr(cmd *cobra.Command, fileConfig *Config) (string, error) {
+	addr := fileConfig.Web.Listen
+	switch {
+	case listenAddr != "":
+		addr = listenAddr
+	case cmd.Flags().Changed("web-port") || addr == "":
+		addr = fmt.Sprintf(":%d", webPort)
+	}
+
+	_, portStr, err := net.SplitHostPort(addr)
+	if err != nil {
+		return "", fmt.Errorf("invalid web server address %q: %w", addr, err)
+	}
+	if p, err := strconv.Atoi(portStr); err != nil || p < 1 || p > 65535 {
+		return "", fmt.Errorf("invalid web server port %q", portStr)
+	}
+	return addr, nil
+}
+
+// resolveTilesetPath returns the tileset configuration file: --tileset,
+// then the tileset of server, then web.tileset. Names without a directory
+// or extension refer to <name>.yaml in web.tileset_dir.
+func resolveTilesetPath(fileConfig *Config, server *ServerConfig) string {
+	tileset := fileConfig.Web.Tileset
+	if server != nil && server.Tileset != "" {
+		tileset = server.Tileset
+	}
+	if tilesetPath != "" {
+		tileset = tilesetPath
+	}
+	if tileset == "" || strings.ContainsRune(tileset, os.PathSeparator) || filepath.Ext(tileset) != "" {
+		return expandPath(tileset)
+	}
+
+	dir := fileConfig.Web.TilesetDir
+	if dir == "" {
+		dir = defaultTilesetDir
+	}
+	return filepath.Join(expandPath(dir), tileset+".yaml")
+}
+
+// resolveViewOptions applies the terminal flags to the view options of the
+// config file
+func resolveViewOptions(fileConfig *Config, serverView *ViewConfig) (dgclient.ViewOptions, error) {
+	opts := fileConfig.viewOptions(serverView)
+	if termCols < 0 || termCols > maxTerminalSize || termRows < 0 || termRows > maxTerminalSize {
+		return opts, fmt.Errorf("invalid terminal size %dx%d", termCols, termRows)
+	}
+	if termCols > 0 {
+		opts.InitialWidth = termCols
+	}
+	if termRows > 0 {
+		opts.InitialHeight = termRows
+	}
+	if termType != "" {
+		opts.TerminalType = termType
+	}
+	if noColor {
+		opts.ColorEnabled = false
+	}
+	if noUnicode {
+		opts.UnicodeEnabled = false
+	}
+	return opts, nil
+}
+
+// resolveEnv returns the environment of the game session: the env of the
+// named server, overridden by the --env flags
+func resolveEnv(fileConfig *Config, serverName string) (map[string]string, error) {
+	env := make(map[string]string)
+	if server, ok := fileConfig.Servers[serverName]; ok {
+		for name, value := range server.Env {
+			env[name] = value
+		}
+	}
+	for _, variable := range envVars {
+		name, value, ok := strings.Cut(variable, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid --env %q, want NAME=VALUE", variable)
+		}
+		if err := validateEnvName(name); err != nil {
+			return nil, err
+		}
+		env[name] = value
+	}
+	return env, nil
 }
 
 func parseConnectionString(conn string, user, host *string) error {
@@ -190,34 +651,27 @@ func parseConnectionString(conn string, user, host *string) error {
 	return nil
 }
 
-func getAuthMethod(user, host string) (dgclient.AuthMethod, error) {
-	// Priority: command line flag > config > SSH agent > default keys > password prompt
+// getAuthMethod chooses how to log in to host as user, from the auth of
+// server when connecting to a configured server, which may be nil
+func getAuthMethod(server *ServerConfig, user, host string, challenges *webui.ChallengeRelay) (dgclient.AuthMethod, error) {
+	// Priority: command line flag > server config > SSH agent > default keys > password prompt
 
 	if password != "" {
 		return dgclient.NewPasswordAuth(password), nil
 	}
 
 	if keyPath != "" {
-		return dgclient.NewKeyAuth(keyPath, ""), nil
+		return newKeyAuth(keyPath, certPath, ""), nil
 	}
 
-	// Check config for auth method
-	defaultServer := viper.GetString("default_server")
-	if defaultServer != "" {
-		serverConfig, err := GetServerConfig(defaultServer)
-		if err == nil {
-			switch serverConfig.Auth.Method {
-			case "key":
-				if serverConfig.Auth.KeyPath != "" {
-					return dgclient.NewKeyAuth(expandPath(serverConfig.Auth.KeyPath), serverConfig.Auth.Passphrase), nil
-				}
-			case "password":
-				// Will fall through to password prompt
-			case "agent":
-				if os.Getenv("SSH_AUTH_SOCK") != "" {
-					return dgclient.NewAgentAuth(), nil
-				}
-			}
+	if interactive {
+		return dgclient.NewInteractiveAuth(interactiveChallenge("", challenges)), nil
+	}
+
+	// Use the auth configured for the selected server
+	if server != nil {
+		if auth, ok := serverAuthMethod(server.Auth, challenges); ok {
+			return auth, nil
 		}
 	}
 
@@ -236,7 +690,7 @@ func getAuthMethod(user, host string) (dgclient.AuthMethod, error) {
 
 	for _, keyPath := range defaultKeys {
 		if _, err := os.Stat(keyPath); err == nil {
-			return dgclient.NewKeyAuth(keyPath, ""), nil
+			return newKeyAuth(keyPath, "", ""), nil
 		}
 	}
 
@@ -251,97 +705,112 @@ func getAuthMethod(user, host string) (dgclient.AuthMethod, error) {
 	return dgclient.NewPasswordAuth(string(passwordBytes)), nil
 }
 
-func getHostKeyCallback() ssh.HostKeyCallback {
-	// Try to use known_hosts file first
-	home, err := os.UserHomeDir()
-	if err != nil {
-		if debug {
-			fmt.Printf("Warning: Could not get home directory: %v\n", err)
+// serverAuthMethod returns the auth method configured for a server, or false
+// when it needs a password prompt or an SSH agent that is not running
+func serverAuthMethod(auth AuthConfig, challenges *webui.ChallengeRelay) (dgclient.AuthMethod, bool) {
+	switch auth.Method {
+	case "key":
+		if auth.KeyPath != "" {
+			return newKeyAuth(expandPath(auth.KeyPath), expandPath(auth.CertPath), auth.Passphrase), true
 		}
-		return createInsecureCallback()
-	}
-
-	knownHostsPath := fmt.Sprintf("%s/.ssh/known_hosts", home)
-	if _, err := os.Stat(knownHostsPath); err != nil {
-		if debug {
-			fmt.Printf("Warning: known_hosts file not found at %s, using insecure callback\n", knownHostsPath)
+	case "password":
+		// Will fall through to password prompt
+	case "agent":
+		if os.Getenv("SSH_AUTH_SOCK") != "" {
+			return dgclient.NewAgentAuth(), true
 		}
-		return createInsecureCallback()
+	case "keyboard-interactive":
+		return dgclient.NewInteractiveAuth(interactiveChallenge(auth.Prompt, challenges)), true
 	}
+	return nil, false
+}
 
-	// Use known_hosts for verification
-	hostKeyCallback, err := knownhosts.New(knownHostsPath)
-	if err != nil {
-		if debug {
-			fmt.Printf("Warning: Failed to load known_hosts: %v, using insecure callback\n", err)
-		}
-		return createInsecureCallback()
+// interactiveChallenge returns the keyboard-interactive callback for prompt:
+// "web" relays the prompts to web clients, "terminal" asks on the terminal,
+// and "" asks on the terminal when stdin is one
+func interactiveChallenge(prompt string, challenges *webui.ChallengeRelay) ssh.KeyboardInteractiveChallenge {
+	if prompt == "web" || (prompt == "" && !term.IsTerminal(int(os.Stdin.Fd()))) {
+		fmt.Println("SSH login prompts will be shown in the web client")
+		return challenges.Challenge
 	}
+	return promptTerminal
+}
 
-	// Wrap the callback to provide better error messages and handle unknown hosts
-	return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
-		err := hostKeyCallback(hostname, remote, key)
-		if err != nil {
-			// Check if this is an unknown host error
-			if keyErr, ok := err.(*knownhosts.KeyError); ok && len(keyErr.Want) == 0 {
-				// Unknown host - prompt user
-				fmt.Printf("\nWarning: Unknown host %s\n", hostname)
-				fmt.Printf("Host key fingerprint: %s\n", ssh.FingerprintSHA256(key))
-				fmt.Print("Do you want to continue connecting? (yes/no): ")
-
-				var response string
-				fmt.Scanln(&response)
-
-				if response == "yes" || response == "y" {
-					// Add to known_hosts
-					if addErr := addToKnownHosts(knownHostsPath, hostname, key); addErr != nil {
-						fmt.Printf("Warning: Could not add host to known_hosts: %v\n", addErr)
-					} else {
-						fmt.Printf("Host %s added to known_hosts\n", hostname)
-					}
-					return nil
-				}
-				return fmt.Errorf("host key verification failed: user rejected unknown host")
-			}
+// promptTerminal answers keyboard-interactive prompts on the terminal,
+// without echoing secrets
+func promptTerminal(name, instruction string, questions []string, echos []bool) ([]string, error) {
+	if name != "" {
+		fmt.Println(name)
+	}
+	if instruction != "" {
+		fmt.Println(instruction)
+	}
 
-			// Host key mismatch or other error
-			if keyErr, ok := err.(*knownhosts.KeyError); ok && len(keyErr.Want) > 0 {
-				fmt.Printf("\nHost key verification failed for %s!\n", hostname)
-				fmt.Printf("Expected fingerprint: %s\n", ssh.FingerprintSHA256(keyErr.Want[0].Key))
-				fmt.Printf("Received fingerprint: %s\n", ssh.FingerprintSHA256(key))
-				return fmt.Errorf("host key verification failed: key mismatch")
+	reader := bufio.NewReader(os.Stdin)
+	answers := make([]string, len(questions))
+	for i, question := range questions {
+		fmt.Print(question)
+		if i < len(echos) && echos[i] {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				return nil, fmt.Errorf("failed to read answer: %w", err)
 			}
-
-			return fmt.Errorf("host key verification failed: %w", err)
+			answers[i] = strings.TrimRight(line, "\r\n")
+			continue
 		}
-
-		if debug {
-			fmt.Printf("Host key verified for %s\n", hostname)
+		secret, err := term.ReadPassword(int(os.Stdin.Fd()))
+		fmt.Println()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read answer: %w", err)
 		}
-		return nil
+		answers[i] = string(secret)
 	}
+	return answers, nil
 }
 
-func createInsecureCallback() ssh.HostKeyCallback {
-	return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
-		if debug {
-			fmt.Printf("Warning: Using insecure host key callback for %s\n", hostname)
-			fmt.Printf("Fingerprint: %s\n", ssh.FingerprintSHA256(key))
+// hostKeyStore opens the managed known_hosts store of web.host_keys, by
+// default ~/.dgconnect/known_hosts trusting ~/.ssh/known_hosts
+func hostKeyStore(fileConfig *Config) (*webui.HostKeyStore, error) {
+	return webui.NewHostKeyStore(hostKeyConfig(fileConfig))
+}
+
+// hostKeyConfig returns web.host_keys with its defaults and paths expanded
+func hostKeyConfig(fileConfig *Config) webui.HostKeyConfig {
+	cfg := webui.HostKeyConfig{
+		KnownHosts: defaultKnownHosts,
+		Trusted:    []string{"~/.ssh/known_hosts"},
+	}
+	if fileConfig.Web.HostKeys != nil {
+		cfg = *fileConfig.Web.HostKeys
+		if cfg.KnownHosts == "" {
+			cfg.KnownHosts = defaultKnownHosts
 		}
-		return nil
 	}
+	cfg.KnownHosts = expandPath(cfg.KnownHosts)
+	trusted := make([]string, len(cfg.Trusted))
+	for i, path := range cfg.Trusted {
+		trusted[i] = expandPath(path)
+	}
+	cfg.Trusted = trusted
+	return cfg
 }
 
-func addToKnownHosts(knownHostsPath, hostname string, key ssh.PublicKey) error {
-	f, err := os.OpenFile(knownHostsPath, os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0o600)
-	if err != nil {
-		return err
+// rtcNegotiator returns the negotiator of web.webrtc, or nil when it is not
+// configured
+func rtcNegotiator(fileConfig *Config) (webui.RTCNegotiator, error) {
+	if fileConfig.Web.WebRTC == nil {
+		return nil, nil
 	}
-	defer f.Close()
+	return rtc.New(*fileConfig.Web.WebRTC)
+}
 
-	line := fmt.Sprintf("%s %s %s\n", hostname, key.Type(), base64.StdEncoding.EncodeToString(key.Marshal()))
-	_, err = f.WriteString(line)
-	return err
+// streamWatchdog returns the watchdog of web.watchdog, or nil when it is not
+// configured
+func streamWatchdog(fileConfig *Config) (*webui.StreamWatchdog, error) {
+	if fileConfig.Web.Watchdog == nil {
+		return nil, nil
+	}
+	return webui.NewStreamWatchdog(*fileConfig.Web.Watchdog)
 }
 
 func expandPath(path string) string {
@@ -4,52 +4,223 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
+	"time"
 
+	"github.com/opd-ai/go-gamelaunch-client/pkg/dgclient"
+	"github.com/opd-ai/go-gamelaunch-www/pkg/announce"
+	"github.com/opd-ai/go-gamelaunch-www/pkg/chardump"
+	"github.com/opd-ai/go-gamelaunch-www/pkg/fanout"
+	"github.com/opd-ai/go-gamelaunch-www/pkg/recording"
+	"github.com/opd-ai/go-gamelaunch-www/pkg/rtc"
+	"github.com/opd-ai/go-gamelaunch-www/pkg/webui"
+	"github.com/opd-ai/go-gamelaunch-www/pkg/yamlcheck"
 	"github.com/spf13/viper"
 	"gopkg.in/yaml.v3"
 )
 
+// defaultTilesetDir holds tilesets referred to by name
+const defaultTilesetDir = "~/.dgconnect/tilesets"
+
+// defaultKnownHosts is the managed store of approved game server host keys
+const defaultKnownHosts = "~/.dgconnect/known_hosts"
+
+// maxTerminalSize bounds the columns and rows of the terminal
+const maxTerminalSize = 1000
+
 // Config represents the configuration file structure
 type Config struct {
 	DefaultServer string                  `yaml:"default_server,omitempty"`
 	Servers       map[string]ServerConfig `yaml:"servers"`
 	Preferences   PreferencesConfig       `yaml:"preferences,omitempty"`
+	Web           WebConfig               `yaml:"web,omitempty"`
+
+	// Communities served by the tenants command, by name
+	Tenants map[string]TenantConfig `yaml:"tenants,omitempty"`
 }
 
 // ServerConfig represents a server configuration
 type ServerConfig struct {
-	Host        string     `yaml:"host"`
-	Port        int        `yaml:"port,omitempty"`
-	Username    string     `yaml:"username"`
-	Auth        AuthConfig `yaml:"auth"`
-	DefaultGame string     `yaml:"default_game,omitempty"`
+	Host        string      `yaml:"host"`
+	Port        int         `yaml:"port,omitempty"`
+	Username    string      `yaml:"username"`
+	Auth        AuthConfig  `yaml:"auth"`
+	DefaultGame string      `yaml:"default_game,omitempty"`
+	View        *ViewConfig `yaml:"view,omitempty"`
+	Tileset     string      `yaml:"tileset,omitempty"`      // path or name in web.tileset_dir
+	InputPreset string      `yaml:"input_preset,omitempty"` // e.g. nethack, see webui.InputPresets
+
+	// Environment of the game session, e.g. LANG; the server may refuse
+	// variables its AcceptEnv does not list. TERM is set by view.terminal.
+	Env map[string]string `yaml:"env,omitempty"`
+}
+
+// ViewConfig sets up the terminal presented to a game server; unset fields
+// keep the preferences or built-in defaults
+type ViewConfig struct {
+	Width    int    `yaml:"width,omitempty"`    // columns, default 80
+	Height   int    `yaml:"height,omitempty"`   // rows, default 24
+	Terminal string `yaml:"terminal,omitempty"` // TERM, default xterm-256color
+	Color    *bool  `yaml:"color,omitempty"`
+	Unicode  *bool  `yaml:"unicode,omitempty"`
 }
 
 // AuthConfig represents authentication configuration
 type AuthConfig struct {
-	Method     string `yaml:"method"` // password, key, agent
+	Method     string `yaml:"method"` // password, key, agent, keyboard-interactive
 	KeyPath    string `yaml:"key_path,omitempty"`
+	CertPath   string `yaml:"cert_path,omitempty"` // OpenSSH user certificate, default <key_path>-cert.pub if present
 	Passphrase string `yaml:"passphrase,omitempty"`
+	Prompt     string `yaml:"prompt,omitempty"` // keyboard-interactive prompts on the terminal or web; default terminal when attached to one
 }
 
 // PreferencesConfig represents user preferences
 type PreferencesConfig struct {
 	Terminal          string `yaml:"terminal,omitempty"`
 	ReconnectAttempts int    `yaml:"reconnect_attempts,omitempty"`
-	ReconnectDelay    string `yaml:"reconnect_delay,omitempty"`
-	KeepAliveInterval string `yaml:"keepalive_interval,omitempty"`
+	ReconnectDelay    string `yaml:"reconnect_delay,omitempty" yamlcheck:"duration"`
+	KeepAliveInterval string `yaml:"keepalive_interval,omitempty" yamlcheck:"duration"`
 	ColorEnabled      bool   `yaml:"color_enabled"`
 	UnicodeEnabled    bool   `yaml:"unicode_enabled"`
 }
 
+// WebConfig represents web server configuration
+type WebConfig struct {
+	Listen        string                    `yaml:"listen,omitempty"`         // e.g. "127.0.0.1:8080"; default ":8080"
+	Tileset       string                    `yaml:"tileset,omitempty"`        // default tileset, path or name
+	TilesetDir    string                    `yaml:"tileset_dir,omitempty"`    // where named tilesets are found
+	TilesetSubset bool                      `yaml:"tileset_subset,omitempty"` // serve only the drawn tiles of the sheet
+	Auth          WebAuthConfig             `yaml:"auth,omitempty"`
+	HTTP3         *webui.HTTP3Config        `yaml:"http3,omitempty"`
+	RateLimit     *webui.RateLimitConfig    `yaml:"rate_limit,omitempty"`
+	AccessLog     *webui.AccessLogConfig    `yaml:"access_log,omitempty"`
+	Bandwidth     *webui.BandwidthConfig    `yaml:"bandwidth,omitempty"`
+	Redis         *fanout.Config            `yaml:"redis,omitempty"` // share the session with replicas
+	DiffBudget    *webui.DiffBudget         `yaml:"diff_budget,omitempty"`
+	Keyframes     *webui.KeyframeConfig     `yaml:"keyframes,omitempty"`
+	EscapePolicy  *webui.EscapePolicyConfig `yaml:"escape_policy,omitempty"`
+
+	// Merge older diffs of the in-memory state history in the background
+	HistoryCompaction *webui.HistoryCompactionConfig `yaml:"history_compaction,omitempty"`
+
+	// Rows that never scroll, e.g. the two status lines of NetHack
+	ProtectedRegions []webui.PinnedRegion `yaml:"protected_regions,omitempty"`
+
+	// Never send concealed (SGR 8) text to spectators
+	WithholdConcealed bool `yaml:"withhold_concealed,omitempty"`
+
+	// Scrape final scores onto a live leaderboard
+	Tournament *webui.TournamentConfig `yaml:"tournament,omitempty"`
+
+	// Post session starts, deaths and records to IRC or Discord
+	Announce *announce.Config `yaml:"announce,omitempty"`
+
+	// Fetch character dumps over SFTP when games end
+	Dumps *chardump.Config `yaml:"dumps,omitempty"`
+
+	// Record games as ttyrec files indexed in SQLite
+	Recordings *recording.Config `yaml:"recordings,omitempty"`
+
+	// Keep recent output in memory for instant replay
+	InstantReplay *webui.InstantReplayConfig `yaml:"instant_replay,omitempty"`
+
+	// Periodic PNG previews of the screen for lobby pages
+	Thumbnails *webui.ThumbnailConfig `yaml:"thumbnails,omitempty"`
+
+	// Managed known_hosts store; unknown and changed keys are approved from
+	// the web client
+	HostKeys *webui.HostKeyConfig `yaml:"host_keys,omitempty"`
+
+	// Overview of the explored map for the client's minimap panel
+	Minimap *webui.MinimapConfig `yaml:"minimap,omitempty"`
+
+	// Lines scrolled off the screen that clients can scroll back through
+	Scrollback *webui.ScrollbackConfig `yaml:"scrollback,omitempty"`
+
+	// Counts of how often each cell changes, served at /debug/damage
+	DamageMap *webui.DamageMapConfig `yaml:"damage_map,omitempty"`
+
+	// Read the turn counter and game clock from the screen
+	Progress *webui.ProgressConfig `yaml:"progress,omitempty"`
+
+	// Parsers that find the map, status and message areas for screen
+	// readers and keyboard navigation, e.g. [nethack]
+	Landmarks []string `yaml:"landmarks,omitempty"`
+
+	// Compressed screen snapshots written periodically and on idle
+	Snapshots *webui.SnapshotConfig `yaml:"snapshots,omitempty"`
+
+	// Entry page listing the configured servers, sessions and recordings
+	Lobby *webui.LobbyConfig `yaml:"lobby,omitempty"`
+
+	// On-screen keyboard layouts for touch clients
+	Keyboards *webui.KeyboardConfig `yaml:"keyboards,omitempty"`
+
+	// Cache-Control overrides by route
+	Cache *webui.CacheConfig `yaml:"cache,omitempty"`
+
+	// Idle timeout, with a countdown to clients before the session ends
+	Expiry *webui.ExpiryConfig `yaml:"expiry,omitempty"`
+
+	// Keys sent to the game, e.g. save and quit, after the last player left
+	Teardown *webui.TeardownConfig `yaml:"teardown,omitempty"`
+
+	// How long the game keeps running with no browser connected
+	Detach *webui.DetachConfig `yaml:"detach,omitempty"`
+
+	// Tell clients when the game stops answering although SSH is up
+	Watchdog *webui.WatchdogConfig `yaml:"watchdog,omitempty"`
+
+	// Memory, scrollback and recording limits of a session
+	Quota *webui.QuotaConfig `yaml:"quota,omitempty"`
+
+	// Remap, mask or refuse client input before it reaches the game
+	InputFilter *webui.InputFilterConfig `yaml:"input_filter,omitempty"`
+
+	// Strip sequences from or draw a watermark over the game's output
+	OutputFilter *webui.OutputFilterConfig `yaml:"output_filter,omitempty"`
+
+	// Color themes offered to web clients besides dark and light
+	Themes *webui.ThemeConfig `yaml:"themes,omitempty"`
+
+	// Bundled webfont and the cell size of text mode
+	Font *webui.FontConfig `yaml:"font,omitempty"`
+
+	// Translations of server messages, by locale and then message key
+	Messages map[string]map[string]string `yaml:"messages,omitempty"`
+
+	// Serve clients over WebRTC data channels as well (experimental)
+	WebRTC *rtc.Config `yaml:"webrtc,omitempty"`
+}
+
+// WebAuthConfig represents web authentication configuration
+type WebAuthConfig struct {
+	OIDC     *webui.OIDCConfig      `yaml:"oidc,omitempty"`
+	Proxy    *webui.ProxyAuthConfig `yaml:"proxy,omitempty"`
+	Tokens   []webui.StaticToken    `yaml:"tokens,omitempty"`
+	Htpasswd *webui.HtpasswdConfig  `yaml:"htpasswd,omitempty"`
+
+	// Policy overrides the minimum role per method, e.g. "admin.*": admin
+	Policy webui.AuthorizationPolicy `yaml:"policy,omitempty"`
+}
+
 // LoadConfig loads configuration from file
 func LoadConfig(path string) (*Config, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read config file: %w", err)
 	}
+	return parseConfig(path, data)
+}
 
+// parseConfig parses data, the contents of the config file at path
+func parseConfig(path string, data []byte) (*Config, error) {
+	// Report typos, wrong types and bad durations rather than ignoring them
 	var config Config
+	if err := yamlcheck.CheckFile(path, data, &config); err != nil {
+		return nil, fmt.Errorf("invalid config file %w", err)
+	}
 	if err := yaml.Unmarshal(data, &config); err != nil {
 		return nil, fmt.Errorf("failed to parse config file: %w", err)
 	}
@@ -126,11 +297,25 @@ func ValidateConfig(config *Config) error {
 		return fmt.Errorf("config is nil")
 	}
 
-	if len(config.Servers) == 0 {
+	if len(config.Servers) == 0 && len(config.Tenants) == 0 {
 		return fmt.Errorf("no servers configured")
 	}
+	if err := validateServers(config.Servers); err != nil {
+		return err
+	}
+
+	if config.DefaultServer != "" {
+		if _, exists := config.Servers[config.DefaultServer]; !exists {
+			return fmt.Errorf("default_server '%s' not found in servers list", config.DefaultServer)
+		}
+	}
+
+	return validateTenants(config.Tenants)
+}
 
-	for name, server := range config.Servers {
+// validateServers checks the server configurations of a config or tenant
+func validateServers(servers map[string]ServerConfig) error {
+	for name, server := range servers {
 		if server.Host == "" {
 			return fmt.Errorf("server '%s' has no host configured", name)
 		}
@@ -143,20 +328,144 @@ func ValidateConfig(config *Config) error {
 		if server.Auth.Method == "key" && server.Auth.KeyPath == "" {
 			return fmt.Errorf("server '%s' uses key auth but no key_path specified", name)
 		}
+		if server.Auth.CertPath != "" && server.Auth.Method != "key" {
+			return fmt.Errorf("server '%s' sets cert_path without key auth", name)
+		}
+		if prompt := server.Auth.Prompt; prompt != "" && prompt != "terminal" && prompt != "web" {
+			return fmt.Errorf("server '%s' has an unknown auth prompt '%s'", name, prompt)
+		}
+		if view := server.View; view != nil && (view.Width < 0 || view.Width > maxTerminalSize || view.Height < 0 || view.Height > maxTerminalSize) {
+			return fmt.Errorf("server '%s' has an invalid view size %dx%d", name, view.Width, view.Height)
+		}
+		if _, ok := webui.LookupInputPreset(server.InputPreset); server.InputPreset != "" && !ok {
+			return fmt.Errorf("server '%s' has an unknown input preset '%s'", name, server.InputPreset)
+		}
+		for variable := range server.Env {
+			if err := validateEnvName(variable); err != nil {
+				return fmt.Errorf("server '%s': %w", name, err)
+			}
+		}
 		if server.Port <= 0 {
 			server.Port = 22 // Set default
 		}
 	}
+	return nil
+}
 
-	if config.DefaultServer != "" {
-		if _, exists := config.Servers[config.DefaultServer]; !exists {
-			return fmt.Errorf("default_server '%s' not found in servers list", config.DefaultServer)
-		}
+// validateEnvName rejects names that cannot be sent as environment variables
+func validateEnvName(name string) error {
+	switch {
+	case name == "" || strings.ContainsAny(name, "= \t\n"):
+		return fmt.Errorf("invalid environment variable name '%s'", name)
+	case name == "TERM":
+		return fmt.Errorf("TERM is set by view.terminal, not env")
 	}
-
 	return nil
 }
 
+// loadActiveConfig parses the config file in use, if any. Viper only exposes
+// loosely typed values, so sections with nested typed structs are read
+// through LoadConfig to honour their yaml tags.
+func loadActiveConfig() (*Config, error) {
+	path := viper.ConfigFileUsed()
+	if path == "" {
+		return &Config{}, nil
+	}
+	if _, err := os.Stat(path); err != nil {
+		return &Config{}, nil
+	}
+	config, err := LoadConfig(path)
+	if err != nil {
+		return nil, err
+	}
+	if htpasswd := config.Web.Auth.Htpasswd; htpasswd != nil {
+		htpasswd.File = expandPath(htpasswd.File)
+	}
+	if h3 := config.Web.HTTP3; h3 != nil {
+		h3.CertFile = expandPath(h3.CertFile)
+		h3.KeyFile = expandPath(h3.KeyFile)
+	}
+	if bandwidth := config.Web.Bandwidth; bandwidth != nil && bandwidth.StateFile != "" {
+		bandwidth.StateFile = expandPath(bandwidth.StateFile)
+	}
+	return config, nil
+}
+
+// reconnectPolicy returns how runDGClient carries on after the game
+// connection drops: preferences.reconnect_attempts (default 3) attempts in
+// a row, the first after preferences.reconnect_delay (default 5s) and each
+// further one after twice the pause before, each rendering to a view from
+// newView
+func (c *Config) reconnectPolicy(newView func() (*webui.WebView, error)) reconnectPolicy {
+	policy := reconnectPolicy{attempts: 3, delay: 5 * time.Second, newView: newView}
+	if c.Preferences.ReconnectAttempts > 0 {
+		policy.attempts = c.Preferences.ReconnectAttempts
+	}
+	if delay, err := time.ParseDuration(c.Preferences.ReconnectDelay); err == nil && delay > 0 {
+		policy.delay = delay
+	}
+	return policy
+}
+
+// viewOptions returns the terminal presented to a game server: the built-in
+// defaults, overridden by the terminal preference, then by view
+func (c *Config) viewOptions(view *ViewConfig) dgclient.ViewOptions {
+	opts := dgclient.DefaultViewOptions()
+	if c.Preferences.Terminal != "" {
+		opts.TerminalType = c.Preferences.Terminal
+	}
+	if view == nil {
+		return opts
+	}
+	if view.Width > 0 {
+		opts.InitialWidth = view.Width
+	}
+	if view.Height > 0 {
+		opts.InitialHeight = view.Height
+	}
+	if view.Terminal != "" {
+		opts.TerminalType = view.Terminal
+	}
+	if view.Color != nil {
+		opts.ColorEnabled = *view.Color
+	}
+	if view.Unicode != nil {
+		opts.UnicodeEnabled = *view.Unicode
+	}
+	return opts
+}
+
+// lobbyConfig returns the lobby configuration listing the configured
+// servers by name, or nil when the lobby is disabled
+func (c *Config) lobbyConfig() *webui.LobbyConfig {
+	if c.Web.Lobby == nil {
+		return nil
+	}
+	lobby := *c.Web.Lobby
+	lobby.Servers = make([]webui.LobbyServer, 0, len(c.Servers))
+	for name, server := range c.Servers {
+		lobby.Servers = append(lobby.Servers, webui.LobbyServer{
+			Name: name,
+			Host: server.Host,
+			Port: server.Port,
+			Game: server.DefaultGame,
+		})
+	}
+	sort.Slice(lobby.Servers, func(i, j int) bool { return lobby.Servers[i].Name < lobby.Servers[j].Name })
+	return &lobby
+}
+
+// sharedBandwidth returns the bandwidth caps without the state file, for
+// sessions beside the one of the root command, which alone keeps it
+func (c *Config) sharedBandwidth() *webui.BandwidthConfig {
+	if c.Web.Bandwidth == nil {
+		return nil
+	}
+	bandwidth := *c.Web.Bandwidth
+	bandwidth.StateFile = ""
+	return &bandwidth
+}
+
 // GetServerConfig retrieves a server configuration by name
 func GetServerConfig(name string) (*ServerConfig, error) {
 	serverKey := fmt.Sprintf("servers.%s", name)
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// systemConfigFile is the configuration shared by all users of a host
+const systemConfigFile = "/etc/dgconnect/config.yaml"
+
+// configPathAll lists every candidate of config path instead of the file in
+// use
+var configPathAll bool
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Inspect the configuration file and share server profiles",
+}
+
+var configPathCmd = &cobra.Command{
+	Use:   "path",
+	Short: "Print the configuration file in use",
+	Long: `Print the configuration file in use: the --config flag, or else the first
+that exists of
+
+  $XDG_CONFIG_HOME/dgconnect/config.yaml (default ~/.config/dgconnect/config.yaml)
+  ~/.dgconnect.yaml
+  /etc/dgconnect/config.yaml
+
+With --all, every candidate is listed in that order with whether it exists.`,
+	Args: cobra.NoArgs,
+	RunE: runConfigPath,
+}
+
+var configCheckCmd = &cobra.Command{
+	Use:   "check [config-file]",
+	Short: "Validate a configuration file",
+	Long: `Validate a configuration file, by default the one in use, listing every
+unknown key, value of the wrong type and malformed duration with its line
+number, then checking that the servers are complete.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runConfigCheck,
+}
+
+// userConfigFile returns the XDG location of the user's configuration
+func userConfigFile() (string, error) {
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" || !filepath.IsAbs(dir) {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("failed to get home directory: %w", err)
+		}
+		dir = filepath.Join(home, ".config")
+	}
+	return filepath.Join(dir, "dgconnect", "config.yaml"), nil
+}
+
+// configSearchPath returns the configuration files looked for when --config
+// is not given, in order of precedence
+func configSearchPath() []string {
+	var paths []string
+	if path, err := userConfigFile(); err == nil {
+		paths = append(paths, path)
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		paths = append(paths, filepath.Join(home, ".dgconnect.yaml"))
+	}
+	return append(paths, systemConfigFile)
+}
+
+// findConfigFile returns the first existing file of configSearchPath, or ""
+func findConfigFile() string {
+	for _, path := range configSearchPath() {
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path
+		}
+	}
+	return ""
+}
+
+// defaultInitPath is where init writes without a path argument: the user's
+// configuration in use, or the XDG location for a new one
+func defaultInitPath() (string, error) {
+	if path := viper.ConfigFileUsed(); path != "" && path != systemConfigFile {
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		}
+	}
+	return userConfigFile()
+}
+
+func runConfigPath(cmd *cobra.Command, args []string) error {
+	used := viper.ConfigFileUsed()
+	if configPathAll {
+		paths := configSearchPath()
+		if cfgFile != "" {
+			paths = append([]string{cfgFile}, paths...)
+		}
+		for _, path := range paths {
+			status := "not found"
+			if _, err := os.Stat(path); err == nil {
+				status = "found"
+			}
+			if path == used {
+				status = "in use"
+			}
+			fmt.Printf("%s (%s)\n", path, status)
+		}
+		return nil
+	}
+
+	if used == "" {
+		return fmt.Errorf("no configuration file found; run 'dgconnect-www init' to create one")
+	}
+	if _, err := os.Stat(used); err != nil {
+		return fmt.Errorf("configuration file %s does not exist", used)
+	}
+	fmt.Println(used)
+	return nil
+}
+
+func runConfigCheck(cmd *cobra.Command, args []string) error {
+	path := viper.ConfigFileUsed()
+	if len(args) > 0 {
+		path = args[0]
+	}
+	if path == "" {
+		return fmt.Errorf("no configuration file found; run 'dgconnect-www init' to create one")
+	}
+
+	config, err := LoadConfig(path)
+	if err != nil {
+		return err
+	}
+	if err := ValidateConfig(config); err != nil {
+		return fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+	fmt.Printf("%s is valid\n", path)
+	return nil
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/opd-ai/go-gamelaunch-www/pkg/demo"
+	"github.com/opd-ai/go-gamelaunch-www/pkg/webui"
+	"github.com/spf13/cobra"
+)
+
+// runDemo serves the bundled demo recording in a loop instead of a game
+// session, ignoring player input
+func runDemo(cmd *cobra.Command, args []string) error {
+	if len(args) > 0 {
+		return fmt.Errorf("--demo does not connect to a server, got %q", args[0])
+	}
+
+	frames, err := demo.Frames()
+	if err != nil {
+		return err
+	}
+
+	fileConfig, err := loadActiveConfig()
+	if err != nil {
+		return err
+	}
+
+	webui.SetBuildInfo(version, commit, date)
+
+	viewOpts, err := resolveViewOptions(fileConfig, nil)
+	if err != nil {
+		return err
+	}
+	webView, err := webui.NewWebView(viewOpts)
+	if err != nil {
+		return fmt.Errorf("failed to create web view: %w", err)
+	}
+
+	addr, err := resolveListenAddr(cmd, fileConfig)
+	if err != nil {
+		return err
+	}
+
+	tilesetPath := resolveTilesetPath(fileConfig, nil)
+	var tilesetConfig *webui.TilesetConfig
+	if tilesetPath != "" {
+		tilesetConfig, err = webui.LoadTilesetConfig(tilesetPath)
+		if err != nil {
+			return fmt.Errorf("failed to load tileset: %w", err)
+		}
+	}
+
+	webServer, err := webui.NewWebUI(webui.WebUIOptions{
+		View:          webView,
+		TilesetPath:   tilesetPath,
+		Tileset:       tilesetConfig,
+		TilesetSubset: fileConfig.Web.TilesetSubset,
+		ListenAddr:    addr,
+		PollTimeout:   30 * time.Second,
+
+		MaxClients:      maxClients,
+		MaxClientsPerIP: maxClientsPerIP,
+
+		HTTP3: fileConfig.Web.HTTP3,
+
+		RateLimit:  fileConfig.Web.RateLimit,
+		AccessLog:  fileConfig.Web.AccessLog,
+		Bandwidth:  fileConfig.sharedBandwidth(),
+		DiffBudget: fileConfig.Web.DiffBudget,
+		Keyframes:  fileConfig.Web.Keyframes,
+		Thumbnails: fileConfig.Web.Thumbnails,
+		Minimap:    fileConfig.Web.Minimap,
+		Scrollback: fileConfig.Web.Scrollback,
+		DamageMap:  fileConfig.Web.DamageMap,
+		Progress:   fileConfig.Web.Progress,
+		Landmarks:  fileConfig.Web.Landmarks,
+		Snapshots:  fileConfig.Web.Snapshots,
+		Keyboards:  fileConfig.Web.Keyboards,
+		Cache:      fileConfig.Web.Cache,
+		Themes:     fileConfig.Web.Themes,
+		Font:       fileConfig.Web.Font,
+		Messages:   fileConfig.Web.Messages,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create web server: %w", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Play the recording until shutdown
+	go func() {
+		err := demo.Loop(ctx, frames, 1, demo.DefaultPause, func(data []byte) error {
+			return webView.RenderContext(ctx, data)
+		})
+		if err != nil && err != context.Canceled {
+			log.Printf("demo playback error: %v", err)
+		}
+	}()
+
+	// Discard input so that the input buffer never fills up
+	go func() {
+		for {
+			if _, err := webView.HandleInput(); err != nil {
+				return
+			}
+		}
+	}()
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-sigCh
+		fmt.Println("\nReceived interrupt signal, shutting down...")
+		cancel()
+	}()
+
+	_, webPortStr, _ := net.SplitHostPort(addr)
+	fmt.Printf("Starting demo web server on %s\n", addr)
+	fmt.Printf("Connect to http://localhost:%s to watch the demo\n", webPortStr)
+
+	err = webServer.StartWithContext(ctx, addr)
+	webView.Close()
+	return err
+}
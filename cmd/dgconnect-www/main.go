@@ -9,6 +9,7 @@ import (
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+	"golang.org/x/term"
 )
 
 var (
@@ -23,11 +24,36 @@ var (
 	// Command flags
 	port        int
 	webPort     int
+	listenAddr  string
 	keyPath     string
+	certPath    string
 	password    string
+	interactive bool
 	gameName    string
 	debug       bool
 	tilesetPath string
+
+	// Web client limits
+	maxClients      int
+	maxClientsPerIP int
+
+	// Terminal presented to the game server, overriding the config file
+	termType  string
+	termCols  int
+	termRows  int
+	noColor   bool
+	noUnicode bool
+	envVars   []string // NAME=VALUE
+
+	// Recording consent of this session, overriding recordings.opt_in
+	record   bool
+	noRecord bool
+
+	// Serve the bundled demo recording instead of connecting to a server
+	demoMode bool
+
+	// Write the example configuration instead of running the init wizard
+	initExample bool
 )
 
 func main() {
@@ -50,7 +76,8 @@ Examples:
   dgconnect-www user@nethack.example.com
   dgconnect-www user@server.example.com --port 2022 --web-port 8080
   dgconnect-www --config ~/.dgconnect.yaml nethack-server --tileset tiles.yaml
-  dgconnect-www user@server.example.com --game nethack --web-port 3000`,
+  dgconnect-www user@server.example.com --game nethack --web-port 3000
+  dgconnect-www --demo`,
 	Args: cobra.MaximumNArgs(1),
 	RunE: runConnect,
 }
@@ -59,16 +86,57 @@ func init() {
 	cobra.OnInitialize(initConfig)
 
 	// Global flags
-	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.dgconnect.yaml)")
+	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is the first of $XDG_CONFIG_HOME/dgconnect/config.yaml, $HOME/.dgconnect.yaml and /etc/dgconnect/config.yaml)")
 	rootCmd.PersistentFlags().BoolVar(&debug, "debug", false, "enable debug output")
 
 	// Connection flags
 	rootCmd.Flags().IntVarP(&port, "port", "p", 22, "SSH port")
 	rootCmd.Flags().IntVarP(&webPort, "web-port", "w", 8080, "Web server port")
+	rootCmd.Flags().StringVar(&listenAddr, "listen", "", "web server address, e.g. 127.0.0.1:8080 (overrides --web-port)")
 	rootCmd.Flags().StringVarP(&keyPath, "key", "k", "", "SSH private key path")
+	rootCmd.Flags().StringVar(&certPath, "cert", "", "OpenSSH user certificate for --key (default <key>-cert.pub if present)")
 	rootCmd.Flags().StringVar(&password, "password", "", "SSH password (use with caution)")
+	rootCmd.Flags().BoolVar(&interactive, "keyboard-interactive", false, "use keyboard-interactive SSH auth, prompting on the terminal or, without one, in the web client")
 	rootCmd.Flags().StringVarP(&gameName, "game", "g", "", "game to launch directly")
 	rootCmd.Flags().StringVarP(&tilesetPath, "tileset", "t", "", "path to tileset configuration file")
+	rootCmd.Flags().IntVar(&maxClients, "max-clients", 0, "maximum concurrent web clients per session (0 = unlimited)")
+	rootCmd.Flags().IntVar(&maxClientsPerIP, "max-clients-per-ip", 0, "maximum concurrent web clients per IP address (0 = unlimited)")
+	rootCmd.Flags().StringVar(&termType, "term", "", "terminal type (TERM) requested from the game server")
+	rootCmd.Flags().IntVar(&termCols, "cols", 0, "terminal width in columns (default 80)")
+	rootCmd.Flags().IntVar(&termRows, "rows", 0, "terminal height in rows (default 24)")
+	rootCmd.Flags().BoolVar(&noColor, "no-color", false, "disable color support")
+	rootCmd.Flags().BoolVar(&noUnicode, "no-unicode", false, "disable Unicode support")
+	rootCmd.Flags().StringArrayVar(&envVars, "env", nil, "environment variable NAME=VALUE for the game session (repeatable)")
+	rootCmd.Flags().BoolVar(&record, "record", false, "record this session even if recordings are opt-in")
+	rootCmd.Flags().BoolVar(&noRecord, "no-record", false, "do not record this session")
+	rootCmd.MarkFlagsMutuallyExclusive("record", "no-record")
+	rootCmd.Flags().BoolVar(&demoMode, "demo", false, "loop a bundled game recording instead of connecting to a server")
+
+	// Replica command
+	replicaCmd.Flags().IntVarP(&webPort, "web-port", "w", 8080, "Web server port")
+	replicaCmd.Flags().StringVar(&listenAddr, "listen", "", "web server address, e.g. 127.0.0.1:8081 (overrides --web-port)")
+	replicaCmd.Flags().StringVarP(&tilesetPath, "tileset", "t", "", "path to tileset configuration file")
+	replicaCmd.Flags().IntVar(&maxClients, "max-clients", 0, "maximum concurrent web clients (0 = unlimited)")
+	replicaCmd.Flags().IntVar(&maxClientsPerIP, "max-clients-per-ip", 0, "maximum concurrent web clients per IP address (0 = unlimited)")
+	rootCmd.AddCommand(replicaCmd)
+
+	// Replay command
+	replayCmd.Flags().Float64Var(&replaySpeed, "speed", 1, "playback speed, e.g. 2 for twice as fast")
+	replayCmd.Flags().DurationVar(&replayMaxDelay, "max-delay", 0, "longest pause between frames, e.g. 2s (0 = as recorded)")
+	replayCmd.Flags().BoolVar(&replayLoop, "loop", false, "start the recording over when it ends")
+	replayCmd.Flags().IntVarP(&webPort, "web-port", "w", 8080, "Web server port")
+	replayCmd.Flags().StringVar(&listenAddr, "listen", "", "web server address, e.g. 127.0.0.1:8080 (overrides --web-port)")
+	replayCmd.Flags().StringVarP(&tilesetPath, "tileset", "t", "", "path to tileset configuration file")
+	replayCmd.Flags().IntVar(&termCols, "cols", 0, "terminal width of the recording in columns (default 80)")
+	replayCmd.Flags().IntVar(&termRows, "rows", 0, "terminal height of the recording in rows (default 24)")
+	replayCmd.Flags().IntVar(&maxClients, "max-clients", 0, "maximum concurrent web clients (0 = unlimited)")
+	replayCmd.Flags().IntVar(&maxClientsPerIP, "max-clients-per-ip", 0, "maximum concurrent web clients per IP address (0 = unlimited)")
+	rootCmd.AddCommand(replayCmd)
+
+	// Tenants command
+	tenantsCmd.Flags().IntVarP(&webPort, "web-port", "w", 8080, "Web server port")
+	tenantsCmd.Flags().StringVar(&listenAddr, "listen", "", "web server address, e.g. 127.0.0.1:8080 (overrides --web-port)")
+	rootCmd.AddCommand(tenantsCmd)
 
 	// Version command
 	rootCmd.AddCommand(&cobra.Command{
@@ -80,32 +148,49 @@ func init() {
 	})
 
 	// Init command
-	rootCmd.AddCommand(&cobra.Command{
+	initCmd := &cobra.Command{
 		Use:   "init [config-file]",
-		Short: "Generate example configuration file",
-		Long: `Generate an example configuration file with common server settings.
-        
-If no path is specified, creates ~/.dgconnect.yaml by default.
+		Short: "Set up a configuration file",
+		Long: `Set up a configuration file interactively: the wizard asks for the game
+server and how to log in, test-connects to it, detects the games offered by its
+dgamelaunch menu, and asks for the web port and tileset. An existing file gets
+the new server added to it.
+
+Without a terminal, or with --example, an example configuration with common
+server settings is written instead.
+
+If no path is specified, uses the configuration file in use, or else
+$XDG_CONFIG_HOME/dgconnect/config.yaml (~/.config/dgconnect/config.yaml).
 
 Examples:
   dgconnect init
-  dgconnect init ./my-config.yaml
+  dgconnect init --example ./my-config.yaml
   dgconnect init ~/.config/dgconnect/config.yaml`,
 		Args: cobra.MaximumNArgs(1),
 		RunE: runInitConfig,
-	})
+	}
+	initCmd.Flags().BoolVar(&initExample, "example", false, "write an example configuration instead of asking")
+	rootCmd.AddCommand(initCmd)
+
+	// Config command
+	configPathCmd.Flags().BoolVar(&configPathAll, "all", false, "list every location searched, in order of precedence")
+	configCmd.AddCommand(configPathCmd)
+	configCmd.AddCommand(configCheckCmd)
+	configExportCmd.Flags().StringVarP(&exportOutput, "output", "o", "", "write the profile to this file instead of standard output")
+	configCmd.AddCommand(configExportCmd)
+	configImportCmd.Flags().StringVar(&importName, "name", "", "name for the server (default the profile's name)")
+	configImportCmd.Flags().StringVar(&importUsername, "username", "", "username on the game server (default $USER)")
+	configImportCmd.Flags().StringVarP(&importKeyPath, "key", "k", "", "SSH private key, when the profile suggests key auth")
+	configImportCmd.Flags().BoolVar(&importForce, "force", false, "replace a server of the same name")
+	configCmd.AddCommand(configImportCmd)
+	rootCmd.AddCommand(configCmd)
 }
 
 func initConfig() {
 	if cfgFile != "" {
 		viper.SetConfigFile(cfgFile)
-	} else {
-		home, err := os.UserHomeDir()
-		cobra.CheckErr(err)
-
-		viper.AddConfigPath(home)
-		viper.SetConfigType("yaml")
-		viper.SetConfigName(".dgconnect")
+	} else if path := findConfigFile(); path != "" {
+		viper.SetConfigFile(path)
 	}
 
 	viper.AutomaticEnv()
@@ -123,11 +208,14 @@ func runInitConfig(cmd *cobra.Command, args []string) error {
 	if len(args) > 0 {
 		configPath = args[0]
 	} else {
-		home, err := os.UserHomeDir()
-		if err != nil {
-			return fmt.Errorf("failed to get home directory: %w", err)
+		var err error
+		if configPath, err = defaultInitPath(); err != nil {
+			return err
 		}
-		configPath = fmt.Sprintf("%s/.dgconnect.yaml", home)
+	}
+
+	if !initExample && term.IsTerminal(int(os.Stdin.Fd())) {
+		return runInitWizard(configPath)
 	}
 
 	// Check if file already exists
@@ -0,0 +1,444 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/opd-ai/go-gamelaunch-www/pkg/yamlcheck"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// profileVersion is the format version of exported server profiles
+const profileVersion = 1
+
+// Flags of config export and import
+var (
+	exportOutput   string
+	importName     string
+	importUsername string
+	importKeyPath  string
+	importForce    bool
+)
+
+// ServerProfile is a shareable description of a game server, so communities
+// can distribute ready-made connection settings. It holds no account or
+// secret: the username, key path and passphrase stay with each player, and
+// so does the game environment.
+type ServerProfile struct {
+	Profile     int         `yaml:"profile"` // format version, see profileVersion
+	Name        string      `yaml:"name"`
+	Host        string      `yaml:"host"`
+	Port        int         `yaml:"port,omitempty"`
+	Auth        string      `yaml:"auth,omitempty"` // suggested auth method
+	DefaultGame string      `yaml:"default_game,omitempty"`
+	View        *ViewConfig `yaml:"view,omitempty"`
+	Tileset     string      `yaml:"tileset,omitempty"` // name in the importer's web.tileset_dir
+	InputPreset string      `yaml:"input_preset,omitempty"`
+}
+
+var configExportCmd = &cobra.Command{
+	Use:   "export [server]",
+	Short: "Write a shareable profile of a configured server",
+	Long: `Write a single-file profile of a configured server, by default the
+default_server, for others to import: host, port, suggested auth method,
+default game, terminal, tileset name and input preset. Usernames, key paths,
+passphrases and environment variables are left out.
+
+Examples:
+  dgconnect config export nethack-server > nethack.profile.yaml
+  dgconnect config export nethack-server -o nethack.profile.yaml`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runConfigExport,
+}
+
+var configImportCmd = &cobra.Command{
+	Use:   "import <profile-file>",
+	Short: "Add a server from a shared profile",
+	Long: `Add the server described by a profile written by config export ("-"
+reads standard input) to the configuration file in use, or else to
+$XDG_CONFIG_HOME/dgconnect/config.yaml. The username defaults to $USER and,
+for key auth, the key to the first of the usual SSH keys that exists.
+
+Examples:
+  dgconnect config import nethack.profile.yaml
+  dgconnect config import --name nao --username alice nethack.profile.yaml`,
+	Args: cobra.ExactArgs(1),
+	RunE: runConfigImport,
+}
+
+// newServerProfile describes server as a profile named name
+func newServerProfile(name string, server ServerConfig) *ServerProfile {
+	profile := &ServerProfile{
+		Profile:     profileVersion,
+		Name:        name,
+		Host:        server.Host,
+		Port:        server.Port,
+		Auth:        server.Auth.Method,
+		DefaultGame: server.DefaultGame,
+		View:        server.View,
+		Tileset:     tilesetName(server.Tileset),
+		InputPreset: server.InputPreset,
+	}
+	if profile.Port == 22 {
+		profile.Port = 0
+	}
+	return profile
+}
+
+// tilesetName returns the name a tileset is shared by: its file name
+// without the extension, as local paths mean nothing to other players
+func tilesetName(tileset string) string {
+	if tileset == "" {
+		return ""
+	}
+	return strings.TrimSuffix(filepath.Base(tileset), filepath.Ext(tileset))
+}
+
+// parseServerProfile reads a profile, reporting unknown keys such as
+// credentials with their line numbers
+func parseServerProfile(name string, data []byte) (*ServerProfile, error) {
+	var profile ServerProfile
+	if err := yamlcheck.CheckFile(name, data, &profile); err != nil {
+		return nil, fmt.Errorf("invalid profile %w", err)
+	}
+	if err := yaml.Unmarshal(data, &profile); err != nil {
+		return nil, fmt.Errorf("failed to parse profile: %w", err)
+	}
+	switch {
+	case profile.Profile == 0:
+		return nil, fmt.Errorf("%s is not a server profile", name)
+	case profile.Profile > profileVersion:
+		return nil, fmt.Errorf("%s uses profile format %d; this version reads up to %d", name, profile.Profile, profileVersion)
+	case profile.Host == "":
+		return nil, fmt.Errorf("profile %s has no host", name)
+	case profile.Tileset != "" && profile.Tileset != tilesetName(profile.Tileset):
+		return nil, fmt.Errorf("profile %s refers to tileset %q by path; only names are shared", name, profile.Tileset)
+	}
+	return &profile, nil
+}
+
+// serverConfig returns the server described by the profile, logging in as
+// username with key for key auth
+func (p *ServerProfile) serverConfig(username, key string) ServerConfig {
+	server := ServerConfig{
+		Host:        p.Host,
+		Port:        p.Port,
+		Username:    username,
+		Auth:        AuthConfig{Method: p.Auth},
+		DefaultGame: p.DefaultGame,
+		View:        p.View,
+		Tileset:     p.Tileset,
+		InputPreset: p.InputPreset,
+	}
+	if server.Port == 0 {
+		server.Port = 22
+	}
+	if server.Auth.Method == "" {
+		server.Auth.Method = "key"
+		if os.Getenv("SSH_AUTH_SOCK") != "" {
+			server.Auth.Method = "agent"
+		}
+	}
+	if server.Auth.Method == "key" {
+		server.Auth.KeyPath = key
+		if key == "" {
+			server.Auth.KeyPath = defaultKeyPath()
+		}
+	}
+	return server
+}
+
+// importServer adds server to the config file at path as name and returns
+// the new configuration. An existing file is edited in place, keeping its
+// comments and layout; a missing one is created with the default
+// preferences.
+func importServer(path, name string, server ServerConfig) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		config := &Config{
+			DefaultServer: name,
+			Servers:       map[string]ServerConfig{name: server},
+			Preferences:   GenerateExampleConfig().Preferences,
+		}
+		if err := ValidateConfig(config); err != nil {
+			return nil, fmt.Errorf("invalid profile: %w", err)
+		}
+		if err := SaveConfig(config, path); err != nil {
+			return nil, fmt.Errorf("failed to save configuration: %w", err)
+		}
+		return config, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	config, err := parseConfig(path, data)
+	if err != nil {
+		return nil, err
+	}
+	if _, exists := config.Servers[name]; exists && !importForce {
+		return nil, fmt.Errorf("server '%s' already exists in %s; pass --name to import it under another name or --force to replace it", name, path)
+	}
+	data, err = insertServer(data, name, server, config.DefaultServer == "")
+	if err != nil {
+		return nil, fmt.Errorf("cannot add server '%s' to %s: %w", name, path, err)
+	}
+	if config, err = parseConfig(path, data); err != nil {
+		return nil, err
+	}
+	if err := ValidateConfig(config); err != nil {
+		return nil, fmt.Errorf("invalid profile: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		return nil, fmt.Errorf("failed to save configuration: %w", err)
+	}
+	return config, nil
+}
+
+// lineEdit replaces lines from..to-1 (numbered from 1) of a file with text
+type lineEdit struct {
+	from, to int
+	text     string
+}
+
+// insertServer returns the config file data with server added under
+// servers as name, replacing a server of that name, and with default_server
+// set to name when setDefault is set. Only the lines of that server and
+// default_server change, so comments and formatting elsewhere are kept.
+func insertServer(data []byte, name string, server ServerConfig, setDefault bool) ([]byte, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	lines := strings.SplitAfter(string(data), "\n")
+	if len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	if len(lines) > 0 && !strings.HasSuffix(lines[len(lines)-1], "\n") {
+		lines[len(lines)-1] += "\n"
+	}
+	eof := len(lines) + 1
+
+	var root *yaml.Node
+	if len(doc.Content) > 0 {
+		root = doc.Content[0]
+	}
+	if root != nil && root.Kind == yaml.ScalarNode && root.Tag == "!!null" {
+		root = nil // only comments
+	}
+	if root != nil && (root.Kind != yaml.MappingNode || root.Style&yaml.FlowStyle != 0) {
+		return nil, fmt.Errorf("the file is not a block mapping")
+	}
+
+	var edits []lineEdit
+	if setDefault {
+		line := "default_server: " + yamlScalar(name) + "\n"
+		if key, value := mappingEntry(root, "default_server"); key != nil {
+			if value.LineComment != "" {
+				line = strings.TrimSuffix(line, "\n") + " " + value.LineComment + "\n"
+			}
+			edits = append(edits, lineEdit{from: key.Line, to: key.Line + 1, text: line})
+		} else {
+			edits = append(edits, lineEdit{from: eof, to: eof, text: line})
+		}
+	}
+
+	key, servers := mappingEntry(root, "servers")
+	switch {
+	case key == nil:
+		entry, err := serverEntry(name, server, 2)
+		if err != nil {
+			return nil, err
+		}
+		edits = append(edits, lineEdit{from: eof, to: eof, text: "servers:\n" + entry})
+	case servers.Kind == yaml.MappingNode && servers.Style&yaml.FlowStyle == 0 && len(servers.Content) > 0:
+		indent := servers.Content[0].Column - 1
+		entry, err := serverEntry(name, server, indent)
+		if err != nil {
+			return nil, err
+		}
+		end := blockEnd(lines, nextKeyLine(root, key, eof))
+		edit := lineEdit{from: end + 1, to: end + 1, text: entry}
+		for i := 0; i < len(servers.Content); i += 2 {
+			if servers.Content[i].Value != name {
+				continue
+			}
+			if i+2 < len(servers.Content) {
+				end = blockEnd(lines, servers.Content[i+2].Line)
+			}
+			edit = lineEdit{from: servers.Content[i].Line, to: end + 1, text: entry}
+		}
+		edits = append(edits, edit)
+	case servers.Tag == "!!null" || (servers.Kind == yaml.MappingNode && len(servers.Content) == 0 && servers.Line == key.Line):
+		// servers: with nothing, ~ or {}
+		entry, err := serverEntry(name, server, 2)
+		if err != nil {
+			return nil, err
+		}
+		edits = append(edits, lineEdit{from: key.Line, to: key.Line + 1, text: "servers:\n" + entry})
+	default:
+		return nil, fmt.Errorf("servers is not a block mapping")
+	}
+
+	// Apply from the bottom up, so that line numbers stay valid
+	sort.SliceStable(edits, func(i, j int) bool { return edits[i].from > edits[j].from })
+	for _, edit := range edits {
+		tail := append([]string{edit.text}, lines[edit.to-1:]...)
+		lines = append(lines[:edit.from-1], tail...)
+	}
+	return []byte(strings.Join(lines, "")), nil
+}
+
+// mappingEntry returns the key and value nodes of key in mapping, or nils
+func mappingEntry(mapping *yaml.Node, key string) (*yaml.Node, *yaml.Node) {
+	if mapping == nil {
+		return nil, nil
+	}
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return mapping.Content[i], mapping.Content[i+1]
+		}
+	}
+	return nil, nil
+}
+
+// nextKeyLine returns the line of the key following key in mapping, or eof
+func nextKeyLine(mapping, key *yaml.Node, eof int) int {
+	for i := 0; i+2 < len(mapping.Content); i += 2 {
+		if mapping.Content[i] == key {
+			return mapping.Content[i+2].Line
+		}
+	}
+	return eof
+}
+
+// blockEnd returns the last line before line next that is neither blank nor
+// a comment, which ends the block before next: comments just above a key
+// belong to it
+func blockEnd(lines []string, next int) int {
+	end := next - 1
+	for end > 0 {
+		text := strings.TrimSpace(lines[end-1])
+		if text != "" && !strings.HasPrefix(text, "#") {
+			break
+		}
+		end--
+	}
+	return end
+}
+
+// serverEntry returns server as the lines of a mapping entry called name,
+// indented by indent spaces, which is also the step of its nested blocks
+func serverEntry(name string, server ServerConfig, indent int) (string, error) {
+	var buf strings.Builder
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(max(indent, 2))
+	if err := encoder.Encode(map[string]ServerConfig{name: server}); err != nil {
+		return "", fmt.Errorf("failed to marshal server: %w", err)
+	}
+	encoder.Close()
+
+	var entry strings.Builder
+	for _, line := range strings.SplitAfter(buf.String(), "\n") {
+		if line != "" {
+			entry.WriteString(strings.Repeat(" ", indent) + line)
+		}
+	}
+	return entry.String(), nil
+}
+
+// yamlScalar returns value as a YAML scalar, quoted when needed
+func yamlScalar(value string) string {
+	data, _ := yaml.Marshal(value)
+	return strings.TrimSuffix(string(data), "\n")
+}
+
+func runConfigExport(cmd *cobra.Command, args []string) error {
+	config, err := loadActiveConfig()
+	if err != nil {
+		return err
+	}
+	name := config.DefaultServer
+	if len(args) > 0 {
+		name = args[0]
+	}
+	if name == "" {
+		return fmt.Errorf("no server given and no default_server configured")
+	}
+	server, ok := config.Servers[name]
+	if !ok {
+		return fmt.Errorf("server '%s' not found in configuration", name)
+	}
+
+	data, err := yaml.Marshal(newServerProfile(name, server))
+	if err != nil {
+		return fmt.Errorf("failed to marshal profile: %w", err)
+	}
+	data = append([]byte("# dgconnect server profile; add it with: dgconnect config import <file>\n"), data...)
+	if exportOutput == "" || exportOutput == "-" {
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+	if err := os.WriteFile(exportOutput, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write profile: %w", err)
+	}
+	fmt.Fprintf(os.Stderr, "Profile of '%s' written to %s\n", name, exportOutput)
+	return nil
+}
+
+func runConfigImport(cmd *cobra.Command, args []string) error {
+	var data []byte
+	var err error
+	if args[0] == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(args[0])
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read profile: %w", err)
+	}
+	profile, err := parseServerProfile(args[0], data)
+	if err != nil {
+		return err
+	}
+
+	name := profile.Name
+	if importName != "" {
+		name = importName
+	}
+	if name == "" {
+		name = serverName(profile.Host)
+	}
+	username := importUsername
+	if username == "" {
+		username = os.Getenv("USER")
+	}
+	if username == "" {
+		return fmt.Errorf("no username for '%s'; pass --username", name)
+	}
+
+	configPath, err := defaultInitPath()
+	if err != nil {
+		return err
+	}
+	server := profile.serverConfig(username, importKeyPath)
+	config, err := importServer(configPath, name, server)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Server '%s' added to %s\n", name, configPath)
+	if profile.Tileset != "" {
+		path := resolveTilesetPath(config, &ServerConfig{Tileset: profile.Tileset})
+		if _, err := os.Stat(path); err != nil {
+			fmt.Printf("The profile uses the tileset '%s'; install it as %s\n", profile.Tileset, path)
+		}
+	}
+	fmt.Printf("Start playing with: dgconnect-www %s\n", name)
+	return nil
+}
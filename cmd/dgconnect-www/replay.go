@@ -0,0 +1,244 @@
+package main
+
+import (
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/opd-ai/go-gamelaunch-www/pkg/ttyrec"
+	"github.com/opd-ai/go-gamelaunch-www/pkg/webui"
+	"github.com/spf13/cobra"
+)
+
+// Playback speeds reachable with the viewer's + and - keys
+const (
+	minReplaySpeed = 1.0 / 16
+	maxReplaySpeed = 64
+)
+
+// replayClear resets the terminal before each pass of a looped replay
+const replayClear = "\x1b[0m\x1b[H\x1b[2J"
+
+// Flags of the replay command
+var (
+	replaySpeed    float64
+	replayMaxDelay time.Duration
+	replayLoop     bool
+)
+
+var replayCmd = &cobra.Command{
+	Use:   "replay <file.ttyrec>",
+	Short: "Watch a recorded game in the web interface",
+	Long: `Serve a ttyrec recording, such as one from web.recordings or a
+dgamelaunch server's archive, through the web interface as if it were a live
+session. Files ending in .gz or .bz2 are decompressed.
+
+Viewers control playback with the keyboard: space pauses and resumes, + and -
+double and halve the speed, and = returns to the starting speed. The final
+screen stays up when the recording ends, unless --loop starts it over.
+
+Examples:
+  dgconnect-www replay alice/2026-10-01.20-15-00.000.ttyrec
+  dgconnect-www replay --speed 4 --max-delay 2s game.ttyrec.bz2`,
+	Args: cobra.ExactArgs(1),
+	RunE: runReplay,
+}
+
+// readRecording reads the frames of the ttyrec file at path, decompressing
+// it by its extension
+func readRecording(path string) ([]ttyrec.Frame, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read recording: %w", err)
+	}
+	var r io.Reader = bytes.NewReader(data)
+	switch filepath.Ext(path) {
+	case ".gz":
+		if r, err = gzip.NewReader(r); err != nil {
+			return nil, fmt.Errorf("failed to decompress recording: %w", err)
+		}
+	case ".bz2":
+		r = bzip2.NewReader(r)
+	}
+
+	frames, err := ttyrec.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read recording %s: %w", path, err)
+	}
+	if len(frames) == 0 {
+		return nil, fmt.Errorf("recording %s has no frames", path)
+	}
+	return frames, nil
+}
+
+// controlReplay applies a viewer's key presses to player, announcing the
+// result to every viewer
+func controlReplay(server *webui.WebUI, player *ttyrec.Player, input []byte) {
+	var notice string
+	for _, key := range input {
+		speed := player.Speed()
+		switch key {
+		case ' ':
+			player.SetPaused(!player.Paused())
+			played, total := player.Position()
+			notice = fmt.Sprintf("Replay resumed (frame %d of %d)", played, total)
+			if player.Paused() {
+				notice = fmt.Sprintf("Replay paused at frame %d of %d", played, total)
+			}
+			continue
+		case '+':
+			speed = min(speed*2, maxReplaySpeed)
+		case '-':
+			speed = max(speed/2, minReplaySpeed)
+		case '=':
+			speed = replaySpeed
+		default:
+			continue
+		}
+		if err := player.SetSpeed(speed); err == nil {
+			notice = fmt.Sprintf("Replay speed %gx", speed)
+		}
+	}
+	if notice != "" {
+		server.BroadcastMessage(webui.BroadcastParams{Message: notice})
+	}
+}
+
+func runReplay(cmd *cobra.Command, args []string) error {
+	frames, err := readRecording(args[0])
+	if err != nil {
+		return err
+	}
+	player, err := ttyrec.NewPlayer(frames, replaySpeed, replayMaxDelay)
+	if err != nil {
+		return fmt.Errorf("invalid --speed: %w", err)
+	}
+
+	fileConfig, err := loadActiveConfig()
+	if err != nil {
+		return err
+	}
+
+	webui.SetBuildInfo(version, commit, date)
+
+	viewOpts, err := resolveViewOptions(fileConfig, nil)
+	if err != nil {
+		return err
+	}
+	webView, err := webui.NewWebView(viewOpts)
+	if err != nil {
+		return fmt.Errorf("failed to create web view: %w", err)
+	}
+
+	addr, err := resolveListenAddr(cmd, fileConfig)
+	if err != nil {
+		return err
+	}
+
+	tilesetPath := resolveTilesetPath(fileConfig, nil)
+	var tilesetConfig *webui.TilesetConfig
+	if tilesetPath != "" {
+		tilesetConfig, err = webui.LoadTilesetConfig(tilesetPath)
+		if err != nil {
+			return fmt.Errorf("failed to load tileset: %w", err)
+		}
+	}
+
+	webServer, err := webui.NewWebUI(webui.WebUIOptions{
+		View:          webView,
+		TilesetPath:   tilesetPath,
+		Tileset:       tilesetConfig,
+		TilesetSubset: fileConfig.Web.TilesetSubset,
+		ListenAddr:    addr,
+		PollTimeout:   30 * time.Second,
+
+		MaxClients:      maxClients,
+		MaxClientsPerIP: maxClientsPerIP,
+
+		HTTP3: fileConfig.Web.HTTP3,
+
+		RateLimit:  fileConfig.Web.RateLimit,
+		AccessLog:  fileConfig.Web.AccessLog,
+		Bandwidth:  fileConfig.sharedBandwidth(),
+		DiffBudget: fileConfig.Web.DiffBudget,
+		Keyframes:  fileConfig.Web.Keyframes,
+		Thumbnails: fileConfig.Web.Thumbnails,
+		Minimap:    fileConfig.Web.Minimap,
+		Scrollback: fileConfig.Web.Scrollback,
+		DamageMap:  fileConfig.Web.DamageMap,
+		Progress:   fileConfig.Web.Progress,
+		Landmarks:  fileConfig.Web.Landmarks,
+		Snapshots:  fileConfig.Web.Snapshots,
+		Keyboards:  fileConfig.Web.Keyboards,
+		Cache:      fileConfig.Web.Cache,
+		Themes:     fileConfig.Web.Themes,
+		Font:       fileConfig.Web.Font,
+		Messages:   fileConfig.Web.Messages,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create web server: %w", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Play the recording, keeping its final screen up unless looping
+	go func() {
+		render := func(data []byte) error {
+			return webView.RenderContext(ctx, data)
+		}
+		for {
+			err := player.Run(ctx, render)
+			if err != nil {
+				if err != context.Canceled {
+					log.Printf("replay playback error: %v", err)
+				}
+				return
+			}
+			if !replayLoop {
+				webServer.BroadcastMessage(webui.BroadcastParams{Message: "Replay finished"})
+				return
+			}
+			if err := render([]byte(replayClear)); err != nil {
+				return
+			}
+		}
+	}()
+
+	// Viewer keys control playback and never reach a game
+	go func() {
+		for {
+			input, err := webView.HandleInput()
+			if err != nil {
+				return
+			}
+			controlReplay(webServer, player, input)
+		}
+	}()
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-sigCh
+		fmt.Println("\nReceived interrupt signal, shutting down...")
+		cancel()
+	}()
+
+	_, webPortStr, _ := net.SplitHostPort(addr)
+	fmt.Printf("Replaying %s (%d frames) on %s\n", args[0], len(frames), addr)
+	fmt.Printf("Connect to http://localhost:%s to watch; space pauses, + and - change the speed\n", webPortStr)
+
+	err = webServer.StartWithContext(ctx, addr)
+	webView.Close()
+	return err
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/opd-ai/go-gamelaunch-client/pkg/dgclient"
+	"github.com/opd-ai/go-gamelaunch-www/pkg/fanout"
+	"github.com/opd-ai/go-gamelaunch-www/pkg/webui"
+	"github.com/spf13/cobra"
+)
+
+var replicaCmd = &cobra.Command{
+	Use:   "replica",
+	Short: "Serve a session owned by another instance through Redis",
+	Long: `Start a web server that mirrors a game session published to Redis by
+another dgconnect-www instance, forwarding player input back to it. Run several
+replicas behind a load balancer to scale out spectators.
+
+Both the owning instance and the replicas need the same web.redis section in
+their configuration file.
+
+Examples:
+  dgconnect-www replica --config shared.yaml --web-port 8081`,
+	Args: cobra.NoArgs,
+	RunE: runReplica,
+}
+
+func runReplica(cmd *cobra.Command, args []string) error {
+	fileConfig, err := loadActiveConfig()
+	if err != nil {
+		return err
+	}
+	if fileConfig.Web.Redis == nil {
+		return fmt.Errorf("replica mode requires a web.redis section in the config file")
+	}
+
+	webui.SetBuildInfo(version, commit, date)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	store, err := fanout.NewRedisStore(ctx, *fileConfig.Web.Redis)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	webView, err := webui.NewWebViewWithStore(dgclient.DefaultViewOptions(), store)
+	if err != nil {
+		return fmt.Errorf("failed to create web view: %w", err)
+	}
+
+	addr, err := resolveListenAddr(cmd, fileConfig)
+	if err != nil {
+		return err
+	}
+
+	tilesetPath := resolveTilesetPath(fileConfig, nil)
+	var tilesetConfig *webui.TilesetConfig
+	if tilesetPath != "" {
+		tilesetConfig, err = webui.LoadTilesetConfig(tilesetPath)
+		if err != nil {
+			return fmt.Errorf("failed to load tileset: %w", err)
+		}
+	}
+
+	webServer, err := webui.NewWebUI(webui.WebUIOptions{
+		View:          webView,
+		TilesetPath:   tilesetPath,
+		Tileset:       tilesetConfig,
+		TilesetSubset: fileConfig.Web.TilesetSubset,
+		ListenAddr:    addr,
+		PollTimeout:   30 * time.Second,
+
+		MaxClients:      maxClients,
+		MaxClientsPerIP: maxClientsPerIP,
+
+		HTTP3: fileConfig.Web.HTTP3,
+
+		OIDC:          fileConfig.Web.Auth.OIDC,
+		ProxyAuth:     fileConfig.Web.Auth.Proxy,
+		StaticTokens:  fileConfig.Web.Auth.Tokens,
+		Htpasswd:      fileConfig.Web.Auth.Htpasswd,
+		Authorization: fileConfig.Web.Auth.Policy,
+		RateLimit:     fileConfig.Web.RateLimit,
+		AccessLog:     fileConfig.Web.AccessLog,
+		Bandwidth:     fileConfig.sharedBandwidth(),
+		DiffBudget:    fileConfig.Web.DiffBudget,
+		Keyframes:     fileConfig.Web.Keyframes,
+		Thumbnails:    fileConfig.Web.Thumbnails,
+		Minimap:       fileConfig.Web.Minimap,
+		DamageMap:     fileConfig.Web.DamageMap,
+		Progress:      fileConfig.Web.Progress,
+		Landmarks:     fileConfig.Web.Landmarks,
+		Snapshots:     fileConfig.Web.Snapshots,
+		Lobby:         fileConfig.lobbyConfig(),
+		Keyboards:     fileConfig.Web.Keyboards,
+		Cache:         fileConfig.Web.Cache,
+		InputFilter:   fileConfig.Web.InputFilter,
+		OutputFilter:  fileConfig.Web.OutputFilter,
+		Themes:        fileConfig.Web.Themes,
+		Font:          fileConfig.Web.Font,
+		Messages:      fileConfig.Web.Messages,
+		InputSink:     store.PublishInput,
+
+		WithholdConcealed: fileConfig.Web.WithholdConcealed,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create web server: %w", err)
+	}
+
+	go func() {
+		if err := store.Follow(ctx, webView.GetStateManager()); err != nil && err != context.Canceled {
+			log.Printf("redis follow error: %v", err)
+		}
+	}()
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-sigCh
+		fmt.Println("\nReceived interrupt signal, shutting down...")
+		cancel()
+	}()
+
+	fmt.Printf("Starting replica web server on %s\n", addr)
+	return webServer.StartWithContext(ctx, addr)
+}
@@ -0,0 +1,338 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"regexp"
+	"sort"
+	"syscall"
+	"time"
+
+	"github.com/opd-ai/go-gamelaunch-www/pkg/sshmux"
+	"github.com/opd-ai/go-gamelaunch-www/pkg/webui"
+	"github.com/spf13/cobra"
+)
+
+var tenantsCmd = &cobra.Command{
+	Use:   "tenants",
+	Short: "Serve every tenant of the config file from one process",
+	Long: `Start one web server hosting several isolated communities, each configured
+as a block under tenants with its own servers, tilesets and web login. A tenant
+is served below its URL prefix, and every server of the tenant is a session
+at <prefix>/s/<server>/; <prefix>/ opens the default server.
+
+The web section applies to every tenant, except for the tileset and auth
+settings each tenant replaces. Recordings, character dumps, tournaments,
+announcements, snapshots, session expiry, the bandwidth state file and HTTP/3
+are not available in this mode.
+
+Examples:
+  dgconnect-www tenants --config hosting.yaml --web-port 8080`,
+	Args: cobra.NoArgs,
+	RunE: runTenants,
+}
+
+// tenantNameChars matches valid tenant names
+var tenantNameChars = regexp.MustCompile(`^[A-Za-z0-9._-]+$`)
+
+// TenantConfig is a community served by the tenants command below its own
+// URL prefix, with its own servers, tilesets and web login
+type TenantConfig struct {
+	Prefix        string                  `yaml:"prefix,omitempty"` // e.g. "/club"; default "/<name>"
+	DefaultServer string                  `yaml:"default_server,omitempty"`
+	Servers       map[string]ServerConfig `yaml:"servers"`
+	Tileset       string                  `yaml:"tileset,omitempty"`     // default tileset, path or name
+	TilesetDir    string                  `yaml:"tileset_dir,omitempty"` // where named tilesets are found
+	Auth          WebAuthConfig           `yaml:"auth,omitempty"`
+}
+
+// prefix returns the URL prefix of the tenant called name
+func (t TenantConfig) prefix(name string) string {
+	if t.Prefix != "" {
+		return t.Prefix
+	}
+	return "/" + name
+}
+
+// defaultServer returns the server opened at the tenant's prefix
+func (t TenantConfig) defaultServer() string {
+	if t.DefaultServer != "" {
+		return t.DefaultServer
+	}
+	names := make([]string, 0, len(t.Servers))
+	for name := range t.Servers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names[0]
+}
+
+// config returns the configuration seen by the sessions of the tenant: c
+// with the servers, tilesets and login of the tenant
+func (t TenantConfig) config(c *Config) *Config {
+	tenant := *c
+	tenant.DefaultServer = t.DefaultServer
+	tenant.Servers = t.Servers
+	tenant.Tenants = nil
+	tenant.Web.Tileset = t.Tileset
+	tenant.Web.TilesetDir = t.TilesetDir
+	tenant.Web.Auth = t.Auth
+	if htpasswd := tenant.Web.Auth.Htpasswd; htpasswd != nil {
+		expanded := *htpasswd
+		expanded.File = expandPath(expanded.File)
+		tenant.Web.Auth.Htpasswd = &expanded
+	}
+	return &tenant
+}
+
+// tenantHostKeyStore opens the host key store of the tenant called name.
+// Tenants may share game servers, so none may approve a key for another:
+// each records the keys its admins approve in a known_hosts file of its
+// own, <known_hosts>.<name>, trusting the managed store of web.host_keys,
+// which is only written outside tenants mode.
+func tenantHostKeyStore(fileConfig *Config, name string) (*webui.HostKeyStore, error) {
+	cfg := hostKeyConfig(fileConfig)
+	cfg.Trusted = append(cfg.Trusted, cfg.KnownHosts)
+	cfg.KnownHosts += "." + name
+	return webui.NewHostKeyStore(cfg)
+}
+
+// validateTenants checks the tenants of a config
+func validateTenants(tenants map[string]TenantConfig) error {
+	prefixes := make(map[string]string, len(tenants))
+	for name, tenant := range tenants {
+		// The name is part of the tenant's known_hosts file name
+		if !tenantNameChars.MatchString(name) {
+			return fmt.Errorf("tenant '%s': names may only use letters, digits, '.', '_' and '-'", name)
+		}
+		if len(tenant.Servers) == 0 {
+			return fmt.Errorf("tenant '%s' has no servers configured", name)
+		}
+		if err := validateServers(tenant.Servers); err != nil {
+			return fmt.Errorf("tenant '%s': %w", name, err)
+		}
+		if tenant.DefaultServer != "" {
+			if _, exists := tenant.Servers[tenant.DefaultServer]; !exists {
+				return fmt.Errorf("tenant '%s': default_server '%s' not found in servers list", name, tenant.DefaultServer)
+			}
+		}
+		for serverName, server := range tenant.Servers {
+			if server.Auth.Method == "password" {
+				return fmt.Errorf("tenant '%s': server '%s' uses password auth, which needs a terminal", name, serverName)
+			}
+		}
+
+		prefix := tenant.prefix(name)
+		if other, ok := prefixes[prefix]; ok {
+			return fmt.Errorf("tenants '%s' and '%s' share the prefix '%s'", other, name, prefix)
+		}
+		prefixes[prefix] = name
+	}
+	return nil
+}
+
+func runTenants(cmd *cobra.Command, args []string) error {
+	fileConfig, err := loadActiveConfig()
+	if err != nil {
+		return err
+	}
+	if len(fileConfig.Tenants) == 0 {
+		return fmt.Errorf("tenants mode requires a tenants section in the config file")
+	}
+	if err := validateTenants(fileConfig.Tenants); err != nil {
+		return err
+	}
+
+	webui.SetBuildInfo(version, commit, date)
+
+	addr, err := resolveListenAddr(cmd, fileConfig)
+	if err != nil {
+		return err
+	}
+
+	// Tenants connecting to the same server as the same user share its
+	// transport
+	sshPool := sshmux.NewPool(time.Minute)
+	defer sshPool.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	names := make([]string, 0, len(fileConfig.Tenants))
+	for name := range fileConfig.Tenants {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	router := webui.NewTenantRouter(nil)
+	for _, name := range names {
+		tenant := fileConfig.Tenants[name]
+		hostKeys, err := tenantHostKeyStore(fileConfig, name)
+		if err != nil {
+			return fmt.Errorf("tenant '%s': %w", name, err)
+		}
+		sessions, err := startTenant(ctx, sshPool, tenant.config(fileConfig), tenant.defaultServer(), hostKeys)
+		if err != nil {
+			return fmt.Errorf("tenant '%s': %w", name, err)
+		}
+		if err := router.Add(tenant.prefix(name), sessions); err != nil {
+			return fmt.Errorf("tenant '%s': %w", name, err)
+		}
+		fmt.Printf("Tenant %s served at %s/\n", name, tenant.prefix(name))
+	}
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-sigCh
+		fmt.Println("\nReceived interrupt signal, shutting down...")
+		cancel()
+	}()
+
+	server := &http.Server{
+		Addr:         addr,
+		Handler:      router,
+		ReadTimeout:  30 * time.Second,
+		WriteTimeout: 30 * time.Second,
+		IdleTimeout:  120 * time.Second,
+	}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+
+	fmt.Printf("Starting tenant web server on %s\n", addr)
+	if err := server.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// startTenant connects to every server of a tenant, returning the router
+// serving each as a session named after the server
+func startTenant(ctx context.Context, sshPool *sshmux.Pool, tenantConfig *Config, defaultServer string, hostKeys *webui.HostKeyStore) (*webui.SessionRouter, error) {
+	// The tenant's prefix opens the default server. The redirect is
+	// relative, as the router removed the prefix from the path.
+	home := http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(rw, r)
+			return
+		}
+		rw.Header().Set("Location", "s/"+defaultServer+"/")
+		rw.WriteHeader(http.StatusFound)
+	})
+	sessions := webui.NewSessionRouter(home)
+	watchdog, err := streamWatchdog(tenantConfig)
+	if err != nil {
+		return nil, err
+	}
+	negotiator, err := rtcNegotiator(tenantConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	for name, server := range tenantConfig.Servers {
+		if server.Port == 0 {
+			server.Port = 22
+		}
+		viewOpts := tenantConfig.viewOptions(server.View)
+		webView, err := webui.NewWebView(viewOpts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create web view: %w", err)
+		}
+		env, err := resolveEnv(tenantConfig, name)
+		if err != nil {
+			return nil, err
+		}
+
+		tilesetPath := resolveTilesetPath(tenantConfig, &server)
+		var tilesetConfig *webui.TilesetConfig
+		if tilesetPath != "" {
+			tilesetConfig, err = webui.LoadTilesetConfig(tilesetPath)
+			if err != nil {
+				return nil, fmt.Errorf("failed to load tileset: %w", err)
+			}
+		}
+
+		// Nobody watches the terminal, so login prompts go to the web
+		challenges := webui.NewChallengeRelay(0)
+		if server.Auth.Method == "keyboard-interactive" {
+			server.Auth.Prompt = "web"
+		}
+		auth, ok := serverAuthMethod(server.Auth, challenges)
+		if !ok {
+			return nil, fmt.Errorf("server '%s': %s auth is not available", name, server.Auth.Method)
+		}
+
+		web := tenantConfig.Web
+		ui, err := webui.NewWebUI(webui.WebUIOptions{
+			View:          webView,
+			TilesetPath:   tilesetPath,
+			Tileset:       tilesetConfig,
+			TilesetSubset: web.TilesetSubset,
+			PollTimeout:   30 * time.Second,
+
+			OIDC:          web.Auth.OIDC,
+			ProxyAuth:     web.Auth.Proxy,
+			StaticTokens:  web.Auth.Tokens,
+			Htpasswd:      web.Auth.Htpasswd,
+			Authorization: web.Auth.Policy,
+			RateLimit:     web.RateLimit,
+			AccessLog:     web.AccessLog,
+			Bandwidth:     tenantConfig.sharedBandwidth(),
+			DiffBudget:    web.DiffBudget,
+			Keyframes:     web.Keyframes,
+
+			HistoryCompaction: web.HistoryCompaction,
+
+			EscapePolicy:     web.EscapePolicy,
+			ProtectedRegions: web.ProtectedRegions,
+
+			WithholdConcealed: web.WithholdConcealed,
+
+			InstantReplay: web.InstantReplay,
+			Thumbnails:    web.Thumbnails,
+			Minimap:       web.Minimap,
+			Scrollback:    web.Scrollback,
+			DamageMap:     web.DamageMap,
+			Progress:      web.Progress,
+			Landmarks:     web.Landmarks,
+			Lobby:         tenantConfig.lobbyConfig(),
+			Keyboards:     web.Keyboards,
+			Cache:         web.Cache,
+			InputFilter:   web.InputFilter,
+			OutputFilter:  web.OutputFilter,
+			Themes:        web.Themes,
+			Font:          web.Font,
+			Messages:      web.Messages,
+			SSHChallenges: challenges,
+			HostKeys:      hostKeys,
+			RTC:           negotiator,
+			InputPreset:   server.InputPreset,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("server '%s': failed to create web server: %w", name, err)
+		}
+		if err := sessions.Add(name, ui); err != nil {
+			return nil, err
+		}
+		go ui.Run(ctx)
+
+		reconnect := tenantConfig.reconnectPolicy(func() (*webui.WebView, error) {
+			return webui.NewWebView(viewOpts)
+		})
+		go func() {
+			if err := runDGClient(ctx, sshPool, server.Host, server.Username, server.Port, viewOpts.TerminalType, server.DefaultGame, env, auth, ui, reconnect, watchdog, nil, nil, challenges, hostKeys); err != nil {
+				log.Printf("dgclient error (%s): %v", name, err)
+			}
+		}()
+	}
+	return sessions, nil
+}
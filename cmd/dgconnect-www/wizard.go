@@ -0,0 +1,377 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/opd-ai/go-gamelaunch-client/pkg/dgclient"
+	"github.com/opd-ai/go-gamelaunch-www/pkg/transport"
+	"github.com/opd-ai/go-gamelaunch-www/pkg/webui"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/term"
+)
+
+// probeTimeout bounds the test connection of the init wizard, including the
+// wait for the game menu
+const probeTimeout = 15 * time.Second
+
+// authMethods are the auth methods offered by the init wizard
+var authMethods = []string{"agent", "key", "password", "keyboard-interactive"}
+
+// prompter asks the questions of the init wizard
+type prompter struct {
+	in  *bufio.Reader
+	out io.Writer
+}
+
+// ask prints question and returns the answer, or def for an empty answer
+func (p *prompter) ask(question, def string) (string, error) {
+	if def != "" {
+		fmt.Fprintf(p.out, "%s [%s]: ", question, def)
+	} else {
+		fmt.Fprintf(p.out, "%s: ", question)
+	}
+	line, err := p.in.ReadString('\n')
+	if err != nil && (err != io.EOF || line == "") {
+		return "", fmt.Errorf("failed to read answer: %w", err)
+	}
+	if answer := strings.TrimSpace(line); answer != "" {
+		return answer, nil
+	}
+	return def, nil
+}
+
+// require asks until the answer is not empty
+func (p *prompter) require(question, def string) (string, error) {
+	for {
+		answer, err := p.ask(question, def)
+		if err != nil || answer != "" {
+			return answer, err
+		}
+		fmt.Fprintln(p.out, "  An answer is required.")
+	}
+}
+
+// askPort asks for a TCP port
+func (p *prompter) askPort(question string, def int) (int, error) {
+	for {
+		answer, err := p.ask(question, strconv.Itoa(def))
+		if err != nil {
+			return 0, err
+		}
+		if port, err := strconv.Atoi(answer); err == nil && port >= 1 && port <= 65535 {
+			return port, nil
+		}
+		fmt.Fprintf(p.out, "  %q is not a port number.\n", answer)
+	}
+}
+
+// confirm asks a yes/no question
+func (p *prompter) confirm(question string, def bool) (bool, error) {
+	hint := "y/N"
+	if def {
+		hint = "Y/n"
+	}
+	for {
+		answer, err := p.ask(fmt.Sprintf("%s (%s)", question, hint), "")
+		if err != nil {
+			return false, err
+		}
+		switch strings.ToLower(answer) {
+		case "":
+			return def, nil
+		case "y", "yes":
+			return true, nil
+		case "n", "no":
+			return false, nil
+		}
+	}
+}
+
+// choose lists options and returns the one picked by number or name
+func (p *prompter) choose(question string, options []string, def string) (string, error) {
+	for i, option := range options {
+		fmt.Fprintf(p.out, "  %d) %s\n", i+1, option)
+	}
+	for {
+		answer, err := p.ask(question, def)
+		if err != nil {
+			return "", err
+		}
+		if n, err := strconv.Atoi(answer); err == nil && n >= 1 && n <= len(options) {
+			return options[n-1], nil
+		}
+		for _, option := range options {
+			if answer == option {
+				return option, nil
+			}
+		}
+		fmt.Fprintf(p.out, "  Pick one of 1-%d.\n", len(options))
+	}
+}
+
+// secret reads an answer without echoing it
+func (p *prompter) secret(question string) (string, error) {
+	fmt.Fprintf(p.out, "%s: ", question)
+	data, err := term.ReadPassword(int(os.Stdin.Fd()))
+	fmt.Fprintln(p.out)
+	if err != nil {
+		return "", fmt.Errorf("failed to read answer: %w", err)
+	}
+	return string(data), nil
+}
+
+// runInitWizard asks for a server, test-connects to it to detect its games
+// and writes the result to configPath, adding to the configuration already
+// there
+func runInitWizard(configPath string) error {
+	p := &prompter{in: bufio.NewReader(os.Stdin), out: os.Stdout}
+
+	config := &Config{Preferences: GenerateExampleConfig().Preferences}
+	if _, err := os.Stat(configPath); err == nil {
+		if config, err = LoadConfig(configPath); err != nil {
+			return err
+		}
+		fmt.Printf("Adding a server to the configuration at %s\n", configPath)
+	}
+	if config.Servers == nil {
+		config.Servers = make(map[string]ServerConfig)
+	}
+
+	fmt.Println("\nGame server")
+	var server ServerConfig
+	var err error
+	if server.Host, err = p.require("Host", ""); err != nil {
+		return err
+	}
+	if server.Port, err = p.askPort("SSH port", 22); err != nil {
+		return err
+	}
+	if server.Username, err = p.require("Username", os.Getenv("USER")); err != nil {
+		return err
+	}
+
+	defaultAuth := "key"
+	if os.Getenv("SSH_AUTH_SOCK") != "" {
+		defaultAuth = "agent"
+	}
+	if server.Auth.Method, err = p.choose("Authentication", authMethods, defaultAuth); err != nil {
+		return err
+	}
+	if server.Auth.Method == "key" {
+		if server.Auth.KeyPath, err = p.require("Private key", defaultKeyPath()); err != nil {
+			return err
+		}
+	}
+
+	// Test the connection and pick a game from its menu
+	probe, err := p.confirm("Test the connection and detect games now?", true)
+	if err != nil {
+		return err
+	}
+	var games []dgclient.GameInfo
+	if probe {
+		games, err = probeServer(p, config, server)
+		if err != nil {
+			fmt.Printf("  Connection test failed: %v\n", err)
+			keep, err := p.confirm("Save this server anyway?", false)
+			if err != nil || !keep {
+				return err
+			}
+		} else if len(games) == 0 {
+			fmt.Println("  Connected, but the menu offers no games; they may only appear after logging in to dgamelaunch.")
+		}
+	}
+	if server.DefaultGame, err = chooseGame(p, games); err != nil {
+		return err
+	}
+
+	name, err := p.require("Name for this server", serverName(server.Host))
+	if err != nil {
+		return err
+	}
+	if _, exists := config.Servers[name]; exists {
+		replace, err := p.confirm(fmt.Sprintf("Server '%s' already exists. Replace it?", name), false)
+		if err != nil || !replace {
+			if err == nil {
+				fmt.Println("Configuration generation cancelled.")
+			}
+			return err
+		}
+	}
+
+	fmt.Println("\nWeb interface")
+	webPort := 8080
+	if _, port, err := net.SplitHostPort(config.Web.Listen); err == nil {
+		webPort, _ = strconv.Atoi(port)
+	}
+	if webPort, err = p.askPort("Web server port", webPort); err != nil {
+		return err
+	}
+	config.Web.Listen = fmt.Sprintf(":%d", webPort)
+	if server.Tileset, err = chooseTileset(p, config); err != nil {
+		return err
+	}
+
+	config.Servers[name] = server
+	if config.DefaultServer == "" {
+		config.DefaultServer = name
+	} else if config.DefaultServer != name {
+		makeDefault, err := p.confirm(fmt.Sprintf("Make '%s' the default server?", name), true)
+		if err != nil {
+			return err
+		}
+		if makeDefault {
+			config.DefaultServer = name
+		}
+	}
+	if err := ValidateConfig(config); err != nil {
+		return fmt.Errorf("invalid configuration: %w", err)
+	}
+	if err := SaveConfig(config, configPath); err != nil {
+		return fmt.Errorf("failed to save configuration: %w", err)
+	}
+
+	fmt.Printf("\nConfiguration written to %s\n", configPath)
+	fmt.Printf("Start playing with: dgconnect-www %s\n", name)
+	return nil
+}
+
+// probeServer logs in to server as configured, approving unknown host keys
+// on the terminal, and returns the games of its dgamelaunch menu
+func probeServer(p *prompter, config *Config, server ServerConfig) ([]dgclient.GameInfo, error) {
+	auth, ok := serverAuthMethod(server.Auth, nil)
+	if !ok {
+		switch server.Auth.Method {
+		case "password":
+			secret, err := p.secret(fmt.Sprintf("Password for %s@%s (not saved)", server.Username, server.Host))
+			if err != nil {
+				return nil, err
+			}
+			auth = dgclient.NewPasswordAuth(secret)
+		case "agent":
+			return nil, fmt.Errorf("no SSH agent is running (SSH_AUTH_SOCK is not set)")
+		default:
+			return nil, fmt.Errorf("auth method %s cannot be tested", server.Auth.Method)
+		}
+	}
+	sshAuth, err := auth.GetSSHAuthMethod()
+	if err != nil {
+		return nil, err
+	}
+
+	hostKeys, err := hostKeyStore(config)
+	if err != nil {
+		return nil, err
+	}
+	addr := net.JoinHostPort(server.Host, strconv.Itoa(server.Port))
+	fmt.Printf("  Connecting to %s@%s...\n", server.Username, addr)
+	client, err := ssh.Dial("tcp", addr, &ssh.ClientConfig{
+		User: server.Username,
+		Auth: []ssh.AuthMethod{sshAuth},
+		HostKeyCallback: hostKeys.CheckWith(func(request *transport.HostKeyPayload) (bool, error) {
+			if len(request.Known) > 0 {
+				fmt.Printf("  WARNING: the host key of %s has CHANGED (was %s)\n", request.Host, strings.Join(request.Known, ", "))
+			} else {
+				fmt.Printf("  The host key of %s is not known yet.\n", request.Host)
+			}
+			fmt.Printf("  %s key fingerprint is %s\n", request.KeyType, request.Fingerprint)
+			return p.confirm("  Trust this host key?", false)
+		}),
+		Timeout: 10 * time.Second,
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+	fmt.Println("  Logged in, waiting for the game menu...")
+
+	ctx, cancel := context.WithTimeout(context.Background(), probeTimeout)
+	defer cancel()
+	games, err := webui.ProbeGameMenu(ctx, client, config.viewOptions(server.View))
+	if err != nil && ctx.Err() != nil {
+		return nil, nil // logged in, but no games on the menu
+	}
+	return games, err
+}
+
+// chooseGame asks for the game to launch directly, listing the detected
+// games, and returns its menu key or "" for none
+func chooseGame(p *prompter, games []dgclient.GameInfo) (string, error) {
+	if len(games) == 0 {
+		return p.ask("Game to launch directly (menu key, blank to show the menu)", "")
+	}
+	fmt.Println("Detected games:")
+	options := make([]string, 0, len(games)+1)
+	for _, game := range games {
+		options = append(options, fmt.Sprintf("%s) %s", game.Command, game.Description))
+	}
+	options = append(options, "none, show the menu")
+	choice, err := p.choose("Game to launch directly", options, strconv.Itoa(len(options)))
+	if err != nil {
+		return "", err
+	}
+	for i, option := range options[:len(games)] {
+		if choice == option {
+			return games[i].Command, nil
+		}
+	}
+	return "", nil
+}
+
+// chooseTileset asks for a tileset by name or path, checking that it loads,
+// and returns "" for none
+func chooseTileset(p *prompter, config *Config) (string, error) {
+	dir := config.Web.TilesetDir
+	if dir == "" {
+		dir = defaultTilesetDir
+	}
+	files, _ := filepath.Glob(filepath.Join(expandPath(dir), "*.yaml"))
+	if len(files) > 0 {
+		fmt.Printf("Tilesets in %s:\n", dir)
+		for _, file := range files {
+			fmt.Printf("  %s\n", strings.TrimSuffix(filepath.Base(file), ".yaml"))
+		}
+	}
+
+	for {
+		tileset, err := p.ask("Tileset (name or path, blank for text only)", "")
+		if err != nil || tileset == "" {
+			return "", err
+		}
+		path := resolveTilesetPath(config, &ServerConfig{Tileset: tileset})
+		if _, err := webui.LoadTilesetConfig(path); err != nil {
+			fmt.Printf("  Cannot use %s: %v\n", path, err)
+			continue
+		}
+		return tileset, nil
+	}
+}
+
+// defaultKeyPath returns the first of the usual SSH keys that exists
+func defaultKeyPath() string {
+	for _, name := range []string{"id_ed25519", "id_ecdsa", "id_rsa"} {
+		path := "~/.ssh/" + name
+		if _, err := os.Stat(expandPath(path)); err == nil {
+			return path
+		}
+	}
+	return "~/.ssh/id_ed25519"
+}
+
+// serverName suggests a config name for host: its first label, e.g.
+// "nethack" for nethack.example.com
+func serverName(host string) string {
+	if name, _, ok := strings.Cut(host, "."); ok && name != "" && net.ParseIP(host) == nil {
+		return name
+	}
+	return host
+}
@@ -0,0 +1,166 @@
+// Package announce posts game events such as deaths and new records to an
+// IRC channel or a Discord webhook, in the manner of the announce bots run by
+// public roguelike servers.
+package announce
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Announcer defaults
+const (
+	queueSize   = 64
+	sendTimeout = 10 * time.Second
+)
+
+// EventType identifies the kind of announced event
+type EventType string
+
+// Announced events
+const (
+	EventSessionStarted EventType = "session_started"
+	EventDeath          EventType = "death"  // a game ended; Outcome holds the cause
+	EventRecord         EventType = "record" // a new tournament high score
+)
+
+// Default message templates, rendered with an Event
+var defaultMessages = map[EventType]string{
+	EventSessionStarted: `{{.Player}} started playing on {{.Session}}`,
+	EventDeath:          `{{.Player}}{{with .Game}} [{{.}}]{{end}}: {{.Outcome}}, {{.Score}} points`,
+	EventRecord:         `New record{{with .Tournament}} in {{.}}{{end}}! {{.Player}} scored {{.Score}} points`,
+}
+
+// Config selects the destinations and messages of an Announcer
+type Config struct {
+	IRC     *IRCConfig     `yaml:"irc,omitempty"`
+	Discord *DiscordConfig `yaml:"discord,omitempty"`
+
+	// Events to announce; default all
+	Events []EventType `yaml:"events,omitempty"`
+
+	// Go text/template overrides keyed by event type, e.g.
+	// death: "RIP {{.Player}}, {{.Outcome}}"
+	Messages map[EventType]string `yaml:"messages,omitempty"`
+}
+
+// Event is an announced occurrence
+type Event struct {
+	Type       EventType
+	Player     string
+	Session    string
+	Game       string
+	Outcome    string
+	Score      int64
+	Tournament string
+}
+
+// sink delivers formatted messages to one destination
+type sink interface {
+	Send(ctx context.Context, text string) error
+	Close() error
+}
+
+// Announcer formats events and delivers them in the background
+type Announcer struct {
+	sinks     []sink
+	templates map[EventType]*template.Template
+	queue     chan string
+}
+
+// New validates cfg and creates an announcer. Messages are delivered once
+// Run is called.
+func New(cfg Config) (*Announcer, error) {
+	a := &Announcer{
+		templates: make(map[EventType]*template.Template),
+		queue:     make(chan string, queueSize),
+	}
+
+	if cfg.IRC != nil {
+		s, err := newIRCSink(*cfg.IRC)
+		if err != nil {
+			return nil, err
+		}
+		a.sinks = append(a.sinks, s)
+	}
+	if cfg.Discord != nil {
+		s, err := newDiscordSink(*cfg.Discord)
+		if err != nil {
+			return nil, err
+		}
+		a.sinks = append(a.sinks, s)
+	}
+	if len(a.sinks) == 0 {
+		return nil, fmt.Errorf("announce: no irc or discord destination configured")
+	}
+
+	events := cfg.Events
+	if len(events) == 0 {
+		events = []EventType{EventSessionStarted, EventDeath, EventRecord}
+	}
+	for _, event := range events {
+		text, ok := defaultMessages[event]
+		if !ok {
+			return nil, fmt.Errorf("announce: unknown event %q", event)
+		}
+		if custom := cfg.Messages[event]; custom != "" {
+			text = custom
+		}
+		tmpl, err := template.New(string(event)).Parse(text)
+		if err != nil {
+			return nil, fmt.Errorf("announce: invalid %s message: %w", event, err)
+		}
+		a.templates[event] = tmpl
+	}
+
+	return a, nil
+}
+
+// Announce queues an event for delivery. Events that are not enabled are
+// ignored, and events are dropped while the queue is full.
+func (a *Announcer) Announce(event Event) {
+	tmpl, ok := a.templates[event.Type]
+	if !ok {
+		return
+	}
+	var sb strings.Builder
+	if err := tmpl.Execute(&sb, event); err != nil {
+		slog.Warn("announce: format failed", "event", event.Type, "error", err)
+		return
+	}
+
+	select {
+	case a.queue <- sb.String():
+	default:
+		slog.Warn("announce: queue full, message dropped", "event", event.Type)
+	}
+}
+
+// Run delivers queued messages until ctx is cancelled, then closes the
+// destinations
+func (a *Announcer) Run(ctx context.Context) {
+	defer func() {
+		for _, s := range a.sinks {
+			s.Close()
+		}
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case text := <-a.queue:
+			for _, s := range a.sinks {
+				sendCtx, cancel := context.WithTimeout(ctx, sendTimeout)
+				if err := s.Send(sendCtx, text); err != nil {
+					slog.Warn("announce: delivery failed", "error", err)
+				}
+				cancel()
+			}
+		}
+	}
+}
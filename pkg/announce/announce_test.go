@@ -0,0 +1,158 @@
+package announce
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newDiscordServer records the content of every webhook message
+func newDiscordServer(t *testing.T) (*httptest.Server, chan string) {
+	t.Helper()
+	messages := make(chan string, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Content string `json:"content"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+		messages <- body.Content
+		rw.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(server.Close)
+	return server, messages
+}
+
+func receive(t *testing.T, messages chan string) string {
+	t.Helper()
+	select {
+	case msg := <-messages:
+		return msg
+	case <-time.After(2 * time.Second):
+		t.Fatal("no message delivered")
+		return ""
+	}
+}
+
+func TestNew_Validation(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  Config
+	}{
+		{"no destination", Config{}},
+		{"bad webhook", Config{Discord: &DiscordConfig{WebhookURL: "not a url"}}},
+		{"irc without port", Config{IRC: &IRCConfig{Server: "irc.libera.chat", Nick: "bot", Channel: "#nethack"}}},
+		{"irc without channel prefix", Config{IRC: &IRCConfig{Server: "irc.libera.chat:6697", Nick: "bot", Channel: "nethack"}}},
+		{"unknown event", Config{Discord: &DiscordConfig{WebhookURL: "https://example.com/hook"}, Events: []EventType{"ascension"}}},
+		{"bad template", Config{Discord: &DiscordConfig{WebhookURL: "https://example.com/hook"}, Messages: map[EventType]string{EventDeath: "{{.Player"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := New(tt.cfg); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
+
+func TestAnnouncer_Discord_FormatsEvents(t *testing.T) {
+	server, messages := newDiscordServer(t)
+	announcer, err := New(Config{
+		Discord:  &DiscordConfig{WebhookURL: server.URL},
+		Events:   []EventType{EventDeath, EventRecord},
+		Messages: map[EventType]string{EventRecord: "{{.Player}} tops {{.Tournament}}"},
+	})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go announcer.Run(ctx)
+
+	// Disabled events are skipped
+	announcer.Announce(Event{Type: EventSessionStarted, Player: "alice"})
+	announcer.Announce(Event{Type: EventDeath, Player: "alice", Game: "nethack", Outcome: "killed by a jackal", Score: 12})
+	announcer.Announce(Event{Type: EventRecord, Player: "alice", Tournament: "cup"})
+
+	if got, want := receive(t, messages), "alice [nethack]: killed by a jackal, 12 points"; got != want {
+		t.Errorf("death message = %q, want %q", got, want)
+	}
+	if got, want := receive(t, messages), "alice tops cup"; got != want {
+		t.Errorf("record message = %q, want %q", got, want)
+	}
+}
+
+func TestAnnouncer_IRC_RegistersAndPosts(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	defer listener.Close()
+
+	lines := make(chan string, 20)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		reader := bufio.NewReader(conn)
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				return
+			}
+			line = strings.TrimRight(line, "\r\n")
+			lines <- line
+			switch {
+			case line == "NICK bot":
+				conn.Write([]byte(":irc.test 433 * bot :Nickname is already in use\r\n"))
+			case line == "NICK bot_":
+				conn.Write([]byte("PING :irc.test\r\n:irc.test 001 bot_ :Welcome\r\n"))
+			}
+		}
+	}()
+
+	announcer, err := New(Config{IRC: &IRCConfig{Server: listener.Addr().String(), Nick: "bot", Channel: "#nethack"}})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go announcer.Run(ctx)
+
+	announcer.Announce(Event{Type: EventSessionStarted, Player: "alice", Session: "alice@nao"})
+
+	want := []string{"NICK bot", "USER bot 0 * :dgconnect-www announcer", "NICK bot_", "PONG :irc.test", "JOIN #nethack", "PRIVMSG #nethack :alice started playing on alice@nao"}
+	for _, w := range want {
+		if got := receive(t, lines); got != w {
+			t.Errorf("server received %q, want %q", got, w)
+		}
+	}
+}
+
+func TestParseIRCLine(t *testing.T) {
+	tests := []struct {
+		line, command, trailing string
+	}{
+		{"PING :irc.test\r\n", "PING", "irc.test"},
+		{":irc.test 001 bot :Welcome to IRC\r\n", "001", "Welcome to IRC"},
+		{"ERROR :Closing link\r\n", "ERROR", "Closing link"},
+		{":prefix-only", "", ""},
+	}
+	for _, tt := range tests {
+		command, trailing := parseIRCLine(tt.line)
+		if command != tt.command || trailing != tt.trailing {
+			t.Errorf("parseIRCLine(%q) = %q, %q; want %q, %q", tt.line, command, trailing, tt.command, tt.trailing)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package announce
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// DiscordConfig configures delivery to a Discord channel webhook
+type DiscordConfig struct {
+	WebhookURL string `yaml:"webhook_url"`
+	Username   string `yaml:"username,omitempty"` // overrides the webhook's display name
+}
+
+// discordSink posts messages to a Discord webhook
+type discordSink struct {
+	url      string
+	username string
+	client   *http.Client
+}
+
+// newDiscordSink validates cfg and creates a Discord destination
+func newDiscordSink(cfg DiscordConfig) (*discordSink, error) {
+	u, err := url.Parse(cfg.WebhookURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("announce: invalid discord webhook_url %q", cfg.WebhookURL)
+	}
+	return &discordSink{
+		url:      cfg.WebhookURL,
+		username: cfg.Username,
+		client:   &http.Client{Timeout: sendTimeout},
+	}, nil
+}
+
+// Send implements sink
+func (s *discordSink) Send(ctx context.Context, text string) error {
+	body, err := json.Marshal(struct {
+		Content  string `json:"content"`
+		Username string `json:"username,omitempty"`
+	}{text, s.username})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("discord: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("discord: %w", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("discord: webhook returned %s", resp.Status)
+	}
+	return nil
+}
+
+// Close implements sink
+func (s *discordSink) Close() error {
+	return nil
+}
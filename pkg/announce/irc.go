@@ -0,0 +1,204 @@
+package announce
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxIRCMessage keeps PRIVMSG lines well inside the 512-byte IRC limit
+const maxIRCMessage = 400
+
+// IRCConfig configures delivery to an IRC channel
+type IRCConfig struct {
+	Server   string `yaml:"server"` // host:port
+	TLS      bool   `yaml:"tls,omitempty"`
+	Nick     string `yaml:"nick"`
+	Channel  string `yaml:"channel"`
+	Password string `yaml:"password,omitempty"` // server password
+}
+
+// ircSink posts messages to an IRC channel, connecting on first use and
+// reconnecting after the connection drops
+type ircSink struct {
+	cfg IRCConfig
+
+	mu   sync.Mutex
+	conn net.Conn
+}
+
+// newIRCSink validates cfg and creates an IRC destination
+func newIRCSink(cfg IRCConfig) (*ircSink, error) {
+	if _, _, err := net.SplitHostPort(cfg.Server); err != nil {
+		return nil, fmt.Errorf("announce: invalid irc server %q: %w", cfg.Server, err)
+	}
+	if cfg.Nick == "" {
+		return nil, fmt.Errorf("announce: irc nick is required")
+	}
+	if !strings.HasPrefix(cfg.Channel, "#") && !strings.HasPrefix(cfg.Channel, "&") {
+		return nil, fmt.Errorf("announce: invalid irc channel %q", cfg.Channel)
+	}
+	return &ircSink{cfg: cfg}, nil
+}
+
+// Send implements sink
+func (s *ircSink) Send(ctx context.Context, text string) error {
+	text = strings.NewReplacer("\r", " ", "\n", " ").Replace(text)
+	if len(text) > maxIRCMessage {
+		text = text[:maxIRCMessage]
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.conn == nil {
+		conn, reader, err := s.connect(ctx)
+		if err != nil {
+			return err
+		}
+		s.conn = conn
+		go s.readLoop(conn, reader)
+	}
+
+	if err := s.writeLine(s.conn, "PRIVMSG %s :%s", s.cfg.Channel, text); err != nil {
+		s.conn.Close()
+		s.conn = nil
+		return fmt.Errorf("irc: %w", err)
+	}
+	return nil
+}
+
+// Close implements sink
+func (s *ircSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.conn == nil {
+		return nil
+	}
+	s.writeLine(s.conn, "QUIT :Shutting down")
+	err := s.conn.Close()
+	s.conn = nil
+	return err
+}
+
+// connect registers with the server and joins the channel
+func (s *ircSink) connect(ctx context.Context) (net.Conn, *bufio.Reader, error) {
+	dialer := &net.Dialer{}
+	var conn net.Conn
+	var err error
+	if s.cfg.TLS {
+		host, _, _ := net.SplitHostPort(s.cfg.Server)
+		tlsDialer := &tls.Dialer{NetDialer: dialer, Config: &tls.Config{ServerName: host}}
+		conn, err = tlsDialer.DialContext(ctx, "tcp", s.cfg.Server)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", s.cfg.Server)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("irc: failed to connect: %w", err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	reader := bufio.NewReader(conn)
+	if err := s.register(conn, reader); err != nil {
+		conn.Close()
+		return nil, nil, fmt.Errorf("irc: %w", err)
+	}
+	conn.SetDeadline(time.Time{})
+	return conn, reader, nil
+}
+
+// register performs the NICK/USER handshake, answering PINGs and picking
+// another nick while the configured one is taken, then joins the channel
+func (s *ircSink) register(conn net.Conn, reader *bufio.Reader) error {
+	nick := s.cfg.Nick
+	if s.cfg.Password != "" {
+		if err := s.writeLine(conn, "PASS %s", s.cfg.Password); err != nil {
+			return err
+		}
+	}
+	if err := s.writeLine(conn, "NICK %s", nick); err != nil {
+		return err
+	}
+	if err := s.writeLine(conn, "USER %s 0 * :dgconnect-www announcer", s.cfg.Nick); err != nil {
+		return err
+	}
+
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return fmt.Errorf("registration failed: %w", err)
+		}
+		command, params := parseIRCLine(line)
+		switch command {
+		case "PING":
+			if err := s.writeLine(conn, "PONG :%s", params); err != nil {
+				return err
+			}
+		case "433": // nickname in use
+			nick += "_"
+			if err := s.writeLine(conn, "NICK %s", nick); err != nil {
+				return err
+			}
+		case "001": // welcome
+			return s.writeLine(conn, "JOIN %s", s.cfg.Channel)
+		case "ERROR":
+			return fmt.Errorf("registration refused: %s", params)
+		}
+	}
+}
+
+// readLoop answers server PINGs and forgets the connection once it closes
+func (s *ircSink) readLoop(conn net.Conn, reader *bufio.Reader) {
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			break
+		}
+		if command, params := parseIRCLine(line); command == "PING" {
+			s.mu.Lock()
+			s.writeLine(conn, "PONG :%s", params)
+			s.mu.Unlock()
+		}
+	}
+
+	s.mu.Lock()
+	if s.conn == conn {
+		s.conn.Close()
+		s.conn = nil
+	}
+	s.mu.Unlock()
+}
+
+// writeLine sends a single IRC protocol line
+func (s *ircSink) writeLine(conn net.Conn, format string, args ...interface{}) error {
+	_, err := fmt.Fprintf(conn, format+"\r\n", args...)
+	return err
+}
+
+// parseIRCLine splits a server line into its command and the text of its
+// trailing parameter
+func parseIRCLine(line string) (command, trailing string) {
+	line = strings.TrimRight(line, "\r\n")
+	if strings.HasPrefix(line, ":") {
+		if i := strings.IndexByte(line, ' '); i >= 0 {
+			line = line[i+1:]
+		} else {
+			return "", ""
+		}
+	}
+	command, rest, _ := strings.Cut(line, " ")
+	if i := strings.Index(rest, ":"); i >= 0 {
+		trailing = rest[i+1:]
+	} else {
+		trailing = rest
+	}
+	return command, trailing
+}
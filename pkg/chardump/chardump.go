@@ -0,0 +1,302 @@
+// Package chardump archives character dumps, such as NetHack dumplogs and
+// Crawl morgue files, fetched from the game server when a game ends.
+package chardump
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+)
+
+// Archive defaults
+const (
+	defaultFetchDelay = 2 * time.Second
+	indexFile         = "index.json"
+	maxDumpSize       = 4 << 20
+)
+
+// unsafeNameChars are rejected in player names, which become directory
+// names on the server and in the archive, and replaced in dump file names
+var unsafeNameChars = regexp.MustCompile(`[^A-Za-z0-9_-]`)
+
+// Config configures dump archival
+type Config struct {
+	Dir     string            `yaml:"dir"`                                  // local archive directory
+	Sources map[string]Source `yaml:"sources"`                              // where each game writes its dumps, keyed by game
+	Delay   string            `yaml:"delay,omitempty" yamlcheck:"duration"` // wait for the server to write the dump; default 2s
+}
+
+// Source locates the dumps of one game on the server
+type Source struct {
+	Dir     string `yaml:"dir"`               // template over .Player, e.g. /dgldir/userdata/{{.Player}}/dumplog
+	Pattern string `yaml:"pattern,omitempty"` // file name glob; default "*"
+}
+
+// Game describes a finished game whose dump should be archived
+type Game struct {
+	Player  string
+	Game    string
+	Outcome string
+	Score   int64
+	Ended   time.Time
+}
+
+// Dump is an archived character dump
+type Dump struct {
+	ID      string    `json:"id"`
+	Player  string    `json:"player"`
+	Game    string    `json:"game"`
+	Outcome string    `json:"outcome,omitempty"`
+	Score   int64     `json:"score"`
+	Ended   time.Time `json:"ended"`
+	Remote  string    `json:"remote"` // path on the game server
+	Size    int       `json:"size"`
+}
+
+// Fetcher retrieves dump files from the game server
+type Fetcher interface {
+	// Latest returns the path and content of the most recently modified
+	// file in dir whose name matches pattern
+	Latest(ctx context.Context, dir, pattern string) (string, []byte, error)
+}
+
+// source is a Source with its directory template parsed
+type source struct {
+	dir     *template.Template
+	pattern string
+}
+
+// Archive stores dumps in a directory with a JSON index
+type Archive struct {
+	dir     string
+	sources map[string]source
+	delay   time.Duration
+
+	mu      sync.Mutex
+	fetcher Fetcher
+	dumps   []Dump // oldest first
+}
+
+// NewArchive validates cfg, creates the archive directory and loads its
+// index
+func NewArchive(cfg Config) (*Archive, error) {
+	if cfg.Dir == "" {
+		return nil, fmt.Errorf("chardump: dir is required")
+	}
+	a := &Archive{
+		dir:     cfg.Dir,
+		sources: make(map[string]source),
+		delay:   defaultFetchDelay,
+	}
+	if cfg.Delay != "" {
+		d, err := time.ParseDuration(cfg.Delay)
+		if err != nil || d < 0 {
+			return nil, fmt.Errorf("chardump: invalid delay %q", cfg.Delay)
+		}
+		a.delay = d
+	}
+	for game, src := range cfg.Sources {
+		tmpl, err := template.New(game).Parse(src.Dir)
+		if err != nil {
+			return nil, fmt.Errorf("chardump: invalid dir for %s: %w", game, err)
+		}
+		pattern := src.Pattern
+		if pattern == "" {
+			pattern = "*"
+		}
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("chardump: invalid pattern for %s: %w", game, err)
+		}
+		a.sources[game] = source{dir: tmpl, pattern: pattern}
+	}
+
+	if err := os.MkdirAll(a.dir, 0o755); err != nil {
+		return nil, fmt.Errorf("chardump: failed to create archive: %w", err)
+	}
+	data, err := os.ReadFile(filepath.Join(a.dir, indexFile))
+	switch {
+	case err == nil:
+		if err := json.Unmarshal(data, &a.dumps); err != nil {
+			return nil, fmt.Errorf("chardump: corrupt index: %w", err)
+		}
+	case !os.IsNotExist(err):
+		return nil, fmt.Errorf("chardump: failed to read index: %w", err)
+	}
+
+	return a, nil
+}
+
+// SetFetcher sets how dumps are retrieved, typically once the SSH
+// credentials for the game server are known
+func (a *Archive) SetFetcher(f Fetcher) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.fetcher = f
+}
+
+// Collect fetches the dump of a finished game and adds it to the archive.
+// The player must be a plain name, as it is read from the screen and
+// becomes part of a path on the server.
+func (a *Archive) Collect(ctx context.Context, game Game) (*Dump, error) {
+	if game.Player == "" || unsafeNameChars.MatchString(game.Player) {
+		return nil, fmt.Errorf("chardump: invalid player name %q", game.Player)
+	}
+	src, ok := a.sources[game.Game]
+	if !ok {
+		return nil, fmt.Errorf("chardump: no source configured for %q", game.Game)
+	}
+	a.mu.Lock()
+	fetcher := a.fetcher
+	a.mu.Unlock()
+	if fetcher == nil {
+		return nil, fmt.Errorf("chardump: not connected to the game server")
+	}
+
+	var dir bytes.Buffer
+	if err := src.dir.Execute(&dir, game); err != nil {
+		return nil, fmt.Errorf("chardump: failed to build dump dir: %w", err)
+	}
+
+	// Give the server time to finish writing the dump
+	select {
+	case <-time.After(a.delay):
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	remote, data, err := fetcher.Latest(ctx, dir.String(), src.pattern)
+	if err != nil {
+		return nil, fmt.Errorf("chardump: fetch failed: %w", err)
+	}
+
+	if game.Ended.IsZero() {
+		game.Ended = time.Now()
+	}
+	name := unsafeNameChars.ReplaceAllString(strings.TrimSuffix(path.Base(remote), path.Ext(remote)), "_")
+	dump := Dump{
+		ID:      fmt.Sprintf("%s/%s-%s%s", game.Player, game.Ended.UTC().Format("20060102-150405"), name, path.Ext(remote)),
+		Player:  game.Player,
+		Game:    game.Game,
+		Outcome: game.Outcome,
+		Score:   game.Score,
+		Ended:   game.Ended,
+		Remote:  remote,
+		Size:    len(data),
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	file := filepath.Join(a.dir, filepath.FromSlash(dump.ID))
+	if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+		return nil, fmt.Errorf("chardump: failed to store dump: %w", err)
+	}
+	if err := os.WriteFile(file, data, 0o644); err != nil {
+		return nil, fmt.Errorf("chardump: failed to store dump: %w", err)
+	}
+	a.dumps = append(a.dumps, dump)
+	if err := a.saveIndexLocked(); err != nil {
+		return nil, err
+	}
+
+	slog.Info("chardump: archived dump", "player", dump.Player, "game", dump.Game, "id", dump.ID)
+	return &dump, nil
+}
+
+// saveIndexLocked atomically rewrites the index
+func (a *Archive) saveIndexLocked() error {
+	data, err := json.MarshalIndent(a.dumps, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := filepath.Join(a.dir, indexFile+".tmp")
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("chardump: failed to write index: %w", err)
+	}
+	if err := os.Rename(tmp, filepath.Join(a.dir, indexFile)); err != nil {
+		return fmt.Errorf("chardump: failed to write index: %w", err)
+	}
+	return nil
+}
+
+// Dumps returns the archived dumps of player, or of everyone when player is
+// empty, newest first
+func (a *Archive) Dumps(player string) []Dump {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	var dumps []Dump
+	for _, d := range a.dumps {
+		if player == "" || strings.EqualFold(d.Player, player) {
+			dumps = append(dumps, d)
+		}
+	}
+	sort.SliceStable(dumps, func(i, j int) bool {
+		return dumps[i].Ended.After(dumps[j].Ended)
+	})
+	return dumps
+}
+
+// lookup returns the indexed dump with the given ID
+func (a *Archive) lookup(id string) (Dump, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for _, d := range a.dumps {
+		if d.ID == id {
+			return d, true
+		}
+	}
+	return Dump{}, false
+}
+
+// HandleList serves GET /dumps[?player=name], listing archived dumps
+func (a *Archive) HandleList(rw http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		rw.Header().Set("Allow", http.MethodGet)
+		http.Error(rw, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	dumps := a.Dumps(r.URL.Query().Get("player"))
+	if dumps == nil {
+		dumps = []Dump{}
+	}
+	rw.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(rw).Encode(map[string]interface{}{"dumps": dumps})
+}
+
+// HandleFile serves GET /dumps/file?id=..., the content of an archived dump
+func (a *Archive) HandleFile(rw http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		rw.Header().Set("Allow", http.MethodGet)
+		http.Error(rw, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Only indexed IDs are served, so the ID cannot escape the archive
+	dump, ok := a.lookup(r.URL.Query().Get("id"))
+	if !ok {
+		http.NotFound(rw, r)
+		return
+	}
+	f, err := os.Open(filepath.Join(a.dir, filepath.FromSlash(dump.ID)))
+	if err != nil {
+		http.NotFound(rw, r)
+		return
+	}
+	defer f.Close()
+
+	rw.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	http.ServeContent(rw, r, "", dump.Ended, f)
+}
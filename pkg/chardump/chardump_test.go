@@ -0,0 +1,200 @@
+package chardump
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pkg/sftp"
+)
+
+// fakeFetcher serves a fixed dump and records the requested directory
+type fakeFetcher struct {
+	dir, pattern string
+	data         string
+}
+
+func (f *fakeFetcher) Latest(ctx context.Context, dir, pattern string) (string, []byte, error) {
+	f.dir, f.pattern = dir, pattern
+	return dir + "/alice.2026-10-01.txt", []byte(f.data), nil
+}
+
+func newTestArchive(t *testing.T, dir string) *Archive {
+	t.Helper()
+	archive, err := NewArchive(Config{
+		Dir:   dir,
+		Delay: "0s",
+		Sources: map[string]Source{
+			"nethack": {Dir: "/dgldir/userdata/{{.Player}}/dumplog", Pattern: "*.txt"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewArchive failed: %v", err)
+	}
+	return archive
+}
+
+func TestNewArchive_Validation(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  Config
+	}{
+		{"missing dir", Config{}},
+		{"bad delay", Config{Dir: t.TempDir(), Delay: "soon"}},
+		{"bad template", Config{Dir: t.TempDir(), Sources: map[string]Source{"nethack": {Dir: "{{.Player"}}}},
+		{"bad pattern", Config{Dir: t.TempDir(), Sources: map[string]Source{"nethack": {Dir: "/d", Pattern: "["}}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewArchive(tt.cfg); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
+
+func TestArchive_Collect_StoresAndIndexesDump(t *testing.T) {
+	dir := t.TempDir()
+	archive := newTestArchive(t, dir)
+
+	if _, err := archive.Collect(context.Background(), Game{Player: "alice", Game: "nethack"}); err == nil {
+		t.Error("expected error before a fetcher is set")
+	}
+
+	fetcher := &fakeFetcher{data: "alice the Valkyrie\n"}
+	archive.SetFetcher(fetcher)
+	ended := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	dump, err := archive.Collect(context.Background(), Game{Player: "alice", Game: "nethack", Score: 1234, Ended: ended})
+	if err != nil {
+		t.Fatalf("Collect failed: %v", err)
+	}
+	if fetcher.dir != "/dgldir/userdata/alice/dumplog" || fetcher.pattern != "*.txt" {
+		t.Errorf("fetched %s/%s", fetcher.dir, fetcher.pattern)
+	}
+	if dump.ID != "alice/20261001-120000-alice_2026-10-01.txt" {
+		t.Errorf("ID = %q", dump.ID)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(dump.ID)))
+	if err != nil || string(data) != fetcher.data {
+		t.Errorf("stored dump = %q, %v", data, err)
+	}
+
+	if _, err := archive.Collect(context.Background(), Game{Player: "bob", Game: "crawl"}); err == nil {
+		t.Error("expected error for a game without a source")
+	}
+
+	// The index survives a restart
+	reopened := newTestArchive(t, dir)
+	if dumps := reopened.Dumps("ALICE"); len(dumps) != 1 || dumps[0].Score != 1234 {
+		t.Errorf("reopened dumps = %+v", dumps)
+	}
+	if dumps := reopened.Dumps("bob"); len(dumps) != 0 {
+		t.Errorf("bob has dumps: %+v", dumps)
+	}
+}
+
+func TestArchive_Collect_RejectsUnsafePlayerName(t *testing.T) {
+	archive := newTestArchive(t, t.TempDir())
+	fetcher := &fakeFetcher{data: "x"}
+	archive.SetFetcher(fetcher)
+
+	for _, player := range []string{"../..", "../../etc", "a/b", "alice bob", ""} {
+		if _, err := archive.Collect(context.Background(), Game{Player: player, Game: "nethack"}); err == nil {
+			t.Errorf("Collect accepted player %q", player)
+		}
+	}
+	if fetcher.dir != "" {
+		t.Errorf("fetched %s for an unsafe player name", fetcher.dir)
+	}
+}
+
+func TestArchive_Handlers(t *testing.T) {
+	archive := newTestArchive(t, t.TempDir())
+	archive.SetFetcher(&fakeFetcher{data: "dump text"})
+	for i, player := range []string{"alice", "bob", "alice"} {
+		ended := time.Date(2026, 10, 1, i, 0, 0, 0, time.UTC)
+		if _, err := archive.Collect(context.Background(), Game{Player: player, Game: "nethack", Ended: ended}); err != nil {
+			t.Fatalf("Collect failed: %v", err)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	archive.HandleList(rec, httptest.NewRequest(http.MethodGet, "/dumps?player=alice", nil))
+	var resp struct {
+		Dumps []Dump `json:"dumps"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if len(resp.Dumps) != 2 || !resp.Dumps[0].Ended.After(resp.Dumps[1].Ended) {
+		t.Fatalf("dumps = %+v, want alice's two dumps newest first", resp.Dumps)
+	}
+
+	rec = httptest.NewRecorder()
+	archive.HandleFile(rec, httptest.NewRequest(http.MethodGet, "/dumps/file?id="+resp.Dumps[0].ID, nil))
+	if rec.Code != http.StatusOK || rec.Body.String() != "dump text" {
+		t.Errorf("file = %d %q", rec.Code, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	archive.HandleFile(rec, httptest.NewRequest(http.MethodGet, "/dumps/file?id=../index.json", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("unindexed ID status = %d, want 404", rec.Code)
+	}
+}
+
+func TestLatestFile_PicksNewestMatch(t *testing.T) {
+	// An in-memory SFTP server connected through pipes
+	clientRead, serverWrite := io.Pipe()
+	serverRead, clientWrite := io.Pipe()
+	server := sftp.NewRequestServer(struct {
+		io.Reader
+		io.WriteCloser
+	}{serverRead, serverWrite}, sftp.InMemHandler())
+	go server.Serve()
+
+	client, err := sftp.NewClientPipe(clientRead, clientWrite)
+	if err != nil {
+		t.Fatalf("NewClientPipe failed: %v", err)
+	}
+	defer func() {
+		serverWrite.Close()
+		client.Close()
+		server.Close()
+	}()
+
+	if err := client.Mkdir("/dumplog"); err != nil {
+		t.Fatalf("Mkdir failed: %v", err)
+	}
+	for i, name := range []string{"old.txt", "new.txt", "newer.log"} {
+		f, err := client.Create("/dumplog/" + name)
+		if err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+		fmt.Fprint(f, name)
+		f.Close()
+		mtime := time.Date(2026, 10, 1, i, 0, 0, 0, time.UTC)
+		if err := client.Chtimes("/dumplog/"+name, mtime, mtime); err != nil {
+			t.Fatalf("Chtimes failed: %v", err)
+		}
+	}
+
+	remote, data, err := latestFile(client, "/dumplog", "*.txt")
+	if err != nil {
+		t.Fatalf("latestFile failed: %v", err)
+	}
+	if remote != "/dumplog/new.txt" || string(data) != "new.txt" {
+		t.Errorf("latestFile = %s %q, want /dumplog/new.txt", remote, data)
+	}
+
+	if _, _, err := latestFile(client, "/dumplog", "*.morgue"); err == nil {
+		t.Error("expected error when nothing matches")
+	}
+}
@@ -0,0 +1,116 @@
+package chardump
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"path"
+
+	"github.com/opd-ai/go-gamelaunch-www/pkg/sshmux"
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+)
+
+// SFTPFetcher fetches dumps over SFTP, opening a connection for each fetch,
+// or a channel on the shared transport of Pool when set
+type SFTPFetcher struct {
+	Addr   string // host:port of the game server
+	Config *ssh.ClientConfig
+	Pool   *sshmux.Pool
+}
+
+// Latest implements Fetcher
+func (f *SFTPFetcher) Latest(ctx context.Context, dir, pattern string) (string, []byte, error) {
+	if f.Pool != nil {
+		return f.latestShared(ctx, dir, pattern)
+	}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", f.Addr)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to connect: %w", err)
+	}
+	sshConn, chans, reqs, err := ssh.NewClientConn(conn, f.Addr, f.Config)
+	if err != nil {
+		conn.Close()
+		return "", nil, fmt.Errorf("ssh handshake failed: %w", err)
+	}
+	sshClient := ssh.NewClient(sshConn, chans, reqs)
+	defer sshClient.Close()
+
+	// Abort transfers when ctx ends
+	stop := context.AfterFunc(ctx, func() { sshClient.Close() })
+	defer stop()
+
+	client, err := sftp.NewClient(sshClient)
+	if err != nil {
+		return "", nil, fmt.Errorf("sftp unavailable: %w", err)
+	}
+	defer client.Close()
+
+	return latestFile(client, dir, pattern)
+}
+
+// latestShared fetches over a channel of the pooled transport
+func (f *SFTPFetcher) latestShared(ctx context.Context, dir, pattern string) (string, []byte, error) {
+	lease, err := f.Pool.Acquire(ctx, f.Addr, f.Config)
+	if err != nil {
+		return "", nil, err
+	}
+	defer lease.Release()
+
+	client, err := sftp.NewClient(lease.Client())
+	if err != nil {
+		return "", nil, fmt.Errorf("sftp unavailable: %w", err)
+	}
+	defer client.Close()
+
+	// Abort transfers when ctx ends, leaving the shared transport open
+	stop := context.AfterFunc(ctx, func() { client.Close() })
+	defer stop()
+
+	return latestFile(client, dir, pattern)
+}
+
+// latestFile reads the most recently modified regular file in dir matching
+// pattern
+func latestFile(client *sftp.Client, dir, pattern string) (string, []byte, error) {
+	entries, err := client.ReadDir(dir)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to list %s: %w", dir, err)
+	}
+
+	var newest string
+	var newestTime int64
+	for _, entry := range entries {
+		if !entry.Mode().IsRegular() {
+			continue
+		}
+		if ok, _ := path.Match(pattern, entry.Name()); !ok {
+			continue
+		}
+		if t := entry.ModTime().UnixNano(); newest == "" || t > newestTime {
+			newest, newestTime = entry.Name(), t
+		}
+	}
+	if newest == "" {
+		return "", nil, fmt.Errorf("no dump matching %q in %s", pattern, dir)
+	}
+
+	remote := path.Join(dir, newest)
+	file, err := client.Open(remote)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to open %s: %w", remote, err)
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, maxDumpSize+1))
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to read %s: %w", remote, err)
+	}
+	if len(data) > maxDumpSize {
+		return "", nil, fmt.Errorf("%s exceeds %d bytes", remote, maxDumpSize)
+	}
+	return remote, data, nil
+}
@@ -0,0 +1,65 @@
+// Package demo plays a bundled recording of a NetHack game in a loop, so the
+// web interface can be evaluated and the front end developed without any
+// game server.
+package demo
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"fmt"
+	"time"
+
+	"github.com/opd-ai/go-gamelaunch-www/pkg/ttyrec"
+)
+
+// nethack is the bundled recording: a short NetHack game on an 80x24
+// terminal
+//
+//go:embed nethack.ttyrec
+var nethack []byte
+
+const (
+	// DefaultPause is how long the final screen stays up before the
+	// recording starts over
+	DefaultPause = 3 * time.Second
+
+	// maxDelay caps the pauses within the recording
+	maxDelay = 2 * time.Second
+
+	// clearScreen resets the terminal before each loop
+	clearScreen = "\x1b[0m\x1b[H\x1b[2J"
+)
+
+// Frames returns the frames of the bundled recording
+func Frames() ([]ttyrec.Frame, error) {
+	frames, err := ttyrec.ReadAll(bytes.NewReader(nethack))
+	if err != nil {
+		return nil, fmt.Errorf("demo: failed to read bundled recording: %w", err)
+	}
+	return frames, nil
+}
+
+// Loop plays frames to render at speed over and over, clearing the screen
+// before each pass and waiting pause after it, until ctx is done or render
+// fails. It returns the error of render or ctx.
+func Loop(ctx context.Context, frames []ttyrec.Frame, speed float64, pause time.Duration, render func([]byte) error) error {
+	if len(frames) == 0 {
+		return fmt.Errorf("demo: recording has no frames")
+	}
+
+	for {
+		if err := render([]byte(clearScreen)); err != nil {
+			return err
+		}
+		if err := ttyrec.Play(ctx, frames, speed, maxDelay, render); err != nil {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(pause):
+		}
+	}
+}
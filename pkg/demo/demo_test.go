@@ -0,0 +1,75 @@
+package demo
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/opd-ai/go-gamelaunch-www/pkg/ttyrec"
+)
+
+func TestFrames(t *testing.T) {
+	frames, err := Frames()
+	if err != nil {
+		t.Fatalf("Frames failed: %v", err)
+	}
+	if len(frames) == 0 {
+		t.Fatal("bundled recording has no frames")
+	}
+	var output bytes.Buffer
+	for _, frame := range frames {
+		output.Write(frame.Data)
+	}
+	if !bytes.Contains(output.Bytes(), []byte("Dlvl:1")) {
+		t.Error("bundled recording never shows the status line")
+	}
+}
+
+func TestLoop_RepeatsUntilCanceled(t *testing.T) {
+	start := time.Unix(1700000000, 0)
+	frames := []ttyrec.Frame{
+		{Time: start, Data: []byte("a")},
+		{Time: start.Add(10 * time.Second), Data: []byte("b")},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var rendered []string
+	err := Loop(ctx, frames, 1000, time.Millisecond, func(data []byte) error {
+		rendered = append(rendered, string(data))
+		if len(rendered) == 6 {
+			cancel()
+		}
+		return nil
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Loop returned %v, want context.Canceled", err)
+	}
+	want := []string{clearScreen, "a", "b", clearScreen, "a", "b"}
+	if len(rendered) != len(want) {
+		t.Fatalf("rendered %q, want %q", rendered, want)
+	}
+	for i := range want {
+		if rendered[i] != want[i] {
+			t.Errorf("render %d = %q, want %q", i, rendered[i], want[i])
+		}
+	}
+}
+
+func TestLoop_StopsOnRenderError(t *testing.T) {
+	frames, err := Frames()
+	if err != nil {
+		t.Fatalf("Frames failed: %v", err)
+	}
+	failure := errors.New("view closed")
+	err = Loop(context.Background(), frames, 1, 0, func([]byte) error { return failure })
+	if err != failure {
+		t.Errorf("Loop returned %v, want %v", err, failure)
+	}
+	if err := Loop(context.Background(), nil, 1, 0, func([]byte) error { return nil }); err == nil {
+		t.Error("Loop accepted an empty recording")
+	}
+}
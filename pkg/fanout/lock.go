@@ -0,0 +1,126 @@
+package fanout
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// ErrNotOwner is returned by Save when another instance owns the session
+var ErrNotOwner = errors.New("fanout: session is owned by another instance")
+
+// renewScript extends the lease in KEYS[1] by ARGV[2] milliseconds if it is
+// still held by ARGV[1]
+var renewScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0`)
+
+// releaseScript deletes the lease in KEYS[1] if it is still held by ARGV[1]
+var releaseScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0`)
+
+// Acquire blocks until this instance owns the session, so that only one of
+// the instances sharing it connects to the game server. Ownership is a lease
+// renewed in the background; when the owner fails to renew it, another
+// instance takes over once LockTTL has passed.
+//
+// The returned context is cancelled when ownership is lost or released, and
+// the SSH connection should be closed with it. release gives up ownership,
+// letting a waiting instance take over at once; it must be called once the
+// connection is closed.
+func (s *RedisStore) Acquire(ctx context.Context) (owned context.Context, release func(), err error) {
+	s.locking.Store(true)
+
+	ticker := time.NewTicker(s.lockTTL / 3)
+	defer ticker.Stop()
+	for {
+		acquired, err := s.tryAcquire()
+		if err != nil {
+			slog.Warn("fanout.RedisStore: failed to acquire session", "error", err)
+		}
+		if acquired {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return nil, nil, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+	s.owned.Store(true)
+
+	owned, lose := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		s.renew(owned, lose)
+	}()
+
+	release = func() {
+		lose()
+		<-done
+
+		ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+		defer cancel()
+		if err := releaseScript.Run(ctx, s.client, []string{s.ownerKey()}, s.origin).Err(); err != nil {
+			slog.Warn("fanout.RedisStore: failed to release session", "error", err)
+		}
+	}
+	return owned, release, nil
+}
+
+// tryAcquire takes the lease if no instance holds it
+func (s *RedisStore) tryAcquire() (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+
+	return s.client.SetNX(ctx, s.ownerKey(), s.origin, s.lockTTL).Result()
+}
+
+// renew extends the lease three times per LockTTL until ctx is cancelled,
+// calling lose when another instance took over or the lease could expire
+// before the next attempt
+func (s *RedisStore) renew(ctx context.Context, lose context.CancelFunc) {
+	defer lose()
+	defer s.owned.Store(false)
+
+	interval := s.lockTTL / 3
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	renewed := time.Now()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		renewCtx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+		held, err := renewScript.Run(renewCtx, s.client, []string{s.ownerKey()}, s.origin, s.lockTTL.Milliseconds()).Int()
+		cancel()
+		switch {
+		case err == nil && held == 1:
+			renewed = time.Now()
+		case err == nil:
+			slog.Warn("fanout.RedisStore: session taken over by another instance")
+			return
+		case time.Since(renewed)+interval >= s.lockTTL:
+			slog.Warn("fanout.RedisStore: giving up session after failing to renew it", "error", err)
+			return
+		}
+	}
+}
+
+// Owned reports whether this instance currently owns the session
+func (s *RedisStore) Owned() bool {
+	return s.owned.Load()
+}
@@ -0,0 +1,116 @@
+package fanout
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/opd-ai/go-gamelaunch-www/pkg/webui"
+)
+
+// newLockingStore creates a store connected to mr with the shortest lease
+func newLockingStore(t *testing.T, mr *miniredis.Miniredis) *RedisStore {
+	t.Helper()
+
+	store, err := NewRedisStore(context.Background(), Config{Addr: mr.Addr(), LockTTL: time.Second})
+	if err != nil {
+		t.Fatalf("NewRedisStore failed: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+func TestNewRedisStore_RejectsShortLockTTL(t *testing.T) {
+	mr := miniredis.RunT(t)
+	if _, err := NewRedisStore(context.Background(), Config{Addr: mr.Addr(), LockTTL: time.Millisecond}); err == nil {
+		t.Error("Expected error with a 1ms lock_ttl")
+	}
+}
+
+func TestRedisStore_AcquireFencesSavesAndReleases(t *testing.T) {
+	mr := miniredis.RunT(t)
+	first := newLockingStore(t, mr)
+	second := newLockingStore(t, mr)
+	state := &webui.GameState{Version: 1, Width: 1, Height: 1}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	owned, release, err := first.Acquire(ctx)
+	if err != nil {
+		t.Fatalf("Acquire failed: %v", err)
+	}
+	if !first.Owned() {
+		t.Error("Owned() = false after Acquire")
+	}
+
+	waitCtx, waitCancel := context.WithTimeout(ctx, 100*time.Millisecond)
+	defer waitCancel()
+	if _, _, err := second.Acquire(waitCtx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("second Acquire = %v, want to wait for the owner", err)
+	}
+	if err := second.Save(state, nil); !errors.Is(err, ErrNotOwner) {
+		t.Errorf("Save by a waiting instance = %v, want ErrNotOwner", err)
+	}
+	if second.Current() != nil {
+		t.Error("Refused state became current")
+	}
+	if err := first.Save(state, nil); err != nil {
+		t.Errorf("Save by the owner failed: %v", err)
+	}
+
+	// Releasing hands the session over without waiting for the lease
+	release()
+	if owned.Err() == nil || first.Owned() {
+		t.Error("Ownership still held after release")
+	}
+	if _, release, err := second.Acquire(ctx); err != nil {
+		t.Fatalf("Acquire after release failed: %v", err)
+	} else {
+		defer release()
+	}
+	if err := first.Save(&webui.GameState{Version: 2, Width: 1, Height: 1}, nil); !errors.Is(err, ErrNotOwner) {
+		t.Errorf("Save by the previous owner = %v, want ErrNotOwner", err)
+	}
+	if got := first.Version(); got != 1 {
+		t.Errorf("Version() = %d after a refused save, want the saved 1", got)
+	}
+}
+
+func TestRedisStore_TakeoverAfterOwnerFailure(t *testing.T) {
+	mr := miniredis.RunT(t)
+	failing := newLockingStore(t, mr)
+	standby := newLockingStore(t, mr)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	owned, _, err := failing.Acquire(ctx)
+	if err != nil {
+		t.Fatalf("Acquire failed: %v", err)
+	}
+
+	// The owner loses its connection and stops renewing; its lease expires
+	failing.Close()
+	mr.FastForward(time.Second)
+
+	if _, release, err := standby.Acquire(ctx); err != nil {
+		t.Fatalf("Standby did not take over: %v", err)
+	} else {
+		defer release()
+	}
+	if !standby.Owned() {
+		t.Error("Standby Owned() = false after taking over")
+	}
+
+	select {
+	case <-owned.Done():
+	case <-ctx.Done():
+		t.Fatal("Failed owner did not give up the session")
+	}
+	if failing.Owned() {
+		t.Error("Failed owner Owned() = true")
+	}
+}
@@ -0,0 +1,335 @@
+// Package fanout provides a Redis-backed state store that lets several
+// dgconnect-www instances serve the same game session. The instance that
+// owns the SSH connection saves states to Redis; replicas follow the update
+// channel and forward client input back to the owner.
+package fanout
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/opd-ai/go-gamelaunch-www/pkg/webui"
+	"github.com/redis/go-redis/v9"
+)
+
+// Defaults for Config
+const (
+	defaultPrefix  = "dgconnect"
+	defaultHistory = 64
+	defaultLockTTL = 15 * time.Second
+	redisTimeout   = 2 * time.Second
+)
+
+// Config configures the Redis connection and key namespace
+type Config struct {
+	Addr     string `yaml:"addr"`
+	Password string `yaml:"password,omitempty"`
+	DB       int    `yaml:"db,omitempty"`
+	Prefix   string `yaml:"prefix,omitempty"`  // key namespace, one per session; default "dgconnect"
+	History  int    `yaml:"history,omitempty"` // diffs kept for catch-up; default 64
+
+	// LockTTL is how long the owner of the session keeps it without
+	// renewing, i.e. how soon another instance takes over after the owner
+	// fails; default 15s, at least 1s
+	LockTTL time.Duration `yaml:"lock_ttl,omitempty"`
+}
+
+// update is published on the updates channel after every save
+type update struct {
+	Origin  string `json:"origin"`
+	Version uint64 `json:"version"`
+}
+
+// RedisStore implements webui.StateStore on top of Redis. Reads are served
+// from a local cache kept current by Save and Follow.
+type RedisStore struct {
+	client  *redis.Client
+	prefix  string
+	history int
+	origin  string
+	lockTTL time.Duration
+
+	locking atomic.Bool // Acquire was called, so saves require ownership
+	owned   atomic.Bool
+
+	mu      sync.RWMutex
+	current *webui.GameState
+}
+
+// NewRedisStore connects to Redis and loads the last saved state, if any
+func NewRedisStore(ctx context.Context, cfg Config) (*RedisStore, error) {
+	if cfg.Addr == "" {
+		return nil, fmt.Errorf("fanout: redis addr is required")
+	}
+	if cfg.LockTTL != 0 && cfg.LockTTL < time.Second {
+		return nil, fmt.Errorf("fanout: lock_ttl must be at least 1s, got %s", cfg.LockTTL)
+	}
+
+	s := &RedisStore{
+		client: redis.NewClient(&redis.Options{
+			Addr:     cfg.Addr,
+			Password: cfg.Password,
+			DB:       cfg.DB,
+		}),
+		prefix:  cfg.Prefix,
+		history: cfg.History,
+		origin:  fmt.Sprintf("%d", time.Now().UnixNano()),
+		lockTTL: cfg.LockTTL,
+	}
+	if s.prefix == "" {
+		s.prefix = defaultPrefix
+	}
+	if s.history <= 0 {
+		s.history = defaultHistory
+	}
+	if s.lockTTL == 0 {
+		s.lockTTL = defaultLockTTL
+	}
+
+	if err := s.client.Ping(ctx).Err(); err != nil {
+		s.client.Close()
+		return nil, fmt.Errorf("fanout: failed to connect to redis: %w", err)
+	}
+	if _, err := s.refresh(ctx); err != nil {
+		s.client.Close()
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// Close releases the Redis connection
+func (s *RedisStore) Close() error {
+	return s.client.Close()
+}
+
+func (s *RedisStore) stateKey() string       { return s.prefix + ":state" }
+func (s *RedisStore) diffsKey() string       { return s.prefix + ":diffs" }
+func (s *RedisStore) updatesChannel() string { return s.prefix + ":updates" }
+func (s *RedisStore) inputChannel() string   { return s.prefix + ":input" }
+func (s *RedisStore) ownerKey() string       { return s.prefix + ":owner" }
+
+// Save implements webui.StateStore. Once Acquire has been called, it
+// returns ErrNotOwner without writing unless this instance owns the session,
+// so that an owner that lost the session cannot overwrite its successor.
+// The local cache only takes state once it is written.
+func (s *RedisStore) Save(state *webui.GameState, diff *webui.StateDiff) error {
+	return s.SaveContext(context.Background(), state, diff)
+}
+
+// SaveContext implements webui.ContextSaver: Save giving up when ctx is done
+func (s *RedisStore) SaveContext(ctx context.Context, state *webui.GameState, diff *webui.StateDiff) error {
+	stateJSON, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("fanout: failed to encode state: %w", err)
+	}
+	updateJSON, err := json.Marshal(update{Origin: s.origin, Version: state.Version})
+	if err != nil {
+		return fmt.Errorf("fanout: failed to encode update: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, redisTimeout)
+	defer cancel()
+
+	write := func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, s.stateKey(), stateJSON, 0)
+		if diff == nil {
+			pipe.Del(ctx, s.diffsKey())
+		} else {
+			diffJSON, err := json.Marshal(diff)
+			if err != nil {
+				return err
+			}
+			pipe.RPush(ctx, s.diffsKey(), diffJSON)
+			pipe.LTrim(ctx, s.diffsKey(), int64(-s.history), -1)
+		}
+		pipe.Publish(ctx, s.updatesChannel(), updateJSON)
+		return nil
+	}
+
+	if s.locking.Load() {
+		err = s.client.Watch(ctx, func(tx *redis.Tx) error {
+			owner, err := tx.Get(ctx, s.ownerKey()).Result()
+			if errors.Is(err, redis.Nil) || (err == nil && owner != s.origin) {
+				return ErrNotOwner
+			}
+			if err != nil {
+				return err
+			}
+			_, err = tx.TxPipelined(ctx, write)
+			return err
+		}, s.ownerKey())
+		if errors.Is(err, ErrNotOwner) {
+			return err
+		}
+	} else {
+		_, err = s.client.TxPipelined(ctx, write)
+	}
+	if err != nil {
+		return fmt.Errorf("fanout: failed to save state: %w", err)
+	}
+
+	s.mu.Lock()
+	s.current = state
+	s.mu.Unlock()
+	return nil
+}
+
+// Current implements webui.StateStore
+func (s *RedisStore) Current() *webui.GameState {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.current
+}
+
+// Version implements webui.StateStore
+func (s *RedisStore) Version() uint64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.current == nil {
+		return 0
+	}
+	return s.current.Version
+}
+
+// DiffsSince implements webui.StateStore
+func (s *RedisStore) DiffsSince(version uint64) ([]*webui.StateDiff, bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+
+	raw, err := s.client.LRange(ctx, s.diffsKey(), 0, -1).Result()
+	if err != nil {
+		slog.Warn("fanout.RedisStore: failed to read diff history", "error", err)
+		return nil, false
+	}
+
+	for i, item := range raw {
+		var diff webui.StateDiff
+		if err := json.Unmarshal([]byte(item), &diff); err != nil || diff.Version != version+1 {
+			continue
+		}
+
+		diffs := make([]*webui.StateDiff, 0, len(raw)-i)
+		for _, item := range raw[i:] {
+			var d webui.StateDiff
+			if err := json.Unmarshal([]byte(item), &d); err != nil {
+				return nil, false
+			}
+			diffs = append(diffs, &d)
+		}
+		return diffs, true
+	}
+	return nil, false
+}
+
+// refresh reloads the current state from Redis and returns the version held
+// before the reload
+func (s *RedisStore) refresh(ctx context.Context) (uint64, error) {
+	previous := s.Version()
+
+	raw, err := s.client.Get(ctx, s.stateKey()).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return previous, nil
+	}
+	if err != nil {
+		return previous, fmt.Errorf("fanout: failed to load state: %w", err)
+	}
+
+	var state webui.GameState
+	if err := json.Unmarshal(raw, &state); err != nil {
+		return previous, fmt.Errorf("fanout: failed to decode state: %w", err)
+	}
+
+	s.mu.Lock()
+	s.current = &state
+	s.mu.Unlock()
+	return previous, nil
+}
+
+// UpdateListener is woken by Follow after another instance saved a state;
+// *webui.StateManager is one
+type UpdateListener interface {
+	NotifyExternalUpdate(fromVersion uint64)
+}
+
+// Follow keeps the local cache in sync with states saved by other instances
+// and wakes pollers of sm, until ctx is cancelled
+func (s *RedisStore) Follow(ctx context.Context, sm UpdateListener) error {
+	sub := s.client.Subscribe(ctx, s.updatesChannel())
+	defer sub.Close()
+
+	if _, err := sub.Receive(ctx); err != nil {
+		return fmt.Errorf("fanout: failed to subscribe: %w", err)
+	}
+
+	// Catch up on anything saved before the subscription was active
+	if previous, err := s.refresh(ctx); err == nil {
+		sm.NotifyExternalUpdate(previous)
+	}
+
+	ch := sub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case msg, ok := <-ch:
+			if !ok {
+				return fmt.Errorf("fanout: subscription closed")
+			}
+
+			var u update
+			if err := json.Unmarshal([]byte(msg.Payload), &u); err != nil || u.Origin == s.origin {
+				continue
+			}
+			previous, err := s.refresh(ctx)
+			if err != nil {
+				slog.Warn("fanout.RedisStore: failed to refresh state", "error", err)
+				continue
+			}
+			sm.NotifyExternalUpdate(previous)
+		}
+	}
+}
+
+// PublishInput forwards client input to the instance that owns the session
+func (s *RedisStore) PublishInput(data []byte) error {
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+
+	if err := s.client.Publish(ctx, s.inputChannel(), data).Err(); err != nil {
+		return fmt.Errorf("fanout: failed to publish input: %w", err)
+	}
+	return nil
+}
+
+// SubscribeInput calls fn with input published by replicas until ctx is
+// cancelled
+func (s *RedisStore) SubscribeInput(ctx context.Context, fn func(data []byte)) error {
+	sub := s.client.Subscribe(ctx, s.inputChannel())
+	defer sub.Close()
+
+	if _, err := sub.Receive(ctx); err != nil {
+		return fmt.Errorf("fanout: failed to subscribe: %w", err)
+	}
+
+	ch := sub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case msg, ok := <-ch:
+			if !ok {
+				return fmt.Errorf("fanout: subscription closed")
+			}
+			fn([]byte(msg.Payload))
+		}
+	}
+}
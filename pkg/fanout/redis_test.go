@@ -0,0 +1,120 @@
+package fanout
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/opd-ai/go-gamelaunch-client/pkg/dgclient"
+	"github.com/opd-ai/go-gamelaunch-www/pkg/webui"
+)
+
+// newTestStore creates a store connected to mr
+func newTestStore(t *testing.T, mr *miniredis.Miniredis) *RedisStore {
+	t.Helper()
+
+	store, err := NewRedisStore(context.Background(), Config{Addr: mr.Addr(), History: 4})
+	if err != nil {
+		t.Fatalf("NewRedisStore failed: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+func TestNewRedisStore_RequiresAddr(t *testing.T) {
+	if _, err := NewRedisStore(context.Background(), Config{}); err == nil {
+		t.Error("Expected error without addr")
+	}
+}
+
+func TestRedisStore_SaveAndReload(t *testing.T) {
+	mr := miniredis.RunT(t)
+	owner := newTestStore(t, mr)
+	sm := webui.NewStateManagerWithStore(owner)
+
+	for _, char := range []rune{'a', 'b', 'c'} {
+		state := &webui.GameState{Width: 1, Height: 1, Buffer: [][]webui.Cell{{{Char: char}}}}
+		sm.UpdateState(state)
+	}
+
+	if owner.Version() != 3 {
+		t.Errorf("Version() = %d, want 3", owner.Version())
+	}
+
+	diffs, ok := owner.DiffsSince(1)
+	if !ok || len(diffs) != 2 {
+		t.Fatalf("DiffsSince(1) = %d diffs, ok=%v; want 2, true", len(diffs), ok)
+	}
+
+	// A new instance picks up the saved state
+	replica := newTestStore(t, mr)
+	current := replica.Current()
+	if current == nil || current.Version != 3 || current.Buffer[0][0].Char != 'c' {
+		t.Errorf("Replica loaded %+v, want version 3 with 'c'", current)
+	}
+}
+
+func TestRedisStore_FollowWakesReplicaPollers(t *testing.T) {
+	mr := miniredis.RunT(t)
+	owner := newTestStore(t, mr)
+	ownerView, err := webui.NewWebViewWithStore(dgclient.ViewOptions{InitialWidth: 10, InitialHeight: 2}, owner)
+	if err != nil {
+		t.Fatalf("NewWebViewWithStore failed: %v", err)
+	}
+
+	replica := newTestStore(t, mr)
+	replicaSM := webui.NewStateManagerWithStore(replica)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	go replica.Follow(ctx, replicaSM)
+
+	// Wait for the subscription before rendering
+	for mr.PubSubNumSub(replica.updatesChannel())[replica.updatesChannel()] == 0 {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if err := ownerView.Render([]byte("x")); err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+
+	diff, err := replicaSM.PollChangesWithContext(ctx, 0)
+	if err != nil {
+		t.Fatalf("PollChangesWithContext failed: %v", err)
+	}
+	if diff == nil || diff.Version == 0 {
+		t.Fatalf("Expected diff from owner, got %+v", diff)
+	}
+	if state := replicaSM.GetCurrentState(); state.Buffer[0][0].Char != 'x' {
+		t.Errorf("Replica cell (0,0) = %q, want 'x'", state.Buffer[0][0].Char)
+	}
+}
+
+func TestRedisStore_InputForwarding(t *testing.T) {
+	mr := miniredis.RunT(t)
+	owner := newTestStore(t, mr)
+	replica := newTestStore(t, mr)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	received := make(chan string, 1)
+	go owner.SubscribeInput(ctx, func(data []byte) { received <- string(data) })
+	for mr.PubSubNumSub(owner.inputChannel())[owner.inputChannel()] == 0 {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if err := replica.PublishInput([]byte("k")); err != nil {
+		t.Fatalf("PublishInput failed: %v", err)
+	}
+
+	select {
+	case input := <-received:
+		if input != "k" {
+			t.Errorf("Received %q, want %q", input, "k")
+		}
+	case <-ctx.Done():
+		t.Fatal("Timed out waiting for forwarded input")
+	}
+}
@@ -0,0 +1,194 @@
+// Package i18n localizes the messages the server shows web clients: refused
+// connections, rejected input and system notices. Every message has an
+// English default; deployments translate any of them per locale, and each
+// client is answered in the locale it asked for.
+package i18n
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"golang.org/x/text/language"
+)
+
+// Message keys
+const (
+	MsgSessionFull         = "session_full"
+	MsgTooManyConnections  = "too_many_connections"
+	MsgUnsupportedProtocol = "unsupported_protocol"
+	MsgInputForbidden      = "input_forbidden"
+	MsgInputSpectating     = "input_spectating"
+	MsgBandwidthDegraded   = "bandwidth_degraded"
+	MsgBandwidthRestored   = "bandwidth_restored"
+	MsgPresetCleared       = "preset_cleared"
+	MsgPresetSwitched      = "preset_switched"
+	MsgRecordingStarted    = "recording_started"
+	MsgRecordingStopped    = "recording_stopped"
+)
+
+// English holds the default text of every message as a fmt template.
+// Translations take the same arguments and may reorder them with explicit
+// indexes such as %[2]s.
+var English = map[string]string{
+	MsgSessionFull:         "session is full (%d clients)",
+	MsgTooManyConnections:  "too many connections from %s (limit %d)",
+	MsgUnsupportedProtocol: "unsupported protocol version %q (server supports %d-%d)",
+	MsgInputForbidden:      "your role does not allow sending input",
+	MsgInputSpectating:     "you are spectating; leave spectator mode to send input",
+	MsgBandwidthDegraded:   "Bandwidth cap reached: screen updates are reduced",
+	MsgBandwidthRestored:   "Bandwidth available again: screen updates restored",
+	MsgPresetCleared:       "Input preset cleared",
+	MsgPresetSwitched:      "Controls switched to %s (%s)",
+	MsgRecordingStarted:    "This session is now being recorded",
+	MsgRecordingStopped:    "Recording stopped for this session",
+}
+
+// localeParam is the query parameter a client uses to choose its locale
+const localeParam = "locale"
+
+// verbPattern matches the formatting verbs of a template
+var verbPattern = regexp.MustCompile(`%(\[\d+\])?[-+# 0-9.]*([a-zA-Z%])`)
+
+// Catalog holds translations of the English messages by locale. The nil
+// Catalog serves English.
+type Catalog struct {
+	locales map[string]map[string]string // by canonical locale, then key
+}
+
+// NewCatalog validates translations, keyed by locale such as "de" or
+// "pt-BR" and then by message key
+func NewCatalog(translations map[string]map[string]string) (*Catalog, error) {
+	c := &Catalog{locales: make(map[string]map[string]string, len(translations))}
+	for locale, messages := range translations {
+		canonical, ok := ParseLocale(locale)
+		if !ok {
+			return nil, fmt.Errorf("i18n: invalid locale %q", locale)
+		}
+		for key, template := range messages {
+			english, ok := English[key]
+			if !ok {
+				return nil, fmt.Errorf("i18n: %s: unknown message %q", locale, key)
+			}
+			if formatted := fmt.Sprintf(template, sampleArgs(english)...); strings.Contains(formatted, "%!") {
+				return nil, fmt.Errorf("i18n: %s: message %q does not match the arguments of %q", locale, key, english)
+			}
+		}
+		c.locales[canonical] = messages
+	}
+	return c, nil
+}
+
+// sampleArgs returns arguments fitting the verbs of template
+func sampleArgs(template string) []any {
+	var args []any
+	for _, verb := range verbPattern.FindAllStringSubmatch(template, -1) {
+		switch verb[2] {
+		case "%":
+		case "d":
+			args = append(args, 0)
+		default:
+			args = append(args, "")
+		}
+	}
+	return args
+}
+
+// Message returns message key formatted with args in locale, or its base
+// language, falling back to English
+func (c *Catalog) Message(locale, key string, args ...any) string {
+	template := English[key]
+	if c != nil {
+		for _, candidate := range candidates(locale) {
+			if translated, ok := c.locales[candidate][key]; ok {
+				template = translated
+				break
+			}
+		}
+	}
+	if len(args) == 0 {
+		return template
+	}
+	return fmt.Sprintf(template, args...)
+}
+
+// Pick returns the entry of texts, keyed by locale, that best matches
+// locale, or fallback
+func Pick(texts map[string]string, locale, fallback string) string {
+	if len(texts) == 0 {
+		return fallback
+	}
+	canonical := make(map[string]string, len(texts))
+	for l, text := range texts {
+		if c, ok := ParseLocale(l); ok {
+			canonical[c] = text
+		}
+	}
+	for _, candidate := range candidates(locale) {
+		if text, ok := canonical[candidate]; ok {
+			return text
+		}
+	}
+	return fallback
+}
+
+// candidates returns locale and its base language, most specific first
+func candidates(locale string) []string {
+	tag, err := language.Parse(locale)
+	if err != nil || locale == "" {
+		return nil
+	}
+	base, _ := tag.Base()
+	if base.String() == tag.String() {
+		return []string{tag.String()}
+	}
+	return []string{tag.String(), base.String()}
+}
+
+// ParseLocale returns the canonical form of a BCP 47 locale such as "de" or
+// "pt-BR"
+func ParseLocale(locale string) (string, bool) {
+	if locale == "" {
+		return "", false
+	}
+	tag, err := language.Parse(locale)
+	if err != nil {
+		return "", false
+	}
+	return tag.String(), true
+}
+
+// RequestLocale returns the locale a client asks for through r: the locale
+// query parameter, or else its preferred Accept-Language, or "" for none
+func RequestLocale(r *http.Request) string {
+	if locale, ok := ParseLocale(r.URL.Query().Get(localeParam)); ok {
+		return locale
+	}
+	tags, _, err := language.ParseAcceptLanguage(r.Header.Get("Accept-Language"))
+	if err != nil {
+		return ""
+	}
+	for _, tag := range tags {
+		// "*" parses as mul, any language
+		if tag != language.Und && tag != language.Make("mul") {
+			return tag.String()
+		}
+	}
+	return ""
+}
+
+// localeKey is the context key of a client's locale
+type localeKey struct{}
+
+// WithLocale returns a copy of ctx carrying a client's locale
+func WithLocale(ctx context.Context, locale string) context.Context {
+	return context.WithValue(ctx, localeKey{}, locale)
+}
+
+// Locale returns the locale carried by ctx, or "" if none
+func Locale(ctx context.Context) string {
+	locale, _ := ctx.Value(localeKey{}).(string)
+	return locale
+}
@@ -0,0 +1,125 @@
+// Package i18n provides tests for the message catalog and locale selection.
+package i18n
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNewCatalog_Validation(t *testing.T) {
+	tests := []struct {
+		name         string
+		translations map[string]map[string]string
+		wantErr      bool
+	}{
+		{"empty", nil, false},
+		{"valid", map[string]map[string]string{"de": {MsgSessionFull: "Sitzung ist voll (%d Clients)"}}, false},
+		{"reordered arguments", map[string]map[string]string{"ja": {MsgPresetSwitched: "%[2]s: %[1]s に切り替えました"}}, false},
+		{"invalid locale", map[string]map[string]string{"not a locale": {MsgSessionFull: "x"}}, true},
+		{"unknown key", map[string]map[string]string{"de": {"no_such_message": "x"}}, true},
+		{"wrong verb", map[string]map[string]string{"de": {MsgSessionFull: "Sitzung ist voll (%s)"}}, true},
+		{"extra argument", map[string]map[string]string{"de": {MsgInputForbidden: "verboten %d"}}, true},
+		{"missing argument", map[string]map[string]string{"de": {MsgTooManyConnections: "zu viele Verbindungen"}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewCatalog(tt.translations)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewCatalog() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCatalog_Message(t *testing.T) {
+	c, err := NewCatalog(map[string]map[string]string{
+		"de":    {MsgSessionFull: "Sitzung ist voll (%d Clients)"},
+		"pt-BR": {MsgPresetCleared: "Predefinição removida"},
+	})
+	if err != nil {
+		t.Fatalf("NewCatalog failed: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		catalog *Catalog
+		locale  string
+		key     string
+		args    []any
+		want    string
+	}{
+		{"exact locale", c, "de", MsgSessionFull, []any{4}, "Sitzung ist voll (4 Clients)"},
+		{"base language", c, "de-CH", MsgSessionFull, []any{4}, "Sitzung ist voll (4 Clients)"},
+		{"region only", c, "pt-BR", MsgPresetCleared, nil, "Predefinição removida"},
+		{"other region", c, "pt-PT", MsgPresetCleared, nil, "Input preset cleared"},
+		{"untranslated key", c, "de", MsgPresetCleared, nil, "Input preset cleared"},
+		{"no locale", c, "", MsgSessionFull, []any{4}, "session is full (4 clients)"},
+		{"nil catalog", nil, "de", MsgSessionFull, []any{4}, "session is full (4 clients)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.catalog.Message(tt.locale, tt.key, tt.args...); got != tt.want {
+				t.Errorf("Message() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPick(t *testing.T) {
+	texts := map[string]string{"de": "Neustart", "fr-CA": "Redémarrage"}
+
+	tests := []struct {
+		locale string
+		want   string
+	}{
+		{"de", "Neustart"},
+		{"de-AT", "Neustart"},
+		{"fr-CA", "Redémarrage"},
+		{"fr", "restart"},
+		{"", "restart"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.locale, func(t *testing.T) {
+			if got := Pick(texts, tt.locale, "restart"); got != tt.want {
+				t.Errorf("Pick(%q) = %q, want %q", tt.locale, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRequestLocale(t *testing.T) {
+	tests := []struct {
+		name           string
+		target         string
+		acceptLanguage string
+		want           string
+	}{
+		{"none", "/ws", "", ""},
+		{"accept language", "/ws", "de-DE,de;q=0.9,en;q=0.8", "de-DE"},
+		{"query wins", "/ws?locale=pt-BR", "de", "pt-BR"},
+		{"invalid query", "/ws?locale=%3F%3F", "fr", "fr"},
+		{"wildcard", "/ws", "*", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			if tt.acceptLanguage != "" {
+				r.Header.Set("Accept-Language", tt.acceptLanguage)
+			}
+			if got := RequestLocale(r); got != tt.want {
+				t.Errorf("RequestLocale() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLocale_Context(t *testing.T) {
+	if got := Locale(context.Background()); got != "" {
+		t.Errorf("Locale() = %q, want empty", got)
+	}
+	if got := Locale(WithLocale(context.Background(), "de")); got != "de" {
+		t.Errorf("Locale() = %q, want %q", got, "de")
+	}
+}
@@ -0,0 +1,103 @@
+package recording
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"strconv"
+	"time"
+)
+
+// HandleList serves GET /recordings, filtered by the player, game, since,
+// until (RFC 3339 or YYYY-MM-DD) and limit query parameters
+func (s *Store) HandleList(rw http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		rw.Header().Set("Allow", http.MethodGet)
+		http.Error(rw, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	q := r.URL.Query()
+	filter := Filter{Player: q.Get("player"), Game: q.Get("game")}
+	var err error
+	if filter.Since, err = parseTime(q.Get("since")); err != nil {
+		http.Error(rw, "Invalid since", http.StatusBadRequest)
+		return
+	}
+	if filter.Until, err = parseTime(q.Get("until")); err != nil {
+		http.Error(rw, "Invalid until", http.StatusBadRequest)
+		return
+	}
+	if v := q.Get("limit"); v != "" {
+		if filter.Limit, err = strconv.Atoi(v); err != nil || filter.Limit < 0 {
+			http.Error(rw, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+	}
+
+	recordings, err := s.Query(r.Context(), filter)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	rw.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(rw).Encode(map[string]interface{}{"recordings": recordings})
+}
+
+// HandleFile serves GET and HEAD /recordings/file?id=N, the ttyrec file of
+// a recording. Range and conditional requests are answered against an ETag
+// that changes as a recording in progress grows.
+func (s *Store) HandleFile(rw http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		rw.Header().Set("Allow", "GET, HEAD")
+		http.Error(rw, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id, err := strconv.ParseInt(r.URL.Query().Get("id"), 10, 64)
+	if err != nil {
+		http.Error(rw, "Invalid id", http.StatusBadRequest)
+		return
+	}
+	rec, err := s.Get(r.Context(), id)
+	if errors.Is(err, ErrNotFound) {
+		http.NotFound(rw, r)
+		return
+	}
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	f, err := os.Open(s.File(rec))
+	if err != nil {
+		http.NotFound(rw, r)
+		return
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// Recordings are only appended to, so the ID and size identify the content
+	rw.Header().Set("Content-Type", "application/octet-stream")
+	rw.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", path.Base(rec.Path)))
+	rw.Header().Set("ETag", fmt.Sprintf(`"%d-%d"`, rec.ID, info.Size()))
+	http.ServeContent(rw, r, "", info.ModTime(), f)
+}
+
+// parseTime parses an RFC 3339 timestamp or a date; empty means unset
+func parseTime(v string) (time.Time, error) {
+	if v == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	return time.Parse("2006-01-02", v)
+}
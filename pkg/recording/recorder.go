@@ -0,0 +1,281 @@
+package recording
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/opd-ai/go-gamelaunch-www/pkg/ttyrec"
+)
+
+// unsafeNameChars are replaced in player names used as directory names
+var unsafeNameChars = regexp.MustCompile(`[^A-Za-z0-9_-]`)
+
+// Result describes how a recorded game ended
+type Result struct {
+	Player  string // overrides the session's player when the game names one
+	Game    string
+	Outcome string
+	Score   int64
+}
+
+// Recorder writes the output of one session as a series of recordings, one
+// per game
+type Recorder struct {
+	store   *Store
+	session string
+	player  string
+
+	mu        sync.Mutex
+	enabled   bool // the session consents to recording
+	loggingIn bool // output is discarded until the login completes
+	file      *os.File
+	writer    *ttyrec.Writer
+	id        int64
+	path      string
+	size      int64
+	failed    bool  // the current recording could not be written
+	maxSize   int64 // bytes recorded per game, 0 for no limit; see SetMaxSize
+	truncated bool  // the current game reached maxSize
+
+	// Frames are recorded shifted back by the time left out by Skip and
+	// cut from idle stretches
+	last     time.Time     // real time of the last frame
+	shift    time.Duration // time left out of the current recording so far
+	skipping bool          // output was skipped since the last frame
+}
+
+// NewRecorder creates a recorder for a session played by player. The
+// session is recorded unless the store is opt-in.
+func (s *Store) NewRecorder(session, player string) *Recorder {
+	if player == "" {
+		player = session
+	}
+	return &Recorder{
+		store:     s,
+		session:   session,
+		player:    player,
+		enabled:   !s.optIn,
+		loggingIn: s.loginEnd != nil,
+	}
+}
+
+// Enabled reports whether the session is being recorded
+func (r *Recorder) Enabled() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.enabled
+}
+
+// SetEnabled opts the session in to or out of recording. Opting out deletes
+// the recording in progress; finished games are kept.
+func (r *Recorder) SetEnabled(enabled bool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.enabled == enabled {
+		return nil
+	}
+	r.enabled = enabled
+	if enabled || r.file == nil {
+		return nil
+	}
+
+	closeErr := r.file.Close()
+	id, path := r.id, r.path
+	r.file, r.writer = nil, nil
+	if err := r.store.remove(id, path); err != nil {
+		return err
+	}
+	if closeErr != nil {
+		return fmt.Errorf("recording: failed to close file: %w", closeErr)
+	}
+	return nil
+}
+
+// SetMaxSize limits the size of each recording to n bytes; the rest of a
+// game that reaches it is not recorded. Zero removes the limit.
+func (r *Recorder) SetMaxSize(n int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.maxSize = n
+}
+
+// Size returns the size of the recording in progress and whether it was cut
+// short at the size limit
+func (r *Recorder) Size() (size int64, truncated bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.file == nil {
+		return 0, false
+	}
+	return r.size, r.truncated
+}
+
+// Write records terminal output, starting a new recording if none is in
+// progress. Output before the login completes is left out when the login is
+// redacted. Failures are logged rather than returned so that recording never
+// interrupts play.
+func (r *Recorder) Write(data []byte) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.writeLocked(data)
+}
+
+// WriteScreen is Write for output after which redraw returns output drawing
+// the whole screen. After skipped output the redraw is recorded in place of
+// data, so playback resumes from the screen, modes and character sets the
+// skipped output left rather than from a terminal state that never existed.
+func (r *Recorder) WriteScreen(data []byte, redraw func() []byte) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.skipping && !r.loggingIn {
+		data = redraw()
+	}
+	r.writeLocked(data)
+}
+
+// writeLocked is Write with r.mu held
+func (r *Recorder) writeLocked(data []byte) {
+	if r.loggingIn {
+		// The output that completes the login may still show credentials
+		r.loggingIn = !r.store.loginEnd.Match(data)
+		return
+	}
+	if !r.enabled {
+		return
+	}
+
+	if r.file == nil && !r.failed {
+		if err := r.startLocked(); err != nil {
+			slog.Error("recording: failed to start recording", "session", r.session, "error", err)
+			r.failed = true
+		}
+	}
+	if r.file == nil || r.truncated {
+		return
+	}
+
+	now := r.recordTimeLocked()
+	for len(data) > 0 {
+		chunk := data
+		if len(chunk) > ttyrec.MaxFrameSize {
+			chunk = chunk[:ttyrec.MaxFrameSize]
+		}
+		data = data[len(chunk):]
+		if r.maxSize > 0 && r.size+int64(len(chunk))+12 > r.maxSize {
+			slog.Warn("recording: size limit reached, rest of the game not recorded",
+				"session", r.session, "limit", r.maxSize)
+			r.truncated = true
+			return
+		}
+		if err := r.writer.WriteFrame(ttyrec.Frame{Time: now, Data: chunk}); err != nil {
+			slog.Error("recording: write failed", "session", r.session, "error", err)
+			return
+		}
+		r.size += int64(len(chunk)) + 12
+	}
+}
+
+// Skip leaves data out of the recording, e.g. because it draws a menu. The
+// time from the last recorded frame to the next Write is left out as well,
+// so that playback continues without a pause. Use WriteScreen for the
+// output after it, as the output left out may have changed modes the rest
+// of the recording relies on.
+func (r *Recorder) Skip(data []byte) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.loggingIn {
+		r.loggingIn = !r.store.loginEnd.Match(data)
+		return
+	}
+	r.skipping = true
+}
+
+// recordTimeLocked returns the time of a frame written now: the real time
+// less what Skip and idle stretches left out
+func (r *Recorder) recordTimeLocked() time.Time {
+	now := r.store.now()
+	gap := now.Sub(r.last)
+	switch {
+	case r.skipping:
+		r.shift += gap
+	case r.store.maxIdle > 0 && gap > r.store.maxIdle:
+		r.shift += gap - r.store.maxIdle
+	}
+	r.last, r.skipping = now, false
+	return now.Add(-r.shift)
+}
+
+// startLocked opens a new ttyrec file and indexes it
+func (r *Recorder) startLocked() error {
+	started := r.store.now()
+	rel := fmt.Sprintf("%s/%s.ttyrec",
+		unsafeNameChars.ReplaceAllString(r.player, "_"),
+		started.UTC().Format("2006-01-02.15-04-05.000"))
+
+	path := filepath.Join(r.store.dir, filepath.FromSlash(rel))
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to create file: %w", err)
+	}
+
+	id, err := r.store.insert(&Recording{
+		Session: r.session,
+		Player:  r.player,
+		Started: started,
+		Path:    rel,
+	})
+	if err != nil {
+		file.Close()
+		os.Remove(path)
+		return err
+	}
+
+	r.file, r.writer, r.id, r.path, r.size = file, ttyrec.NewWriter(file), id, rel, 0
+	r.truncated = false
+	r.last, r.shift, r.skipping = started, 0, false
+	return nil
+}
+
+// EndGame completes the current recording with the result of its game; the
+// next output starts a new recording
+func (r *Recorder) EndGame(result Result) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.failed = false
+	r.loggingIn = false // a game was played, so the login is over
+	return r.finishLocked(result)
+}
+
+// Close completes the current recording without a result
+func (r *Recorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.finishLocked(Result{})
+}
+
+// finishLocked closes the current file and updates its index entry
+func (r *Recorder) finishLocked(result Result) error {
+	if r.file == nil {
+		return nil
+	}
+	closeErr := r.file.Close()
+	err := r.store.finish(r.id, result.Player, result.Game, result.Outcome, result.Score, r.size, r.store.now())
+	r.file, r.writer = nil, nil
+	if err != nil {
+		return err
+	}
+	if closeErr != nil {
+		return fmt.Errorf("recording: failed to close file: %w", closeErr)
+	}
+	return nil
+}
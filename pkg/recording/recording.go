@@ -0,0 +1,310 @@
+// Package recording records game sessions as ttyrec files and indexes them
+// in SQLite, so that recorded games can be browsed by player, game and date.
+package recording
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	_ "modernc.org/sqlite" // SQLite driver, without cgo
+)
+
+// Query limits
+const (
+	defaultQueryLimit = 100
+	maxQueryLimit     = 1000
+)
+
+// ErrNotFound is returned for unknown recording IDs
+var ErrNotFound = errors.New("recording not found")
+
+// schema creates the index; statements are idempotent
+const schema = `
+CREATE TABLE IF NOT EXISTS recordings (
+	id         INTEGER PRIMARY KEY AUTOINCREMENT,
+	session    TEXT NOT NULL,
+	player     TEXT NOT NULL,
+	game       TEXT NOT NULL DEFAULT '',
+	started_at INTEGER NOT NULL,
+	ended_at   INTEGER,
+	result     TEXT NOT NULL DEFAULT '',
+	score      INTEGER NOT NULL DEFAULT 0,
+	path       TEXT NOT NULL,
+	size       INTEGER NOT NULL DEFAULT 0
+);
+CREATE INDEX IF NOT EXISTS recordings_player ON recordings (player, started_at);
+CREATE INDEX IF NOT EXISTS recordings_game ON recordings (game, started_at);
+CREATE INDEX IF NOT EXISTS recordings_started ON recordings (started_at);
+`
+
+// defaultLoginEnd matches the dgamelaunch main menu shown after login
+const defaultLoginEnd = `(?i)logged in as`
+
+// Config configures where recordings and their index are stored, and the
+// privacy and retention policy applied to them
+type Config struct {
+	Dir   string `yaml:"dir"`             // ttyrec files
+	Index string `yaml:"index,omitempty"` // SQLite database; default <dir>/recordings.db
+
+	// Privacy
+	OptIn       bool   `yaml:"opt_in,omitempty"`       // record sessions only after they opt in
+	RedactLogin bool   `yaml:"redact_login,omitempty"` // leave out output until login completes
+	LoginEnd    string `yaml:"login_end,omitempty"`    // regexp marking login completion; default "(?i)logged in as"
+
+	// Retention deletes recordings that ended longer ago, e.g. "720h";
+	// empty keeps them forever
+	Retention string `yaml:"retention,omitempty" yamlcheck:"duration"`
+
+	// Pausing keeps recordings to actual play: gaps in the output longer
+	// than MaxIdle, e.g. "5s", are cut to it, and screens recognized by the
+	// parser plugins named in PauseOn, e.g. "dgamelaunch" for its menus,
+	// are left out
+	MaxIdle string   `yaml:"max_idle,omitempty" yamlcheck:"duration"`
+	PauseOn []string `yaml:"pause_on,omitempty"`
+}
+
+// Recording is the metadata of a recorded game
+type Recording struct {
+	ID      int64      `json:"id"`
+	Session string     `json:"session"`
+	Player  string     `json:"player"`
+	Game    string     `json:"game,omitempty"`
+	Started time.Time  `json:"started"`
+	Ended   *time.Time `json:"ended,omitempty"` // nil while in progress
+	Result  string     `json:"result,omitempty"`
+	Score   int64      `json:"score"`
+	Path    string     `json:"-"` // relative to the recording directory
+	Size    int64      `json:"size"`
+}
+
+// Filter selects recordings; zero fields match everything
+type Filter struct {
+	Player string
+	Game   string
+	Since  time.Time // started at or after
+	Until  time.Time // started before
+	Limit  int       // default 100, at most 1000
+}
+
+// Store keeps recordings on disk with a SQLite index
+type Store struct {
+	dir       string
+	db        *sql.DB
+	now       func() time.Time
+	optIn     bool
+	loginEnd  *regexp.Regexp // nil unless the login is redacted
+	retention time.Duration  // zero keeps recordings forever
+	maxIdle   time.Duration  // longest gap between frames, zero for no limit
+	pauseOn   []string       // see PauseOn
+}
+
+// Open creates the recording directory and opens or creates the index
+func Open(cfg Config) (*Store, error) {
+	if cfg.Dir == "" {
+		return nil, fmt.Errorf("recording: dir is required")
+	}
+	store := &Store{dir: cfg.Dir, now: time.Now, optIn: cfg.OptIn}
+	if cfg.RedactLogin {
+		pattern := cfg.LoginEnd
+		if pattern == "" {
+			pattern = defaultLoginEnd
+		}
+		loginEnd, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("recording: invalid login_end %q: %w", cfg.LoginEnd, err)
+		}
+		store.loginEnd = loginEnd
+	}
+	if cfg.Retention != "" {
+		retention, err := time.ParseDuration(cfg.Retention)
+		if err != nil || retention <= 0 {
+			return nil, fmt.Errorf("recording: invalid retention %q", cfg.Retention)
+		}
+		store.retention = retention
+	}
+	if cfg.MaxIdle != "" {
+		maxIdle, err := time.ParseDuration(cfg.MaxIdle)
+		if err != nil || maxIdle <= 0 {
+			return nil, fmt.Errorf("recording: invalid max_idle %q", cfg.MaxIdle)
+		}
+		store.maxIdle = maxIdle
+	}
+	for _, name := range cfg.PauseOn {
+		if name == "" {
+			return nil, fmt.Errorf("recording: empty pause_on entry")
+		}
+	}
+	store.pauseOn = cfg.PauseOn
+	if err := os.MkdirAll(cfg.Dir, 0o755); err != nil {
+		return nil, fmt.Errorf("recording: failed to create directory: %w", err)
+	}
+
+	index := cfg.Index
+	if index == "" {
+		index = filepath.Join(cfg.Dir, "recordings.db")
+	}
+
+	db, err := sql.Open("sqlite", "file:"+index+"?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)")
+	if err != nil {
+		return nil, fmt.Errorf("recording: failed to open index: %w", err)
+	}
+	if _, err := db.Exec(schema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("recording: failed to initialize index: %w", err)
+	}
+
+	store.db = db
+	return store, nil
+}
+
+// PauseOn returns the names of the parser plugins whose screens are left
+// out of recordings, see Config.PauseOn
+func (s *Store) PauseOn() []string {
+	return s.pauseOn
+}
+
+// Close closes the index
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// Query returns the recordings matching f, newest first
+func (s *Store) Query(ctx context.Context, f Filter) ([]Recording, error) {
+	var where []string
+	var args []interface{}
+	if f.Player != "" {
+		where = append(where, "player = ? COLLATE NOCASE")
+		args = append(args, f.Player)
+	}
+	if f.Game != "" {
+		where = append(where, "game = ? COLLATE NOCASE")
+		args = append(args, f.Game)
+	}
+	if !f.Since.IsZero() {
+		where = append(where, "started_at >= ?")
+		args = append(args, f.Since.UnixMilli())
+	}
+	if !f.Until.IsZero() {
+		where = append(where, "started_at < ?")
+		args = append(args, f.Until.UnixMilli())
+	}
+	limit := f.Limit
+	if limit <= 0 {
+		limit = defaultQueryLimit
+	}
+	if limit > maxQueryLimit {
+		limit = maxQueryLimit
+	}
+
+	query := "SELECT id, session, player, game, started_at, ended_at, result, score, path, size FROM recordings"
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	query += " ORDER BY started_at DESC, id DESC LIMIT ?"
+	args = append(args, limit)
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("recording: query failed: %w", err)
+	}
+	defer rows.Close()
+
+	recordings := []Recording{}
+	for rows.Next() {
+		rec, err := scanRecording(rows)
+		if err != nil {
+			return nil, err
+		}
+		recordings = append(recordings, *rec)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("recording: query failed: %w", err)
+	}
+	return recordings, nil
+}
+
+// Get returns the recording with the given ID
+func (s *Store) Get(ctx context.Context, id int64) (*Recording, error) {
+	row := s.db.QueryRowContext(ctx,
+		"SELECT id, session, player, game, started_at, ended_at, result, score, path, size FROM recordings WHERE id = ?", id)
+	rec, err := scanRecording(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	return rec, err
+}
+
+// scanner is implemented by *sql.Row and *sql.Rows
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanRecording reads a recording row
+func scanRecording(row scanner) (*Recording, error) {
+	var rec Recording
+	var started int64
+	var ended sql.NullInt64
+	err := row.Scan(&rec.ID, &rec.Session, &rec.Player, &rec.Game, &started, &ended,
+		&rec.Result, &rec.Score, &rec.Path, &rec.Size)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("recording: failed to read index: %w", err)
+	}
+	rec.Started = time.UnixMilli(started)
+	if ended.Valid {
+		t := time.UnixMilli(ended.Int64)
+		rec.Ended = &t
+	}
+	return &rec, nil
+}
+
+// File returns the absolute path of a recording's ttyrec file
+func (s *Store) File(rec *Recording) string {
+	return filepath.Join(s.dir, filepath.FromSlash(rec.Path))
+}
+
+// insert indexes a new recording and returns its ID
+func (s *Store) insert(rec *Recording) (int64, error) {
+	res, err := s.db.Exec(
+		"INSERT INTO recordings (session, player, game, started_at, path) VALUES (?, ?, ?, ?, ?)",
+		rec.Session, rec.Player, rec.Game, rec.Started.UnixMilli(), rec.Path)
+	if err != nil {
+		return 0, fmt.Errorf("recording: failed to index recording: %w", err)
+	}
+	return res.LastInsertId()
+}
+
+// remove deletes a recording's file and index entry
+func (s *Store) remove(id int64, path string) error {
+	if err := os.Remove(filepath.Join(s.dir, filepath.FromSlash(path))); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("recording: failed to delete file: %w", err)
+	}
+	if _, err := s.db.Exec("DELETE FROM recordings WHERE id = ?", id); err != nil {
+		return fmt.Errorf("recording: failed to update index: %w", err)
+	}
+	return nil
+}
+
+// finish records the end of a recording
+func (s *Store) finish(id int64, player, game, result string, score, size int64, ended time.Time) error {
+	_, err := s.db.Exec(
+		`UPDATE recordings SET
+			player = CASE WHEN ? != '' THEN ? ELSE player END,
+			game = CASE WHEN ? != '' THEN ? ELSE game END,
+			result = ?, score = ?, size = ?, ended_at = ?
+		WHERE id = ?`,
+		player, player, game, game, result, score, size, ended.UnixMilli(), id)
+	if err != nil {
+		return fmt.Errorf("recording: failed to update index: %w", err)
+	}
+	return nil
+}
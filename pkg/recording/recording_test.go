@@ -0,0 +1,467 @@
+package recording
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/opd-ai/go-gamelaunch-www/pkg/ttyrec"
+)
+
+// newTestStore opens a store in a temporary directory with a controllable
+// clock
+func newTestStore(t *testing.T) (*Store, *time.Time) {
+	t.Helper()
+	return newTestStoreConfig(t, Config{})
+}
+
+// newTestStoreConfig is newTestStore with the privacy and retention settings
+// of cfg
+func newTestStoreConfig(t *testing.T, cfg Config) (*Store, *time.Time) {
+	t.Helper()
+	cfg.Dir = t.TempDir()
+	store, err := Open(cfg)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	store.now = func() time.Time { return now }
+	return store, &now
+}
+
+func TestOpen_RequiresDir(t *testing.T) {
+	if _, err := Open(Config{}); err == nil {
+		t.Error("expected error without dir")
+	}
+}
+
+func TestRecorder_OneRecordingPerGame(t *testing.T) {
+	store, now := newTestStore(t)
+	rec := store.NewRecorder("alice@nao", "alice")
+
+	rec.Write([]byte("hello "))
+	*now = now.Add(time.Second)
+	rec.Write([]byte("world"))
+	if err := rec.EndGame(Result{Game: "nethack", Outcome: "died", Score: 1234}); err != nil {
+		t.Fatalf("EndGame failed: %v", err)
+	}
+
+	*now = now.Add(time.Minute)
+	rec.Write([]byte("second game"))
+	if err := rec.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	recordings, err := store.Query(context.Background(), Filter{Player: "ALICE"})
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(recordings) != 2 {
+		t.Fatalf("got %d recordings, want 2", len(recordings))
+	}
+
+	second, first := recordings[0], recordings[1]
+	if first.Game != "nethack" || first.Result != "died" || first.Score != 1234 || first.Ended == nil {
+		t.Errorf("first recording = %+v", first)
+	}
+	if second.Result != "" || second.Ended == nil {
+		t.Errorf("second recording = %+v", second)
+	}
+
+	frames, err := readFrames(store, &first)
+	if err != nil {
+		t.Fatalf("reading ttyrec failed: %v", err)
+	}
+	if len(frames) != 2 || string(frames[1].Data) != "world" || frames[1].Time.Sub(frames[0].Time) != time.Second {
+		t.Errorf("frames = %+v", frames)
+	}
+	if first.Size != int64(len("hello world")+2*12) {
+		t.Errorf("Size = %d", first.Size)
+	}
+}
+
+func readFrames(store *Store, rec *Recording) ([]ttyrec.Frame, error) {
+	data, err := os.ReadFile(store.File(rec))
+	if err != nil {
+		return nil, err
+	}
+	return ttyrec.ReadAll(bytes.NewReader(data))
+}
+
+func TestRecorder_MaxSize(t *testing.T) {
+	store, now := newTestStore(t)
+	rec := store.NewRecorder("alice@nao", "alice")
+	rec.SetMaxSize(12 + 5 + 12 + 5)
+
+	rec.Write([]byte("first"))
+	rec.Write([]byte("again"))
+	rec.Write([]byte("dropped"))
+	rec.Write([]byte("x"))
+	if size, truncated := rec.Size(); size != 34 || !truncated {
+		t.Errorf("Size = %d, %v; want 34, true", size, truncated)
+	}
+	if err := rec.EndGame(Result{Game: "nethack"}); err != nil {
+		t.Fatalf("EndGame failed: %v", err)
+	}
+
+	// The next game is recorded again
+	*now = now.Add(time.Minute)
+	rec.Write([]byte("next"))
+	if size, truncated := rec.Size(); size != 16 || truncated {
+		t.Errorf("Size of the next game = %d, %v; want 16, false", size, truncated)
+	}
+	rec.Close()
+
+	recordings, err := store.Query(context.Background(), Filter{Game: "nethack"})
+	if err != nil || len(recordings) != 1 {
+		t.Fatalf("Query = %+v, %v", recordings, err)
+	}
+	frames, err := readFrames(store, &recordings[0])
+	if err != nil {
+		t.Fatalf("reading ttyrec failed: %v", err)
+	}
+	if len(frames) != 2 || string(frames[1].Data) != "again" {
+		t.Errorf("frames = %+v", frames)
+	}
+}
+
+func TestRecorder_GameNamesPlayer(t *testing.T) {
+	store, _ := newTestStore(t)
+	rec := store.NewRecorder("guest@cao", "")
+
+	rec.Write([]byte("x"))
+	if err := rec.EndGame(Result{Player: "bob", Game: "crawl"}); err != nil {
+		t.Fatalf("EndGame failed: %v", err)
+	}
+
+	recordings, err := store.Query(context.Background(), Filter{Player: "bob"})
+	if err != nil || len(recordings) != 1 {
+		t.Fatalf("Query = %+v, %v", recordings, err)
+	}
+	if filepath.Dir(recordings[0].Path) != "guest_cao" {
+		t.Errorf("Path = %q, want it under the session directory", recordings[0].Path)
+	}
+}
+
+func TestStore_Query_Filters(t *testing.T) {
+	store, now := newTestStore(t)
+	for _, g := range []struct {
+		player, game string
+		day          int
+	}{{"alice", "nethack", 1}, {"alice", "crawl", 2}, {"bob", "nethack", 3}} {
+		*now = time.Date(2026, 10, g.day, 0, 0, 0, 0, time.UTC)
+		rec := store.NewRecorder(g.player+"@nao", g.player)
+		rec.Write([]byte("x"))
+		rec.EndGame(Result{Game: g.game})
+	}
+
+	tests := []struct {
+		name   string
+		filter Filter
+		want   []string
+	}{
+		{"all newest first", Filter{}, []string{"bob", "alice", "alice"}},
+		{"by player", Filter{Player: "alice"}, []string{"alice", "alice"}},
+		{"by game", Filter{Game: "nethack"}, []string{"bob", "alice"}},
+		{"by date", Filter{Since: time.Date(2026, 10, 2, 0, 0, 0, 0, time.UTC), Until: time.Date(2026, 10, 3, 0, 0, 0, 0, time.UTC)}, []string{"alice"}},
+		{"limit", Filter{Limit: 1}, []string{"bob"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recordings, err := store.Query(context.Background(), tt.filter)
+			if err != nil {
+				t.Fatalf("Query failed: %v", err)
+			}
+			var got []string
+			for _, r := range recordings {
+				got = append(got, r.Player)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("players = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("players = %v, want %v", got, tt.want)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestStore_Handlers(t *testing.T) {
+	store, _ := newTestStore(t)
+	rec := store.NewRecorder("alice@nao", "alice")
+	rec.Write([]byte("frame"))
+	rec.EndGame(Result{Game: "nethack"})
+
+	w := httptest.NewRecorder()
+	store.HandleList(w, httptest.NewRequest(http.MethodGet, "/recordings?game=nethack&since=2026-10-01", nil))
+	var resp struct {
+		Recordings []Recording `json:"recordings"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if len(resp.Recordings) != 1 {
+		t.Fatalf("recordings = %+v", resp.Recordings)
+	}
+
+	w = httptest.NewRecorder()
+	store.HandleFile(w, httptest.NewRequest(http.MethodGet, "/recordings/file?id=1", nil))
+	frames, err := ttyrec.ReadAll(w.Body)
+	if w.Code != http.StatusOK || err != nil || len(frames) != 1 || string(frames[0].Data) != "frame" {
+		t.Errorf("file = %d, %+v, %v", w.Code, frames, err)
+	}
+
+	// Downloads can be validated and resumed
+	etag := w.Header().Get("ETag")
+	for _, tc := range []struct {
+		method string
+		header string
+		value  string
+		code   int
+		body   int
+	}{
+		{http.MethodHead, "", "", http.StatusOK, 0},
+		{http.MethodGet, "Range", "bytes=12-", http.StatusPartialContent, len("frame")},
+		{http.MethodGet, "If-None-Match", etag, http.StatusNotModified, 0},
+		{http.MethodGet, "If-Range", `"1-1"`, http.StatusOK, 12 + len("frame")},
+		{http.MethodPost, "", "", http.StatusMethodNotAllowed, -1},
+	} {
+		w = httptest.NewRecorder()
+		req := httptest.NewRequest(tc.method, "/recordings/file?id=1", nil)
+		if tc.header != "" {
+			req.Header.Set(tc.header, tc.value)
+		}
+		if tc.header == "If-Range" {
+			req.Header.Set("Range", "bytes=12-")
+		}
+		store.HandleFile(w, req)
+		if w.Code != tc.code || (tc.body >= 0 && w.Body.Len() != tc.body) {
+			t.Errorf("%s with %s %q = %d with %d bytes, want %d with %d", tc.method, tc.header, tc.value, w.Code, w.Body.Len(), tc.code, tc.body)
+		}
+	}
+
+	for _, tc := range []struct {
+		url  string
+		code int
+	}{
+		{"/recordings/file?id=99", http.StatusNotFound},
+		{"/recordings/file?id=x", http.StatusBadRequest},
+		{"/recordings?since=yesterday", http.StatusBadRequest},
+	} {
+		w = httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, tc.url, nil)
+		if req.URL.Path == "/recordings" {
+			store.HandleList(w, req)
+		} else {
+			store.HandleFile(w, req)
+		}
+		if w.Code != tc.code {
+			t.Errorf("%s status = %d, want %d", tc.url, w.Code, tc.code)
+		}
+	}
+}
+
+func TestOpen_RejectsInvalidPolicy(t *testing.T) {
+	for _, cfg := range []Config{
+		{RedactLogin: true, LoginEnd: "("},
+		{Retention: "forever"},
+		{Retention: "-1h"},
+		{MaxIdle: "0s"},
+		{PauseOn: []string{""}},
+	} {
+		cfg.Dir = t.TempDir()
+		if _, err := Open(cfg); err == nil {
+			t.Errorf("Open(%+v) succeeded, want error", cfg)
+		}
+	}
+}
+
+func TestRecorder_OptInAndOptOut(t *testing.T) {
+	store, now := newTestStoreConfig(t, Config{OptIn: true})
+	rec := store.NewRecorder("alice@nao", "alice")
+
+	rec.Write([]byte("before consent"))
+	if rec.Enabled() {
+		t.Error("opt-in session is recorded without consent")
+	}
+
+	if err := rec.SetEnabled(true); err != nil {
+		t.Fatalf("SetEnabled(true) failed: %v", err)
+	}
+	rec.Write([]byte("first game"))
+	if err := rec.EndGame(Result{Game: "nethack"}); err != nil {
+		t.Fatalf("EndGame failed: %v", err)
+	}
+	*now = now.Add(time.Minute)
+	rec.Write([]byte("second game"))
+
+	recordings, _ := store.Query(context.Background(), Filter{})
+	if len(recordings) != 2 {
+		t.Fatalf("got %d recordings after opting in, want 2", len(recordings))
+	}
+	inProgress := recordings[0]
+
+	// Opting out discards the game in progress but keeps finished ones
+	if err := rec.SetEnabled(false); err != nil {
+		t.Fatalf("SetEnabled(false) failed: %v", err)
+	}
+	rec.Write([]byte("after opting out"))
+	if err := rec.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	recordings, _ = store.Query(context.Background(), Filter{})
+	if len(recordings) != 1 || recordings[0].Game != "nethack" {
+		t.Fatalf("recordings after opting out = %+v, want the finished game", recordings)
+	}
+	if _, err := os.Stat(store.File(&inProgress)); !os.IsNotExist(err) {
+		t.Errorf("file of the discarded recording still exists: %v", err)
+	}
+	frames, err := readFrames(store, &recordings[0])
+	if err != nil || len(frames) != 1 || string(frames[0].Data) != "first game" {
+		t.Errorf("frames = %+v, %v; want only the first game", frames, err)
+	}
+}
+
+func TestRecorder_RedactsLogin(t *testing.T) {
+	store, _ := newTestStoreConfig(t, Config{RedactLogin: true})
+	rec := store.NewRecorder("alice@nao", "alice")
+
+	rec.Write([]byte("Please enter your username: alice"))
+	rec.Write([]byte("Please enter your password: ******"))
+	rec.Write([]byte("Logged in as: alice\r\np) Play NetHack"))
+	rec.Write([]byte("\x1b[2JWelcome to NetHack!"))
+	if err := rec.EndGame(Result{}); err != nil {
+		t.Fatalf("EndGame failed: %v", err)
+	}
+
+	recordings, _ := store.Query(context.Background(), Filter{})
+	if len(recordings) != 1 {
+		t.Fatalf("got %d recordings, want 1", len(recordings))
+	}
+	frames, err := readFrames(store, &recordings[0])
+	if err != nil || len(frames) != 1 || string(frames[0].Data) != "\x1b[2JWelcome to NetHack!" {
+		t.Errorf("frames = %+v, %v; want only the output after login", frames, err)
+	}
+
+	// A finished game ends the login even if the marker never appeared
+	rec = store.NewRecorder("bob@nao", "bob")
+	rec.Write([]byte("custom login"))
+	rec.EndGame(Result{})
+	rec.Write([]byte("next game"))
+	rec.Close()
+	if recordings, _ := store.Query(context.Background(), Filter{Player: "bob"}); len(recordings) != 1 {
+		t.Errorf("got %d recordings for bob, want 1 after the first game", len(recordings))
+	}
+}
+
+func TestStore_Prune_DeletesExpiredRecordings(t *testing.T) {
+	store, now := newTestStoreConfig(t, Config{Retention: "24h"})
+	rec := store.NewRecorder("alice@nao", "alice")
+
+	rec.Write([]byte("old game"))
+	rec.EndGame(Result{})
+	*now = now.Add(20 * time.Hour)
+	rec.Write([]byte("recent game"))
+	rec.EndGame(Result{})
+	*now = now.Add(time.Minute)
+	rec.Write([]byte("game in progress"))
+
+	old, _ := store.Query(context.Background(), Filter{Until: now.Add(-time.Hour)})
+	*now = now.Add(10 * time.Hour)
+
+	n, err := store.Prune(context.Background())
+	if err != nil || n != 1 {
+		t.Fatalf("Prune() = %d, %v; want 1", n, err)
+	}
+	recordings, _ := store.Query(context.Background(), Filter{})
+	if len(recordings) != 2 {
+		t.Errorf("got %d recordings after pruning, want the recent and in-progress games", len(recordings))
+	}
+	if len(old) != 1 {
+		t.Fatalf("got %d old recordings, want 1", len(old))
+	}
+	if _, err := os.Stat(store.File(&old[0])); !os.IsNotExist(err) {
+		t.Errorf("file of the expired recording still exists: %v", err)
+	}
+	rec.Close()
+}
+
+func TestRecorder_SkipsMenusAndIdleStretches(t *testing.T) {
+	store, now := newTestStoreConfig(t, Config{RedactLogin: true, MaxIdle: "5s", PauseOn: []string{"dgamelaunch"}})
+	if got := store.PauseOn(); len(got) != 1 || got[0] != "dgamelaunch" {
+		t.Errorf("PauseOn = %v", got)
+	}
+	rec := store.NewRecorder("alice@nao", "alice")
+
+	// Skipped output still completes the login, but starts no recording
+	rec.Skip([]byte("Logged in as: alice"))
+	*now = now.Add(time.Hour)
+	rec.Skip([]byte("p) Play NetHack"))
+	if size, _ := rec.Size(); size != 0 {
+		t.Fatalf("Size = %d after skipped output, want 0", size)
+	}
+
+	rec.Write([]byte("a"))
+	*now = now.Add(2 * time.Second)
+	rec.Write([]byte("b"))
+	*now = now.Add(time.Hour) // idle
+	rec.Write([]byte("c"))
+	*now = now.Add(time.Second)
+	rec.Skip([]byte("menu"))
+	*now = now.Add(10 * time.Minute)
+	rec.Write([]byte("d"))
+	rec.Close()
+
+	recordings, _ := store.Query(context.Background(), Filter{})
+	if len(recordings) != 1 {
+		t.Fatalf("got %d recordings, want 1", len(recordings))
+	}
+	frames, err := readFrames(store, &recordings[0])
+	if err != nil || len(frames) != 4 {
+		t.Fatalf("frames = %+v, %v", frames, err)
+	}
+	want := []time.Duration{0, 2 * time.Second, 7 * time.Second, 7 * time.Second}
+	for i, frame := range frames {
+		if got := frame.Time.Sub(frames[0].Time); got != want[i] {
+			t.Errorf("frame %d (%q) at %s, want %s", i, frame.Data, got, want[i])
+		}
+	}
+}
+
+func TestRecorder_WriteScreen_RedrawsAfterSkip(t *testing.T) {
+	store, _ := newTestStoreConfig(t, Config{PauseOn: []string{"dgamelaunch"}})
+	rec := store.NewRecorder("alice@nao", "alice")
+	redraw := func() []byte { return []byte("<screen>") }
+
+	rec.WriteScreen([]byte("a"), redraw)
+	rec.Skip([]byte("\x1b(0menu"))
+	rec.WriteScreen([]byte("b"), redraw)
+	rec.WriteScreen([]byte("c"), redraw)
+	rec.Close()
+
+	recordings, _ := store.Query(context.Background(), Filter{})
+	if len(recordings) != 1 {
+		t.Fatalf("got %d recordings, want 1", len(recordings))
+	}
+	frames, err := readFrames(store, &recordings[0])
+	if err != nil || len(frames) != 3 {
+		t.Fatalf("frames = %+v, %v", frames, err)
+	}
+	for i, want := range []string{"a", "<screen>", "c"} {
+		if string(frames[i].Data) != want {
+			t.Errorf("frame %d = %q, want %q", i, frames[i].Data, want)
+		}
+	}
+}
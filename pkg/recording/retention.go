@@ -0,0 +1,75 @@
+package recording
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// retentionInterval is how often expired recordings are deleted
+const retentionInterval = time.Hour
+
+// Prune deletes the files and index entries of recordings that ended longer
+// ago than the retention window and returns how many were deleted.
+// Recordings in progress are never deleted.
+func (s *Store) Prune(ctx context.Context) (int, error) {
+	if s.retention <= 0 {
+		return 0, nil
+	}
+
+	cutoff := s.now().Add(-s.retention).UnixMilli()
+	rows, err := s.db.QueryContext(ctx,
+		"SELECT id, path FROM recordings WHERE ended_at IS NOT NULL AND ended_at < ?", cutoff)
+	if err != nil {
+		return 0, fmt.Errorf("recording: query failed: %w", err)
+	}
+	type expired struct {
+		id   int64
+		path string
+	}
+	var victims []expired
+	for rows.Next() {
+		var v expired
+		if err := rows.Scan(&v.id, &v.path); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("recording: failed to read index: %w", err)
+		}
+		victims = append(victims, v)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("recording: query failed: %w", err)
+	}
+
+	for i, v := range victims {
+		if err := s.remove(v.id, v.path); err != nil {
+			return i, err
+		}
+	}
+	return len(victims), nil
+}
+
+// RunRetention prunes expired recordings now and every hour until ctx is
+// cancelled. It returns at once when no retention window is configured.
+func (s *Store) RunRetention(ctx context.Context) {
+	if s.retention <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(retentionInterval)
+	defer ticker.Stop()
+	for {
+		if n, err := s.Prune(ctx); err != nil {
+			slog.Error("recording: retention failed", "error", err)
+		} else if n > 0 {
+			slog.Info("recording: deleted expired recordings", "count", n, "retention", s.retention)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
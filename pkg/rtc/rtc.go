@@ -0,0 +1,234 @@
+// Package rtc answers WebRTC offers from web clients with pion/webrtc,
+// serving each client over the data channel it opens. It implements
+// webui.RTCNegotiator for the experimental WebRTC transport.
+package rtc
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/opd-ai/go-gamelaunch-www/pkg/transport"
+	"github.com/opd-ai/go-gamelaunch-www/pkg/webui"
+	"github.com/pion/webrtc/v4"
+)
+
+// Negotiator defaults
+const (
+	// openTimeout bounds how long after the answer the client may take to
+	// open its data channel
+	openTimeout = 30 * time.Second
+
+	// maxBufferedAmount is how many bytes Write queues on a data channel
+	// before waiting for the client to catch up
+	maxBufferedAmount = 1 << 20
+)
+
+// Config configures ICE for the peer connections
+type Config struct {
+	// STUN and TURN servers, needed when the server is behind NAT
+	ICEServers []ICEServer `yaml:"ice_servers,omitempty"`
+
+	// Addresses announced instead of the host's own, for servers behind
+	// 1:1 NAT such as cloud instances with a public IP
+	PublicIPs []string `yaml:"public_ips,omitempty"`
+
+	// UDP port range for ICE, e.g. to open in a firewall; default any
+	PortMin uint16 `yaml:"port_min,omitempty"`
+	PortMax uint16 `yaml:"port_max,omitempty"`
+}
+
+// ICEServer is a STUN or TURN server
+type ICEServer struct {
+	URLs       []string `yaml:"urls"` // e.g. "stun:stun.l.google.com:19302"
+	Username   string   `yaml:"username,omitempty"`
+	Credential string   `yaml:"credential,omitempty"`
+}
+
+// Negotiator answers WebRTC offers. It implements webui.RTCNegotiator.
+type Negotiator struct {
+	api    *webrtc.API
+	config webrtc.Configuration
+}
+
+var _ webui.RTCNegotiator = (*Negotiator)(nil)
+
+// New validates cfg and creates a negotiator
+func New(cfg Config) (*Negotiator, error) {
+	var settings webrtc.SettingEngine
+	if cfg.PortMin != 0 || cfg.PortMax != 0 {
+		if err := settings.SetEphemeralUDPPortRange(cfg.PortMin, cfg.PortMax); err != nil {
+			return nil, fmt.Errorf("webrtc: invalid port range %d-%d: %w", cfg.PortMin, cfg.PortMax, err)
+		}
+	}
+	if len(cfg.PublicIPs) > 0 {
+		for _, ip := range cfg.PublicIPs {
+			if net.ParseIP(ip) == nil {
+				return nil, fmt.Errorf("webrtc: invalid public IP %q", ip)
+			}
+		}
+		rule := webrtc.ICEAddressRewriteRule{External: cfg.PublicIPs, AsCandidateType: webrtc.ICECandidateTypeHost}
+		if err := settings.SetICEAddressRewriteRules(rule); err != nil {
+			return nil, fmt.Errorf("webrtc: %w", err)
+		}
+	}
+
+	n := &Negotiator{api: webrtc.NewAPI(webrtc.WithSettingEngine(settings))}
+	for _, server := range cfg.ICEServers {
+		n.config.ICEServers = append(n.config.ICEServers, webrtc.ICEServer{
+			URLs:       server.URLs,
+			Username:   server.Username,
+			Credential: server.Credential,
+		})
+	}
+
+	// Creating a peer connection validates the ICE servers
+	pc, err := n.api.NewPeerConnection(n.config)
+	if err != nil {
+		return nil, fmt.Errorf("webrtc: %w", err)
+	}
+	pc.Close()
+	return n, nil
+}
+
+// Answer implements webui.RTCNegotiator. It gathers every candidate before
+// answering, as the client sends its offer in a single request.
+func (n *Negotiator) Answer(ctx context.Context, offer webui.RTCSessionDescription) (webui.RTCSessionDescription, transport.MessageConn, error) {
+	pc, err := n.api.NewPeerConnection(n.config)
+	if err != nil {
+		return webui.RTCSessionDescription{}, nil, err
+	}
+	conn := newChannelConn(pc)
+
+	if err := pc.SetRemoteDescription(webrtc.SessionDescription{Type: webrtc.SDPTypeOffer, SDP: offer.SDP}); err != nil {
+		conn.Close()
+		return webui.RTCSessionDescription{}, nil, fmt.Errorf("invalid offer: %w", err)
+	}
+	answer, err := pc.CreateAnswer(nil)
+	if err != nil {
+		conn.Close()
+		return webui.RTCSessionDescription{}, nil, err
+	}
+	gathered := webrtc.GatheringCompletePromise(pc)
+	if err := pc.SetLocalDescription(answer); err != nil {
+		conn.Close()
+		return webui.RTCSessionDescription{}, nil, err
+	}
+	select {
+	case <-gathered:
+	case <-ctx.Done():
+		conn.Close()
+		return webui.RTCSessionDescription{}, nil, ctx.Err()
+	}
+
+	// A client that never opens its channel does not keep a slot
+	time.AfterFunc(openTimeout, func() {
+		select {
+		case <-conn.open:
+		default:
+			conn.Close()
+		}
+	})
+	return webui.RTCSessionDescription{Type: "answer", SDP: pc.LocalDescription().SDP}, conn, nil
+}
+
+// channelConn is the data channel a client opens on its peer connection,
+// carrying the same text messages as a WebSocket
+type channelConn struct {
+	pc       *webrtc.PeerConnection
+	attached atomic.Bool   // a channel was offered; later ones are refused
+	open     chan struct{} // closed once channel is open
+	channel  *webrtc.DataChannel
+	messages chan []byte
+	drained  chan struct{} // signalled when the send buffer runs low
+	done     chan struct{} // closed by Close
+	once     sync.Once
+}
+
+// newChannelConn serves the first data channel the client opens on pc
+func newChannelConn(pc *webrtc.PeerConnection) *channelConn {
+	c := &channelConn{
+		pc:       pc,
+		open:     make(chan struct{}),
+		messages: make(chan []byte),
+		drained:  make(chan struct{}, 1),
+		done:     make(chan struct{}),
+	}
+	pc.OnDataChannel(func(dc *webrtc.DataChannel) {
+		if !c.attached.CompareAndSwap(false, true) {
+			dc.Close()
+			return
+		}
+		dc.SetBufferedAmountLowThreshold(maxBufferedAmount / 2)
+		dc.OnBufferedAmountLow(func() {
+			select {
+			case c.drained <- struct{}{}:
+			default:
+			}
+		})
+		dc.OnOpen(func() {
+			c.channel = dc
+			close(c.open)
+		})
+		dc.OnMessage(func(msg webrtc.DataChannelMessage) {
+			select {
+			case c.messages <- msg.Data:
+			case <-c.done:
+			}
+		})
+		dc.OnClose(func() { go c.Close() })
+	})
+	pc.OnConnectionStateChange(func(state webrtc.PeerConnectionState) {
+		if state == webrtc.PeerConnectionStateFailed || state == webrtc.PeerConnectionStateClosed {
+			go c.Close()
+		}
+	})
+	return c
+}
+
+// Read implements transport.MessageConn
+func (c *channelConn) Read(ctx context.Context) ([]byte, error) {
+	select {
+	case data := <-c.messages:
+		return data, nil
+	case <-c.done:
+		return nil, net.ErrClosed
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Write implements transport.MessageConn, waiting while the client is more
+// than maxBufferedAmount behind
+func (c *channelConn) Write(ctx context.Context, data []byte) error {
+	select {
+	case <-c.open:
+	case <-c.done:
+		return net.ErrClosed
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	for c.channel.BufferedAmount() > maxBufferedAmount {
+		select {
+		case <-c.drained:
+		case <-c.done:
+			return net.ErrClosed
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return c.channel.SendText(string(data))
+}
+
+// Close implements transport.MessageConn, closing the peer connection
+func (c *channelConn) Close() error {
+	var err error
+	c.once.Do(func() {
+		close(c.done)
+		err = c.pc.Close()
+	})
+	return err
+}
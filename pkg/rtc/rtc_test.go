@@ -0,0 +1,117 @@
+package rtc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/opd-ai/go-gamelaunch-www/pkg/webui"
+	"github.com/pion/webrtc/v4"
+)
+
+// newTestClient returns a peer connection with an open data channel to a
+// client served by n, the channel's incoming messages and the server's end
+func newTestClient(t *testing.T, n *Negotiator) (*webrtc.PeerConnection, <-chan string, *channelConn) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	client, err := webrtc.NewPeerConnection(webrtc.Configuration{})
+	if err != nil {
+		t.Fatalf("NewPeerConnection failed: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+	dc, err := client.CreateDataChannel("dgconnect", nil)
+	if err != nil {
+		t.Fatalf("CreateDataChannel failed: %v", err)
+	}
+	opened := make(chan struct{})
+	dc.OnOpen(func() { close(opened) })
+	received := make(chan string, 8)
+	dc.OnMessage(func(msg webrtc.DataChannelMessage) { received <- string(msg.Data) })
+
+	offer, err := client.CreateOffer(nil)
+	if err != nil {
+		t.Fatalf("CreateOffer failed: %v", err)
+	}
+	gathered := webrtc.GatheringCompletePromise(client)
+	if err := client.SetLocalDescription(offer); err != nil {
+		t.Fatalf("SetLocalDescription failed: %v", err)
+	}
+	<-gathered
+
+	answer, conn, err := n.Answer(ctx, webui.RTCSessionDescription{Type: "offer", SDP: client.LocalDescription().SDP})
+	if err != nil {
+		t.Fatalf("Answer failed: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	if answer.Type != "answer" {
+		t.Fatalf("answer type = %q", answer.Type)
+	}
+	if err := client.SetRemoteDescription(webrtc.SessionDescription{Type: webrtc.SDPTypeAnswer, SDP: answer.SDP}); err != nil {
+		t.Fatalf("SetRemoteDescription failed: %v", err)
+	}
+	select {
+	case <-opened:
+	case <-ctx.Done():
+		t.Fatal("data channel not opened")
+	}
+	go func() {
+		<-opened
+		dc.SendText("hello server")
+	}()
+	return client, received, conn.(*channelConn)
+}
+
+func TestNegotiator_Answer_ServesDataChannel(t *testing.T) {
+	n, err := New(Config{})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	client, received, conn := newTestClient(t, n)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if data, err := conn.Read(ctx); err != nil || string(data) != "hello server" {
+		t.Fatalf("Read = %q, %v", data, err)
+	}
+	if err := conn.Write(ctx, []byte(`{"type":"state"}`)); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	select {
+	case msg := <-received:
+		if msg != `{"type":"state"}` {
+			t.Errorf("client received %q", msg)
+		}
+	case <-ctx.Done():
+		t.Fatal("client received nothing")
+	}
+
+	// Reads fail once the client goes away
+	client.Close()
+	if _, err := conn.Read(ctx); err == nil || ctx.Err() != nil {
+		t.Errorf("Read after the client closed = %v, want the connection closed", err)
+	}
+}
+
+func TestNegotiator_Answer_RejectsInvalidOffer(t *testing.T) {
+	n, err := New(Config{})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	if _, _, err := n.Answer(context.Background(), webui.RTCSessionDescription{Type: "offer", SDP: "v=0"}); err == nil {
+		t.Error("Answer accepted an invalid offer")
+	}
+}
+
+func TestNew_RejectsInvalidConfig(t *testing.T) {
+	for name, cfg := range map[string]Config{
+		"port range": {PortMin: 6000, PortMax: 5000},
+		"public IP":  {PublicIPs: []string{"example.com"}},
+		"ICE server": {ICEServers: []ICEServer{{URLs: []string{"http://stun.example.com"}}}},
+	} {
+		if _, err := New(cfg); err == nil {
+			t.Errorf("%s: New accepted %+v", name, cfg)
+		}
+	}
+}
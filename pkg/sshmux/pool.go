@@ -0,0 +1,176 @@
+// Package sshmux shares one SSH transport among the users of a process that
+// connect to the same server as the same user, opening a channel for each
+// instead of a TCP connection and handshake. Popular public servers limit
+// connections per address, so this keeps dump fetches and other side
+// channels from competing with game sessions for them.
+package sshmux
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// Pool keeps one SSH transport per user and server address. Transports are
+// keyed by user and address only: the config of whoever connects first
+// authenticates the transport every later lease shares.
+type Pool struct {
+	idle time.Duration
+
+	mu         sync.Mutex
+	transports map[string]*sharedTransport
+}
+
+// sharedTransport is an SSH client and the number of leases on it
+type sharedTransport struct {
+	ready  chan struct{} // closed once dialing finished
+	client *ssh.Client
+	err    error
+	refs   int
+	timer  *time.Timer // closes the unused transport, or nil
+}
+
+// Lease is a share of a pooled transport. Open channels on Client and call
+// Release when done; the transport stays open while it has leases.
+type Lease struct {
+	pool      *Pool
+	key       string
+	transport *sharedTransport
+	once      sync.Once
+}
+
+// NewPool creates a pool that closes transports once they have been unused
+// for idle, or immediately when idle is zero
+func NewPool(idle time.Duration) *Pool {
+	return &Pool{idle: idle, transports: make(map[string]*sharedTransport)}
+}
+
+// Acquire leases the transport to addr (host:port) for config.User, dialing
+// it unless the pool already has one
+func (p *Pool) Acquire(ctx context.Context, addr string, config *ssh.ClientConfig) (*Lease, error) {
+	key := config.User + "@" + addr
+
+	p.mu.Lock()
+	t, ok := p.transports[key]
+	if !ok {
+		t = &sharedTransport{ready: make(chan struct{})}
+		p.transports[key] = t
+	}
+	t.refs++
+	if t.timer != nil {
+		t.timer.Stop()
+		t.timer = nil
+	}
+	p.mu.Unlock()
+
+	if !ok {
+		client, err := dial(ctx, addr, config)
+		p.mu.Lock()
+		t.client, t.err = client, err
+		if err != nil && p.transports[key] == t {
+			delete(p.transports, key)
+		}
+		p.mu.Unlock()
+		close(t.ready)
+		if err == nil {
+			go p.watch(key, t)
+		}
+	}
+
+	select {
+	case <-t.ready:
+	case <-ctx.Done():
+		p.release(key, t)
+		return nil, ctx.Err()
+	}
+	if t.err != nil {
+		return nil, t.err
+	}
+	return &Lease{pool: p, key: key, transport: t}, nil
+}
+
+// Close closes every transport; outstanding leases fail on their next use
+func (p *Pool) Close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for key, t := range p.transports {
+		if t.timer != nil {
+			t.timer.Stop()
+		}
+		if t.client != nil {
+			t.client.Close()
+		}
+		delete(p.transports, key)
+	}
+}
+
+// release drops a lease on t, closing t when unused for the idle period
+func (p *Pool) release(key string, t *sharedTransport) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	t.refs--
+	if t.refs > 0 || t.client == nil {
+		return
+	}
+	if p.idle <= 0 {
+		p.closeLocked(key, t)
+		return
+	}
+	t.timer = time.AfterFunc(p.idle, func() {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		if t.refs == 0 {
+			p.closeLocked(key, t)
+		}
+	})
+}
+
+// closeLocked closes t and forgets it unless it was replaced; p.mu must be
+// held
+func (p *Pool) closeLocked(key string, t *sharedTransport) {
+	if p.transports[key] == t {
+		delete(p.transports, key)
+	}
+	t.client.Close()
+}
+
+// watch forgets t once its connection ends, so the next Acquire dials again
+func (p *Pool) watch(key string, t *sharedTransport) {
+	t.client.Wait()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.transports[key] == t {
+		delete(p.transports, key)
+	}
+}
+
+// dial connects and authenticates to addr
+func dial(ctx context.Context, addr string, config *ssh.ClientConfig) (*ssh.Client, error) {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect: %w", err)
+	}
+	sshConn, chans, reqs, err := ssh.NewClientConn(conn, addr, config)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("ssh handshake failed: %w", err)
+	}
+	return ssh.NewClient(sshConn, chans, reqs), nil
+}
+
+// Client returns the shared SSH client
+func (l *Lease) Client() *ssh.Client {
+	return l.transport.client
+}
+
+// Release returns the lease to the pool; later calls do nothing
+func (l *Lease) Release() {
+	l.once.Do(func() { l.pool.release(l.key, l.transport) })
+}
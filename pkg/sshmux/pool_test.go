@@ -0,0 +1,208 @@
+package sshmux
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"net"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// testServer accepts SSH connections with any password. Its sessions record
+// their requests and answer a shell request with "hello".
+type testServer struct {
+	addr  string
+	conns atomic.Int32
+
+	mu       sync.Mutex
+	requests []string // type, or NAME=VALUE for env requests
+}
+
+// newTestServer listens on a local port until the test ends
+func newTestServer(t *testing.T) *testServer {
+	t.Helper()
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey failed: %v", err)
+	}
+	signer, err := ssh.NewSignerFromKey(priv)
+	if err != nil {
+		t.Fatalf("NewSignerFromKey failed: %v", err)
+	}
+	config := &ssh.ServerConfig{
+		PasswordCallback: func(ssh.ConnMetadata, []byte) (*ssh.Permissions, error) { return nil, nil },
+	}
+	config.AddHostKey(signer)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	s := &testServer{addr: listener.Addr().String()}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			s.conns.Add(1)
+			go func() {
+				_, chans, reqs, err := ssh.NewServerConn(conn, config)
+				if err != nil {
+					return
+				}
+				go ssh.DiscardRequests(reqs)
+				for newChannel := range chans {
+					channel, requests, err := newChannel.Accept()
+					if err != nil {
+						continue
+					}
+					go s.serveSession(channel, requests)
+				}
+			}()
+		}
+	}()
+	return s
+}
+
+// serveSession records the requests of a session channel
+func (s *testServer) serveSession(channel ssh.Channel, requests <-chan *ssh.Request) {
+	defer channel.Close()
+	for req := range requests {
+		name := req.Type
+		if req.Type == "env" {
+			var env envRequest
+			if err := ssh.Unmarshal(req.Payload, &env); err == nil {
+				name = env.Name + "=" + env.Value
+			}
+		}
+		s.mu.Lock()
+		s.requests = append(s.requests, name)
+		s.mu.Unlock()
+		req.Reply(true, nil)
+
+		if req.Type == "shell" {
+			channel.Write([]byte("hello\n"))
+			channel.SendRequest("exit-status", false, ssh.Marshal(struct{ Status uint32 }{0}))
+			return
+		}
+	}
+}
+
+// clientConfig authenticates as user with a password
+func clientConfig(user string) *ssh.ClientConfig {
+	return &ssh.ClientConfig{
+		User:            user,
+		Auth:            []ssh.AuthMethod{ssh.Password("secret")},
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+		Timeout:         5 * time.Second,
+	}
+}
+
+func TestPool_Acquire_SharesTransport(t *testing.T) {
+	server := newTestServer(t)
+	pool := NewPool(0)
+	defer pool.Close()
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	leases := make([]*Lease, 4)
+	for i := range leases {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			lease, err := pool.Acquire(ctx, server.addr, clientConfig("alice"))
+			if err != nil {
+				t.Errorf("Acquire failed: %v", err)
+				return
+			}
+			leases[i] = lease
+		}(i)
+	}
+	wg.Wait()
+	if t.Failed() {
+		t.FailNow()
+	}
+
+	for _, lease := range leases {
+		session, err := lease.Client().NewSession()
+		if err != nil {
+			t.Fatalf("NewSession failed: %v", err)
+		}
+		session.Close()
+	}
+	if n := server.conns.Load(); n != 1 {
+		t.Errorf("connections = %d, want 1 shared by all leases", n)
+	}
+
+	// Another user gets a transport of its own
+	bob, err := pool.Acquire(ctx, server.addr, clientConfig("bob"))
+	if err != nil {
+		t.Fatalf("Acquire failed: %v", err)
+	}
+	bob.Release()
+	if n := server.conns.Load(); n != 2 {
+		t.Errorf("connections = %d, want 2", n)
+	}
+
+	// The last release closes the transport; the next lease dials again
+	for _, lease := range leases {
+		lease.Release()
+		lease.Release()
+	}
+	pool.mu.Lock()
+	remaining := len(pool.transports)
+	pool.mu.Unlock()
+	if remaining != 0 {
+		t.Errorf("%d transports open after releasing every lease", remaining)
+	}
+	lease, err := pool.Acquire(ctx, server.addr, clientConfig("alice"))
+	if err != nil {
+		t.Fatalf("Acquire failed: %v", err)
+	}
+	lease.Release()
+	if n := server.conns.Load(); n != 3 {
+		t.Errorf("connections = %d, want 3", n)
+	}
+}
+
+func TestPool_Release_KeepsIdleTransport(t *testing.T) {
+	server := newTestServer(t)
+	pool := NewPool(time.Minute)
+	defer pool.Close()
+
+	for i := 0; i < 3; i++ {
+		lease, err := pool.Acquire(context.Background(), server.addr, clientConfig("alice"))
+		if err != nil {
+			t.Fatalf("Acquire failed: %v", err)
+		}
+		lease.Release()
+	}
+	if n := server.conns.Load(); n != 1 {
+		t.Errorf("connections = %d, want 1 reused while idle", n)
+	}
+}
+
+func TestPool_Acquire_DialError(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	addr := listener.Addr().String()
+	listener.Close()
+
+	pool := NewPool(0)
+	if _, err := pool.Acquire(context.Background(), addr, clientConfig("alice")); err == nil {
+		t.Fatal("Acquire of a closed port succeeded")
+	}
+	if len(pool.transports) != 0 {
+		t.Error("failed transport kept in the pool")
+	}
+}
@@ -0,0 +1,233 @@
+package sshmux
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"sort"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// Relay lets an SSH client that dials and handshakes on its own, such as
+// dgclient, run its sessions on a pooled transport. It serves an in-process
+// SSH endpoint, reachable only through the connection Dial returns, whose
+// session channels are opened on the transport, sending Env to the server
+// before each PTY request.
+type Relay struct {
+	Pool   *Pool
+	Addr   string            // host:port of the server
+	Config *ssh.ClientConfig // authenticates the pooled transport
+	Env    map[string]string // e.g. LANG; the server may refuse variables it does not accept
+}
+
+// envRequest is the payload of an "env" channel request (RFC 4254 6.4)
+type envRequest struct {
+	Name  string
+	Value string
+}
+
+// Dial leases the transport and returns the client end of a connection to
+// the relay, with the host key callback that accepts the relay's key. The
+// relay serves the connection until either side closes it.
+func (r *Relay) Dial(ctx context.Context) (net.Conn, ssh.HostKeyCallback, error) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to generate relay key: %w", err)
+	}
+	signer, err := ssh.NewSignerFromKey(key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to generate relay key: %w", err)
+	}
+	config := &ssh.ServerConfig{NoClientAuth: true}
+	config.AddHostKey(signer)
+
+	lease, err := r.Pool.Acquire(ctx, r.Addr, r.Config)
+	if err != nil {
+		return nil, nil, err
+	}
+	local, remote := pipe()
+
+	go func() {
+		defer lease.Release()
+		conn, chans, reqs, err := ssh.NewServerConn(remote, config)
+		if err != nil {
+			remote.Close()
+			return
+		}
+		defer conn.Close()
+		go ssh.DiscardRequests(reqs)
+		for newChannel := range chans {
+			go r.forward(lease.Client(), newChannel)
+		}
+	}()
+	return local, ssh.FixedHostKey(signer.PublicKey()), nil
+}
+
+// pipe returns both ends of an in-memory connection, which nothing outside
+// the process can reach. Writes to the relay's end are queued, as a socket
+// would buffer them, since on a bare net.Pipe SSH deadlocks: both sides
+// send their version before reading the other's.
+func pipe() (client, relay net.Conn) {
+	client, server := net.Pipe()
+	relay = newQueuedConn(server)
+	return client, relay
+}
+
+// queuedConn sends its writes from a goroutine so that they never wait for
+// the peer to read
+type queuedConn struct {
+	net.Conn
+	mu      sync.Mutex
+	cond    sync.Cond
+	queue   [][]byte
+	closing bool
+	err     error // of the last write, after which nothing is sent
+}
+
+// newQueuedConn queues the writes to conn
+func newQueuedConn(conn net.Conn) *queuedConn {
+	c := &queuedConn{Conn: conn}
+	c.cond.L = &c.mu
+	go c.flush()
+	return c
+}
+
+// Write queues a copy of p
+func (c *queuedConn) Write(p []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	switch {
+	case c.err != nil:
+		return 0, c.err
+	case c.closing:
+		return 0, net.ErrClosed
+	}
+	c.queue = append(c.queue, append([]byte(nil), p...))
+	c.cond.Signal()
+	return len(p), nil
+}
+
+// Close sends what is queued, giving the peer a second to read it, and then
+// closes the connection
+func (c *queuedConn) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.closing {
+		c.closing = true
+		c.Conn.SetWriteDeadline(time.Now().Add(time.Second))
+		c.cond.Signal()
+	}
+	return nil
+}
+
+// flush writes the queue to the connection in order until it is closed
+func (c *queuedConn) flush() {
+	defer c.Conn.Close()
+	for {
+		c.mu.Lock()
+		for len(c.queue) == 0 && !c.closing {
+			c.cond.Wait()
+		}
+		if len(c.queue) == 0 {
+			c.mu.Unlock()
+			return
+		}
+		p := c.queue[0]
+		c.queue = c.queue[1:]
+		c.mu.Unlock()
+
+		if _, err := c.Conn.Write(p); err != nil {
+			c.mu.Lock()
+			c.err, c.queue = err, nil
+			c.mu.Unlock()
+			return
+		}
+	}
+}
+
+// forward opens newChannel on the server and copies data and requests both
+// ways until the server closes it
+func (r *Relay) forward(client *ssh.Client, newChannel ssh.NewChannel) {
+	if newChannel.ChannelType() != "session" {
+		newChannel.Reject(ssh.UnknownChannelType, "only sessions are relayed")
+		return
+	}
+	upstream, upstreamReqs, err := client.OpenChannel("session", newChannel.ExtraData())
+	if err != nil {
+		newChannel.Reject(ssh.ConnectionFailed, err.Error())
+		return
+	}
+	defer upstream.Close()
+	downstream, downstreamReqs, err := newChannel.Accept()
+	if err != nil {
+		return
+	}
+	defer downstream.Close()
+
+	// Requests from the server, such as exit-status, follow its output, and
+	// the replies to the client's requests precede the channel's close
+	var replies sync.WaitGroup
+	var inFlight sync.Mutex
+	replies.Add(1)
+	go func() {
+		defer replies.Done()
+		relayRequests(upstreamReqs, downstream, nil, nil)
+	}()
+	go relayRequests(downstreamReqs, upstream, &inFlight, func(req *ssh.Request) {
+		if req.Type == "pty-req" {
+			r.sendEnv(upstream)
+		}
+	})
+
+	go func() {
+		io.Copy(upstream, downstream)
+		upstream.CloseWrite()
+	}()
+	go io.Copy(downstream.Stderr(), upstream.Stderr())
+	io.Copy(downstream, upstream)
+	replies.Wait()
+	inFlight.Lock()
+	inFlight.Unlock()
+}
+
+// sendEnv sets Env on the server's session, in name order
+func (r *Relay) sendEnv(channel ssh.Channel) {
+	names := make([]string, 0, len(r.Env))
+	for name := range r.Env {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		ok, err := channel.SendRequest("env", true, ssh.Marshal(envRequest{Name: name, Value: r.Env[name]}))
+		if err != nil || !ok {
+			slog.Warn("sshmux: server refused environment variable", "name", name, "error", err)
+		}
+	}
+}
+
+// relayRequests sends each request to channel and relays its reply, calling
+// before first when set, and holding inFlight, when set, until it replied
+func relayRequests(reqs <-chan *ssh.Request, channel ssh.Channel, inFlight *sync.Mutex, before func(*ssh.Request)) {
+	for req := range reqs {
+		if inFlight != nil {
+			inFlight.Lock()
+		}
+		if before != nil {
+			before(req)
+		}
+		ok, err := channel.SendRequest(req.Type, req.WantReply, req.Payload)
+		if req.WantReply {
+			req.Reply(ok && err == nil, nil)
+		}
+		if inFlight != nil {
+			inFlight.Unlock()
+		}
+	}
+}
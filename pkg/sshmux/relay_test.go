@@ -0,0 +1,91 @@
+package sshmux
+
+import (
+	"context"
+	"io"
+	"reflect"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+)
+
+func TestRelay_Dial_SendsEnvBeforePTY(t *testing.T) {
+	server := newTestServer(t)
+	pool := NewPool(0)
+	defer pool.Close()
+	relay := &Relay{
+		Pool:   pool,
+		Addr:   server.addr,
+		Config: clientConfig("alice"),
+		Env:    map[string]string{"LANG": "en_US.UTF-8", "NETHACKOPTIONS": "number_pad"},
+	}
+
+	conn, hostKeyCallback, err := relay.Dial(context.Background())
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	// The relay accepts any client; the pooled transport authenticated
+	sshConn, chans, reqs, err := ssh.NewClientConn(conn, "relay", &ssh.ClientConfig{User: "alice", HostKeyCallback: hostKeyCallback})
+	if err != nil {
+		t.Fatalf("handshake with the relay failed: %v", err)
+	}
+	client := ssh.NewClient(sshConn, chans, reqs)
+	defer client.Close()
+
+	session, err := client.NewSession()
+	if err != nil {
+		t.Fatalf("NewSession failed: %v", err)
+	}
+	if err := session.RequestPty("screen-256color", 24, 80, ssh.TerminalModes{}); err != nil {
+		t.Fatalf("RequestPty failed: %v", err)
+	}
+	stdout, err := session.StdoutPipe()
+	if err != nil {
+		t.Fatalf("StdoutPipe failed: %v", err)
+	}
+	if err := session.Shell(); err != nil {
+		t.Fatalf("Shell failed: %v", err)
+	}
+	output, _ := io.ReadAll(stdout)
+	if err := session.Wait(); err != nil {
+		t.Errorf("Wait = %v, want exit status 0", err)
+	}
+	if string(output) != "hello\n" {
+		t.Errorf("output = %q, want the server's", output)
+	}
+
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	want := []string{"LANG=en_US.UTF-8", "NETHACKOPTIONS=number_pad", "pty-req", "shell"}
+	if !reflect.DeepEqual(server.requests, want) {
+		t.Errorf("server requests = %v, want %v", server.requests, want)
+	}
+	if n := server.conns.Load(); n != 1 {
+		t.Errorf("connections = %d, want 1", n)
+	}
+}
+
+func TestPipe_QueuesRelayWrites(t *testing.T) {
+	client, relay := pipe()
+	defer client.Close()
+
+	// Neither write waits for the client to read
+	for _, p := range []string{"SSH-2.0-relay\r\n", "queued"} {
+		if _, err := relay.Write([]byte(p)); err != nil {
+			t.Fatalf("Write failed: %v", err)
+		}
+	}
+	relay.Close()
+	if _, err := relay.Write([]byte("late")); err == nil {
+		t.Error("Write after Close succeeded")
+	}
+
+	// Close sends what was queued before ending the connection
+	data, err := io.ReadAll(client)
+	if err != nil {
+		t.Fatalf("ReadAll failed: %v", err)
+	}
+	if string(data) != "SSH-2.0-relay\r\nqueued" {
+		t.Errorf("client read %q", data)
+	}
+}
@@ -0,0 +1,407 @@
+// Package testutil provides a mock dgamelaunch server for development and
+// tests. It accepts any SSH login, plays a scripted sequence of terminal
+// output to each shell and records the input it receives, so the whole
+// pipeline from the SSH session to web clients can be exercised without a
+// real game host.
+package testutil
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// defaultExpectTimeout is how long a step waits for its expected input
+const defaultExpectTimeout = 5 * time.Second
+
+// Step is one exchange of a Script. The server waits Delay, writes Output,
+// and then, when Expect is set, waits for input containing it before moving
+// on to the next step.
+type Step struct {
+	Delay  time.Duration
+	Output string
+	Expect string
+}
+
+// Script is the sequence of steps played to every shell. Once it ends the
+// session stays open, echoing nothing, until the client leaves or Close is
+// called.
+type Script []Step
+
+// PTY is the terminal requested by a client
+type PTY struct {
+	Term   string
+	Width  int
+	Height int
+}
+
+// GameServer is a mock dgamelaunch SSH server on a local port
+type GameServer struct {
+	script        Script
+	listener      net.Listener
+	config        *ssh.ServerConfig
+	expectTimeout time.Duration
+
+	mu       sync.Mutex
+	changed  chan struct{} // closed and replaced whenever input or state changes
+	input    bytes.Buffer  // all input of all sessions
+	pty      PTY
+	sessions int
+	errs     []error
+	closed   bool
+	conns    map[net.Conn]struct{}
+	wg       sync.WaitGroup
+}
+
+// NewGameServer listens on a random local port and serves script to every
+// shell until Close
+func NewGameServer(script Script) (*GameServer, error) {
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("testutil: failed to generate host key: %w", err)
+	}
+	signer, err := ssh.NewSignerFromKey(priv)
+	if err != nil {
+		return nil, fmt.Errorf("testutil: failed to create host key: %w", err)
+	}
+	config := &ssh.ServerConfig{
+		PasswordCallback:  func(ssh.ConnMetadata, []byte) (*ssh.Permissions, error) { return nil, nil },
+		PublicKeyCallback: func(ssh.ConnMetadata, ssh.PublicKey) (*ssh.Permissions, error) { return nil, nil },
+		KeyboardInteractiveCallback: func(ssh.ConnMetadata, ssh.KeyboardInteractiveChallenge) (*ssh.Permissions, error) {
+			return nil, nil
+		},
+	}
+	config.AddHostKey(signer)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, fmt.Errorf("testutil: failed to listen: %w", err)
+	}
+
+	s := &GameServer{
+		script:        script,
+		listener:      listener,
+		config:        config,
+		expectTimeout: defaultExpectTimeout,
+		changed:       make(chan struct{}),
+		conns:         make(map[net.Conn]struct{}),
+	}
+	s.wg.Add(1)
+	go s.accept()
+	return s, nil
+}
+
+// StartGameServer is NewGameServer for tests: it fails t when the server
+// cannot start, closes it when the test ends, and then reports any script
+// step that did not receive its expected input
+func StartGameServer(t testing.TB, script Script) *GameServer {
+	t.Helper()
+	s, err := NewGameServer(script)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		s.Close()
+		for _, err := range s.Errors() {
+			t.Errorf("mock game server: %v", err)
+		}
+	})
+	return s
+}
+
+// SetExpectTimeout changes how long a step waits for its expected input
+// before the script gives up; the default is 5s
+func (s *GameServer) SetExpectTimeout(timeout time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.expectTimeout = timeout
+}
+
+// Addr returns the host:port the server listens on
+func (s *GameServer) Addr() string {
+	return s.listener.Addr().String()
+}
+
+// Host returns the host the server listens on
+func (s *GameServer) Host() string {
+	host, _, _ := net.SplitHostPort(s.Addr())
+	return host
+}
+
+// Port returns the port the server listens on
+func (s *GameServer) Port() int {
+	_, port, _ := net.SplitHostPort(s.Addr())
+	n, _ := strconv.Atoi(port)
+	return n
+}
+
+// Input returns all input received so far, from every session
+func (s *GameServer) Input() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.input.String()
+}
+
+// PTY returns the terminal most recently requested or resized by a client
+func (s *GameServer) PTY() PTY {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.pty
+}
+
+// Sessions returns the number of shells started so far
+func (s *GameServer) Sessions() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.sessions
+}
+
+// Errors returns the script steps that timed out waiting for their input
+func (s *GameServer) Errors() []error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]error(nil), s.errs...)
+}
+
+// WaitForInput waits until the input received contains want, or returns
+// ctx.Err() with what arrived so far
+func (s *GameServer) WaitForInput(ctx context.Context, want string) error {
+	for {
+		s.mu.Lock()
+		got, changed := s.input.String(), s.changed
+		s.mu.Unlock()
+		if strings.Contains(got, want) {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("testutil: input %q does not contain %q: %w", got, want, ctx.Err())
+		case <-changed:
+		}
+	}
+}
+
+// Close stops the server and ends every session
+func (s *GameServer) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	for conn := range s.conns {
+		conn.Close()
+	}
+	s.notifyLocked()
+	s.mu.Unlock()
+
+	err := s.listener.Close()
+	s.wg.Wait()
+	return err
+}
+
+// notifyLocked wakes waiters of changed; s.mu must be held
+func (s *GameServer) notifyLocked() {
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
+
+// accept serves connections until the listener is closed
+func (s *GameServer) accept() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		if s.closed {
+			s.mu.Unlock()
+			conn.Close()
+			return
+		}
+		s.conns[conn] = struct{}{}
+		s.wg.Add(1)
+		s.mu.Unlock()
+		go s.serveConn(conn)
+	}
+}
+
+// serveConn runs the SSH handshake and serves the session channels of conn
+func (s *GameServer) serveConn(conn net.Conn) {
+	defer s.wg.Done()
+	defer func() {
+		s.mu.Lock()
+		delete(s.conns, conn)
+		s.mu.Unlock()
+		conn.Close()
+	}()
+
+	_, chans, reqs, err := ssh.NewServerConn(conn, s.config)
+	if err != nil {
+		return
+	}
+	go ssh.DiscardRequests(reqs)
+
+	var sessions sync.WaitGroup
+	for newChannel := range chans {
+		if newChannel.ChannelType() != "session" {
+			newChannel.Reject(ssh.UnknownChannelType, "only sessions are supported")
+			continue
+		}
+		channel, requests, err := newChannel.Accept()
+		if err != nil {
+			continue
+		}
+		sessions.Add(1)
+		go func() {
+			defer sessions.Done()
+			s.serveSession(channel, requests)
+		}()
+	}
+	sessions.Wait()
+}
+
+// serveSession answers the requests of a session channel and plays the
+// script once a shell is started
+func (s *GameServer) serveSession(channel ssh.Channel, requests <-chan *ssh.Request) {
+	defer channel.Close()
+
+	inputs := make(chan struct{}, 1)
+	done := make(chan struct{})
+	defer close(done)
+	for req := range requests {
+		switch req.Type {
+		case "pty-req":
+			s.setPTY(parsePTYRequest(req.Payload))
+			req.Reply(true, nil)
+		case "window-change":
+			s.resizePTY(req.Payload)
+			req.Reply(true, nil)
+		case "shell", "exec":
+			req.Reply(true, nil)
+			s.mu.Lock()
+			s.sessions++
+			s.mu.Unlock()
+			go s.readInput(channel, inputs)
+			go s.play(channel, inputs, done)
+		default:
+			req.Reply(req.Type == "env", nil)
+		}
+	}
+}
+
+// readInput records the input of a session, signaling inputs after each
+// read
+func (s *GameServer) readInput(channel ssh.Channel, inputs chan<- struct{}) {
+	buf := make([]byte, 1024)
+	for {
+		n, err := channel.Read(buf)
+		if n > 0 {
+			s.mu.Lock()
+			s.input.Write(buf[:n])
+			s.notifyLocked()
+			s.mu.Unlock()
+			select {
+			case inputs <- struct{}{}:
+			default:
+			}
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// play writes the script to channel, waiting for the expected input of each
+// step; it returns early once the session ends
+func (s *GameServer) play(channel ssh.Channel, inputs <-chan struct{}, done <-chan struct{}) {
+	s.mu.Lock()
+	start, timeout := s.input.Len(), s.expectTimeout
+	s.mu.Unlock()
+
+	for i, step := range s.script {
+		if step.Delay > 0 {
+			select {
+			case <-time.After(step.Delay):
+			case <-done:
+				return
+			}
+		}
+		if step.Output != "" {
+			if _, err := channel.Write([]byte(step.Output)); err != nil {
+				return
+			}
+		}
+		if step.Expect == "" {
+			continue
+		}
+
+		deadline := time.After(timeout)
+		for {
+			s.mu.Lock()
+			received := s.input.String()[start:]
+			s.mu.Unlock()
+			if at := strings.Index(received, step.Expect); at >= 0 {
+				start += at + len(step.Expect)
+				break
+			}
+			select {
+			case <-inputs:
+			case <-done:
+				return
+			case <-deadline:
+				s.mu.Lock()
+				s.errs = append(s.errs, fmt.Errorf("step %d: no input %q within %s (got %q)", i, step.Expect, timeout, received))
+				s.mu.Unlock()
+				return
+			}
+		}
+	}
+}
+
+// setPTY records the terminal of a pty-req
+func (s *GameServer) setPTY(pty PTY) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pty = pty
+	s.notifyLocked()
+}
+
+// resizePTY records the size of a window-change request
+func (s *GameServer) resizePTY(payload []byte) {
+	if len(payload) < 8 {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pty.Width = int(binary.BigEndian.Uint32(payload[0:4]))
+	s.pty.Height = int(binary.BigEndian.Uint32(payload[4:8]))
+	s.notifyLocked()
+}
+
+// parsePTYRequest decodes the terminal name and size of a pty-req payload
+func parsePTYRequest(payload []byte) PTY {
+	var req struct {
+		Term          string
+		Width, Height uint32
+		PixelWidth    uint32
+		PixelHeight   uint32
+		Modes         string
+	}
+	if err := ssh.Unmarshal(payload, &req); err != nil {
+		return PTY{}
+	}
+	return PTY{Term: req.Term, Width: int(req.Width), Height: int(req.Height)}
+}
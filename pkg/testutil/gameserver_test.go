@@ -0,0 +1,117 @@
+package testutil
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// dial opens a shell on s with a 100x30 terminal
+func dial(t *testing.T, s *GameServer) (stdin io.WriteCloser, stdout io.Reader) {
+	t.Helper()
+	client, err := ssh.Dial("tcp", s.Addr(), &ssh.ClientConfig{
+		User:            "tester",
+		Auth:            []ssh.AuthMethod{ssh.Password("anything")},
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+	})
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+
+	session, err := client.NewSession()
+	if err != nil {
+		t.Fatalf("NewSession failed: %v", err)
+	}
+	if err := session.RequestPty("xterm-256color", 30, 100, ssh.TerminalModes{}); err != nil {
+		t.Fatalf("RequestPty failed: %v", err)
+	}
+	if stdin, err = session.StdinPipe(); err != nil {
+		t.Fatalf("StdinPipe failed: %v", err)
+	}
+	if stdout, err = session.StdoutPipe(); err != nil {
+		t.Fatalf("StdoutPipe failed: %v", err)
+	}
+	if err := session.Shell(); err != nil {
+		t.Fatalf("Shell failed: %v", err)
+	}
+	return stdin, stdout
+}
+
+// readUntil reads from r until the output contains want
+func readUntil(t *testing.T, r io.Reader, want string) string {
+	t.Helper()
+	var got strings.Builder
+	buf := make([]byte, 256)
+	for !strings.Contains(got.String(), want) {
+		n, err := r.Read(buf)
+		got.Write(buf[:n])
+		if err != nil {
+			t.Fatalf("output %q ended before %q: %v", got.String(), want, err)
+		}
+	}
+	return got.String()
+}
+
+func TestGameServer_PlaysScriptAndRecordsInput(t *testing.T) {
+	s := StartGameServer(t, Script{
+		{Output: "dgamelaunch\r\n p) Play\r\n", Expect: "p"},
+		{Delay: 10 * time.Millisecond, Output: "Hello tester, welcome to NetHack!", Expect: "\x1b"},
+		{Output: "Really quit?"},
+	})
+
+	stdin, stdout := dial(t, s)
+	readUntil(t, stdout, "p) Play")
+	if pty := s.PTY(); pty.Term != "xterm-256color" || pty.Width != 100 || pty.Height != 30 {
+		t.Errorf("PTY = %+v", pty)
+	}
+
+	// The script waits for the expected input before going on
+	stdin.Write([]byte("p"))
+	readUntil(t, stdout, "welcome to NetHack!")
+	stdin.Write([]byte("hjkl\x1b"))
+	readUntil(t, stdout, "Really quit?")
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := s.WaitForInput(ctx, "hjkl"); err != nil {
+		t.Error(err)
+	}
+	if got := s.Input(); got != "phjkl\x1b" {
+		t.Errorf("Input = %q", got)
+	}
+	if s.Sessions() != 1 {
+		t.Errorf("Sessions = %d, want 1", s.Sessions())
+	}
+}
+
+func TestGameServer_ReportsMissingInput(t *testing.T) {
+	s, err := NewGameServer(Script{{Output: "What do you want to eat?", Expect: "y"}})
+	if err != nil {
+		t.Fatalf("NewGameServer failed: %v", err)
+	}
+	defer s.Close()
+	s.SetExpectTimeout(20 * time.Millisecond)
+
+	stdin, stdout := dial(t, s)
+	readUntil(t, stdout, "eat?")
+	stdin.Write([]byte("n"))
+
+	deadline := time.Now().Add(time.Second)
+	for len(s.Errors()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if errs := s.Errors(); len(errs) != 1 || !strings.Contains(errs[0].Error(), `"y"`) {
+		t.Errorf("Errors = %v", errs)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := s.WaitForInput(ctx, "y"); err == nil {
+		t.Error("WaitForInput succeeded without the input")
+	}
+}
@@ -0,0 +1,142 @@
+// Package transport provides slow-client detection for the WebSocket handler.
+package transport
+
+import "sort"
+
+// slowClientThreshold is the number of consecutive messages a client may
+// fail to accept before it is switched to keyframe-only delivery
+const slowClientThreshold = 3
+
+// ClientStats reports delivery statistics for a connected client
+type ClientStats struct {
+	ID           string `json:"id"`
+	IP           string `json:"ip"`
+	Protocol     int    `json:"protocol"`
+	Queued       int    `json:"queued"`
+	Skipped      uint64 `json:"skipped"`       // messages dropped or superseded
+	KeyframeOnly bool   `json:"keyframe_only"` // client only receives the latest state
+	Spectating   bool   `json:"spectating"`    // client is in spectator mode
+
+	BytesSent     uint64 `json:"bytes_sent"`
+	BytesReceived uint64 `json:"bytes_received"`
+
+	// CorrelationID appears in the client's log entries and errors: the
+	// X-Correlation-ID of its connection request, or else ID
+	CorrelationID string `json:"correlation_id"`
+}
+
+// deliver queues msg for the client. Clients that repeatedly fail to keep up
+// are switched to keyframe-only mode, in which state messages are coalesced
+// so that only the most recent one is written.
+func (c *Client) deliver(msg Message) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if msg.Type == MsgTypeState && c.keyframeOnly {
+		c.setPendingStateLocked(&msg)
+		return
+	}
+
+	select {
+	case c.send <- msg:
+		c.consecutiveSkips = 0
+		return
+	default:
+	}
+
+	c.skipped++
+	c.consecutiveSkips++
+	if c.consecutiveSkips >= slowClientThreshold {
+		c.keyframeOnly = true
+	}
+	if msg.Type == MsgTypeState && c.keyframeOnly {
+		c.setPendingStateLocked(&msg)
+	}
+}
+
+// setPendingStateLocked replaces the coalesced state and wakes the write
+// pump; c.mu must be held
+func (c *Client) setPendingStateLocked(msg *Message) {
+	if c.pendingState != nil {
+		c.skipped++
+	}
+	c.pendingState = msg
+
+	select {
+	case c.stateReady <- struct{}{}:
+	default:
+	}
+}
+
+// takePendingState returns and clears the coalesced state
+func (c *Client) takePendingState() *Message {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	msg := c.pendingState
+	c.pendingState = nil
+	return msg
+}
+
+// skipStale reports whether a queued message should be dropped because a
+// newer coalesced state will be written instead
+func (c *Client) skipStale(msg Message) bool {
+	if msg.Type != MsgTypeState {
+		return false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.keyframeOnly {
+		return false
+	}
+	c.skipped++
+	return true
+}
+
+// keyframeWritten leaves keyframe-only mode once the client has drained its
+// queue
+func (c *Client) keyframeWritten() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.send) == 0 && c.pendingState == nil {
+		c.keyframeOnly = false
+		c.consecutiveSkips = 0
+	}
+}
+
+// Stats returns the delivery statistics of the client
+func (c *Client) Stats() ClientStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return ClientStats{
+		ID:           c.id,
+		IP:           c.ip,
+		Protocol:     c.protocol,
+		Queued:       len(c.send),
+		Skipped:      c.skipped,
+		KeyframeOnly: c.keyframeOnly,
+		Spectating:   c.spectating,
+
+		BytesSent:     c.bytesSent,
+		BytesReceived: c.bytesReceived,
+		CorrelationID: c.corrID,
+	}
+}
+
+// ClientStats returns delivery statistics for all connected clients ordered
+// by client ID
+func (h *Handler) ClientStats() []ClientStats {
+	h.clientsMu.RLock()
+	stats := make([]ClientStats, 0, len(h.clients))
+	for _, client := range h.clients {
+		stats = append(stats, client.Stats())
+	}
+	h.clientsMu.RUnlock()
+
+	sort.Slice(stats, func(i, j int) bool { return stats[i].ID < stats[j].ID })
+	return stats
+}
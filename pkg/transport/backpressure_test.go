@@ -0,0 +1,146 @@
+package transport
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"nhooyr.io/websocket"
+)
+
+// newTestClient creates an unconnected client with a send queue of size n
+func newTestClient(id string, n int) *Client {
+	return &Client{id: id, send: make(chan Message, n), stateReady: make(chan struct{}, 1)}
+}
+
+func TestClient_Deliver_SwitchesSlowClientToKeyframes(t *testing.T) {
+	c := newTestClient("slow", 1)
+
+	for i := 0; i < slowClientThreshold+1; i++ {
+		c.deliver(Message{Type: MsgTypeState, Timestamp: int64(i)})
+	}
+
+	stats := c.Stats()
+	if !stats.KeyframeOnly {
+		t.Fatal("Expected client to be switched to keyframe-only mode")
+	}
+	if stats.Skipped != slowClientThreshold {
+		t.Errorf("Skipped = %d, want %d", stats.Skipped, slowClientThreshold)
+	}
+
+	// Further states are coalesced into the pending slot
+	c.deliver(Message{Type: MsgTypeState, Timestamp: 100})
+	c.deliver(Message{Type: MsgTypeState, Timestamp: 101})
+	pending := c.takePendingState()
+	if pending == nil || pending.Timestamp != 101 {
+		t.Errorf("Expected latest state pending, got %+v", pending)
+	}
+
+	// Queued states are now stale
+	if !c.skipStale(<-c.send) {
+		t.Error("Expected queued state to be skipped in keyframe-only mode")
+	}
+
+	// Draining the queue restores normal delivery
+	c.keyframeWritten()
+	if c.Stats().KeyframeOnly {
+		t.Error("Expected client to leave keyframe-only mode after catching up")
+	}
+}
+
+func TestClient_Deliver_ResetsOnSuccess(t *testing.T) {
+	c := newTestClient("flaky", 1)
+
+	c.deliver(Message{Type: MsgTypeState})
+	c.deliver(Message{Type: MsgTypeState}) // dropped
+	<-c.send
+	c.deliver(Message{Type: MsgTypeState})
+
+	stats := c.Stats()
+	if stats.KeyframeOnly {
+		t.Error("Expected a single drop not to trigger keyframe-only mode")
+	}
+	if stats.Skipped != 1 {
+		t.Errorf("Skipped = %d, want 1", stats.Skipped)
+	}
+}
+
+func TestClient_Deliver_KeepsSystemMessagesQueued(t *testing.T) {
+	c := newTestClient("slow", 4)
+	c.keyframeOnly = true
+
+	c.deliver(Message{Type: MsgTypeSystem})
+	if len(c.send) != 1 {
+		t.Error("Expected system message to be queued in keyframe-only mode")
+	}
+	if c.skipStale(<-c.send) {
+		t.Error("Expected system message not to be skipped")
+	}
+}
+
+func TestHandler_ClientStats(t *testing.T) {
+	h := NewHandler()
+	h.clients["b"] = newTestClient("b", 1)
+	h.clients["a"] = newTestClient("a", 1)
+
+	h.BroadcastSystem(&SystemPayload{Message: "one"})
+	h.BroadcastSystem(&SystemPayload{Message: "two"})
+
+	stats := h.ClientStats()
+	if len(stats) != 2 || stats[0].ID != "a" {
+		t.Fatalf("Unexpected stats %+v", stats)
+	}
+	for _, s := range stats {
+		if s.Queued != 1 || s.Skipped != 1 {
+			t.Errorf("client %s: queued=%d skipped=%d, want 1/1", s.ID, s.Queued, s.Skipped)
+		}
+	}
+}
+
+func TestHandler_Traffic_CountsBytesBothWays(t *testing.T) {
+	h := NewHandler()
+	inputs := make(chan string, 1)
+	h.SetInputHandler(func(clientID, input string) error {
+		inputs <- input
+		return nil
+	})
+	server := httptest.NewServer(h)
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	conn, _, err := websocket.Dial(ctx, "ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	defer conn.Close(websocket.StatusNormalClosure, "")
+
+	_, connectMsg, err := conn.Read(ctx)
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	payload, _ := json.Marshal(InputPayload{Input: "hjkl"})
+	input, _ := json.Marshal(Message{Type: MsgTypeInput, Payload: payload})
+	if err := conn.Write(ctx, websocket.MessageText, input); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	<-inputs
+
+	// The write pump counts a message once the write returns
+	sent, received := h.Traffic()
+	for deadline := time.Now().Add(time.Second); sent == 0 && time.Now().Before(deadline); {
+		time.Sleep(time.Millisecond)
+		sent, received = h.Traffic()
+	}
+	if sent != uint64(len(connectMsg)) || received != uint64(len(input)) {
+		t.Errorf("Traffic() = %d, %d; want %d, %d", sent, received, len(connectMsg), len(input))
+	}
+	stats := h.ClientStats()
+	if len(stats) != 1 || stats[0].BytesSent != sent || stats[0].BytesReceived != received {
+		t.Errorf("ClientStats() = %+v, want per-client traffic", stats)
+	}
+}
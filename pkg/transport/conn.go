@@ -0,0 +1,137 @@
+package transport
+
+import (
+	"context"
+	"net/http"
+	"sync"
+
+	"github.com/opd-ai/go-gamelaunch-www/pkg/i18n"
+	"nhooyr.io/websocket"
+)
+
+// MessageConn is a message-oriented connection to a client. WebSockets are
+// built in; other transports, such as WebRTC data channels, implement it to
+// carry the same protocol.
+type MessageConn interface {
+	// Read blocks until the next message arrives or ctx is done
+	Read(ctx context.Context) ([]byte, error)
+
+	// Write sends one message
+	Write(ctx context.Context, data []byte) error
+
+	// Close closes the connection
+	Close() error
+}
+
+// wsConn adapts a WebSocket to MessageConn, exchanging text messages
+type wsConn struct {
+	conn *websocket.Conn
+}
+
+// Read implements MessageConn
+func (c *wsConn) Read(ctx context.Context) ([]byte, error) {
+	_, data, err := c.conn.Read(ctx)
+	return data, err
+}
+
+// Write implements MessageConn
+func (c *wsConn) Write(ctx context.Context, data []byte) error {
+	return c.conn.Write(ctx, websocket.MessageText, data)
+}
+
+// Close implements MessageConn
+func (c *wsConn) Close() error {
+	return c.conn.Close(websocket.StatusNormalClosure, "")
+}
+
+// AdmissionError is returned by Admit when a client is refused
+type AdmissionError struct {
+	Status  int // HTTP status for the refusal
+	Payload *ErrorPayload
+}
+
+// Error implements error
+func (e *AdmissionError) Error() string {
+	return e.Payload.Message
+}
+
+// WriteHTTP writes the refusal as a JSON error response
+func (e *AdmissionError) WriteHTTP(w http.ResponseWriter) {
+	writeErrorPayload(w, e.Status, e.Payload)
+}
+
+// Admission is a client admitted by Admit. It holds a connection slot until
+// Serve returns or Release is called.
+type Admission struct {
+	handler     *Handler
+	ip          string
+	protocol    int
+	locale      string
+	viewOptions ViewOptions
+	spectating  bool
+	release     sync.Once
+}
+
+// Admit checks the protocol version, view options, spectator mode and
+// connection limits of a client connecting through r, and reserves a
+// connection slot for it. Transports negotiated over HTTP admit the client
+// before answering so that refusals are reported in the HTTP response.
+func (h *Handler) Admit(r *http.Request) (*Admission, *AdmissionError) {
+	locale := i18n.RequestLocale(r)
+	admission, refusal := h.admit(r)
+	if refusal != nil {
+		if refusal.Payload.Key != "" {
+			refusal.Payload.Message = h.catalog.Message(locale, refusal.Payload.Key, refusal.Payload.args...)
+		}
+		refusal.Payload.CorrelationID = CorrelationID(r.Context())
+		return nil, refusal
+	}
+	admission.locale = locale
+	return admission, nil
+}
+
+// admit implements Admit
+func (h *Handler) admit(r *http.Request) (*Admission, *AdmissionError) {
+	protocol, protoErr := negotiateProtocol(r)
+	if protoErr != nil {
+		return nil, &AdmissionError{Status: http.StatusBadRequest, Payload: protoErr}
+	}
+
+	viewOptions, optsErr := viewOptionsFromRequest(r)
+	if optsErr != nil {
+		return nil, &AdmissionError{Status: http.StatusBadRequest, Payload: optsErr}
+	}
+
+	spectating, spectateErr := spectateFromRequest(r)
+	if spectateErr != nil {
+		return nil, &AdmissionError{Status: http.StatusBadRequest, Payload: spectateErr}
+	}
+
+	ip := RemoteIP(r)
+	if limitErr := h.reserveSlot(ip); limitErr != nil {
+		status := http.StatusServiceUnavailable
+		if limitErr.Code == ErrCodeTooManyFromAddr {
+			status = http.StatusTooManyRequests
+		}
+		return nil, &AdmissionError{Status: status, Payload: limitErr}
+	}
+
+	return &Admission{handler: h, ip: ip, protocol: protocol, viewOptions: viewOptions, spectating: spectating}, nil
+}
+
+// Serve runs the client on conn until the connection fails or ctx is done,
+// then closes conn and releases the slot. ctx should carry the values of the
+// admitted request, such as the authenticated identity; the client's locale
+// is added.
+func (a *Admission) Serve(ctx context.Context, conn MessageConn) {
+	defer a.Release()
+	if a.locale != "" {
+		ctx = i18n.WithLocale(ctx, a.locale)
+	}
+	a.handler.handleConnection(ctx, conn, a.ip, a.protocol, a.viewOptions, a.spectating)
+}
+
+// Release gives up the connection slot of a client that will not be served
+func (a *Admission) Release() {
+	a.release.Do(func() { a.handler.releaseSlot(a.ip) })
+}
@@ -0,0 +1,142 @@
+package transport
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// chanConn is an in-memory MessageConn; the test plays the client through
+// toServer and fromServer
+type chanConn struct {
+	toServer   chan []byte
+	fromServer chan []byte
+	closed     chan struct{}
+}
+
+func newChanConn() *chanConn {
+	return &chanConn{
+		toServer:   make(chan []byte, 16),
+		fromServer: make(chan []byte, 16),
+		closed:     make(chan struct{}),
+	}
+}
+
+func (c *chanConn) Read(ctx context.Context) ([]byte, error) {
+	select {
+	case data := <-c.toServer:
+		return data, nil
+	case <-c.closed:
+		return nil, errors.New("closed")
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (c *chanConn) Write(ctx context.Context, data []byte) error {
+	select {
+	case c.fromServer <- data:
+		return nil
+	case <-c.closed:
+		return errors.New("closed")
+	}
+}
+
+func (c *chanConn) Close() error {
+	select {
+	case <-c.closed:
+	default:
+		close(c.closed)
+	}
+	return nil
+}
+
+// next returns the next message the server sent
+func (c *chanConn) next(t *testing.T) Message {
+	t.Helper()
+	select {
+	case data := <-c.fromServer:
+		var msg Message
+		if err := json.Unmarshal(data, &msg); err != nil {
+			t.Fatalf("invalid message %q: %v", data, err)
+		}
+		return msg
+	case <-time.After(2 * time.Second):
+		t.Fatal("no message from server")
+		return Message{}
+	}
+}
+
+func TestAdmission_Serve_RunsProtocolOverMessageConn(t *testing.T) {
+	h := NewHandler()
+	inputs := make(chan string, 1)
+	h.SetInputHandler(func(clientID, input string) error {
+		inputs <- input
+		return nil
+	})
+
+	admission, refusal := h.Admit(httptest.NewRequest(http.MethodPost, "/rtc/offer?protocol=1", nil))
+	if refusal != nil {
+		t.Fatalf("Admit() refused: %v", refusal)
+	}
+	conn := newChanConn()
+	done := make(chan struct{})
+	go func() {
+		admission.Serve(context.Background(), conn)
+		close(done)
+	}()
+
+	var connect ConnectPayload
+	if msg := conn.next(t); msg.Type != MsgTypeConnect || json.Unmarshal(msg.Payload, &connect) != nil || connect.ProtocolVersion != 1 {
+		t.Fatalf("first message = %+v, want connect with protocol 1", msg)
+	}
+
+	conn.toServer <- []byte(`{"type":"input","payload":{"input":"h"}}`)
+	select {
+	case input := <-inputs:
+		if input != "h" {
+			t.Errorf("input = %q, want h", input)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("input not delivered")
+	}
+
+	conn.Close()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Serve() did not return after the connection closed")
+	}
+	if h.GetClientCount() != 0 {
+		t.Errorf("GetClientCount() = %d after disconnect, want 0", h.GetClientCount())
+	}
+}
+
+func TestHandler_Admit_RefusesAndReleasesSlots(t *testing.T) {
+	h := NewHandler()
+	h.SetLimits(1, 0)
+	r := httptest.NewRequest(http.MethodPost, "/rtc/offer", nil)
+
+	admission, refusal := h.Admit(r)
+	if refusal != nil {
+		t.Fatalf("Admit() refused: %v", refusal)
+	}
+	if _, refusal := h.Admit(r); refusal == nil || refusal.Status != http.StatusServiceUnavailable || refusal.Payload.Code != ErrCodeSessionFull {
+		t.Fatalf("Admit() over the limit = %+v, want session full", refusal)
+	}
+
+	admission.Release()
+	admission.Release() // idempotent
+	if _, refusal := h.Admit(r); refusal != nil {
+		t.Errorf("Admit() after Release() refused: %v", refusal)
+	}
+
+	bad := httptest.NewRequest(http.MethodPost, "/rtc/offer?protocol=0", nil)
+	if _, refusal := h.Admit(bad); refusal == nil || refusal.Status != http.StatusBadRequest {
+		t.Errorf("Admit() with unsupported protocol = %+v, want bad request", refusal)
+	}
+}
@@ -0,0 +1,58 @@
+// Package transport provides correlation IDs that tie the log entries,
+// errors and metrics of one web client together.
+package transport
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"strconv"
+	"time"
+)
+
+// CorrelationHeader carries a web client's correlation ID. Clients that send
+// back the ID of an earlier response keep it across requests.
+const CorrelationHeader = "X-Correlation-ID"
+
+// maxCorrelationID bounds the length of IDs accepted from clients
+const maxCorrelationID = 64
+
+// correlationKey is the context key of the correlation ID
+type correlationKey struct{}
+
+// WithCorrelationID returns a copy of ctx carrying the correlation ID id
+func WithCorrelationID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, correlationKey{}, id)
+}
+
+// CorrelationID returns the correlation ID carried by ctx, or "" if none
+func CorrelationID(ctx context.Context) string {
+	id, _ := ctx.Value(correlationKey{}).(string)
+	return id
+}
+
+// NewCorrelationID returns a random correlation ID starting with prefix
+func NewCorrelationID(prefix string) string {
+	b := make([]byte, 6)
+	if _, err := rand.Read(b); err != nil {
+		return prefix + strconv.FormatInt(time.Now().UnixNano(), 36)
+	}
+	return prefix + hex.EncodeToString(b)
+}
+
+// ValidCorrelationID reports whether id, received from a client, can be
+// logged as is: up to 64 letters, digits, dots, dashes and underscores
+func ValidCorrelationID(id string) bool {
+	if id == "" || len(id) > maxCorrelationID {
+		return false
+	}
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '.', c == '-', c == '_':
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,92 @@
+package transport
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestValidCorrelationID(t *testing.T) {
+	tests := []struct {
+		id   string
+		want bool
+	}{
+		{"c-0123abcd", true},
+		{"Trace_1.2-3", true},
+		{"", false},
+		{"has space", false},
+		{"line\nbreak", false},
+		{strings.Repeat("a", 64), true},
+		{strings.Repeat("a", 65), false},
+	}
+	for _, tt := range tests {
+		if got := ValidCorrelationID(tt.id); got != tt.want {
+			t.Errorf("ValidCorrelationID(%q) = %v, want %v", tt.id, got, tt.want)
+		}
+	}
+
+	if a, b := NewCorrelationID("c-"), NewCorrelationID("c-"); a == b || !ValidCorrelationID(a) || !strings.HasPrefix(a, "c-") {
+		t.Errorf("NewCorrelationID() = %q, %q; want distinct valid IDs with the prefix", a, b)
+	}
+}
+
+func TestAdmission_Serve_CarriesCorrelationID(t *testing.T) {
+	tests := []struct {
+		name string
+		ctx  context.Context
+		want string // "" for the client ID
+	}{
+		{"from the request", WithCorrelationID(context.Background(), "c-request"), "c-request"},
+		{"client ID otherwise", context.Background(), ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewHandler()
+			h.SetInputHandler(func(clientID, input string) error {
+				return errors.New("spectators cannot send input")
+			})
+			admission, refusal := h.Admit(httptest.NewRequest(http.MethodPost, "/rtc/offer", nil))
+			if refusal != nil {
+				t.Fatalf("Admit() refused: %v", refusal)
+			}
+			conn := newChanConn()
+			defer conn.Close()
+			go admission.Serve(tt.ctx, conn)
+
+			var connect ConnectPayload
+			if msg := conn.next(t); json.Unmarshal(msg.Payload, &connect) != nil {
+				t.Fatalf("first message = %+v, want connect", msg)
+			}
+			want := tt.want
+			if want == "" {
+				want = connect.ClientID
+			}
+			if connect.CorrelationID != want {
+				t.Errorf("connect correlation ID = %q, want %q", connect.CorrelationID, want)
+			}
+			if ctx, ok := h.ClientContext(connect.ClientID); !ok || CorrelationID(ctx) != want {
+				t.Errorf("client context correlation ID = %q, want %q", CorrelationID(ctx), want)
+			}
+
+			// Errors reported to the client carry the ID
+			conn.toServer <- []byte(`{"type":"input","payload":{"input":"h"}}`)
+			var payload ErrorPayload
+			if msg := conn.next(t); msg.Type != MsgTypeError || json.Unmarshal(msg.Payload, &payload) != nil || payload.CorrelationID != want {
+				t.Errorf("error = %+v, want correlation ID %q", payload, want)
+			}
+		})
+	}
+
+	// Refusals carry the ID of the request
+	h := NewHandler()
+	r := httptest.NewRequest(http.MethodPost, "/rtc/offer?protocol=0", nil)
+	_, refusal := h.Admit(r.WithContext(WithCorrelationID(r.Context(), "c-refused")))
+	if refusal == nil || refusal.Payload.CorrelationID != "c-refused" {
+		t.Errorf("Admit() refusal = %+v, want correlation ID c-refused", refusal)
+	}
+}
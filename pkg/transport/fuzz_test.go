@@ -0,0 +1,49 @@
+package transport
+
+import (
+	"context"
+	"testing"
+)
+
+// FuzzClientReceive feeds arbitrary client messages through the decoding
+// and dispatch of readPump
+func FuzzClientReceive(f *testing.F) {
+	for _, seed := range []string{
+		`{"type":"input","payload":{"input":"h","seq":1}}`,
+		`{"type":"input","payload":{"input":"\u001b[A","seq":18446744073709551615}}`,
+		`{"type":"view_options","payload":{"color_mode":"256","palette":"solarized","reveal_concealed":true}}`,
+		`{"type":"view_options","payload":null}`,
+		`{"type":"pong","timestamp":1}`,
+		`{"type":"input","payload":"h"}`,
+		`{"type":"unknown"}`,
+		`[]`,
+		`{`,
+	} {
+		f.Add([]byte(seed))
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		h := NewHandler()
+		var inputs []string
+		h.SetInputHandler(func(clientID, input string) error {
+			inputs = append(inputs, input)
+			return nil
+		})
+		h.SetViewOptionsHandler(func(string) {})
+
+		c := newTestClient("fuzz", 8)
+		c.handler = h
+		c.ctx = context.Background()
+		if err := c.receive(data); err != nil && len(inputs) > 0 {
+			t.Fatalf("input %q delivered from a message that failed to decode", inputs)
+		}
+		if len(inputs) > 1 {
+			t.Fatalf("one message delivered %d inputs", len(inputs))
+		}
+		if opts := c.ViewOptions(); opts != (ViewOptions{}) {
+			if err := opts.Validate(); err != nil {
+				t.Fatalf("invalid view options %+v applied: %v", opts, err)
+			}
+		}
+	})
+}
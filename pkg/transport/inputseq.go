@@ -0,0 +1,39 @@
+// Package transport provides input acknowledgements for client-side
+// prediction and duplicate suppression.
+package transport
+
+// Clients that echo keystrokes locally before the server confirms them
+// number their input messages with InputPayload.Seq. Every state message
+// sent to the client carries, in Message.InputSeq, the sequence number of
+// the last input delivered to the game before the state was sent. The client
+// drops its predictions up to that number, compares them with the screen the
+// server sent, and redraws the remaining ones on top of it. Numbered input
+// at or below the acknowledged number is a duplicate, e.g. sent again after
+// a timeout, and is ignored.
+
+// duplicateInput reports whether the input numbered seq was already
+// delivered to the game
+func (c *Client) duplicateInput(seq uint64) bool {
+	return seq != 0 && seq <= c.InputSeq()
+}
+
+// ackInput records that the input numbered seq was delivered to the game.
+// Unnumbered input (seq 0) leaves the acknowledgement unchanged.
+func (c *Client) ackInput(seq uint64) {
+	if seq == 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if seq > c.inputSeq {
+		c.inputSeq = seq
+	}
+}
+
+// InputSeq returns the sequence number of the last input from the client
+// that was delivered to the game
+func (c *Client) InputSeq() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.inputSeq
+}
@@ -0,0 +1,74 @@
+package transport
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestHandler_BroadcastStateFunc_AcknowledgesInputPerClient(t *testing.T) {
+	h := NewHandler()
+	h.SetInputHandler(func(clientID, input string) error {
+		if input == "x" {
+			return errors.New("rejected")
+		}
+		return nil
+	})
+	for _, id := range []string{"a", "b"} {
+		client := newTestClient(id, 4)
+		client.handler = h
+		h.clients[id] = client
+	}
+
+	inputs := []struct {
+		client string
+		input  InputPayload
+	}{
+		{"a", InputPayload{Input: "h", Seq: 1}},
+		{"a", InputPayload{Input: "i", Seq: 2}},
+		{"a", InputPayload{Input: "x", Seq: 3}}, // rejected, not acknowledged
+		{"a", InputPayload{Input: "!"}},         // unnumbered
+		{"b", InputPayload{Input: "k", Seq: 7}},
+		{"b", InputPayload{Input: "j", Seq: 5}}, // late, never moves back
+	}
+	for _, in := range inputs {
+		payload, _ := json.Marshal(in.input)
+		h.clients[in.client].handleMessage(Message{Type: MsgTypeInput, Payload: payload})
+	}
+	for _, client := range h.clients {
+		for len(client.send) > 0 {
+			<-client.send
+		}
+	}
+
+	h.BroadcastStateFunc(func(opts ViewOptions) *StatePayload {
+		return &StatePayload{Version: 1}
+	})
+	for id, want := range map[string]uint64{"a": 2, "b": 7} {
+		msg := <-h.clients[id].send
+		if msg.Type != MsgTypeState || msg.InputSeq != want {
+			t.Errorf("client %s: got %s with input_seq %d, want state with %d", id, msg.Type, msg.InputSeq, want)
+		}
+	}
+}
+
+func TestClient_HandleMessage_IgnoresDuplicateInput(t *testing.T) {
+	h := NewHandler()
+	var delivered []string
+	h.SetInputHandler(func(clientID, input string) error {
+		delivered = append(delivered, input)
+		return nil
+	})
+	c := newTestClient("a", 4)
+	c.handler = h
+	h.clients["a"] = c
+
+	for _, in := range []InputPayload{{Input: "h", Seq: 1}, {Input: "h", Seq: 1}, {Input: "j", Seq: 2}, {Input: "k"}, {Input: "k"}} {
+		payload, _ := json.Marshal(in)
+		c.handleMessage(Message{Type: MsgTypeInput, Payload: payload})
+	}
+	if got := strings.Join(delivered, ""); got != "hjkk" {
+		t.Errorf("delivered %q, want the retried input once and unnumbered input always", got)
+	}
+}
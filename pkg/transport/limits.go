@@ -0,0 +1,106 @@
+// Package transport provides connection limits for the WebSocket handler.
+package transport
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"sync"
+
+	"github.com/opd-ai/go-gamelaunch-www/pkg/i18n"
+)
+
+// Error codes carried in ErrorPayload when a connection is refused
+const (
+	ErrCodeSessionFull     = 1001 // Session reached its maximum number of clients
+	ErrCodeTooManyFromAddr = 1002 // Remote address holds too many connections
+)
+
+// connectionLimits tracks connection slots reserved per session and per remote IP
+type connectionLimits struct {
+	maxClients      int
+	maxClientsPerIP int
+	active          int
+	perIP           map[string]int
+}
+
+// SetLimits configures the maximum number of concurrent clients for the
+// session and per remote IP address. Zero disables the respective limit.
+func (h *Handler) SetLimits(maxClients, maxClientsPerIP int) {
+	h.slotsMu.Lock()
+	defer h.slotsMu.Unlock()
+
+	h.limits.maxClients = maxClients
+	h.limits.maxClientsPerIP = maxClientsPerIP
+}
+
+// reserveSlot claims a connection slot for ip, returning a structured error
+// payload when a limit has been reached
+func (h *Handler) reserveSlot(ip string) *ErrorPayload {
+	h.slotsMu.Lock()
+	defer h.slotsMu.Unlock()
+
+	if h.limits.maxClients > 0 && h.limits.active >= h.limits.maxClients {
+		return newErrorPayload(ErrCodeSessionFull, i18n.MsgSessionFull, h.limits.maxClients)
+	}
+	if h.limits.maxClientsPerIP > 0 && h.limits.perIP[ip] >= h.limits.maxClientsPerIP {
+		return newErrorPayload(ErrCodeTooManyFromAddr, i18n.MsgTooManyConnections, ip, h.limits.maxClientsPerIP)
+	}
+
+	h.limits.active++
+	h.limits.perIP[ip]++
+	return nil
+}
+
+// ReserveAddrSlot claims a connection slot of ip for an HTTP request held
+// open outside the WebSocket handler, such as POST /input, which counts
+// toward the per-IP limit but does not attach to the session. It returns
+// the function releasing the slot, or a structured error payload when ip
+// holds too many connections.
+func (h *Handler) ReserveAddrSlot(ip string) (release func(), payload *ErrorPayload) {
+	h.slotsMu.Lock()
+	defer h.slotsMu.Unlock()
+
+	if h.limits.maxClientsPerIP > 0 && h.limits.perIP[ip] >= h.limits.maxClientsPerIP {
+		return nil, newErrorPayload(ErrCodeTooManyFromAddr, i18n.MsgTooManyConnections, ip, h.limits.maxClientsPerIP)
+	}
+	h.limits.perIP[ip]++
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			h.slotsMu.Lock()
+			defer h.slotsMu.Unlock()
+			if h.limits.perIP[ip]--; h.limits.perIP[ip] <= 0 {
+				delete(h.limits.perIP, ip)
+			}
+		})
+	}, nil
+}
+
+// releaseSlot returns a connection slot previously claimed by reserveSlot
+func (h *Handler) releaseSlot(ip string) {
+	h.slotsMu.Lock()
+	defer h.slotsMu.Unlock()
+
+	h.limits.active--
+	if h.limits.perIP[ip]--; h.limits.perIP[ip] <= 0 {
+		delete(h.limits.perIP, ip)
+	}
+}
+
+// writeErrorPayload writes payload as a JSON error response with status
+func writeErrorPayload(w http.ResponseWriter, status int, payload *ErrorPayload) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(payload)
+}
+
+// RemoteIP returns the address of the peer that sent r, without the port
+func RemoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,149 @@
+// Package transport provides tests for WebSocket connection limits.
+package transport
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/opd-ai/go-gamelaunch-www/pkg/i18n"
+)
+
+func TestHandler_ReserveSlot_EnforcesSessionLimit(t *testing.T) {
+	h := NewHandler()
+	h.SetLimits(2, 0)
+
+	if err := h.reserveSlot("10.0.0.1"); err != nil {
+		t.Fatalf("first reservation failed: %v", err.Message)
+	}
+	if err := h.reserveSlot("10.0.0.2"); err != nil {
+		t.Fatalf("second reservation failed: %v", err.Message)
+	}
+
+	err := h.reserveSlot("10.0.0.3")
+	if err == nil || err.Code != ErrCodeSessionFull {
+		t.Fatalf("expected ErrCodeSessionFull, got %+v", err)
+	}
+
+	h.releaseSlot("10.0.0.1")
+	if err := h.reserveSlot("10.0.0.3"); err != nil {
+		t.Errorf("reservation after release failed: %v", err.Message)
+	}
+}
+
+func TestHandler_ReserveSlot_EnforcesPerIPLimit(t *testing.T) {
+	h := NewHandler()
+	h.SetLimits(0, 1)
+
+	if err := h.reserveSlot("10.0.0.1"); err != nil {
+		t.Fatalf("first reservation failed: %v", err.Message)
+	}
+
+	err := h.reserveSlot("10.0.0.1")
+	if err == nil || err.Code != ErrCodeTooManyFromAddr {
+		t.Fatalf("expected ErrCodeTooManyFromAddr, got %+v", err)
+	}
+
+	if err := h.reserveSlot("10.0.0.2"); err != nil {
+		t.Errorf("other IP should not be limited: %v", err.Message)
+	}
+
+	h.releaseSlot("10.0.0.1")
+	if _, ok := h.limits.perIP["10.0.0.1"]; ok {
+		t.Error("released IP should be removed from tracking")
+	}
+}
+
+func TestHandler_ReserveAddrSlot_SharesPerIPLimit(t *testing.T) {
+	h := NewHandler()
+	h.SetLimits(1, 2)
+
+	if err := h.reserveSlot("10.0.0.1"); err != nil {
+		t.Fatalf("WebSocket reservation failed: %v", err.Message)
+	}
+	release, err := h.ReserveAddrSlot("10.0.0.1")
+	if err != nil {
+		t.Fatalf("HTTP reservation failed: %v", err.Message)
+	}
+	if _, err := h.ReserveAddrSlot("10.0.0.1"); err == nil || err.Code != ErrCodeTooManyFromAddr {
+		t.Fatalf("expected ErrCodeTooManyFromAddr, got %+v", err)
+	}
+
+	// HTTP requests do not attach to the session
+	if _, err := h.ReserveAddrSlot("10.0.0.2"); err != nil {
+		t.Errorf("HTTP reservation of a full session failed: %v", err.Message)
+	}
+
+	release()
+	release()
+	if got := h.limits.perIP["10.0.0.1"]; got != 1 {
+		t.Errorf("slots of 10.0.0.1 = %d after release, want 1", got)
+	}
+}
+
+func TestHandler_ServeHTTP_RejectsWithStructuredError(t *testing.T) {
+	h := NewHandler()
+	h.SetLimits(0, 1)
+	h.reserveSlot("192.0.2.1")
+
+	req := httptest.NewRequest(http.MethodGet, "/ws", nil)
+	req.RemoteAddr = "192.0.2.1:5000"
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusTooManyRequests)
+	}
+
+	var payload ErrorPayload
+	if err := json.Unmarshal(rec.Body.Bytes(), &payload); err != nil {
+		t.Fatalf("failed to decode error payload: %v", err)
+	}
+	if payload.Code != ErrCodeTooManyFromAddr {
+		t.Errorf("code = %d, want %d", payload.Code, ErrCodeTooManyFromAddr)
+	}
+}
+
+func TestHandler_ServeHTTP_LocalizesRefusal(t *testing.T) {
+	catalog, err := i18n.NewCatalog(map[string]map[string]string{
+		"de": {i18n.MsgTooManyConnections: "zu viele Verbindungen von %s (Grenze %d)"},
+	})
+	if err != nil {
+		t.Fatalf("NewCatalog failed: %v", err)
+	}
+
+	tests := []struct {
+		name           string
+		acceptLanguage string
+		want           string
+	}{
+		{"translated", "de-AT,de;q=0.9", "zu viele Verbindungen von 192.0.2.1 (Grenze 1)"},
+		{"english fallback", "fr", "too many connections from 192.0.2.1 (limit 1)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewHandler()
+			h.SetCatalog(catalog)
+			h.SetLimits(0, 1)
+			h.reserveSlot("192.0.2.1")
+
+			req := httptest.NewRequest(http.MethodGet, "/ws", nil)
+			req.RemoteAddr = "192.0.2.1:5000"
+			req.Header.Set("Accept-Language", tt.acceptLanguage)
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			var payload ErrorPayload
+			if err := json.Unmarshal(rec.Body.Bytes(), &payload); err != nil {
+				t.Fatalf("failed to decode error payload: %v", err)
+			}
+			if payload.Message != tt.want {
+				t.Errorf("message = %q, want %q", payload.Message, tt.want)
+			}
+			if payload.Key != i18n.MsgTooManyConnections {
+				t.Errorf("key = %q, want %q", payload.Key, i18n.MsgTooManyConnections)
+			}
+		})
+	}
+}
@@ -0,0 +1,60 @@
+// Package transport provides wire protocol version negotiation.
+package transport
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/opd-ai/go-gamelaunch-www/pkg/i18n"
+)
+
+// Protocol versions understood by this server. The protocol version covers
+// the message envelope and the state, diff and cell payload formats; it is
+// bumped whenever one of them changes incompatibly.
+const (
+	ProtocolVersion    = 1 // newest version spoken by the server
+	MinProtocolVersion = 1 // oldest version still served
+)
+
+// ErrCodeUnsupportedProtocol is carried in ErrorPayload when a client asks
+// for a protocol version the server no longer speaks
+const ErrCodeUnsupportedProtocol = 1003
+
+// protocolParam is the query parameter a client uses to request a version
+const protocolParam = "protocol"
+
+// ConnectPayload is sent to every client as the first message after the
+// WebSocket upgrade and reports the negotiated protocol version
+type ConnectPayload struct {
+	ClientID           string `json:"client_id"`
+	CorrelationID      string `json:"correlation_id,omitempty"` // see ClientStats.CorrelationID
+	ProtocolVersion    int    `json:"protocol_version"`
+	MinProtocolVersion int    `json:"min_protocol_version"`
+	MaxProtocolVersion int    `json:"max_protocol_version"`
+	Spectating         bool   `json:"spectating,omitempty"` // input is rejected, see Handler.SetSpectating
+}
+
+// negotiateProtocol picks the protocol version for a connection request.
+// Clients name the newest version they understand in the protocol query
+// parameter; clients that omit it are treated as speaking version 1.
+func negotiateProtocol(r *http.Request) (int, *ErrorPayload) {
+	raw := r.URL.Query().Get(protocolParam)
+	if raw == "" {
+		return 1, nil
+	}
+
+	requested, err := strconv.Atoi(raw)
+	if err != nil || requested < MinProtocolVersion {
+		return 0, newErrorPayload(ErrCodeUnsupportedProtocol, i18n.MsgUnsupportedProtocol,
+			raw, MinProtocolVersion, ProtocolVersion)
+	}
+	if requested > ProtocolVersion {
+		requested = ProtocolVersion
+	}
+	return requested, nil
+}
+
+// Protocol returns the protocol version negotiated with the client
+func (c *Client) Protocol() int {
+	return c.protocol
+}
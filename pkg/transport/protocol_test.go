@@ -0,0 +1,88 @@
+package transport
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"nhooyr.io/websocket"
+	"nhooyr.io/websocket/wsjson"
+)
+
+func TestNegotiateProtocol(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		want    int
+		wantErr bool
+	}{
+		{"legacy client", "", 1, false},
+		{"current version", "?protocol=1", ProtocolVersion, false},
+		{"newer client", "?protocol=99", ProtocolVersion, false},
+		{"too old", "?protocol=0", 0, true},
+		{"garbage", "?protocol=abc", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, errPayload := negotiateProtocol(httptest.NewRequest("GET", "/ws"+tt.query, nil))
+			if (errPayload != nil) != tt.wantErr {
+				t.Fatalf("error = %+v, wantErr %v", errPayload, tt.wantErr)
+			}
+			if errPayload != nil && errPayload.Code != ErrCodeUnsupportedProtocol {
+				t.Errorf("code = %d, want %d", errPayload.Code, ErrCodeUnsupportedProtocol)
+			}
+			if got != tt.want {
+				t.Errorf("version = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandler_ServeHTTP_RejectsUnsupportedProtocol(t *testing.T) {
+	h := NewHandler()
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/ws?protocol=0", nil))
+
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want 400", rec.Code)
+	}
+	var payload ErrorPayload
+	if err := json.Unmarshal(rec.Body.Bytes(), &payload); err != nil || payload.Code != ErrCodeUnsupportedProtocol {
+		t.Errorf("unexpected body %s", rec.Body.String())
+	}
+}
+
+func TestHandler_ServeHTTP_AnnouncesProtocolFirst(t *testing.T) {
+	server := httptest.NewServer(NewHandler())
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	conn, _, err := websocket.Dial(ctx, "ws"+strings.TrimPrefix(server.URL, "http")+"?protocol=5", nil)
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	defer conn.Close(websocket.StatusNormalClosure, "")
+
+	var msg Message
+	if err := wsjson.Read(ctx, conn, &msg); err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	if msg.Type != MsgTypeConnect {
+		t.Fatalf("first message type = %q, want %q", msg.Type, MsgTypeConnect)
+	}
+
+	var payload ConnectPayload
+	if err := json.Unmarshal(msg.Payload, &payload); err != nil {
+		t.Fatalf("Failed to decode payload: %v", err)
+	}
+	if payload.ProtocolVersion != ProtocolVersion || payload.ClientID == "" {
+		t.Errorf("unexpected connect payload %+v", payload)
+	}
+}
@@ -0,0 +1,87 @@
+// Package transport provides watch-only (spectator) clients.
+package transport
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/opd-ai/go-gamelaunch-www/pkg/i18n"
+)
+
+// spectateParam is the query parameter a client sets to connect in
+// spectator mode
+const spectateParam = "spectate"
+
+// ErrCodeInvalidSpectate is carried in ErrorPayload when a client asks for
+// spectator mode with a value that is not a boolean
+const ErrCodeInvalidSpectate = 1005
+
+// spectateFromRequest reads whether the client connects in spectator mode
+func spectateFromRequest(r *http.Request) (bool, *ErrorPayload) {
+	raw := r.URL.Query().Get(spectateParam)
+	if raw == "" {
+		return false, nil
+	}
+	spectate, err := strconv.ParseBool(raw)
+	if err != nil {
+		return false, &ErrorPayload{Code: ErrCodeInvalidSpectate, Message: fmt.Sprintf("invalid spectate %q", raw)}
+	}
+	return spectate, nil
+}
+
+// SetSpectating switches a client in or out of spectator mode. Spectators
+// receive the game state like every other client, but their input is
+// rejected without reaching the input handler.
+func (h *Handler) SetSpectating(clientID string, spectating bool) error {
+	h.clientsMu.RLock()
+	client, ok := h.clients[clientID]
+	h.clientsMu.RUnlock()
+
+	if !ok {
+		return fmt.Errorf("client not found: %s", clientID)
+	}
+	client.mu.Lock()
+	client.spectating = spectating
+	client.mu.Unlock()
+	return nil
+}
+
+// Spectating reports whether a client is in spectator mode
+func (h *Handler) Spectating(clientID string) bool {
+	h.clientsMu.RLock()
+	client, ok := h.clients[clientID]
+	h.clientsMu.RUnlock()
+
+	return ok && client.Spectating()
+}
+
+// SpectatorCount returns the number of connected clients in spectator mode
+func (h *Handler) SpectatorCount() int {
+	h.clientsMu.RLock()
+	defer h.clientsMu.RUnlock()
+
+	count := 0
+	for _, client := range h.clients {
+		if client.Spectating() {
+			count++
+		}
+	}
+	return count
+}
+
+// Spectating reports whether the client is in spectator mode
+func (c *Client) Spectating() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.spectating
+}
+
+// rejectSpectatorInput answers input from a spectator with an error in the
+// client's locale
+func (c *Client) rejectSpectatorInput() {
+	payload := newErrorPayload(ErrCodeInputRejected, i18n.MsgInputSpectating)
+	payload.Message = c.handler.catalog.Message(c.locale, payload.Key)
+	payload.CorrelationID = c.corrID
+	c.handler.SendToClient(c.id, newMessage(MsgTypeError, payload))
+}
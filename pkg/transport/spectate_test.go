@@ -0,0 +1,110 @@
+package transport
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/opd-ai/go-gamelaunch-www/pkg/i18n"
+)
+
+func TestSpectateFromRequest(t *testing.T) {
+	tests := []struct {
+		query   string
+		want    bool
+		wantErr bool
+	}{
+		{"", false, false},
+		{"?spectate=1", true, false},
+		{"?spectate=false", false, false},
+		{"?spectate=maybe", false, true},
+	}
+	for _, tt := range tests {
+		got, errPayload := spectateFromRequest(httptest.NewRequest(http.MethodGet, "/ws"+tt.query, nil))
+		if got != tt.want || (errPayload != nil) != tt.wantErr {
+			t.Errorf("spectateFromRequest(%q) = %v, %+v; want %v, error %v", tt.query, got, errPayload, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestClient_SpectatorInputRejected(t *testing.T) {
+	h := NewHandler()
+	catalog, err := i18n.NewCatalog(map[string]map[string]string{"de": {i18n.MsgInputSpectating: "Du schaust nur zu"}})
+	if err != nil {
+		t.Fatalf("NewCatalog failed: %v", err)
+	}
+	h.SetCatalog(catalog)
+	delivered := 0
+	h.SetInputHandler(func(clientID, input string) error {
+		delivered++
+		return nil
+	})
+	c := &Client{id: "a", locale: "de", send: make(chan Message, 1), handler: h}
+	h.clients[c.id] = c
+
+	if err := h.SetSpectating("a", true); err != nil {
+		t.Fatalf("SetSpectating failed: %v", err)
+	}
+	if err := h.SetSpectating("b", true); err == nil {
+		t.Error("SetSpectating accepted an unknown client")
+	}
+	if !h.Spectating("a") || h.SpectatorCount() != 1 || !h.ClientStats()[0].Spectating {
+		t.Fatalf("client a is not reported as a spectator")
+	}
+
+	payload, _ := json.Marshal(InputPayload{Input: "h", Seq: 1})
+	c.handleMessage(Message{Type: MsgTypeInput, Payload: payload})
+	if delivered != 0 {
+		t.Errorf("spectator input reached the input handler")
+	}
+	msg := <-c.send
+	var errPayload ErrorPayload
+	if err := json.Unmarshal(msg.Payload, &errPayload); err != nil || errPayload.Code != ErrCodeInputRejected || errPayload.Message != "Du schaust nur zu" {
+		t.Errorf("got %s %+v, want localized input rejected error", msg.Type, errPayload)
+	}
+
+	// Leaving spectator mode accepts input again, including the rejected
+	// sequence number
+	h.SetSpectating("a", false)
+	c.handleMessage(Message{Type: MsgTypeInput, Payload: payload})
+	if delivered != 1 || h.SpectatorCount() != 0 {
+		t.Errorf("delivered = %d, spectators = %d after leaving spectator mode", delivered, h.SpectatorCount())
+	}
+}
+
+func TestAdmission_Serve_Spectating(t *testing.T) {
+	h := NewHandler()
+	if _, refusal := h.Admit(httptest.NewRequest(http.MethodGet, "/ws?spectate=maybe", nil)); refusal == nil || refusal.Status != http.StatusBadRequest {
+		t.Fatalf("Admit() = %+v, want bad request", refusal)
+	}
+
+	admission, refusal := h.Admit(httptest.NewRequest(http.MethodGet, "/ws?spectate=1", nil))
+	if refusal != nil {
+		t.Fatalf("Admit() refused: %v", refusal)
+	}
+	conn := newChanConn()
+	done := make(chan struct{})
+	go func() {
+		admission.Serve(context.Background(), conn)
+		close(done)
+	}()
+	defer func() {
+		conn.Close()
+		select {
+		case <-done:
+		case <-time.After(2 * time.Second):
+			t.Error("Serve() did not return after the connection closed")
+		}
+	}()
+
+	var connect ConnectPayload
+	if msg := conn.next(t); json.Unmarshal(msg.Payload, &connect) != nil || !connect.Spectating {
+		t.Fatalf("connect = %+v, want spectating", connect)
+	}
+	if !h.Spectating(connect.ClientID) {
+		t.Error("client connected with spectate=1 is not a spectator")
+	}
+}
@@ -0,0 +1,183 @@
+// Package transport provides per-client rendering preferences.
+package transport
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+// Color modes a client may request. The server rewrites cell colors before
+// sending them so that thin clients need no palette logic of their own.
+const (
+	ColorModeNormal       = ""
+	ColorModeDeuteranopia = "deuteranopia"  // red-green safe palette (missing green cones)
+	ColorModeProtanopia   = "protanopia"    // red-green safe palette (missing red cones)
+	ColorModeHighContrast = "high_contrast" // pure background with maximally contrasting text
+)
+
+// Palettes a client may request. With PaletteANSI16 every cell color is
+// replaced by the nearest of the 16 standard ANSI colors, for clients with a
+// limited palette such as e-ink displays.
+const (
+	PaletteFull   = ""
+	PaletteANSI16 = "ansi16"
+)
+
+// MsgTypeViewOptions is sent by a client to change its rendering
+// preferences; the server answers with the effective options followed by a
+// fresh state
+const MsgTypeViewOptions = "view_options"
+
+// ErrCodeInvalidViewOptions is carried in ErrorPayload when a client sends
+// options the server does not understand
+const ErrCodeInvalidViewOptions = 1004
+
+// ViewOptions holds the rendering preferences of a single client. Clients
+// may set them with query parameters on the WebSocket URL or with a
+// view_options message at any time.
+type ViewOptions struct {
+	ColorMode string `json:"color_mode,omitempty"`
+
+	// ResolveInverse makes the server swap the colors of inverse-video
+	// cells and clear their Inverse flag
+	ResolveInverse bool `json:"resolve_inverse,omitempty"`
+
+	// Palette limits the colors of the cells, applied after ColorMode
+	Palette string `json:"palette,omitempty"`
+
+	// RevealConcealed shows concealed (SGR 8) text as ordinary text
+	RevealConcealed bool `json:"reveal_concealed,omitempty"`
+
+	// WithholdConcealed blanks concealed cells instead of sending their
+	// content. Only the server sets it, see SetViewOptionsPolicy.
+	WithholdConcealed bool `json:"-"`
+}
+
+// Validate reports whether the options are understood by the server
+func (o ViewOptions) Validate() error {
+	switch o.ColorMode {
+	case ColorModeNormal, ColorModeDeuteranopia, ColorModeProtanopia, ColorModeHighContrast:
+	default:
+		return fmt.Errorf("unknown color mode %q", o.ColorMode)
+	}
+	switch o.Palette {
+	case PaletteFull, PaletteANSI16:
+		return nil
+	default:
+		return fmt.Errorf("unknown palette %q", o.Palette)
+	}
+}
+
+// viewOptionsFromRequest reads view options from the connection URL
+func viewOptionsFromRequest(r *http.Request) (ViewOptions, *ErrorPayload) {
+	query := r.URL.Query()
+	opts := ViewOptions{ColorMode: query.Get("color_mode"), Palette: query.Get("palette")}
+	if raw := query.Get("resolve_inverse"); raw != "" {
+		resolve, err := strconv.ParseBool(raw)
+		if err != nil {
+			return ViewOptions{}, &ErrorPayload{Code: ErrCodeInvalidViewOptions, Message: fmt.Sprintf("invalid resolve_inverse %q", raw)}
+		}
+		opts.ResolveInverse = resolve
+	}
+	if raw := query.Get("reveal_concealed"); raw != "" {
+		reveal, err := strconv.ParseBool(raw)
+		if err != nil {
+			return ViewOptions{}, &ErrorPayload{Code: ErrCodeInvalidViewOptions, Message: fmt.Sprintf("invalid reveal_concealed %q", raw)}
+		}
+		opts.RevealConcealed = reveal
+	}
+	if err := opts.Validate(); err != nil {
+		return ViewOptions{}, &ErrorPayload{Code: ErrCodeInvalidViewOptions, Message: err.Error()}
+	}
+	return opts, nil
+}
+
+// SetViewOptionsHandler sets the callback invoked after a client changes its
+// view options, typically to resend the current state
+func (h *Handler) SetViewOptionsHandler(fn func(clientID string)) {
+	h.onViewOptions = fn
+}
+
+// SetViewOptionsPolicy sets a function that adjusts the view options of a
+// client, given the context of its connection, whenever they are set. It
+// may restrict options by role, e.g. withholding concealed text from
+// spectators.
+func (h *Handler) SetViewOptionsPolicy(fn func(ctx context.Context, opts ViewOptions) ViewOptions) {
+	h.viewPolicy = fn
+}
+
+// applyViewPolicy returns opts as adjusted by the view options policy
+func (h *Handler) applyViewPolicy(ctx context.Context, opts ViewOptions) ViewOptions {
+	opts.WithholdConcealed = false
+	if h.viewPolicy != nil {
+		opts = h.viewPolicy(ctx, opts)
+	}
+	return opts
+}
+
+// ViewOptions returns the rendering preferences of a client
+func (h *Handler) ViewOptions(clientID string) (ViewOptions, bool) {
+	h.clientsMu.RLock()
+	client, ok := h.clients[clientID]
+	h.clientsMu.RUnlock()
+
+	if !ok {
+		return ViewOptions{}, false
+	}
+	return client.ViewOptions(), true
+}
+
+// ViewOptions returns the client's rendering preferences
+func (c *Client) ViewOptions() ViewOptions {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.viewOptions
+}
+
+// handleViewOptions applies a view_options message from the client
+func (c *Client) handleViewOptions(payload json.RawMessage) {
+	var opts ViewOptions
+	if err := json.Unmarshal(payload, &opts); err != nil {
+		c.deliver(newMessage(MsgTypeError, &ErrorPayload{Code: ErrCodeInvalidViewOptions, Message: "malformed view options"}))
+		return
+	}
+	if err := opts.Validate(); err != nil {
+		c.deliver(newMessage(MsgTypeError, &ErrorPayload{Code: ErrCodeInvalidViewOptions, Message: err.Error()}))
+		return
+	}
+	opts = c.handler.applyViewPolicy(c.ctx, opts)
+
+	c.mu.Lock()
+	c.viewOptions = opts
+	c.mu.Unlock()
+
+	c.deliver(newMessage(MsgTypeViewOptions, &opts))
+	if c.handler.onViewOptions != nil {
+		c.handler.onViewOptions(c.id)
+	}
+}
+
+// BroadcastStateFunc sends a state rendered for each client's view options.
+// render is called once per distinct set of options among the connected
+// clients; each client's copy carries its own input acknowledgement.
+func (h *Handler) BroadcastStateFunc(render func(opts ViewOptions) *StatePayload) {
+	h.clientsMu.RLock()
+	defer h.clientsMu.RUnlock()
+
+	messages := make(map[ViewOptions]Message, 1)
+	for _, client := range h.clients {
+		opts := client.ViewOptions()
+		msg, ok := messages[opts]
+		if !ok {
+			msg = newMessage(MsgTypeState, render(opts))
+			messages[opts] = msg
+		}
+		if msg.Payload != nil {
+			msg.InputSeq = client.InputSeq()
+			client.deliver(msg)
+		}
+	}
+}
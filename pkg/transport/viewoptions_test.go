@@ -0,0 +1,151 @@
+package transport
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestViewOptionsFromRequest(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		want    ViewOptions
+		wantErr bool
+	}{
+		{"defaults", "", ViewOptions{}, false},
+		{"deuteranopia", "?color_mode=deuteranopia", ViewOptions{ColorMode: ColorModeDeuteranopia}, false},
+		{"high contrast", "?color_mode=high_contrast", ViewOptions{ColorMode: ColorModeHighContrast}, false},
+		{"unknown mode", "?color_mode=sepia", ViewOptions{}, true},
+		{"resolve inverse", "?resolve_inverse=1", ViewOptions{ResolveInverse: true}, false},
+		{"combined", "?color_mode=protanopia&resolve_inverse=true", ViewOptions{ColorMode: ColorModeProtanopia, ResolveInverse: true}, false},
+		{"invalid resolve inverse", "?resolve_inverse=maybe", ViewOptions{}, true},
+		{"ansi16 palette", "?palette=ansi16", ViewOptions{Palette: PaletteANSI16}, false},
+		{"unknown palette", "?palette=cga", ViewOptions{}, true},
+		{"reveal concealed", "?reveal_concealed=true", ViewOptions{RevealConcealed: true}, false},
+		{"invalid reveal concealed", "?reveal_concealed=maybe", ViewOptions{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, errPayload := viewOptionsFromRequest(httptest.NewRequest("GET", "/ws"+tt.query, nil))
+			if (errPayload != nil) != tt.wantErr {
+				t.Fatalf("error = %+v, wantErr %v", errPayload, tt.wantErr)
+			}
+			if errPayload != nil && errPayload.Code != ErrCodeInvalidViewOptions {
+				t.Errorf("code = %d, want %d", errPayload.Code, ErrCodeInvalidViewOptions)
+			}
+			if got != tt.want {
+				t.Errorf("options = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandler_ServeHTTP_RejectsInvalidViewOptions(t *testing.T) {
+	rec := httptest.NewRecorder()
+	NewHandler().ServeHTTP(rec, httptest.NewRequest("GET", "/ws?color_mode=sepia", nil))
+
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want 400", rec.Code)
+	}
+}
+
+func TestClient_HandleViewOptions(t *testing.T) {
+	h := NewHandler()
+	var notified []string
+	h.SetViewOptionsHandler(func(clientID string) { notified = append(notified, clientID) })
+
+	client := newTestClient("a", 4)
+	client.handler = h
+	h.clients["a"] = client
+
+	client.handleMessage(Message{Type: MsgTypeViewOptions, Payload: json.RawMessage(`{"color_mode":"protanopia"}`)})
+	if opts, _ := h.ViewOptions("a"); opts.ColorMode != ColorModeProtanopia {
+		t.Errorf("color mode = %q, want %q", opts.ColorMode, ColorModeProtanopia)
+	}
+	if msg := <-client.send; msg.Type != MsgTypeViewOptions {
+		t.Errorf("ack type = %q, want %q", msg.Type, MsgTypeViewOptions)
+	}
+	if len(notified) != 1 || notified[0] != "a" {
+		t.Errorf("notified = %v, want [a]", notified)
+	}
+
+	client.handleMessage(Message{Type: MsgTypeViewOptions, Payload: json.RawMessage(`{"color_mode":"sepia"}`)})
+	if opts, _ := h.ViewOptions("a"); opts.ColorMode != ColorModeProtanopia {
+		t.Errorf("invalid options replaced color mode with %q", opts.ColorMode)
+	}
+	msg := <-client.send
+	var errPayload ErrorPayload
+	if msg.Type != MsgTypeError || json.Unmarshal(msg.Payload, &errPayload) != nil || errPayload.Code != ErrCodeInvalidViewOptions {
+		t.Errorf("expected invalid options error, got %s %s", msg.Type, msg.Payload)
+	}
+	if len(notified) != 1 {
+		t.Errorf("handler notified for invalid options")
+	}
+}
+
+func TestClient_HandleViewOptions_AppliesPolicy(t *testing.T) {
+	type spectatorKey struct{}
+	h := NewHandler()
+	h.SetViewOptionsPolicy(func(ctx context.Context, opts ViewOptions) ViewOptions {
+		if ctx.Value(spectatorKey{}) != nil {
+			opts.RevealConcealed, opts.WithholdConcealed = false, true
+		}
+		return opts
+	})
+
+	for _, tt := range []struct {
+		id        string
+		ctx       context.Context
+		want      ViewOptions
+		wantReply string
+	}{
+		{"player", context.Background(), ViewOptions{RevealConcealed: true}, `{"reveal_concealed":true}`},
+		{"spectator", context.WithValue(context.Background(), spectatorKey{}, true), ViewOptions{WithholdConcealed: true}, `{}`},
+	} {
+		client := newTestClient(tt.id, 4)
+		client.handler, client.ctx = h, tt.ctx
+		h.clients[tt.id] = client
+
+		client.handleMessage(Message{Type: MsgTypeViewOptions, Payload: json.RawMessage(`{"reveal_concealed":true}`)})
+		if opts, _ := h.ViewOptions(tt.id); opts != tt.want {
+			t.Errorf("%s: options = %+v, want %+v", tt.id, opts, tt.want)
+		}
+		if msg := <-client.send; string(msg.Payload) != tt.wantReply {
+			t.Errorf("%s: ack = %s, want the effective options %s", tt.id, msg.Payload, tt.wantReply)
+		}
+	}
+}
+
+func TestHandler_BroadcastStateFunc_RendersOncePerOptions(t *testing.T) {
+	h := NewHandler()
+	modes := map[string]string{"a": "", "b": ColorModeHighContrast, "c": ColorModeHighContrast}
+	for id, mode := range modes {
+		client := newTestClient(id, 1)
+		client.viewOptions = ViewOptions{ColorMode: mode}
+		h.clients[id] = client
+	}
+
+	renders := map[ViewOptions]int{}
+	h.BroadcastStateFunc(func(opts ViewOptions) *StatePayload {
+		renders[opts]++
+		return &StatePayload{Buffer: [][]Cell{{{Char: "x", FgColor: opts.ColorMode}}}}
+	})
+
+	if len(renders) != 2 || renders[ViewOptions{ColorMode: ColorModeHighContrast}] != 1 {
+		t.Errorf("renders = %v, want one per distinct options", renders)
+	}
+	for id, mode := range modes {
+		msg := <-h.clients[id].send
+		var state StatePayload
+		if err := json.Unmarshal(msg.Payload, &state); err != nil {
+			t.Fatalf("client %s: invalid payload: %v", id, err)
+		}
+		if state.Buffer[0][0].FgColor != mode {
+			t.Errorf("client %s: received state rendered for %q, want %q", id, state.Buffer[0][0].FgColor, mode)
+		}
+	}
+}
@@ -7,10 +7,11 @@ import (
 	"fmt"
 	"net/http"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/opd-ai/go-gamelaunch-www/pkg/i18n"
 	"nhooyr.io/websocket"
-	"nhooyr.io/websocket/wsjson"
 )
 
 // Message types for WebSocket communication
@@ -23,12 +24,22 @@ const (
 	MsgTypeError      = "error"
 	MsgTypeConnect    = "connect"
 	MsgTypeDisconnect = "disconnect"
+	MsgTypeSystem     = "system"
+
+	MsgTypeAuthChallenge = "auth_challenge"
+	MsgTypeHostKey       = "host_key"
+	MsgTypeTileset       = "tileset"
 )
 
+// ErrCodeInputRejected is carried in ErrorPayload when the server refuses a
+// client's input, e.g. because the client may only spectate
+const ErrCodeInputRejected = 1004
+
 // Message represents a WebSocket message
 type Message struct {
 	Type      string          `json:"type"`
 	Payload   json.RawMessage `json:"payload,omitempty"`
+	InputSeq  uint64          `json:"input_seq,omitempty"` // on state messages, see InputPayload.Seq
 	Timestamp int64           `json:"timestamp"`
 }
 
@@ -39,11 +50,83 @@ type StatePayload struct {
 	Height    int      `json:"height"`
 	CursorX   int      `json:"cursor_x"`
 	CursorY   int      `json:"cursor_y"`
+	Pinned    []Region `json:"pinned,omitempty"`
+	Echo      bool     `json:"echo"` // clients may echo typed characters locally
 	Version   uint64   `json:"version"`
 	Timestamp int64    `json:"timestamp"`
+
+	Progress  *Progress  `json:"progress,omitempty"`  // turn counter and game clock, when known
+	Landmarks []Landmark `json:"landmarks,omitempty"` // semantic regions, for navigation and ARIA labels
+	Expiry    *Expiry    `json:"expiry,omitempty"`    // pending end of the session
+	Stall     *Stall     `json:"stall,omitempty"`     // the game stopped answering
+	Broadcast *Broadcast `json:"broadcast,omitempty"` // message of an admin to all clients
+
+	Scrollback *Scrollback `json:"scrollback,omitempty"` // lines kept at /game/scrollback
+}
+
+// Expiry announces that the session ends at Deadline, in Unix milliseconds,
+// unless Cancelable and the player presses a key first
+type Expiry struct {
+	Reason     string `json:"reason"`
+	Deadline   int64  `json:"deadline"`
+	Message    string `json:"message,omitempty"`
+	Cancelable bool   `json:"cancelable"`
+}
+
+// Stall reports that the game has not answered input since Since, in Unix
+// milliseconds, although the connection is up; Probes counts the probes the
+// server sent it meanwhile
+type Stall struct {
+	Since  int64 `json:"since"`
+	Probes int   `json:"probes,omitempty"`
+}
+
+// Broadcast is a message of an admin to all clients, sent at Sent in Unix
+// milliseconds. Clients show it once, taking the entry of Translations for
+// their locale, e.g. "pt-BR", or else for its language over Message.
+type Broadcast struct {
+	Message      string            `json:"message"`
+	Level        string            `json:"level"`
+	Translations map[string]string `json:"translations,omitempty"`
+	Sent         int64             `json:"sent"`
+}
+
+// Scrollback numbers the lines that scrolled off the screen from 0; those
+// from First to Total-1 are kept
+type Scrollback struct {
+	First uint64 `json:"first"`
+	Total uint64 `json:"total"`
+}
+
+// Progress is the game's turn counter and in-game clock as shown on its
+// status lines
+type Progress struct {
+	Turn  int64  `json:"turn,omitempty"`
+	Clock string `json:"clock,omitempty"`
 }
 
-// Cell represents a terminal cell
+// Landmark is a rectangle of the screen with a semantic role, e.g. "map",
+// "status" or "message", which clients offer for keyboard navigation and
+// label for assistive technology
+type Landmark struct {
+	Kind   string `json:"kind"`
+	Label  string `json:"label,omitempty"`
+	X      int    `json:"x"`
+	Y      int    `json:"y"`
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+}
+
+// Region is a band of rows, Top to Bottom inclusive, that the server never
+// scrolls; clients may use it as a layout hint (e.g. to style status lines)
+type Region struct {
+	Name   string `json:"name"`
+	Top    int    `json:"top"`
+	Bottom int    `json:"bottom"`
+}
+
+// Cell represents a terminal cell. Char is a whole grapheme cluster, and
+// empty for the right half of a Wide character.
 type Cell struct {
 	Char    string `json:"char"`
 	FgColor string `json:"fg_color"`
@@ -53,46 +136,150 @@ type Cell struct {
 	Blink   bool   `json:"blink"`
 	TileX   int    `json:"tile_x,omitempty"`
 	TileY   int    `json:"tile_y,omitempty"`
+	Wide    bool   `json:"wide,omitempty"` // takes this column and the next
+
+	Underline      string `json:"underline,omitempty"`       // single, double, curly, dotted or dashed
+	UnderlineColor string `json:"underline_color,omitempty"` // empty for the foreground color
+	Overline       bool   `json:"overline,omitempty"`
+	Concealed      bool   `json:"concealed,omitempty"` // hidden text (SGR 8); blank when withheld
+
+	Meta map[string]string `json:"meta,omitempty"` // namespaced metadata from parser plugins
+}
+
+// SystemPayload contains a server-generated message that clients display
+// as an overlay or system line, independent of game output
+type SystemPayload struct {
+	Message string `json:"message"`
+	Level   string `json:"level,omitempty"` // info, warning, critical
+}
+
+// AuthChallengePayload relays a keyboard-interactive prompt of the game
+// server's SSH login. Clients answer every prompt, in order, through
+// POST /ssh/challenge with the challenge ID; no prompts means the challenge
+// was answered or expired.
+type AuthChallengePayload struct {
+	ID          uint64       `json:"id"`
+	Name        string       `json:"name,omitempty"`
+	Instruction string       `json:"instruction,omitempty"`
+	Prompts     []AuthPrompt `json:"prompts"`
+}
+
+// AuthPrompt is a question of an AuthChallengePayload; Echo is false for
+// secrets such as passwords and one-time codes
+type AuthPrompt struct {
+	Text string `json:"text"`
+	Echo bool   `json:"echo"`
+}
+
+// Statuses of a HostKeyPayload
+const (
+	HostKeyPending  = "pending"
+	HostKeyApproved = "approved"
+	HostKeyDenied   = "denied"
+)
+
+// HostKeyPayload asks for a decision on a game server host key that is not
+// in known_hosts, or that differs from the Known keys recorded for Host.
+// Authorized clients approve or deny it through POST /ssh/hostkey; a later
+// message with the same ID reports the outcome.
+type HostKeyPayload struct {
+	ID          uint64   `json:"id"`
+	Status      string   `json:"status"`
+	Host        string   `json:"host"`
+	KeyType     string   `json:"key_type"`
+	Fingerprint string   `json:"fingerprint"`     // SHA256, as printed by ssh-keygen -l
+	Known       []string `json:"known,omitempty"` // fingerprints on record when the key changed
+}
+
+// TilesetPayload announces that the tileset image changed, e.g. because a
+// subset sheet grew; clients download Image before drawing newer states
+type TilesetPayload struct {
+	Image string `json:"image"` // URL of the tileset image
 }
 
 // InputPayload contains user input data
 type InputPayload struct {
 	Input string `json:"input"`
+	Seq   uint64 `json:"seq,omitempty"` // client-assigned, increasing
 }
 
 // ErrorPayload contains error information
 type ErrorPayload struct {
-	Code    int    `json:"code"`
-	Message string `json:"message"`
+	Code          int    `json:"code"`
+	Message       string `json:"message"`
+	Key           string `json:"key,omitempty"`            // see i18n.English, for clients that localize themselves
+	CorrelationID string `json:"correlation_id,omitempty"` // of the client, for reporting the error
+
+	args []any // of the message Key
+}
+
+// newErrorPayload returns an error with the English text of message key,
+// which Admit translates for the client
+func newErrorPayload(code int, key string, args ...any) *ErrorPayload {
+	return &ErrorPayload{Code: code, Message: fmt.Sprintf(i18n.English[key], args...), Key: key, args: args}
 }
 
-// Client represents a connected WebSocket client
+// Client represents a connected client
 type Client struct {
-	conn    *websocket.Conn
-	send    chan Message
-	handler *Handler
-	id      string
-	version uint64
-	mu      sync.Mutex
-	ctx     context.Context
-	cancel  context.CancelFunc
+	conn     MessageConn
+	send     chan Message
+	handler  *Handler
+	id       string
+	ip       string
+	protocol int
+	corrID   string // see ClientStats.CorrelationID
+	locale   string // see i18n.RequestLocale
+	version  uint64
+	mu       sync.Mutex
+	ctx      context.Context
+	cancel   context.CancelFunc
+
+	// Rendering preferences, guarded by mu
+	viewOptions ViewOptions
+
+	// Spectator mode, guarded by mu
+	spectating bool
+
+	// Sequence number of the last input delivered to the game, guarded by mu
+	inputSeq uint64
+
+	// Traffic counters, guarded by mu
+	bytesSent     uint64
+	bytesReceived uint64
+
+	// Backpressure state, guarded by mu
+	skipped          uint64
+	consecutiveSkips int
+	keyframeOnly     bool
+	pendingState     *Message
+	stateReady       chan struct{}
 }
 
 // Handler manages WebSocket connections
 type Handler struct {
-	clients      map[string]*Client
-	clientsMu    sync.RWMutex
-	onInput      func(clientID, input string) error
-	onConnect    func(clientID string)
-	onDisconnect func(clientID string)
-	idCounter    uint64
-	idMu         sync.Mutex
+	clients       map[string]*Client
+	clientsMu     sync.RWMutex
+	onInput       func(clientID, input string) error
+	onConnect     func(clientID string)
+	onDisconnect  func(clientID string)
+	onViewOptions func(clientID string)
+	viewPolicy    func(ctx context.Context, opts ViewOptions) ViewOptions
+	idCounter     uint64
+	idMu          sync.Mutex
+	limits        connectionLimits
+	slotsMu       sync.Mutex
+	catalog       *i18n.Catalog
+
+	// Traffic of all clients since the handler was created
+	bytesSent     atomic.Uint64
+	bytesReceived atomic.Uint64
 }
 
 // NewHandler creates a new WebSocket handler
 func NewHandler() *Handler {
 	return &Handler{
 		clients: make(map[string]*Client),
+		limits:  connectionLimits{perIP: make(map[string]int)},
 	}
 }
 
@@ -111,8 +298,21 @@ func (h *Handler) SetDisconnectHandler(fn func(clientID string)) {
 	h.onDisconnect = fn
 }
 
+// SetCatalog sets the translations of the messages sent to clients; nil
+// sends English
+func (h *Handler) SetCatalog(c *i18n.Catalog) {
+	h.catalog = c
+}
+
 // ServeHTTP implements http.Handler for WebSocket upgrades
 func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	admission, refusal := h.Admit(r)
+	if refusal != nil {
+		refusal.WriteHTTP(w)
+		return
+	}
+	defer admission.Release()
+
 	conn, err := websocket.Accept(w, r, &websocket.AcceptOptions{
 		OriginPatterns: []string{"*"},
 	})
@@ -121,22 +321,45 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	h.handleConnection(r.Context(), conn)
+	admission.Serve(r.Context(), &wsConn{conn: conn})
 }
 
-// handleConnection manages a single WebSocket connection
-func (h *Handler) handleConnection(ctx context.Context, conn *websocket.Conn) {
-	clientCtx, cancel := context.WithCancel(ctx)
+// handleConnection manages a single client connection
+func (h *Handler) handleConnection(ctx context.Context, conn MessageConn, ip string, protocol int, viewOptions ViewOptions, spectating bool) {
+	// Clients without a correlation ID from their request go by their ID
+	id := h.generateClientID()
+	corrID := CorrelationID(ctx)
+	if corrID == "" {
+		corrID = id
+	}
+	clientCtx, cancel := context.WithCancel(WithCorrelationID(ctx, corrID))
 
 	client := &Client{
-		conn:    conn,
-		send:    make(chan Message, 256),
-		handler: h,
-		id:      h.generateClientID(),
-		ctx:     clientCtx,
-		cancel:  cancel,
+		conn:        conn,
+		send:        make(chan Message, 256),
+		stateReady:  make(chan struct{}, 1),
+		handler:     h,
+		id:          id,
+		corrID:      corrID,
+		locale:      i18n.Locale(ctx),
+		ip:          ip,
+		protocol:    protocol,
+		ctx:         clientCtx,
+		cancel:      cancel,
+		viewOptions: h.applyViewPolicy(clientCtx, viewOptions),
+		spectating:  spectating,
 	}
 
+	// Announce the negotiated protocol before any other message
+	client.send <- newMessage(MsgTypeConnect, &ConnectPayload{
+		ClientID:           client.id,
+		CorrelationID:      client.corrID,
+		ProtocolVersion:    protocol,
+		MinProtocolVersion: MinProtocolVersion,
+		MaxProtocolVersion: ProtocolVersion,
+		Spectating:         spectating,
+	})
+
 	h.registerClient(client)
 	defer h.unregisterClient(client)
 
@@ -180,29 +403,79 @@ func (h *Handler) unregisterClient(client *Client) {
 
 // BroadcastState sends state to all connected clients
 func (h *Handler) BroadcastState(state *StatePayload) {
-	payload, err := json.Marshal(state)
-	if err != nil {
-		return
+	h.broadcast(MsgTypeState, state)
+}
+
+// BroadcastSystem sends a system message to all connected clients
+func (h *Handler) BroadcastSystem(system *SystemPayload) {
+	h.broadcast(MsgTypeSystem, system)
+}
+
+// BroadcastSystemFunc sends a system message rendered for each client's
+// locale. render is called once per distinct locale among the connected
+// clients, with "" for clients that did not ask for one.
+func (h *Handler) BroadcastSystemFunc(render func(locale string) *SystemPayload) {
+	h.clientsMu.RLock()
+	defer h.clientsMu.RUnlock()
+
+	messages := make(map[string]Message, 1)
+	for _, client := range h.clients {
+		msg, ok := messages[client.locale]
+		if !ok {
+			msg = newMessage(MsgTypeSystem, render(client.locale))
+			messages[client.locale] = msg
+		}
+		if msg.Payload != nil {
+			client.deliver(msg)
+		}
 	}
+}
 
-	msg := Message{
-		Type:      MsgTypeState,
-		Payload:   payload,
-		Timestamp: time.Now().UnixMilli(),
+// BroadcastAuthChallenge sends an SSH login prompt to all connected clients
+func (h *Handler) BroadcastAuthChallenge(challenge *AuthChallengePayload) {
+	h.broadcast(MsgTypeAuthChallenge, challenge)
+}
+
+// BroadcastHostKey sends a host key decision to all connected clients
+func (h *Handler) BroadcastHostKey(hostKey *HostKeyPayload) {
+	h.broadcast(MsgTypeHostKey, hostKey)
+}
+
+// BroadcastTileset tells all connected clients to download a new tileset
+// image
+func (h *Handler) BroadcastTileset(tileset *TilesetPayload) {
+	h.broadcast(MsgTypeTileset, tileset)
+}
+
+// broadcast marshals v as the payload of a msgType message and queues it
+// for every connected client
+func (h *Handler) broadcast(msgType string, v interface{}) {
+	msg := newMessage(msgType, v)
+	if msg.Payload == nil {
+		return
 	}
 
 	h.clientsMu.RLock()
 	defer h.clientsMu.RUnlock()
 
 	for _, client := range h.clients {
-		select {
-		case client.send <- msg:
-		default:
-			// Client send buffer full, skip
-		}
+		client.deliver(msg)
 	}
 }
 
+// newMessage builds a message with v marshalled as its payload. The payload
+// is left empty if v cannot be marshalled.
+func newMessage(msgType string, v interface{}) Message {
+	msg := Message{
+		Type:      msgType,
+		Timestamp: time.Now().UnixMilli(),
+	}
+	if payload, err := json.Marshal(v); err == nil {
+		msg.Payload = payload
+	}
+	return msg
+}
+
 // SendToClient sends a message to a specific client
 func (h *Handler) SendToClient(clientID string, msg Message) error {
 	h.clientsMu.RLock()
@@ -221,6 +494,19 @@ func (h *Handler) SendToClient(clientID string, msg Message) error {
 	}
 }
 
+// ClientContext returns the context of a client's connection, which carries
+// the values of the HTTP request that opened it
+func (h *Handler) ClientContext(clientID string) (context.Context, bool) {
+	h.clientsMu.RLock()
+	defer h.clientsMu.RUnlock()
+
+	client, ok := h.clients[clientID]
+	if !ok {
+		return nil, false
+	}
+	return client.ctx, true
+}
+
 // GetClientCount returns the number of connected clients
 func (h *Handler) GetClientCount() int {
 	h.clientsMu.RLock()
@@ -230,17 +516,60 @@ func (h *Handler) GetClientCount() int {
 
 // readPump handles incoming messages from the client
 func (c *Client) readPump() {
-	defer c.conn.Close(websocket.StatusNormalClosure, "")
+	defer c.conn.Close()
 
 	for {
-		var msg Message
-		err := wsjson.Read(c.ctx, c.conn, &msg)
+		data, err := c.conn.Read(c.ctx)
 		if err != nil {
 			return
 		}
+		if err := c.receive(data); err != nil {
+			return
+		}
+	}
+}
 
-		c.handleMessage(msg)
+// receive decodes and handles a message read from the client. Malformed
+// messages are an error, which ends the connection.
+func (c *Client) receive(data []byte) error {
+	c.countTraffic(0, len(data))
+
+	var msg Message
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return err
 	}
+	c.handleMessage(msg)
+	return nil
+}
+
+// write sends a single message to the client
+func (c *Client) write(msg Message) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	if err := c.conn.Write(c.ctx, data); err != nil {
+		return err
+	}
+	c.countTraffic(len(data), 0)
+	return nil
+}
+
+// countTraffic adds to the client's and the handler's traffic counters
+func (c *Client) countTraffic(sent, received int) {
+	c.mu.Lock()
+	c.bytesSent += uint64(sent)
+	c.bytesReceived += uint64(received)
+	c.mu.Unlock()
+
+	c.handler.bytesSent.Add(uint64(sent))
+	c.handler.bytesReceived.Add(uint64(received))
+}
+
+// Traffic returns the bytes written to and read from all clients since the
+// handler was created, including clients that have disconnected
+func (h *Handler) Traffic() (sent, received uint64) {
+	return h.bytesSent.Load(), h.bytesReceived.Load()
 }
 
 // writePump handles outgoing messages to the client
@@ -254,16 +583,28 @@ func (c *Client) writePump() {
 			if !ok {
 				return
 			}
-			if err := wsjson.Write(c.ctx, c.conn, msg); err != nil {
+			if c.skipStale(msg) {
+				continue
+			}
+			if err := c.write(msg); err != nil {
 				return
 			}
+		case <-c.stateReady:
+			msg := c.takePendingState()
+			if msg == nil {
+				continue
+			}
+			if err := c.write(*msg); err != nil {
+				return
+			}
+			c.keyframeWritten()
 		case <-ticker.C:
 			// Send ping
 			msg := Message{
 				Type:      MsgTypePing,
 				Timestamp: time.Now().UnixMilli(),
 			}
-			if err := wsjson.Write(c.ctx, c.conn, msg); err != nil {
+			if err := c.write(msg); err != nil {
 				return
 			}
 		case <-c.ctx.Done():
@@ -278,10 +619,27 @@ func (c *Client) handleMessage(msg Message) {
 	case MsgTypeInput:
 		var input InputPayload
 		if err := json.Unmarshal(msg.Payload, &input); err == nil {
+			if c.duplicateInput(input.Seq) {
+				return
+			}
+			if c.Spectating() {
+				c.rejectSpectatorInput()
+				return
+			}
 			if c.handler.onInput != nil {
-				c.handler.onInput(c.id, input.Input)
+				if err := c.handler.onInput(c.id, input.Input); err != nil {
+					c.handler.SendToClient(c.id, newMessage(MsgTypeError, &ErrorPayload{
+						Code:          ErrCodeInputRejected,
+						Message:       err.Error(),
+						CorrelationID: c.corrID,
+					}))
+				} else {
+					c.ackInput(input.Seq)
+				}
 			}
 		}
+	case MsgTypeViewOptions:
+		c.handleViewOptions(msg.Payload)
 	case MsgTypePong:
 		// Client responded to ping
 	}
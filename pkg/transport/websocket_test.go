@@ -2,6 +2,10 @@
 package transport
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
 	"testing"
 )
 
@@ -156,3 +160,106 @@ func TestHandler_SendToClient_FailsForUnknownClient(t *testing.T) {
 		t.Error("expected error for unknown client")
 	}
 }
+
+func TestHandler_BroadcastSystem_QueuesMessageForAllClients(t *testing.T) {
+	h := NewHandler()
+	clients := []*Client{
+		{id: "a", send: make(chan Message, 1)},
+		{id: "b", send: make(chan Message, 1)},
+	}
+	for _, c := range clients {
+		h.clients[c.id] = c
+	}
+
+	h.BroadcastSystem(&SystemPayload{Message: "restart in 5 minutes", Level: "warning"})
+
+	for _, c := range clients {
+		select {
+		case msg := <-c.send:
+			if msg.Type != MsgTypeSystem {
+				t.Errorf("client %s: type = %q, want %q", c.id, msg.Type, MsgTypeSystem)
+			}
+			if !strings.Contains(string(msg.Payload), "restart in 5 minutes") {
+				t.Errorf("client %s: payload missing message: %s", c.id, msg.Payload)
+			}
+		default:
+			t.Errorf("client %s: no message queued", c.id)
+		}
+	}
+}
+
+func TestHandler_BroadcastSystemFunc_RendersPerLocale(t *testing.T) {
+	h := NewHandler()
+	clients := []*Client{
+		{id: "a", locale: "de", send: make(chan Message, 1)},
+		{id: "b", locale: "de", send: make(chan Message, 1)},
+		{id: "c", send: make(chan Message, 1)},
+	}
+	for _, c := range clients {
+		h.clients[c.id] = c
+	}
+
+	renders := 0
+	h.BroadcastSystemFunc(func(locale string) *SystemPayload {
+		renders++
+		if locale == "de" {
+			return &SystemPayload{Message: "Neustart"}
+		}
+		return &SystemPayload{Message: "restart"}
+	})
+
+	if renders != 2 {
+		t.Errorf("renders = %d, want 2", renders)
+	}
+	want := map[string]string{"a": "Neustart", "b": "Neustart", "c": "restart"}
+	for _, c := range clients {
+		msg := <-c.send
+		var payload SystemPayload
+		if err := json.Unmarshal(msg.Payload, &payload); err != nil {
+			t.Fatalf("client %s: failed to decode payload: %v", c.id, err)
+		}
+		if payload.Message != want[c.id] {
+			t.Errorf("client %s: message = %q, want %q", c.id, payload.Message, want[c.id])
+		}
+	}
+}
+
+func TestClient_HandleMessage_ReportsRejectedInput(t *testing.T) {
+	h := NewHandler()
+	h.SetInputHandler(func(clientID, input string) error {
+		return errors.New("spectators cannot send input")
+	})
+	c := &Client{id: "a", send: make(chan Message, 1), handler: h}
+	h.clients[c.id] = c
+
+	payload, _ := json.Marshal(InputPayload{Input: "h"})
+	c.handleMessage(Message{Type: MsgTypeInput, Payload: payload})
+
+	select {
+	case msg := <-c.send:
+		var errPayload ErrorPayload
+		if err := json.Unmarshal(msg.Payload, &errPayload); err != nil {
+			t.Fatalf("failed to decode error: %v", err)
+		}
+		if msg.Type != MsgTypeError || errPayload.Code != ErrCodeInputRejected {
+			t.Errorf("got %s %+v, want input rejected error", msg.Type, errPayload)
+		}
+	default:
+		t.Error("no error queued for rejected input")
+	}
+}
+
+func TestHandler_ClientContext(t *testing.T) {
+	h := NewHandler()
+	type key struct{}
+	ctx := context.WithValue(context.Background(), key{}, "alice")
+	h.clients["a"] = &Client{id: "a", ctx: ctx}
+
+	got, ok := h.ClientContext("a")
+	if !ok || got.Value(key{}) != "alice" {
+		t.Errorf("ClientContext(a) = %v, %v; want request context", got, ok)
+	}
+	if _, ok := h.ClientContext("b"); ok {
+		t.Error("ClientContext(b) should report an unknown client")
+	}
+}
@@ -0,0 +1,18 @@
+package ttyrec
+
+import (
+	"context"
+	"time"
+)
+
+// Play passes the data of each frame to write, waiting between frames as
+// long as the recording did divided by speed. Pauses are capped at maxDelay
+// when it is positive, so idle stretches are skipped. Play returns early
+// with the error of write or ctx.
+func Play(ctx context.Context, frames []Frame, speed float64, maxDelay time.Duration, write func([]byte) error) error {
+	player, err := NewPlayer(frames, speed, maxDelay)
+	if err != nil {
+		return err
+	}
+	return player.Run(ctx, write)
+}
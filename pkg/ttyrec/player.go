@@ -0,0 +1,149 @@
+package ttyrec
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Player plays frames like Play, but may be paused, resumed, sped up and
+// slowed down while it runs, e.g. from the controls of a replay viewer
+type Player struct {
+	frames   []Frame
+	maxDelay time.Duration
+
+	mu     sync.Mutex
+	speed  float64
+	paused bool
+	played int           // frames written by the current run
+	wake   chan struct{} // closed and replaced when the controls change
+}
+
+// NewPlayer creates a player of frames at speed, capping pauses at maxDelay
+// when it is positive
+func NewPlayer(frames []Frame, speed float64, maxDelay time.Duration) (*Player, error) {
+	if speed <= 0 {
+		return nil, fmt.Errorf("ttyrec: invalid speed %g", speed)
+	}
+	return &Player{frames: frames, maxDelay: maxDelay, speed: speed, wake: make(chan struct{})}, nil
+}
+
+// Run passes the data of each frame to write, from the first, waiting
+// between frames as long as the recording did divided by the speed. It
+// returns once the last frame is written, or early with the error of write
+// or ctx.
+func (p *Player) Run(ctx context.Context, write func([]byte) error) error {
+	p.mu.Lock()
+	p.played = 0
+	p.mu.Unlock()
+
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	<-timer.C
+
+	for i, frame := range p.frames {
+		var gap time.Duration
+		if i > 0 {
+			gap = frame.Time.Sub(p.frames[i-1].Time)
+		}
+		if err := p.wait(ctx, timer, gap); err != nil {
+			return err
+		}
+		if err := write(frame.Data); err != nil {
+			return err
+		}
+		p.mu.Lock()
+		p.played = i + 1
+		p.mu.Unlock()
+	}
+	return nil
+}
+
+// wait sleeps for gap of recording time while the player is not paused,
+// following speed changes made meanwhile
+func (p *Player) wait(ctx context.Context, timer *time.Timer, gap time.Duration) error {
+	for {
+		p.mu.Lock()
+		speed, paused, wake := p.speed, p.paused, p.wake
+		p.mu.Unlock()
+
+		if paused {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-wake:
+				continue
+			}
+		}
+		if gap <= 0 {
+			return nil
+		}
+
+		delay := time.Duration(float64(gap) / speed)
+		if p.maxDelay > 0 && delay > p.maxDelay {
+			delay = p.maxDelay
+			gap = time.Duration(float64(delay) * speed)
+		}
+		began := time.Now()
+		timer.Reset(delay)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C:
+			return nil
+		case <-wake:
+			timer.Stop()
+			gap -= time.Duration(float64(time.Since(began)) * speed)
+		}
+	}
+}
+
+// notifyLocked wakes a waiting Run to apply changed controls; p.mu must be
+// held
+func (p *Player) notifyLocked() {
+	close(p.wake)
+	p.wake = make(chan struct{})
+}
+
+// SetSpeed changes the speed, also of the pause in progress
+func (p *Player) SetSpeed(speed float64) error {
+	if speed <= 0 {
+		return fmt.Errorf("ttyrec: invalid speed %g", speed)
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.speed = speed
+	p.notifyLocked()
+	return nil
+}
+
+// Speed returns the current speed
+func (p *Player) Speed() float64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.speed
+}
+
+// SetPaused pauses or resumes playback before the next frame
+func (p *Player) SetPaused(paused bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.paused = paused
+	p.notifyLocked()
+}
+
+// Paused reports whether playback is paused
+func (p *Player) Paused() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.paused
+}
+
+// Position returns the number of frames played by the current run and the
+// number of frames in the recording
+func (p *Player) Position() (played, total int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.played, len(p.frames)
+}
@@ -0,0 +1,74 @@
+package ttyrec
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestPlayer_PauseAndSpeed(t *testing.T) {
+	start := time.Unix(1700000000, 0)
+	frames := []Frame{
+		{Time: start, Data: []byte("a")},
+		{Time: start.Add(time.Hour), Data: []byte("b")},
+	}
+	if _, err := NewPlayer(frames, -1, 0); err == nil {
+		t.Error("NewPlayer() with a negative speed succeeded, want error")
+	}
+	player, err := NewPlayer(frames, 1, 0)
+	if err != nil {
+		t.Fatalf("NewPlayer() failed: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	written := make(chan string, len(frames))
+	done := make(chan error, 1)
+	go func() {
+		done <- player.Run(ctx, func(data []byte) error {
+			written <- string(data)
+			return nil
+		})
+	}()
+
+	if got := <-written; got != "a" {
+		t.Fatalf("first frame = %q, want a", got)
+	}
+	player.SetPaused(true)
+	if !player.Paused() {
+		t.Error("Paused() = false after SetPaused(true)")
+	}
+
+	// Speeding up while paused does not resume playback; at a million
+	// times the speed the hour-long gap would take 3.6ms
+	if err := player.SetSpeed(1e6); err != nil {
+		t.Fatalf("SetSpeed() failed: %v", err)
+	}
+	select {
+	case got := <-written:
+		t.Fatalf("frame %q written while paused", got)
+	case <-time.After(50 * time.Millisecond):
+	}
+	if played, total := player.Position(); played != 1 || total != 2 {
+		t.Errorf("Position() = %d, %d; want 1, 2", played, total)
+	}
+
+	if err := player.SetSpeed(1e9); err != nil {
+		t.Fatalf("SetSpeed() failed: %v", err)
+	}
+	player.SetPaused(false)
+	select {
+	case got := <-written:
+		if got != "b" {
+			t.Errorf("second frame = %q, want b", got)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("playback did not resume at the new speed")
+	}
+	if err := <-done; err != nil {
+		t.Errorf("Run() = %v, want nil at the end of the recording", err)
+	}
+	if err := player.SetSpeed(0); err == nil || player.Speed() != 1e9 {
+		t.Errorf("SetSpeed(0) = %v, speed %g; want error and unchanged speed", err, player.Speed())
+	}
+}
@@ -0,0 +1,138 @@
+package ttyrec
+
+import (
+	"io"
+	"sync"
+	"time"
+)
+
+// Ring keeps the terminal output of a recent time window in memory as
+// frames, for instant replay without recording to disk
+type Ring struct {
+	window   time.Duration
+	maxBytes int
+	now      func() time.Time
+
+	mu        sync.Mutex
+	frames    []Frame
+	head      int // index of the oldest retained frame
+	size      int // payload bytes of the retained frames
+	keyframer Keyframer
+	evicted   bool // frames were dropped since the keyframer was set
+}
+
+// Keyframer rebuilds the screen the frames dropped from a Ring left, so
+// the retained output, which only updates that screen, can be replayed
+type Keyframer interface {
+	// Drop applies the output of a frame leaving the ring
+	Drop(data []byte)
+	// Keyframe returns output drawing the screen the dropped frames left,
+	// whatever the terminal showed before
+	Keyframe() []byte
+}
+
+// SetKeyframer makes the ring start its frames with a keyframe from k once
+// it dropped frames, instead of mid-stream. The ring calls k with its lock
+// held.
+func (r *Ring) SetKeyframer(k Keyframer) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.keyframer = k
+	r.evicted = false
+}
+
+// NewRing creates a ring keeping the output of the last window, up to
+// maxBytes of payload; zero maxBytes means no size bound
+func NewRing(window time.Duration, maxBytes int) *Ring {
+	return &Ring{window: window, maxBytes: maxBytes, now: time.Now}
+}
+
+// Write records a copy of data as a frame produced now. It never fails, so
+// it can be used as an output tap.
+func (r *Ring) Write(data []byte) {
+	if len(data) == 0 {
+		return
+	}
+	frame := Frame{Time: r.now(), Data: append([]byte(nil), data...)}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.frames = append(r.frames, frame)
+	r.size += len(frame.Data)
+	r.trim(frame.Time)
+}
+
+// Frames returns the retained frames, oldest first, after a keyframe of
+// the screen the dropped frames left when there is a keyframer
+func (r *Ring) Frames() []Frame {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := r.now()
+	r.trim(now)
+	frames := make([]Frame, 0, len(r.frames)-r.head+1)
+	if r.keyframer != nil && r.evicted {
+		keyframe := Frame{Time: now, Data: r.keyframer.Keyframe()}
+		if r.head < len(r.frames) {
+			keyframe.Time = r.frames[r.head].Time
+		}
+		frames = append(frames, keyframe)
+	}
+	return append(frames, r.frames[r.head:]...)
+}
+
+// Size returns the payload bytes of the retained frames
+func (r *Ring) Size() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.trim(r.now())
+	return r.size
+}
+
+// WriteTo writes the retained frames to w as a ttyrec stream
+func (r *Ring) WriteTo(w io.Writer) (int64, error) {
+	counter := &countingWriter{w: w}
+	writer := NewWriter(counter)
+	for _, frame := range r.Frames() {
+		if err := writer.WriteFrame(frame); err != nil {
+			return counter.n, err
+		}
+	}
+	return counter.n, nil
+}
+
+// trim drops frames older than the window and, oldest first, frames over
+// the size bound, passing them to the keyframer and compacting the backing
+// array once half of it is unused
+func (r *Ring) trim(now time.Time) {
+	cutoff := now.Add(-r.window)
+	for r.head < len(r.frames) {
+		oldest := r.frames[r.head]
+		if !oldest.Time.Before(cutoff) && (r.maxBytes <= 0 || r.size <= r.maxBytes) {
+			break
+		}
+		r.size -= len(oldest.Data)
+		if r.keyframer != nil {
+			r.keyframer.Drop(oldest.Data)
+			r.evicted = true
+		}
+		r.frames[r.head] = Frame{}
+		r.head++
+	}
+	if r.head > len(r.frames)/2 {
+		r.frames = append(r.frames[:0], r.frames[r.head:]...)
+		r.head = 0
+	}
+}
+
+// countingWriter counts the bytes written through it
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+// Write implements io.Writer
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,135 @@
+package ttyrec
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// newTestRing returns a ring whose clock is advanced by the returned func
+func newTestRing(window time.Duration, maxBytes int) (*Ring, func(time.Duration)) {
+	ring := NewRing(window, maxBytes)
+	now := time.Unix(1700000000, 0)
+	ring.now = func() time.Time { return now }
+	return ring, func(d time.Duration) { now = now.Add(d) }
+}
+
+func TestRing_Frames_DropsOutputOutsideWindow(t *testing.T) {
+	ring, advance := newTestRing(10*time.Second, 0)
+	for _, data := range []string{"a", "b", "c"} {
+		ring.Write([]byte(data))
+		advance(4 * time.Second)
+	}
+
+	// Now 12s after "a", 8s after "b" and 4s after "c"
+	frames := ring.Frames()
+	if len(frames) != 2 || string(frames[0].Data) != "b" || string(frames[1].Data) != "c" {
+		t.Fatalf("Frames() = %v, want b and c", frames)
+	}
+
+	advance(time.Minute)
+	if frames := ring.Frames(); len(frames) != 0 {
+		t.Errorf("Frames() after the window = %d frames, want none", len(frames))
+	}
+}
+
+func TestRing_Write_BoundsSizeAndCopiesData(t *testing.T) {
+	ring, _ := newTestRing(time.Hour, 5)
+	data := []byte("abc")
+	ring.Write(data)
+	data[0] = 'x'
+	ring.Write([]byte("de"))
+	ring.Write([]byte("fg"))
+
+	frames := ring.Frames()
+	if len(frames) != 2 || string(frames[0].Data) != "de" || string(frames[1].Data) != "fg" {
+		t.Fatalf("Frames() = %v, want de and fg", frames)
+	}
+
+	ring.Write([]byte("abcd"))
+	var buf bytes.Buffer
+	if _, err := ring.WriteTo(&buf); err != nil {
+		t.Fatalf("WriteTo() error = %v", err)
+	}
+	read, err := ReadAll(&buf)
+	if err != nil || len(read) != 1 || string(read[0].Data) != "abcd" {
+		t.Errorf("ReadAll(WriteTo()) = %v, %v; want the abcd frame", read, err)
+	}
+}
+
+// screenKeyframer keeps the dropped output as the screen
+type screenKeyframer struct{ screen string }
+
+func (k *screenKeyframer) Drop(data []byte) { k.screen += string(data) }
+func (k *screenKeyframer) Keyframe() []byte { return []byte("[" + k.screen + "]") }
+
+func TestRing_Frames_StartsWithKeyframeAfterEviction(t *testing.T) {
+	ring, advance := newTestRing(10*time.Second, 0)
+	ring.SetKeyframer(&screenKeyframer{})
+	ring.Write([]byte("a"))
+	if frames := ring.Frames(); len(frames) != 1 || string(frames[0].Data) != "a" {
+		t.Fatalf("Frames() before eviction = %v, want only a", frames)
+	}
+
+	advance(6 * time.Second)
+	ring.Write([]byte("b"))
+	advance(6 * time.Second)
+	ring.Write([]byte("c"))
+
+	// a was dropped; the keyframe draws it at the time of b
+	frames := ring.Frames()
+	if len(frames) != 3 || string(frames[0].Data) != "[a]" || string(frames[1].Data) != "b" || string(frames[2].Data) != "c" {
+		t.Fatalf("Frames() = %v, want the keyframe of a, then b and c", frames)
+	}
+	if !frames[0].Time.Equal(frames[1].Time) {
+		t.Errorf("keyframe time = %v, want that of b (%v)", frames[0].Time, frames[1].Time)
+	}
+
+	advance(time.Minute)
+	if frames := ring.Frames(); len(frames) != 1 || string(frames[0].Data) != "[abc]" {
+		t.Errorf("Frames() after the window = %v, want only the keyframe of abc", frames)
+	}
+}
+
+func TestPlay_ScalesAndCapsDelays(t *testing.T) {
+	start := time.Unix(1700000000, 0)
+	frames := []Frame{
+		{Time: start, Data: []byte("a")},
+		{Time: start.Add(100 * time.Millisecond), Data: []byte("b")},
+		{Time: start.Add(time.Hour), Data: []byte("c")},
+	}
+
+	var out bytes.Buffer
+	began := time.Now()
+	err := Play(context.Background(), frames, 4, 50*time.Millisecond, func(data []byte) error {
+		out.Write(data)
+		return nil
+	})
+	elapsed := time.Since(began)
+
+	if err != nil || out.String() != "abc" {
+		t.Fatalf("Play() = %q, %v; want abc", out.String(), err)
+	}
+	// 25ms for the scaled gap plus 50ms for the capped one
+	if elapsed < 75*time.Millisecond || elapsed > time.Second {
+		t.Errorf("Play() took %v, want about 75ms", elapsed)
+	}
+}
+
+func TestPlay_StopsOnCancelAndInvalidSpeed(t *testing.T) {
+	start := time.Unix(1700000000, 0)
+	frames := []Frame{{Time: start, Data: []byte("a")}, {Time: start.Add(time.Hour), Data: []byte("b")}}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	err := Play(ctx, frames, 1, 0, func([]byte) error { return nil })
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Play() error = %v, want deadline exceeded", err)
+	}
+
+	if err := Play(context.Background(), frames, 0, 0, func([]byte) error { return nil }); err == nil {
+		t.Error("Play() with speed 0 succeeded, want error")
+	}
+}
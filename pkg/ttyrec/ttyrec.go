@@ -0,0 +1,116 @@
+// Package ttyrec reads and writes ttyrec terminal recordings, the format
+// used by dgamelaunch servers to record NetHack, DCSS and other games.
+//
+// A ttyrec file is a sequence of frames, each a 12-byte little-endian header
+// (seconds, microseconds, length) followed by length bytes of terminal
+// output.
+package ttyrec
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// headerSize is the size of a frame header in bytes
+const headerSize = 12
+
+// MaxFrameSize bounds the payload of a single frame so that corrupt files
+// cannot trigger huge allocations
+const MaxFrameSize = 1 << 20
+
+// ErrFrameTooLarge is returned for frames larger than MaxFrameSize
+var ErrFrameTooLarge = errors.New("ttyrec: frame too large")
+
+// Frame is a chunk of terminal output with the time it was produced
+type Frame struct {
+	Time time.Time
+	Data []byte
+}
+
+// Reader reads frames from a ttyrec stream
+type Reader struct {
+	r      io.Reader
+	header [headerSize]byte
+}
+
+// NewReader creates a reader for the ttyrec stream r
+func NewReader(r io.Reader) *Reader {
+	return &Reader{r: r}
+}
+
+// Next returns the next frame, or io.EOF at the end of the stream
+func (r *Reader) Next() (Frame, error) {
+	if _, err := io.ReadFull(r.r, r.header[:]); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return Frame{}, fmt.Errorf("ttyrec: truncated frame header: %w", err)
+		}
+		return Frame{}, err
+	}
+
+	sec := binary.LittleEndian.Uint32(r.header[0:4])
+	usec := binary.LittleEndian.Uint32(r.header[4:8])
+	length := binary.LittleEndian.Uint32(r.header[8:12])
+	if length > MaxFrameSize {
+		return Frame{}, fmt.Errorf("%w: %d bytes", ErrFrameTooLarge, length)
+	}
+
+	data := make([]byte, length)
+	if _, err := io.ReadFull(r.r, data); err != nil {
+		return Frame{}, fmt.Errorf("ttyrec: truncated frame data: %w", err)
+	}
+
+	return Frame{
+		Time: time.Unix(int64(sec), int64(usec)*int64(time.Microsecond)),
+		Data: data,
+	}, nil
+}
+
+// ReadAll reads every frame of a ttyrec stream
+func ReadAll(r io.Reader) ([]Frame, error) {
+	reader := NewReader(r)
+
+	var frames []Frame
+	for {
+		frame, err := reader.Next()
+		if err == io.EOF {
+			return frames, nil
+		}
+		if err != nil {
+			return frames, err
+		}
+		frames = append(frames, frame)
+	}
+}
+
+// Writer writes frames to a ttyrec stream
+type Writer struct {
+	w      io.Writer
+	header [headerSize]byte
+}
+
+// NewWriter creates a writer producing a ttyrec stream on w
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: w}
+}
+
+// WriteFrame appends a frame to the stream
+func (w *Writer) WriteFrame(frame Frame) error {
+	if len(frame.Data) > MaxFrameSize {
+		return fmt.Errorf("%w: %d bytes", ErrFrameTooLarge, len(frame.Data))
+	}
+
+	binary.LittleEndian.PutUint32(w.header[0:4], uint32(frame.Time.Unix()))
+	binary.LittleEndian.PutUint32(w.header[4:8], uint32(frame.Time.Nanosecond()/int(time.Microsecond)))
+	binary.LittleEndian.PutUint32(w.header[8:12], uint32(len(frame.Data)))
+
+	if _, err := w.w.Write(w.header[:]); err != nil {
+		return fmt.Errorf("ttyrec: failed to write header: %w", err)
+	}
+	if _, err := w.w.Write(frame.Data); err != nil {
+		return fmt.Errorf("ttyrec: failed to write data: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package ttyrec
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestWriterReader_RoundTrip(t *testing.T) {
+	frames := []Frame{
+		{Time: time.Unix(1700000000, 250000*int64(time.Microsecond)), Data: []byte("\x1b[2J\x1b[Hhello")},
+		{Time: time.Unix(1700000001, 0), Data: []byte{}},
+		{Time: time.Unix(1700000002, 999999*int64(time.Microsecond)), Data: []byte("@")},
+	}
+
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	for _, f := range frames {
+		if err := w.WriteFrame(f); err != nil {
+			t.Fatalf("WriteFrame failed: %v", err)
+		}
+	}
+
+	got, err := ReadAll(&buf)
+	if err != nil {
+		t.Fatalf("ReadAll failed: %v", err)
+	}
+	if len(got) != len(frames) {
+		t.Fatalf("Read %d frames, want %d", len(got), len(frames))
+	}
+	for i := range frames {
+		if !got[i].Time.Equal(frames[i].Time) {
+			t.Errorf("frame %d: time = %v, want %v", i, got[i].Time, frames[i].Time)
+		}
+		if !bytes.Equal(got[i].Data, frames[i].Data) {
+			t.Errorf("frame %d: data = %q, want %q", i, got[i].Data, frames[i].Data)
+		}
+	}
+}
+
+func TestReader_RejectsCorruptStreams(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"truncated header", []byte{1, 2, 3}},
+		{"truncated data", []byte{0, 0, 0, 0, 0, 0, 0, 0, 5, 0, 0, 0, 'a'}},
+		{"oversized frame", []byte{0, 0, 0, 0, 0, 0, 0, 0, 0xff, 0xff, 0xff, 0x7f}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewReader(bytes.NewReader(tt.data)).Next()
+			if err == nil || err == io.EOF {
+				t.Errorf("Expected corruption error, got %v", err)
+			}
+		})
+	}
+
+	_, err := NewReader(bytes.NewReader([]byte{0, 0, 0, 0, 0, 0, 0, 0, 0xff, 0xff, 0xff, 0x7f})).Next()
+	if !errors.Is(err, ErrFrameTooLarge) {
+		t.Errorf("Expected ErrFrameTooLarge, got %v", err)
+	}
+}
+
+func TestReader_EmptyStream(t *testing.T) {
+	if _, err := NewReader(bytes.NewReader(nil)).Next(); err != io.EOF {
+		t.Errorf("Expected io.EOF, got %v", err)
+	}
+}
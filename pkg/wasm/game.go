@@ -44,6 +44,7 @@ type Game struct {
 	sceneMu   sync.RWMutex
 	input     *InputHandler
 	transport Transport
+	predictor *Predictor
 
 	// Game state
 	buffer     [][]Cell
@@ -56,6 +57,7 @@ func NewGame(config GameConfig) *Game {
 	g := &Game{
 		config:     config,
 		input:      NewInputHandler(),
+		predictor:  NewPredictor(),
 		needRedraw: true,
 	}
 	g.initBuffer()
@@ -101,7 +103,11 @@ func (g *Game) Update() error {
 	// Get pending input and send to server
 	if g.transport != nil {
 		for _, key := range g.input.PopPressedKeys() {
-			g.transport.SendInput(key)
+			g.bufferMu.Lock()
+			seq := g.predictor.Predict(key, g.buffer)
+			g.needRedraw = true
+			g.bufferMu.Unlock()
+			g.transport.SendInput(key, seq)
 		}
 	}
 
@@ -119,6 +125,7 @@ func (g *Game) Update() error {
 	// Check for state updates from transport
 	if g.transport != nil {
 		if state := g.transport.GetLatestState(); state != nil {
+			g.predictor.Reconcile(state)
 			g.ApplyState(state)
 		}
 	}
@@ -0,0 +1,145 @@
+// Package wasm provides predictive local echo of typed characters.
+package wasm
+
+import "unicode/utf8"
+
+// prediction is a character echoed locally before the server confirmed it
+type prediction struct {
+	seq        uint64
+	char       rune
+	x, y       int    // where it was drawn
+	ackVersion uint64 // state version that first acknowledged it, 0 before
+}
+
+// Predictor numbers the input sent to the server and, while the server
+// reports that local echo is safe, draws typed characters at the cursor
+// right away so typing feels immediate over high-latency links. Every state
+// from the server acknowledges the input delivered to the game so far:
+// predictions that show up on the screen are dropped, and the remaining ones
+// are redrawn on top of the server's screen. A prediction still missing one
+// state after its acknowledgement was wrong, and all pending predictions are
+// discarded.
+type Predictor struct {
+	seq     uint64
+	echo    bool
+	cursorX int
+	cursorY int
+
+	// barrier pauses prediction until the server acknowledges the key
+	// numbered barrier, e.g. Enter, which moves the cursor unpredictably
+	barrier uint64
+
+	pending        []prediction
+	mispredictions uint64
+}
+
+// NewPredictor creates a predictor that stays idle until the first state
+// enables local echo
+func NewPredictor() *Predictor {
+	return &Predictor{}
+}
+
+// Predict numbers input for sending and, when it can be echoed, draws it into
+// buffer at the predicted cursor. It returns the sequence number to send with
+// the input.
+func (p *Predictor) Predict(input string, buffer [][]Cell) uint64 {
+	p.seq++
+
+	char, ok := printableRune(input)
+	if !ok {
+		p.barrier = p.seq
+		return p.seq
+	}
+	if !p.echo || p.barrier != 0 {
+		return p.seq
+	}
+
+	pred := prediction{seq: p.seq, char: char}
+	if p.draw(&pred, buffer) {
+		p.pending = append(p.pending, pred)
+	} else {
+		// The cursor would wrap; let the server decide where it goes
+		p.barrier = p.seq
+	}
+	return p.seq
+}
+
+// Reconcile checks the pending predictions against a state received from the
+// server and redraws the ones still outstanding into state.Buffer
+func (p *Predictor) Reconcile(state *GameState) {
+	p.echo = state.Echo
+	p.cursorX, p.cursorY = state.CursorX, state.CursorY
+	if p.barrier != 0 && state.InputSeq >= p.barrier {
+		p.barrier = 0
+	}
+
+	keep := p.pending[:0]
+	for _, pred := range p.pending {
+		if pred.seq <= state.InputSeq {
+			// The game has the key: it is confirmed once echoed where it
+			// was predicted, and wrong if still absent one state later
+			if cellChar(state.Buffer, pred.x, pred.y) == pred.char {
+				continue
+			}
+			if pred.ackVersion == 0 {
+				pred.ackVersion = state.Version
+			} else if state.Version > pred.ackVersion {
+				p.mispredictions++
+				keep = keep[:0]
+				break
+			}
+		}
+		keep = append(keep, pred)
+	}
+	p.pending = keep
+	if !p.echo {
+		p.pending = p.pending[:0]
+	}
+
+	for i := range p.pending {
+		if !p.draw(&p.pending[i], state.Buffer) {
+			p.pending = p.pending[:i]
+			break
+		}
+	}
+}
+
+// Pending returns the number of predictions the server has not confirmed
+func (p *Predictor) Pending() int {
+	return len(p.pending)
+}
+
+// Mispredictions returns how many times predictions had to be discarded
+func (p *Predictor) Mispredictions() uint64 {
+	return p.mispredictions
+}
+
+// draw writes pred at the predicted cursor and advances it, reporting false
+// when the cursor is outside buffer
+func (p *Predictor) draw(pred *prediction, buffer [][]Cell) bool {
+	if p.cursorY < 0 || p.cursorY >= len(buffer) || p.cursorX < 0 || p.cursorX >= len(buffer[p.cursorY]) {
+		return false
+	}
+	buffer[p.cursorY][p.cursorX].Char = pred.char
+	pred.x, pred.y = p.cursorX, p.cursorY
+	p.cursorX++
+	return true
+}
+
+// printableRune returns the character typed by input when it is a single
+// printable character
+func printableRune(input string) (rune, bool) {
+	char, size := utf8.DecodeRuneInString(input)
+	if size == 0 || size != len(input) || char == utf8.RuneError || char < ' ' || char == 0x7f {
+		return 0, false
+	}
+	return char, true
+}
+
+// cellChar returns the character at x, y of buffer, or 0 outside it
+func cellChar(buffer [][]Cell, x, y int) rune {
+	if y < 0 || y >= len(buffer) || x < 0 || x >= len(buffer[y]) {
+		return 0
+	}
+	return buffer[y][x].Char
+}
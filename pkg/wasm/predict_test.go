@@ -0,0 +1,126 @@
+// Package wasm provides tests for predictive local echo.
+package wasm
+
+import "testing"
+
+// predictTestState returns a one-row state showing text with the cursor
+// after it
+func predictTestState(text string, version, ack uint64) *GameState {
+	row := make([]Cell, 10)
+	for x := range row {
+		row[x] = Cell{Char: ' '}
+	}
+	for x, char := range []rune(text) {
+		row[x].Char = char
+	}
+	return &GameState{
+		Buffer:   [][]Cell{row},
+		Width:    10,
+		Height:   1,
+		CursorX:  len([]rune(text)),
+		Echo:     true,
+		Version:  version,
+		InputSeq: ack,
+	}
+}
+
+// rowText returns the characters of the first row without trailing spaces
+func rowText(buffer [][]Cell) string {
+	chars := make([]rune, 0, len(buffer[0]))
+	for _, cell := range buffer[0] {
+		chars = append(chars, cell.Char)
+	}
+	text := string(chars)
+	for len(text) > 0 && text[len(text)-1] == ' ' {
+		text = text[:len(text)-1]
+	}
+	return text
+}
+
+func TestPredictor_Predict_EchoesPrintableKeys(t *testing.T) {
+	p := NewPredictor()
+	state := predictTestState("> ", 1, 0)
+	p.Reconcile(state)
+
+	for i, key := range []string{"h", "i"} {
+		if seq := p.Predict(key, state.Buffer); seq != uint64(i+1) {
+			t.Errorf("Predict(%q) = %d, want %d", key, seq, i+1)
+		}
+	}
+	if got := rowText(state.Buffer); got != "> hi" {
+		t.Errorf("buffer = %q, want %q", got, "> hi")
+	}
+
+	// Enter moves the cursor somewhere the client cannot know
+	p.Predict("\r", state.Buffer)
+	p.Predict("x", state.Buffer)
+	if got := rowText(state.Buffer); got != "> hi" || p.Pending() != 2 {
+		t.Errorf("after Enter: buffer = %q with %d pending, want no new prediction", got, p.Pending())
+	}
+}
+
+func TestPredictor_Predict_IdleWithoutEcho(t *testing.T) {
+	p := NewPredictor()
+	state := predictTestState("", 1, 0)
+	state.Echo = false
+	p.Reconcile(state)
+
+	p.Predict("k", state.Buffer)
+	if got := rowText(state.Buffer); got != "" || p.Pending() != 0 {
+		t.Errorf("buffer = %q with %d pending, want nothing echoed", got, p.Pending())
+	}
+}
+
+func TestPredictor_Reconcile(t *testing.T) {
+	p := NewPredictor()
+	p.Reconcile(predictTestState("", 1, 0))
+	local := predictTestState("", 1, 0).Buffer
+	for _, key := range []string{"a", "b", "c"} {
+		p.Predict(key, local)
+	}
+
+	// The server echoed a; b and c are redrawn after it
+	state := predictTestState("a", 2, 1)
+	p.Reconcile(state)
+	if got := rowText(state.Buffer); got != "abc" || p.Pending() != 2 {
+		t.Fatalf("buffer = %q with %d pending, want %q with 2", got, p.Pending(), "abc")
+	}
+
+	// b was delivered but the game has not drawn it yet
+	state = predictTestState("a", 3, 2)
+	p.Reconcile(state)
+	if got := rowText(state.Buffer); got != "abc" || p.Mispredictions() != 0 {
+		t.Fatalf("buffer = %q after %d mispredictions, want predictions kept", got, p.Mispredictions())
+	}
+
+	// The game drew something else: the predictions are discarded
+	state = predictTestState("aX", 4, 2)
+	p.Reconcile(state)
+	if got := rowText(state.Buffer); got != "aX" || p.Pending() != 0 || p.Mispredictions() != 1 {
+		t.Errorf("buffer = %q with %d pending after %d mispredictions, want server screen only",
+			got, p.Pending(), p.Mispredictions())
+	}
+}
+
+func TestPrintableRune(t *testing.T) {
+	tests := []struct {
+		input string
+		want  rune
+		ok    bool
+	}{
+		{"a", 'a', true},
+		{"é", 'é', true},
+		{" ", ' ', true},
+		{"\r", 0, false},
+		{"\x7f", 0, false},
+		{"\x1b[A", 0, false},
+		{"ab", 0, false},
+		{"", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := printableRune(tt.input)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("printableRune(%q) = %q, %v; want %q, %v", tt.input, got, ok, tt.want, tt.ok)
+		}
+	}
+}
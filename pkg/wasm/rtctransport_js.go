@@ -0,0 +1,245 @@
+//go:build js && wasm
+// +build js,wasm
+
+// Package wasm provides an experimental WebRTC data channel transport for
+// WASM clients.
+package wasm
+
+import (
+	"fmt"
+	"sync"
+	"syscall/js"
+	"time"
+)
+
+// iceGatheringTimeout bounds the wait for ICE candidates before the offer
+// is sent with those gathered so far
+const iceGatheringTimeout = 5 * time.Second
+
+// RTCTransport implements Transport over a WebRTC data channel, negotiated by
+// posting an offer to the server's /rtc/offer endpoint. The server must have
+// web.webrtc configured; clients fall back to WebSocketTransport otherwise.
+type RTCTransport struct {
+	iceServers []string
+	pc         js.Value
+	dc         js.Value
+	connected  bool
+	mu         sync.Mutex
+	inbox      stateInbox
+	onMessage  js.Func
+	onOpen     js.Func
+	onClose    js.Func
+}
+
+// NewRTCTransport creates a WebRTC transport using the STUN or TURN servers
+// iceServers, e.g. "stun:stun.l.google.com:19302"
+func NewRTCTransport(iceServers ...string) *RTCTransport {
+	return &RTCTransport{iceServers: iceServers}
+}
+
+// Connect starts negotiating with the offer endpoint at url, e.g.
+// "https://example.com/rtc/offer". The transport is connected once the data
+// channel opens.
+func (t *RTCTransport) Connect(url string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.pc.Truthy() {
+		return nil
+	}
+
+	servers := make([]interface{}, 0, len(t.iceServers))
+	for _, server := range t.iceServers {
+		servers = append(servers, map[string]interface{}{"urls": server})
+	}
+	t.pc = js.Global().Get("RTCPeerConnection").New(map[string]interface{}{"iceServers": servers})
+	t.dc = t.pc.Call("createDataChannel", "dgconnect")
+	t.setupEventHandlers()
+
+	// Negotiation waits on promises, which must not block the event loop
+	pc := t.pc
+	go func() {
+		if err := negotiate(pc, withProtocol(url)); err != nil {
+			js.Global().Get("console").Call("warn", "WebRTC negotiation failed:", err.Error())
+			t.mu.Lock()
+			if t.pc.Equal(pc) {
+				t.closeLocked()
+			}
+			t.mu.Unlock()
+		}
+	}()
+	return nil
+}
+
+// negotiate sends the offer of pc, with every ICE candidate, to url and
+// applies the answer
+func negotiate(pc js.Value, url string) error {
+	offer, err := await(pc.Call("createOffer"))
+	if err != nil {
+		return err
+	}
+	if _, err := await(pc.Call("setLocalDescription", offer)); err != nil {
+		return err
+	}
+	waitGathered(pc)
+
+	body := js.Global().Get("JSON").Call("stringify", pc.Get("localDescription"))
+	resp, err := await(js.Global().Call("fetch", url, map[string]interface{}{
+		"method":  "POST",
+		"headers": map[string]interface{}{"Content-Type": "application/json"},
+		"body":    body,
+	}))
+	if err != nil {
+		return err
+	}
+	if !resp.Get("ok").Bool() {
+		return fmt.Errorf("offer refused with status %d", resp.Get("status").Int())
+	}
+	answer, err := await(resp.Call("json"))
+	if err != nil {
+		return err
+	}
+	_, err = await(pc.Call("setRemoteDescription", answer))
+	return err
+}
+
+// waitGathered waits until pc has gathered its ICE candidates or
+// iceGatheringTimeout passed
+func waitGathered(pc js.Value) {
+	done := make(chan struct{})
+	var once sync.Once
+	onChange := js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		if pc.Get("iceGatheringState").String() == "complete" {
+			once.Do(func() { close(done) })
+		}
+		return nil
+	})
+	defer onChange.Release()
+	pc.Call("addEventListener", "icegatheringstatechange", onChange)
+	defer pc.Call("removeEventListener", "icegatheringstatechange", onChange)
+
+	if pc.Get("iceGatheringState").String() == "complete" {
+		return
+	}
+	select {
+	case <-done:
+	case <-time.After(iceGatheringTimeout):
+	}
+}
+
+// await blocks until promise settles, returning its value. It must not be
+// called from a JavaScript callback.
+func await(promise js.Value) (js.Value, error) {
+	values := make(chan js.Value, 1)
+	errs := make(chan error, 1)
+	then := js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		value := js.Undefined()
+		if len(args) > 0 {
+			value = args[0]
+		}
+		values <- value
+		return nil
+	})
+	defer then.Release()
+	catch := js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		errs <- js.Error{Value: args[0]}
+		return nil
+	})
+	defer catch.Release()
+
+	promise.Call("then", then, catch)
+	select {
+	case value := <-values:
+		return value, nil
+	case err := <-errs:
+		return js.Undefined(), err
+	}
+}
+
+// setupEventHandlers sets up JavaScript event handlers for the data channel
+func (t *RTCTransport) setupEventHandlers() {
+	t.onOpen = js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		t.mu.Lock()
+		t.connected = true
+		t.mu.Unlock()
+		return nil
+	})
+	t.dc.Set("onopen", t.onOpen)
+
+	t.onClose = js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		t.mu.Lock()
+		t.connected = false
+		t.mu.Unlock()
+		return nil
+	})
+	t.dc.Set("onclose", t.onClose)
+
+	t.onMessage = js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		if len(args) == 0 {
+			return nil
+		}
+		if t.inbox.receive(args[0].Get("data").String()) {
+			if data, err := pongMessage(); err == nil {
+				t.dc.Call("send", data)
+			}
+		}
+		return nil
+	})
+	t.dc.Set("onmessage", t.onMessage)
+}
+
+// Disconnect closes the data channel and the peer connection
+func (t *RTCTransport) Disconnect() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.closeLocked()
+	return nil
+}
+
+// closeLocked implements Disconnect with t.mu held
+func (t *RTCTransport) closeLocked() {
+	if !t.pc.Truthy() {
+		return
+	}
+
+	t.dc.Call("close")
+	t.pc.Call("close")
+	t.pc, t.dc = js.Undefined(), js.Undefined()
+	t.connected = false
+
+	// Release JavaScript functions
+	t.onOpen.Release()
+	t.onClose.Release()
+	t.onMessage.Release()
+}
+
+// SendInput sends user input numbered seq to the server
+func (t *RTCTransport) SendInput(input string, seq uint64) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if !t.connected {
+		return nil
+	}
+
+	data, err := inputMessage(input, seq)
+	if err != nil {
+		return err
+	}
+
+	t.dc.Call("send", data)
+	return nil
+}
+
+// GetLatestState returns the latest game state from the server
+func (t *RTCTransport) GetLatestState() *GameState {
+	return t.inbox.take()
+}
+
+// IsConnected returns true if the data channel is open
+func (t *RTCTransport) IsConnected() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.connected
+}
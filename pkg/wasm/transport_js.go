@@ -6,6 +6,8 @@ package wasm
 
 import (
 	"encoding/json"
+	"net/url"
+	"strconv"
 	"sync"
 	"syscall/js"
 	"time"
@@ -13,36 +15,102 @@ import (
 
 // Message types for WebSocket communication
 const (
-	MsgTypeState = "state"
-	MsgTypeInput = "input"
-	MsgTypePing  = "ping"
-	MsgTypePong  = "pong"
+	MsgTypeState   = "state"
+	MsgTypeInput   = "input"
+	MsgTypePing    = "ping"
+	MsgTypePong    = "pong"
+	MsgTypeConnect = "connect"
 )
 
+// ProtocolVersion is the newest wire protocol version this client speaks
+const ProtocolVersion = 1
+
 // WebSocketMessage represents a WebSocket message
 type WebSocketMessage struct {
 	Type      string          `json:"type"`
 	Payload   json.RawMessage `json:"payload,omitempty"`
+	InputSeq  uint64          `json:"input_seq,omitempty"`
 	Timestamp int64           `json:"timestamp"`
 }
 
 // InputPayload contains user input data
 type InputPayload struct {
 	Input string `json:"input"`
+	Seq   uint64 `json:"seq,omitempty"`
+}
+
+// stateInbox keeps the latest state a transport received
+type stateInbox struct {
+	mu     sync.Mutex
+	latest *GameState
+}
+
+// receive handles a message from the server, reporting whether it was a
+// ping to answer with pongMessage
+func (b *stateInbox) receive(data string) (ping bool) {
+	var msg WebSocketMessage
+	if err := json.Unmarshal([]byte(data), &msg); err != nil {
+		return false
+	}
+
+	switch msg.Type {
+	case MsgTypeState:
+		var state GameState
+		if err := json.Unmarshal(msg.Payload, &state); err == nil {
+			state.InputSeq = msg.InputSeq
+			b.mu.Lock()
+			b.latest = &state
+			b.mu.Unlock()
+		}
+	case MsgTypePing:
+		return true
+	}
+	return false
+}
+
+// take returns the latest state, or nil if none arrived since the last call
+func (b *stateInbox) take() *GameState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	state := b.latest
+	b.latest = nil
+	return state
+}
+
+// pongMessage returns the answer to a ping
+func pongMessage() (string, error) {
+	msg := WebSocketMessage{
+		Type:      MsgTypePong,
+		Timestamp: time.Now().UnixMilli(),
+	}
+	data, err := json.Marshal(msg)
+	return string(data), err
+}
+
+// inputMessage returns the message sending input numbered seq
+func inputMessage(input string, seq uint64) (string, error) {
+	payload, _ := json.Marshal(InputPayload{Input: input, Seq: seq})
+	msg := WebSocketMessage{
+		Type:      MsgTypeInput,
+		Payload:   payload,
+		Timestamp: time.Now().UnixMilli(),
+	}
+	data, err := json.Marshal(msg)
+	return string(data), err
 }
 
 // WebSocketTransport implements Transport interface using JavaScript WebSocket API
 type WebSocketTransport struct {
-	ws          js.Value
-	url         string
-	connected   bool
-	mu          sync.Mutex
-	latestState *GameState
-	stateMu     sync.RWMutex
-	onMessage   js.Func
-	onOpen      js.Func
-	onClose     js.Func
-	onError     js.Func
+	ws        js.Value
+	url       string
+	connected bool
+	mu        sync.Mutex
+	inbox     stateInbox
+	onMessage js.Func
+	onOpen    js.Func
+	onClose   js.Func
+	onError   js.Func
 }
 
 // NewWebSocketTransport creates a new WebSocket transport
@@ -59,11 +127,11 @@ func (t *WebSocketTransport) Connect(url string) error {
 		return nil
 	}
 
-	t.url = url
+	t.url = withProtocol(url)
 
 	// Create WebSocket using JavaScript API
 	wsConstructor := js.Global().Get("WebSocket")
-	t.ws = wsConstructor.New(url)
+	t.ws = wsConstructor.New(t.url)
 
 	// Set up event handlers
 	t.setupEventHandlers()
@@ -71,6 +139,20 @@ func (t *WebSocketTransport) Connect(url string) error {
 	return nil
 }
 
+// withProtocol adds the protocol version request to a WebSocket URL
+func withProtocol(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	q := u.Query()
+	if q.Get("protocol") == "" {
+		q.Set("protocol", strconv.Itoa(ProtocolVersion))
+		u.RawQuery = q.Encode()
+	}
+	return u.String()
+}
+
 // setupEventHandlers sets up JavaScript event handlers for the WebSocket
 func (t *WebSocketTransport) setupEventHandlers() {
 	// onopen handler
@@ -115,20 +197,7 @@ func (t *WebSocketTransport) setupEventHandlers() {
 
 // handleMessage processes an incoming WebSocket message
 func (t *WebSocketTransport) handleMessage(data string) {
-	var msg WebSocketMessage
-	if err := json.Unmarshal([]byte(data), &msg); err != nil {
-		return
-	}
-
-	switch msg.Type {
-	case MsgTypeState:
-		var state GameState
-		if err := json.Unmarshal(msg.Payload, &state); err == nil {
-			t.stateMu.Lock()
-			t.latestState = &state
-			t.stateMu.Unlock()
-		}
-	case MsgTypePing:
+	if t.inbox.receive(data) {
 		// Respond with pong
 		t.sendPong()
 	}
@@ -136,15 +205,11 @@ func (t *WebSocketTransport) handleMessage(data string) {
 
 // sendPong sends a pong response
 func (t *WebSocketTransport) sendPong() {
-	msg := WebSocketMessage{
-		Type:      MsgTypePong,
-		Timestamp: time.Now().UnixMilli(),
-	}
-	data, err := json.Marshal(msg)
+	data, err := pongMessage()
 	if err != nil {
 		return
 	}
-	t.ws.Call("send", string(data))
+	t.ws.Call("send", data)
 }
 
 // Disconnect closes the connection
@@ -168,8 +233,8 @@ func (t *WebSocketTransport) Disconnect() error {
 	return nil
 }
 
-// SendInput sends user input to the server
-func (t *WebSocketTransport) SendInput(input string) error {
+// SendInput sends user input numbered seq to the server
+func (t *WebSocketTransport) SendInput(input string, seq uint64) error {
 	t.mu.Lock()
 	defer t.mu.Unlock()
 
@@ -177,30 +242,18 @@ func (t *WebSocketTransport) SendInput(input string) error {
 		return nil
 	}
 
-	payload, _ := json.Marshal(InputPayload{Input: input})
-	msg := WebSocketMessage{
-		Type:      MsgTypeInput,
-		Payload:   payload,
-		Timestamp: time.Now().UnixMilli(),
-	}
-
-	data, err := json.Marshal(msg)
+	data, err := inputMessage(input, seq)
 	if err != nil {
 		return err
 	}
 
-	t.ws.Call("send", string(data))
+	t.ws.Call("send", data)
 	return nil
 }
 
 // GetLatestState returns the latest game state from the server
 func (t *WebSocketTransport) GetLatestState() *GameState {
-	t.stateMu.Lock()
-	defer t.stateMu.Unlock()
-
-	state := t.latestState
-	t.latestState = nil
-	return state
+	return t.inbox.take()
 }
 
 // IsConnected returns true if connected to the server
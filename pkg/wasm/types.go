@@ -20,8 +20,13 @@ type GameState struct {
 	Height    int      `json:"height"`
 	CursorX   int      `json:"cursor_x"`
 	CursorY   int      `json:"cursor_y"`
+	Echo      bool     `json:"echo"` // typed characters may be echoed locally
 	Version   uint64   `json:"version"`
 	Timestamp int64    `json:"timestamp"`
+
+	// InputSeq acknowledges the client's input; it is taken from the
+	// message envelope
+	InputSeq uint64 `json:"-"`
 }
 
 // Transport defines the interface for server communication
@@ -32,8 +37,8 @@ type Transport interface {
 	// Disconnect closes the connection
 	Disconnect() error
 
-	// SendInput sends user input to the server
-	SendInput(input string) error
+	// SendInput sends user input numbered seq to the server
+	SendInput(input string, seq uint64) error
 
 	// GetLatestState returns the latest game state from the server
 	// Returns nil if no new state is available
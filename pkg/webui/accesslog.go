@@ -0,0 +1,163 @@
+// Package webui provides a structured access log for HTTP requests, with
+// sampling of high-frequency polling routes.
+package webui
+
+import (
+	"bufio"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/opd-ai/go-gamelaunch-www/pkg/transport"
+)
+
+// defaultSampledRoutes are polled by clients often enough to flood the log
+var defaultSampledRoutes = []string{"session.info", "metrics", "tileset.image"}
+
+// AccessLogConfig configures the access log. Requests to SampledRoutes are
+// logged once every SampleEvery requests; errors and slow requests are always
+// logged.
+type AccessLogConfig struct {
+	SampledRoutes []string `yaml:"sampled_routes,omitempty"`                    // route names as in AuthorizationPolicy
+	SampleEvery   int      `yaml:"sample_every,omitempty"`                      // default 100
+	SlowRequest   string   `yaml:"slow_request,omitempty" yamlcheck:"duration"` // e.g. "1s", the default
+}
+
+// accessLogger writes one structured record per HTTP request
+type accessLogger struct {
+	logger      *slog.Logger
+	sampleEvery uint64
+	slow        time.Duration
+	counters    map[string]*atomic.Uint64 // sampled route -> requests seen
+}
+
+// newAccessLogger validates cfg and creates an access logger
+func newAccessLogger(cfg AccessLogConfig) (*accessLogger, error) {
+	if cfg.SampleEvery < 0 {
+		return nil, fmt.Errorf("access log: invalid sample_every %d", cfg.SampleEvery)
+	}
+	if cfg.SampleEvery == 0 {
+		cfg.SampleEvery = 100
+	}
+	if cfg.SampledRoutes == nil {
+		cfg.SampledRoutes = defaultSampledRoutes
+	}
+
+	slow := time.Second
+	if cfg.SlowRequest != "" {
+		parsed, err := time.ParseDuration(cfg.SlowRequest)
+		if err != nil || parsed <= 0 {
+			return nil, fmt.Errorf("access log: invalid slow_request %q", cfg.SlowRequest)
+		}
+		slow = parsed
+	}
+
+	l := &accessLogger{
+		logger:      slog.Default(),
+		sampleEvery: uint64(cfg.SampleEvery),
+		slow:        slow,
+		counters:    make(map[string]*atomic.Uint64, len(cfg.SampledRoutes)),
+	}
+	for _, route := range cfg.SampledRoutes {
+		l.counters[route] = new(atomic.Uint64)
+	}
+	return l, nil
+}
+
+// serve calls next and logs the request once it completes
+func (l *accessLogger) serve(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	start := time.Now()
+	recorder := &accessLogWriter{ResponseWriter: rw}
+	next(recorder, r)
+	duration := time.Since(start)
+
+	status := recorder.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	route := routeMethod(r.URL.Path)
+
+	attrs := []any{
+		"method", r.Method,
+		"route", route,
+		"status", status,
+		"bytes", recorder.bytes,
+		"duration", duration,
+		"remote", transport.RemoteIP(r),
+		"correlation_id", transport.CorrelationID(r.Context()),
+	}
+	if recorder.subject != "" {
+		attrs = append(attrs, "subject", recorder.subject)
+	}
+
+	// WebSocket connections are long-lived rather than slow
+	slow := duration >= l.slow && status != http.StatusSwitchingProtocols
+	if counter, ok := l.counters[route]; ok && status < 400 && !slow {
+		if (counter.Add(1)-1)%l.sampleEvery != 0 {
+			return
+		}
+		attrs = append(attrs, "sampled", l.sampleEvery)
+	}
+	l.logger.Info("http request", attrs...)
+}
+
+// accessLogWriter records the status and size of a response
+type accessLogWriter struct {
+	http.ResponseWriter
+	status  int
+	bytes   int64
+	subject string // authenticated user, if any
+}
+
+// WriteHeader implements http.ResponseWriter
+func (w *accessLogWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// Write implements http.ResponseWriter
+func (w *accessLogWriter) Write(p []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(p)
+	w.bytes += int64(n)
+	return n, err
+}
+
+// Flush implements http.Flusher
+func (w *accessLogWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Hijack implements http.Hijacker for WebSocket upgrades
+func (w *accessLogWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("response writer does not support hijacking")
+	}
+	if w.status == 0 {
+		w.status = http.StatusSwitchingProtocols
+	}
+	return hijacker.Hijack()
+}
+
+// Unwrap returns the underlying writer for http.ResponseController
+func (w *accessLogWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// setAccessLogSubject records the authenticated user of a request on its
+// access log entry
+func setAccessLogSubject(rw http.ResponseWriter, subject string) {
+	if recorder, ok := rw.(*accessLogWriter); ok {
+		recorder.subject = subject
+	}
+}
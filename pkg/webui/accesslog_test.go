@@ -0,0 +1,179 @@
+package webui
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"nhooyr.io/websocket"
+)
+
+// logBuffer is a bytes.Buffer safe for concurrent logging and reading
+type logBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *logBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *logBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// captureAccessLog directs the access log of ui into a buffer of JSON records
+func captureAccessLog(t *testing.T, ui *WebUI) *logBuffer {
+	t.Helper()
+
+	if ui.accessLog == nil {
+		t.Fatal("Access log not configured")
+	}
+	buf := &logBuffer{}
+	ui.accessLog.logger = slog.New(slog.NewJSONHandler(buf, nil))
+	return buf
+}
+
+// accessLogRecords decodes the captured records
+func accessLogRecords(t *testing.T, buf *logBuffer) []map[string]interface{} {
+	t.Helper()
+
+	var records []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var record map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("Invalid log record %q: %v", line, err)
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+func TestNewAccessLogger_InvalidConfig(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  AccessLogConfig
+	}{
+		{"negative sampling", AccessLogConfig{SampleEvery: -1}},
+		{"bad slow threshold", AccessLogConfig{SlowRequest: "soon"}},
+		{"zero slow threshold", AccessLogConfig{SlowRequest: "0s"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := newAccessLogger(tt.cfg); err == nil {
+				t.Error("Expected configuration error")
+			}
+		})
+	}
+}
+
+func TestWebUI_AccessLog_RecordsRequests(t *testing.T) {
+	ui, err := NewWebUI(WebUIOptions{
+		View:         newTestView(t),
+		AccessLog:    &AccessLogConfig{},
+		StaticTokens: []StaticToken{{Name: "bot", Token: "0123456789abcdef", Role: "spectator"}},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create WebUI: %v", err)
+	}
+	buf := captureAccessLog(t, ui)
+
+	req := httptest.NewRequest("GET", "/version", nil)
+	req.Header.Set("Authorization", "Bearer 0123456789abcdef")
+	rr := httptest.NewRecorder()
+	ui.ServeHTTP(rr, req)
+
+	ui.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/admin/clients", nil))
+
+	records := accessLogRecords(t, buf)
+	if len(records) != 2 {
+		t.Fatalf("Expected 2 records, got %d: %s", len(records), buf.String())
+	}
+
+	first := records[0]
+	if first["method"] != "GET" || first["route"] != "version" || first["subject"] != "token:bot" {
+		t.Errorf("Unexpected record %v", first)
+	}
+	if first["status"] != float64(http.StatusOK) || first["bytes"] != float64(rr.Body.Len()) {
+		t.Errorf("Expected status 200 and %d bytes, got %v", rr.Body.Len(), first)
+	}
+	if _, ok := first["duration"]; !ok {
+		t.Error("Expected duration in record")
+	}
+	if records[1]["status"] != float64(http.StatusUnauthorized) || records[1]["route"] != "admin.clients" {
+		t.Errorf("Unexpected record %v", records[1])
+	}
+}
+
+func TestWebUI_AccessLog_SamplesPollingRoutes(t *testing.T) {
+	ui, err := NewWebUI(WebUIOptions{
+		View:      newTestView(t),
+		AccessLog: &AccessLogConfig{SampleEvery: 5},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create WebUI: %v", err)
+	}
+	buf := captureAccessLog(t, ui)
+
+	for i := 0; i < 10; i++ {
+		ui.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/session/info", nil))
+	}
+	// Failures of sampled routes are always logged
+	ui.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/tileset/image", nil))
+
+	records := accessLogRecords(t, buf)
+	if len(records) != 3 {
+		t.Fatalf("Expected 2 sampled records and 1 error, got %d: %s", len(records), buf.String())
+	}
+	for _, record := range records[:2] {
+		if record["route"] != "session.info" || record["sampled"] != float64(5) {
+			t.Errorf("Unexpected sampled record %v", record)
+		}
+	}
+	if records[2]["status"] != float64(http.StatusNotFound) {
+		t.Errorf("Expected logged 404, got %v", records[2])
+	}
+}
+
+func TestWebUI_AccessLog_WebSocketUpgrade(t *testing.T) {
+	ui, err := NewWebUI(WebUIOptions{View: newTestView(t), AccessLog: &AccessLogConfig{}})
+	if err != nil {
+		t.Fatalf("Failed to create WebUI: %v", err)
+	}
+	buf := captureAccessLog(t, ui)
+
+	server := httptest.NewServer(ui)
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	conn, _, err := websocket.Dial(ctx, "ws"+strings.TrimPrefix(server.URL, "http")+"/ws", nil)
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	conn.Close(websocket.StatusNormalClosure, "")
+
+	// The record is written once the connection ends
+	deadline := time.Now().Add(5 * time.Second)
+	for !strings.Contains(buf.String(), `"route":"ws"`) && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if !strings.Contains(buf.String(), `"status":101`) {
+		t.Errorf("Expected a 101 record for the WebSocket, got %s", buf.String())
+	}
+}
@@ -0,0 +1,308 @@
+// Package webui provides administrative HTTP endpoints for operators.
+package webui
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/opd-ai/go-gamelaunch-www/pkg/i18n"
+	"github.com/opd-ai/go-gamelaunch-www/pkg/transport"
+)
+
+const (
+	// maxBroadcastLength bounds the size of an admin broadcast message
+	maxBroadcastLength = 512
+
+	// broadcastShown is how long a broadcast stays in the state, so that
+	// clients connecting or polling meanwhile see it too
+	broadcastShown = 5 * time.Minute
+)
+
+// BroadcastParams represents parameters for an admin broadcast
+type BroadcastParams struct {
+	Message      string            `json:"message"`
+	Level        string            `json:"level,omitempty"`        // info (default), warning, critical
+	Translations map[string]string `json:"translations,omitempty"` // locale -> message, for clients in that locale
+}
+
+// SessionBroadcast is a message of an admin to all clients, sent at Sent;
+// clients show it once over the screen, in their locale when Translations
+// has it
+type SessionBroadcast struct {
+	Message      string            `json:"message"`
+	Level        string            `json:"level"`                  // info, warning or critical
+	Translations map[string]string `json:"translations,omitempty"` // by canonical locale
+	Sent         int64             `json:"sent"`                   // Unix milliseconds, like GameState.Timestamp
+}
+
+// BroadcastMessage pushes a server message to every client, in the client's
+// locale when a translation is given. Like expiry notices, the message
+// travels with the state and its diffs, to WebSocket clients and pollers
+// alike, for broadcastShown; it is not part of the game screen.
+func (w *WebUI) BroadcastMessage(params BroadcastParams) error {
+	message, err := validateBroadcastText(params.Message)
+	if err != nil {
+		return err
+	}
+	translations := make(map[string]string, len(params.Translations))
+	for locale, text := range params.Translations {
+		canonical, ok := i18n.ParseLocale(locale)
+		if !ok {
+			return fmt.Errorf("invalid locale %q", locale)
+		}
+		if translations[canonical], err = validateBroadcastText(text); err != nil {
+			return fmt.Errorf("%s: %w", locale, err)
+		}
+	}
+
+	level := params.Level
+	switch level {
+	case "":
+		level = "info"
+	case "info", "warning", "critical":
+	default:
+		return fmt.Errorf("unknown level %q", level)
+	}
+
+	w.log.Info("webui.BroadcastMessage", "level", level, "clients", w.wsHandler.GetClientCount(), "translations", len(translations))
+	broadcast := &SessionBroadcast{Message: message, Level: level, Sent: time.Now().UnixMilli()}
+	if len(translations) > 0 {
+		broadcast.Translations = translations
+	}
+	w.showBroadcast(nil, broadcast)
+	time.AfterFunc(broadcastShown, func() { w.showBroadcast(broadcast, nil) })
+	return nil
+}
+
+// showBroadcast shows next, or no broadcast when nil. Given shown, it only
+// replaces that broadcast, so that hiding an old one keeps a newer one.
+func (w *WebUI) showBroadcast(shown, next *SessionBroadcast) {
+	w.broadcastMu.Lock()
+	defer w.broadcastMu.Unlock()
+	if shown != nil && w.broadcast != shown {
+		return
+	}
+	w.broadcast = next
+	if view := w.GetView(); view != nil {
+		view.SetBroadcast(next)
+	}
+}
+
+// currentBroadcast returns the broadcast shown, or nil
+func (w *WebUI) currentBroadcast() *SessionBroadcast {
+	w.broadcastMu.Lock()
+	defer w.broadcastMu.Unlock()
+	return w.broadcast
+}
+
+// SetBroadcast shows broadcast to clients with the next state, or stops
+// showing it when nil
+func (v *WebView) SetBroadcast(broadcast *SessionBroadcast) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.closed || v.broadcast == broadcast {
+		return
+	}
+	v.broadcast = broadcast
+	v.publishState()
+}
+
+// toWireBroadcast converts a broadcast to its wire representation
+func toWireBroadcast(broadcast *SessionBroadcast) *transport.Broadcast {
+	if broadcast == nil {
+		return nil
+	}
+	return &transport.Broadcast{
+		Message:      broadcast.Message,
+		Level:        broadcast.Level,
+		Translations: broadcast.Translations,
+		Sent:         broadcast.Sent,
+	}
+}
+
+// validateBroadcastText trims a broadcast message and checks its length
+func validateBroadcastText(text string) (string, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return "", fmt.Errorf("message is required")
+	}
+	if len(text) > maxBroadcastLength {
+		return "", fmt.Errorf("message exceeds %d bytes", maxBroadcastLength)
+	}
+	return text, nil
+}
+
+// broadcastNotice pushes message key of the server's catalog to every
+// connected client, translated to the client's locale
+func (w *WebUI) broadcastNotice(level, key string, args ...any) {
+	w.log.Info("webui.broadcastNotice", "level", level, "key", key, "clients", w.wsHandler.GetClientCount())
+	w.wsHandler.BroadcastSystemFunc(func(locale string) *transport.SystemPayload {
+		return &transport.SystemPayload{
+			Message: w.messages.Message(locale, key, args...),
+			Level:   level,
+		}
+	})
+}
+
+// handleAdminBroadcast handles POST /admin/broadcast
+func (w *WebUI) handleAdminBroadcast(rw http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		rw.Header().Set("Allow", http.MethodPost)
+		http.Error(rw, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var params BroadcastParams
+	if err := json.NewDecoder(http.MaxBytesReader(rw, r.Body, 16384)).Decode(&params); err != nil {
+		http.Error(rw, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := w.BroadcastMessage(params); err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	writeJSON(rw, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"clients": w.wsHandler.GetClientCount(),
+	})
+}
+
+// handleAdminEvents handles GET /admin/events?type=...&limit=N, returning
+// the most recent session events
+func (w *WebUI) handleAdminEvents(rw http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		rw.Header().Set("Allow", http.MethodGet)
+		http.Error(rw, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	view := w.GetView()
+	if view == nil {
+		http.Error(rw, "No active session", http.StatusNotFound)
+		return
+	}
+
+	limit := 0
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 0 {
+			http.Error(rw, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+
+	writeJSON(rw, http.StatusOK, map[string]interface{}{
+		"events": view.Events().Events(r.URL.Query().Get("type"), limit),
+	})
+}
+
+// handleAdminSecurity handles GET /admin/security, reporting escape policy
+// enforcement and the most recent security events
+func (w *WebUI) handleAdminSecurity(rw http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		rw.Header().Set("Allow", http.MethodGet)
+		http.Error(rw, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	view := w.GetView()
+	if view == nil {
+		http.Error(rw, "No active session", http.StatusNotFound)
+		return
+	}
+
+	writeJSON(rw, http.StatusOK, map[string]interface{}{
+		"escape": view.EscapeStats(),
+		"events": view.Events().Events(EventEscapeOverflow, 50),
+	})
+}
+
+// handleAdminClients handles GET /admin/clients, listing connected clients
+// with their delivery statistics
+func (w *WebUI) handleAdminClients(rw http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		rw.Header().Set("Allow", http.MethodGet)
+		http.Error(rw, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	writeJSON(rw, http.StatusOK, map[string]interface{}{
+		"clients": w.wsHandler.ClientStats(),
+	})
+}
+
+// BanParams represents parameters for a manual ban
+type BanParams struct {
+	IP       string `json:"ip"`
+	Reason   string `json:"reason,omitempty"`
+	Duration string `json:"duration,omitempty"` // default: configured ban duration
+}
+
+// handleAdminBans handles /admin/bans: GET lists active bans, POST adds one
+// and DELETE lifts the ban given by ?ip=, or every ban when ip is omitted
+func (w *WebUI) handleAdminBans(rw http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(rw, http.StatusOK, map[string]interface{}{
+			"bans": w.rateLimiter.Bans(),
+		})
+
+	case http.MethodPost:
+		var params BanParams
+		if err := json.NewDecoder(http.MaxBytesReader(rw, r.Body, 4096)).Decode(&params); err != nil {
+			http.Error(rw, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if net.ParseIP(params.IP) == nil {
+			http.Error(rw, "A valid ip is required", http.StatusBadRequest)
+			return
+		}
+
+		var duration time.Duration
+		if params.Duration != "" {
+			d, err := time.ParseDuration(params.Duration)
+			if err != nil || d <= 0 {
+				http.Error(rw, "Invalid duration", http.StatusBadRequest)
+				return
+			}
+			duration = d
+		}
+		if params.Reason == "" {
+			params.Reason = "banned by administrator"
+		}
+
+		w.rateLimiter.Ban(params.IP, params.Reason, duration)
+		writeJSON(rw, http.StatusOK, map[string]interface{}{"success": true})
+
+	case http.MethodDelete:
+		ip := r.URL.Query().Get("ip")
+		if ip == "" {
+			writeJSON(rw, http.StatusOK, map[string]interface{}{
+				"success": true,
+				"removed": w.rateLimiter.ClearBans(),
+			})
+			return
+		}
+
+		removed := 0
+		if w.rateLimiter.Unban(ip) {
+			removed = 1
+		}
+		writeJSON(rw, http.StatusOK, map[string]interface{}{
+			"success": true,
+			"removed": removed,
+		})
+
+	default:
+		rw.Header().Set("Allow", "GET, POST, DELETE")
+		http.Error(rw, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,267 @@
+package webui
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/opd-ai/go-gamelaunch-client/pkg/dgclient"
+	"github.com/opd-ai/go-gamelaunch-www/pkg/i18n"
+	"github.com/opd-ai/go-gamelaunch-www/pkg/transport"
+	"nhooyr.io/websocket"
+	"nhooyr.io/websocket/wsjson"
+)
+
+// readSystem reads messages from conn until a system message arrives
+func readSystem(t *testing.T, ctx context.Context, conn *websocket.Conn) *transport.SystemPayload {
+	t.Helper()
+
+	for {
+		var msg transport.Message
+		if err := wsjson.Read(ctx, conn, &msg); err != nil {
+			t.Fatalf("Failed to read message: %v", err)
+		}
+		if msg.Type != transport.MsgTypeSystem {
+			continue
+		}
+
+		var system transport.SystemPayload
+		if err := json.Unmarshal(msg.Payload, &system); err != nil {
+			t.Fatalf("Failed to decode system message: %v", err)
+		}
+		return &system
+	}
+}
+
+// adminTokens authenticates adminToken, which the administrative
+// endpoints need to exist at all
+var adminTokens = []StaticToken{{Name: "op", Token: adminToken, Role: "admin"}}
+
+// newAdminWebUI creates a WebUI with opts that accepts adminToken
+func newAdminWebUI(t *testing.T, opts WebUIOptions) *WebUI {
+	t.Helper()
+
+	if opts.View == nil {
+		opts.View = newTestView(t)
+	}
+	opts.StaticTokens = append(opts.StaticTokens, adminTokens...)
+	ui, err := NewWebUI(opts)
+	if err != nil {
+		t.Fatalf("Failed to create WebUI: %v", err)
+	}
+	return ui
+}
+
+// newAdminRequest returns a request authenticated as an admin
+func newAdminRequest(method, target string, body io.Reader) *http.Request {
+	req := httptest.NewRequest(method, target, body)
+	req.Header.Set("Authorization", "Bearer "+adminToken)
+	return req
+}
+
+func TestWebUI_AdminRoutes_RequireAuthentication(t *testing.T) {
+	routes := []string{"/admin/broadcast", "/admin/events", "/admin/expiry", "/admin/clients", "/admin/security", "/admin/bans", "/admin/snapshots", "/debug/damage"}
+	opts := WebUIOptions{
+		RateLimit: &RateLimitConfig{RequestsPerSecond: 100, Burst: 100},
+		Snapshots: &SnapshotConfig{Dir: t.TempDir()},
+		DamageMap: &DamageMapConfig{},
+	}
+
+	// Without authentication nobody is an admin, so the routes do not exist
+	opts.View = newTestView(t)
+	open, err := NewWebUI(opts)
+	if err != nil {
+		t.Fatalf("Failed to create WebUI: %v", err)
+	}
+	for _, route := range routes {
+		rec := httptest.NewRecorder()
+		open.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, route, nil))
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("GET %s without authentication = %d, want 404", route, rec.Code)
+		}
+	}
+
+	opts.View = nil
+	ui := newAdminWebUI(t, opts)
+	for _, route := range routes {
+		rec := httptest.NewRecorder()
+		ui.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, route, nil))
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("anonymous GET %s = %d, want 401", route, rec.Code)
+		}
+		rec = httptest.NewRecorder()
+		ui.ServeHTTP(rec, newAdminRequest(http.MethodGet, route, nil))
+		if rec.Code == http.StatusNotFound || rec.Code == http.StatusUnauthorized || rec.Code == http.StatusForbidden {
+			t.Errorf("admin GET %s = %d", route, rec.Code)
+		}
+	}
+}
+
+func TestWebUI_AdminBroadcast_ValidatesRequests(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		body       string
+		wantStatus int
+	}{
+		{"ValidMessage", http.MethodPost, `{"message":"restart in 5 minutes"}`, http.StatusOK},
+		{"WarningLevel", http.MethodPost, `{"message":"restart","level":"warning"}`, http.StatusOK},
+		{"EmptyMessage", http.MethodPost, `{"message":"   "}`, http.StatusBadRequest},
+		{"UnknownLevel", http.MethodPost, `{"message":"hi","level":"loud"}`, http.StatusBadRequest},
+		{"TooLong", http.MethodPost, `{"message":"` + strings.Repeat("x", maxBroadcastLength+1) + `"}`, http.StatusBadRequest},
+		{"Translated", http.MethodPost, `{"message":"restart","translations":{"de":"Neustart","pt-BR":"reinício"}}`, http.StatusOK},
+		{"InvalidLocale", http.MethodPost, `{"message":"restart","translations":{"??":"x"}}`, http.StatusBadRequest},
+		{"EmptyTranslation", http.MethodPost, `{"message":"restart","translations":{"de":" "}}`, http.StatusBadRequest},
+		{"InvalidJSON", http.MethodPost, `{`, http.StatusBadRequest},
+		{"WrongMethod", http.MethodGet, ``, http.StatusMethodNotAllowed},
+	}
+
+	webUI := newAdminWebUI(t, WebUIOptions{})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			req := newAdminRequest(tt.method, "/admin/broadcast", strings.NewReader(tt.body))
+			webUI.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d (body: %s)", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestWebUI_AdminClients_ListsDeliveryStats(t *testing.T) {
+	webUI := newAdminWebUI(t, WebUIOptions{})
+
+	rec := httptest.NewRecorder()
+	webUI.ServeHTTP(rec, newAdminRequest(http.MethodGet, "/admin/clients", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", rec.Code)
+	}
+	if !strings.Contains(rec.Body.String(), `"clients":[]`) {
+		t.Errorf("unexpected body: %s", rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	webUI.ServeHTTP(rec, newAdminRequest(http.MethodGet, "/metrics", nil))
+	if !strings.Contains(rec.Body.String(), `dgconnect_ws_slow_clients{session_id="`+webUI.CorrelationID()+`"} 0`) {
+		t.Errorf("metrics missing slow client gauge:\n%s", rec.Body.String())
+	}
+}
+
+func TestWebUI_BroadcastMessage_TravelsWithState(t *testing.T) {
+	ui := newTestWebUI(t)
+	view := ui.GetView()
+	if err := view.Render([]byte("x")); err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	sm := view.GetStateManager()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	go ui.streamState(ctx, view, sm.GetCurrentVersion())
+	server := httptest.NewServer(ui)
+	defer server.Close()
+	conn, _, err := websocket.Dial(ctx, "ws"+strings.TrimPrefix(server.URL, "http")+"/ws", nil)
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	defer conn.Close(websocket.StatusNormalClosure, "")
+	conn.SetReadLimit(1 << 20)
+	readState(t, ctx, conn)
+
+	polled := make(chan *StateDiff, 1)
+	go func(version uint64) {
+		diff, _ := sm.PollChanges(version, 5*time.Second)
+		polled <- diff
+	}(sm.GetCurrentVersion())
+
+	err = ui.BroadcastMessage(BroadcastParams{Message: "restart", Level: "warning", Translations: map[string]string{"de": "Neustart"}})
+	if err != nil {
+		t.Fatalf("BroadcastMessage failed: %v", err)
+	}
+
+	// WebSocket clients and pollers alike receive it with the state
+	if got := readState(t, ctx, conn).Broadcast; got == nil || got.Message != "restart" || got.Level != "warning" || got.Translations["de"] != "Neustart" {
+		t.Errorf("WebSocket state carried broadcast %+v, want the translated warning", got)
+	}
+	diff := <-polled
+	if diff == nil || diff.Broadcast == nil || diff.Broadcast.Message != "restart" {
+		t.Fatalf("poller received %+v, want the broadcast", diff)
+	}
+	broadcast := diff.Broadcast
+
+	// It is still shown to clients arriving later, until it is hidden
+	if got := sm.GetCurrentState().Broadcast; got != broadcast {
+		t.Errorf("current state carries broadcast %+v, want %+v", got, broadcast)
+	}
+	ui.showBroadcast(&SessionBroadcast{Message: "old"}, nil)
+	if sm.GetCurrentState().Broadcast != broadcast {
+		t.Error("hiding an older broadcast hid the current one")
+	}
+	ui.showBroadcast(broadcast, nil)
+	if got := readState(t, ctx, conn).Broadcast; got != nil {
+		t.Errorf("state after hiding carried broadcast %+v", got)
+	}
+}
+
+func TestWebUI_BroadcastNotice_LocalizesPerClient(t *testing.T) {
+	view, err := NewWebView(dgclient.ViewOptions{InitialWidth: 80, InitialHeight: 24})
+	if err != nil {
+		t.Fatalf("Failed to create WebView: %v", err)
+	}
+	ui, err := NewWebUI(WebUIOptions{
+		View:     view,
+		Messages: map[string]map[string]string{"de": {i18n.MsgRecordingStarted: "Diese Sitzung wird aufgezeichnet"}},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create WebUI: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	server := httptest.NewServer(ui)
+	defer server.Close()
+
+	base := "ws" + strings.TrimPrefix(server.URL, "http") + "/ws"
+	german, _, err := websocket.Dial(ctx, base+"?locale=de-AT", nil)
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	defer german.Close(websocket.StatusNormalClosure, "")
+	english, _, err := websocket.Dial(ctx, base, nil)
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	defer english.Close(websocket.StatusNormalClosure, "")
+	for ui.wsHandler.GetClientCount() < 2 {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	ui.broadcastNotice("info", i18n.MsgRecordingStarted)
+	if got := readSystem(t, ctx, german).Message; got != "Diese Sitzung wird aufgezeichnet" {
+		t.Errorf("german client got %q, want the translated notice", got)
+	}
+	if got, want := readSystem(t, ctx, english).Message, i18n.English[i18n.MsgRecordingStarted]; got != want {
+		t.Errorf("english client got %q, want %q", got, want)
+	}
+}
+
+func TestNewWebUI_RejectsInvalidMessages(t *testing.T) {
+	view, err := NewWebView(dgclient.ViewOptions{InitialWidth: 80, InitialHeight: 24})
+	if err != nil {
+		t.Fatalf("Failed to create WebView: %v", err)
+	}
+	_, err = NewWebUI(WebUIOptions{
+		View:     view,
+		Messages: map[string]map[string]string{"de": {i18n.MsgSessionFull: "Sitzung ist voll"}},
+	})
+	if err == nil {
+		t.Error("expected an error for a translation missing its argument")
+	}
+}
@@ -0,0 +1,105 @@
+// Package webui provides the text attributes that persist across SGR
+// sequences: underline, underline color, overline and concealment (SGR 4,
+// 8, 21, 24, 28, 53, 55, 58 and 59).
+package webui
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/opd-ai/go-gamelaunch-www/pkg/transport"
+)
+
+// Underline styles of a cell; the empty style is no underline
+const (
+	UnderlineSingle = "single"
+	UnderlineDouble = "double"
+	UnderlineCurly  = "curly"
+	UnderlineDotted = "dotted"
+	UnderlineDashed = "dashed"
+)
+
+// underlineStyles maps the sub-parameter of SGR 4 (as in 4:3) to a style
+var underlineStyles = []string{"", UnderlineSingle, UnderlineDouble, UnderlineCurly, UnderlineDotted, UnderlineDashed}
+
+// textAttributes are the attributes of the cursor that, unlike colors,
+// persist across SGR sequences until reset
+type textAttributes struct {
+	underline      string
+	underlineColor string // #RRGGBB, or empty for the foreground color
+	overline       bool
+	concealed      bool
+}
+
+// applyTextAttributes updates the text attributes from SGR params, which may
+// use semicolons or colons (58:2::255:0:0) between color components
+func (v *WebView) applyTextAttributes(params []string) {
+	a := &v.currentAttrs
+	for i := 0; i < len(params); i++ {
+		code, sub, hasSub := strings.Cut(params[i], ":")
+		switch code {
+		case "", "0":
+			*a = textAttributes{}
+		case "4":
+			a.underline = UnderlineSingle
+			if hasSub {
+				a.underline = ""
+				if style, err := strconv.Atoi(sub); err == nil && style >= 0 && style < len(underlineStyles) {
+					a.underline = underlineStyles[style]
+				}
+			}
+		case "8":
+			a.concealed = true
+		case "28":
+			a.concealed = false
+		case "21":
+			a.underline = UnderlineDouble
+		case "24":
+			a.underline = ""
+		case "53":
+			a.overline = true
+		case "55":
+			a.overline = false
+		case "58":
+			if hasSub {
+				a.underlineColor = v.colonColor(sub)
+				continue
+			}
+			color, consumed := v.colorConverter.parseExtendedColor(params[i+1:])
+			if color != "" {
+				a.underlineColor = color
+			}
+			i += consumed
+		case "59":
+			a.underlineColor = ""
+		case "38", "48":
+			// Skip the components, which could otherwise read as codes
+			if !hasSub {
+				_, consumed := v.colorConverter.parseExtendedColor(params[i+1:])
+				i += consumed
+			}
+		}
+	}
+}
+
+// colonColor converts the colon-separated components of an extended color,
+// "5:N", "2:R:G:B" or "2:ID:R:G:B" with a color space ID, to #RRGGBB
+func (v *WebView) colonColor(sub string) string {
+	fields := strings.Split(sub, ":")
+	if fields[0] == "2" && len(fields) > 4 {
+		fields = append(fields[:1], fields[len(fields)-3:]...)
+	}
+	color, _ := v.colorConverter.parseExtendedColor(fields)
+	return color
+}
+
+// concealmentPolicy withholds concealed text from clients that may not send
+// input; see WebUIOptions.WithholdConcealed
+func (w *WebUI) concealmentPolicy(ctx context.Context, opts transport.ViewOptions) transport.ViewOptions {
+	if !w.authorize(ctx, MethodInput) {
+		opts.RevealConcealed = false
+		opts.WithholdConcealed = true
+	}
+	return opts
+}
@@ -0,0 +1,114 @@
+package webui
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/opd-ai/go-gamelaunch-www/pkg/transport"
+)
+
+func TestWebView_Render_TextAttributes(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		underline string
+		color     string
+		overline  bool
+	}{
+		{"single", "\x1b[4mx", UnderlineSingle, "", false},
+		{"double", "\x1b[21mx", UnderlineDouble, "", false},
+		{"curly sub-parameter", "\x1b[4:3mx", UnderlineCurly, "", false},
+		{"style 0 removes it", "\x1b[4m\x1b[4:0mx", "", "", false},
+		{"persists across color changes", "\x1b[4;58;5;196m\x1b[32mx", UnderlineSingle, "#FF0000", false},
+		{"RGB color", "\x1b[4;58;2;1;2;3mx", UnderlineSingle, "#010203", false},
+		{"colon color with a color space", "\x1b[4;58:2::255:128:0mx", UnderlineSingle, "#FF8000", false},
+		{"colon 256 color", "\x1b[58:5:21mx", "", "#0000FF", false},
+		{"59 restores the default color", "\x1b[4;58;5;196m\x1b[59mx", UnderlineSingle, "", false},
+		{"24 removes it", "\x1b[21m\x1b[24mx", "", "", false},
+		{"overline", "\x1b[53mx", "", "", true},
+		{"55 removes overline", "\x1b[53m\x1b[55mx", "", "", false},
+		{"reset", "\x1b[4;53;58;5;196m\x1b[mx", "", "", false},
+		{"foreground components are not codes", "\x1b[38;5;4mx", "", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			view := newTestView(t)
+			if err := view.Render([]byte(tt.input)); err != nil {
+				t.Fatalf("Render failed: %v", err)
+			}
+			cell := view.GetCurrentState().Buffer[0][0]
+			if cell.Underline != tt.underline || cell.UnderlineColor != tt.color || cell.Overline != tt.overline {
+				t.Errorf("cell = underline %q color %q overline %t, want %q %q %t",
+					cell.Underline, cell.UnderlineColor, cell.Overline, tt.underline, tt.color, tt.overline)
+			}
+		})
+	}
+}
+
+func TestToStatePayloadFor_UnderlineColor(t *testing.T) {
+	state := &GameState{Width: 1, Height: 1, Buffer: newCellGrid(1, 1)}
+	state.Buffer[0][0] = Cell{Char: 'x', FgColor: "#FFFFFF", BgColor: "#000000", Underline: UnderlineDouble, UnderlineColor: "#FE0102"}
+
+	payload := toStatePayloadFor(state, transport.ViewOptions{Palette: transport.PaletteANSI16}, NewColorConverter())
+	defer releaseStatePayload(payload)
+	if cell := payload.Buffer[0][0]; cell.Underline != UnderlineDouble || cell.UnderlineColor != "#FF0000" {
+		t.Errorf("wire cell = %+v, want a double underline in the nearest palette color", cell)
+	}
+}
+
+func TestWebUI_WithholdConcealed(t *testing.T) {
+	view := newTestView(t)
+	ui, err := NewWebUI(WebUIOptions{
+		View: view,
+		StaticTokens: []StaticToken{
+			{Name: "watcher", Token: spectatorToken, Role: "spectator"},
+			{Name: "hero", Token: playerToken, Role: "player"},
+		},
+		WithholdConcealed: true,
+		InstantReplay:     &InstantReplayConfig{},
+	})
+	if err != nil {
+		t.Fatalf("NewWebUI failed: %v", err)
+	}
+	if err := view.Render([]byte("\x1b[8mpw\x1b[28m!")); err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	state := view.GetCurrentState()
+	if !state.Buffer[0][0].Concealed || state.Buffer[0][0].Char != 'p' || state.Buffer[0][2].Concealed {
+		t.Fatalf("cells = %+v, want the concealed text kept and flagged", state.Buffer[0][:3])
+	}
+
+	tests := []struct {
+		token         string
+		reveal        bool
+		wantChar      string
+		wantConcealed bool
+		wantReplay    int
+	}{
+		{playerToken, false, "p", true, http.StatusOK},
+		{playerToken, true, "p", false, http.StatusOK},
+		{spectatorToken, false, " ", true, http.StatusForbidden},
+		{spectatorToken, true, " ", true, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/replay/instant", nil)
+		req.Header.Set("Authorization", "Bearer "+tt.token)
+		identity, provider := ui.authenticate(req)
+		ctx := withAuthProvider(WithIdentity(req.Context(), identity), provider)
+
+		opts := ui.concealmentPolicy(ctx, transport.ViewOptions{RevealConcealed: tt.reveal})
+		payload := toStatePayloadFor(state, opts, nil)
+		if cell := payload.Buffer[0][0]; cell.Char != tt.wantChar || cell.Concealed != tt.wantConcealed {
+			t.Errorf("%s reveal=%t: cell = %q concealed %t, want %q %t", identity.Name, tt.reveal, cell.Char, cell.Concealed, tt.wantChar, tt.wantConcealed)
+		}
+		releaseStatePayload(payload)
+
+		rec := httptest.NewRecorder()
+		ui.ServeHTTP(rec, req)
+		if rec.Code != tt.wantReplay {
+			t.Errorf("%s: instant replay = %d, want %d", identity.Name, rec.Code, tt.wantReplay)
+		}
+	}
+}
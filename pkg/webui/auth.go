@@ -0,0 +1,212 @@
+// Package webui provides web authentication primitives shared by the
+// supported login mechanisms: roles, identities and cookie-backed sessions.
+package webui
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Role identifies what an authenticated user may do in a session
+type Role string
+
+// Supported roles, from least to most privileged
+const (
+	RoleSpectator Role = "spectator"
+	RolePlayer    Role = "player"
+	RoleAdmin     Role = "admin"
+)
+
+// roleRank orders roles by privilege
+var roleRank = map[Role]int{
+	RoleSpectator: 1,
+	RolePlayer:    2,
+	RoleAdmin:     3,
+}
+
+// ParseRole validates a role name
+func ParseRole(name string) (Role, bool) {
+	role := Role(name)
+	_, ok := roleRank[role]
+	return role, ok
+}
+
+// Allows reports whether r grants at least the privileges of required
+func (r Role) Allows(required Role) bool {
+	return roleRank[r] >= roleRank[required]
+}
+
+// validateRoleMapping checks that every role named in a group mapping and the
+// default role are known roles
+func validateRoleMapping(groupRoles map[string]string, defaultRole string) error {
+	if defaultRole != "" {
+		if _, ok := ParseRole(defaultRole); !ok {
+			return fmt.Errorf("unknown default_role %q", defaultRole)
+		}
+	}
+	for group, role := range groupRoles {
+		if _, ok := ParseRole(role); !ok {
+			return fmt.Errorf("group %q maps to unknown role %q", group, role)
+		}
+	}
+	return nil
+}
+
+// resolveRole returns the most privileged role granted to any of groups by
+// groupRoles, falling back to defaultRole. An empty result denies access.
+func resolveRole(groupRoles map[string]string, defaultRole string, groups []string) Role {
+	var best Role
+	for _, group := range groups {
+		if role, ok := ParseRole(groupRoles[group]); ok && !best.Allows(role) {
+			best = role
+		}
+	}
+	if best == "" {
+		best = Role(defaultRole)
+	}
+	return best
+}
+
+// Identity describes an authenticated web user
+type Identity struct {
+	Subject string   `json:"subject"`
+	Name    string   `json:"name,omitempty"`
+	Groups  []string `json:"groups,omitempty"`
+	Role    Role     `json:"role"`
+}
+
+// identityContextKey is the request context key for the current Identity
+type identityContextKey struct{}
+
+// WithIdentity returns a copy of ctx carrying identity
+func WithIdentity(ctx context.Context, identity *Identity) context.Context {
+	return context.WithValue(ctx, identityContextKey{}, identity)
+}
+
+// IdentityFromContext returns the authenticated identity of a request, or
+// nil when authentication is disabled or the request is anonymous
+func IdentityFromContext(ctx context.Context) *Identity {
+	identity, _ := ctx.Value(identityContextKey{}).(*Identity)
+	return identity
+}
+
+// sessionCookieName is the cookie holding the web session token
+const sessionCookieName = "dgconnect_session"
+
+// authSession is a logged-in browser session
+type authSession struct {
+	identity *Identity
+	expires  time.Time
+}
+
+// authSessionStore keeps web sessions in memory, keyed by random token
+type authSessionStore struct {
+	mu       sync.Mutex
+	sessions map[string]*authSession
+	ttl      time.Duration
+}
+
+// newAuthSessionStore creates a session store whose sessions live for ttl
+func newAuthSessionStore(ttl time.Duration) *authSessionStore {
+	return &authSessionStore{
+		sessions: make(map[string]*authSession),
+		ttl:      ttl,
+	}
+}
+
+// create stores identity under a new random token and returns the token
+func (s *authSessionStore) create(identity *Identity) (string, error) {
+	token, err := randomToken(32)
+	if err != nil {
+		return "", err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for key, session := range s.sessions {
+		if now.After(session.expires) {
+			delete(s.sessions, key)
+		}
+	}
+	s.sessions[token] = &authSession{identity: identity, expires: now.Add(s.ttl)}
+	return token, nil
+}
+
+// lookup returns the identity for token if the session is still valid
+func (s *authSessionStore) lookup(token string) *Identity {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session, ok := s.sessions[token]
+	if !ok {
+		return nil
+	}
+	if time.Now().After(session.expires) {
+		delete(s.sessions, token)
+		return nil
+	}
+	return session.identity
+}
+
+// remove ends the session identified by token
+func (s *authSessionStore) remove(token string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.sessions, token)
+}
+
+// fromRequest returns the identity of the session cookie on r, if any
+func (s *authSessionStore) fromRequest(r *http.Request) *Identity {
+	cookie, err := r.Cookie(sessionCookieName)
+	if err != nil {
+		return nil
+	}
+	return s.lookup(cookie.Value)
+}
+
+// setCookie writes the session cookie for token
+func (s *authSessionStore) setCookie(rw http.ResponseWriter, r *http.Request, token string) {
+	http.SetCookie(rw, &http.Cookie{
+		Name:     sessionCookieName,
+		Value:    token,
+		Path:     cookiePath(r),
+		MaxAge:   int(s.ttl.Seconds()),
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// cookiePath scopes cookies to the session or tenant that r was routed
+// through, so logins and preferences do not leak between them
+func cookiePath(r *http.Request) string {
+	return basePath(r.Context()) + "/"
+}
+
+// clearCookie expires the cookie name set for path
+func clearCookie(rw http.ResponseWriter, name, path string) {
+	http.SetCookie(rw, &http.Cookie{
+		Name:     name,
+		Value:    "",
+		Path:     path,
+		MaxAge:   -1,
+		HttpOnly: true,
+	})
+}
+
+// randomToken returns n random bytes encoded as hex
+func randomToken(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,150 @@
+// Package webui provides the pluggable authentication provider interface and
+// bearer-token authentication for scripts and bots.
+package webui
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// minStaticTokenLength rejects tokens short enough to be guessed
+const minStaticTokenLength = 16
+
+// AuthProvider connects the web interface to an identity system. The
+// built-in providers cover OIDC, trusted proxy headers, static tokens and
+// htpasswd files; deployments add their own through
+// WebUIOptions.AuthProviders.
+type AuthProvider interface {
+	// Authenticate returns the identity behind r, or nil when r carries no
+	// credentials the provider recognises
+	Authenticate(r *http.Request) *Identity
+
+	// Authorize reports whether identity may act with the privileges of
+	// required
+	Authorize(identity *Identity, required Role) bool
+
+	// Routes returns the provider's session endpoints, such as login and
+	// logout, keyed by path. Paths must be below /auth/, which is reachable
+	// without a login. Providers without sessions return nil.
+	Routes() map[string]http.HandlerFunc
+}
+
+// AuthChallenger is implemented by providers that can tell clients how to
+// authenticate, e.g. with a WWW-Authenticate challenge
+type AuthChallenger interface {
+	// Challenge returns the WWW-Authenticate header value sent with 401
+	// responses
+	Challenge() string
+}
+
+// RoleAuthorizer implements AuthProvider.Authorize by comparing the role of
+// the identity with the required role. Providers embed it.
+type RoleAuthorizer struct{}
+
+// Authorize reports whether identity holds at least the required role
+func (RoleAuthorizer) Authorize(identity *Identity, required Role) bool {
+	return identity != nil && identity.Role != "" && identity.Role.Allows(required)
+}
+
+// StaticToken is a bearer token granting a fixed identity, typically to a
+// bot or script
+type StaticToken struct {
+	Name  string `yaml:"name"`
+	Token string `yaml:"token"`
+	Role  string `yaml:"role"`
+}
+
+// StaticTokenAuthenticator accepts the configured tokens in an
+// "Authorization: Bearer" header, or in an access_token query parameter for
+// WebSocket clients that cannot set headers
+type StaticTokenAuthenticator struct {
+	RoleAuthorizer
+	tokens map[[sha256.Size]byte]*Identity
+}
+
+// NewStaticTokenAuthenticator validates tokens and creates an authenticator
+func NewStaticTokenAuthenticator(tokens []StaticToken) (*StaticTokenAuthenticator, error) {
+	a := &StaticTokenAuthenticator{tokens: make(map[[sha256.Size]byte]*Identity, len(tokens))}
+	for i, token := range tokens {
+		if token.Name == "" {
+			return nil, fmt.Errorf("static tokens: token %d has no name", i)
+		}
+		if len(token.Token) < minStaticTokenLength {
+			return nil, fmt.Errorf("static tokens: token %q must be at least %d characters", token.Name, minStaticTokenLength)
+		}
+		role, ok := ParseRole(token.Role)
+		if !ok {
+			return nil, fmt.Errorf("static tokens: token %q has unknown role %q", token.Name, token.Role)
+		}
+
+		// Tokens are looked up by hash so that comparisons do not leak
+		// their contents through timing
+		key := sha256.Sum256([]byte(token.Token))
+		if _, exists := a.tokens[key]; exists {
+			return nil, fmt.Errorf("static tokens: token %q is a duplicate", token.Name)
+		}
+		a.tokens[key] = &Identity{Subject: "token:" + token.Name, Name: token.Name, Role: role}
+	}
+	return a, nil
+}
+
+// Authenticate returns the identity of the token presented with r, or nil
+func (a *StaticTokenAuthenticator) Authenticate(r *http.Request) *Identity {
+	token := r.URL.Query().Get("access_token")
+	if scheme, value, ok := strings.Cut(r.Header.Get("Authorization"), " "); ok && strings.EqualFold(scheme, "Bearer") {
+		token = strings.TrimSpace(value)
+	}
+	if token == "" {
+		return nil
+	}
+
+	identity, ok := a.tokens[sha256.Sum256([]byte(token))]
+	if !ok {
+		return nil
+	}
+	copied := *identity
+	return &copied
+}
+
+// Routes implements AuthProvider; tokens need no session endpoints
+func (a *StaticTokenAuthenticator) Routes() map[string]http.HandlerFunc {
+	return nil
+}
+
+// newAuthProviders creates the built-in providers configured in opts,
+// followed by opts.AuthProviders. Trusted proxy headers and tokens are
+// checked before session cookies.
+func newAuthProviders(opts WebUIOptions) ([]AuthProvider, error) {
+	var providers []AuthProvider
+	if opts.ProxyAuth != nil {
+		proxyAuth, err := NewProxyAuthenticator(*opts.ProxyAuth)
+		if err != nil {
+			return nil, err
+		}
+		providers = append(providers, proxyAuth)
+	}
+	if len(opts.StaticTokens) > 0 {
+		tokens, err := NewStaticTokenAuthenticator(opts.StaticTokens)
+		if err != nil {
+			return nil, err
+		}
+		providers = append(providers, tokens)
+	}
+	if opts.Htpasswd != nil {
+		htpasswd, err := NewHtpasswdAuthenticator(*opts.Htpasswd)
+		if err != nil {
+			return nil, err
+		}
+		providers = append(providers, htpasswd)
+	}
+	if opts.OIDC != nil {
+		oidc, err := NewOIDCAuthenticator(*opts.OIDC)
+		if err != nil {
+			return nil, err
+		}
+		providers = append(providers, oidc)
+	}
+	return append(providers, opts.AuthProviders...), nil
+}
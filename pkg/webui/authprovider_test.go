@@ -0,0 +1,178 @@
+package webui
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// headerProvider is a custom AuthProvider trusting an X-Test-User header
+type headerProvider struct {
+	RoleAuthorizer
+	routes map[string]http.HandlerFunc
+}
+
+func (p headerProvider) Authenticate(r *http.Request) *Identity {
+	if user := r.Header.Get("X-Test-User"); user != "" {
+		return &Identity{Subject: user, Role: RolePlayer}
+	}
+	return nil
+}
+
+func (p headerProvider) Routes() map[string]http.HandlerFunc {
+	return p.routes
+}
+
+func TestNewStaticTokenAuthenticator_InvalidConfig(t *testing.T) {
+	tests := []struct {
+		name   string
+		tokens []StaticToken
+	}{
+		{"no name", []StaticToken{{Token: "0123456789abcdef", Role: "player"}}},
+		{"short token", []StaticToken{{Name: "bot", Token: "short", Role: "player"}}},
+		{"bad role", []StaticToken{{Name: "bot", Token: "0123456789abcdef", Role: "root"}}},
+		{"duplicate", []StaticToken{
+			{Name: "a", Token: "0123456789abcdef", Role: "player"},
+			{Name: "b", Token: "0123456789abcdef", Role: "admin"},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewStaticTokenAuthenticator(tt.tokens); err == nil {
+				t.Error("Expected configuration error")
+			}
+		})
+	}
+}
+
+func TestStaticTokenAuthenticator_Authenticate(t *testing.T) {
+	auth, err := NewStaticTokenAuthenticator([]StaticToken{
+		{Name: "scorebot", Token: "s3cret-token-for-bot", Role: "spectator"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create authenticator: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		target string
+		header string
+		want   bool
+	}{
+		{"bearer header", "/version", "Bearer s3cret-token-for-bot", true},
+		{"query parameter", "/ws?access_token=s3cret-token-for-bot", "", true},
+		{"wrong token", "/version", "Bearer s3cret-token-for-bob", false},
+		{"other scheme", "/version", "Basic s3cret-token-for-bot", false},
+		{"no credentials", "/version", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", tt.target, nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			identity := auth.Authenticate(req)
+			if (identity != nil) != tt.want {
+				t.Fatalf("Expected authenticated=%v, got %+v", tt.want, identity)
+			}
+			if identity != nil && (identity.Name != "scorebot" || identity.Role != RoleSpectator) {
+				t.Errorf("Unexpected identity %+v", identity)
+			}
+		})
+	}
+}
+
+func TestRoleAuthorizer_Authorize(t *testing.T) {
+	var authz RoleAuthorizer
+	tests := []struct {
+		name     string
+		identity *Identity
+		required Role
+		want     bool
+	}{
+		{"nil identity", nil, RoleSpectator, false},
+		{"no role", &Identity{Subject: "eve"}, RoleSpectator, false},
+		{"player as spectator", &Identity{Role: RolePlayer}, RoleSpectator, true},
+		{"player as admin", &Identity{Role: RolePlayer}, RoleAdmin, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := authz.Authorize(tt.identity, tt.required); got != tt.want {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestWebUI_CustomAuthProvider(t *testing.T) {
+	loggedIn := false
+	view := newTestView(t)
+	ui, err := NewWebUI(WebUIOptions{
+		View: view,
+		StaticTokens: []StaticToken{
+			{Name: "bot", Token: "0123456789abcdef", Role: "admin"},
+		},
+		AuthProviders: []AuthProvider{headerProvider{routes: map[string]http.HandlerFunc{
+			"/auth/custom": func(rw http.ResponseWriter, r *http.Request) { loggedIn = true },
+		}}},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create WebUI: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		target     string
+		header     string
+		value      string
+		wantStatus int
+	}{
+		{"anonymous", "/version", "", "", http.StatusUnauthorized},
+		{"custom provider", "/version", "X-Test-User", "alice", http.StatusOK},
+		{"static token", "/version", "Authorization", "Bearer 0123456789abcdef", http.StatusOK},
+		{"provider route", "/auth/custom", "", "", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", tt.target, nil)
+			if tt.header != "" {
+				req.Header.Set(tt.header, tt.value)
+			}
+			rr := httptest.NewRecorder()
+			ui.ServeHTTP(rr, req)
+
+			if rr.Code != tt.wantStatus {
+				t.Errorf("Expected status %d, got %d", tt.wantStatus, rr.Code)
+			}
+		})
+	}
+	if !loggedIn {
+		t.Error("Expected the provider route to be registered")
+	}
+}
+
+func TestNewWebUI_RejectsInvalidProviderRoutes(t *testing.T) {
+	noop := func(rw http.ResponseWriter, r *http.Request) {}
+	tests := []struct {
+		name      string
+		providers []AuthProvider
+	}{
+		{"outside /auth/", []AuthProvider{headerProvider{routes: map[string]http.HandlerFunc{"/login": noop}}}},
+		{"duplicate", []AuthProvider{
+			headerProvider{routes: map[string]http.HandlerFunc{"/auth/login": noop}},
+			headerProvider{routes: map[string]http.HandlerFunc{"/auth/login": noop}},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewWebUI(WebUIOptions{View: newTestView(t), AuthProviders: tt.providers}); err == nil {
+				t.Error("Expected route error")
+			}
+		})
+	}
+}
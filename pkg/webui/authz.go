@@ -0,0 +1,136 @@
+// Package webui provides role-based authorization of HTTP routes and
+// WebSocket messages.
+package webui
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// MethodInput names WebSocket input messages in an AuthorizationPolicy
+const MethodInput = "input"
+
+// AuthorizationPolicy maps method names to the least privileged role allowed
+// to call them. HTTP routes are named by their path with dots for slashes,
+// e.g. "admin.broadcast" for /admin/broadcast, and WebSocket input is named
+// "input". Keys are exact names, prefixes ending in ".*", or "*" for
+// everything else.
+type AuthorizationPolicy map[string]string
+
+// DefaultAuthorizationPolicy lets spectators watch, players send input
+// (including menu navigation through /games/* and presets through /input/*),
+// answer SSH login prompts through /ssh/* and choose whether their session
+// is recorded, and admins use /admin/* and /debug/* and decide on host keys.
+// Configured policies are applied on top of it.
+var DefaultAuthorizationPolicy = AuthorizationPolicy{
+	MethodInput:         string(RolePlayer),
+	"input.*":           string(RolePlayer),
+	"games.*":           string(RolePlayer),
+	"ssh.*":             string(RolePlayer),
+	"ssh.hostkey":       string(RoleAdmin),
+	"session.recording": string(RolePlayer),
+	"admin.*":           string(RoleAdmin),
+	"debug.*":           string(RoleAdmin),
+	"*":                 string(RoleSpectator),
+}
+
+// authzPolicy is a validated AuthorizationPolicy
+type authzPolicy struct {
+	exact    map[string]Role
+	prefixes map[string]Role // prefix including the trailing dot
+	fallback Role
+}
+
+// newAuthzPolicy merges overrides into DefaultAuthorizationPolicy and
+// validates the result
+func newAuthzPolicy(overrides AuthorizationPolicy) (*authzPolicy, error) {
+	merged := make(AuthorizationPolicy, len(DefaultAuthorizationPolicy)+len(overrides))
+	for method, role := range DefaultAuthorizationPolicy {
+		merged[method] = role
+	}
+	for method, role := range overrides {
+		merged[method] = role
+	}
+
+	p := &authzPolicy{exact: make(map[string]Role), prefixes: make(map[string]Role)}
+	for method, name := range merged {
+		role, ok := ParseRole(name)
+		if !ok {
+			return nil, fmt.Errorf("method %q maps to unknown role %q", method, name)
+		}
+		switch {
+		case method == "*":
+			p.fallback = role
+		case strings.HasSuffix(method, ".*"):
+			p.prefixes[strings.TrimSuffix(method, "*")] = role
+		case method == "" || strings.Contains(method, "*"):
+			return nil, fmt.Errorf("invalid method pattern %q", method)
+		default:
+			p.exact[method] = role
+		}
+	}
+	return p, nil
+}
+
+// required returns the role needed to call method, preferring an exact
+// match, then the longest matching prefix
+func (p *authzPolicy) required(method string) Role {
+	if role, ok := p.exact[method]; ok {
+		return role
+	}
+	best, role := -1, p.fallback
+	for prefix, r := range p.prefixes {
+		if strings.HasPrefix(method, prefix) && len(prefix) > best {
+			best, role = len(prefix), r
+		}
+	}
+	return role
+}
+
+// routeMethod names the method of an HTTP route in an AuthorizationPolicy
+func routeMethod(path string) string {
+	method := strings.ReplaceAll(strings.Trim(path, "/"), "/", ".")
+	if method == "" {
+		return "index"
+	}
+	return method
+}
+
+// authProviderContextKey is the request context key for the AuthProvider
+// that authenticated the request
+type authProviderContextKey struct{}
+
+// withAuthProvider returns a copy of ctx carrying provider
+func withAuthProvider(ctx context.Context, provider AuthProvider) context.Context {
+	return context.WithValue(ctx, authProviderContextKey{}, provider)
+}
+
+// authorize reports whether the identity in ctx may call method. Everything
+// is allowed when authentication is disabled.
+func (w *WebUI) authorize(ctx context.Context, method string) bool {
+	if !w.authRequired() {
+		return true
+	}
+	return w.authorizeRole(ctx, w.policy.required(method))
+}
+
+// authorizeRole reports whether the identity in ctx has at least role.
+// Unlike authorize, nothing is allowed when authentication is disabled.
+func (w *WebUI) authorizeRole(ctx context.Context, role Role) bool {
+	provider, _ := ctx.Value(authProviderContextKey{}).(AuthProvider)
+	if !w.authRequired() || provider == nil {
+		return false
+	}
+	return provider.Authorize(IdentityFromContext(ctx), role)
+}
+
+// rejectForbidden reports a request the caller's role does not allow
+func rejectForbidden(rw http.ResponseWriter, identity *Identity) {
+	if identity.Role == "" {
+		http.Error(rw, "No role assigned to this account", http.StatusForbidden)
+		return
+	}
+	http.Error(rw, "Insufficient role for this request", http.StatusForbidden)
+}
@@ -0,0 +1,213 @@
+package webui
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/opd-ai/go-gamelaunch-www/pkg/transport"
+	"nhooyr.io/websocket"
+	"nhooyr.io/websocket/wsjson"
+)
+
+// Static tokens for each role
+const (
+	spectatorToken = "spectator-token-0001"
+	playerToken    = "player-token-000001"
+	adminToken     = "admin-token-0000001"
+)
+
+// newRBACWebUI creates a WebUI authenticating one static token per role
+func newRBACWebUI(t *testing.T, policy AuthorizationPolicy, sink func([]byte) error) *WebUI {
+	t.Helper()
+
+	ui, err := NewWebUI(WebUIOptions{
+		View: newTestView(t),
+		StaticTokens: []StaticToken{
+			{Name: "watcher", Token: spectatorToken, Role: "spectator"},
+			{Name: "hero", Token: playerToken, Role: "player"},
+			{Name: "op", Token: adminToken, Role: "admin"},
+		},
+		Authorization: policy,
+		InputSink:     sink,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create WebUI: %v", err)
+	}
+	return ui
+}
+
+func TestAuthzPolicy_Required(t *testing.T) {
+	policy, err := newAuthzPolicy(AuthorizationPolicy{
+		"admin.events": "player",
+		"recordings.*": "player",
+	})
+	if err != nil {
+		t.Fatalf("newAuthzPolicy failed: %v", err)
+	}
+
+	tests := []struct {
+		method string
+		want   Role
+	}{
+		{MethodInput, RolePlayer},
+		{"admin.broadcast", RoleAdmin},
+		{"admin.events", RolePlayer},
+		{"games.watch", RolePlayer},
+		{"recordings.file", RolePlayer},
+		{"recordings", RoleSpectator},
+		{"version", RoleSpectator},
+		{"index", RoleSpectator},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method, func(t *testing.T) {
+			if got := policy.required(tt.method); got != tt.want {
+				t.Errorf("required(%q) = %q, want %q", tt.method, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewAuthzPolicy_InvalidConfig(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy AuthorizationPolicy
+	}{
+		{"unknown role", AuthorizationPolicy{"version": "root"}},
+		{"inner wildcard", AuthorizationPolicy{"admin*": "admin"}},
+		{"empty method", AuthorizationPolicy{"": "admin"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := newAuthzPolicy(tt.policy); err == nil {
+				t.Error("Expected policy error")
+			}
+		})
+	}
+}
+
+func TestRouteMethod(t *testing.T) {
+	tests := map[string]string{
+		"/":                       "index",
+		"/version":                "version",
+		"/admin/broadcast":        "admin.broadcast",
+		"/tournament/leaderboard": "tournament.leaderboard",
+	}
+	for path, want := range tests {
+		if got := routeMethod(path); got != want {
+			t.Errorf("routeMethod(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
+
+func TestWebUI_RBAC_EnforcesRoutePolicy(t *testing.T) {
+	ui := newRBACWebUI(t, nil, nil)
+
+	tests := []struct {
+		name       string
+		method     string
+		target     string
+		token      string
+		wantStatus int
+	}{
+		{"spectator reads version", "GET", "/version", spectatorToken, http.StatusOK},
+		{"spectator navigates menu", "GET", "/games/watch", spectatorToken, http.StatusForbidden},
+		{"player navigates menu", "GET", "/games/watch", playerToken, http.StatusMethodNotAllowed},
+		{"player lists clients", "GET", "/admin/clients", playerToken, http.StatusForbidden},
+		{"admin lists clients", "GET", "/admin/clients", adminToken, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.target, nil)
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+			rr := httptest.NewRecorder()
+			ui.ServeHTTP(rr, req)
+
+			if rr.Code != tt.wantStatus {
+				t.Errorf("Expected status %d, got %d", tt.wantStatus, rr.Code)
+			}
+		})
+	}
+}
+
+func TestWebUI_RBAC_PolicyOverride(t *testing.T) {
+	ui := newRBACWebUI(t, AuthorizationPolicy{"version": "admin"}, nil)
+
+	req := httptest.NewRequest("GET", "/version", nil)
+	req.Header.Set("Authorization", "Bearer "+playerToken)
+	rr := httptest.NewRecorder()
+	ui.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusForbidden {
+		t.Errorf("Expected 403, got %d", rr.Code)
+	}
+}
+
+func TestWebUI_RBAC_SpectatorInputRejected(t *testing.T) {
+	received := make(chan string, 4)
+	ui := newRBACWebUI(t, nil, func(data []byte) error {
+		received <- string(data)
+		return nil
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	server := httptest.NewServer(ui)
+	defer server.Close()
+	base := "ws" + strings.TrimPrefix(server.URL, "http") + "/ws?access_token="
+
+	sendInput := func(conn *websocket.Conn, input string) {
+		payload, _ := json.Marshal(transport.InputPayload{Input: input})
+		if err := wsjson.Write(ctx, conn, transport.Message{Type: transport.MsgTypeInput, Payload: payload}); err != nil {
+			t.Fatalf("Write failed: %v", err)
+		}
+	}
+
+	spectator, _, err := websocket.Dial(ctx, base+spectatorToken, nil)
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	defer spectator.Close(websocket.StatusNormalClosure, "")
+	spectator.SetReadLimit(1 << 20)
+
+	sendInput(spectator, "q")
+	for {
+		var msg transport.Message
+		if err := wsjson.Read(ctx, spectator, &msg); err != nil {
+			t.Fatalf("Read failed: %v", err)
+		}
+		if msg.Type != transport.MsgTypeError {
+			continue
+		}
+		var payload transport.ErrorPayload
+		json.Unmarshal(msg.Payload, &payload)
+		if payload.Code != transport.ErrCodeInputRejected {
+			t.Errorf("Error code = %d, want %d", payload.Code, transport.ErrCodeInputRejected)
+		}
+		break
+	}
+
+	player, _, err := websocket.Dial(ctx, base+playerToken, nil)
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	defer player.Close(websocket.StatusNormalClosure, "")
+
+	sendInput(player, "h")
+	select {
+	case got := <-received:
+		if got != "h" {
+			t.Errorf("Received input %q, want %q (spectator input leaked?)", got, "h")
+		}
+	case <-ctx.Done():
+		t.Fatal("Player input was not delivered")
+	}
+}
@@ -0,0 +1,366 @@
+// Package webui provides per-session bandwidth accounting with optional
+// daily and monthly caps.
+package webui
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/opd-ai/go-gamelaunch-www/pkg/i18n"
+)
+
+const (
+	// bandwidthCheckInterval is how often usage is compared with the caps
+	bandwidthCheckInterval = 10 * time.Second
+
+	// bandwidthSaveInterval is how often usage is written to the state file
+	bandwidthSaveInterval = time.Minute
+)
+
+// byteUnits are the size suffixes accepted in bandwidth caps
+var byteUnits = []struct {
+	suffix string
+	size   uint64
+}{
+	{"TIB", 1 << 40}, {"GIB", 1 << 30}, {"MIB", 1 << 20}, {"KIB", 1 << 10},
+	{"TB", 1e12}, {"GB", 1e9}, {"MB", 1e6}, {"KB", 1e3},
+	{"B", 1},
+}
+
+// BandwidthConfig sets optional caps on the traffic of a session, counting
+// WebSocket and game traffic in both directions. Once a cap is reached the
+// session degrades gracefully: screen updates are sent at most once per
+// DegradedInterval until the day or month (UTC) ends. With a StateFile,
+// the usage of the current day and month survives restarts.
+type BandwidthConfig struct {
+	DailyCap         string `yaml:"daily_cap,omitempty"`                              // e.g. "2GB"; empty for no cap
+	MonthlyCap       string `yaml:"monthly_cap,omitempty"`                            // e.g. "50GB"; empty for no cap
+	DegradedInterval string `yaml:"degraded_interval,omitempty" yamlcheck:"duration"` // default "2s"
+	StateFile        string `yaml:"state_file,omitempty"`                             // where usage is kept; empty to keep it in memory
+}
+
+// TrafficStats counts bytes in each direction
+type TrafficStats struct {
+	Sent     uint64 `json:"sent"`
+	Received uint64 `json:"received"`
+}
+
+// ClientTraffic is the traffic of a single WebSocket client
+type ClientTraffic struct {
+	ID string `json:"id"`
+	TrafficStats
+}
+
+// SessionStats reports the traffic of a session
+type SessionStats struct {
+	Web        TrafficStats    `json:"web"`  // WebSocket traffic of all clients
+	Game       TrafficStats    `json:"game"` // input sent to and output received from the game
+	Clients    []ClientTraffic `json:"clients"`
+	Today      uint64          `json:"today"` // bytes since midnight UTC
+	Month      uint64          `json:"month"` // bytes since the first of the month
+	DailyCap   uint64          `json:"daily_cap,omitempty"`
+	MonthlyCap uint64          `json:"monthly_cap,omitempty"`
+	Degraded   bool            `json:"degraded"`
+	Progress   *GameProgress   `json:"progress,omitempty"` // turn counter and game clock, when known
+	Quota      QuotaUsage      `json:"quota"`
+}
+
+// bandwidthMeter tracks game traffic and the usage of the current day and
+// month against the configured caps
+type bandwidthMeter struct {
+	dailyCap   uint64
+	monthlyCap uint64
+	interval   time.Duration
+	web        func() (sent, received uint64) // WebSocket traffic totals
+	stateFile  string
+
+	gameSent     atomic.Uint64
+	gameReceived atomic.Uint64
+
+	mu         sync.Mutex
+	day        time.Time // start of the current day window
+	month      time.Time // start of the current month window
+	dayBase    uint64    // total traffic at the start of the day window
+	monthBase  uint64    // total traffic at the start of the month window
+	dayPrior   uint64    // usage of the day window before the server started
+	monthPrior uint64    // usage of the month window before the server started
+	degraded   bool
+	now        func() time.Time
+}
+
+// newBandwidthMeter validates cfg and creates a meter over the WebSocket
+// traffic reported by web
+func newBandwidthMeter(cfg BandwidthConfig, web func() (uint64, uint64)) (*bandwidthMeter, error) {
+	m := &bandwidthMeter{interval: 2 * time.Second, web: web, now: time.Now}
+
+	var err error
+	if m.dailyCap, err = parseByteSize(cfg.DailyCap); err != nil {
+		return nil, fmt.Errorf("bandwidth: invalid daily_cap %q", cfg.DailyCap)
+	}
+	if m.monthlyCap, err = parseByteSize(cfg.MonthlyCap); err != nil {
+		return nil, fmt.Errorf("bandwidth: invalid monthly_cap %q", cfg.MonthlyCap)
+	}
+	if cfg.DegradedInterval != "" {
+		parsed, err := time.ParseDuration(cfg.DegradedInterval)
+		if err != nil || parsed <= 0 {
+			return nil, fmt.Errorf("bandwidth: invalid degraded_interval %q", cfg.DegradedInterval)
+		}
+		m.interval = parsed
+	}
+
+	now := m.now().UTC()
+	m.day, m.month = dayStart(now), monthStart(now)
+	if cfg.StateFile != "" {
+		m.stateFile = cfg.StateFile
+		if err := m.load(); err != nil {
+			return nil, fmt.Errorf("bandwidth: invalid state_file %q: %w", cfg.StateFile, err)
+		}
+	}
+	return m, nil
+}
+
+// bandwidthState is the usage kept in the state file
+type bandwidthState struct {
+	Day        time.Time `json:"day"`   // start of the day window
+	Month      time.Time `json:"month"` // start of the month window
+	DayBytes   uint64    `json:"day_bytes"`
+	MonthBytes uint64    `json:"month_bytes"`
+}
+
+// load carries over the usage saved in the state file for the current
+// windows; a missing file is no usage
+func (m *bandwidthMeter) load() error {
+	data, err := os.ReadFile(m.stateFile)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var state bandwidthState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return err
+	}
+	if state.Day.Equal(m.day) {
+		m.dayPrior = state.DayBytes
+	}
+	if state.Month.Equal(m.month) {
+		m.monthPrior = state.MonthBytes
+	}
+	return nil
+}
+
+// save writes the usage of the current windows to the state file, through
+// a temporary file so that a crash never leaves a partial one
+func (m *bandwidthMeter) save() error {
+	if m.stateFile == "" {
+		return nil
+	}
+	total, now := m.total(), m.now().UTC()
+
+	m.mu.Lock()
+	today, month := m.roll(total, now)
+	state := bandwidthState{Day: m.day, Month: m.month, DayBytes: today, MonthBytes: month}
+	m.mu.Unlock()
+
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(m.stateFile), 0o700); err != nil {
+		return err
+	}
+	tmp := m.stateFile + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, m.stateFile)
+}
+
+// parseByteSize parses sizes such as "500MB" or "2GiB"; an empty string is
+// zero
+func parseByteSize(s string) (uint64, error) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	if s == "" {
+		return 0, nil
+	}
+	for _, unit := range byteUnits {
+		if number, ok := strings.CutSuffix(s, unit.suffix); ok {
+			value, err := strconv.ParseFloat(strings.TrimSpace(number), 64)
+			if err != nil || value < 0 {
+				return 0, fmt.Errorf("invalid size %q", s)
+			}
+			return uint64(value * float64(unit.size)), nil
+		}
+	}
+	value, err := strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	return value, nil
+}
+
+// dayStart returns midnight of t's day
+func dayStart(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// monthStart returns midnight of the first day of t's month
+func monthStart(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+// total returns all traffic of the session so far
+func (m *bandwidthMeter) total() uint64 {
+	sent, received := m.web()
+	return sent + received + m.gameSent.Load() + m.gameReceived.Load()
+}
+
+// usage returns the traffic of the current day and month, starting new
+// windows when the day or month has changed
+func (m *bandwidthMeter) usage() (today, month uint64) {
+	total := m.total()
+	now := m.now().UTC()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.roll(total, now)
+}
+
+// roll starts new windows when the day or month of now differs from theirs
+// and returns their usage given the total traffic; m.mu must be held
+func (m *bandwidthMeter) roll(total uint64, now time.Time) (today, month uint64) {
+	if day := dayStart(now); day.After(m.day) {
+		m.day, m.dayBase, m.dayPrior = day, total, 0
+	}
+	if start := monthStart(now); start.After(m.month) {
+		m.month, m.monthBase, m.monthPrior = start, total, 0
+	}
+	return total - m.dayBase + m.dayPrior, total - m.monthBase + m.monthPrior
+}
+
+// untilRollover returns the time left until the next day window starts
+func (m *bandwidthMeter) untilRollover() time.Duration {
+	now := m.now().UTC()
+	return dayStart(now).AddDate(0, 0, 1).Sub(now)
+}
+
+// check updates the degraded state from the current usage and reports
+// whether it changed
+func (m *bandwidthMeter) check() (degraded, changed bool) {
+	today, month := m.usage()
+	over := (m.dailyCap > 0 && today >= m.dailyCap) || (m.monthlyCap > 0 && month >= m.monthlyCap)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	changed = over != m.degraded
+	m.degraded = over
+	return over, changed
+}
+
+// isDegraded reports whether a cap has been reached
+func (m *bandwidthMeter) isDegraded() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.degraded
+}
+
+// watchBandwidth compares usage with the caps and saves it until ctx is
+// cancelled, telling clients when updates are reduced and restored. New
+// windows start at midnight even when nothing asks for the usage, so that
+// traffic after a quiet day counts towards the new one.
+func (w *WebUI) watchBandwidth(ctx context.Context) {
+	check := time.NewTicker(bandwidthCheckInterval)
+	defer check.Stop()
+	save := time.NewTicker(bandwidthSaveInterval)
+	defer save.Stop()
+	rollover := time.NewTimer(w.bandwidth.untilRollover())
+	defer rollover.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			w.saveBandwidth()
+			return
+		case <-check.C:
+			w.checkBandwidth()
+		case <-save.C:
+			w.saveBandwidth()
+		case <-rollover.C:
+			w.checkBandwidth()
+			w.saveBandwidth()
+			rollover.Reset(w.bandwidth.untilRollover())
+		}
+	}
+}
+
+// saveBandwidth writes the usage to the state file, if any
+func (w *WebUI) saveBandwidth() {
+	if err := w.bandwidth.save(); err != nil {
+		w.log.Warn("webui: failed to save bandwidth usage", "session", w.sessionName(), "error", err)
+	}
+}
+
+// checkBandwidth applies a change of the degraded state
+func (w *WebUI) checkBandwidth() {
+	degraded, changed := w.bandwidth.check()
+	if !changed {
+		return
+	}
+
+	today, month := w.bandwidth.usage()
+	if degraded {
+		w.log.Warn("webui: bandwidth cap reached", "session", w.sessionName(), "today", today, "month", month)
+		w.broadcastNotice("warning", i18n.MsgBandwidthDegraded)
+	} else {
+		w.log.Info("webui: bandwidth cap cleared", "session", w.sessionName(), "today", today, "month", month)
+		w.broadcastNotice("info", i18n.MsgBandwidthRestored)
+	}
+}
+
+// GetSessionStats returns the traffic, the game progress and the resource
+// usage of the session
+func (w *WebUI) GetSessionStats() SessionStats {
+	today, month := w.bandwidth.usage()
+	stats := SessionStats{
+		Game: TrafficStats{
+			Sent:     w.bandwidth.gameSent.Load(),
+			Received: w.bandwidth.gameReceived.Load(),
+		},
+		Clients:    []ClientTraffic{},
+		Today:      today,
+		Month:      month,
+		DailyCap:   w.bandwidth.dailyCap,
+		MonthlyCap: w.bandwidth.monthlyCap,
+		Degraded:   w.bandwidth.isDegraded(),
+		Quota:      w.GetQuotaUsage(),
+	}
+	if view := w.GetView(); view != nil {
+		if state := view.GetStateManager().GetCurrentState(); state != nil {
+			stats.Progress = state.Progress
+		}
+	}
+	stats.Web.Sent, stats.Web.Received = w.wsHandler.Traffic()
+	for _, client := range w.wsHandler.ClientStats() {
+		stats.Clients = append(stats.Clients, ClientTraffic{
+			ID:           client.ID,
+			TrafficStats: TrafficStats{Sent: client.BytesSent, Received: client.BytesReceived},
+		})
+	}
+	return stats
+}
+
+// handleSessionStats serves the traffic of the session
+func (w *WebUI) handleSessionStats(rw http.ResponseWriter, r *http.Request) {
+	writeJSON(rw, http.StatusOK, w.GetSessionStats())
+}
@@ -0,0 +1,207 @@
+package webui
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseByteSize(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    uint64
+		wantErr bool
+	}{
+		{"", 0, false},
+		{"1024", 1024, false},
+		{"500MB", 500e6, false},
+		{"2 GiB", 2 << 30, false},
+		{"1.5kb", 1500, false},
+		{"10B", 10, false},
+		{"lots", 0, true},
+		{"-1GB", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := parseByteSize(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseByteSize(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("parseByteSize(%q) = %d, want %d", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewBandwidthMeter_InvalidConfig(t *testing.T) {
+	web := func() (uint64, uint64) { return 0, 0 }
+	tests := []struct {
+		name string
+		cfg  BandwidthConfig
+	}{
+		{"bad daily cap", BandwidthConfig{DailyCap: "lots"}},
+		{"bad monthly cap", BandwidthConfig{MonthlyCap: "1XB"}},
+		{"bad interval", BandwidthConfig{DegradedInterval: "-1s"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := newBandwidthMeter(tt.cfg, web); err == nil {
+				t.Error("Expected configuration error")
+			}
+		})
+	}
+}
+
+func TestBandwidthMeter_CapsAndWindows(t *testing.T) {
+	var webSent uint64
+	m, err := newBandwidthMeter(BandwidthConfig{DailyCap: "1KB", MonthlyCap: "3KB"},
+		func() (uint64, uint64) { return webSent, 0 })
+	if err != nil {
+		t.Fatalf("newBandwidthMeter failed: %v", err)
+	}
+	now := time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC)
+	m.now = func() time.Time { return now }
+	m.day, m.month = dayStart(now), monthStart(now)
+
+	webSent = 600
+	m.gameReceived.Add(500)
+	if degraded, changed := m.check(); !degraded || !changed {
+		t.Fatalf("Expected daily cap to degrade the session, got %v %v", degraded, changed)
+	}
+
+	// A new day clears the daily cap
+	now = now.Add(24 * time.Hour)
+	if degraded, changed := m.check(); degraded || !changed {
+		t.Fatalf("Expected new day to restore the session, got %v %v", degraded, changed)
+	}
+	if today, month := m.usage(); today != 0 || month != 1100 {
+		t.Errorf("usage() = %d, %d; want 0, 1100", today, month)
+	}
+
+	// The monthly cap holds across days until the month ends
+	webSent = 3600
+	now = now.Add(24 * time.Hour)
+	if degraded, _ := m.check(); !degraded {
+		t.Error("Expected monthly cap to degrade the session")
+	}
+	now = time.Date(2024, 6, 1, 0, 0, 1, 0, time.UTC)
+	if degraded, _ := m.check(); degraded {
+		t.Error("Expected new month to restore the session")
+	}
+}
+
+func TestBandwidthMeter_PersistsUsage(t *testing.T) {
+	web := func() (uint64, uint64) { return 0, 0 }
+	cfg := BandwidthConfig{StateFile: filepath.Join(t.TempDir(), "state", "bandwidth.json")}
+	m, err := newBandwidthMeter(cfg, web)
+	if err != nil {
+		t.Fatalf("newBandwidthMeter failed: %v", err)
+	}
+	m.gameReceived.Add(500)
+	if err := m.save(); err != nil {
+		t.Fatalf("save failed: %v", err)
+	}
+
+	// A restart carries the usage of the current windows over
+	restarted, err := newBandwidthMeter(cfg, web)
+	if err != nil {
+		t.Fatalf("newBandwidthMeter failed: %v", err)
+	}
+	restarted.gameSent.Add(100)
+	if today, month := restarted.usage(); today != 600 || month != 600 {
+		t.Errorf("usage() after restart = %d, %d; want 600, 600", today, month)
+	}
+
+	// ... until the windows end
+	tomorrow := restarted.day.AddDate(0, 0, 1)
+	restarted.now = func() time.Time { return tomorrow }
+	wantMonth := uint64(600)
+	if tomorrow.Day() == 1 {
+		wantMonth = 0
+	}
+	if today, month := restarted.usage(); today != 0 || month != wantMonth {
+		t.Errorf("usage() the next day = %d, %d; want 0, %d", today, month, wantMonth)
+	}
+
+	// Usage saved in a past month is dropped
+	stale, _ := json.Marshal(bandwidthState{Day: time.Date(2020, 1, 5, 0, 0, 0, 0, time.UTC), Month: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC), DayBytes: 7, MonthBytes: 9})
+	if err := os.WriteFile(cfg.StateFile, stale, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if m, err = newBandwidthMeter(cfg, web); err != nil {
+		t.Fatalf("newBandwidthMeter failed: %v", err)
+	}
+	if today, month := m.usage(); today != 0 || month != 0 {
+		t.Errorf("usage() with stale state = %d, %d; want 0, 0", today, month)
+	}
+
+	if err := os.WriteFile(cfg.StateFile, []byte("not json"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := newBandwidthMeter(cfg, web); err == nil {
+		t.Error("Expected an error for a corrupt state file")
+	}
+}
+
+func TestBandwidthMeter_UntilRollover(t *testing.T) {
+	m, err := newBandwidthMeter(BandwidthConfig{}, func() (uint64, uint64) { return 0, 0 })
+	if err != nil {
+		t.Fatalf("newBandwidthMeter failed: %v", err)
+	}
+	m.now = func() time.Time { return time.Date(2024, 5, 31, 23, 59, 50, 0, time.UTC) }
+	if got := m.untilRollover(); got != 10*time.Second {
+		t.Errorf("untilRollover() = %v, want 10s", got)
+	}
+}
+
+func TestWebUI_SessionStats_CountsGameTraffic(t *testing.T) {
+	var sent []byte
+	ui, err := NewWebUI(WebUIOptions{
+		View:      newTestView(t),
+		Bandwidth: &BandwidthConfig{DailyCap: "10B"},
+		InputSink: func(data []byte) error {
+			sent = append(sent, data...)
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create WebUI: %v", err)
+	}
+
+	if err := ui.GetView().Render([]byte("hello world")); err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	if err := ui.sendGameInput([]byte("hjkl")); err != nil {
+		t.Fatalf("sendGameInput failed: %v", err)
+	}
+	ui.checkBandwidth()
+
+	rr := httptest.NewRecorder()
+	ui.ServeHTTP(rr, httptest.NewRequest("GET", "/session/stats", nil))
+	var stats SessionStats
+	if err := json.Unmarshal(rr.Body.Bytes(), &stats); err != nil {
+		t.Fatalf("Failed to decode stats: %v", err)
+	}
+	if stats.Game.Received != 11 || stats.Game.Sent != 4 {
+		t.Errorf("Game traffic = %+v, want 11 received and 4 sent", stats.Game)
+	}
+	if stats.Today != 15 || stats.DailyCap != 10 || !stats.Degraded {
+		t.Errorf("Expected the daily cap to be exceeded, got %+v", stats)
+	}
+
+	rr = httptest.NewRecorder()
+	ui.ServeHTTP(rr, httptest.NewRequest("GET", "/metrics", nil))
+	labels := `{session_id="` + ui.CorrelationID() + `"}`
+	for _, want := range []string{"dgconnect_game_received_bytes_total" + labels + " 11", "dgconnect_bandwidth_degraded" + labels + " 1", "dgconnect_ws_sent_bytes_total" + labels + " 0"} {
+		if !strings.Contains(rr.Body.String(), want) {
+			t.Errorf("Metrics missing %q", want)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+// Package webui provides build and version metadata reporting.
+package webui
+
+import (
+	"runtime"
+	"sync"
+)
+
+// BuildInfo describes the build of the server that is currently running
+type BuildInfo struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	Date      string `json:"date"`
+	GoVersion string `json:"go_version"`
+}
+
+var (
+	buildInfoMu sync.RWMutex
+	buildInfo   = BuildInfo{
+		Version: "dev",
+		Commit:  "none",
+		Date:    "unknown",
+	}
+)
+
+// SetBuildInfo registers the build version, commit and date reported by the
+// /version, /session/info and /metrics endpoints. Empty values keep the
+// current setting so callers can pass through unset linker variables.
+func SetBuildInfo(version, commit, date string) {
+	buildInfoMu.Lock()
+	defer buildInfoMu.Unlock()
+
+	if version != "" {
+		buildInfo.Version = version
+	}
+	if commit != "" {
+		buildInfo.Commit = commit
+	}
+	if date != "" {
+		buildInfo.Date = date
+	}
+}
+
+// GetBuildInfo returns the registered build information
+func GetBuildInfo() BuildInfo {
+	buildInfoMu.RLock()
+	defer buildInfoMu.RUnlock()
+
+	info := buildInfo
+	info.GoVersion = runtime.Version()
+	return info
+}
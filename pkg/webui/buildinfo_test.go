@@ -0,0 +1,101 @@
+package webui
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/opd-ai/go-gamelaunch-client/pkg/dgclient"
+)
+
+// newTestWebUI creates a WebUI backed by an 80x24 WebView
+func newTestWebUI(t *testing.T) *WebUI {
+	t.Helper()
+
+	view, err := NewWebView(dgclient.ViewOptions{InitialWidth: 80, InitialHeight: 24})
+	if err != nil {
+		t.Fatalf("Failed to create WebView: %v", err)
+	}
+	webUI, err := NewWebUI(WebUIOptions{View: view})
+	if err != nil {
+		t.Fatalf("Failed to create WebUI: %v", err)
+	}
+	return webUI
+}
+
+func TestSetBuildInfo_KeepsCurrentValuesForEmptyFields(t *testing.T) {
+	previous := GetBuildInfo()
+	defer SetBuildInfo(previous.Version, previous.Commit, previous.Date)
+
+	SetBuildInfo("1.2.3", "abc123", "2026-01-01")
+	SetBuildInfo("", "def456", "")
+
+	info := GetBuildInfo()
+	if info.Version != "1.2.3" {
+		t.Errorf("Version = %q, want %q", info.Version, "1.2.3")
+	}
+	if info.Commit != "def456" {
+		t.Errorf("Commit = %q, want %q", info.Commit, "def456")
+	}
+	if info.Date != "2026-01-01" {
+		t.Errorf("Date = %q, want %q", info.Date, "2026-01-01")
+	}
+	if info.GoVersion == "" {
+		t.Error("GoVersion should be populated")
+	}
+}
+
+func TestWebUI_VersionEndpoint_ReturnsBuildInfo(t *testing.T) {
+	previous := GetBuildInfo()
+	defer SetBuildInfo(previous.Version, previous.Commit, previous.Date)
+	SetBuildInfo("9.9.9", "cafe", "today")
+
+	webUI := newTestWebUI(t)
+	rec := httptest.NewRecorder()
+	webUI.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/version", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+
+	var info BuildInfo
+	if err := json.Unmarshal(rec.Body.Bytes(), &info); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if info.Version != "9.9.9" || info.Commit != "cafe" {
+		t.Errorf("unexpected build info: %+v", info)
+	}
+}
+
+func TestWebUI_SessionInfoEndpoint_IncludesSizeAndBuild(t *testing.T) {
+	webUI := newTestWebUI(t)
+	rec := httptest.NewRecorder()
+	webUI.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/session/info", nil))
+
+	var info SessionInfo
+	if err := json.Unmarshal(rec.Body.Bytes(), &info); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if info.Width != 80 || info.Height != 24 {
+		t.Errorf("size = %dx%d, want 80x24", info.Width, info.Height)
+	}
+	if info.Build.Version == "" {
+		t.Error("build version should be populated")
+	}
+}
+
+func TestWebUI_MetricsEndpoint_ExposesBuildInfoLabels(t *testing.T) {
+	webUI := newTestWebUI(t)
+	rec := httptest.NewRecorder()
+	webUI.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	body := rec.Body.String()
+	if !strings.Contains(body, "dgconnect_build_info{") {
+		t.Errorf("metrics missing build info:\n%s", body)
+	}
+	if !strings.Contains(body, `version="`+GetBuildInfo().Version+`"`) {
+		t.Errorf("metrics missing version label:\n%s", body)
+	}
+}
@@ -0,0 +1,229 @@
+// Package webui provides configurable HTTP caching: per-route Cache-Control
+// policies and content-hashed URLs for assets that may be cached forever.
+package webui
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"image/png"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// immutableMaxAge is the Cache-Control of responses to content-hashed URLs
+const immutableMaxAge = "max-age=31536000, immutable"
+
+// CacheConfig overrides the Cache-Control header of routes. Keys are paths
+// such as "/theme.css", or prefixes ending in "/" such as "/" for the static
+// files; the longest match wins. Values are sent as they are, e.g.
+// "public, max-age=600".
+type CacheConfig struct {
+	Routes map[string]string `yaml:"routes"`
+}
+
+// cachePolicy holds the validated Cache-Control overrides
+type cachePolicy struct {
+	routes map[string]string
+}
+
+// newCachePolicy validates cfg; a nil cfg overrides nothing
+func newCachePolicy(cfg *CacheConfig) (*cachePolicy, error) {
+	p := &cachePolicy{routes: make(map[string]string)}
+	if cfg == nil {
+		return p, nil
+	}
+	for route, value := range cfg.Routes {
+		if !strings.HasPrefix(route, "/") {
+			return nil, fmt.Errorf("cache: route %q must start with /", route)
+		}
+		if strings.TrimSpace(value) == "" || strings.ContainsAny(value, "\r\n") {
+			return nil, fmt.Errorf("cache: invalid Cache-Control %q for %s", value, route)
+		}
+		p.routes[route] = value
+	}
+	return p, nil
+}
+
+// lookup returns the Cache-Control configured for urlPath
+func (p *cachePolicy) lookup(urlPath string) (string, bool) {
+	if p == nil {
+		return "", false
+	}
+	if value, ok := p.routes[urlPath]; ok {
+		return value, true
+	}
+	best := ""
+	for route := range p.routes {
+		if strings.HasSuffix(route, "/") && strings.HasPrefix(urlPath, route) && len(route) > len(best) {
+			best = route
+		}
+	}
+	if best == "" {
+		return "", false
+	}
+	return p.routes[best], true
+}
+
+// setCacheControl sets the Cache-Control configured for the route of r, or
+// else fallback, replacing the no-store default of addCORSHeaders
+func (w *WebUI) setCacheControl(rw http.ResponseWriter, r *http.Request, fallback string) {
+	if value, ok := w.cache.lookup(r.URL.Path); ok {
+		fallback = value
+	}
+	rw.Header().Set("Cache-Control", fallback)
+	rw.Header().Del("Pragma")
+	rw.Header().Del("Expires")
+}
+
+// cacheVisibility is public, or private when responses depend on the login
+func (w *WebUI) cacheVisibility() string {
+	if w.authRequired() {
+		return "private"
+	}
+	return "public"
+}
+
+// contentHash names content in URLs and ETags
+func contentHash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:8])
+}
+
+// encodedImage is a tileset image encoded as PNG
+type encodedImage struct {
+	tileset  *TilesetConfig
+	revision uint64
+	data     []byte
+	hash     string
+	modTime  time.Time
+}
+
+// tilesetImageCache keeps the PNG encoding of the current tileset image,
+// so it is encoded and hashed once per change rather than per request
+type tilesetImageCache struct {
+	mu      sync.Mutex
+	current *encodedImage
+}
+
+// get returns the encoding of tileset's image, or nil when it has none
+func (c *tilesetImageCache) get(tileset *TilesetConfig) (*encodedImage, error) {
+	if tileset == nil || tileset.GetImageData() == nil {
+		return nil, nil
+	}
+	revision := tileset.Revision()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e := c.current; e != nil && e.tileset == tileset && e.revision == revision {
+		return e, nil
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, tileset.GetImageData()); err != nil {
+		return nil, err
+	}
+	c.current = &encodedImage{
+		tileset:  tileset,
+		revision: revision,
+		data:     buf.Bytes(),
+		hash:     contentHash(buf.Bytes()),
+		modTime:  time.Now().UTC().Truncate(time.Second),
+	}
+	return c.current, nil
+}
+
+// TilesetImageURL returns the content-hashed URL of the tileset image, which
+// may be cached forever, or "" when no image is loaded
+func (w *WebUI) TilesetImageURL() string {
+	image, err := w.tilesetImages.get(w.GetTileset())
+	if err != nil || image == nil {
+		return ""
+	}
+	return "/tileset/image?v=" + image.hash
+}
+
+// staticAsset is the content hash of a static file
+type staticAsset struct {
+	modTime time.Time
+	size    int64
+	hash    string
+}
+
+// staticAssets serves the files of a directory. Requests naming the file's
+// content hash in v may be cached forever; others revalidate with the hash
+// as ETag.
+type staticAssets struct {
+	webui  *WebUI
+	dir    string
+	files  http.Handler
+	mu     sync.Mutex
+	hashes map[string]staticAsset
+}
+
+// newStaticAssets serves the files in dir
+func newStaticAssets(w *WebUI, dir string) *staticAssets {
+	return &staticAssets{webui: w, dir: dir, files: http.FileServer(http.Dir(dir)), hashes: make(map[string]staticAsset)}
+}
+
+// hash returns the content hash of the file at urlPath, or "" when there
+// is no such file
+func (s *staticAssets) hash(urlPath string) string {
+	name := path.Clean("/" + urlPath)
+	if strings.HasSuffix(name, "/") || name == "/" {
+		name = path.Join(name, "index.html")
+	}
+	file, err := os.Open(filepath.Join(s.dir, filepath.FromSlash(name)))
+	if err != nil {
+		return ""
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil || info.IsDir() {
+		return ""
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if cached, ok := s.hashes[name]; ok && cached.modTime.Equal(info.ModTime()) && cached.size == info.Size() {
+		return cached.hash
+	}
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return ""
+	}
+	asset := staticAsset{modTime: info.ModTime(), size: info.Size(), hash: contentHash(data)}
+	s.hashes[name] = asset
+	return asset.hash
+}
+
+// ServeHTTP implements http.Handler
+func (s *staticAssets) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	if hash := s.hash(r.URL.Path); hash != "" {
+		if r.URL.Query().Get("v") == hash {
+			s.webui.setCacheControl(rw, r, s.webui.cacheVisibility()+", "+immutableMaxAge)
+		} else {
+			s.webui.setCacheControl(rw, r, s.webui.cacheVisibility()+", no-cache")
+		}
+		rw.Header().Set("ETag", `"`+hash+`"`)
+	}
+	s.files.ServeHTTP(rw, r)
+}
+
+// AssetURL returns the content-hashed URL of a file in StaticPath, which may
+// be cached forever, or urlPath unchanged when there is no such file
+func (w *WebUI) AssetURL(urlPath string) string {
+	if w.static == nil {
+		return urlPath
+	}
+	if hash := w.static.hash(urlPath); hash != "" {
+		return urlPath + "?v=" + hash
+	}
+	return urlPath
+}
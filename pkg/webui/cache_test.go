@@ -0,0 +1,183 @@
+package webui
+
+import (
+	"image"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCachePolicy_Lookup(t *testing.T) {
+	policy, err := newCachePolicy(&CacheConfig{Routes: map[string]string{
+		"/":          "public, max-age=60",
+		"/tileset/":  "public, max-age=600",
+		"/theme.css": "private, max-age=10",
+	}})
+	if err != nil {
+		t.Fatalf("newCachePolicy failed: %v", err)
+	}
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/theme.css", "private, max-age=10"},
+		{"/tileset/image", "public, max-age=600"},
+		{"/app.js", "public, max-age=60"},
+	}
+	for _, tt := range tests {
+		if got, ok := policy.lookup(tt.path); !ok || got != tt.want {
+			t.Errorf("lookup(%s) = %q, %v, want %q", tt.path, got, ok, tt.want)
+		}
+	}
+
+	for _, routes := range []map[string]string{
+		{"theme.css": "no-cache"},
+		{"/theme.css": " "},
+		{"/theme.css": "no-cache\r\nX-Injected: 1"},
+	} {
+		if _, err := newCachePolicy(&CacheConfig{Routes: routes}); err == nil {
+			t.Errorf("newCachePolicy(%q) accepted an invalid route", routes)
+		}
+	}
+}
+
+func TestWebUI_HandleTilesetImage_ConditionalRequests(t *testing.T) {
+	ui := newTestWebUI(t)
+	tileset := DefaultTilesetConfig()
+	tileset.SetImageData(image.NewRGBA(image.Rect(0, 0, 32, 32)))
+	if err := ui.UpdateTileset(tileset); err != nil {
+		t.Fatalf("UpdateTileset failed: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	ui.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/tileset/image", nil))
+	etag := rec.Header().Get("ETag")
+	if rec.Code != http.StatusOK || etag == "" || rec.Header().Get("Cache-Control") != "public, max-age=3600" || rec.Header().Get("Pragma") != "" {
+		t.Fatalf("GET /tileset/image = %d with %v", rec.Code, rec.Header())
+	}
+	url := ui.TilesetImageURL()
+	if url != "/tileset/image?v="+strings.Trim(etag, `"`) || ui.GetSessionInfo().TilesetImage != url {
+		t.Errorf("TilesetImageURL() = %q, want the hash of ETag %s", url, etag)
+	}
+
+	tests := []struct {
+		name       string
+		method     string
+		url        string
+		header     map[string]string
+		wantStatus int
+		wantCache  string
+	}{
+		{"Versioned", http.MethodGet, url, nil, http.StatusOK, "public, max-age=31536000, immutable"},
+		{"StaleVersion", http.MethodGet, "/tileset/image?v=0123", nil, http.StatusOK, "public, max-age=3600"},
+		{"IfNoneMatchList", http.MethodGet, "/tileset/image", map[string]string{"If-None-Match": `"other", W/` + etag}, http.StatusNotModified, ""},
+		{"IfNoneMatchStale", http.MethodGet, "/tileset/image", map[string]string{"If-None-Match": `"other"`}, http.StatusOK, ""},
+		{"IfModifiedSince", http.MethodGet, "/tileset/image", map[string]string{"If-Modified-Since": time.Now().Add(time.Hour).UTC().Format(http.TimeFormat)}, http.StatusNotModified, ""},
+		{"IfMatchStale", http.MethodGet, "/tileset/image", map[string]string{"If-Match": `"other"`}, http.StatusPreconditionFailed, ""},
+		{"Range", http.MethodGet, "/tileset/image", map[string]string{"Range": "bytes=0-7"}, http.StatusPartialContent, ""},
+		{"Head", http.MethodHead, "/tileset/image", nil, http.StatusOK, ""},
+		{"Post", http.MethodPost, "/tileset/image", nil, http.StatusMethodNotAllowed, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.url, nil)
+			for name, value := range tt.header {
+				req.Header.Set(name, value)
+			}
+			rec := httptest.NewRecorder()
+			ui.ServeHTTP(rec, req)
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if tt.wantCache != "" && rec.Header().Get("Cache-Control") != tt.wantCache {
+				t.Errorf("Cache-Control = %q, want %q", rec.Header().Get("Cache-Control"), tt.wantCache)
+			}
+		})
+	}
+}
+
+func TestWebUI_Cache_StaticAssetsAndOverrides(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{"app.js": "console.log(1)", "style.css": "body {}"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ui, err := NewWebUI(WebUIOptions{
+		View:       newTestView(t),
+		StaticPath: dir,
+		Cache: &CacheConfig{Routes: map[string]string{
+			"/style.css":    "public, max-age=60",
+			"/session/info": "private, max-age=5",
+		}},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create WebUI: %v", err)
+	}
+
+	appURL := ui.AssetURL("/app.js")
+	if !strings.HasPrefix(appURL, "/app.js?v=") || ui.AssetURL("/missing.js") != "/missing.js" {
+		t.Fatalf("AssetURL() = %q", appURL)
+	}
+
+	tests := []struct {
+		url        string
+		wantStatus int
+		wantCache  string
+	}{
+		{appURL, http.StatusOK, "public, max-age=31536000, immutable"},
+		{"/app.js", http.StatusOK, "public, no-cache"},
+		{"/style.css", http.StatusOK, "public, max-age=60"},
+		{"/session/info", http.StatusOK, "private, max-age=5"},
+		{"/session/stats", http.StatusOK, "no-cache, no-store, must-revalidate"},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		ui.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.url, nil))
+		if rec.Code != tt.wantStatus || rec.Header().Get("Cache-Control") != tt.wantCache {
+			t.Errorf("GET %s = %d with Cache-Control %q, want %d with %q", tt.url, rec.Code, rec.Header().Get("Cache-Control"), tt.wantStatus, tt.wantCache)
+		}
+	}
+
+	// Static files revalidate with their content hash
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/app.js", nil)
+	req.Header.Set("If-None-Match", `"`+strings.TrimPrefix(appURL, "/app.js?v=")+`"`)
+	ui.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNotModified {
+		t.Errorf("conditional GET /app.js = %d, want 304", rec.Code)
+	}
+}
+
+func TestWebUI_HandleTilesetImage_HeadAndRange(t *testing.T) {
+	ui := newTestWebUI(t)
+	tileset := DefaultTilesetConfig()
+	tileset.SetImageData(image.NewRGBA(image.Rect(0, 0, 32, 32)))
+	if err := ui.UpdateTileset(tileset); err != nil {
+		t.Fatalf("UpdateTileset failed: %v", err)
+	}
+	full := httptest.NewRecorder()
+	ui.ServeHTTP(full, httptest.NewRequest(http.MethodGet, "/tileset/image", nil))
+
+	head := httptest.NewRecorder()
+	ui.ServeHTTP(head, httptest.NewRequest(http.MethodHead, "/tileset/image", nil))
+	if head.Body.Len() != 0 || head.Header().Get("Content-Length") != strconv.Itoa(full.Body.Len()) || head.Header().Get("Accept-Ranges") != "bytes" {
+		t.Errorf("HEAD /tileset/image = %d bytes with %v, want headers only for %d bytes", head.Body.Len(), head.Header(), full.Body.Len())
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/tileset/image", nil)
+	req.Header.Set("Range", "bytes=8-15")
+	req.Header.Set("If-Range", full.Header().Get("ETag"))
+	part := httptest.NewRecorder()
+	ui.ServeHTTP(part, req)
+	want := "bytes 8-15/" + strconv.Itoa(full.Body.Len())
+	if part.Code != http.StatusPartialContent || part.Header().Get("Content-Range") != want || part.Body.String() != full.Body.String()[8:16] {
+		t.Errorf("ranged GET = %d with Content-Range %q, want 206 with %q", part.Code, part.Header().Get("Content-Range"), want)
+	}
+}
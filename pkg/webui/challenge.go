@@ -0,0 +1,167 @@
+// Package webui provides relaying of keyboard-interactive SSH prompts to web
+// clients.
+package webui
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/opd-ai/go-gamelaunch-www/pkg/transport"
+)
+
+// defaultChallengeTimeout is how long a relayed prompt waits for an answer
+const defaultChallengeTimeout = 5 * time.Minute
+
+// errNoChallenge is returned when an answer does not match the pending
+// challenge
+var errNoChallenge = errors.New("no such challenge pending")
+
+// ChallengeRelay relays the keyboard-interactive prompts of the game
+// server's SSH login, such as one-time codes, to web clients and waits for
+// one of them to answer. Challenge is an ssh.KeyboardInteractiveChallenge.
+type ChallengeRelay struct {
+	timeout time.Duration
+
+	mu      sync.Mutex
+	nextID  uint64
+	pending *transport.AuthChallengePayload
+	answers chan []string
+	notify  func(*transport.AuthChallengePayload)
+}
+
+// answerRequest is the request body of POST /ssh/challenge
+type answerRequest struct {
+	ID      uint64   `json:"id"`
+	Answers []string `json:"answers"`
+}
+
+// NewChallengeRelay creates a relay whose prompts expire after timeout, or
+// five minutes when timeout is zero
+func NewChallengeRelay(timeout time.Duration) *ChallengeRelay {
+	if timeout <= 0 {
+		timeout = defaultChallengeTimeout
+	}
+	return &ChallengeRelay{timeout: timeout}
+}
+
+// setNotifier installs the function that pushes challenges to clients
+func (r *ChallengeRelay) setNotifier(notify func(*transport.AuthChallengePayload)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.notify = notify
+}
+
+// Challenge publishes the prompts and blocks until they are answered or
+// expire. A round without questions, which only carries instructions, is
+// answered right away.
+func (r *ChallengeRelay) Challenge(name, instruction string, questions []string, echos []bool) ([]string, error) {
+	if len(questions) == 0 {
+		return []string{}, nil
+	}
+
+	prompts := make([]transport.AuthPrompt, len(questions))
+	for i, question := range questions {
+		prompts[i] = transport.AuthPrompt{Text: question, Echo: i < len(echos) && echos[i]}
+	}
+
+	r.mu.Lock()
+	r.nextID++
+	challenge := &transport.AuthChallengePayload{ID: r.nextID, Name: name, Instruction: instruction, Prompts: prompts}
+	answers := make(chan []string, 1)
+	r.pending, r.answers = challenge, answers
+	notify := r.notify
+	r.mu.Unlock()
+
+	slog.Info("webui: relaying SSH challenge", "id", challenge.ID, "prompts", len(prompts))
+	if notify != nil {
+		notify(challenge)
+	}
+	defer r.finish(challenge.ID)
+
+	timer := time.NewTimer(r.timeout)
+	defer timer.Stop()
+	select {
+	case answered := <-answers:
+		return answered, nil
+	case <-timer.C:
+		return nil, fmt.Errorf("ssh challenge: no answer within %s", r.timeout)
+	}
+}
+
+// finish withdraws challenge id, if still pending, and tells clients it is
+// over
+func (r *ChallengeRelay) finish(id uint64) {
+	r.mu.Lock()
+	if r.pending != nil && r.pending.ID == id {
+		r.pending, r.answers = nil, nil
+	}
+	notify := r.notify
+	r.mu.Unlock()
+
+	if notify != nil {
+		notify(&transport.AuthChallengePayload{ID: id, Prompts: []transport.AuthPrompt{}})
+	}
+}
+
+// Pending returns the challenge waiting for an answer, or nil
+func (r *ChallengeRelay) Pending() *transport.AuthChallengePayload {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.pending
+}
+
+// Answer completes challenge id with one answer per prompt
+func (r *ChallengeRelay) Answer(id uint64, answers []string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.pending == nil || r.pending.ID != id {
+		return errNoChallenge
+	}
+	if len(answers) != len(r.pending.Prompts) {
+		return fmt.Errorf("expected %d answers, got %d", len(r.pending.Prompts), len(answers))
+	}
+	r.answers <- answers
+	r.pending, r.answers = nil, nil
+	return nil
+}
+
+// handleSSHChallenge serves GET /ssh/challenge, the pending login prompt
+// (204 when there is none), and POST /ssh/challenge, answering it
+// ({"id": 1, "answers": ["123456"]})
+func (w *WebUI) handleSSHChallenge(rw http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		challenge := w.challenges.Pending()
+		if challenge == nil {
+			rw.WriteHeader(http.StatusNoContent)
+			return
+		}
+		writeJSON(rw, http.StatusOK, challenge)
+
+	case http.MethodPost:
+		var req answerRequest
+		if err := json.NewDecoder(http.MaxBytesReader(rw, r.Body, 4096)).Decode(&req); err != nil {
+			http.Error(rw, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if err := w.challenges.Answer(req.ID, req.Answers); err != nil {
+			status := http.StatusBadRequest
+			if errors.Is(err, errNoChallenge) {
+				status = http.StatusConflict
+			}
+			http.Error(rw, err.Error(), status)
+			return
+		}
+		rw.WriteHeader(http.StatusNoContent)
+
+	default:
+		rw.Header().Set("Allow", "GET, POST")
+		http.Error(rw, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
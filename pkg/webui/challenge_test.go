@@ -0,0 +1,122 @@
+package webui
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/opd-ai/go-gamelaunch-www/pkg/transport"
+)
+
+func TestChallengeRelay_RelaysPromptsAndAnswers(t *testing.T) {
+	relay := NewChallengeRelay(time.Second)
+	notified := make(chan *transport.AuthChallengePayload, 2)
+	relay.setNotifier(func(c *transport.AuthChallengePayload) { notified <- c })
+
+	type result struct {
+		answers []string
+		err     error
+	}
+	done := make(chan result, 1)
+	go func() {
+		answers, err := relay.Challenge("dgl", "Two-factor login", []string{"Password: ", "Code: "}, []bool{false, true})
+		done <- result{answers, err}
+	}()
+
+	challenge := <-notified
+	want := []transport.AuthPrompt{{Text: "Password: "}, {Text: "Code: ", Echo: true}}
+	if !reflect.DeepEqual(challenge.Prompts, want) || challenge.Instruction != "Two-factor login" {
+		t.Fatalf("challenge = %+v, want the prompts", challenge)
+	}
+	if pending := relay.Pending(); pending == nil || pending.ID != challenge.ID {
+		t.Fatalf("Pending() = %+v, want challenge %d", pending, challenge.ID)
+	}
+
+	if err := relay.Answer(challenge.ID+1, []string{"a", "b"}); err == nil {
+		t.Error("Answer with a stale ID succeeded")
+	}
+	if err := relay.Answer(challenge.ID, []string{"a"}); err == nil {
+		t.Error("Answer with too few answers succeeded")
+	}
+	if err := relay.Answer(challenge.ID, []string{"hunter2", "123456"}); err != nil {
+		t.Fatalf("Answer failed: %v", err)
+	}
+
+	got := <-done
+	if got.err != nil || !reflect.DeepEqual(got.answers, []string{"hunter2", "123456"}) {
+		t.Errorf("Challenge = %v, %v; want the answers", got.answers, got.err)
+	}
+	if over := <-notified; over.ID != challenge.ID || len(over.Prompts) != 0 {
+		t.Errorf("final notification = %+v, want no prompts", over)
+	}
+	if relay.Pending() != nil {
+		t.Error("challenge still pending after the answer")
+	}
+}
+
+func TestChallengeRelay_Challenge_Expires(t *testing.T) {
+	relay := NewChallengeRelay(10 * time.Millisecond)
+	if _, err := relay.Challenge("", "", []string{"Code: "}, []bool{false}); err == nil {
+		t.Fatal("Challenge without an answer succeeded")
+	}
+	if relay.Pending() != nil {
+		t.Error("expired challenge still pending")
+	}
+
+	answers, err := relay.Challenge("", "Welcome", nil, nil)
+	if err != nil || len(answers) != 0 {
+		t.Errorf("Challenge without questions = %v, %v; want no answers", answers, err)
+	}
+}
+
+func TestWebUI_HandleSSHChallenge(t *testing.T) {
+	relay := NewChallengeRelay(time.Second)
+	w, err := NewWebUI(WebUIOptions{View: newTestView(t), SSHChallenges: relay})
+	if err != nil {
+		t.Fatalf("NewWebUI failed: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	w.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/ssh/challenge", nil))
+	if rec.Code != http.StatusNoContent {
+		t.Errorf("GET without a challenge = %d, want 204", rec.Code)
+	}
+
+	done := make(chan []string, 1)
+	go func() {
+		answers, _ := relay.Challenge("", "", []string{"Code: "}, []bool{false})
+		done <- answers
+	}()
+	for relay.Pending() == nil {
+		time.Sleep(time.Millisecond)
+	}
+
+	rec = httptest.NewRecorder()
+	w.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/ssh/challenge", nil))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"Code: "`) {
+		t.Fatalf("GET = %d %s, want the challenge", rec.Code, rec.Body)
+	}
+
+	tests := []struct {
+		name string
+		body string
+		want int
+	}{
+		{"stale id", `{"id": 7, "answers": ["1"]}`, http.StatusConflict},
+		{"wrong count", `{"id": 1, "answers": []}`, http.StatusBadRequest},
+		{"answered", `{"id": 1, "answers": ["424242"]}`, http.StatusNoContent},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		w.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/ssh/challenge", strings.NewReader(tt.body)))
+		if rec.Code != tt.want {
+			t.Errorf("%s: POST = %d, want %d", tt.name, rec.Code, tt.want)
+		}
+	}
+	if answers := <-done; len(answers) != 1 || answers[0] != "424242" {
+		t.Errorf("answers = %v, want [424242]", answers)
+	}
+}
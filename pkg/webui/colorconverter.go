@@ -6,13 +6,18 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/fatih/color"
 )
 
 // ColorConverter handles ANSI color parsing and conversion using fatih/color library
 // Moved from: color.go
-type ColorConverter struct{}
+type ColorConverter struct {
+	// Cache of colors transformed for client color modes
+	mu    sync.Mutex
+	cache map[colorKey]colorPair
+}
 
 // NewColorConverter creates a new color converter with ANSI256 profile
 // NewColorConverter creates a new color converter
@@ -75,6 +80,11 @@ func (cc *ColorConverter) ProcessSGRParams(params []string) (fgColor, bgColor st
 				}
 				i += consumed
 			}
+		case 58: // Underline color, see applyTextAttributes; skip its components
+			if i+1 < len(params) {
+				_, consumed := cc.parseExtendedColor(params[i+1:])
+				i += consumed
+			}
 		case 39: // Default foreground color
 			fgColor = "#FFFFFF"
 		case 49: // Default background color
@@ -135,6 +135,15 @@ func TestProcessSGRParams_StandardCodes_ProcessesCorrectly(t *testing.T) {
 			expectedInverse: false,
 			expectedBlink:   true,
 		},
+		{
+			name:            "Underline color components are skipped",
+			params:          []string{"58", "2", "1", "5", "7", "32"},
+			expectedFg:      "#008000", // Green
+			expectedBg:      "#000000",
+			expectedBold:    false,
+			expectedInverse: false,
+			expectedBlink:   false,
+		},
 	}
 
 	for _, tt := range tests {
@@ -0,0 +1,206 @@
+// Package webui provides color transformations for color vision deficiencies and high contrast.
+package webui
+
+import (
+	"math"
+	"strconv"
+
+	"github.com/opd-ai/go-gamelaunch-www/pkg/transport"
+)
+
+// maxColorCacheEntries bounds the per-converter cache of transformed colors
+const maxColorCacheEntries = 4096
+
+// minHighContrastRatio is the WCAG AAA contrast ratio enforced between text
+// and background in high contrast mode
+const minHighContrastRatio = 7.0
+
+// colorKey identifies a transformed foreground/background pair
+type colorKey struct {
+	fg, bg, mode string
+}
+
+// colorPair is a transformed foreground/background pair
+type colorPair struct {
+	fg, bg string
+}
+
+// cvdSafePalette maps the 16 standard ANSI colors to the Okabe-Ito palette,
+// which stays distinguishable under red-green color blindness
+var cvdSafePalette = map[string]string{
+	"#800000": "#8F3F00", // Dark Red -> dark vermillion
+	"#008000": "#00735A", // Dark Green -> dark bluish green
+	"#808000": "#A89F2E", // Dark Yellow
+	"#000080": "#004C78", // Dark Blue
+	"#800080": "#8A5270", // Dark Magenta -> dark reddish purple
+	"#008080": "#3A7A9E", // Dark Cyan -> dark sky blue
+	"#FF0000": "#D55E00", // Bright Red -> vermillion
+	"#00FF00": "#009E73", // Bright Green -> bluish green
+	"#FFFF00": "#F0E442", // Bright Yellow
+	"#0000FF": "#0072B2", // Bright Blue
+	"#FF00FF": "#CC79A7", // Bright Magenta -> reddish purple
+	"#00FFFF": "#56B4E9", // Bright Cyan -> sky blue
+}
+
+// highContrastPalette brightens the dim ANSI colors
+var highContrastPalette = map[string]string{
+	"#800000": "#FF0000",
+	"#008000": "#00FF00",
+	"#808000": "#FFFF00",
+	"#000080": "#0000FF",
+	"#800080": "#FF00FF",
+	"#008080": "#00FFFF",
+	"#C0C0C0": "#FFFFFF",
+	"#808080": "#FFFFFF",
+}
+
+// TransformColors rewrites a cell's foreground and background colors for a
+// client color mode. Colors that are not in #RRGGBB form are returned
+// unchanged.
+func (cc *ColorConverter) TransformColors(fg, bg, mode string) (string, string) {
+	if mode == transport.ColorModeNormal {
+		return fg, bg
+	}
+
+	key := colorKey{fg: fg, bg: bg, mode: mode}
+	cc.mu.Lock()
+	if pair, ok := cc.cache[key]; ok {
+		cc.mu.Unlock()
+		return pair.fg, pair.bg
+	}
+	cc.mu.Unlock()
+
+	var pair colorPair
+	switch mode {
+	case transport.ColorModeDeuteranopia, transport.ColorModeProtanopia:
+		pair = colorPair{fg: cvdSafeColor(fg, mode), bg: cvdSafeColor(bg, mode)}
+	case transport.ColorModeHighContrast:
+		pair.fg, pair.bg = highContrastColors(fg, bg)
+	default:
+		return fg, bg
+	}
+
+	cc.mu.Lock()
+	if cc.cache == nil || len(cc.cache) >= maxColorCacheEntries {
+		cc.cache = make(map[colorKey]colorPair)
+	}
+	cc.cache[key] = pair
+	cc.mu.Unlock()
+
+	return pair.fg, pair.bg
+}
+
+// cvdSafeColor maps palette colors to the Okabe-Ito palette and daltonizes
+// any other color for the given deficiency
+func cvdSafeColor(hex, mode string) string {
+	if mapped, ok := cvdSafePalette[hex]; ok {
+		return mapped
+	}
+
+	r, g, b, ok := parseHexColor(hex)
+	if !ok {
+		return hex
+	}
+	r, g, b = daltonize(r, g, b, mode)
+	return formatHexColor(r, g, b)
+}
+
+// daltonize shifts the color information a viewer with the given deficiency
+// cannot perceive into channels they can (Fidaner, Lin and Ozguven)
+func daltonize(r, g, b float64, mode string) (float64, float64, float64) {
+	// RGB to LMS cone space
+	l := 17.8824*r + 43.5161*g + 4.11935*b
+	m := 3.45565*r + 27.1554*g + 3.86714*b
+	s := 0.0299566*r + 0.184309*g + 1.46709*b
+
+	// Simulate the deficiency
+	switch mode {
+	case transport.ColorModeProtanopia:
+		l = 2.02344*m - 2.52581*s
+	case transport.ColorModeDeuteranopia:
+		m = 0.494207*l + 1.24827*s
+	}
+
+	// Simulated color back to RGB
+	sr := 0.0809444479*l - 0.130504409*m + 0.116721066*s
+	sg := -0.0102485335*l + 0.0540193266*m - 0.113614708*s
+	sb := -0.000365296938*l - 0.00412161469*m + 0.693511405*s
+
+	// Redistribute the lost information into green and blue
+	er, eg, eb := r-sr, g-sg, b-sb
+	return r, g + 0.7*er + eg, b + 0.7*er + eb
+}
+
+// highContrastColors forces a black or white background and adjusts the
+// foreground until it reaches minHighContrastRatio against it
+func highContrastColors(fg, bg string) (string, string) {
+	if mapped, ok := highContrastPalette[fg]; ok {
+		fg = mapped
+	}
+
+	br, bgG, bb, ok := parseHexColor(bg)
+	if !ok {
+		return fg, bg
+	}
+	newBg := "#000000"
+	target := 0.0
+	if relativeLuminance(br, bgG, bb) > 0.5 {
+		newBg, target = "#FFFFFF", 255
+	}
+
+	r, g, b, ok := parseHexColor(fg)
+	if !ok {
+		return fg, newBg
+	}
+
+	// Mix the foreground away from the background until it is legible
+	toward := 255 - target
+	for i := 0; i < 10 && contrastRatio(r, g, b, target, target, target) < minHighContrastRatio; i++ {
+		r += (toward - r) * 0.3
+		g += (toward - g) * 0.3
+		b += (toward - b) * 0.3
+	}
+	if contrastRatio(r, g, b, target, target, target) < minHighContrastRatio {
+		r, g, b = toward, toward, toward
+	}
+	return formatHexColor(r, g, b), newBg
+}
+
+// relativeLuminance returns the WCAG relative luminance of an sRGB color
+func relativeLuminance(r, g, b float64) float64 {
+	channel := func(c float64) float64 {
+		c /= 255
+		if c <= 0.03928 {
+			return c / 12.92
+		}
+		return math.Pow((c+0.055)/1.055, 2.4)
+	}
+	return 0.2126*channel(r) + 0.7152*channel(g) + 0.0722*channel(b)
+}
+
+// contrastRatio returns the WCAG contrast ratio of two sRGB colors
+func contrastRatio(r1, g1, b1, r2, g2, b2 float64) float64 {
+	l1, l2 := relativeLuminance(r1, g1, b1), relativeLuminance(r2, g2, b2)
+	if l1 < l2 {
+		l1, l2 = l2, l1
+	}
+	return (l1 + 0.05) / (l2 + 0.05)
+}
+
+// parseHexColor parses a #RRGGBB color
+func parseHexColor(hex string) (r, g, b float64, ok bool) {
+	if len(hex) != 7 || hex[0] != '#' {
+		return 0, 0, 0, false
+	}
+	v, err := strconv.ParseUint(hex[1:], 16, 32)
+	if err != nil {
+		return 0, 0, 0, false
+	}
+	return float64(v >> 16 & 0xFF), float64(v >> 8 & 0xFF), float64(v & 0xFF), true
+}
+
+// formatHexColor formats channel values as #RRGGBB, rounding and clamping
+// them to 0-255
+func formatHexColor(r, g, b float64) string {
+	return new(ColorConverter).rgbToHex(int(math.Round(r)), int(math.Round(g)), int(math.Round(b)))
+}
@@ -0,0 +1,121 @@
+package webui
+
+import (
+	"testing"
+
+	"github.com/opd-ai/go-gamelaunch-www/pkg/transport"
+)
+
+func TestColorConverter_TransformColors_NormalModeIsIdentity(t *testing.T) {
+	cc := NewColorConverter()
+	fg, bg := cc.TransformColors("#FF0000", "#000000", transport.ColorModeNormal)
+	if fg != "#FF0000" || bg != "#000000" {
+		t.Errorf("TransformColors = (%s, %s), want unchanged", fg, bg)
+	}
+}
+
+func TestColorConverter_TransformColors_CVDModesSeparateRedAndGreen(t *testing.T) {
+	cc := NewColorConverter()
+	for _, mode := range []string{transport.ColorModeDeuteranopia, transport.ColorModeProtanopia} {
+		t.Run(mode, func(t *testing.T) {
+			red, _ := cc.TransformColors("#FF0000", "#000000", mode)
+			green, _ := cc.TransformColors("#00FF00", "#000000", mode)
+			if red != "#D55E00" || green != "#009E73" {
+				t.Errorf("palette red/green = %s/%s, want Okabe-Ito vermillion/bluish green", red, green)
+			}
+
+			// Colors outside the ANSI palette are daltonized; a pure
+			// red-green difference must gain a blue component
+			r1, g1, b1, _ := parseHexColor(cvdSafeColor("#C83C32", mode))
+			r2, g2, b2, _ := parseHexColor(cvdSafeColor("#32C83C", mode))
+			if b1 == b2 && g1 == g2 {
+				t.Errorf("daltonized colors differ only in red: (%v,%v,%v) vs (%v,%v,%v)", r1, g1, b1, r2, g2, b2)
+			}
+		})
+	}
+}
+
+func TestColorConverter_TransformColors_HighContrast(t *testing.T) {
+	tests := []struct {
+		name   string
+		fg, bg string
+		wantBg string
+	}{
+		{"DimBlueOnBlack", "#000080", "#000000", "#000000"},
+		{"DarkGrayOnDarkBackground", "#444444", "#101010", "#000000"},
+		{"YellowOnWhite", "#FFFF00", "#F0F0F0", "#FFFFFF"},
+		{"BlackOnBlack", "#000000", "#000000", "#000000"},
+	}
+
+	cc := NewColorConverter()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fg, bg := cc.TransformColors(tt.fg, tt.bg, transport.ColorModeHighContrast)
+			if bg != tt.wantBg {
+				t.Errorf("background = %s, want %s", bg, tt.wantBg)
+			}
+			r1, g1, b1, _ := parseHexColor(fg)
+			r2, g2, b2, _ := parseHexColor(bg)
+			if ratio := contrastRatio(r1, g1, b1, r2, g2, b2); ratio < minHighContrastRatio {
+				t.Errorf("contrast of %s on %s = %.2f, want >= %.1f", fg, bg, ratio, minHighContrastRatio)
+			}
+		})
+	}
+}
+
+func TestColorConverter_TransformColors_KeepsUnparseableColors(t *testing.T) {
+	cc := NewColorConverter()
+	fg, bg := cc.TransformColors("red", "", transport.ColorModeDeuteranopia)
+	if fg != "red" || bg != "" {
+		t.Errorf("TransformColors = (%q, %q), want unchanged", fg, bg)
+	}
+}
+
+func TestToStatePayloadFor_AppliesColorMode(t *testing.T) {
+	state := &GameState{Buffer: [][]Cell{{{Char: 'd', FgColor: "#FF0000", BgColor: "#000000"}}}, Width: 1, Height: 1}
+
+	payload := toStatePayloadFor(state, transport.ViewOptions{ColorMode: transport.ColorModeDeuteranopia}, NewColorConverter())
+	defer releaseStatePayload(payload)
+	if got := payload.Buffer[0][0].FgColor; got != "#D55E00" {
+		t.Errorf("FgColor = %s, want #D55E00", got)
+	}
+	if state.Buffer[0][0].FgColor != "#FF0000" {
+		t.Error("Transforming the payload modified the source state")
+	}
+}
+
+func TestToStatePayloadFor_ResolvesInverse(t *testing.T) {
+	state := &GameState{Buffer: [][]Cell{{
+		{Char: 'a', FgColor: "#C0C0C0", BgColor: "#000080", Inverse: true},
+		{Char: 'b', FgColor: "#C0C0C0", BgColor: "#000080"},
+	}}, Width: 2, Height: 1}
+
+	tests := []struct {
+		name        string
+		opts        transport.ViewOptions
+		wantFg      string
+		wantBg      string
+		wantInverse bool
+	}{
+		{"Unresolved", transport.ViewOptions{}, "#C0C0C0", "#000080", true},
+		{"Resolved", transport.ViewOptions{ResolveInverse: true}, "#000080", "#C0C0C0", false},
+		// The color mode sees the swapped colors, so the light
+		// former foreground becomes the background
+		{"ResolvedHighContrast", transport.ViewOptions{ResolveInverse: true, ColorMode: transport.ColorModeHighContrast}, "", "#FFFFFF", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			payload := toStatePayloadFor(state, tt.opts, NewColorConverter())
+			defer releaseStatePayload(payload)
+
+			got := payload.Buffer[0][0]
+			if (tt.wantFg != "" && got.FgColor != tt.wantFg) || got.BgColor != tt.wantBg || got.Inverse != tt.wantInverse {
+				t.Errorf("Cell = %+v, want fg %s bg %s inverse %v", got, tt.wantFg, tt.wantBg, tt.wantInverse)
+			}
+			if plain := payload.Buffer[0][1]; plain.Inverse || plain.FgColor == "#000080" {
+				t.Errorf("Non-inverse cell changed: %+v", plain)
+			}
+		})
+	}
+}
@@ -0,0 +1,63 @@
+// Package webui provides background compaction of the diff history.
+package webui
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// HistoryCompactionConfig periodically merges the older diffs of the state
+// history into deltas spanning several versions, so the same number of
+// history entries lets clients catch up from further behind
+type HistoryCompactionConfig struct {
+	Interval string `yaml:"interval,omitempty" yamlcheck:"duration"` // between passes, default "5s"
+	Recent   int    `yaml:"recent,omitempty"`                        // newest diffs left as they are, default 8
+	Span     int    `yaml:"span,omitempty"`                          // most versions merged into one delta, default 16
+}
+
+// historyCompaction compacts the history of the view's store every interval
+type historyCompaction struct {
+	interval time.Duration
+	recent   int
+	span     int
+}
+
+// newHistoryCompaction validates cfg
+func newHistoryCompaction(cfg HistoryCompactionConfig) (*historyCompaction, error) {
+	c := &historyCompaction{interval: 5 * time.Second, recent: 8, span: 16}
+	if cfg.Interval != "" {
+		parsed, err := time.ParseDuration(cfg.Interval)
+		if err != nil || parsed <= 0 {
+			return nil, fmt.Errorf("history compaction: invalid interval %q", cfg.Interval)
+		}
+		c.interval = parsed
+	}
+	if cfg.Recent < 0 {
+		return nil, fmt.Errorf("history compaction: invalid recent %d", cfg.Recent)
+	}
+	if cfg.Span < 0 || cfg.Span == 1 {
+		return nil, fmt.Errorf("history compaction: invalid span %d", cfg.Span)
+	}
+	if cfg.Recent > 0 {
+		c.recent = cfg.Recent
+	}
+	if cfg.Span > 0 {
+		c.span = cfg.Span
+	}
+	return c, nil
+}
+
+// run compacts the history of store until ctx is done
+func (c *historyCompaction) run(ctx context.Context, store HistoryCompactor) {
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			store.Compact(c.recent, c.span)
+		}
+	}
+}
@@ -0,0 +1,16 @@
+package webui
+
+import "testing"
+
+func TestNewHistoryCompaction_RejectsInvalidConfig(t *testing.T) {
+	for _, cfg := range []HistoryCompactionConfig{
+		{Interval: "soon"},
+		{Interval: "-1s"},
+		{Recent: -1},
+		{Span: 1},
+	} {
+		if _, err := newHistoryCompaction(cfg); err == nil {
+			t.Errorf("newHistoryCompaction(%+v) succeeded, want an error", cfg)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+// Package webui provides correlation IDs tying the log entries, errors and
+// metrics of a session and of each web client together.
+package webui
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+
+	"github.com/opd-ai/go-gamelaunch-www/pkg/transport"
+)
+
+// CorrelationID identifies this session in its log entries and metrics
+func (w *WebUI) CorrelationID() string {
+	return w.correlationID
+}
+
+// withCorrelation gives r the correlation ID its client sent in
+// X-Correlation-ID, or a new one, and returns it in the response so the
+// client can send it again
+func withCorrelation(rw http.ResponseWriter, r *http.Request) *http.Request {
+	id := r.Header.Get(transport.CorrelationHeader)
+	if !transport.ValidCorrelationID(id) {
+		id = transport.NewCorrelationID("c-")
+	}
+	rw.Header().Set(transport.CorrelationHeader, id)
+	return r.WithContext(transport.WithCorrelationID(r.Context(), id))
+}
+
+// logger returns the session's logger, naming the web client whose
+// correlation ID ctx carries
+func (w *WebUI) logger(ctx context.Context) *slog.Logger {
+	if id := transport.CorrelationID(ctx); id != "" {
+		return w.log.With("correlation_id", id)
+	}
+	return w.log
+}
+
+// clientLogger returns the session's logger naming WebSocket client clientID
+func (w *WebUI) clientLogger(clientID string) *slog.Logger {
+	if ctx, ok := w.wsHandler.ClientContext(clientID); ok {
+		return w.logger(ctx)
+	}
+	return w.log.With("client", clientID)
+}
@@ -0,0 +1,54 @@
+package webui
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/opd-ai/go-gamelaunch-www/pkg/transport"
+)
+
+func TestWebUI_ServeHTTP_CorrelationID(t *testing.T) {
+	ui, err := NewWebUI(WebUIOptions{View: newTestView(t), AccessLog: &AccessLogConfig{}})
+	if err != nil {
+		t.Fatalf("NewWebUI failed: %v", err)
+	}
+	if !strings.HasPrefix(ui.CorrelationID(), "s-") {
+		t.Errorf("CorrelationID() = %q, want a session ID", ui.CorrelationID())
+	}
+	tests := []struct {
+		name   string
+		header string
+		want   string // "" for a new ID
+	}{
+		{"new client", "", ""},
+		{"returning client", "c-0123abcd", "c-0123abcd"},
+		{"invalid ID replaced", "bad id\n", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := &logBuffer{}
+			ui.log = slog.New(slog.NewJSONHandler(buf, nil)).With("session_id", ui.CorrelationID())
+			ui.accessLog.logger = ui.log
+
+			r := httptest.NewRequest(http.MethodGet, "/input/preset", nil)
+			if tt.header != "" {
+				r.Header.Set(transport.CorrelationHeader, tt.header)
+			}
+			rec := httptest.NewRecorder()
+			ui.ServeHTTP(rec, r)
+
+			got := rec.Header().Get(transport.CorrelationHeader)
+			if tt.want != "" && got != tt.want || tt.want == "" && !strings.HasPrefix(got, "c-") {
+				t.Errorf("%s = %q, want %q", transport.CorrelationHeader, got, tt.want)
+			}
+			records := accessLogRecords(t, buf)
+			if len(records) != 1 || records[0]["correlation_id"] != got || records[0]["session_id"] != ui.CorrelationID() {
+				t.Errorf("access log = %v, want the client's and the session's IDs", records)
+			}
+		})
+	}
+}
@@ -0,0 +1,207 @@
+// Package webui provides a heatmap of how often each screen cell changes,
+// for diagnosing games that spam redraws.
+package webui
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// maxDamageScale bounds the pixels per cell of the PNG heatmap
+const maxDamageScale = 16
+
+// DamageMapConfig counts the changes of each cell over the last Window,
+// served at /debug/damage as JSON or a PNG heatmap to find games that
+// redraw more than they need and to tune DiffBudget and bandwidth
+// coalescing
+type DamageMapConfig struct {
+	Window string `yaml:"window,omitempty" yamlcheck:"duration"` // longest window kept, default "60s"
+}
+
+// DamageMap is the response of GET /debug/damage
+type DamageMap struct {
+	Window  float64    `json:"window_seconds"`
+	Width   int        `json:"width"`
+	Height  int        `json:"height"`
+	Updates int        `json:"updates"` // state updates that changed cells
+	Changes uint64     `json:"changes"` // cell changes over all updates
+	Max     uint32     `json:"max"`     // changes of the busiest cell
+	Counts  [][]uint32 `json:"counts"`  // changes by row and column
+}
+
+// damageMap counts cell changes in one bucket per second of the window
+type damageMap struct {
+	window time.Duration
+
+	mu      sync.Mutex
+	width   int
+	height  int
+	buckets []damageBucket // indexed by Unix second modulo the window
+}
+
+// damageBucket holds the changes of one second
+type damageBucket struct {
+	second  int64
+	updates int
+	counts  []uint32 // width x height, row-major
+}
+
+// newDamageMap validates cfg
+func newDamageMap(cfg DamageMapConfig) (*damageMap, error) {
+	window := time.Minute
+	if cfg.Window != "" {
+		parsed, err := time.ParseDuration(cfg.Window)
+		if err != nil || parsed < time.Second {
+			return nil, fmt.Errorf("damage map: invalid window %q", cfg.Window)
+		}
+		window = parsed
+	}
+	seconds := int(window / time.Second)
+	return &damageMap{window: time.Duration(seconds) * time.Second, buckets: make([]damageBucket, seconds)}, nil
+}
+
+// setDamageMap makes UpdateState count changed cells in d
+func (sm *StateManager) setDamageMap(d *damageMap) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	sm.damage = d
+}
+
+// record counts changes on a width x height screen at now. A resize starts
+// the counts over.
+func (d *damageMap) record(now time.Time, width, height int, changes []CellDiff) {
+	if len(changes) == 0 {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if width != d.width || height != d.height {
+		d.width, d.height = width, height
+		clear(d.buckets)
+	}
+	second := now.Unix()
+	b := &d.buckets[second%int64(len(d.buckets))]
+	if b.second != second || len(b.counts) != width*height {
+		*b = damageBucket{second: second, counts: make([]uint32, width*height)}
+	}
+	b.updates++
+	for _, change := range changes {
+		if change.X >= 0 && change.X < width && change.Y >= 0 && change.Y < height {
+			b.counts[change.Y*width+change.X]++
+		}
+	}
+}
+
+// snapshot sums the buckets of the last window before now
+func (d *damageMap) snapshot(now time.Time, window time.Duration) *DamageMap {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	m := &DamageMap{Window: window.Seconds(), Width: d.width, Height: d.height, Counts: make([][]uint32, d.height)}
+	for y := range m.Counts {
+		m.Counts[y] = make([]uint32, d.width)
+	}
+	oldest := now.Unix() - int64(window/time.Second)
+	for _, b := range d.buckets {
+		if b.second <= oldest || b.second > now.Unix() || len(b.counts) != d.width*d.height {
+			continue
+		}
+		m.Updates += b.updates
+		for i, n := range b.counts {
+			m.Counts[i/d.width][i%d.width] += n
+			m.Changes += uint64(n)
+		}
+	}
+	for _, row := range m.Counts {
+		for _, n := range row {
+			m.Max = max(m.Max, n)
+		}
+	}
+	return m
+}
+
+// heatColor maps a share of the busiest cell's changes to a color from
+// blue through green to red; cells that never changed are black
+func heatColor(n, most uint32) color.RGBA {
+	if n == 0 || most == 0 {
+		return color.RGBA{A: 0xFF}
+	}
+	t := float64(n) / float64(most)
+	if t < 0.5 {
+		return color.RGBA{G: uint8(510 * t), B: uint8(255 * (1 - 2*t)), A: 0xFF}
+	}
+	return color.RGBA{R: uint8(510 * (t - 0.5)), G: uint8(255 * (2 - 2*t)), A: 0xFF}
+}
+
+// renderDamage draws m with scale x scale pixels per cell
+func renderDamage(m *DamageMap, scale int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, m.Width*scale, m.Height*scale))
+	for y, row := range m.Counts {
+		for x, n := range row {
+			block := image.Rect(x*scale, y*scale, (x+1)*scale, (y+1)*scale)
+			draw.Draw(img, block, image.NewUniform(heatColor(n, m.Max)), image.Point{}, draw.Src)
+		}
+	}
+	return img
+}
+
+// handleDamage serves GET /debug/damage: the changes of each cell over the
+// last window (?window=10s, default and at most the configured window), as
+// JSON or, with ?format=png, a heatmap of ?scale pixels per cell (default 4)
+func (w *WebUI) handleDamage(rw http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		rw.Header().Set("Allow", http.MethodGet)
+		http.Error(rw, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	q := r.URL.Query()
+	window := w.damage.window
+	if v := q.Get("window"); v != "" {
+		parsed, err := time.ParseDuration(v)
+		if err != nil || parsed < time.Second || parsed > w.damage.window {
+			http.Error(rw, "Invalid window", http.StatusBadRequest)
+			return
+		}
+		window = parsed.Truncate(time.Second)
+	}
+	m := w.damage.snapshot(time.Now(), window)
+
+	switch q.Get("format") {
+	case "", "json":
+		writeJSON(rw, http.StatusOK, m)
+	case "png":
+		scale := 4
+		if v := q.Get("scale"); v != "" {
+			parsed, err := strconv.Atoi(v)
+			if err != nil || parsed < 1 || parsed > maxDamageScale {
+				http.Error(rw, "Invalid scale", http.StatusBadRequest)
+				return
+			}
+			scale = parsed
+		}
+		if m.Width == 0 || m.Height == 0 {
+			http.Error(rw, "No screen changes recorded yet", http.StatusServiceUnavailable)
+			return
+		}
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, renderDamage(m, scale)); err != nil {
+			http.Error(rw, "Failed to render heatmap", http.StatusInternalServerError)
+			return
+		}
+		rw.Header().Set("Content-Type", "image/png")
+		w.setCacheControl(rw, r, "no-store")
+		rw.Write(buf.Bytes())
+	default:
+		http.Error(rw, "Invalid format", http.StatusBadRequest)
+	}
+}
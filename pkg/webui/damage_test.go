@@ -0,0 +1,121 @@
+package webui
+
+import (
+	"encoding/json"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestDamageMap_Snapshot(t *testing.T) {
+	d, err := newDamageMap(DamageMapConfig{Window: "10s"})
+	if err != nil {
+		t.Fatalf("newDamageMap failed: %v", err)
+	}
+	start := time.Unix(1000, 0)
+	d.record(start, 3, 2, []CellDiff{{X: 0, Y: 0}, {X: 2, Y: 1}})
+	d.record(start.Add(5*time.Second), 3, 2, []CellDiff{{X: 0, Y: 0}})
+	d.record(start.Add(5*time.Second), 3, 2, nil)
+
+	tests := []struct {
+		name    string
+		now     time.Time
+		window  time.Duration
+		updates int
+		origin  uint32
+		max     uint32
+	}{
+		{"whole window", start.Add(5 * time.Second), 10 * time.Second, 2, 2, 2},
+		{"shorter window", start.Add(5 * time.Second), 2 * time.Second, 1, 1, 1},
+		{"older changes expire", start.Add(12 * time.Second), 10 * time.Second, 1, 1, 1},
+		{"everything expired", start.Add(30 * time.Second), 10 * time.Second, 0, 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := d.snapshot(tt.now, tt.window)
+			if m.Width != 3 || m.Height != 2 {
+				t.Fatalf("size = %dx%d, want 3x2", m.Width, m.Height)
+			}
+			if m.Updates != tt.updates || m.Counts[0][0] != tt.origin || m.Max != tt.max {
+				t.Errorf("updates, origin, max = %d, %d, %d, want %d, %d, %d",
+					m.Updates, m.Counts[0][0], m.Max, tt.updates, tt.origin, tt.max)
+			}
+		})
+	}
+
+	// A resize starts over
+	d.record(start.Add(6*time.Second), 4, 2, []CellDiff{{X: 3, Y: 0}})
+	m := d.snapshot(start.Add(6*time.Second), 10*time.Second)
+	if m.Width != 4 || m.Changes != 1 || m.Counts[0][3] != 1 {
+		t.Errorf("after resize = %+v, want only the new change", m)
+	}
+}
+
+func TestWebUI_HandleDamage(t *testing.T) {
+	view := newTestView(t)
+	w := newAdminWebUI(t, WebUIOptions{
+		View:         view,
+		DamageMap:    &DamageMapConfig{},
+		StaticTokens: []StaticToken{{Name: "watcher", Token: spectatorToken, Role: "spectator"}},
+	})
+	for _, frame := range []string{"a", "\rb", "\rc"} {
+		if err := view.Render([]byte(frame)); err != nil {
+			t.Fatalf("Render failed: %v", err)
+		}
+	}
+
+	// Only admins see the damage
+	req := httptest.NewRequest(http.MethodGet, "/debug/damage", nil)
+	req.Header.Set("Authorization", "Bearer "+spectatorToken)
+	rec := httptest.NewRecorder()
+	w.ServeHTTP(rec, req)
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("spectator GET /debug/damage = %d, want 403", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	w.ServeHTTP(rec, newAdminRequest(http.MethodGet, "/debug/damage?window=10s", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET /debug/damage = %d, want 200", rec.Code)
+	}
+	var damage DamageMap
+	if err := json.Unmarshal(rec.Body.Bytes(), &damage); err != nil {
+		t.Fatalf("invalid response: %v", err)
+	}
+	if damage.Window != 10 || damage.Max == 0 || damage.Counts[0][0] != damage.Max {
+		t.Errorf("damage = window %v, max %d, origin %d, want the first cell busiest",
+			damage.Window, damage.Max, damage.Counts[0][0])
+	}
+
+	rec = httptest.NewRecorder()
+	w.ServeHTTP(rec, newAdminRequest(http.MethodGet, "/debug/damage?format=png&scale=2", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET /debug/damage?format=png = %d, want 200", rec.Code)
+	}
+	img, err := png.Decode(rec.Body)
+	if err != nil {
+		t.Fatalf("invalid PNG: %v", err)
+	}
+	if b := img.Bounds(); b.Dx() != damage.Width*2 || b.Dy() != damage.Height*2 {
+		t.Errorf("image size = %v, want 2 pixels per cell", b)
+	}
+
+	for _, query := range []string{"window=2h", "window=soon", "format=gif", "format=png&scale=0"} {
+		rec = httptest.NewRecorder()
+		w.ServeHTTP(rec, newAdminRequest(http.MethodGet, "/debug/damage?"+query, nil))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("GET /debug/damage?%s = %d, want 400", query, rec.Code)
+		}
+	}
+}
+
+func TestNewDamageMap_RejectsInvalidConfig(t *testing.T) {
+	for _, window := range []string{"soon", "500ms", "-1m"} {
+		if _, err := newDamageMap(DamageMapConfig{Window: window}); err == nil {
+			t.Errorf("newDamageMap(%q) succeeded, want an error", window)
+		}
+	}
+}
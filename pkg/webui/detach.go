@@ -0,0 +1,85 @@
+// Package webui provides detach and reattach semantics like screen or tmux:
+// the game keeps running with no browser connected, and a player who comes
+// back resumes the screen where they left it.
+package webui
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// DetachConfig bounds how long a session stays alive with no WebSocket
+// client connected. The game and its screen are kept for TTL after the last
+// client left; a client connecting in time reattaches to them, and
+// otherwise the session expires.
+type DetachConfig struct {
+	TTL string `yaml:"ttl" yamlcheck:"duration"` // e.g. "24h"
+}
+
+// detachWatch tracks whether any client is attached to the session
+type detachWatch struct {
+	ttl time.Duration // 0 keeps detached sessions until they end otherwise
+
+	mu    sync.Mutex
+	since time.Time // when the last client left, zero while attached
+}
+
+// newDetachWatch validates cfg; a nil cfg keeps detached sessions
+func newDetachWatch(cfg *DetachConfig) (*detachWatch, error) {
+	d := &detachWatch{}
+	if cfg == nil {
+		return d, nil
+	}
+	ttl, err := time.ParseDuration(cfg.TTL)
+	if err != nil || ttl <= 0 {
+		return nil, fmt.Errorf("detach: invalid ttl %q", cfg.TTL)
+	}
+	d.ttl = ttl
+	return d, nil
+}
+
+// attach records a client connecting, calling off the expiry of a detached
+// session
+func (w *WebUI) attach() {
+	w.detach.mu.Lock()
+	defer w.detach.mu.Unlock()
+	if since := w.detach.since; !since.IsZero() {
+		w.detach.since = time.Time{}
+		if view := w.GetView(); view != nil {
+			view.Events().Record(EventReattach, "reattached after %s", time.Since(since).Round(time.Second))
+		}
+	}
+	if w.expiry.cancelReason(ExpiryDetached) {
+		w.showExpiry(nil)
+	}
+}
+
+// detached records a client leaving and, once none is left, schedules the
+// expiry of the session unless another one is pending
+func (w *WebUI) detached() {
+	w.detach.mu.Lock()
+	defer w.detach.mu.Unlock()
+	if w.wsHandler.GetClientCount() > 0 || !w.detach.since.IsZero() {
+		return
+	}
+	w.detach.since = time.Now()
+	if w.detach.ttl <= 0 || w.expiry.current() != nil {
+		return
+	}
+	if _, err := w.scheduleExpiry(ExpiryDetached, w.detach.ttl, "", false); err != nil {
+		w.log.Debug("webui: detached session not scheduled to expire", "error", err)
+	}
+}
+
+// detachedSince returns when the last client left, or nil while one is
+// attached
+func (w *WebUI) detachedSince() *time.Time {
+	w.detach.mu.Lock()
+	defer w.detach.mu.Unlock()
+	if w.detach.since.IsZero() {
+		return nil
+	}
+	since := w.detach.since
+	return &since
+}
@@ -0,0 +1,55 @@
+package webui
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWebUI_DetachAndReattach(t *testing.T) {
+	view := newTestView(t)
+	ui, err := NewWebUI(WebUIOptions{View: view, Detach: &DetachConfig{TTL: "1h"}})
+	if err != nil {
+		t.Fatalf("Failed to create WebUI: %v", err)
+	}
+	if err := view.Render([]byte("You see here a scroll.")); err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	expiry := func() *SessionExpiry { return view.GetStateManager().GetCurrentState().Expiry }
+
+	// The last client leaving detaches the session for the TTL
+	ui.handleClientConnect("c1")
+	ui.handleClientDisconnect("c1")
+	pending := expiry()
+	if pending == nil || pending.Reason != ExpiryDetached || pending.Cancelable {
+		t.Fatalf("expiry = %+v, want a detached expiry", pending)
+	}
+	if remaining := time.Until(time.UnixMilli(pending.Deadline)); remaining < 59*time.Minute || remaining > time.Hour {
+		t.Errorf("detached session expires in %s, want 1h", remaining)
+	}
+	if ui.GetSessionInfo().DetachedSince == nil {
+		t.Error("session info does not report the detached session")
+	}
+
+	// Reattaching keeps the game and its screen
+	ui.handleClientConnect("c2")
+	if expiry() != nil || ui.GetSessionInfo().DetachedSince != nil {
+		t.Errorf("reattached session still detached: %+v", expiry())
+	}
+	if got := stateLines(view.GetStateManager().GetCurrentState())[0]; got != "You see here a scroll." {
+		t.Errorf("screen after reattach = %q", got)
+	}
+
+	// A pending admin kill is neither replaced nor called off
+	if _, err := ui.ExpireSession(time.Minute, "maintenance", false); err != nil {
+		t.Fatalf("ExpireSession failed: %v", err)
+	}
+	ui.handleClientDisconnect("c2")
+	ui.handleClientConnect("c3")
+	if pending := expiry(); pending == nil || pending.Reason != ExpiryAdmin {
+		t.Errorf("expiry = %+v, want the admin kill", pending)
+	}
+
+	if _, err := newDetachWatch(&DetachConfig{TTL: "0s"}); err == nil {
+		t.Error("newDetachWatch accepted a zero ttl")
+	}
+}
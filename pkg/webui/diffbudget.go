@@ -0,0 +1,136 @@
+// Package webui provides a size budget for diffs sent to pollers, falling
+// back to row or keyframe encoding for large updates.
+package webui
+
+import "fmt"
+
+// cellDiffOverhead approximates the JSON size of a CellDiff without its
+// color strings
+const cellDiffOverhead = 96
+
+// DiffBudget limits the size of diffs delivered to pollers. A diff over
+// either limit, such as a full screen redraw, is sent as whole rows, or as a
+// keyframe of the entire screen, instead of individual cell changes. Zero
+// disables a limit.
+type DiffBudget struct {
+	MaxChanges int `yaml:"max_changes,omitempty"` // e.g. 500
+	MaxBytes   int `yaml:"max_bytes,omitempty"`   // estimated JSON size, e.g. 65536
+}
+
+// RowDiff replaces an entire row of the screen
+type RowDiff struct {
+	Y     int    `json:"y"`
+	Cells []Cell `json:"cells"`
+}
+
+// validate checks that the limits are not negative
+func (b DiffBudget) validate() error {
+	if b.MaxChanges < 0 {
+		return fmt.Errorf("diff budget: invalid max_changes %d", b.MaxChanges)
+	}
+	if b.MaxBytes < 0 {
+		return fmt.Errorf("diff budget: invalid max_bytes %d", b.MaxBytes)
+	}
+	return nil
+}
+
+// exceeded reports whether changes are over either limit
+func (b DiffBudget) exceeded(changes []CellDiff) bool {
+	if b.MaxChanges > 0 && len(changes) > b.MaxChanges {
+		return true
+	}
+	if b.MaxBytes <= 0 {
+		return false
+	}
+	size := 0
+	for _, change := range changes {
+		size += cellDiffOverhead + len(change.Cell.FgColor) + len(change.Cell.BgColor)
+		if size > b.MaxBytes {
+			return true
+		}
+	}
+	return false
+}
+
+// SetDiffBudget replaces the size budget of delivered diffs
+func (sm *StateManager) SetDiffBudget(budget DiffBudget) error {
+	if err := budget.validate(); err != nil {
+		return err
+	}
+	sm.budget.Store(&budget)
+	return nil
+}
+
+// applyBudget returns diff unchanged when it fits the budget. Otherwise it
+// returns a copy that carries the changed rows of current, the state diff
+// leads to, or a keyframe when more than half of the rows changed. The
+// stored diff is never modified, so history can still be merged.
+func (sm *StateManager) applyBudget(diff *StateDiff, current *GameState) *StateDiff {
+	budget := sm.budget.Load()
+	if diff == nil || budget == nil || !budget.exceeded(diff.Changes) {
+		return diff
+	}
+
+	changed := make([]bool, current.Height)
+	count := 0
+	for _, change := range diff.Changes {
+		if change.Y < current.Height && !changed[change.Y] {
+			changed[change.Y] = true
+			count++
+		}
+	}
+
+	if count*2 > current.Height {
+		return keyframeDiff(current)
+	}
+
+	compact := &StateDiff{
+		Version:    diff.Version,
+		Changes:    []CellDiff{},
+		CursorX:    diff.CursorX,
+		CursorY:    diff.CursorY,
+		Echo:       diff.Echo,
+		Progress:   diff.Progress,
+		Landmarks:  diff.Landmarks,
+		Expiry:     diff.Expiry,
+		Stall:      diff.Stall,
+		Broadcast:  diff.Broadcast,
+		Scrollback: diff.Scrollback,
+		Timestamp:  diff.Timestamp,
+	}
+	for y, rowChanged := range changed {
+		if rowChanged {
+			compact.Rows = append(compact.Rows, RowDiff{
+				Y:     y,
+				Cells: append([]Cell(nil), current.Buffer[y]...),
+			})
+		}
+	}
+	return compact
+}
+
+// keyframeDiff returns a diff that replaces the entire screen with state
+func keyframeDiff(state *GameState) *StateDiff {
+	diff := &StateDiff{
+		Version:    state.Version,
+		Changes:    []CellDiff{},
+		CursorX:    state.CursorX,
+		CursorY:    state.CursorY,
+		Echo:       state.Echo,
+		Progress:   state.Progress,
+		Landmarks:  state.Landmarks,
+		Expiry:     state.Expiry,
+		Stall:      state.Stall,
+		Broadcast:  state.Broadcast,
+		Scrollback: state.Scrollback,
+		Timestamp:  state.Timestamp,
+		Rows:       make([]RowDiff, state.Height),
+		Keyframe:   true,
+		Width:      state.Width,
+		Height:     state.Height,
+	}
+	for y := range diff.Rows {
+		diff.Rows[y] = RowDiff{Y: y, Cells: append([]Cell(nil), state.Buffer[y]...)}
+	}
+	return diff
+}
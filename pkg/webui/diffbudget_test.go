@@ -0,0 +1,134 @@
+package webui
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// budgetTestState returns a 10x4 state with the given rows filled with char
+func budgetTestState(char rune, rows ...int) *GameState {
+	state := &GameState{Width: 10, Height: 4, Buffer: createTestBuffer(4, 10)}
+	for _, y := range rows {
+		for x := range state.Buffer[y] {
+			state.Buffer[y][x].Char = char
+		}
+	}
+	return state
+}
+
+func TestStateManager_applyBudget_EncodesOversizedDiffs(t *testing.T) {
+	tests := []struct {
+		name         string
+		budget       DiffBudget
+		changedRows  []int
+		wantChanges  int
+		wantRows     []int
+		wantKeyframe bool
+	}{
+		{"NoBudget_KeepsChanges", DiffBudget{}, []int{0, 1, 2, 3}, 40, nil, false},
+		{"WithinBudget_KeepsChanges", DiffBudget{MaxChanges: 10}, []int{2}, 10, nil, false},
+		{"OverChanges_SendsRows", DiffBudget{MaxChanges: 5}, []int{1, 3}, 0, []int{1, 3}, false},
+		{"OverBytes_SendsRows", DiffBudget{MaxBytes: 500}, []int{2}, 0, []int{2}, false},
+		{"MostRows_SendsKeyframe", DiffBudget{MaxChanges: 5}, []int{0, 1, 2}, 0, []int{0, 1, 2, 3}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sm := NewStateManager()
+			if err := sm.SetDiffBudget(tt.budget); err != nil {
+				t.Fatalf("SetDiffBudget() error = %v", err)
+			}
+			sm.UpdateState(budgetTestState(' '))
+
+			ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+			defer cancel()
+			result := make(chan *StateDiff, 1)
+			go func() {
+				diff, _ := sm.PollChangesWithContext(ctx, 1)
+				result <- diff
+			}()
+			waitForWaiters(t, sm, 1)
+			sm.UpdateState(budgetTestState('#', tt.changedRows...))
+
+			diff := <-result
+			if diff == nil {
+				t.Fatal("PollChangesWithContext() returned no diff")
+			}
+			if len(diff.Changes) != tt.wantChanges {
+				t.Errorf("len(Changes) = %d, want %d", len(diff.Changes), tt.wantChanges)
+			}
+			if diff.Keyframe != tt.wantKeyframe {
+				t.Errorf("Keyframe = %v, want %v", diff.Keyframe, tt.wantKeyframe)
+			}
+			if tt.wantKeyframe && (diff.Width != 10 || diff.Height != 4) {
+				t.Errorf("keyframe size = %dx%d, want 10x4", diff.Width, diff.Height)
+			}
+			if len(diff.Rows) != len(tt.wantRows) {
+				t.Fatalf("len(Rows) = %d, want %d", len(diff.Rows), len(tt.wantRows))
+			}
+			for i, row := range diff.Rows {
+				if row.Y != tt.wantRows[i] || len(row.Cells) != 10 {
+					t.Errorf("Rows[%d] = y %d with %d cells, want y %d with 10", i, row.Y, len(row.Cells), tt.wantRows[i])
+				}
+			}
+		})
+	}
+}
+
+func TestStateManager_applyBudget_KeepsHistoryMergeable(t *testing.T) {
+	sm := NewStateManager()
+	if err := sm.SetDiffBudget(DiffBudget{MaxChanges: 15}); err != nil {
+		t.Fatalf("SetDiffBudget() error = %v", err)
+	}
+	sm.UpdateState(budgetTestState(' '))
+	sm.UpdateState(budgetTestState('#', 0, 1)) // over budget on its own
+	sm.UpdateState(budgetTestState('#', 1))    // restores row 0
+
+	diffs, ok := sm.Store().DiffsSince(1)
+	if !ok || len(diffs) != 2 || len(diffs[0].Changes) != 20 || diffs[0].Rows != nil {
+		t.Fatalf("stored history was compacted: %+v", diffs)
+	}
+
+	diff, err := sm.generateDiffFromVersion(1)
+	if err != nil {
+		t.Fatalf("generateDiffFromVersion() error = %v", err)
+	}
+	if len(diff.Changes) != 0 || len(diff.Rows) != 2 {
+		t.Fatalf("merged diff = %d changes, %d rows, want 2 rows", len(diff.Changes), len(diff.Rows))
+	}
+	if got := diff.Rows[0].Cells[0].Char; got != ' ' {
+		t.Errorf("row 0 = %q, want the current blank row", got)
+	}
+	if got := diff.Rows[1].Cells[0].Char; got != '#' {
+		t.Errorf("row 1 = %q, want the current filled row", got)
+	}
+}
+
+func TestStateManager_SetDiffBudget_RejectsNegativeLimits(t *testing.T) {
+	sm := NewStateManager()
+	for _, budget := range []DiffBudget{{MaxChanges: -1}, {MaxBytes: -1}} {
+		if err := sm.SetDiffBudget(budget); err == nil {
+			t.Errorf("SetDiffBudget(%+v) succeeded, want error", budget)
+		}
+	}
+}
+
+// waitForWaiters waits until n pollers are registered with sm
+func waitForWaiters(t *testing.T, sm *StateManager, n int) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		sm.waitersMu.Lock()
+		count := 0
+		for _, waiters := range sm.waiters {
+			count += len(waiters)
+		}
+		sm.waitersMu.Unlock()
+		if count >= n {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("timed out waiting for %d pollers", n)
+}
@@ -0,0 +1,58 @@
+// Package webui provides detection of whether the game leaves echo to the
+// terminal, so web clients know when they may echo typed characters locally.
+package webui
+
+import "strings"
+
+// terminalModes holds the private modes full-screen applications set when
+// they take over the keyboard and draw typed characters themselves
+type terminalModes struct {
+	altScreen  bool // DECSET 47, 1047 or 1049
+	appCursor  bool // DECSET 1, application cursor keys
+	appKeypad  bool // DECKPAM (ESC =)
+	noAutowrap bool // DECRST 7, characters past the last column overwrite it
+}
+
+// handlePrivateMode processes DECSET (CSI ? Pm h) and DECRST (CSI ? Pm l)
+// sequences; other modes are ignored
+func (v *WebView) handlePrivateMode(seq string, set bool) {
+	params, ok := strings.CutPrefix(seq[2:len(seq)-1], "?")
+	if !ok {
+		return
+	}
+	for _, param := range strings.Split(params, ";") {
+		switch param {
+		case "1":
+			v.modes.appCursor = set
+		case "7":
+			v.modes.noAutowrap = !set
+		case "47", "1047":
+			v.switchScreen(set, false)
+		case "1048":
+			if set {
+				v.saveCursor()
+			} else {
+				v.restoreCursor()
+			}
+		case "1049":
+			v.switchScreen(set, true)
+		}
+	}
+}
+
+// localEcho reports whether the application leaves echo to the terminal.
+// Full-screen applications, which switch to the alternate screen or
+// application keys, and password prompts do not echo typed characters
+// as-is, so clients must wait for the server there.
+func (v *WebView) localEcho() bool {
+	return !v.secretPrompt && !v.modes.altScreen && !v.modes.appCursor && !v.modes.appKeypad
+}
+
+// LocalEcho reports whether clients may echo typed characters locally
+// before the server does; see GameState.Echo
+func (v *WebView) LocalEcho() bool {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+
+	return v.localEcho()
+}
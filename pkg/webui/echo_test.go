@@ -0,0 +1,37 @@
+package webui
+
+import "testing"
+
+func TestWebView_LocalEcho(t *testing.T) {
+	tests := []struct {
+		name   string
+		output []string
+		want   bool
+	}{
+		{"shell prompt", []string{"$ "}, true},
+		{"alternate screen", []string{"\x1b[?1049h"}, false},
+		{"alternate screen left", []string{"\x1b[?1049h", "\x1b[?1049l"}, true},
+		{"application cursor keys", []string{"\x1b[?1;25h"}, false},
+		{"application keypad", []string{"\x1b="}, false},
+		{"normal keypad", []string{"\x1b=", "\x1b>"}, true},
+		{"password prompt", []string{"Password: "}, false},
+		{"terminal reset", []string{"\x1b[?1049h\x1b=", "\x1bc"}, true},
+		{"other modes", []string{"\x1b[4h\x1b[?25l"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			view := newTestView(t)
+			for _, output := range tt.output {
+				if err := view.Render([]byte(output)); err != nil {
+					t.Fatalf("Render(%q) error = %v", output, err)
+				}
+			}
+			if got := view.LocalEcho(); got != tt.want {
+				t.Errorf("LocalEcho() = %v, want %v", got, tt.want)
+			}
+			if got := view.GetCurrentState().Echo; got != tt.want {
+				t.Errorf("state Echo = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,51 @@
+// Package webui provides state manager epochs that let pollers detect
+// server restarts.
+package webui
+
+import (
+	"context"
+	"strconv"
+	"time"
+)
+
+// newEpoch returns a random identifier for a StateManager instance
+func newEpoch() string {
+	epoch, err := randomToken(8)
+	if err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 36)
+	}
+	return epoch
+}
+
+// Epoch identifies this state manager instance. Versions start again from 1
+// when the server restarts, so every delivered diff carries the epoch and
+// pollers that send it back with PollChangesWithEpoch receive a keyframe
+// instead of waiting for a version that may never come.
+func (sm *StateManager) Epoch() string {
+	return sm.epoch
+}
+
+// PollChangesWithEpoch is PollChangesWithContext for clients that remember
+// the epoch of their last diff. When epoch is set and differs from Epoch,
+// the client's version belongs to a previous instance and the current state
+// is returned immediately as a keyframe.
+func (sm *StateManager) PollChangesWithEpoch(ctx context.Context, epoch string, version uint64) (*StateDiff, error) {
+	if epoch != "" && epoch != sm.epoch {
+		if current := sm.store.Current(); current != nil {
+			return sm.stamp(keyframeDiff(current)), nil
+		}
+		version = 0
+	}
+	return sm.PollChangesWithContext(ctx, version)
+}
+
+// stamp returns a copy of diff carrying the epoch; the copy shares the
+// changes of diff, which must not be modified
+func (sm *StateManager) stamp(diff *StateDiff) *StateDiff {
+	if diff == nil || diff.Epoch == sm.epoch {
+		return diff
+	}
+	stamped := *diff
+	stamped.Epoch = sm.epoch
+	return &stamped
+}
@@ -0,0 +1,64 @@
+package webui
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestStateManager_Epoch_DiffersPerInstance(t *testing.T) {
+	first, second := NewStateManager(), NewStateManager()
+	if first.Epoch() == "" || first.Epoch() == second.Epoch() {
+		t.Errorf("epochs %q and %q, want distinct non-empty values", first.Epoch(), second.Epoch())
+	}
+
+	first.UpdateState(budgetTestState(' '))
+	if diff := pollNextUpdate(t, first, budgetTestState('#', 0)); diff.Epoch != first.Epoch() {
+		t.Errorf("delivered diff has epoch %q, want %q", diff.Epoch, first.Epoch())
+	}
+}
+
+func TestStateManager_PollChangesWithEpoch_RestartSendsKeyframe(t *testing.T) {
+	// The restarted server is at version 2; the client last saw version 40
+	// from the previous instance
+	sm := NewStateManager()
+	sm.UpdateState(budgetTestState(' '))
+	sm.UpdateState(budgetTestState('#', 1))
+
+	tests := []struct {
+		name    string
+		epoch   string
+		version uint64
+	}{
+		{"stale epoch", "previous-instance", 1},
+		{"version ahead without epoch", "", 40},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+			defer cancel()
+
+			diff, err := sm.PollChangesWithEpoch(ctx, tt.epoch, tt.version)
+			if err != nil {
+				t.Fatalf("PollChangesWithEpoch() error = %v, want an immediate keyframe", err)
+			}
+			if !diff.Keyframe || diff.Version != 2 || len(diff.Rows) != 4 {
+				t.Errorf("diff = version %d, keyframe %v, %d rows; want keyframe of version 2", diff.Version, diff.Keyframe, len(diff.Rows))
+			}
+			if diff.Epoch != sm.Epoch() {
+				t.Errorf("Epoch = %q, want %q", diff.Epoch, sm.Epoch())
+			}
+		})
+	}
+}
+
+func TestStateManager_PollChangesWithEpoch_CurrentEpochWaits(t *testing.T) {
+	sm := NewStateManager()
+	sm.UpdateState(budgetTestState(' '))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if diff, err := sm.PollChangesWithEpoch(ctx, sm.Epoch(), 1); err == nil {
+		t.Errorf("PollChangesWithEpoch() = %+v, want to wait for the next version", diff)
+	}
+}
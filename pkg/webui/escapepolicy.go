@@ -0,0 +1,159 @@
+// Package webui provides the escape-sequence security policy for the terminal parser.
+package webui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// Escape policy defaults
+const (
+	defaultMaxEscapeLength  = 32
+	defaultStrictViolations = 10
+	defaultViolationWindow  = time.Minute
+)
+
+// ErrEscapePolicyViolation is returned by Render once a strict escape policy
+// has been tripped; the SSH session loop ends on this error
+var ErrEscapePolicyViolation = errors.New("escape sequence policy violated")
+
+// EscapePolicyConfig configures how the terminal parser treats oversized
+// escape sequences. Oversized sequences are always discarded and recorded as
+// security events; in strict mode repeated violations end the session.
+type EscapePolicyConfig struct {
+	MaxSequenceLength int    `yaml:"max_sequence_length,omitempty"` // default 32
+	Strict            bool   `yaml:"strict,omitempty"`
+	MaxViolations     int    `yaml:"max_violations,omitempty"`                        // strict mode, default 10
+	ViolationWindow   string `yaml:"violation_window,omitempty" yamlcheck:"duration"` // strict mode, default 1m
+}
+
+// EscapeSecurityStats reports escape policy enforcement for a view
+type EscapeSecurityStats struct {
+	MaxSequenceLength int       `json:"max_sequence_length"`
+	Strict            bool      `json:"strict"`
+	Violations        uint64    `json:"violations"`
+	LastViolation     time.Time `json:"last_violation,omitempty"`
+	Terminated        bool      `json:"terminated"`
+}
+
+// escapePolicy is the validated runtime form of EscapePolicyConfig
+type escapePolicy struct {
+	maxLength     int
+	strict        bool
+	maxViolations int
+	window        time.Duration
+
+	violations uint64
+	recent     []time.Time // violation times inside the window, strict mode only
+	last       time.Time
+	terminated bool
+	now        func() time.Time
+}
+
+// newEscapePolicy validates cfg and returns the runtime policy
+func newEscapePolicy(cfg EscapePolicyConfig) (*escapePolicy, error) {
+	p := &escapePolicy{
+		maxLength:     cfg.MaxSequenceLength,
+		strict:        cfg.Strict,
+		maxViolations: cfg.MaxViolations,
+		window:        defaultViolationWindow,
+		now:           time.Now,
+	}
+	if p.maxLength == 0 {
+		p.maxLength = defaultMaxEscapeLength
+	}
+	if p.maxLength < 2 {
+		return nil, fmt.Errorf("escape policy: max_sequence_length must be at least 2")
+	}
+	if p.maxViolations <= 0 {
+		p.maxViolations = defaultStrictViolations
+	}
+	if cfg.ViolationWindow != "" {
+		d, err := time.ParseDuration(cfg.ViolationWindow)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("escape policy: invalid violation_window %q", cfg.ViolationWindow)
+		}
+		p.window = d
+	}
+	return p, nil
+}
+
+// violation records an oversized sequence and reports whether the session
+// must be terminated
+func (p *escapePolicy) violation() bool {
+	now := p.now()
+	p.violations++
+	p.last = now
+	if !p.strict || p.terminated {
+		return p.terminated
+	}
+
+	cutoff := now.Add(-p.window)
+	kept := p.recent[:0]
+	for _, t := range p.recent {
+		if t.After(cutoff) {
+			kept = append(kept, t)
+		}
+	}
+	p.recent = append(kept, now)
+	if len(p.recent) >= p.maxViolations {
+		p.terminated = true
+	}
+	return p.terminated
+}
+
+// stats returns a snapshot of the policy counters
+func (p *escapePolicy) stats() EscapeSecurityStats {
+	return EscapeSecurityStats{
+		MaxSequenceLength: p.maxLength,
+		Strict:            p.strict,
+		Violations:        p.violations,
+		LastViolation:     p.last,
+		Terminated:        p.terminated,
+	}
+}
+
+// SetEscapePolicy replaces the view's escape-sequence policy. Counters are
+// reset.
+func (v *WebView) SetEscapePolicy(cfg EscapePolicyConfig) error {
+	policy, err := newEscapePolicy(cfg)
+	if err != nil {
+		return err
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	v.escapePolicy = policy
+	return nil
+}
+
+// EscapeStats returns escape policy enforcement counters
+func (v *WebView) EscapeStats() EscapeSecurityStats {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+
+	return v.escapePolicy.stats()
+}
+
+// escapeOverflow handles an escape sequence exceeding the policy limit
+func (v *WebView) escapeOverflow() {
+	terminate := v.escapePolicy.violation()
+	v.events.Record(EventEscapeOverflow, "escape sequence exceeded %d bytes, discarded", v.escapePolicy.maxLength)
+	level := slog.LevelDebug
+	if v.escapePolicy.violations == 1 {
+		level = slog.LevelWarn // log the first violation loudly, the rest are counted
+	}
+	v.logger.Log(context.Background(), level, "webui.WebView discarded oversized escape sequence",
+		"limit", v.escapePolicy.maxLength, "violations", v.escapePolicy.violations)
+
+	if terminate && !v.escapeTerminated {
+		v.escapeTerminated = true
+		v.events.Record(EventEscapeOverflow, "strict escape policy tripped, terminating session")
+		v.logger.Error("webui.WebView terminating session after repeated escape sequence violations",
+			"violations", v.escapePolicy.violations)
+	}
+}
@@ -0,0 +1,140 @@
+package webui
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// oversizedEscape returns an unterminated CSI sequence longer than n bytes
+func oversizedEscape(n int) []byte {
+	return []byte("\x1b[" + strings.Repeat("1;", n))
+}
+
+func TestNewEscapePolicy_ValidatesConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     EscapePolicyConfig
+		wantErr bool
+		wantMax int
+	}{
+		{"Defaults", EscapePolicyConfig{}, false, defaultMaxEscapeLength},
+		{"CustomLimit", EscapePolicyConfig{MaxSequenceLength: 128}, false, 128},
+		{"LimitTooSmall", EscapePolicyConfig{MaxSequenceLength: 1}, true, 0},
+		{"InvalidWindow", EscapePolicyConfig{Strict: true, ViolationWindow: "soon"}, true, 0},
+		{"NegativeWindow", EscapePolicyConfig{Strict: true, ViolationWindow: "-1s"}, true, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy, err := newEscapePolicy(tt.cfg)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("newEscapePolicy() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && policy.maxLength != tt.wantMax {
+				t.Errorf("maxLength = %d, want %d", policy.maxLength, tt.wantMax)
+			}
+		})
+	}
+}
+
+func TestWebView_EscapeOverflow_RecordsViolation(t *testing.T) {
+	view := newTestView(t)
+
+	if err := view.Render(append(oversizedEscape(40), "ok"...)); err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+
+	stats := view.EscapeStats()
+	if stats.Violations != 1 || stats.Terminated {
+		t.Errorf("EscapeStats = %+v, want one violation without termination", stats)
+	}
+	if events := view.Events().Events(EventEscapeOverflow, 0); len(events) != 1 {
+		t.Errorf("Recorded %d escape events, want 1", len(events))
+	}
+}
+
+func TestWebView_EscapePolicy_CustomLimitAllowsLongerSequences(t *testing.T) {
+	view := newTestView(t)
+	if err := view.SetEscapePolicy(EscapePolicyConfig{MaxSequenceLength: 64}); err != nil {
+		t.Fatalf("SetEscapePolicy failed: %v", err)
+	}
+
+	// 40 bytes: over the default limit but under the configured one
+	seq := "\x1b[" + strings.Repeat("0;", 18) + "1m"
+	if err := view.Render([]byte(seq + "x")); err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	if stats := view.EscapeStats(); stats.Violations != 0 {
+		t.Errorf("Violations = %d, want 0", stats.Violations)
+	}
+	if got := view.GetCurrentState().Buffer[0][0]; got.Char != 'x' || !got.Bold {
+		t.Errorf("Cell = %+v, want bold 'x'", got)
+	}
+}
+
+func TestWebView_EscapePolicy_StrictModeTerminatesSession(t *testing.T) {
+	view := newTestView(t)
+	if err := view.SetEscapePolicy(EscapePolicyConfig{Strict: true, MaxViolations: 3, ViolationWindow: "1m"}); err != nil {
+		t.Fatalf("SetEscapePolicy failed: %v", err)
+	}
+
+	now := time.Unix(1000, 0)
+	view.escapePolicy.now = func() time.Time { return now }
+
+	// Violations spread beyond the window do not accumulate
+	for i := 0; i < 4; i++ {
+		if err := view.Render(oversizedEscape(40)); err != nil {
+			t.Fatalf("Render %d failed: %v", i, err)
+		}
+		now = now.Add(31 * time.Second)
+	}
+	now = now.Add(2 * time.Minute)
+
+	for i := 0; i < 2; i++ {
+		if err := view.Render(oversizedEscape(40)); err != nil {
+			t.Fatalf("Render failed before threshold: %v", err)
+		}
+	}
+	if err := view.Render(oversizedEscape(40)); !errors.Is(err, ErrEscapePolicyViolation) {
+		t.Fatalf("Render = %v, want ErrEscapePolicyViolation", err)
+	}
+	if err := view.Render([]byte("harmless")); !errors.Is(err, ErrEscapePolicyViolation) {
+		t.Errorf("Render after termination = %v, want ErrEscapePolicyViolation", err)
+	}
+	if !view.EscapeStats().Terminated {
+		t.Error("Expected stats to report termination")
+	}
+}
+
+func TestWebUI_AdminSecurity_ReportsViolations(t *testing.T) {
+	webUI := newAdminWebUI(t, WebUIOptions{})
+	webUI.GetView().Render(oversizedEscape(40))
+
+	rec := httptest.NewRecorder()
+	webUI.ServeHTTP(rec, newAdminRequest(http.MethodGet, "/admin/security", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", rec.Code)
+	}
+
+	var body struct {
+		Escape EscapeSecurityStats `json:"escape"`
+		Events []SessionEvent      `json:"events"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("Invalid response: %v", err)
+	}
+	if body.Escape.Violations != 1 || len(body.Events) != 1 {
+		t.Errorf("Response = %+v, want one violation and event", body)
+	}
+
+	rec = httptest.NewRecorder()
+	webUI.ServeHTTP(rec, newAdminRequest(http.MethodGet, "/metrics", nil))
+	if !strings.Contains(rec.Body.String(), `dgconnect_escape_violations_total{session_id="`+webUI.CorrelationID()+`"} 1`) {
+		t.Error("Expected escape violation metric")
+	}
+}
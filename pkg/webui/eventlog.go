@@ -0,0 +1,151 @@
+// Package webui provides a bounded per-session event log for field debugging.
+package webui
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Session event types
+const (
+	EventRender           = "render"
+	EventResize           = "resize"
+	EventClear            = "clear"
+	EventClose            = "close"
+	EventClientConnect    = "client_connect"
+	EventClientDisconnect = "client_disconnect"
+	EventInputDropped     = "input_dropped"
+	EventSlowPoll         = "slow_poll"
+	EventEscapeOverflow   = "escape_overflow"
+	EventExpiryPending    = "expiry_pending"
+	EventExpiryCanceled   = "expiry_canceled"
+	EventExpired          = "expired"
+	EventTeardown         = "teardown"
+	EventReattach         = "reattach"
+	EventQuota            = "quota"
+	EventViewReplaced     = "view_replaced"
+	EventStreamStalled    = "stream_stalled"
+	EventStreamResumed    = "stream_resumed"
+)
+
+// defaultEventLogSize is the number of events kept per session
+const defaultEventLogSize = 512
+
+// renderCoalesceWindow groups bursts of renders into a single event
+const renderCoalesceWindow = time.Second
+
+// SessionEvent is a single entry in the session event log
+type SessionEvent struct {
+	Time    time.Time `json:"time"`
+	Type    string    `json:"type"`
+	Message string    `json:"message,omitempty"`
+	Count   int       `json:"count,omitempty"` // renders coalesced into this event
+	Bytes   int       `json:"bytes,omitempty"` // render payload size
+}
+
+// EventLog is a fixed-size ring of session events. Older events are
+// overwritten once the log is full.
+type EventLog struct {
+	mu     sync.Mutex
+	events []SessionEvent
+	next   int
+	full   bool
+	now    func() time.Time
+}
+
+// NewEventLog creates an event log holding up to size events; zero or
+// negative uses the default
+func NewEventLog(size int) *EventLog {
+	if size <= 0 {
+		size = defaultEventLogSize
+	}
+	return &EventLog{
+		events: make([]SessionEvent, size),
+		now:    time.Now,
+	}
+}
+
+// Record appends an event with a formatted message
+func (l *EventLog) Record(eventType, format string, args ...interface{}) {
+	if l == nil {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.appendLocked(SessionEvent{
+		Time:    l.now(),
+		Type:    eventType,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// RecordRender logs a render of n bytes, merging it into the previous event
+// when renders arrive in quick succession
+func (l *EventLog) RecordRender(n int) {
+	if l == nil {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	if last := l.lastLocked(); last != nil && last.Type == EventRender && now.Sub(last.Time) < renderCoalesceWindow {
+		last.Count++
+		last.Bytes += n
+		return
+	}
+	l.appendLocked(SessionEvent{Time: now, Type: EventRender, Count: 1, Bytes: n})
+}
+
+// Events returns up to limit of the most recent events of the given type,
+// oldest first. An empty eventType matches all events and a limit of zero
+// or less returns everything retained.
+func (l *EventLog) Events(eventType string, limit int) []SessionEvent {
+	if l == nil {
+		return nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	ordered := make([]SessionEvent, 0, len(l.events))
+	if l.full {
+		ordered = append(ordered, l.events[l.next:]...)
+	}
+	ordered = append(ordered, l.events[:l.next]...)
+
+	result := make([]SessionEvent, 0, len(ordered))
+	for _, event := range ordered {
+		if eventType == "" || event.Type == eventType {
+			result = append(result, event)
+		}
+	}
+	if limit > 0 && len(result) > limit {
+		result = result[len(result)-limit:]
+	}
+	return result
+}
+
+// appendLocked writes event at the head of the ring; l.mu must be held
+func (l *EventLog) appendLocked(event SessionEvent) {
+	l.events[l.next] = event
+	l.next++
+	if l.next == len(l.events) {
+		l.next = 0
+		l.full = true
+	}
+}
+
+// lastLocked returns the most recent event; l.mu must be held
+func (l *EventLog) lastLocked() *SessionEvent {
+	switch {
+	case l.next > 0:
+		return &l.events[l.next-1]
+	case l.full:
+		return &l.events[len(l.events)-1]
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package webui
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestEventLog_RingOverwritesOldest(t *testing.T) {
+	log := NewEventLog(3)
+	for i := 0; i < 5; i++ {
+		log.Record(EventResize, "resize %d", i)
+	}
+
+	events := log.Events("", 0)
+	if len(events) != 3 {
+		t.Fatalf("Expected 3 events, got %d", len(events))
+	}
+	for i, want := range []string{"resize 2", "resize 3", "resize 4"} {
+		if events[i].Message != want {
+			t.Errorf("events[%d] = %q, want %q", i, events[i].Message, want)
+		}
+	}
+}
+
+func TestEventLog_FilterAndLimit(t *testing.T) {
+	log := NewEventLog(10)
+	log.Record(EventClientConnect, "a")
+	log.Record(EventInputDropped, "x")
+	log.Record(EventClientConnect, "b")
+	log.Record(EventClientConnect, "c")
+
+	events := log.Events(EventClientConnect, 2)
+	if len(events) != 2 || events[0].Message != "b" || events[1].Message != "c" {
+		t.Errorf("Unexpected filtered events %+v", events)
+	}
+}
+
+func TestEventLog_RecordRender_Coalesces(t *testing.T) {
+	log := NewEventLog(10)
+	now := time.Unix(100, 0)
+	log.now = func() time.Time { return now }
+
+	log.RecordRender(10)
+	log.RecordRender(5)
+	now = now.Add(2 * time.Second)
+	log.RecordRender(1)
+
+	events := log.Events(EventRender, 0)
+	if len(events) != 2 {
+		t.Fatalf("Expected 2 render events, got %d", len(events))
+	}
+	if events[0].Count != 2 || events[0].Bytes != 15 {
+		t.Errorf("First event = %+v, want count 2 and 15 bytes", events[0])
+	}
+}
+
+func TestEventLog_NilIsNoop(t *testing.T) {
+	var log *EventLog
+	log.Record(EventClose, "ignored")
+	log.RecordRender(1)
+	if events := log.Events("", 0); events != nil {
+		t.Errorf("Expected nil events, got %+v", events)
+	}
+}
+
+func TestWebUI_AdminEvents(t *testing.T) {
+	webUI := newAdminWebUI(t, WebUIOptions{})
+	view := webUI.GetView()
+	view.Render([]byte("hello"))
+	view.SetSize(100, 30)
+
+	rec := httptest.NewRecorder()
+	webUI.ServeHTTP(rec, newAdminRequest(http.MethodGet, "/admin/events?type=resize", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", rec.Code)
+	}
+
+	var body struct {
+		Events []SessionEvent `json:"events"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(body.Events) != 1 || body.Events[0].Message != "80x24 -> 100x30" {
+		t.Errorf("Unexpected events %+v", body.Events)
+	}
+
+	rec = httptest.NewRecorder()
+	webUI.ServeHTTP(rec, newAdminRequest(http.MethodGet, "/admin/events?limit=-1", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want 400 for invalid limit", rec.Code)
+	}
+}
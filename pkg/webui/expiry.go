@@ -0,0 +1,361 @@
+// Package webui provides session expiry with advance notice: before an idle
+// timeout or an admin kill ends the session, clients are sent a countdown
+// through the diff stream, and a keystroke may call the expiry off.
+package webui
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/opd-ai/go-gamelaunch-www/pkg/transport"
+)
+
+// Reasons a session expires
+const (
+	ExpiryIdle     = "idle"
+	ExpiryAdmin    = "admin"
+	ExpiryDeparted = "departed" // see TeardownConfig
+	ExpiryDetached = "detached" // see DetachConfig
+)
+
+// defaultExpiryWarning is how long clients are warned before a session
+// expires
+const defaultExpiryWarning = time.Minute
+
+// ExpiryConfig ends the session once no input has reached the game for
+// Idle. Clients are warned Warning before, and any keystroke in between
+// keeps the session.
+type ExpiryConfig struct {
+	Idle    string `yaml:"idle" yamlcheck:"duration"`              // e.g. "30m"
+	Warning string `yaml:"warning,omitempty" yamlcheck:"duration"` // default "60s"
+}
+
+// SessionExpiry announces that the session ends at Deadline unless, when
+// Cancelable, a keystroke reaches the game first
+type SessionExpiry struct {
+	Reason     string `json:"reason"`   // idle, admin, departed or detached
+	Deadline   int64  `json:"deadline"` // Unix milliseconds, like GameState.Timestamp
+	Message    string `json:"message,omitempty"`
+	Cancelable bool   `json:"cancelable"`
+}
+
+// expiryTimer tracks when the session last had input and the expiry
+// pending, if any
+type expiryTimer struct {
+	idle    time.Duration // 0 disables the idle timeout
+	warning time.Duration
+
+	mu        sync.Mutex
+	lastInput time.Time
+	pending   *SessionExpiry
+	reason    string        // of the expiry, once expired is closed
+	wake      chan struct{} // the deadlines changed
+	expired   chan struct{}
+	now       func() time.Time
+}
+
+// newExpiryTimer validates cfg; a nil cfg disables the idle timeout but
+// still allows admin kills
+func newExpiryTimer(cfg *ExpiryConfig) (*expiryTimer, error) {
+	e := &expiryTimer{
+		warning: defaultExpiryWarning,
+		wake:    make(chan struct{}, 1),
+		expired: make(chan struct{}),
+		now:     time.Now,
+	}
+	e.lastInput = e.now()
+	if cfg == nil {
+		return e, nil
+	}
+
+	idle, err := time.ParseDuration(cfg.Idle)
+	if err != nil || idle <= 0 {
+		return nil, fmt.Errorf("expiry: invalid idle %q", cfg.Idle)
+	}
+	e.idle = idle
+	if cfg.Warning != "" {
+		warning, err := time.ParseDuration(cfg.Warning)
+		if err != nil || warning <= 0 {
+			return nil, fmt.Errorf("expiry: invalid warning %q", cfg.Warning)
+		}
+		e.warning = warning
+	}
+	if e.warning >= e.idle {
+		return nil, fmt.Errorf("expiry: warning %s must be shorter than idle %s", e.warning, e.idle)
+	}
+	return e, nil
+}
+
+// notify wakes run to recompute its deadline
+func (e *expiryTimer) notify() {
+	select {
+	case e.wake <- struct{}{}:
+	default:
+	}
+}
+
+// next returns when the timer must next be checked, or false when nothing
+// is due
+func (e *expiryTimer) next() (time.Time, bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	switch {
+	case e.reason != "":
+		return time.Time{}, false
+	case e.pending != nil:
+		return time.UnixMilli(e.pending.Deadline), true
+	case e.idle > 0:
+		return e.lastInput.Add(e.idle - e.warning), true
+	}
+	return time.Time{}, false
+}
+
+// check starts the idle warning once it is due and expires the session at
+// the deadline. It returns the expiry to show when that changed.
+func (e *expiryTimer) check() (expiry *SessionExpiry, changed, expired bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	now := e.now()
+	switch {
+	case e.reason != "":
+		return nil, false, false
+	case e.pending != nil:
+		if now.UnixMilli() < e.pending.Deadline {
+			return nil, false, false
+		}
+		e.reason = e.pending.Reason
+		close(e.expired)
+		return e.pending, false, true
+	case e.idle > 0 && !now.Before(e.lastInput.Add(e.idle-e.warning)):
+		e.pending = &SessionExpiry{
+			Reason:     ExpiryIdle,
+			Deadline:   e.lastInput.Add(e.idle).UnixMilli(),
+			Cancelable: true,
+		}
+		return e.pending, true, false
+	}
+	return nil, false, false
+}
+
+// input records a keystroke, reporting whether it called off the pending
+// expiry
+func (e *expiryTimer) input() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.lastInput = e.now()
+	if e.pending == nil || !e.pending.Cancelable || e.reason != "" {
+		return false
+	}
+	e.pending = nil
+	e.notify()
+	return true
+}
+
+// schedule replaces the pending expiry; it fails once the session expired
+func (e *expiryTimer) schedule(expiry *SessionExpiry) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.reason != "" {
+		return fmt.Errorf("session already expired")
+	}
+	e.pending = expiry
+	e.notify()
+	return nil
+}
+
+// cancel drops the pending expiry, reporting whether there was one
+func (e *expiryTimer) cancel() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.pending == nil || e.reason != "" {
+		return false
+	}
+	e.pending = nil
+	e.lastInput = e.now()
+	e.notify()
+	return true
+}
+
+// cancelReason drops the pending expiry if it is for reason, reporting
+// whether it did
+func (e *expiryTimer) cancelReason(reason string) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.pending == nil || e.pending.Reason != reason || e.reason != "" {
+		return false
+	}
+	e.pending = nil
+	e.notify()
+	return true
+}
+
+// current returns the pending expiry, or nil
+func (e *expiryTimer) current() *SessionExpiry {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.pending
+}
+
+// run warns clients and expires the session as the deadlines pass
+func (e *expiryTimer) run(ctx context.Context, w *WebUI) {
+	timer := time.NewTimer(time.Hour)
+	defer timer.Stop()
+	for {
+		timer.Stop()
+		if at, ok := e.next(); ok {
+			timer.Reset(max(at.Sub(e.now()), 0))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-e.expired:
+			return
+		case <-e.wake:
+		case <-timer.C:
+			expiry, changed, expired := e.check()
+			if changed {
+				w.showExpiry(expiry)
+			}
+			if expired {
+				w.GetView().Events().Record(EventExpired, "session expired (%s)", expiry.Reason)
+				w.log.Info("webui: session expired", "reason", expiry.Reason)
+			}
+		}
+	}
+}
+
+// showExpiry publishes expiry, or its cancellation when nil, to clients
+func (w *WebUI) showExpiry(expiry *SessionExpiry) {
+	view := w.GetView()
+	if view == nil {
+		return
+	}
+	if expiry != nil {
+		view.Events().Record(EventExpiryPending, "%s expiry at %s", expiry.Reason, time.UnixMilli(expiry.Deadline).UTC().Format(time.RFC3339))
+	} else {
+		view.Events().Record(EventExpiryCanceled, "expiry canceled")
+	}
+	view.SetExpiry(expiry)
+}
+
+// noteInput records that input reached the game, calling off an expiry
+// that a keystroke may cancel
+func (w *WebUI) noteInput() {
+	if w.expiry.input() {
+		w.showExpiry(nil)
+	}
+}
+
+// ExpireSession ends the session after grace, warning clients with message
+// in the meantime. A cancelable expiry is called off by the next keystroke.
+// It replaces any expiry already pending.
+func (w *WebUI) ExpireSession(grace time.Duration, message string, cancelable bool) (*SessionExpiry, error) {
+	return w.scheduleExpiry(ExpiryAdmin, grace, message, cancelable)
+}
+
+// scheduleExpiry ends the session for reason after grace
+func (w *WebUI) scheduleExpiry(reason string, grace time.Duration, message string, cancelable bool) (*SessionExpiry, error) {
+	if grace < 0 {
+		return nil, fmt.Errorf("invalid grace period %s", grace)
+	}
+	expiry := &SessionExpiry{
+		Reason:     reason,
+		Deadline:   w.expiry.now().Add(grace).UnixMilli(),
+		Message:    message,
+		Cancelable: cancelable,
+	}
+	if err := w.expiry.schedule(expiry); err != nil {
+		return nil, err
+	}
+	w.showExpiry(expiry)
+	return expiry, nil
+}
+
+// CancelExpiry calls off the pending expiry, reporting whether there was
+// one
+func (w *WebUI) CancelExpiry() bool {
+	if !w.expiry.cancel() {
+		return false
+	}
+	w.showExpiry(nil)
+	return true
+}
+
+// Expired returns a channel that is closed when the session expires. The
+// caller ends the game session in response, e.g. by cancelling the
+// context of the game client.
+func (w *WebUI) Expired() <-chan struct{} {
+	return w.expiry.expired
+}
+
+// SetExpiry shows expiry to clients with the next state, or stops showing
+// it when nil
+func (v *WebView) SetExpiry(expiry *SessionExpiry) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.closed || v.expiry == expiry {
+		return
+	}
+	v.expiry = expiry
+	v.publishState()
+}
+
+// toWireExpiry converts an expiry to its wire representation
+func toWireExpiry(expiry *SessionExpiry) *transport.Expiry {
+	if expiry == nil {
+		return nil
+	}
+	return &transport.Expiry{
+		Reason:     expiry.Reason,
+		Deadline:   expiry.Deadline,
+		Message:    expiry.Message,
+		Cancelable: expiry.Cancelable,
+	}
+}
+
+// ExpiryParams are the parameters of POST /admin/expiry
+type ExpiryParams struct {
+	Grace      string `json:"grace,omitempty"` // default "60s"
+	Message    string `json:"message,omitempty"`
+	Cancelable bool   `json:"cancelable,omitempty"`
+}
+
+// handleAdminExpiry handles GET /admin/expiry, returning the pending
+// expiry, POST, scheduling an admin kill, and DELETE, calling it off
+func (w *WebUI) handleAdminExpiry(rw http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(rw, http.StatusOK, map[string]interface{}{"expiry": w.expiry.current()})
+	case http.MethodPost:
+		var params ExpiryParams
+		if err := json.NewDecoder(http.MaxBytesReader(rw, r.Body, 16384)).Decode(&params); err != nil {
+			http.Error(rw, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		grace := defaultExpiryWarning
+		if params.Grace != "" {
+			parsed, err := time.ParseDuration(params.Grace)
+			if err != nil || parsed < 0 {
+				http.Error(rw, "Invalid grace period", http.StatusBadRequest)
+				return
+			}
+			grace = parsed
+		}
+		expiry, err := w.ExpireSession(grace, params.Message, params.Cancelable)
+		if err != nil {
+			http.Error(rw, err.Error(), http.StatusConflict)
+			return
+		}
+		w.logger(r.Context()).Info("webui: session expiry scheduled", "grace", grace)
+		writeJSON(rw, http.StatusOK, map[string]interface{}{"expiry": expiry})
+	case http.MethodDelete:
+		writeJSON(rw, http.StatusOK, map[string]interface{}{"canceled": w.CancelExpiry()})
+	default:
+		rw.Header().Set("Allow", "GET, POST, DELETE")
+		http.Error(rw, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,141 @@
+package webui
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExpiryTimer_IdleCountdown(t *testing.T) {
+	e, err := newExpiryTimer(&ExpiryConfig{Idle: "10m", Warning: "1m"})
+	if err != nil {
+		t.Fatalf("newExpiryTimer failed: %v", err)
+	}
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	now := start
+	e.now = func() time.Time { return now }
+	e.lastInput = start
+
+	if at, ok := e.next(); !ok || !at.Equal(start.Add(9*time.Minute)) {
+		t.Errorf("next() = %v, %v, want the warning at 9m", at, ok)
+	}
+	now = start.Add(8 * time.Minute)
+	if _, changed, expired := e.check(); changed || expired {
+		t.Errorf("check() before the warning = %v, %v", changed, expired)
+	}
+
+	// The warning counts down to the idle deadline; a keystroke calls it off
+	now = start.Add(9 * time.Minute)
+	expiry, changed, _ := e.check()
+	if !changed || expiry.Reason != ExpiryIdle || !expiry.Cancelable || expiry.Deadline != start.Add(10*time.Minute).UnixMilli() {
+		t.Fatalf("check() at the warning = %+v, %v", expiry, changed)
+	}
+	if !e.input() || e.current() != nil {
+		t.Errorf("input() did not cancel the idle expiry")
+	}
+
+	// Without input the session expires at the deadline
+	now = start.Add(18 * time.Minute)
+	e.check()
+	now = start.Add(19 * time.Minute)
+	if _, _, expired := e.check(); !expired {
+		t.Fatalf("check() at the deadline did not expire")
+	}
+	select {
+	case <-e.expired:
+	default:
+		t.Errorf("expired channel not closed")
+	}
+	if e.input() || e.schedule(&SessionExpiry{Reason: ExpiryAdmin}) == nil {
+		t.Errorf("expired timer accepted a change")
+	}
+
+	for _, cfg := range []ExpiryConfig{{Idle: ""}, {Idle: "-1m"}, {Idle: "1m", Warning: "x"}, {Idle: "1m", Warning: "2m"}} {
+		if _, err := newExpiryTimer(&cfg); err == nil {
+			t.Errorf("newExpiryTimer(%+v) accepted an invalid config", cfg)
+		}
+	}
+}
+
+func TestWebUI_AdminExpiry(t *testing.T) {
+	ui := newAdminWebUI(t, WebUIOptions{})
+	var sent []string
+	ui.options.InputSink = func(data []byte) error {
+		sent = append(sent, string(data))
+		return nil
+	}
+
+	rec := httptest.NewRecorder()
+	body := `{"grace":"30s","message":"Server restarting"}`
+	ui.ServeHTTP(rec, newAdminRequest(http.MethodPost, "/admin/expiry", strings.NewReader(body)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("POST /admin/expiry = %d: %s", rec.Code, rec.Body.String())
+	}
+
+	// The countdown reaches clients with the state and its diffs
+	state := ui.GetView().GetStateManager().GetCurrentState()
+	if state.Expiry == nil || state.Expiry.Reason != ExpiryAdmin || state.Expiry.Message != "Server restarting" {
+		t.Fatalf("state expiry = %+v, want the admin kill", state.Expiry)
+	}
+	if payload := toStatePayload(state); payload.Expiry == nil || payload.Expiry.Deadline != state.Expiry.Deadline {
+		t.Errorf("payload expiry = %+v, want %+v", payload.Expiry, state.Expiry)
+	}
+	if err := ui.GetView().Render([]byte("x")); err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	diff, err := ui.GetView().GetStateManager().PollChanges(state.Version, time.Second)
+	if err != nil || diff.Expiry != state.Expiry {
+		t.Errorf("diff expiry = %+v, %v, want %+v", diff.Expiry, err, state.Expiry)
+	}
+
+	// A keystroke does not call off an admin kill that is not cancelable
+	if err := ui.sendGameInput([]byte("a")); err != nil {
+		t.Fatalf("sendGameInput failed: %v", err)
+	}
+	if ui.GetView().GetStateManager().GetCurrentState().Expiry == nil {
+		t.Errorf("keystroke canceled an admin kill")
+	}
+
+	rec = httptest.NewRecorder()
+	ui.ServeHTTP(rec, newAdminRequest(http.MethodDelete, "/admin/expiry", nil))
+	var result struct {
+		Canceled bool `json:"canceled"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &result); err != nil || !result.Canceled {
+		t.Errorf("DELETE /admin/expiry = %s", rec.Body.String())
+	}
+	if ui.GetView().GetStateManager().GetCurrentState().Expiry != nil {
+		t.Errorf("state still shows the canceled expiry")
+	}
+
+	// A cancelable one is called off by the next keystroke
+	if _, err := ui.ExpireSession(time.Minute, "", true); err != nil {
+		t.Fatalf("ExpireSession failed: %v", err)
+	}
+	if err := ui.sendGameInput([]byte("b")); err != nil {
+		t.Fatalf("sendGameInput failed: %v", err)
+	}
+	if ui.GetView().GetStateManager().GetCurrentState().Expiry != nil {
+		t.Errorf("keystroke did not cancel the expiry")
+	}
+
+	// A kill without grace expires the session at once
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go ui.expiry.run(ctx, ui)
+	if _, err := ui.ExpireSession(0, "", false); err != nil {
+		t.Fatalf("ExpireSession failed: %v", err)
+	}
+	select {
+	case <-ui.Expired():
+	case <-time.After(5 * time.Second):
+		t.Fatal("session did not expire")
+	}
+	if strings.Join(sent, "") != "ab" {
+		t.Errorf("game input = %q, want the keystrokes", sent)
+	}
+}
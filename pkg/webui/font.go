@@ -0,0 +1,165 @@
+// Package webui provides a bundled webfont and the cell size clients should
+// render text at, so text mode lines up with the tileset.
+package webui
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Cell size recommended when neither a tileset nor the font configuration
+// sets one, matching the default tileset
+const (
+	defaultCellWidth  = 8
+	defaultCellHeight = 16
+)
+
+// fontTypes maps the supported font file extensions to their content types
+var fontTypes = map[string]string{
+	".woff2": "font/woff2",
+	".woff":  "font/woff",
+	".ttf":   "font/ttf",
+	".otf":   "font/otf",
+}
+
+// FontConfig bundles a monospace webfont with the server, served at /font,
+// and sets the cell size used when no tileset is loaded
+type FontConfig struct {
+	File       string `yaml:"file,omitempty"`        // .woff2, .woff, .ttf or .otf
+	Family     string `yaml:"family,omitempty"`      // CSS font-family, default the file name without extension
+	Size       int    `yaml:"size,omitempty"`        // pixel size, default the cell height
+	CellWidth  int    `yaml:"cell_width,omitempty"`  // default 8
+	CellHeight int    `yaml:"cell_height,omitempty"` // default 16
+}
+
+// FontMetrics is the response of GET /font/metrics. Clients rendering text
+// draw each cell as CellWidth x CellHeight pixels, which is the tile size
+// while a tileset is loaded.
+type FontMetrics struct {
+	Family     string `json:"family,omitempty"`
+	URL        string `json:"url,omitempty"` // versioned, may be cached forever
+	Format     string `json:"format,omitempty"`
+	Size       int    `json:"size"`
+	CellWidth  int    `json:"cell_width"`
+	CellHeight int    `json:"cell_height"`
+	Tileset    bool   `json:"tileset"` // cell size taken from the tileset
+}
+
+// bundledFont is a loaded FontConfig
+type bundledFont struct {
+	family      string
+	contentType string
+	data        []byte // nil when no file is bundled
+	version     string // content hash
+	modTime     time.Time
+	size        int
+	cellW       int
+	cellH       int
+}
+
+// newBundledFont validates cfg and loads its font file
+func newBundledFont(cfg FontConfig) (*bundledFont, error) {
+	f := &bundledFont{family: cfg.Family, size: cfg.Size, cellW: defaultCellWidth, cellH: defaultCellHeight}
+	if cfg.CellWidth < 0 || cfg.CellWidth > 256 {
+		return nil, fmt.Errorf("font: invalid cell_width %d", cfg.CellWidth)
+	}
+	if cfg.CellHeight < 0 || cfg.CellHeight > 256 {
+		return nil, fmt.Errorf("font: invalid cell_height %d", cfg.CellHeight)
+	}
+	if cfg.Size < 0 || cfg.Size > 256 {
+		return nil, fmt.Errorf("font: invalid size %d", cfg.Size)
+	}
+	if cfg.CellWidth > 0 {
+		f.cellW = cfg.CellWidth
+	}
+	if cfg.CellHeight > 0 {
+		f.cellH = cfg.CellHeight
+	}
+
+	if cfg.File == "" {
+		return f, nil
+	}
+	ext := strings.ToLower(filepath.Ext(cfg.File))
+	contentType, ok := fontTypes[ext]
+	if !ok {
+		return nil, fmt.Errorf("font: unsupported file type %q, use .woff2, .woff, .ttf or .otf", ext)
+	}
+	info, err := os.Stat(cfg.File)
+	if err != nil {
+		return nil, fmt.Errorf("font: %w", err)
+	}
+	data, err := os.ReadFile(cfg.File)
+	if err != nil {
+		return nil, fmt.Errorf("font: %w", err)
+	}
+	sum := sha256.Sum256(data)
+
+	f.contentType = contentType
+	f.data = data
+	f.version = hex.EncodeToString(sum[:8])
+	f.modTime = info.ModTime()
+	if f.family == "" {
+		f.family = strings.TrimSuffix(filepath.Base(cfg.File), filepath.Ext(cfg.File))
+	}
+	return f, nil
+}
+
+// metrics returns the font and the cell size for tileset, which may be nil
+func (f *bundledFont) metrics(tileset *TilesetConfig) FontMetrics {
+	m := FontMetrics{Family: f.family, CellWidth: f.cellW, CellHeight: f.cellH}
+	if tileset != nil && tileset.TileWidth > 0 && tileset.TileHeight > 0 {
+		m.CellWidth, m.CellHeight, m.Tileset = tileset.TileWidth, tileset.TileHeight, true
+	}
+	m.Size = f.size
+	if m.Size == 0 {
+		m.Size = m.CellHeight
+	}
+	if f.data != nil {
+		m.URL = "/font?v=" + f.version
+		m.Format = strings.TrimPrefix(f.contentType, "font/")
+	}
+	return m
+}
+
+// handleFont serves the bundled font. Requests for the current version (see
+// FontMetrics.URL) may be cached forever; others revalidate with the ETag.
+func (w *WebUI) handleFont(rw http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		rw.Header().Set("Allow", "GET, HEAD")
+		http.Error(rw, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if w.font.data == nil {
+		http.NotFound(rw, r)
+		return
+	}
+
+	if r.URL.Query().Get("v") == w.font.version {
+		w.setCacheControl(rw, r, w.cacheVisibility()+", "+immutableMaxAge)
+	} else {
+		w.setCacheControl(rw, r, w.cacheVisibility()+", no-cache")
+	}
+	rw.Header().Set("Content-Type", w.font.contentType)
+	rw.Header().Set("ETag", `"`+w.font.version+`"`)
+	http.ServeContent(rw, r, "", w.font.modTime, bytes.NewReader(w.font.data))
+}
+
+// handleFontMetrics serves GET /font/metrics, the bundled font and the cell
+// size clients should render text at
+func (w *WebUI) handleFontMetrics(rw http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		rw.Header().Set("Allow", http.MethodGet)
+		http.Error(rw, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.setCacheControl(rw, r, "no-cache")
+	writeJSON(rw, http.StatusOK, w.font.metrics(w.GetTileset()))
+}
@@ -0,0 +1,114 @@
+package webui
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeTestFont writes fake font data to a file called name
+func writeTestFont(t *testing.T, name string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte("wOF2 test font data"), 0o644); err != nil {
+		t.Fatalf("Failed to write font: %v", err)
+	}
+	return path
+}
+
+func TestNewBundledFont_Validation(t *testing.T) {
+	font := writeTestFont(t, "Terminus.woff2")
+
+	tests := []struct {
+		name    string
+		cfg     FontConfig
+		wantErr bool
+	}{
+		{"Empty", FontConfig{}, false},
+		{"File", FontConfig{File: font}, false},
+		{"CellSize", FontConfig{CellWidth: 10, CellHeight: 20, Size: 18}, false},
+		{"MissingFile", FontConfig{File: filepath.Join(t.TempDir(), "missing.woff2")}, true},
+		{"UnsupportedType", FontConfig{File: writeTestFont(t, "font.svg")}, true},
+		{"NegativeWidth", FontConfig{CellWidth: -1}, true},
+		{"HugeHeight", FontConfig{CellHeight: 1000}, true},
+		{"NegativeSize", FontConfig{Size: -2}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newBundledFont(tt.cfg)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("newBundledFont() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestWebUI_HandleFontMetrics_FollowsTileset(t *testing.T) {
+	ui, err := NewWebUI(WebUIOptions{View: newTestView(t), Font: &FontConfig{File: writeTestFont(t, "Terminus.woff2"), CellWidth: 10, CellHeight: 20}})
+	if err != nil {
+		t.Fatalf("Failed to create WebUI: %v", err)
+	}
+
+	metrics := func() FontMetrics {
+		rec := httptest.NewRecorder()
+		ui.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/font/metrics", nil))
+		var m FontMetrics
+		if err := json.Unmarshal(rec.Body.Bytes(), &m); err != nil {
+			t.Fatalf("invalid response: %v", err)
+		}
+		return m
+	}
+
+	m := metrics()
+	if m.Family != "Terminus" || m.Format != "woff2" || !strings.HasPrefix(m.URL, "/font?v=") {
+		t.Errorf("metrics = %+v, want the bundled Terminus woff2", m)
+	}
+	if m.CellWidth != 10 || m.CellHeight != 20 || m.Size != 20 || m.Tileset {
+		t.Errorf("metrics = %+v, want the configured 10x20 cells", m)
+	}
+
+	tileset := DefaultTilesetConfig()
+	tileset.TileWidth, tileset.TileHeight = 12, 24
+	if err := ui.UpdateTileset(tileset); err != nil {
+		t.Fatalf("UpdateTileset failed: %v", err)
+	}
+	if m := metrics(); m.CellWidth != 12 || m.CellHeight != 24 || m.Size != 24 || !m.Tileset {
+		t.Errorf("metrics = %+v, want the tileset's 12x24 cells", m)
+	}
+}
+
+func TestWebUI_HandleFont_Caching(t *testing.T) {
+	ui, err := NewWebUI(WebUIOptions{View: newTestView(t), Font: &FontConfig{File: writeTestFont(t, "Terminus.woff2")}})
+	if err != nil {
+		t.Fatalf("Failed to create WebUI: %v", err)
+	}
+	url := ui.font.metrics(nil).URL
+
+	rec := httptest.NewRecorder()
+	ui.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, url, nil))
+	if rec.Code != http.StatusOK || rec.Body.String() != "wOF2 test font data" {
+		t.Fatalf("GET %s = %d %q", url, rec.Code, rec.Body.String())
+	}
+	if got := rec.Header().Get("Content-Type"); got != "font/woff2" {
+		t.Errorf("Content-Type = %q, want font/woff2", got)
+	}
+	if got := rec.Header().Get("Cache-Control"); !strings.Contains(got, "immutable") {
+		t.Errorf("versioned Cache-Control = %q, want immutable", got)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/font", nil)
+	req.Header.Set("If-None-Match", rec.Header().Get("ETag"))
+	rec = httptest.NewRecorder()
+	ui.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNotModified {
+		t.Errorf("revalidation status = %d, want %d", rec.Code, http.StatusNotModified)
+	}
+	if got := rec.Header().Get("Cache-Control"); strings.Contains(got, "immutable") {
+		t.Errorf("unversioned Cache-Control = %q, want revalidation", got)
+	}
+}
@@ -0,0 +1,168 @@
+package webui
+
+// Fuzz targets for the parsers of untrusted remote data: the terminal output
+// of the game server and the input of web clients. Run one with, e.g.
+//
+//	go test -run '^$' -fuzz FuzzWebView_Render -fuzztime 1m ./pkg/webui
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/opd-ai/go-gamelaunch-www/pkg/ttyrec"
+)
+
+// checkScreen fails t unless the view's buffer and cursor are consistent
+// with its size
+func checkScreen(t *testing.T, v *WebView) {
+	t.Helper()
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	if len(v.buffer) != v.height {
+		t.Fatalf("buffer has %d rows, height is %d", len(v.buffer), v.height)
+	}
+	for y, row := range v.buffer {
+		if len(row) != v.width {
+			t.Fatalf("row %d has %d cells, width is %d", y, len(row), v.width)
+		}
+	}
+	if v.cursorX < 0 || v.cursorX > v.width || v.cursorY < 0 || v.cursorY >= v.height {
+		t.Fatalf("cursor %d,%d outside %dx%d", v.cursorX, v.cursorY, v.width, v.height)
+	}
+	if v.scrollTop < 0 || v.scrollBottom >= v.height || v.scrollTop > v.scrollBottom {
+		t.Fatalf("scroll region %d-%d outside height %d", v.scrollTop, v.scrollBottom, v.height)
+	}
+}
+
+// FuzzWebView_Render feeds arbitrary game output through processTerminalData,
+// seeded with the golden captures
+func FuzzWebView_Render(f *testing.F) {
+	for _, seed := range []string{
+		"\x1b[H\x1b[2JHello\r\n\x1b[1;31mred\x1b[0m",
+		"\x1b[5;10r\x1b[10;1H\n\n\x1bM\x1b[r",
+		"\x1b7\x1b[99;99H\x1b8\x1b[?25l\x1b[?1049h",
+		"\x1b[38;2;1;2;3;48;5;300m\x1b[4:3;58:2::1:2:3m",
+		"e\xcc\x81\xf0\x9f\x91\x8d\xe6\x97\xa5\xff\xfe",
+		"\x1b[3L\x1b[2M\x1b[4@\x1b[9P\x1b[5X\x1b[2S\x1b[T\x1b[?7l\x1b#8",
+		"\x1b(0lqk\x0e\x1b)0x\x0f\x1b]0;title\x07\x1bPq\x1b\\\x1b[1\x18",
+	} {
+		f.Add([]byte(seed))
+	}
+	captures, _ := filepath.Glob(filepath.Join("testdata", "golden", "*.ttyrec"))
+	for _, capture := range captures {
+		data, err := os.ReadFile(capture)
+		if err != nil {
+			f.Fatal(err)
+		}
+		frames, err := ttyrec.ReadAll(bytes.NewReader(data))
+		if err != nil {
+			f.Fatal(err)
+		}
+		for _, frame := range frames[:min(len(frames), 5)] {
+			f.Add(frame.Data)
+		}
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		view := newTestView(t)
+		if err := view.Render(data); err != nil {
+			t.Fatalf("Render failed: %v", err)
+		}
+		checkScreen(t, view)
+		state := view.GetCurrentState()
+		if state == nil || len(state.Buffer) != state.Height {
+			t.Fatalf("inconsistent state %+v", state)
+		}
+
+		// Output split across reads shows the same, whatever it cuts apart
+		split := newTestView(t)
+		for _, part := range [][]byte{data[:len(data)/2], data[len(data)/2:]} {
+			if err := split.Render(part); err != nil {
+				t.Fatalf("Render failed: %v", err)
+			}
+		}
+		got := split.GetCurrentState()
+		for y := range state.Buffer {
+			if want, got := rowGraphemes(state, y, state.Width), rowGraphemes(got, y, got.Width); !slices.Equal(got, want) {
+				t.Fatalf("row %d = %q when split, want %q", y, got, want)
+			}
+		}
+	})
+}
+
+// FuzzWebView_HandleCSISequence feeds arbitrary parameters and final bytes
+// to handleCSISequence, as processEscapeSequence would pass them
+func FuzzWebView_HandleCSISequence(f *testing.F) {
+	for _, seed := range []struct {
+		params string
+		final  byte
+	}{
+		{"", 'm'}, {"1;31;48;5;22", 'm'}, {"38;2;255;0", 'm'}, {"4:3", 'm'},
+		{"10;20", 'H'}, {"0;0", 'f'}, {"-5;-5", 'H'}, {";", 'H'},
+		{"2", 'J'}, {"1", 'K'}, {"99999999999999999999", 'A'}, {"3", 'D'},
+		{"5;2", 'r'}, {"30;10", 'r'}, {"?1049", 'h'}, {"?25;1", 'l'},
+	} {
+		f.Add(seed.params, seed.final)
+	}
+
+	f.Fuzz(func(t *testing.T, params string, final byte) {
+		view := newTestView(t)
+		view.mu.Lock()
+		view.cursorX, view.cursorY = 79, 23
+		view.handleCSISequence("\x1b[" + params + string(final))
+		view.handleCSISequence(params) // malformed, must be ignored
+		view.mu.Unlock()
+		checkScreen(t, view)
+
+		// The screen must stay writable afterwards
+		if err := view.Render([]byte("x\r\n\x1b[Ky")); err != nil {
+			t.Fatalf("Render failed: %v", err)
+		}
+		checkScreen(t, view)
+	})
+}
+
+// FuzzWebUI_HandleInput feeds arbitrary request bodies to POST /input
+func FuzzWebUI_HandleInput(f *testing.F) {
+	for _, seed := range []string{
+		`{"client":"c1","seq":1,"events":[{"type":"key","key":"h"},{"type":"key","key":"Enter"}]}`,
+		`{"client":"c1","seq":2,"events":[{"type":"text","data":"\u0000\u001b[200~"}]}`,
+		`{"client":"c1","seq":18446744073709551615,"events":[{"key":"F13"}]}`,
+		`{"client":"","seq":0}`,
+		`{"client":"c1","seq":1,"events":null}`,
+		`{"events":[{}]}`,
+		`[`,
+	} {
+		f.Add([]byte(seed))
+	}
+
+	f.Fuzz(func(t *testing.T, body []byte) {
+		var sent [][]byte
+		ui, err := NewWebUI(WebUIOptions{
+			View: newTestView(t),
+			InputSink: func(data []byte) error {
+				sent = append(sent, data)
+				return nil
+			},
+		})
+		if err != nil {
+			t.Fatalf("Failed to create WebUI: %v", err)
+		}
+
+		rr := httptest.NewRecorder()
+		ui.ServeHTTP(rr, httptest.NewRequest("POST", "/input", bytes.NewReader(body)))
+		switch {
+		case rr.Code >= http.StatusInternalServerError:
+			t.Fatalf("status = %d: %s", rr.Code, rr.Body.String())
+		case rr.Code != http.StatusOK && len(sent) > 0:
+			t.Fatalf("input %q sent for a rejected batch (status %d)", sent, rr.Code)
+		case len(sent) > 1:
+			t.Fatalf("one batch sent %d times", len(sent))
+		}
+	})
+}
@@ -0,0 +1,109 @@
+// Package webui provides detection of the games offered by the dgamelaunch
+// main menu.
+package webui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/opd-ai/go-gamelaunch-client/pkg/dgclient"
+	"golang.org/x/crypto/ssh"
+)
+
+// gameEntryPattern matches a "p) Play NetHack 3.6.7" line of the main menu
+var gameEntryPattern = regexp.MustCompile(`(?i)^\s*([a-z0-9])\)\s+play\s+(\S.*?)\s*$`)
+
+// ParseGameMenu returns the games offered by a screen showing the
+// dgamelaunch main menu. Command is the menu key that starts the game, Name
+// the first word of its title in lower case. A screen without game entries,
+// e.g. the menu shown before logging in, yields none.
+func ParseGameMenu(lines []string) []dgclient.GameInfo {
+	var games []dgclient.GameInfo
+	for _, line := range lines {
+		m := gameEntryPattern.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		games = append(games, dgclient.GameInfo{
+			Name:        strings.ToLower(strings.Fields(m[2])[0]),
+			Description: m[2],
+			Command:     m[1],
+			Available:   true,
+		})
+	}
+	return games
+}
+
+// ProbeGameMenu starts a shell on client with the terminal of opts, waits
+// until the dgamelaunch main menu lists games and stops changing, and
+// returns them. It gives up when ctx is done first.
+func ProbeGameMenu(ctx context.Context, client *ssh.Client, opts dgclient.ViewOptions) ([]dgclient.GameInfo, error) {
+	view, err := NewWebView(opts)
+	if err != nil {
+		return nil, err
+	}
+	defer view.Close()
+
+	session, err := client.NewSession()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open session: %w", err)
+	}
+	defer session.Close()
+	if err := session.RequestPty(opts.TerminalType, opts.InitialHeight, opts.InitialWidth, ssh.TerminalModes{}); err != nil {
+		return nil, fmt.Errorf("failed to request terminal: %w", err)
+	}
+	stdout, err := session.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read session: %w", err)
+	}
+	if err := session.Shell(); err != nil {
+		return nil, fmt.Errorf("failed to start shell: %w", err)
+	}
+
+	go func() {
+		buf := make([]byte, 4096)
+		for {
+			n, err := stdout.Read(buf)
+			if n > 0 {
+				view.Render(buf[:n])
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	var version uint64
+	for {
+		var err error
+		if version, err = view.WaitForUpdateContext(ctx, version); err != nil {
+			return nil, fmt.Errorf("no game menu shown: %w", err)
+		}
+		if len(ParseGameMenu(stateLines(view.GetCurrentState()))) == 0 {
+			continue
+		}
+
+		// Wait for the rest of the menu to be drawn
+		for {
+			settleCtx, cancel := context.WithTimeout(ctx, watchMenuSettle)
+			next, err := view.WaitForUpdateContext(settleCtx, version)
+			cancel()
+			if ctx.Err() != nil {
+				return nil, fmt.Errorf("no game menu shown: %w", ctx.Err())
+			}
+			if errors.Is(err, context.DeadlineExceeded) {
+				break // quiet for watchMenuSettle
+			}
+			if err != nil {
+				return nil, fmt.Errorf("no game menu shown: %w", err)
+			}
+			version = next
+		}
+		if games := ParseGameMenu(stateLines(view.GetCurrentState())); len(games) > 0 {
+			return games, nil
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package webui
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/opd-ai/go-gamelaunch-client/pkg/dgclient"
+	"github.com/opd-ai/go-gamelaunch-www/pkg/testutil"
+	"golang.org/x/crypto/ssh"
+)
+
+// testGameMenu is a dgamelaunch main menu after logging in
+const testGameMenu = "\x1b[2J\x1b[H ## dgamelaunch 1.5.1 - network console game launcher\r\n" +
+	" ## Logged in as: tester\r\n\r\n" +
+	" c) Change password\r\n" +
+	" w) Watch games in progress\r\n" +
+	" p) Play NetHack 3.6.7\r\n" +
+	" D) play Dungeon Crawl Stone Soup 0.31\r\n" +
+	" q) Quit\r\n\r\n => "
+
+func TestParseGameMenu(t *testing.T) {
+	got := ParseGameMenu([]string{
+		" c) Change password",
+		" p) Play NetHack 3.6.7",
+		"  D) play Dungeon Crawl Stone Soup 0.31  ",
+		" 2) Play   Brogue",
+		" x) Playground",
+		" q) Quit",
+	})
+	want := []dgclient.GameInfo{
+		{Name: "nethack", Description: "NetHack 3.6.7", Command: "p", Available: true},
+		{Name: "dungeon", Description: "Dungeon Crawl Stone Soup 0.31", Command: "D", Available: true},
+		{Name: "brogue", Description: "Brogue", Command: "2", Available: true},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseGameMenu = %+v, want %+v", got, want)
+	}
+
+	if games := ParseGameMenu([]string{" l) Login", " r) Register new user", " w) Watch games in progress"}); len(games) != 0 {
+		t.Errorf("login menu yielded games %+v", games)
+	}
+}
+
+// dialGameServer logs in to a mock game server
+func dialGameServer(t *testing.T, server *testutil.GameServer) *ssh.Client {
+	t.Helper()
+	client, err := ssh.Dial("tcp", server.Addr(), &ssh.ClientConfig{
+		User:            "tester",
+		Auth:            []ssh.AuthMethod{ssh.Password("secret")},
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+	})
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+func TestProbeGameMenu(t *testing.T) {
+	server := testutil.StartGameServer(t, testutil.Script{
+		{Output: "\x1b[2J\x1b[HWelcome!"},
+		{Delay: 20 * time.Millisecond, Output: testGameMenu},
+	})
+	client := dialGameServer(t, server)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	games, err := ProbeGameMenu(ctx, client, dgclient.DefaultViewOptions())
+	if err != nil {
+		t.Fatalf("ProbeGameMenu failed: %v", err)
+	}
+	if len(games) != 2 || games[0].Command != "p" || games[1].Command != "D" {
+		t.Errorf("games = %+v", games)
+	}
+	if pty := server.PTY(); pty.Width != 80 || pty.Height != 24 {
+		t.Errorf("PTY = %+v, want 80x24", pty)
+	}
+
+	// A menu without games times out
+	server2 := testutil.StartGameServer(t, testutil.Script{{Output: " l) Login\r\n q) Quit\r\n"}})
+	client2 := dialGameServer(t, server2)
+	ctx2, cancel2 := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel2()
+	if games, err := ProbeGameMenu(ctx2, client2, dgclient.DefaultViewOptions()); err == nil {
+		t.Errorf("ProbeGameMenu found games %+v on the login menu", games)
+	}
+}
@@ -14,18 +14,68 @@ type Cell struct {
 	TileX   int    `json:"tile_x,omitempty"`
 	TileY   int    `json:"tile_y,omitempty"`
 	Changed bool   `json:"-"`
+
+	// Underline is a style such as UnderlineDouble, or empty; the line takes
+	// UnderlineColor when set and the foreground color otherwise
+	Underline      string `json:"underline,omitempty"`
+	UnderlineColor string `json:"underline_color,omitempty"`
+	Overline       bool   `json:"overline,omitempty"`
+
+	// Concealed text (SGR 8) is kept but hidden; clients only see it when
+	// they reveal it and are allowed to, see WebUIOptions.WithholdConcealed
+	Concealed bool `json:"concealed,omitempty"`
+
+	// Text is the whole grapheme cluster, in NFC, when it is more than Char,
+	// e.g. a letter with several combining marks or an emoji sequence
+	Text string `json:"text,omitempty"`
+	// Wide marks a character two columns wide; the cell to its right holds
+	// Char 0 and shows nothing
+	Wide bool `json:"wide,omitempty"`
+
+	// Meta carries namespaced semantic information from parser plugins,
+	// e.g. "nethack.monster": "jackal"; see CellAnnotator
+	Meta map[string]string `json:"meta,omitempty"`
 }
 
 // GameState represents the current state of the game screen
 // Moved from: view.go via types.go
 type GameState struct {
-	Buffer    [][]Cell `json:"buffer"`
-	Width     int      `json:"width"`
-	Height    int      `json:"height"`
-	CursorX   int      `json:"cursor_x"`
-	CursorY   int      `json:"cursor_y"`
-	Version   uint64   `json:"version"`
-	Timestamp int64    `json:"timestamp"`
+	Buffer    [][]Cell       `json:"buffer"`
+	Width     int            `json:"width"`
+	Height    int            `json:"height"`
+	CursorX   int            `json:"cursor_x"`
+	CursorY   int            `json:"cursor_y"`
+	Pinned    []ScreenRegion `json:"pinned,omitempty"` // rows that never scroll, for client layout
+	Echo      bool           `json:"echo"`             // clients may echo typed characters locally
+	Version   uint64         `json:"version"`
+	Timestamp int64          `json:"timestamp"`
+
+	// Progress is the turn counter and game clock found by parser plugins,
+	// or nil; see ProgressParser. It is never modified once set, so copies
+	// of the state may share it.
+	Progress *GameProgress `json:"progress,omitempty"`
+
+	// Landmarks are the semantic regions of the screen found by parser
+	// plugins, or nil; see LandmarkParser. Like Progress they are never
+	// modified once set.
+	Landmarks []Landmark `json:"landmarks,omitempty"`
+
+	// Expiry announces that the session is about to end, or is nil; see
+	// SessionExpiry. Like Progress it is never modified once set.
+	Expiry *SessionExpiry `json:"expiry,omitempty"`
+
+	// Stall reports that the game stopped answering, or is nil; see
+	// StreamWatchdog. Like Progress it is never modified once set.
+	Stall *StreamStall `json:"stall,omitempty"`
+
+	// Broadcast is the last message of an admin to all clients while it is
+	// shown, or nil; see WebUI.BroadcastMessage. Like Progress it is never
+	// modified once set.
+	Broadcast *SessionBroadcast `json:"broadcast,omitempty"`
+
+	// Scrollback tells which lines /game/scrollback holds, or is nil; see
+	// ScrollbackInfo. Like Progress it is never modified once set.
+	Scrollback *ScrollbackInfo `json:"scrollback,omitempty"`
 }
 
 // StateDiff represents changes between game states
@@ -36,6 +86,25 @@ type StateDiff struct {
 	CursorX   int        `json:"cursor_x"`
 	CursorY   int        `json:"cursor_y"`
 	Timestamp int64      `json:"timestamp"`
+	Echo      bool       `json:"echo"`            // see GameState.Echo
+	Epoch     string     `json:"epoch,omitempty"` // see StateManager.Epoch
+
+	Progress  *GameProgress  `json:"progress,omitempty"`  // see GameState.Progress
+	Landmarks []Landmark     `json:"landmarks,omitempty"` // see GameState.Landmarks
+	Expiry    *SessionExpiry `json:"expiry,omitempty"`    // see GameState.Expiry
+	Stall     *StreamStall   `json:"stall,omitempty"`     // see GameState.Stall
+
+	Broadcast *SessionBroadcast `json:"broadcast,omitempty"` // see GameState.Broadcast
+
+	Scrollback *ScrollbackInfo `json:"scrollback,omitempty"` // see GameState.Scrollback
+
+	// Compact encoding used instead of Changes when a diff exceeds the
+	// DiffBudget: Rows replace whole rows, and a keyframe covers the entire
+	// Width x Height screen
+	Rows     []RowDiff `json:"rows,omitempty"`
+	Keyframe bool      `json:"keyframe,omitempty"`
+	Width    int       `json:"width,omitempty"`
+	Height   int       `json:"height,omitempty"`
 }
 
 // CellDiff represents a change to a specific cell
@@ -45,3 +114,34 @@ type CellDiff struct {
 	Y    int  `json:"y"`
 	Cell Cell `json:"cell"`
 }
+
+// Clone returns a copy of the state with its own buffer and pinned regions,
+// which may be modified without affecting s. Cell.Meta maps are shared, as
+// are Progress, Landmarks and the other notices: they are copy-on-write, so
+// replace a cell's Meta rather than modify it.
+func (s *GameState) Clone() *GameState {
+	if s == nil {
+		return nil
+	}
+
+	clone := *s
+	if s.Pinned != nil {
+		clone.Pinned = append([]ScreenRegion(nil), s.Pinned...)
+	}
+	if s.Buffer != nil {
+		total := 0
+		for _, row := range s.Buffer {
+			total += len(row)
+		}
+
+		cells := make([]Cell, total)
+		clone.Buffer = make([][]Cell, len(s.Buffer))
+		offset := 0
+		for y, row := range s.Buffer {
+			n := copy(cells[offset:], row)
+			clone.Buffer[y] = cells[offset : offset+n : offset+n]
+			offset += n
+		}
+	}
+	return &clone
+}
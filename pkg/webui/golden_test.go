@@ -0,0 +1,150 @@
+package webui
+
+// Golden-file tests of terminal emulation.
+//
+// Every testdata/golden/*.ttyrec capture is fed through WebView frame by
+// frame and the final screen is compared with the snapshot next to it in
+// <name>.golden: the text of each row, then runs of cells whose style differs
+// from the default. Changes to the escape-sequence parser that alter how a
+// capture renders show up as a diff of the snapshot. New captures of real
+// games can be dropped into testdata/golden; after checking that a change
+// renders correctly, rewrite the snapshots with
+//
+//	go test -run TestGolden ./pkg/webui -update
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/opd-ai/go-gamelaunch-www/pkg/ttyrec"
+)
+
+// updateGolden rewrites the golden snapshots instead of comparing them
+var updateGolden = flag.Bool("update", false, "rewrite testdata/golden snapshots")
+
+func TestGolden_TerminalEmulation(t *testing.T) {
+	captures, err := filepath.Glob(filepath.Join("testdata", "golden", "*.ttyrec"))
+	if err != nil || len(captures) == 0 {
+		t.Fatalf("no captures in testdata/golden: %v", err)
+	}
+
+	for _, capture := range captures {
+		name := strings.TrimSuffix(filepath.Base(capture), ".ttyrec")
+		t.Run(name, func(t *testing.T) {
+			data, err := os.ReadFile(capture)
+			if err != nil {
+				t.Fatalf("Failed to read capture: %v", err)
+			}
+			frames, err := ttyrec.ReadAll(bytes.NewReader(data))
+			if err != nil {
+				t.Fatalf("Failed to parse capture: %v", err)
+			}
+
+			view := newTestView(t)
+			for i, frame := range frames {
+				if err := view.Render(frame.Data); err != nil {
+					t.Fatalf("Render of frame %d failed: %v", i, err)
+				}
+			}
+			got := screenSnapshot(view.GetCurrentState())
+
+			golden := strings.TrimSuffix(capture, ".ttyrec") + ".golden"
+			if *updateGolden {
+				if err := os.WriteFile(golden, []byte(got), 0o644); err != nil {
+					t.Fatalf("Failed to write snapshot: %v", err)
+				}
+				return
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("Failed to read snapshot (run with -update to create it): %v", err)
+			}
+			if diff := firstDifference(string(want), got); diff != "" {
+				t.Errorf("screen differs from %s after %d frames:\n%s", golden, len(frames), diff)
+			}
+		})
+	}
+}
+
+// screenSnapshot renders the size, cursor, text and styled runs of a state
+// as text suitable for a golden file
+func screenSnapshot(state *GameState) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "size %dx%d cursor %d,%d\n", state.Width, state.Height, state.CursorX, state.CursorY)
+	sb.WriteString("-- text\n")
+	for _, line := range stateLines(state) {
+		fmt.Fprintf(&sb, "|%s\n", line)
+	}
+	sb.WriteString("-- styles\n")
+	for y, row := range state.Buffer {
+		for x := 0; x < len(row); {
+			style := cellStyle(row[x])
+			end := x + 1
+			for end < len(row) && cellStyle(row[end]) == style {
+				end++
+			}
+			if style != "" {
+				fmt.Fprintf(&sb, "%d:%d-%d %s\n", y, x, end-1, style)
+			}
+			x = end
+		}
+	}
+	return sb.String()
+}
+
+// cellStyle describes how a cell differs from the default white on black,
+// or is empty when it does not
+func cellStyle(cell Cell) string {
+	var parts []string
+	if cell.FgColor != "#FFFFFF" {
+		parts = append(parts, "fg="+cell.FgColor)
+	}
+	if cell.BgColor != "#000000" {
+		parts = append(parts, "bg="+cell.BgColor)
+	}
+	for _, flag := range []struct {
+		name string
+		set  bool
+	}{
+		{"bold", cell.Bold}, {"inverse", cell.Inverse}, {"blink", cell.Blink},
+		{"overline", cell.Overline}, {"concealed", cell.Concealed}, {"wide", cell.Wide},
+	} {
+		if flag.set {
+			parts = append(parts, flag.name)
+		}
+	}
+	if cell.Underline != "" {
+		parts = append(parts, "underline="+cell.Underline)
+	}
+	if cell.UnderlineColor != "" {
+		parts = append(parts, "underline_color="+cell.UnderlineColor)
+	}
+	return strings.Join(parts, " ")
+}
+
+// firstDifference returns the first differing line of two snapshots, or ""
+// when they are equal
+func firstDifference(want, got string) string {
+	if want == got {
+		return ""
+	}
+	wantLines, gotLines := strings.Split(want, "\n"), strings.Split(got, "\n")
+	for i := 0; i < len(wantLines) || i < len(gotLines); i++ {
+		var w, g string
+		if i < len(wantLines) {
+			w = wantLines[i]
+		}
+		if i < len(gotLines) {
+			g = gotLines[i]
+		}
+		if w != g {
+			return fmt.Sprintf("line %d:\n  want %q\n  got  %q", i+1, w, g)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,125 @@
+// Package webui provides grapheme-cluster aware placement of characters in
+// the screen buffer.
+package webui
+
+import (
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+	"golang.org/x/text/width"
+)
+
+// zeroWidthJoiner joins emoji into one grapheme, e.g. woman + ZWJ + rocket
+const zeroWidthJoiner = '\u200d'
+
+// graphemeState tracks the cell holding the cluster last written, so the
+// runes that extend it are added to that cell instead of taking their own
+type graphemeState struct {
+	x, y     int
+	ok       bool // x, y holds a cluster that may still be extended
+	last     rune // last rune of the cluster
+	regional int  // regional indicators in the cluster; flags are pairs
+}
+
+// Grapheme returns the text shown by the cell: its grapheme cluster, or
+// nothing for the right half of a wide character
+func (c Cell) Grapheme() string {
+	if c.Text != "" {
+		return c.Text
+	}
+	if c.Char == 0 {
+		return ""
+	}
+	return runeString(c.Char)
+}
+
+// extendsCluster reports whether r continues a grapheme cluster ending in
+// last that holds regional regional indicators. It covers combining marks,
+// variation selectors, emoji modifiers and tags, ZWJ sequences and flags;
+// Hangul syllables and other script-specific rules are not applied.
+func extendsCluster(last rune, regional int, r rune) bool {
+	switch {
+	case last == zeroWidthJoiner:
+		return true
+	case r == zeroWidthJoiner:
+		return true
+	case unicode.In(r, unicode.Mn, unicode.Me, unicode.Mc):
+		return true
+	case r >= 0xfe00 && r <= 0xfe0f, r >= 0xe0100 && r <= 0xe01ef: // variation selectors
+		return true
+	case r >= 0x1f3fb && r <= 0x1f3ff: // skin tone modifiers
+		return true
+	case r >= 0xe0020 && r <= 0xe007f: // tags, as in subdivision flags
+		return true
+	case isRegionalIndicator(r):
+		return isRegionalIndicator(last) && regional%2 == 1
+	}
+	return false
+}
+
+// isRegionalIndicator reports whether r is one of the letters that pair up
+// into flags
+func isRegionalIndicator(r rune) bool {
+	return r >= 0x1f1e6 && r <= 0x1f1ff
+}
+
+// runeWidth returns the columns r takes: two for East Asian wide and
+// fullwidth characters, which include most emoji, and one otherwise
+func runeWidth(r rune) int {
+	switch width.LookupRune(r).Kind() {
+	case width.EastAsianWide, width.EastAsianFullwidth:
+		return 2
+	}
+	return 1
+}
+
+// extendGrapheme adds r to the cluster last written, normalized to NFC so
+// that e.g. "e" and a combining acute accent become "é"
+func (v *WebView) extendGrapheme(r rune) {
+	g := &v.grapheme
+	cell := &v.buffer[g.y][g.x]
+	text := norm.NFC.String(cell.Grapheme() + string(r))
+
+	first, size := utf8.DecodeRuneInString(text)
+	cell.Char = first
+	cell.Text = ""
+	if size < len(text) {
+		cell.Text = text
+	}
+	cell.Changed = true
+	v.applyTilesetMapping(cell, first)
+
+	g.last = r
+	if isRegionalIndicator(r) {
+		g.regional++
+	}
+}
+
+// startGrapheme records that the cluster at x, y begins with r
+func (v *WebView) startGrapheme(x, y int, r rune) {
+	v.grapheme = graphemeState{x: x, y: y, ok: true, last: r}
+	if isRegionalIndicator(r) {
+		v.grapheme.regional = 1
+	}
+}
+
+// breakGrapheme stops later runes from joining the cluster last written,
+// as after cursor movement, control characters or scrolling
+func (v *WebView) breakGrapheme() {
+	v.grapheme.ok = false
+}
+
+// splitWide blanks the other half of a wide character at x, y that is
+// about to be partly overwritten
+func (v *WebView) splitWide(x, y int) {
+	row := v.buffer[y]
+	switch {
+	case row[x].Wide && x+1 < len(row):
+		row[x+1].Char = ' '
+		row[x+1].Changed = true
+	case row[x].Char == 0 && x > 0 && row[x-1].Wide:
+		row[x-1].Char, row[x-1].Text, row[x-1].Wide = ' ', "", false
+		row[x-1].Changed = true
+	}
+}
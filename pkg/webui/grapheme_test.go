@@ -0,0 +1,112 @@
+package webui
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/opd-ai/go-gamelaunch-client/pkg/dgclient"
+	"github.com/opd-ai/go-gamelaunch-www/pkg/transport"
+)
+
+// rowGraphemes returns the graphemes of the first n cells of row y
+func rowGraphemes(state *GameState, y, n int) []string {
+	graphemes := make([]string, n)
+	for x := range graphemes {
+		graphemes[x] = state.Buffer[y][x].Grapheme()
+	}
+	return graphemes
+}
+
+func TestWebView_Render_Graphemes(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    []string
+		cursorX int
+	}{
+		{"ascii", "ab", []string{"a", "b", " "}, 2},
+		{"combining mark composes", "e\u0301x", []string{"\u00e9", "x", " "}, 2},
+		{"marks without a composition", "q\u0307\u0323.", []string{"q\u0323\u0307", ".", " "}, 2},
+		{"wide character", "日x", []string{"日", "", "x"}, 3},
+		{"emoji ZWJ sequence", "\U0001F469\u200d\U0001F680.", []string{"\U0001F469\u200d\U0001F680", "", "."}, 3},
+		{"skin tone modifier", "\U0001F44B\U0001F3FD.", []string{"\U0001F44B\U0001F3FD", "", "."}, 3},
+		{"flags pair up", "\U0001F1EB\U0001F1F7\U0001F1E9\U0001F1EA", []string{"\U0001F1EB\U0001F1F7", "\U0001F1E9\U0001F1EA"}, 2},
+		{"control character breaks the cluster", "e\r\u0301", []string{"\u0301", " "}, 1},
+		{"escape sequence breaks the cluster", "e\x1b[C\u0301", []string{"e", " ", "\u0301"}, 3},
+		{"stray byte", "\xff", []string{"\ufffd", " "}, 1},
+		{"sequence cut short", "\xe6\x97x\xe6\x97\x1b[C.", []string{"\ufffd", "x", "\ufffd", " ", "."}, 5},
+		{"overlong encoding", "\xc0\xaf.", []string{"\ufffd", "\ufffd", "."}, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			view := newTestView(t)
+			if err := view.Render([]byte(tt.input)); err != nil {
+				t.Fatalf("Render failed: %v", err)
+			}
+			state := view.GetCurrentState()
+			got := rowGraphemes(state, 0, len(tt.want))
+			for x := range tt.want {
+				if got[x] != tt.want[x] {
+					t.Errorf("cells = %q, want %q", got, tt.want)
+					break
+				}
+			}
+			if state.CursorX != tt.cursorX {
+				t.Errorf("cursor x = %d, want %d", state.CursorX, tt.cursorX)
+			}
+		})
+	}
+}
+
+func TestWebView_Render_SplitUTF8(t *testing.T) {
+	// Game output arrives in reads of any size, cutting characters apart
+	view := newTestView(t)
+	for _, b := range []byte("\u00e9\u65e5\U0001F44D\u2500") {
+		if err := view.Render([]byte{b}); err != nil {
+			t.Fatalf("Render failed: %v", err)
+		}
+	}
+	state := view.GetCurrentState()
+	want := []string{"\u00e9", "\u65e5", "", "\U0001F44D", "", "\u2500"}
+	if got := rowGraphemes(state, 0, len(want)); !slices.Equal(got, want) {
+		t.Errorf("cells = %q, want %q", got, want)
+	}
+	if state.CursorX != len(want) {
+		t.Errorf("cursor x = %d, want %d", state.CursorX, len(want))
+	}
+}
+
+func TestWebView_Render_WideCharacters(t *testing.T) {
+	view, err := NewWebView(dgclient.ViewOptions{InitialWidth: 5, InitialHeight: 3})
+	if err != nil {
+		t.Fatalf("NewWebView failed: %v", err)
+	}
+
+	// The last column cannot hold a wide character: it wraps early
+	if err := view.Render([]byte("abcd日")); err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	state := view.GetCurrentState()
+	if got := rowGraphemes(state, 1, 3); got[0] != "日" || got[1] != "" || !state.Buffer[1][0].Wide {
+		t.Errorf("row 1 = %q, want the wide character at the start", got)
+	}
+
+	// Overwriting either half blanks the other
+	if err := view.Render([]byte("\x1b[2;2Hx\x1b[3;1H語語\x1b[3;1Hy")); err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	state = view.GetCurrentState()
+	if got := rowGraphemes(state, 1, 2); got[0] != " " || got[1] != "x" || state.Buffer[1][0].Wide {
+		t.Errorf("row 1 = %q, want the orphaned half blanked", got)
+	}
+	if got := rowGraphemes(state, 2, 4); got[0] != "y" || got[1] != " " || got[2] != "語" {
+		t.Errorf("row 2 = %q, want the orphaned half blanked", got)
+	}
+
+	payload := toStatePayloadFor(state, transport.ViewOptions{}, nil)
+	defer releaseStatePayload(payload)
+	if lead, right := payload.Buffer[2][2], payload.Buffer[2][3]; lead.Char != "語" || !lead.Wide || right.Char != "" {
+		t.Errorf("wire cells = %+v %+v, want a wide lead and an empty right half", lead, right)
+	}
+}
@@ -0,0 +1,308 @@
+// Package webui provides a managed known_hosts store whose unknown and
+// changed host keys are approved from the web client.
+package webui
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/opd-ai/go-gamelaunch-www/pkg/transport"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// errNoHostKey is returned when a decision does not match the pending host
+// key
+var errNoHostKey = errors.New("no such host key pending")
+
+// HostKeyConfig keeps game server host keys in a known_hosts file managed by
+// the server. When the web UI authenticates its clients, keys that are
+// neither there nor in a trusted file, or that changed, are shown to them
+// until an admin approves or denies them at /ssh/hostkey; otherwise they
+// are rejected.
+type HostKeyConfig struct {
+	KnownHosts string   `yaml:"known_hosts,omitempty"`                  // managed store, e.g. ~/.dgconnect/known_hosts
+	Trusted    []string `yaml:"trusted,omitempty"`                      // read-only known_hosts files, e.g. ~/.ssh/known_hosts
+	Timeout    string   `yaml:"timeout,omitempty" yamlcheck:"duration"` // wait for a decision, default "5m"
+}
+
+// HostKeyStore verifies host keys against its known_hosts files and asks web
+// clients about the others, once a WebUI with authentication takes the
+// questions. Check is an ssh.HostKeyCallback.
+type HostKeyStore struct {
+	path    string
+	trusted []string
+	timeout time.Duration
+
+	fileMu sync.Mutex // serializes rewrites of path
+
+	mu       sync.Mutex
+	nextID   uint64
+	pending  *transport.HostKeyPayload
+	decision chan bool
+	notify   func(*transport.HostKeyPayload)
+}
+
+// hostKeyDecision is the request body of POST /ssh/hostkey
+type hostKeyDecision struct {
+	ID      uint64 `json:"id"`
+	Approve bool   `json:"approve"`
+}
+
+// NewHostKeyStore validates cfg
+func NewHostKeyStore(cfg HostKeyConfig) (*HostKeyStore, error) {
+	if cfg.KnownHosts == "" {
+		return nil, fmt.Errorf("host keys: known_hosts is required")
+	}
+	s := &HostKeyStore{path: cfg.KnownHosts, trusted: cfg.Trusted, timeout: defaultChallengeTimeout}
+	if cfg.Timeout != "" {
+		timeout, err := time.ParseDuration(cfg.Timeout)
+		if err != nil || timeout <= 0 {
+			return nil, fmt.Errorf("host keys: invalid timeout %q", cfg.Timeout)
+		}
+		s.timeout = timeout
+	}
+	return s, nil
+}
+
+// setNotifier installs the function that pushes host key decisions to
+// clients
+func (s *HostKeyStore) setNotifier(notify func(*transport.HostKeyPayload)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.notify = notify
+}
+
+// Check accepts a known host key and otherwise waits for a decision,
+// recording an approved key in the managed store in place of any other key
+// of the host
+func (s *HostKeyStore) Check(hostname string, remote net.Addr, key ssh.PublicKey) error {
+	return s.check(hostname, remote, key, s.ask)
+}
+
+// CheckWith returns a host key callback like Check that lets decide approve
+// or deny unknown and changed keys instead of the web clients, e.g. by
+// asking on the terminal
+func (s *HostKeyStore) CheckWith(decide func(request *transport.HostKeyPayload) (bool, error)) ssh.HostKeyCallback {
+	return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		return s.check(hostname, remote, key, decide)
+	}
+}
+
+// check verifies key, asking decide about keys that are not known
+func (s *HostKeyStore) check(hostname string, remote net.Addr, key ssh.PublicKey, decide func(*transport.HostKeyPayload) (bool, error)) error {
+	err := s.verify(hostname, remote, key)
+	if err == nil {
+		return nil
+	}
+	var keyErr *knownhosts.KeyError
+	if !errors.As(err, &keyErr) {
+		return fmt.Errorf("host key verification failed: %w", err)
+	}
+
+	request := &transport.HostKeyPayload{
+		Host:        hostname,
+		KeyType:     key.Type(),
+		Fingerprint: ssh.FingerprintSHA256(key),
+	}
+	for _, want := range keyErr.Want {
+		request.Known = append(request.Known, ssh.FingerprintSHA256(want.Key))
+	}
+	approved, err := decide(request)
+	if err != nil {
+		return fmt.Errorf("host key verification failed: %w", err)
+	}
+	if !approved {
+		return fmt.Errorf("host key verification failed: key of %s denied", hostname)
+	}
+	if err := s.record(hostname, key); err != nil {
+		return fmt.Errorf("failed to record host key: %w", err)
+	}
+	return nil
+}
+
+// verify checks key against the trusted files and the managed store
+func (s *HostKeyStore) verify(hostname string, remote net.Addr, key ssh.PublicKey) error {
+	var files []string
+	for _, path := range append(append([]string(nil), s.trusted...), s.path) {
+		if _, err := os.Stat(path); err == nil {
+			files = append(files, path)
+		}
+	}
+	if len(files) == 0 {
+		return &knownhosts.KeyError{}
+	}
+
+	s.fileMu.Lock()
+	callback, err := knownhosts.New(files...)
+	s.fileMu.Unlock()
+	if err != nil {
+		return err
+	}
+	return callback(hostname, remote, key)
+}
+
+// ask publishes request and waits for a decision, denying the key when
+// nobody decides in time. Without a notifier, which only a WebUI that
+// authenticates its clients installs, nobody could decide: unknown keys
+// are rejected and changed ones fail as a mismatch, as ssh does.
+func (s *HostKeyStore) ask(request *transport.HostKeyPayload) (bool, error) {
+	s.mu.Lock()
+	if s.notify == nil {
+		s.mu.Unlock()
+		if len(request.Known) > 0 {
+			return false, fmt.Errorf("key mismatch for %s: got %s, want %s", request.Host, request.Fingerprint, strings.Join(request.Known, ", "))
+		}
+		return false, fmt.Errorf("unknown key %s of %s: add it to known_hosts, or enable web authentication to approve it", request.Fingerprint, request.Host)
+	}
+	s.nextID++
+	request.ID = s.nextID
+	request.Status = transport.HostKeyPending
+	decision := make(chan bool, 1)
+	s.pending, s.decision = request, decision
+	notify := s.notify
+	s.mu.Unlock()
+
+	slog.Warn("webui: host key needs approval", "host", request.Host, "fingerprint", request.Fingerprint, "changed", len(request.Known) > 0)
+	if notify != nil {
+		notify(request)
+	}
+
+	timer := time.NewTimer(s.timeout)
+	defer timer.Stop()
+	var approved bool
+	var err error
+	select {
+	case approved = <-decision:
+	case <-timer.C:
+		err = fmt.Errorf("no decision within %s", s.timeout)
+	}
+
+	s.mu.Lock()
+	if s.pending != nil && s.pending.ID == request.ID {
+		s.pending, s.decision = nil, nil
+	}
+	notify = s.notify
+	s.mu.Unlock()
+
+	outcome := *request
+	outcome.Status = transport.HostKeyDenied
+	if approved {
+		outcome.Status = transport.HostKeyApproved
+	}
+	slog.Info("webui: host key decided", "host", request.Host, "status", outcome.Status)
+	if notify != nil {
+		notify(&outcome)
+	}
+	return approved, err
+}
+
+// Pending returns the host key waiting for a decision, or nil
+func (s *HostKeyStore) Pending() *transport.HostKeyPayload {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.pending
+}
+
+// Decide approves or denies the pending host key id
+func (s *HostKeyStore) Decide(id uint64, approve bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.pending == nil || s.pending.ID != id {
+		return errNoHostKey
+	}
+	s.decision <- approve
+	s.pending, s.decision = nil, nil
+	return nil
+}
+
+// record replaces the keys of hostname in the managed store with key
+func (s *HostKeyStore) record(hostname string, key ssh.PublicKey) error {
+	s.fileMu.Lock()
+	defer s.fileMu.Unlock()
+
+	data, err := os.ReadFile(s.path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	address := knownhosts.Normalize(hostname)
+	var b strings.Builder
+	for _, line := range strings.SplitAfter(string(data), "\n") {
+		if line == "" || knownHostsLineFor(line, address) {
+			continue
+		}
+		b.WriteString(strings.TrimSuffix(line, "\n") + "\n")
+	}
+	b.WriteString(knownhosts.Line([]string{address}, key) + "\n")
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o700); err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(b.String()), 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
+
+// knownHostsLineFor reports whether a known_hosts line lists address in
+// plain text; markers, comments and hashed hosts never match
+func knownHostsLineFor(line, address string) bool {
+	fields := strings.Fields(line)
+	if len(fields) < 2 || strings.HasPrefix(fields[0], "#") || strings.HasPrefix(fields[0], "@") {
+		return false
+	}
+	for _, host := range strings.Split(fields[0], ",") {
+		if host == address {
+			return true
+		}
+	}
+	return false
+}
+
+// handleHostKey serves GET /ssh/hostkey, the host key waiting for a decision
+// (204 when there is none), and POST /ssh/hostkey, deciding it
+// ({"id": 1, "approve": true}). Whatever the authorization policy, only
+// admins may approve a changed key.
+func (w *WebUI) handleHostKey(rw http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		pending := w.hostKeys.Pending()
+		if pending == nil {
+			rw.WriteHeader(http.StatusNoContent)
+			return
+		}
+		writeJSON(rw, http.StatusOK, pending)
+
+	case http.MethodPost:
+		var req hostKeyDecision
+		if err := json.NewDecoder(http.MaxBytesReader(rw, r.Body, 4096)).Decode(&req); err != nil {
+			http.Error(rw, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		pending := w.hostKeys.Pending()
+		if req.Approve && pending != nil && pending.ID == req.ID && len(pending.Known) > 0 && !w.authorizeRole(r.Context(), RoleAdmin) {
+			http.Error(rw, "Only admins may approve a changed host key", http.StatusForbidden)
+			return
+		}
+		if err := w.hostKeys.Decide(req.ID, req.Approve); err != nil {
+			http.Error(rw, err.Error(), http.StatusConflict)
+			return
+		}
+		rw.WriteHeader(http.StatusNoContent)
+
+	default:
+		rw.Header().Set("Allow", "GET, POST")
+		http.Error(rw, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
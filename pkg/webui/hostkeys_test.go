@@ -0,0 +1,252 @@
+package webui
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/opd-ai/go-gamelaunch-www/pkg/transport"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// newTestHostKey returns a random ed25519 public key
+func newTestHostKey(t *testing.T) ssh.PublicKey {
+	t.Helper()
+	pub, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey failed: %v", err)
+	}
+	key, err := ssh.NewPublicKey(pub)
+	if err != nil {
+		t.Fatalf("NewPublicKey failed: %v", err)
+	}
+	return key
+}
+
+// decideNext answers the next host key request with approve
+func decideNext(t *testing.T, store *HostKeyStore, approve bool) <-chan *transport.HostKeyPayload {
+	t.Helper()
+	requests := make(chan *transport.HostKeyPayload, 2)
+	store.setNotifier(func(p *transport.HostKeyPayload) {
+		if p.Status == transport.HostKeyPending {
+			requests <- p
+			if err := store.Decide(p.ID, approve); err != nil {
+				t.Errorf("Decide failed: %v", err)
+			}
+		}
+	})
+	return requests
+}
+
+func TestHostKeyStore_Check(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewHostKeyStore(HostKeyConfig{KnownHosts: filepath.Join(dir, "dgconnect", "known_hosts"), Timeout: "1s"})
+	if err != nil {
+		t.Fatalf("NewHostKeyStore failed: %v", err)
+	}
+	remote := &net.TCPAddr{IP: net.IPv4(192, 0, 2, 1), Port: 22}
+	first, second := newTestHostKey(t), newTestHostKey(t)
+
+	requests := decideNext(t, store, false)
+	if err := store.Check("nethack.example.com:22", remote, first); err == nil {
+		t.Fatal("denied key accepted")
+	}
+	<-requests
+
+	requests = decideNext(t, store, true)
+	if err := store.Check("nethack.example.com:22", remote, first); err != nil {
+		t.Fatalf("approved key rejected: %v", err)
+	}
+	if request := <-requests; request.Fingerprint != ssh.FingerprintSHA256(first) || len(request.Known) != 0 {
+		t.Errorf("request = %+v, want an unknown key", request)
+	}
+
+	// Known now: no question asked
+	requests = decideNext(t, store, false)
+	if err := store.Check("nethack.example.com:22", remote, first); err != nil {
+		t.Fatalf("recorded key rejected: %v", err)
+	}
+	if len(requests) != 0 {
+		t.Error("recorded key asked about")
+	}
+
+	// A changed key replaces the recorded one once approved
+	requests = decideNext(t, store, true)
+	if err := store.Check("nethack.example.com:22", remote, second); err != nil {
+		t.Fatalf("approved changed key rejected: %v", err)
+	}
+	if request := <-requests; len(request.Known) != 1 || request.Known[0] != ssh.FingerprintSHA256(first) {
+		t.Errorf("request = %+v, want the recorded fingerprint", request)
+	}
+	data, err := os.ReadFile(store.path)
+	if err != nil {
+		t.Fatalf("ReadFile failed: %v", err)
+	}
+	if lines := strings.Count(string(data), "\n"); lines != 1 || !strings.Contains(string(data), knownhosts.Line([]string{"nethack.example.com"}, second)) {
+		t.Errorf("known_hosts = %q, want only the new key", data)
+	}
+}
+
+func TestHostKeyStore_Check_TrustedFile(t *testing.T) {
+	dir := t.TempDir()
+	key := newTestHostKey(t)
+	trusted := filepath.Join(dir, "ssh_known_hosts")
+	if err := os.WriteFile(trusted, []byte(knownhosts.Line([]string{"[dgl.example.com]:2022"}, key)+"\n"), 0o600); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+	store, err := NewHostKeyStore(HostKeyConfig{KnownHosts: filepath.Join(dir, "known_hosts"), Trusted: []string{trusted}, Timeout: "10ms"})
+	if err != nil {
+		t.Fatalf("NewHostKeyStore failed: %v", err)
+	}
+
+	if err := store.Check("dgl.example.com:2022", &net.TCPAddr{IP: net.IPv4(192, 0, 2, 2), Port: 2022}, key); err != nil {
+		t.Errorf("trusted key rejected: %v", err)
+	}
+	if err := store.Check("other.example.com:22", &net.TCPAddr{IP: net.IPv4(192, 0, 2, 3), Port: 22}, key); err == nil {
+		t.Error("key of another host accepted without a decision")
+	}
+}
+
+func TestHostKeyStore_CheckWith(t *testing.T) {
+	store, err := NewHostKeyStore(HostKeyConfig{KnownHosts: filepath.Join(t.TempDir(), "known_hosts"), Timeout: "10ms"})
+	if err != nil {
+		t.Fatalf("NewHostKeyStore failed: %v", err)
+	}
+	remote := &net.TCPAddr{IP: net.IPv4(192, 0, 2, 1), Port: 22}
+	key := newTestHostKey(t)
+
+	var asked []*transport.HostKeyPayload
+	check := store.CheckWith(func(request *transport.HostKeyPayload) (bool, error) {
+		asked = append(asked, request)
+		return true, nil
+	})
+	if err := check("nethack.example.com:22", remote, key); err != nil {
+		t.Fatalf("approved key rejected: %v", err)
+	}
+	if len(asked) != 1 || asked[0].Fingerprint != ssh.FingerprintSHA256(key) {
+		t.Errorf("asked %+v", asked)
+	}
+	if store.Pending() != nil {
+		t.Error("key shown to web clients")
+	}
+
+	// The approval is recorded for Check
+	if err := store.Check("nethack.example.com:22", remote, key); err != nil {
+		t.Errorf("recorded key rejected: %v", err)
+	}
+}
+
+func TestHostKeyStore_Check_WithoutWebApproval(t *testing.T) {
+	store, err := NewHostKeyStore(HostKeyConfig{KnownHosts: filepath.Join(t.TempDir(), "known_hosts"), Timeout: "1m"})
+	if err != nil {
+		t.Fatalf("NewHostKeyStore failed: %v", err)
+	}
+	// A WebUI without authentication does not take the questions
+	w, err := NewWebUI(WebUIOptions{View: newTestView(t), HostKeys: store})
+	if err != nil {
+		t.Fatalf("NewWebUI failed: %v", err)
+	}
+	rec := httptest.NewRecorder()
+	w.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/ssh/hostkey", strings.NewReader(`{"id": 1, "approve": true}`)))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("POST /ssh/hostkey without authentication = %d, want 404", rec.Code)
+	}
+
+	// Unknown keys are rejected at once rather than waiting for a decision
+	remote := &net.TCPAddr{IP: net.IPv4(192, 0, 2, 1), Port: 22}
+	first, second := newTestHostKey(t), newTestHostKey(t)
+	if err := store.Check("nethack.example.com:22", remote, first); err == nil || !strings.Contains(err.Error(), "unknown key") {
+		t.Fatalf("Check of an unknown key = %v, want it rejected", err)
+	}
+	if store.Pending() != nil {
+		t.Error("key left pending without anyone to decide")
+	}
+
+	// Changed keys fail as a mismatch
+	if err := store.record("nethack.example.com:22", first); err != nil {
+		t.Fatalf("record failed: %v", err)
+	}
+	if err := store.Check("nethack.example.com:22", remote, second); err == nil || !strings.Contains(err.Error(), "mismatch") {
+		t.Errorf("Check of a changed key = %v, want a mismatch", err)
+	}
+}
+
+func TestWebUI_HandleHostKey(t *testing.T) {
+	store, err := NewHostKeyStore(HostKeyConfig{KnownHosts: filepath.Join(t.TempDir(), "known_hosts"), Timeout: "5s"})
+	if err != nil {
+		t.Fatalf("NewHostKeyStore failed: %v", err)
+	}
+	w, err := NewWebUI(WebUIOptions{
+		View: newTestView(t),
+		StaticTokens: []StaticToken{
+			{Name: "hero", Token: playerToken, Role: "player"},
+			{Name: "op", Token: adminToken, Role: "admin"},
+		},
+		// Even a policy letting players decide keeps changed keys to admins
+		Authorization: AuthorizationPolicy{"ssh.hostkey": "player"},
+		HostKeys:      store,
+	})
+	if err != nil {
+		t.Fatalf("NewWebUI failed: %v", err)
+	}
+	serve := func(method, body, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/ssh/hostkey", strings.NewReader(body))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		w.ServeHTTP(rec, req)
+		return rec
+	}
+	remote := &net.TCPAddr{IP: net.IPv4(192, 0, 2, 1), Port: 22}
+	check := func(key ssh.PublicKey) <-chan error {
+		done := make(chan error, 1)
+		go func() { done <- store.Check("nethack.example.com:22", remote, key) }()
+		for store.Pending() == nil {
+			time.Sleep(time.Millisecond)
+		}
+		return done
+	}
+
+	if rec := serve(http.MethodGet, "", playerToken); rec.Code != http.StatusNoContent {
+		t.Errorf("GET without a pending key = %d, want 204", rec.Code)
+	}
+	if rec := serve(http.MethodGet, "", ""); rec.Code != http.StatusUnauthorized {
+		t.Errorf("anonymous GET = %d, want 401", rec.Code)
+	}
+
+	first := newTestHostKey(t)
+	done := check(first)
+	if rec := serve(http.MethodGet, "", playerToken); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "SHA256:") {
+		t.Fatalf("GET = %d %s, want the pending key", rec.Code, rec.Body)
+	}
+	if rec := serve(http.MethodPost, `{"id": 9, "approve": true}`, playerToken); rec.Code != http.StatusConflict {
+		t.Errorf("POST with a stale id = %d, want 409", rec.Code)
+	}
+	if rec := serve(http.MethodPost, `{"id": 1, "approve": true}`, playerToken); rec.Code != http.StatusNoContent {
+		t.Errorf("POST = %d, want 204", rec.Code)
+	}
+	if err := <-done; err != nil {
+		t.Errorf("Check failed after approval: %v", err)
+	}
+
+	// A changed key may be a man in the middle: only admins approve it
+	done = check(newTestHostKey(t))
+	if rec := serve(http.MethodPost, `{"id": 2, "approve": true}`, playerToken); rec.Code != http.StatusForbidden {
+		t.Errorf("player approving a changed key = %d, want 403", rec.Code)
+	}
+	if rec := serve(http.MethodPost, `{"id": 2, "approve": true}`, adminToken); rec.Code != http.StatusNoContent {
+		t.Errorf("admin approving a changed key = %d, want 204", rec.Code)
+	}
+	if err := <-done; err != nil {
+		t.Errorf("Check failed after approval: %v", err)
+	}
+}
@@ -0,0 +1,267 @@
+// Package webui provides password authentication against htpasswd-style
+// files, through HTTP Basic auth or a login form backed by a session cookie.
+package webui
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/opd-ai/go-gamelaunch-www/pkg/transport"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// htpasswdRealm is the default realm of the Basic auth challenge
+const htpasswdRealm = "dgconnect-www"
+
+// HtpasswdConfig configures password login against an htpasswd file with
+// bcrypt ("htpasswd -B") or SHA-1 ("htpasswd -s") hashes
+type HtpasswdConfig struct {
+	File  string `yaml:"file"`
+	Realm string `yaml:"realm,omitempty"` // of the Basic auth challenge, default "dgconnect-www"
+
+	// Authorization mapping
+	UserRoles   map[string]string `yaml:"user_roles,omitempty"`   // user -> role
+	DefaultRole string            `yaml:"default_role,omitempty"` // role of other users; empty denies
+
+	SessionTTL string `yaml:"session_ttl,omitempty" yamlcheck:"duration"` // e.g. "24h"
+}
+
+// HtpasswdAuthenticator checks passwords against an htpasswd file, which is
+// reloaded when it changes
+type HtpasswdAuthenticator struct {
+	RoleAuthorizer
+	config   HtpasswdConfig
+	sessions *authSessionStore
+
+	mu       sync.Mutex
+	hashes   map[string]string            // user -> hash
+	verified map[string][sha256.Size]byte // user -> digest of the last verified password
+	modTime  time.Time
+}
+
+// NewHtpasswdAuthenticator validates cfg, loads the password file and
+// creates an authenticator
+func NewHtpasswdAuthenticator(cfg HtpasswdConfig) (*HtpasswdAuthenticator, error) {
+	if cfg.File == "" {
+		return nil, fmt.Errorf("htpasswd: file is required")
+	}
+	if err := validateRoleMapping(nil, cfg.DefaultRole); err != nil {
+		return nil, fmt.Errorf("htpasswd: %w", err)
+	}
+	for user, role := range cfg.UserRoles {
+		if _, ok := ParseRole(role); !ok {
+			return nil, fmt.Errorf("htpasswd: user %q maps to unknown role %q", user, role)
+		}
+	}
+
+	ttl := 24 * time.Hour
+	if cfg.SessionTTL != "" {
+		parsed, err := time.ParseDuration(cfg.SessionTTL)
+		if err != nil || parsed <= 0 {
+			return nil, fmt.Errorf("htpasswd: invalid session_ttl %q", cfg.SessionTTL)
+		}
+		ttl = parsed
+	}
+
+	a := &HtpasswdAuthenticator{config: cfg, sessions: newAuthSessionStore(ttl)}
+	if err := a.reload(); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// Authenticate returns the identity of the session cookie or the Basic auth
+// credentials on r, or nil
+func (a *HtpasswdAuthenticator) Authenticate(r *http.Request) *Identity {
+	if identity := a.sessions.fromRequest(r); identity != nil {
+		return identity
+	}
+	user, password, ok := r.BasicAuth()
+	if !ok {
+		return nil
+	}
+	return a.Login(user, password)
+}
+
+// Login checks a user's password and returns their identity, or nil
+func (a *HtpasswdAuthenticator) Login(user, password string) *Identity {
+	if err := a.reload(); err != nil {
+		slog.Error("webui.htpasswd: reload failed", "error", err)
+	}
+
+	a.mu.Lock()
+	hash, ok := a.hashes[user]
+	digest := sha256.Sum256([]byte(password))
+	last, cached := a.verified[user]
+	a.mu.Unlock()
+	if !ok {
+		return nil
+	}
+
+	// bcrypt is deliberately slow, so Basic auth clients that send the
+	// password with every request are only checked once
+	if !cached || subtle.ConstantTimeCompare(last[:], digest[:]) != 1 {
+		if !checkHtpasswdHash(hash, password) {
+			return nil
+		}
+		a.mu.Lock()
+		if a.hashes[user] == hash {
+			a.verified[user] = digest
+		}
+		a.mu.Unlock()
+	}
+
+	identity := &Identity{Subject: user, Name: user}
+	if role, ok := ParseRole(a.config.UserRoles[user]); ok {
+		identity.Role = role
+	} else {
+		identity.Role = Role(a.config.DefaultRole)
+	}
+	return identity
+}
+
+// Routes implements AuthProvider
+func (a *HtpasswdAuthenticator) Routes() map[string]http.HandlerFunc {
+	return map[string]http.HandlerFunc{
+		"/auth/session": a.HandleSession,
+	}
+}
+
+// Challenge implements AuthChallenger
+func (a *HtpasswdAuthenticator) Challenge() string {
+	realm := a.config.Realm
+	if realm == "" {
+		realm = htpasswdRealm
+	}
+	return fmt.Sprintf(`Basic realm=%q, charset="UTF-8"`, realm)
+}
+
+// HandleSession handles POST /auth/session with username and password form
+// fields, starting a cookie session, and DELETE /auth/session, ending it
+func (a *HtpasswdAuthenticator) HandleSession(rw http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPost:
+		r.Body = http.MaxBytesReader(rw, r.Body, 4096)
+		if err := r.ParseForm(); err != nil {
+			http.Error(rw, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		identity := a.Login(r.PostForm.Get("username"), r.PostForm.Get("password"))
+		if identity == nil {
+			http.Error(rw, "Invalid username or password", http.StatusUnauthorized)
+			return
+		}
+		if identity.Role == "" {
+			http.Error(rw, "Access denied", http.StatusForbidden)
+			return
+		}
+
+		token, err := a.sessions.create(identity)
+		if err != nil {
+			http.Error(rw, "Failed to create session", http.StatusInternalServerError)
+			return
+		}
+		a.sessions.setCookie(rw, r, token)
+
+		slog.Info("webui.htpasswd: user logged in", "subject", identity.Subject, "role", identity.Role, "correlation_id", transport.CorrelationID(r.Context()))
+		writeJSON(rw, http.StatusOK, identity)
+
+	case http.MethodDelete:
+		if cookie, err := r.Cookie(sessionCookieName); err == nil {
+			a.sessions.remove(cookie.Value)
+		}
+		clearCookie(rw, sessionCookieName, cookiePath(r))
+		rw.WriteHeader(http.StatusNoContent)
+
+	default:
+		rw.Header().Set("Allow", "POST, DELETE")
+		http.Error(rw, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// reload reads the password file if it changed since it was last loaded
+func (a *HtpasswdAuthenticator) reload() error {
+	info, err := os.Stat(a.config.File)
+	if err != nil {
+		return fmt.Errorf("htpasswd: %w", err)
+	}
+
+	a.mu.Lock()
+	unchanged := a.hashes != nil && info.ModTime().Equal(a.modTime)
+	a.mu.Unlock()
+	if unchanged {
+		return nil
+	}
+
+	hashes, err := loadHtpasswd(a.config.File)
+	if err != nil {
+		return err
+	}
+
+	a.mu.Lock()
+	a.hashes = hashes
+	a.verified = make(map[string][sha256.Size]byte)
+	a.modTime = info.ModTime()
+	a.mu.Unlock()
+	return nil
+}
+
+// loadHtpasswd parses "user:hash" lines, rejecting hash formats that cannot
+// be verified
+func loadHtpasswd(path string) (map[string]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("htpasswd: %w", err)
+	}
+	defer file.Close()
+
+	hashes := make(map[string]string)
+	scanner := bufio.NewScanner(file)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		user, hash, ok := strings.Cut(line, ":")
+		if !ok || user == "" {
+			return nil, fmt.Errorf("htpasswd: %s:%d: expected user:hash", path, lineNo)
+		}
+		if !isBcryptHash(hash) && !strings.HasPrefix(hash, "{SHA}") {
+			return nil, fmt.Errorf("htpasswd: %s:%d: unsupported hash for user %q, use bcrypt (htpasswd -B)", path, lineNo, user)
+		}
+		hashes[user] = hash
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("htpasswd: %w", err)
+	}
+	return hashes, nil
+}
+
+// isBcryptHash reports whether hash uses one of the bcrypt prefixes
+func isBcryptHash(hash string) bool {
+	return strings.HasPrefix(hash, "$2a$") || strings.HasPrefix(hash, "$2b$") || strings.HasPrefix(hash, "$2y$")
+}
+
+// checkHtpasswdHash reports whether password matches an htpasswd hash
+func checkHtpasswdHash(hash, password string) bool {
+	if isBcryptHash(hash) {
+		return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+	}
+	if encoded, ok := strings.CutPrefix(hash, "{SHA}"); ok {
+		sum := sha1.Sum([]byte(password))
+		expected := base64.StdEncoding.EncodeToString(sum[:])
+		return subtle.ConstantTimeCompare([]byte(encoded), []byte(expected)) == 1
+	}
+	return false
+}
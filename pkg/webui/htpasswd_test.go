@@ -0,0 +1,203 @@
+package webui
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// writeHtpasswd writes an htpasswd file with a bcrypt entry for alice
+// ("wonderland") and a SHA-1 entry for bob ("builder")
+func writeHtpasswd(t *testing.T) string {
+	t.Helper()
+
+	hash, err := bcrypt.GenerateFromPassword([]byte("wonderland"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("Failed to hash password: %v", err)
+	}
+	content := "# users\nalice:" + string(hash) + "\nbob:{SHA}9SMYoF5RilWWASry7TjeaKwmpGg=\n"
+	path := filepath.Join(t.TempDir(), "htpasswd")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("Failed to write htpasswd: %v", err)
+	}
+	return path
+}
+
+func TestNewHtpasswdAuthenticator_InvalidConfig(t *testing.T) {
+	dir := t.TempDir()
+	md5File := filepath.Join(dir, "md5")
+	os.WriteFile(md5File, []byte("alice:$apr1$abc$def\n"), 0o600)
+	malformed := filepath.Join(dir, "malformed")
+	os.WriteFile(malformed, []byte("alice\n"), 0o600)
+	valid := writeHtpasswd(t)
+
+	tests := []struct {
+		name string
+		cfg  HtpasswdConfig
+	}{
+		{"no file", HtpasswdConfig{}},
+		{"missing file", HtpasswdConfig{File: filepath.Join(dir, "nope")}},
+		{"md5 hash", HtpasswdConfig{File: md5File}},
+		{"malformed line", HtpasswdConfig{File: malformed}},
+		{"bad default role", HtpasswdConfig{File: valid, DefaultRole: "root"}},
+		{"bad user role", HtpasswdConfig{File: valid, UserRoles: map[string]string{"alice": "root"}}},
+		{"bad session ttl", HtpasswdConfig{File: valid, SessionTTL: "soon"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewHtpasswdAuthenticator(tt.cfg); err == nil {
+				t.Error("Expected configuration error")
+			}
+		})
+	}
+}
+
+func TestHtpasswdAuthenticator_Login(t *testing.T) {
+	auth, err := NewHtpasswdAuthenticator(HtpasswdConfig{
+		File:        writeHtpasswd(t),
+		UserRoles:   map[string]string{"alice": "admin"},
+		DefaultRole: "spectator",
+	})
+	if err != nil {
+		t.Fatalf("Failed to create authenticator: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		user     string
+		password string
+		wantRole Role
+	}{
+		{"bcrypt", "alice", "wonderland", RoleAdmin},
+		{"bcrypt cached", "alice", "wonderland", RoleAdmin},
+		{"bcrypt wrong password", "alice", "looking-glass", ""},
+		{"sha1 default role", "bob", "builder", RoleSpectator},
+		{"unknown user", "carol", "builder", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			identity := auth.Login(tt.user, tt.password)
+			if tt.wantRole == "" {
+				if identity != nil {
+					t.Errorf("Expected login to fail, got %+v", identity)
+				}
+				return
+			}
+			if identity == nil || identity.Role != tt.wantRole {
+				t.Errorf("Expected role %q, got %+v", tt.wantRole, identity)
+			}
+		})
+	}
+}
+
+func TestHtpasswdAuthenticator_ReloadsChangedFile(t *testing.T) {
+	path := writeHtpasswd(t)
+	auth, err := NewHtpasswdAuthenticator(HtpasswdConfig{File: path, DefaultRole: "player"})
+	if err != nil {
+		t.Fatalf("Failed to create authenticator: %v", err)
+	}
+	if auth.Login("bob", "builder") == nil {
+		t.Fatal("Expected bob to log in")
+	}
+
+	if err := os.WriteFile(path, []byte("alice:{SHA}9SMYoF5RilWWASry7TjeaKwmpGg=\n"), 0o600); err != nil {
+		t.Fatalf("Failed to rewrite htpasswd: %v", err)
+	}
+	later := time.Now().Add(time.Minute)
+	os.Chtimes(path, later, later)
+
+	if auth.Login("bob", "builder") != nil {
+		t.Error("Expected bob to be removed after reload")
+	}
+	if auth.Login("alice", "builder") == nil {
+		t.Error("Expected alice's new password to be accepted")
+	}
+}
+
+func TestWebUI_Htpasswd_BasicAuthAndSessions(t *testing.T) {
+	ui, err := NewWebUI(WebUIOptions{
+		View:     newTestView(t),
+		Htpasswd: &HtpasswdConfig{File: writeHtpasswd(t), DefaultRole: "player"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create WebUI: %v", err)
+	}
+
+	// Anonymous requests are challenged for Basic auth
+	rr := httptest.NewRecorder()
+	ui.ServeHTTP(rr, httptest.NewRequest("GET", "/version", nil))
+	if rr.Code != http.StatusUnauthorized || !strings.HasPrefix(rr.Header().Get("WWW-Authenticate"), "Basic ") {
+		t.Fatalf("Expected Basic challenge, got %d %q", rr.Code, rr.Header().Get("WWW-Authenticate"))
+	}
+
+	req := httptest.NewRequest("GET", "/version", nil)
+	req.SetBasicAuth("bob", "builder")
+	rr = httptest.NewRecorder()
+	ui.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected Basic auth to succeed, got %d", rr.Code)
+	}
+
+	// A login form post starts a cookie session
+	form := url.Values{"username": {"alice"}, "password": {"wonderland"}}
+	req = httptest.NewRequest("POST", "/auth/session", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rr = httptest.NewRecorder()
+	ui.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected login to succeed, got %d", rr.Code)
+	}
+	cookies := rr.Result().Cookies()
+	if len(cookies) == 0 || cookies[0].Name != sessionCookieName {
+		t.Fatalf("Expected session cookie, got %v", cookies)
+	}
+
+	req = httptest.NewRequest("GET", "/version", nil)
+	req.AddCookie(cookies[0])
+	rr = httptest.NewRecorder()
+	ui.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected session to authenticate, got %d", rr.Code)
+	}
+
+	// Logging out ends the session
+	req = httptest.NewRequest("DELETE", "/auth/session", nil)
+	req.AddCookie(cookies[0])
+	ui.ServeHTTP(httptest.NewRecorder(), req)
+
+	req = httptest.NewRequest("GET", "/version", nil)
+	req.AddCookie(cookies[0])
+	rr = httptest.NewRecorder()
+	ui.ServeHTTP(rr, req)
+	if rr.Code != http.StatusUnauthorized {
+		t.Errorf("Expected logged-out session to be rejected, got %d", rr.Code)
+	}
+}
+
+func TestWebUI_Htpasswd_RejectsBadPassword(t *testing.T) {
+	ui, err := NewWebUI(WebUIOptions{
+		View:     newTestView(t),
+		Htpasswd: &HtpasswdConfig{File: writeHtpasswd(t), DefaultRole: "player"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create WebUI: %v", err)
+	}
+
+	form := url.Values{"username": {"alice"}, "password": {"wrong"}}
+	req := httptest.NewRequest("POST", "/auth/session", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rr := httptest.NewRecorder()
+	ui.ServeHTTP(rr, req)
+	if rr.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401, got %d", rr.Code)
+	}
+}
@@ -0,0 +1,75 @@
+// Package webui provides an optional HTTP/3 (QUIC) listener alongside the
+// TCP one.
+package webui
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/http3"
+)
+
+// HTTP3Config serves the web UI over HTTP/3 in addition to TCP, which holds
+// up better for long polls and streams on lossy mobile networks. HTTP/3
+// requires TLS, so the TCP listener serves HTTPS with the same certificate
+// and advertises the QUIC listener through Alt-Svc. WebSockets stay on TCP.
+type HTTP3Config struct {
+	Listen   string `yaml:"listen,omitempty"` // UDP address; default the web address
+	CertFile string `yaml:"cert_file"`
+	KeyFile  string `yaml:"key_file"`
+}
+
+// newHTTP3TLSConfig validates cfg and loads its certificate
+func newHTTP3TLSConfig(cfg HTTP3Config) (*tls.Config, error) {
+	if cfg.CertFile == "" || cfg.KeyFile == "" {
+		return nil, fmt.Errorf("http3: cert_file and key_file are required")
+	}
+	cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("http3: %w", err)
+	}
+	return &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12}, nil
+}
+
+// listen starts server, and the HTTP/3 listener when configured, in the
+// background. The first listener error is sent on the returned channel;
+// shutdown gracefully stops every listener.
+func (w *WebUI) listen(server *http.Server) (errs <-chan error, shutdown func(context.Context) error) {
+	errCh := make(chan error, 2)
+	if w.http3TLS == nil {
+		go func() { errCh <- server.ListenAndServe() }()
+		return errCh, server.Shutdown
+	}
+
+	addr := w.options.HTTP3.Listen
+	if addr == "" {
+		addr = server.Addr
+	}
+	quicServer := &http3.Server{
+		Addr:      addr,
+		Handler:   server.Handler,
+		TLSConfig: w.http3TLS.Clone(),
+		// Without 0-RTT, requests such as input cannot be replayed
+		QUICConfig: &quic.Config{},
+	}
+
+	handler := server.Handler
+	server.Handler = http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		quicServer.SetQUICHeaders(rw.Header())
+		handler.ServeHTTP(rw, r)
+	})
+	server.TLSConfig = w.http3TLS.Clone()
+
+	slog.Info("webui: serving HTTP/3", "addr", addr)
+	go func() { errCh <- server.ListenAndServeTLS("", "") }()
+	go func() { errCh <- quicServer.ListenAndServe() }()
+
+	return errCh, func(ctx context.Context) error {
+		return errors.Join(server.Shutdown(ctx), quicServer.Shutdown(ctx))
+	}
+}
@@ -0,0 +1,151 @@
+package webui
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/opd-ai/go-gamelaunch-client/pkg/dgclient"
+	"github.com/quic-go/quic-go/http3"
+)
+
+// writeTestCertificate writes a self-signed certificate for 127.0.0.1 and
+// returns the certificate and key paths with a pool trusting it
+func writeTestCertificate(t *testing.T) (certFile, keyFile string, pool *x509.CertPool) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey() error = %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "127.0.0.1"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("CreateCertificate() error = %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("MarshalECPrivateKey() error = %v", err)
+	}
+
+	dir := t.TempDir()
+	certFile = filepath.Join(dir, "cert.pem")
+	keyFile = filepath.Join(dir, "key.pem")
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool = x509.NewCertPool()
+	pool.AddCert(cert)
+	return certFile, keyFile, pool
+}
+
+func TestWebUI_HTTP3_ServesAlongsideTCP(t *testing.T) {
+	certFile, keyFile, pool := writeTestCertificate(t)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := listener.Addr().String()
+	listener.Close()
+
+	view, err := NewWebView(dgclient.ViewOptions{InitialWidth: 80, InitialHeight: 24})
+	if err != nil {
+		t.Fatalf("Failed to create WebView: %v", err)
+	}
+	webUI, err := NewWebUI(WebUIOptions{
+		View:  view,
+		HTTP3: &HTTP3Config{CertFile: certFile, KeyFile: keyFile},
+	})
+	if err != nil {
+		t.Fatalf("NewWebUI() error = %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- webUI.StartWithContext(ctx, addr) }()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	tlsConfig := &tls.Config{RootCAs: pool}
+	h3 := &http3.Transport{TLSClientConfig: tlsConfig}
+	defer h3.Close()
+	clients := map[string]*http.Client{
+		"tcp":  {Transport: &http.Transport{TLSClientConfig: tlsConfig}, Timeout: time.Second},
+		"quic": {Transport: h3, Timeout: time.Second},
+	}
+
+	for name, client := range clients {
+		var resp *http.Response
+		deadline := time.Now().Add(5 * time.Second)
+		for {
+			resp, err = client.Get("https://" + addr + "/version")
+			if err == nil || time.Now().After(deadline) {
+				break
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+		if err != nil {
+			t.Fatalf("%s: GET /version error = %v", name, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("%s: status = %d, want %d", name, resp.StatusCode, http.StatusOK)
+		}
+		if name == "tcp" && !strings.Contains(resp.Header.Get("Alt-Svc"), "h3=") {
+			t.Errorf("tcp: Alt-Svc = %q, want the HTTP/3 listener advertised", resp.Header.Get("Alt-Svc"))
+		}
+		if name == "quic" && resp.ProtoMajor != 3 {
+			t.Errorf("quic: protocol = %s, want HTTP/3", resp.Proto)
+		}
+	}
+}
+
+func TestNewWebUI_RejectsInvalidHTTP3Config(t *testing.T) {
+	certFile, _, _ := writeTestCertificate(t)
+	view, err := NewWebView(dgclient.ViewOptions{InitialWidth: 80, InitialHeight: 24})
+	if err != nil {
+		t.Fatalf("Failed to create WebView: %v", err)
+	}
+
+	for _, cfg := range []HTTP3Config{
+		{},
+		{CertFile: certFile},
+		{CertFile: certFile, KeyFile: filepath.Join(t.TempDir(), "missing.pem")},
+	} {
+		if _, err := NewWebUI(WebUIOptions{View: view, HTTP3: &cfg}); err == nil {
+			t.Errorf("NewWebUI(HTTP3: %+v) succeeded, want error", cfg)
+		}
+	}
+}
@@ -0,0 +1,224 @@
+// Package webui provides HTTP input delivery with idempotent retries.
+package webui
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/opd-ai/go-gamelaunch-www/pkg/transport"
+)
+
+const (
+	// maxInputBatchBytes bounds the request body of POST /input
+	maxInputBatchBytes = 64 << 10
+
+	// maxInputClients bounds the number of clients whose last applied
+	// batch is remembered
+	maxInputClients = 4096
+
+	// inputClientTTL is how long the last applied batch of an idle client
+	// is remembered
+	inputClientTTL = 10 * time.Minute
+)
+
+// namedKeys maps the key names of InputEvent to terminal input
+var namedKeys = map[string]string{
+	"Enter":      "\r",
+	"Backspace":  "\x7f",
+	"Tab":        "\t",
+	"Escape":     "\x1b",
+	"ArrowUp":    "\x1b[A",
+	"ArrowDown":  "\x1b[B",
+	"ArrowRight": "\x1b[C",
+	"ArrowLeft":  "\x1b[D",
+	"Home":       "\x1b[H",
+	"End":        "\x1b[F",
+	"PageUp":     "\x1b[5~",
+	"PageDown":   "\x1b[6~",
+	"Insert":     "\x1b[2~",
+	"Delete":     "\x1b[3~",
+}
+
+// InputEvent is a key press or typed text sent by an HTTP client. Data, when
+// set, is sent to the game as is; otherwise Key is a single character or a
+// key name such as "Enter" or "ArrowUp".
+type InputEvent struct {
+	Type      string `json:"type"`
+	Key       string `json:"key,omitempty"`
+	KeyCode   int    `json:"keyCode,omitempty"`
+	Data      string `json:"data,omitempty"`
+	Timestamp int64  `json:"timestamp"`
+}
+
+// InputBatch is the request body of POST /input. Seq increases with every
+// new batch from Client and stays the same when a batch is retried, so the
+// server applies each batch at most once. A batch older than the last one
+// applied is refused with 409 Conflict. Client IDs are scoped to the
+// authenticated user, or to the client's address without authentication.
+type InputBatch struct {
+	Client string       `json:"client"` // client-generated ID, e.g. a random token
+	Seq    uint64       `json:"seq"`
+	Events []InputEvent `json:"events"`
+}
+
+// InputResult is the response to POST /input
+type InputResult struct {
+	Applied bool   `json:"applied"` // false when the batch was a retry of the last one applied
+	Seq     uint64 `json:"seq"`     // last batch applied for the client
+}
+
+// bytes returns the terminal input of the event, with named keys remapped
+// by preset
+func (e InputEvent) bytes(preset *InputPreset) ([]byte, error) {
+	if e.Data != "" {
+		return []byte(e.Data), nil
+	}
+	if input, ok := preset.translate(e.Key); ok {
+		return []byte(input), nil
+	}
+	if seq, ok := namedKeys[e.Key]; ok {
+		return []byte(seq), nil
+	}
+	if utf8.RuneCountInString(e.Key) == 1 {
+		return []byte(e.Key), nil
+	}
+	return nil, fmt.Errorf("unknown key %q", e.Key)
+}
+
+// inputSequencer remembers the last batch applied for each client
+type inputSequencer struct {
+	mu      sync.Mutex
+	clients map[string]*inputClient
+}
+
+// inputClient is the last batch applied for a client. mu is held while a
+// batch is checked and sent, so that a retry waits for the original.
+type inputClient struct {
+	mu   sync.Mutex
+	seq  uint64
+	seen time.Time // guarded by inputSequencer.mu
+}
+
+// newInputSequencer creates an empty sequencer
+func newInputSequencer() *inputSequencer {
+	return &inputSequencer{clients: make(map[string]*inputClient)}
+}
+
+// lock returns client, locked; the caller applies a new batch and records
+// its seq before unlocking
+func (s *inputSequencer) lock(client string, now time.Time) *inputClient {
+	s.mu.Lock()
+	c, ok := s.clients[client]
+	if !ok {
+		if len(s.clients) >= maxInputClients {
+			s.evictLocked(now)
+		}
+		c = &inputClient{}
+		s.clients[client] = c
+	}
+	c.seen = now
+	s.mu.Unlock()
+
+	c.mu.Lock()
+	return c
+}
+
+// evictLocked forgets idle clients, or the least recently seen one when
+// none is idle; s.mu must be held
+func (s *inputSequencer) evictLocked(now time.Time) {
+	var oldest string
+	for id, c := range s.clients {
+		if now.Sub(c.seen) > inputClientTTL {
+			delete(s.clients, id)
+			continue
+		}
+		if oldest == "" || c.seen.Before(s.clients[oldest].seen) {
+			oldest = id
+		}
+	}
+	if len(s.clients) >= maxInputClients {
+		delete(s.clients, oldest)
+	}
+}
+
+// handleInput serves POST /input, sending a batch of input events to the game
+// unless the batch was already applied
+func (w *WebUI) handleInput(rw http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		rw.Header().Set("Allow", http.MethodPost)
+		http.Error(rw, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// A batch waiting for the one before it holds a connection, which
+	// counts toward the per-IP limit like WebSocket clients
+	release, limitErr := w.wsHandler.ReserveAddrSlot(transport.RemoteIP(r))
+	if limitErr != nil {
+		writeJSON(rw, http.StatusTooManyRequests, limitErr)
+		return
+	}
+	defer release()
+
+	var batch InputBatch
+	if err := json.NewDecoder(http.MaxBytesReader(rw, r.Body, maxInputBatchBytes)).Decode(&batch); err != nil {
+		http.Error(rw, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if batch.Client == "" || batch.Seq == 0 {
+		http.Error(rw, "client and seq are required", http.StatusBadRequest)
+		return
+	}
+
+	var data []byte
+	preset := w.inputPreset.Load()
+	for _, event := range batch.Events {
+		input, err := event.bytes(preset)
+		if err != nil {
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+		data = append(data, input...)
+	}
+	data, err := w.filterInput(r.Context(), data)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusForbidden)
+		return
+	}
+
+	// Clients number batches of their own; IDs chosen by other callers
+	// must not mark them applied
+	caller := "ip:" + transport.RemoteIP(r)
+	if identity := IdentityFromContext(r.Context()); identity != nil {
+		caller = "user:" + identity.Subject
+	}
+	client := w.inputSeqs.lock(caller+"\x00"+batch.Client, time.Now())
+	defer client.mu.Unlock()
+	switch {
+	case batch.Seq == client.seq:
+		w.logger(r.Context()).Debug("webui.handleInput: duplicate batch ignored", "client", batch.Client, "seq", batch.Seq)
+		writeJSON(rw, http.StatusOK, InputResult{Seq: client.seq})
+		return
+	case batch.Seq < client.seq:
+		http.Error(rw, fmt.Sprintf("seq %d is older than the last applied batch %d", batch.Seq, client.seq), http.StatusConflict)
+		return
+	}
+
+	// The batch counts as applied only once sent, so that a failed one
+	// can be retried
+	if len(data) > 0 {
+		if view := w.GetView(); view != nil {
+			w.logger(r.Context()).Debug("webui.handleInput", "client", batch.Client, "seq", batch.Seq, "input", view.RedactInput(data))
+		}
+		if err := w.sendGameInput(data); err != nil {
+			http.Error(rw, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		w.keys.add(data)
+	}
+	client.seq = batch.Seq
+	writeJSON(rw, http.StatusOK, InputResult{Applied: true, Seq: client.seq})
+}
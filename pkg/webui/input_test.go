@@ -0,0 +1,201 @@
+package webui
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWebUI_HandleInput_AppliesRetriedBatchOnce(t *testing.T) {
+	var sent []string
+	ui, err := NewWebUI(WebUIOptions{
+		View: newTestView(t),
+		InputSink: func(data []byte) error {
+			sent = append(sent, string(data))
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create WebUI: %v", err)
+	}
+
+	requests := []struct {
+		body        string
+		wantApplied bool
+		wantSeq     uint64
+	}{
+		{`{"client":"c1","seq":1,"events":[{"type":"key","key":"h"},{"type":"key","key":"Enter"}]}`, true, 1},
+		{`{"client":"c1","seq":1,"events":[{"type":"key","key":"h"},{"type":"key","key":"Enter"}]}`, false, 1},
+		{`{"client":"c2","seq":1,"events":[{"type":"text","data":"yes"}]}`, true, 1},
+		{`{"client":"c1","seq":3,"events":[{"type":"key","key":"ArrowUp"}]}`, true, 3},
+	}
+	for i, req := range requests {
+		rr := httptest.NewRecorder()
+		ui.ServeHTTP(rr, httptest.NewRequest("POST", "/input", strings.NewReader(req.body)))
+		if rr.Code != http.StatusOK {
+			t.Fatalf("request %d: status = %d, want 200: %s", i, rr.Code, rr.Body.String())
+		}
+		var result InputResult
+		if err := json.Unmarshal(rr.Body.Bytes(), &result); err != nil {
+			t.Fatalf("request %d: invalid response: %v", i, err)
+		}
+		if result.Applied != req.wantApplied || result.Seq != req.wantSeq {
+			t.Errorf("request %d: result = %+v, want applied %v at seq %d", i, result, req.wantApplied, req.wantSeq)
+		}
+	}
+
+	// A batch arriving after a newer one is refused, not acknowledged
+	rr := httptest.NewRecorder()
+	ui.ServeHTTP(rr, httptest.NewRequest("POST", "/input", strings.NewReader(`{"client":"c1","seq":2,"events":[{"type":"key","key":"j"}]}`)))
+	if rr.Code != http.StatusConflict {
+		t.Errorf("out-of-order batch: status = %d, want 409", rr.Code)
+	}
+
+	want := []string{"h\r", "yes", "\x1b[A"}
+	if strings.Join(sent, "|") != strings.Join(want, "|") {
+		t.Errorf("game input = %q, want %q", sent, want)
+	}
+}
+
+func TestWebUI_HandleInput_RetriesFailedSend(t *testing.T) {
+	var sent []string
+	fail := true
+	ui, err := NewWebUI(WebUIOptions{
+		View: newTestView(t),
+		InputSink: func(data []byte) error {
+			if fail {
+				return errors.New("game unavailable")
+			}
+			sent = append(sent, string(data))
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create WebUI: %v", err)
+	}
+	body := `{"client":"c1","seq":1,"events":[{"type":"text","data":"go"}]}`
+
+	rr := httptest.NewRecorder()
+	ui.ServeHTTP(rr, httptest.NewRequest("POST", "/input", strings.NewReader(body)))
+	if rr.Code != http.StatusServiceUnavailable {
+		t.Fatalf("status = %d, want 503 while the game is unavailable", rr.Code)
+	}
+
+	fail = false
+	rr = httptest.NewRecorder()
+	ui.ServeHTTP(rr, httptest.NewRequest("POST", "/input", strings.NewReader(body)))
+	var result InputResult
+	if err := json.Unmarshal(rr.Body.Bytes(), &result); err != nil || !result.Applied {
+		t.Errorf("retry = %d %s, want applied", rr.Code, rr.Body.String())
+	}
+	if len(sent) != 1 || sent[0] != "go" {
+		t.Errorf("game input = %q, want the retried batch", sent)
+	}
+}
+
+func TestWebUI_HandleInput_ScopesClientIDsToCaller(t *testing.T) {
+	var sent []string
+	ui, err := NewWebUI(WebUIOptions{
+		View: newTestView(t),
+		InputSink: func(data []byte) error {
+			sent = append(sent, string(data))
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create WebUI: %v", err)
+	}
+
+	// Another caller reusing a client ID cannot mark its batches applied
+	for _, addr := range []string{"192.0.2.1:1000", "192.0.2.2:1000"} {
+		req := httptest.NewRequest("POST", "/input", strings.NewReader(`{"client":"c1","seq":5,"events":[{"type":"text","data":"x"}]}`))
+		req.RemoteAddr = addr
+		rr := httptest.NewRecorder()
+		ui.ServeHTTP(rr, req)
+		var result InputResult
+		if err := json.Unmarshal(rr.Body.Bytes(), &result); err != nil || !result.Applied {
+			t.Errorf("batch from %s = %d %s, want applied", addr, rr.Code, rr.Body.String())
+		}
+	}
+	if len(sent) != 2 {
+		t.Errorf("game input = %q, want both batches", sent)
+	}
+}
+
+func TestWebUI_HandleInput_EnforcesPerIPLimit(t *testing.T) {
+	ui, err := NewWebUI(WebUIOptions{View: newTestView(t), MaxClientsPerIP: 1})
+	if err != nil {
+		t.Fatalf("Failed to create WebUI: %v", err)
+	}
+	release, limitErr := ui.wsHandler.ReserveAddrSlot("192.0.2.1")
+	if limitErr != nil {
+		t.Fatalf("ReserveAddrSlot failed: %v", limitErr.Message)
+	}
+
+	post := func() int {
+		req := httptest.NewRequest("POST", "/input", strings.NewReader(`{"client":"c1","seq":1,"events":[{"type":"text","data":"x"}]}`))
+		req.RemoteAddr = "192.0.2.1:1000"
+		rr := httptest.NewRecorder()
+		ui.ServeHTTP(rr, req)
+		return rr.Code
+	}
+	if code := post(); code != http.StatusTooManyRequests {
+		t.Errorf("status = %d while the address holds its connections, want 429", code)
+	}
+	release()
+	if code := post(); code != http.StatusOK {
+		t.Errorf("status = %d after a connection closed, want 200", code)
+	}
+}
+
+func TestWebUI_HandleInput_RejectsInvalidBatches(t *testing.T) {
+	ui := newTestWebUI(t)
+	for _, body := range []string{
+		`{"seq":1,"events":[]}`,
+		`{"client":"c1","events":[]}`,
+		`{"client":"c1","seq":1,"events":[{"type":"key","key":"Hyper"}]}`,
+		`not json`,
+	} {
+		rr := httptest.NewRecorder()
+		ui.ServeHTTP(rr, httptest.NewRequest("POST", "/input", strings.NewReader(body)))
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("POST %s: status = %d, want 400", body, rr.Code)
+		}
+	}
+
+	// A rejected batch does not use up its sequence number
+	if len(ui.inputSeqs.clients) != 0 {
+		t.Error("invalid batch was recorded as applied")
+	}
+}
+
+func TestInputSequencer_EvictsIdleClients(t *testing.T) {
+	s := newInputSequencer()
+	start := time.Now()
+	accept := func(client string, seq uint64, now time.Time) (bool, uint64) {
+		c := s.lock(client, now)
+		defer c.mu.Unlock()
+		if seq <= c.seq {
+			return false, c.seq
+		}
+		c.seq = seq
+		return true, seq
+	}
+	for i := 0; i < maxInputClients; i++ {
+		accept(string(rune('a'+i%26))+strings.Repeat("x", i/26), 1, start)
+	}
+	accept("a", 2, start.Add(inputClientTTL+2*time.Minute))
+
+	later := start.Add(inputClientTTL + time.Minute*3)
+	accept("new", 1, later)
+	if len(s.clients) != 2 {
+		t.Errorf("tracked clients = %d, want idle ones evicted", len(s.clients))
+	}
+	if applied, seq := accept("a", 2, later); applied || seq != 2 {
+		t.Errorf("accept(a, 2) = %v, %d; want the recent client remembered", applied, seq)
+	}
+}
@@ -0,0 +1,256 @@
+// Package webui provides the input filter chain that transforms or vetoes
+// client input before it reaches the game.
+package webui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// ErrInputRejected is wrapped by the errors of filters that veto input
+var ErrInputRejected = errors.New("input rejected")
+
+// InputFilter is a plugin that sees client input before it is sent to the
+// game. It returns the input to send, which may be changed or empty to drop
+// it silently, or an error to refuse it; the error is reported to the
+// client. ctx carries the client's identity (see IdentityFromContext).
+type InputFilter interface {
+	FilterInput(ctx context.Context, input []byte) ([]byte, error)
+}
+
+// InputFilterFunc adapts a function to InputFilter
+type InputFilterFunc func(ctx context.Context, input []byte) ([]byte, error)
+
+// FilterInput implements InputFilter
+func (f InputFilterFunc) FilterInput(ctx context.Context, input []byte) ([]byte, error) {
+	return f(ctx, input)
+}
+
+// InputFilterConfig configures the built-in input filters, applied in the
+// order remap, block, allow
+type InputFilterConfig struct {
+	// Remap replaces keys, given by name (see InputEvent) or as the literal
+	// input, with other input, e.g. {"Insert": "i"}
+	Remap map[string]string `yaml:"remap,omitempty"`
+
+	// Block lists words masked with asterisks when they appear in input
+	// sent in one piece, such as pasted text, ignoring case
+	Block []string `yaml:"block,omitempty"`
+
+	// Allow, when set, lists the only keys, by name or as literal input,
+	// that may be sent; input with any other key is refused, e.g. for demo
+	// kiosks
+	Allow []string `yaml:"allow,omitempty"`
+}
+
+// inputFilterChain runs the registered filters in order
+type inputFilterChain struct {
+	mu      sync.RWMutex
+	filters []InputFilter
+}
+
+// add appends filter to the chain
+func (c *inputFilterChain) add(filter InputFilter) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.filters = append(c.filters, filter)
+}
+
+// filter passes input through the filters in order, stopping when one
+// refuses it or leaves nothing to send
+func (c *inputFilterChain) filter(ctx context.Context, input []byte) ([]byte, error) {
+	c.mu.RLock()
+	filters := c.filters
+	c.mu.RUnlock()
+
+	for _, f := range filters {
+		var err error
+		if input, err = f.FilterInput(ctx, input); err != nil {
+			return nil, err
+		}
+		if len(input) == 0 {
+			return nil, nil
+		}
+	}
+	return input, nil
+}
+
+// AddInputFilter appends a filter to the chain that client input from
+// WebSocket connections and POST /input passes through. Input the server
+// sends itself, such as watch menu navigation, is not filtered.
+func (w *WebUI) AddInputFilter(filter InputFilter) {
+	w.inputFilters.add(filter)
+}
+
+// filterInput runs input through the filter chain
+func (w *WebUI) filterInput(ctx context.Context, input []byte) ([]byte, error) {
+	return w.inputFilters.filter(ctx, input)
+}
+
+// NewInputFilters validates cfg and returns its built-in filters in the
+// order they apply
+func NewInputFilters(cfg InputFilterConfig) ([]InputFilter, error) {
+	var filters []InputFilter
+	if len(cfg.Remap) > 0 {
+		remap, err := newRemapFilter(cfg.Remap)
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, remap)
+	}
+	if len(cfg.Block) > 0 {
+		block, err := newBlockFilter(cfg.Block)
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, block)
+	}
+	if len(cfg.Allow) > 0 {
+		allow, err := newAllowFilter(cfg.Allow)
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, allow)
+	}
+	return filters, nil
+}
+
+// keyInput returns the input of a key given by name or as literal input
+func keyInput(key string) string {
+	if seq, ok := namedKeys[key]; ok {
+		return seq
+	}
+	return key
+}
+
+// keyTokenizer splits input into keys: the longest named key sequence or
+// configured key at the start of the input, or else one character, so that
+// an arrow key is not taken for Escape followed by text
+type keyTokenizer struct {
+	seqs []string // longest first
+}
+
+// newKeyTokenizer creates a tokenizer that knows the named keys and keys
+func newKeyTokenizer(keys []string) keyTokenizer {
+	seqs := append([]string(nil), keys...)
+	for _, seq := range namedKeys {
+		seqs = append(seqs, seq)
+	}
+	sort.Slice(seqs, func(i, j int) bool {
+		if len(seqs[i]) != len(seqs[j]) {
+			return len(seqs[i]) > len(seqs[j])
+		}
+		return seqs[i] < seqs[j]
+	})
+	return keyTokenizer{seqs: seqs}
+}
+
+// next returns the key at the start of input, which must not be empty
+func (t keyTokenizer) next(input string) string {
+	for _, seq := range t.seqs {
+		if strings.HasPrefix(input, seq) {
+			return seq
+		}
+	}
+	_, size := utf8.DecodeRuneInString(input)
+	return input[:size]
+}
+
+// remapFilter replaces keys with other input
+type remapFilter struct {
+	keys keyTokenizer
+	to   map[string]string
+}
+
+// newRemapFilter validates remap and creates its filter
+func newRemapFilter(remap map[string]string) (*remapFilter, error) {
+	f := &remapFilter{to: make(map[string]string, len(remap))}
+	keys := make([]string, 0, len(remap))
+	for key, replacement := range remap {
+		if key == "" {
+			return nil, fmt.Errorf("input filter: empty key in remap")
+		}
+		keys = append(keys, keyInput(key))
+		f.to[keyInput(key)] = keyInput(replacement)
+	}
+	f.keys = newKeyTokenizer(keys)
+	return f, nil
+}
+
+// FilterInput implements InputFilter
+func (f *remapFilter) FilterInput(ctx context.Context, input []byte) ([]byte, error) {
+	var out []byte
+	for rest := string(input); rest != ""; {
+		key := f.keys.next(rest)
+		if replacement, ok := f.to[key]; ok {
+			out = append(out, replacement...)
+		} else {
+			out = append(out, key...)
+		}
+		rest = rest[len(key):]
+	}
+	return out, nil
+}
+
+// blockFilter masks blocked words
+type blockFilter struct {
+	words *regexp.Regexp
+}
+
+// newBlockFilter validates words and creates their filter
+func newBlockFilter(words []string) (*blockFilter, error) {
+	quoted := make([]string, 0, len(words))
+	for _, word := range words {
+		if strings.TrimSpace(word) == "" {
+			return nil, fmt.Errorf("input filter: empty word in block")
+		}
+		quoted = append(quoted, regexp.QuoteMeta(word))
+	}
+	return &blockFilter{words: regexp.MustCompile(`(?i)` + strings.Join(quoted, "|"))}, nil
+}
+
+// FilterInput implements InputFilter
+func (f *blockFilter) FilterInput(ctx context.Context, input []byte) ([]byte, error) {
+	return f.words.ReplaceAllFunc(input, func(word []byte) []byte {
+		return []byte(strings.Repeat("*", len([]rune(string(word)))))
+	}), nil
+}
+
+// allowFilter refuses input with keys outside its list
+type allowFilter struct {
+	keys    keyTokenizer
+	allowed map[string]bool
+}
+
+// newAllowFilter validates keys and creates their filter
+func newAllowFilter(keys []string) (*allowFilter, error) {
+	f := &allowFilter{allowed: make(map[string]bool, len(keys))}
+	seqs := make([]string, 0, len(keys))
+	for _, key := range keys {
+		if key == "" {
+			return nil, fmt.Errorf("input filter: empty key in allow")
+		}
+		seqs = append(seqs, keyInput(key))
+		f.allowed[keyInput(key)] = true
+	}
+	f.keys = newKeyTokenizer(seqs)
+	return f, nil
+}
+
+// FilterInput implements InputFilter
+func (f *allowFilter) FilterInput(ctx context.Context, input []byte) ([]byte, error) {
+	for rest := string(input); rest != ""; {
+		key := f.keys.next(rest)
+		if !f.allowed[key] {
+			return nil, fmt.Errorf("%w: key %q is not allowed", ErrInputRejected, key)
+		}
+		rest = rest[len(key):]
+	}
+	return input, nil
+}
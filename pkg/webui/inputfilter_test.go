@@ -0,0 +1,129 @@
+package webui
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNewInputFilters_Validation(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     InputFilterConfig
+		want    int
+		wantErr bool
+	}{
+		{"Empty", InputFilterConfig{}, 0, false},
+		{"All", InputFilterConfig{Remap: map[string]string{"Insert": "i"}, Block: []string{"darn"}, Allow: []string{"h", "Enter"}}, 3, false},
+		{"EmptyRemapKey", InputFilterConfig{Remap: map[string]string{"": "x"}}, 0, true},
+		{"EmptyBlockWord", InputFilterConfig{Block: []string{" "}}, 0, true},
+		{"EmptyAllowKey", InputFilterConfig{Allow: []string{"h", ""}}, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filters, err := NewInputFilters(tt.cfg)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewInputFilters() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(filters) != tt.want {
+				t.Errorf("NewInputFilters() returned %d filters, want %d", len(filters), tt.want)
+			}
+		})
+	}
+}
+
+func TestInputFilters_FilterInput(t *testing.T) {
+	cfg := InputFilterConfig{
+		Remap: map[string]string{"ArrowUp": "k", "Escape": "q"},
+		Block: []string{"darn"},
+		Allow: []string{"h", "j", "k", "q", "*", " ", "Enter", "ArrowDown"},
+	}
+	filters, err := NewInputFilters(cfg)
+	if err != nil {
+		t.Fatalf("NewInputFilters failed: %v", err)
+	}
+	chain := &inputFilterChain{filters: filters}
+
+	tests := []struct {
+		name    string
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{"Allowed", "hj\r", "hj\r", false},
+		{"RemappedSequence", "\x1b[A\x1b", "kq", false},
+		{"AllowedSequence", "\x1b[B", "\x1b[B", false},
+		{"Masked", "DARN", "****", false},
+		{"Refused", "hx", "", true},
+		{"RefusedSequence", "\x1b[C", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := chain.filter(context.Background(), []byte(tt.input))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("filter() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrInputRejected) {
+				t.Errorf("filter() error = %v, want ErrInputRejected", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("filter() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWebUI_AddInputFilter(t *testing.T) {
+	var sent []string
+	ui, err := NewWebUI(WebUIOptions{
+		View:        newTestView(t),
+		InputFilter: &InputFilterConfig{Remap: map[string]string{"x": "y"}},
+		InputSink: func(data []byte) error {
+			sent = append(sent, string(data))
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create WebUI: %v", err)
+	}
+	// A plugin that drops input of spaces and vetoes quitting, after the
+	// configured filters
+	ui.AddInputFilter(InputFilterFunc(func(ctx context.Context, input []byte) ([]byte, error) {
+		if bytes.Contains(input, []byte("Q")) {
+			return nil, ErrInputRejected
+		}
+		return bytes.ReplaceAll(input, []byte(" "), nil), nil
+	}))
+
+	if err := ui.handleClientInput("c1", "x"); err != nil {
+		t.Fatalf("handleClientInput failed: %v", err)
+	}
+	if err := ui.handleClientInput("c1", " "); err != nil {
+		t.Fatalf("handleClientInput failed: %v", err)
+	}
+	if err := ui.handleClientInput("c1", "Q"); !errors.Is(err, ErrInputRejected) {
+		t.Errorf("handleClientInput(Q) error = %v, want ErrInputRejected", err)
+	}
+
+	rec := httptest.NewRecorder()
+	body := `{"client":"c1","seq":1,"events":[{"type":"key","key":"Q"}]}`
+	ui.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/input", strings.NewReader(body)))
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("POST /input with vetoed input = %d, want 403", rec.Code)
+	}
+	rec = httptest.NewRecorder()
+	body = `{"client":"c1","seq":1,"events":[{"type":"text","data":"a x"}]}`
+	ui.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/input", strings.NewReader(body)))
+	if rec.Code != http.StatusOK {
+		t.Errorf("POST /input = %d, want 200: %s", rec.Code, rec.Body.String())
+	}
+
+	want := []string{"y", "ay"}
+	if strings.Join(sent, "|") != strings.Join(want, "|") {
+		t.Errorf("game input = %q, want %q", sent, want)
+	}
+}
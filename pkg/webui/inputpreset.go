@@ -0,0 +1,226 @@
+// Package webui provides per-game input presets that remap keys and tell
+// clients which controls to show.
+package webui
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/opd-ai/go-gamelaunch-www/pkg/i18n"
+)
+
+// KeyHint describes a key for the client's controls help
+type KeyHint struct {
+	Key    string `json:"key"`
+	Action string `json:"action"`
+}
+
+// InputPreset adapts the controls to a game. Keymap replaces the input of
+// named keys (see InputEvent) before it reaches the game, e.g. to send vi
+// keys for the arrows, and Hints are advertised to clients.
+type InputPreset struct {
+	Name        string            `json:"name"`
+	Game        string            `json:"game"`
+	Description string            `json:"description"`
+	Keymap      map[string]string `json:"keymap,omitempty"`
+	Hints       []KeyHint         `json:"hints"`
+}
+
+// viKeys moves with hjkl and yubn, the default of NetHack and the
+// roguelike keyset of Angband and DCSS
+var viKeys = map[string]string{
+	"ArrowLeft":  "h",
+	"ArrowDown":  "j",
+	"ArrowUp":    "k",
+	"ArrowRight": "l",
+	"Home":       "y",
+	"PageUp":     "u",
+	"End":        "b",
+	"PageDown":   "n",
+}
+
+// viMoveHints advertises the movement keys of viKeys
+var viMoveHints = []KeyHint{
+	{"h j k l", "move west, south, north, east"},
+	{"y u b n", "move diagonally"},
+}
+
+// inputPresets are the presets shipped with the server, by name
+var inputPresets = map[string]InputPreset{
+	"nethack": {
+		Name:        "nethack",
+		Game:        "NetHack",
+		Description: "number_pad off: arrows and Home/PgUp/End/PgDn send vi keys",
+		Keymap:      viKeys,
+		Hints: append(append([]KeyHint(nil), viMoveHints...),
+			KeyHint{"s", "search"},
+			KeyHint{",", "pick up"},
+			KeyHint{"i", "inventory"},
+			KeyHint{"< >", "go up or down stairs"},
+		),
+	},
+	"nethack-numpad": {
+		Name:        "nethack-numpad",
+		Game:        "NetHack",
+		Description: "number_pad on: arrows and Home/PgUp/End/PgDn send digits",
+		Keymap: map[string]string{
+			"ArrowLeft":  "4",
+			"ArrowDown":  "2",
+			"ArrowUp":    "8",
+			"ArrowRight": "6",
+			"Home":       "7",
+			"PageUp":     "9",
+			"End":        "1",
+			"PageDown":   "3",
+		},
+		Hints: []KeyHint{
+			{"1-9", "move"},
+			{"s", "search"},
+			{"k", "kick"},
+			{",", "pick up"},
+			{"i", "inventory"},
+			{"< >", "go up or down stairs"},
+		},
+	},
+	"dcss-tiles": {
+		Name:        "dcss-tiles",
+		Game:        "Dungeon Crawl Stone Soup",
+		Description: "tiles-style controls: arrows move, Home/PgUp/End/PgDn move diagonally",
+		Keymap: map[string]string{
+			"Home":     "y",
+			"PageUp":   "u",
+			"End":      "b",
+			"PageDown": "n",
+		},
+		Hints: []KeyHint{
+			{"arrows", "move"},
+			{"Home PgUp End PgDn", "move diagonally"},
+			{"o", "explore"},
+			{"Tab", "fight nearest"},
+			{"5", "rest"},
+			{"g", "pick up"},
+			{"i", "inventory"},
+		},
+	},
+	"angband-roguelike": {
+		Name:        "angband-roguelike",
+		Game:        "Angband",
+		Description: "roguelike keyset: arrows and Home/PgUp/End/PgDn send vi keys",
+		Keymap:      viKeys,
+		Hints: append(append([]KeyHint(nil), viMoveHints...),
+			KeyHint{"g", "pick up"},
+			KeyHint{"i", "inventory"},
+			KeyHint{"R", "rest"},
+			KeyHint{"< >", "go up or down stairs"},
+		),
+	},
+}
+
+// InputPresetList is the response of GET /input/preset
+type InputPresetList struct {
+	Current string        `json:"current,omitempty"`
+	Presets []InputPreset `json:"presets"`
+}
+
+// inputPresetRequest is the request body of POST /input/preset
+type inputPresetRequest struct {
+	Name string `json:"name"` // empty for no preset
+}
+
+// LookupInputPreset returns the shipped preset called name
+func LookupInputPreset(name string) (InputPreset, bool) {
+	preset, ok := inputPresets[name]
+	return preset, ok
+}
+
+// InputPresets returns the shipped presets sorted by name
+func InputPresets() []InputPreset {
+	presets := make([]InputPreset, 0, len(inputPresets))
+	for _, preset := range inputPresets {
+		presets = append(presets, preset)
+	}
+	sort.Slice(presets, func(i, j int) bool { return presets[i].Name < presets[j].Name })
+	return presets
+}
+
+// translate returns the input for a named key under the preset
+func (p *InputPreset) translate(key string) (string, bool) {
+	if p == nil {
+		return "", false
+	}
+	input, ok := p.Keymap[key]
+	return input, ok
+}
+
+// translateRaw remaps input that is exactly the sequence of a named key,
+// as sent by clients that forward single key presses
+func (p *InputPreset) translateRaw(input string) string {
+	if p == nil {
+		return input
+	}
+	for key, replacement := range p.Keymap {
+		if namedKeys[key] == input {
+			return replacement
+		}
+	}
+	return input
+}
+
+// SetInputPreset selects the preset called name; an empty name removes it
+func (w *WebUI) SetInputPreset(name string) error {
+	if name == "" {
+		w.inputPreset.Store(nil)
+		return nil
+	}
+	preset, ok := LookupInputPreset(name)
+	if !ok {
+		return fmt.Errorf("unknown input preset %q", name)
+	}
+	w.inputPreset.Store(&preset)
+	return nil
+}
+
+// InputPreset returns the name of the selected preset, or ""
+func (w *WebUI) InputPreset() string {
+	if preset := w.inputPreset.Load(); preset != nil {
+		return preset.Name
+	}
+	return ""
+}
+
+// handleInputPreset serves GET /input/preset, listing the presets, and
+// POST /input/preset, selecting one ({"name": "nethack"}) for everybody
+// playing the session
+func (w *WebUI) handleInputPreset(rw http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(rw, http.StatusOK, InputPresetList{Current: w.InputPreset(), Presets: InputPresets()})
+
+	case http.MethodPost:
+		var req inputPresetRequest
+		if err := json.NewDecoder(http.MaxBytesReader(rw, r.Body, 4096)).Decode(&req); err != nil {
+			http.Error(rw, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		changed := w.InputPreset() != req.Name
+		if err := w.SetInputPreset(req.Name); err != nil {
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if changed {
+			if preset := w.inputPreset.Load(); preset != nil {
+				w.broadcastNotice("info", i18n.MsgPresetSwitched, preset.Name, preset.Game)
+			} else {
+				w.broadcastNotice("info", i18n.MsgPresetCleared)
+			}
+		}
+		writeJSON(rw, http.StatusOK, InputPresetList{Current: w.InputPreset(), Presets: InputPresets()})
+
+	default:
+		rw.Header().Set("Allow", "GET, POST")
+		http.Error(rw, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,94 @@
+package webui
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestWebUI_HandleInputPreset(t *testing.T) {
+	ui := newRBACWebUI(t, nil, nil)
+
+	post := func(token, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/input/preset", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		rr := httptest.NewRecorder()
+		ui.ServeHTTP(rr, req)
+		return rr
+	}
+
+	if rr := post(spectatorToken, `{"name":"nethack"}`); rr.Code != http.StatusForbidden {
+		t.Errorf("spectator selecting a preset: status = %d, want 403", rr.Code)
+	}
+	if rr := post(playerToken, `{"name":"rogue"}`); rr.Code != http.StatusBadRequest {
+		t.Errorf("unknown preset: status = %d, want 400", rr.Code)
+	}
+	rr := post(playerToken, `{"name":"nethack-numpad"}`)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("selecting a preset: status = %d, want 200: %s", rr.Code, rr.Body.String())
+	}
+	var list InputPresetList
+	if err := json.Unmarshal(rr.Body.Bytes(), &list); err != nil {
+		t.Fatalf("invalid response: %v", err)
+	}
+	if list.Current != "nethack-numpad" || len(list.Presets) != len(inputPresets) {
+		t.Errorf("list = current %q with %d presets, want nethack-numpad of %d", list.Current, len(list.Presets), len(inputPresets))
+	}
+	if info := ui.GetSessionInfo(); info.InputPreset != "nethack-numpad" {
+		t.Errorf("session info preset = %q, want nethack-numpad", info.InputPreset)
+	}
+
+	if rr := post(playerToken, `{"name":""}`); rr.Code != http.StatusOK || ui.InputPreset() != "" {
+		t.Errorf("clearing the preset: status = %d, preset %q", rr.Code, ui.InputPreset())
+	}
+}
+
+func TestWebUI_InputPreset_RemapsKeys(t *testing.T) {
+	var sent []string
+	ui, err := NewWebUI(WebUIOptions{
+		View:        newTestView(t),
+		InputPreset: "nethack-numpad",
+		InputSink: func(data []byte) error {
+			sent = append(sent, string(data))
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create WebUI: %v", err)
+	}
+
+	// Both WebSocket and HTTP input follow the preset's keymap
+	for _, input := range []string{"\x1b[A", "\x1b[Ax"} {
+		if err := ui.handleClientInput("c1", input); err != nil {
+			t.Fatalf("handleClientInput failed: %v", err)
+		}
+	}
+	body := `{"client":"c1","seq":1,"events":[{"type":"key","key":"Home"},{"type":"key","key":"x"},{"type":"key","key":"Enter"}]}`
+	ui.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/input", strings.NewReader(body)))
+
+	want := []string{"8", "\x1b[Ax", "7x\r"}
+	if strings.Join(sent, "|") != strings.Join(want, "|") {
+		t.Errorf("game input = %q, want %q", sent, want)
+	}
+}
+
+func TestNewWebUI_RejectsUnknownInputPreset(t *testing.T) {
+	if _, err := NewWebUI(WebUIOptions{View: newTestView(t), InputPreset: "rogue"}); err == nil {
+		t.Error("NewWebUI() accepted an unknown input preset")
+	}
+}
+
+func TestInputPresets_MapOnlyNamedKeys(t *testing.T) {
+	for _, preset := range InputPresets() {
+		if len(preset.Hints) == 0 {
+			t.Errorf("preset %s advertises no hints", preset.Name)
+		}
+		for key := range preset.Keymap {
+			if _, ok := namedKeys[key]; !ok {
+				t.Errorf("preset %s maps %q, which is not a named key", preset.Name, key)
+			}
+		}
+	}
+}
@@ -0,0 +1,164 @@
+// Package webui provides instant replay of the session's recent terminal
+// output, kept in memory.
+package webui
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/opd-ai/go-gamelaunch-client/pkg/dgclient"
+	"github.com/opd-ai/go-gamelaunch-www/pkg/ttyrec"
+)
+
+const (
+	// maxReplaySpeed bounds the speed parameter of instant replay
+	maxReplaySpeed = 64
+
+	// maxReplayDelay caps pauses during instant replay so idle stretches
+	// are skipped
+	maxReplayDelay = 2 * time.Second
+)
+
+// InstantReplayConfig keeps the last Window of raw game output in memory so
+// players can review what just happened without enabling recordings
+type InstantReplayConfig struct {
+	Window   string `yaml:"window,omitempty" yamlcheck:"duration"` // default "60s"
+	MaxBytes string `yaml:"max_bytes,omitempty"`                   // default "4MB"
+}
+
+// newReplayRing validates cfg and creates the ring holding the output, at
+// most limit bytes of it unless limit is zero, and the keyframer rebuilding
+// the screen of the output it drops
+func newReplayRing(cfg InstantReplayConfig, limit uint64) (*ttyrec.Ring, *replayKeyframer, error) {
+	window := time.Minute
+	if cfg.Window != "" {
+		parsed, err := time.ParseDuration(cfg.Window)
+		if err != nil || parsed <= 0 {
+			return nil, nil, fmt.Errorf("instant replay: invalid window %q", cfg.Window)
+		}
+		window = parsed
+	}
+
+	maxBytes := uint64(4e6)
+	if cfg.MaxBytes != "" {
+		parsed, err := parseByteSize(cfg.MaxBytes)
+		if err != nil || parsed == 0 {
+			return nil, nil, fmt.Errorf("instant replay: invalid max_bytes %q", cfg.MaxBytes)
+		}
+		maxBytes = parsed
+	}
+	if limit > 0 && maxBytes > limit {
+		maxBytes = limit
+	}
+	ring := ttyrec.NewRing(window, int(maxBytes))
+	keyframer, err := newReplayKeyframer()
+	if err != nil {
+		return nil, nil, err
+	}
+	ring.SetKeyframer(keyframer)
+	return ring, keyframer, nil
+}
+
+// replayKeyframer renders the output dropped from the instant replay ring
+// on a view of its own, so replays start with the screen it left rather
+// than mid-stream
+type replayKeyframer struct {
+	view *WebView
+
+	mu            sync.Mutex
+	width, height int // of the live view, see follow
+}
+
+// newReplayKeyframer creates a keyframer with an empty 80x24 screen
+func newReplayKeyframer() (*replayKeyframer, error) {
+	// The view's history is never read
+	view, err := NewWebViewWithStore(dgclient.ViewOptions{}, NewMemoryStateStore(1))
+	if err != nil {
+		return nil, fmt.Errorf("instant replay: %w", err)
+	}
+	return &replayKeyframer{view: view, width: 80, height: 24}, nil
+}
+
+// follow is a screen tap of the live view keeping the size of the screen
+// the output is rendered on
+func (k *replayKeyframer) follow(_ []byte, state *GameState) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.width, k.height = state.Width, state.Height
+}
+
+// Drop implements ttyrec.Keyframer
+func (k *replayKeyframer) Drop(data []byte) {
+	k.mu.Lock()
+	width, height := k.width, k.height
+	k.mu.Unlock()
+
+	if w, h := k.view.GetSize(); w != width || h != height {
+		k.view.SetSize(width, height)
+	}
+	k.view.Render(data)
+}
+
+// Keyframe implements ttyrec.Keyframer
+func (k *replayKeyframer) Keyframe() []byte {
+	k.view.mu.Lock()
+	defer k.view.mu.Unlock()
+	return k.view.redraw()
+}
+
+// handleInstantReplay serves GET /replay/instant, streaming the recent output
+// as raw terminal data at the pace it was produced, scaled by the speed
+// parameter (default 1). Once older output was dropped, the stream starts
+// with a redraw of the screen it left. With format=ttyrec the output is downloaded as a
+// ttyrec file instead.
+func (w *WebUI) handleInstantReplay(rw http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		rw.Header().Set("Allow", http.MethodGet)
+		http.Error(rw, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	// Raw output holds concealed text verbatim
+	if w.options.WithholdConcealed && !w.authorize(r.Context(), MethodInput) {
+		rejectForbidden(rw, IdentityFromContext(r.Context()))
+		return
+	}
+
+	q := r.URL.Query()
+	speed := 1.0
+	if v := q.Get("speed"); v != "" {
+		parsed, err := strconv.ParseFloat(v, 64)
+		if err != nil || parsed <= 0 || parsed > maxReplaySpeed {
+			http.Error(rw, "Invalid speed", http.StatusBadRequest)
+			return
+		}
+		speed = parsed
+	}
+
+	switch q.Get("format") {
+	case "ttyrec":
+		rw.Header().Set("Content-Type", "application/octet-stream")
+		rw.Header().Set("Content-Disposition", `attachment; filename="instant-replay.ttyrec"`)
+		w.replay.WriteTo(rw)
+		return
+	case "", "raw":
+	default:
+		http.Error(rw, "Invalid format", http.StatusBadRequest)
+		return
+	}
+
+	rw.Header().Set("Content-Type", "application/octet-stream")
+	rw.Header().Set("X-Content-Type-Options", "nosniff")
+	rw.WriteHeader(http.StatusOK)
+
+	controller := http.NewResponseController(rw)
+	ttyrec.Play(r.Context(), w.replay.Frames(), speed, maxReplayDelay, func(data []byte) error {
+		if _, err := rw.Write(data); err != nil {
+			return err
+		}
+		controller.Flush()
+		return nil
+	})
+}
@@ -0,0 +1,120 @@
+package webui
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/opd-ai/go-gamelaunch-www/pkg/ttyrec"
+)
+
+func newReplayWebUI(t *testing.T) *WebUI {
+	t.Helper()
+	webUI, err := NewWebUI(WebUIOptions{View: newTestView(t), InstantReplay: &InstantReplayConfig{}})
+	if err != nil {
+		t.Fatalf("NewWebUI() error = %v", err)
+	}
+	return webUI
+}
+
+func TestWebUI_handleInstantReplay_StreamsRecentOutput(t *testing.T) {
+	webUI := newReplayWebUI(t)
+	for _, data := range []string{"You die...", "\r\nDo you want your possessions identified?"} {
+		if err := webUI.GetView().Render([]byte(data)); err != nil {
+			t.Fatalf("Render() error = %v", err)
+		}
+	}
+	want := "You die...\r\nDo you want your possessions identified?"
+
+	rec := httptest.NewRecorder()
+	webUI.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/replay/instant?speed=64", nil))
+	if rec.Code != http.StatusOK || rec.Body.String() != want {
+		t.Errorf("GET /replay/instant = %d %q, want 200 %q", rec.Code, rec.Body.String(), want)
+	}
+
+	rec = httptest.NewRecorder()
+	webUI.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/replay/instant?format=ttyrec", nil))
+	frames, err := ttyrec.ReadAll(bytes.NewReader(rec.Body.Bytes()))
+	if err != nil || len(frames) != 2 {
+		t.Errorf("ttyrec download = %d frames, %v; want 2", len(frames), err)
+	}
+}
+
+func TestWebUI_handleInstantReplay_RejectsInvalidRequests(t *testing.T) {
+	webUI := newReplayWebUI(t)
+	tests := []struct {
+		method, target string
+		want           int
+	}{
+		{http.MethodPost, "/replay/instant", http.StatusMethodNotAllowed},
+		{http.MethodGet, "/replay/instant?speed=0", http.StatusBadRequest},
+		{http.MethodGet, "/replay/instant?speed=100", http.StatusBadRequest},
+		{http.MethodGet, "/replay/instant?format=gif", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		webUI.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.target, nil))
+		if rec.Code != tt.want {
+			t.Errorf("%s %s = %d, want %d", tt.method, tt.target, rec.Code, tt.want)
+		}
+	}
+}
+
+func TestNewWebUI_InstantReplay_RejectsInvalidConfig(t *testing.T) {
+	for _, cfg := range []InstantReplayConfig{{Window: "-1s"}, {Window: "later"}, {MaxBytes: "lots"}, {MaxBytes: "0"}} {
+		if _, err := NewWebUI(WebUIOptions{View: newTestView(t), InstantReplay: &cfg}); err == nil {
+			t.Errorf("NewWebUI(%+v) succeeded, want error", cfg)
+		}
+	}
+	if webUI := newTestWebUI(t); webUI.replay != nil {
+		t.Error("instant replay enabled without configuration")
+	}
+}
+
+func TestWebUI_handleInstantReplay_StartsWithScreenOfDroppedOutput(t *testing.T) {
+	webUI, err := NewWebUI(WebUIOptions{View: newTestView(t), InstantReplay: &InstantReplayConfig{MaxBytes: "64"}})
+	if err != nil {
+		t.Fatalf("NewWebUI() error = %v", err)
+	}
+	live := webUI.GetView()
+	for _, data := range []string{
+		"\x1b[2J\x1b[1;1H\x1b[1;33mWelcome to NetHack!\x1b[0m",
+		"\x1b[3;5H\x1b[44m-----\x1b[4;5H|.@.|\x1b[0m",
+		"\x1b(0\x1b[6;1Hqqq\x1b(B\x1b[2;10r",
+		"\x1b[10;1H\x1b[4mThe door opens.\x1b[24m",
+		"\x1b[3;7H\x1b[31md",
+	} {
+		if err := live.Render([]byte(data)); err != nil {
+			t.Fatalf("Render() error = %v", err)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	webUI.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/replay/instant?format=ttyrec", nil))
+	frames, err := ttyrec.ReadAll(bytes.NewReader(rec.Body.Bytes()))
+	if err != nil || len(frames) < 2 || len(frames) > 4 {
+		t.Fatalf("ttyrec download = %d frames, %v; want a keyframe and the newest output", len(frames), err)
+	}
+
+	// Played on a fresh terminal, the replay ends on the live screen
+	replayed := newTestView(t)
+	for _, frame := range frames {
+		if err := replayed.Render(frame.Data); err != nil {
+			t.Fatalf("Render(replay) error = %v", err)
+		}
+	}
+	want, got := live.GetCurrentState(), replayed.GetCurrentState()
+	for y := range want.Buffer {
+		for x, cell := range want.Buffer[y] {
+			other := got.Buffer[y][x]
+			if cell.Char != other.Char || cell.FgColor != other.FgColor || cell.BgColor != other.BgColor ||
+				cell.Bold != other.Bold || cell.Underline != other.Underline {
+				t.Fatalf("replayed cell (%d, %d) = %+v, want %+v", x, y, other, cell)
+			}
+		}
+	}
+	if got.CursorX != want.CursorX || got.CursorY != want.CursorY {
+		t.Errorf("replayed cursor = (%d, %d), want (%d, %d)", got.CursorX, got.CursorY, want.CursorX, want.CursorY)
+	}
+}
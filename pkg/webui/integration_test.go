@@ -31,7 +31,7 @@ func TestNewWebUI_Integration(t *testing.T) {
 	}
 
 	// Verify that the WebUI was created correctly
-	if webUI.view == nil {
+	if webUI.GetView() == nil {
 		t.Fatal("WebUI view is nil")
 	}
 
@@ -96,12 +96,12 @@ func TestWebUI_ValidationFixesWork(t *testing.T) {
 		}
 
 		// Verify view is initialized
-		if webUI.view == nil {
+		if webUI.GetView() == nil {
 			t.Fatal("WebUI view is nil")
 		}
 
 		// Verify view has proper dimensions
-		width, height := webUI.view.GetSize()
+		width, height := webUI.GetView().GetSize()
 		if width != 80 || height != 24 {
 			t.Fatalf("WebView dimensions incorrect: expected 80x24, got %dx%d", width, height)
 		}
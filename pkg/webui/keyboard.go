@@ -0,0 +1,104 @@
+// Package webui provides on-screen keyboard layouts for touch clients.
+package webui
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// KeyboardConfig lists the on-screen keyboard layouts served to touch
+// clients, so operators define the controls instead of the client
+type KeyboardConfig struct {
+	Layouts []KeyboardLayout `yaml:"layouts"`
+}
+
+// KeyboardLayout is an on-screen keyboard for a game, or for every game when
+// Game is empty
+type KeyboardLayout struct {
+	Name string         `yaml:"name" json:"name"`
+	Game string         `yaml:"game,omitempty" json:"game,omitempty"`
+	Rows [][]VirtualKey `yaml:"rows" json:"rows"`
+}
+
+// VirtualKey is a labeled key of an on-screen keyboard. It sends Input, or
+// the sequence of the named key Key (see InputEvent), e.g. "ArrowUp".
+type VirtualKey struct {
+	Label string  `yaml:"label" json:"label"`
+	Input string  `yaml:"input,omitempty" json:"input"`
+	Key   string  `yaml:"key,omitempty" json:"-"`
+	Width float64 `yaml:"width,omitempty" json:"width,omitempty"` // relative, default 1
+}
+
+// newKeyboardLayouts validates cfg and resolves named keys to their input
+func newKeyboardLayouts(cfg KeyboardConfig) ([]KeyboardLayout, error) {
+	layouts := make([]KeyboardLayout, 0, len(cfg.Layouts))
+	names := make(map[string]bool, len(cfg.Layouts))
+	for _, layout := range cfg.Layouts {
+		if layout.Name == "" {
+			return nil, fmt.Errorf("keyboard: layout without a name")
+		}
+		if names[layout.Name] {
+			return nil, fmt.Errorf("keyboard: duplicate layout %q", layout.Name)
+		}
+		names[layout.Name] = true
+
+		rows := make([][]VirtualKey, len(layout.Rows))
+		for i, row := range layout.Rows {
+			rows[i] = make([]VirtualKey, len(row))
+			for j, key := range row {
+				resolved, err := key.resolve()
+				if err != nil {
+					return nil, fmt.Errorf("keyboard: layout %q row %d: %w", layout.Name, i+1, err)
+				}
+				rows[i][j] = resolved
+			}
+		}
+		layout.Rows = rows
+		layouts = append(layouts, layout)
+	}
+	return layouts, nil
+}
+
+// resolve checks the key and replaces a named key with its input
+func (k VirtualKey) resolve() (VirtualKey, error) {
+	if k.Label == "" {
+		return k, fmt.Errorf("key without a label")
+	}
+	if k.Width < 0 {
+		return k, fmt.Errorf("key %q has invalid width %g", k.Label, k.Width)
+	}
+	switch {
+	case k.Input != "" && k.Key != "":
+		return k, fmt.Errorf("key %q sets both input and key", k.Label)
+	case k.Key != "":
+		input, ok := namedKeys[k.Key]
+		if !ok {
+			return k, fmt.Errorf("key %q names unknown key %q", k.Label, k.Key)
+		}
+		k.Input, k.Key = input, ""
+	case k.Input == "":
+		return k, fmt.Errorf("key %q sends no input", k.Label)
+	}
+	return k, nil
+}
+
+// handleKeyboard serves GET /keyboard?game=..., the layouts for the game
+// (matched case-insensitively) and those for every game; all layouts when
+// game is omitted
+func (w *WebUI) handleKeyboard(rw http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		rw.Header().Set("Allow", http.MethodGet)
+		http.Error(rw, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	game := r.URL.Query().Get("game")
+	layouts := make([]KeyboardLayout, 0, len(w.keyboards))
+	for _, layout := range w.keyboards {
+		if game == "" || layout.Game == "" || strings.EqualFold(layout.Game, game) {
+			layouts = append(layouts, layout)
+		}
+	}
+	writeJSON(rw, http.StatusOK, layouts)
+}
@@ -0,0 +1,95 @@
+package webui
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+const testKeyboardYAML = `
+layouts:
+  - name: arrows
+    rows:
+      - [{label: "Esc", key: Escape}, {label: "↑", key: ArrowUp, width: 2}]
+  - name: nethack
+    game: NetHack
+    rows:
+      - [{label: "search", input: "s"}, {label: "pray", input: "#pray\r"}]
+  - name: crawl
+    game: Crawl
+    rows:
+      - [{label: "explore", input: "o"}]
+`
+
+func TestWebUI_HandleKeyboard(t *testing.T) {
+	var cfg KeyboardConfig
+	if err := yaml.Unmarshal([]byte(testKeyboardYAML), &cfg); err != nil {
+		t.Fatalf("invalid test config: %v", err)
+	}
+	ui, err := NewWebUI(WebUIOptions{View: newTestView(t), Keyboards: &cfg})
+	if err != nil {
+		t.Fatalf("Failed to create WebUI: %v", err)
+	}
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"", []string{"arrows", "nethack", "crawl"}},
+		{"?game=nethack", []string{"arrows", "nethack"}},
+		{"?game=Angband", []string{"arrows"}},
+	}
+	for _, tt := range tests {
+		rr := httptest.NewRecorder()
+		ui.ServeHTTP(rr, httptest.NewRequest("GET", "/keyboard"+tt.query, nil))
+		if rr.Code != http.StatusOK {
+			t.Fatalf("GET /keyboard%s: status = %d", tt.query, rr.Code)
+		}
+		var layouts []KeyboardLayout
+		if err := json.Unmarshal(rr.Body.Bytes(), &layouts); err != nil {
+			t.Fatalf("invalid response: %v", err)
+		}
+		var names []string
+		for _, layout := range layouts {
+			names = append(names, layout.Name)
+		}
+		if len(names) != len(tt.want) {
+			t.Errorf("GET /keyboard%s = %v, want %v", tt.query, names, tt.want)
+			continue
+		}
+		for i := range names {
+			if names[i] != tt.want[i] {
+				t.Errorf("GET /keyboard%s = %v, want %v", tt.query, names, tt.want)
+				break
+			}
+		}
+		if tt.query == "" && layouts[0].Rows[0][1].Input != "\x1b[A" {
+			t.Errorf("named key resolved to %q, want the ArrowUp sequence", layouts[0].Rows[0][1].Input)
+		}
+	}
+}
+
+func TestNewKeyboardLayouts_RejectsInvalidLayouts(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  KeyboardConfig
+	}{
+		{"unnamed layout", KeyboardConfig{Layouts: []KeyboardLayout{{}}}},
+		{"duplicate layout", KeyboardConfig{Layouts: []KeyboardLayout{{Name: "a"}, {Name: "a"}}}},
+		{"key without label", KeyboardConfig{Layouts: []KeyboardLayout{{Name: "a", Rows: [][]VirtualKey{{{Input: "x"}}}}}}},
+		{"key without input", KeyboardConfig{Layouts: []KeyboardLayout{{Name: "a", Rows: [][]VirtualKey{{{Label: "x"}}}}}}},
+		{"input and key", KeyboardConfig{Layouts: []KeyboardLayout{{Name: "a", Rows: [][]VirtualKey{{{Label: "x", Input: "x", Key: "Enter"}}}}}}},
+		{"unknown key", KeyboardConfig{Layouts: []KeyboardLayout{{Name: "a", Rows: [][]VirtualKey{{{Label: "x", Key: "Hyper"}}}}}}},
+		{"negative width", KeyboardConfig{Layouts: []KeyboardLayout{{Name: "a", Rows: [][]VirtualKey{{{Label: "x", Input: "x", Width: -1}}}}}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := newKeyboardLayouts(tt.cfg); err == nil {
+				t.Error("newKeyboardLayouts() succeeded, want error")
+			}
+		})
+	}
+}
@@ -0,0 +1,70 @@
+// Package webui provides periodic full-state keyframes for pollers.
+package webui
+
+import (
+	"fmt"
+	"time"
+)
+
+// KeyframeConfig makes the state manager send a keyframe of the entire
+// screen to pollers every EveryVersions updates or every Interval, whichever
+// comes first, instead of a diff. Each keyframe also restarts the diff
+// history, so clients further behind than the last keyframe receive the full
+// state. Zero or empty disables a trigger.
+type KeyframeConfig struct {
+	EveryVersions int    `yaml:"every_versions,omitempty"`                // e.g. 100
+	Interval      string `yaml:"interval,omitempty" yamlcheck:"duration"` // e.g. "30s"
+}
+
+// keyframeSchedule is the validated runtime form of KeyframeConfig
+type keyframeSchedule struct {
+	every    uint64
+	interval time.Duration
+
+	lastVersion uint64
+	lastAt      time.Time
+}
+
+// newKeyframeSchedule validates cfg
+func newKeyframeSchedule(cfg KeyframeConfig) (*keyframeSchedule, error) {
+	if cfg.EveryVersions < 0 {
+		return nil, fmt.Errorf("keyframes: invalid every_versions %d", cfg.EveryVersions)
+	}
+	s := &keyframeSchedule{every: uint64(cfg.EveryVersions)}
+	if cfg.Interval != "" {
+		parsed, err := time.ParseDuration(cfg.Interval)
+		if err != nil || parsed <= 0 {
+			return nil, fmt.Errorf("keyframes: invalid interval %q", cfg.Interval)
+		}
+		s.interval = parsed
+	}
+	return s, nil
+}
+
+// due reports whether version should be sent as a keyframe
+func (s *keyframeSchedule) due(version uint64, now time.Time) bool {
+	if s.every > 0 && version-s.lastVersion >= s.every {
+		return true
+	}
+	return s.interval > 0 && now.Sub(s.lastAt) >= s.interval
+}
+
+// mark records that version was sent as a keyframe
+func (s *keyframeSchedule) mark(version uint64, now time.Time) {
+	s.lastVersion, s.lastAt = version, now
+}
+
+// SetKeyframes replaces the keyframe schedule; the current state counts as
+// the last keyframe
+func (sm *StateManager) SetKeyframes(cfg KeyframeConfig) error {
+	schedule, err := newKeyframeSchedule(cfg)
+	if err != nil {
+		return err
+	}
+
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	schedule.mark(sm.store.Version(), time.Now())
+	sm.keyframes = schedule
+	return nil
+}
@@ -0,0 +1,94 @@
+package webui
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// pollNextUpdate applies update while a poller waits at the current version
+// and returns the diff the poller receives
+func pollNextUpdate(t *testing.T, sm *StateManager, update *GameState) *StateDiff {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	result := make(chan *StateDiff, 1)
+	version := sm.GetCurrentVersion()
+	go func() {
+		diff, _ := sm.PollChangesWithContext(ctx, version)
+		result <- diff
+	}()
+	waitForWaiters(t, sm, 1)
+	sm.UpdateState(update)
+
+	diff := <-result
+	if diff == nil {
+		t.Fatal("PollChangesWithContext() returned no diff")
+	}
+	return diff
+}
+
+func TestStateManager_UpdateState_FirstStateWakesPollers(t *testing.T) {
+	sm := NewStateManager()
+	diff := pollNextUpdate(t, sm, budgetTestState('#'))
+	if diff.Version != 1 || !diff.Keyframe || len(diff.Rows) != 4 {
+		t.Errorf("first diff = version %d, keyframe %v, %d rows; want the full state", diff.Version, diff.Keyframe, len(diff.Rows))
+	}
+}
+
+func TestStateManager_SetKeyframes_EveryVersions(t *testing.T) {
+	sm := NewStateManager()
+	sm.UpdateState(budgetTestState(' '))
+	if err := sm.SetKeyframes(KeyframeConfig{EveryVersions: 3}); err != nil {
+		t.Fatalf("SetKeyframes() error = %v", err)
+	}
+
+	for version := uint64(2); version <= 7; version++ {
+		diff := pollNextUpdate(t, sm, budgetTestState('#', int(version%4)))
+		wantKeyframe := version == 4 || version == 7
+		if diff.Keyframe != wantKeyframe {
+			t.Errorf("version %d: Keyframe = %v, want %v", version, diff.Keyframe, wantKeyframe)
+		}
+		if wantKeyframe && (len(diff.Rows) != 4 || len(diff.Changes) != 0) {
+			t.Errorf("version %d: keyframe has %d rows and %d changes, want 4 rows", version, len(diff.Rows), len(diff.Changes))
+		}
+	}
+
+	// The keyframe at version 7 restarted the history
+	if _, ok := sm.Store().DiffsSince(5); ok {
+		t.Error("DiffsSince(5) reaches back past the last keyframe")
+	}
+	if _, ok := sm.Store().DiffsSince(6); ok {
+		t.Error("DiffsSince(6) returned the keyframe as a diff")
+	}
+	diff, _ := sm.generateDiffFromVersion(5)
+	if len(diff.Changes) != 40 {
+		t.Errorf("client behind the keyframe got %d changes, want the full 40-cell state", len(diff.Changes))
+	}
+}
+
+func TestStateManager_SetKeyframes_Interval(t *testing.T) {
+	sm := NewStateManager()
+	sm.UpdateState(budgetTestState(' '))
+	if err := sm.SetKeyframes(KeyframeConfig{Interval: "20ms"}); err != nil {
+		t.Fatalf("SetKeyframes() error = %v", err)
+	}
+
+	if diff := pollNextUpdate(t, sm, budgetTestState('#', 0)); diff.Keyframe {
+		t.Error("update before the interval was sent as a keyframe")
+	}
+	time.Sleep(30 * time.Millisecond)
+	if diff := pollNextUpdate(t, sm, budgetTestState('#', 1)); !diff.Keyframe {
+		t.Error("update after the interval was not sent as a keyframe")
+	}
+}
+
+func TestStateManager_SetKeyframes_RejectsInvalidConfig(t *testing.T) {
+	sm := NewStateManager()
+	for _, cfg := range []KeyframeConfig{{EveryVersions: -1}, {Interval: "soon"}, {Interval: "-1s"}} {
+		if err := sm.SetKeyframes(cfg); err == nil {
+			t.Errorf("SetKeyframes(%+v) succeeded, want error", cfg)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+// Package webui provides accessibility landmarks: the semantic regions of
+// the screen, such as the map and status lines, found by parser plugins.
+package webui
+
+import (
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"sync"
+
+	"github.com/opd-ai/go-gamelaunch-www/pkg/transport"
+)
+
+// Kinds of landmark
+const (
+	LandmarkMap     = "map"     // the game world
+	LandmarkStatus  = "status"  // character status and stats
+	LandmarkMessage = "message" // game messages
+)
+
+// Landmark is a rectangle of the screen with a semantic role. Clients offer
+// landmarks for keyboard navigation and label them for assistive
+// technology, e.g. as ARIA regions.
+type Landmark struct {
+	Kind   string `json:"kind"`            // LandmarkMap, LandmarkStatus, LandmarkMessage or a plugin's own
+	Label  string `json:"label,omitempty"` // human-readable name, e.g. "Status lines"
+	X      int    `json:"x"`
+	Y      int    `json:"y"`
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+}
+
+// LandmarkParser is a parser plugin that divides the screen into landmarks
+type LandmarkParser interface {
+	// Landmarks returns the landmarks state shows, or none when it does
+	// not recognize the screen. state must not be modified.
+	Landmarks(state *GameState) []Landmark
+}
+
+// LandmarkParserFunc adapts a function to LandmarkParser
+type LandmarkParserFunc func(state *GameState) []Landmark
+
+// Landmarks implements LandmarkParser
+func (f LandmarkParserFunc) Landmarks(state *GameState) []Landmark {
+	return f(state)
+}
+
+var (
+	landmarkParsersMu sync.RWMutex
+	landmarkParsers   = map[string]LandmarkParser{
+		"nethack": LandmarkParserFunc(nethackLandmarks),
+	}
+)
+
+// RegisterLandmarkParser makes a landmark parser available under name,
+// replacing any parser registered with the same name
+func RegisterLandmarkParser(name string, parser LandmarkParser) {
+	landmarkParsersMu.Lock()
+	defer landmarkParsersMu.Unlock()
+	landmarkParsers[name] = parser
+}
+
+// LandmarkParsers returns the names of the registered landmark parsers
+func LandmarkParsers() []string {
+	landmarkParsersMu.RLock()
+	defer landmarkParsersMu.RUnlock()
+	return slices.Sorted(maps.Keys(landmarkParsers))
+}
+
+// lookupLandmarkParsers returns the landmark parsers registered under names
+func lookupLandmarkParsers(names []string) ([]LandmarkParser, error) {
+	landmarkParsersMu.RLock()
+	defer landmarkParsersMu.RUnlock()
+	parsers := make([]LandmarkParser, 0, len(names))
+	for _, name := range names {
+		parser, ok := landmarkParsers[name]
+		if !ok {
+			return nil, fmt.Errorf("landmarks: unknown parser %q", name)
+		}
+		parsers = append(parsers, parser)
+	}
+	return parsers, nil
+}
+
+// nethackStatus matches the bottom lines of NetHack: St:18/02 Dx:14 ... on
+// the first and Dlvl:1 $:0 HP:12(12) ... on the second
+var nethackStatus = regexp.MustCompile(`\b(St:\d|Dlvl:\d|HP:-?\d)`)
+
+// nethackLandmarks finds NetHack's layout: the message line at the top, the
+// status lines at the bottom and the map in between. Screens without status
+// lines, such as menus and the inventory, have no landmarks.
+func nethackLandmarks(state *GameState) []Landmark {
+	lines := stateLines(state)
+	bottom := len(lines) - 1
+	for bottom >= 0 && lines[bottom] == "" {
+		bottom--
+	}
+	top := bottom + 1
+	for top > 1 && nethackStatus.MatchString(lines[top-1]) {
+		top--
+	}
+	if top > bottom {
+		return nil
+	}
+
+	landmarks := []Landmark{
+		{Kind: LandmarkMessage, Label: "Messages", Width: state.Width, Height: 1},
+	}
+	if top > 1 {
+		landmarks = append(landmarks, Landmark{Kind: LandmarkMap, Label: "Map", Y: 1, Width: state.Width, Height: top - 1})
+	}
+	return append(landmarks, Landmark{Kind: LandmarkStatus, Label: "Status lines", Y: top, Width: state.Width, Height: bottom - top + 1})
+}
+
+// AddLandmarkParser registers a parser plugin run on every new state. The
+// first parser that recognizes the screen sets GameState.Landmarks.
+func (v *WebView) AddLandmarkParser(parser LandmarkParser) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.landmarkParsers = append(v.landmarkParsers, parser)
+}
+
+// landmarks runs the registered landmark parsers on state, clipping their
+// landmarks to the screen and dropping empty ones
+func (v *WebView) landmarks(state *GameState) []Landmark {
+	for _, parser := range v.landmarkParsers {
+		var landmarks []Landmark
+		for _, landmark := range parser.Landmarks(state) {
+			x0, y0 := max(landmark.X, 0), max(landmark.Y, 0)
+			x1 := min(landmark.X+landmark.Width, state.Width)
+			y1 := min(landmark.Y+landmark.Height, state.Height)
+			if landmark.Kind == "" || x1 <= x0 || y1 <= y0 {
+				continue
+			}
+			landmark.X, landmark.Y, landmark.Width, landmark.Height = x0, y0, x1-x0, y1-y0
+			landmarks = append(landmarks, landmark)
+		}
+		if len(landmarks) > 0 {
+			return landmarks
+		}
+	}
+	return nil
+}
+
+// toWireLandmarks converts landmarks to their wire representation
+func toWireLandmarks(landmarks []Landmark) []transport.Landmark {
+	if len(landmarks) == 0 {
+		return nil
+	}
+	wire := make([]transport.Landmark, len(landmarks))
+	for i, l := range landmarks {
+		wire[i] = transport.Landmark{Kind: l.Kind, Label: l.Label, X: l.X, Y: l.Y, Width: l.Width, Height: l.Height}
+	}
+	return wire
+}
@@ -0,0 +1,92 @@
+package webui
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+// testNetHackScreen has a message, a bit of map and the two status lines
+const testNetHackScreen = "\x1b[2J\x1b[HHello Agent, welcome to NetHack!" +
+	"\x1b[5;10H-----\x1b[6;10H|.@.|\x1b[7;10H-----" +
+	"\x1b[23;1HAgent the Stripling   St:16 Dx:13 Co:17 In:8 Wi:9 Ch:7 Neutral" +
+	"\x1b[24;1HDlvl:1 $:0 HP:14(14) Pw:1(1) AC:6 Xp:1/0 T:1"
+
+func TestNethackLandmarks(t *testing.T) {
+	view := newTestView(t)
+	if err := view.Render([]byte(testNetHackScreen)); err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	width, height := view.GetSize()
+	want := []Landmark{
+		{Kind: LandmarkMessage, Label: "Messages", Width: width, Height: 1},
+		{Kind: LandmarkMap, Label: "Map", Y: 1, Width: width, Height: height - 3},
+		{Kind: LandmarkStatus, Label: "Status lines", Y: height - 2, Width: width, Height: 2},
+	}
+	if got := nethackLandmarks(view.GetCurrentState()); !slices.Equal(got, want) {
+		t.Errorf("nethackLandmarks = %+v, want %+v", got, want)
+	}
+
+	if err := view.Render([]byte("\x1b[2J\x1b[H ## dgamelaunch\r\n\r\n p) Play NetHack")); err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	if got := nethackLandmarks(view.GetCurrentState()); got != nil {
+		t.Errorf("nethackLandmarks of a menu = %+v, want none", got)
+	}
+}
+
+func TestWebView_AddLandmarkParser_ClipsToScreen(t *testing.T) {
+	view := newTestView(t)
+	width, height := view.GetSize()
+	view.AddLandmarkParser(LandmarkParserFunc(func(state *GameState) []Landmark { return nil }))
+	view.AddLandmarkParser(LandmarkParserFunc(func(state *GameState) []Landmark {
+		return []Landmark{
+			{Kind: "sidebar", X: width - 10, Y: -2, Width: 20, Height: 5},
+			{Kind: "offscreen", Y: height, Width: width, Height: 1},
+			{Kind: "", Width: width, Height: 1},
+		}
+	}))
+	view.AddLandmarkParser(LandmarkParserFunc(func(state *GameState) []Landmark {
+		t.Error("parser after the one that recognized the screen was run")
+		return nil
+	}))
+
+	want := []Landmark{{Kind: "sidebar", X: width - 10, Width: 10, Height: 3}}
+	if got := view.GetCurrentState().Landmarks; !slices.Equal(got, want) {
+		t.Errorf("Landmarks = %+v, want %+v", got, want)
+	}
+}
+
+func TestWebUI_Landmarks_InDiffsAndPayloads(t *testing.T) {
+	if _, err := NewWebUI(WebUIOptions{View: newTestView(t), Landmarks: []string{"no-such-game"}}); err == nil {
+		t.Error("NewWebUI accepted an unknown landmark parser")
+	}
+	ui, err := NewWebUI(WebUIOptions{View: newTestView(t), Landmarks: []string{"nethack"}})
+	if err != nil {
+		t.Fatalf("NewWebUI failed: %v", err)
+	}
+	view := ui.GetView()
+	version := view.GetStateManager().GetCurrentVersion()
+	if err := view.Render([]byte(testNetHackScreen)); err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+
+	diff, err := view.GetStateManager().PollChanges(version, time.Second)
+	if err != nil {
+		t.Fatalf("PollChanges failed: %v", err)
+	}
+	if len(diff.Landmarks) != 3 || diff.Landmarks[1].Kind != LandmarkMap {
+		t.Errorf("diff landmarks = %+v, want message, map and status", diff.Landmarks)
+	}
+
+	payload := toStatePayload(view.GetStateManager().GetCurrentState())
+	defer releaseStatePayload(payload)
+	if len(payload.Landmarks) != 3 || payload.Landmarks[2].Kind != LandmarkStatus || payload.Landmarks[2].Height != 2 {
+		t.Errorf("wire landmarks = %+v, want message, map and status", payload.Landmarks)
+	}
+
+	RegisterLandmarkParser("angband", LandmarkParserFunc(func(state *GameState) []Landmark { return nil }))
+	if !slices.Contains(LandmarkParsers(), "angband") {
+		t.Errorf("LandmarkParsers = %v, want angband registered", LandmarkParsers())
+	}
+}
@@ -0,0 +1,163 @@
+// Package webui provides live session statistics for streaming overlays.
+package webui
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// liveStatsInterval is how often /session/live sends statistics to event
+// stream clients
+const liveStatsInterval = time.Second
+
+// LiveStats are spectate-friendly statistics of a session, for streaming
+// overlays such as an OBS browser source next to the embedded game
+type LiveStats struct {
+	APM      int           `json:"apm"`      // keys pressed in the last minute
+	Keys     uint64        `json:"keys"`     // keys pressed since the session started
+	Started  time.Time     `json:"started"`  // start of the session
+	Duration int64         `json:"duration"` // seconds since Started
+	Watchers int           `json:"watchers"` // connected WebSocket clients
+	Progress *GameProgress `json:"progress,omitempty"`
+}
+
+// keyCounter counts the keys players press, in total and per second of the
+// last minute
+type keyCounter struct {
+	mu      sync.Mutex
+	total   uint64
+	counts  [60]int   // keys by second, indexed by Unix second modulo 60
+	seconds [60]int64 // Unix second each count belongs to
+	now     func() time.Time
+}
+
+// newKeyCounter creates an empty counter
+func newKeyCounter() *keyCounter {
+	return &keyCounter{now: time.Now}
+}
+
+// add counts the keys in input sent to the game
+func (c *keyCounter) add(data []byte) {
+	n := countKeys(data)
+	if n == 0 {
+		return
+	}
+	second := c.now().Unix()
+	slot := second % int64(len(c.counts))
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.total += uint64(n)
+	if c.seconds[slot] != second {
+		c.seconds[slot], c.counts[slot] = second, 0
+	}
+	c.counts[slot] += n
+}
+
+// perMinute returns the keys pressed in the last 60 seconds
+func (c *keyCounter) perMinute() int {
+	since := c.now().Unix() - int64(len(c.counts))
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	apm := 0
+	for i, second := range c.seconds {
+		if second > since {
+			apm += c.counts[i]
+		}
+	}
+	return apm
+}
+
+// count returns the keys pressed so far
+func (c *keyCounter) count() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.total
+}
+
+// countKeys returns the number of keys in input: an escape sequence, such
+// as an arrow key, counts as one key, and so does every other character
+func countKeys(data []byte) int {
+	keys := 0
+	for i := 0; i < len(data); keys++ {
+		if data[i] == 0x1b && i+2 < len(data) && (data[i+1] == '[' || data[i+1] == 'O') {
+			// CSI or SS3 sequence up to its final byte
+			i += 2
+			for i < len(data) && (data[i] < 0x40 || data[i] > 0x7e) {
+				i++
+			}
+			i++
+			continue
+		}
+		_, size := utf8.DecodeRune(data[i:])
+		i += size
+	}
+	return keys
+}
+
+// GetLiveStats returns the live statistics of the session
+func (w *WebUI) GetLiveStats() LiveStats {
+	now := w.keys.now()
+	stats := LiveStats{
+		APM:      w.keys.perMinute(),
+		Keys:     w.keys.count(),
+		Started:  w.started,
+		Duration: int64(now.Sub(w.started) / time.Second),
+		Watchers: w.wsHandler.GetClientCount(),
+	}
+	if view := w.GetView(); view != nil {
+		if state := view.GetStateManager().GetCurrentState(); state != nil {
+			stats.Progress = state.Progress
+		}
+	}
+	return stats
+}
+
+// handleLiveStats serves the live statistics as JSON, or as a stream of
+// server-sent events, one per liveStatsInterval, to clients that accept
+// text/event-stream such as EventSource
+func (w *WebUI) handleLiveStats(rw http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		rw.Header().Set("Allow", "GET, HEAD")
+		http.Error(rw, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !strings.Contains(r.Header.Get("Accept"), "text/event-stream") {
+		writeJSON(rw, http.StatusOK, w.GetLiveStats())
+		return
+	}
+
+	rw.Header().Set("Content-Type", "text/event-stream")
+	rw.Header().Set("Cache-Control", "no-cache")
+	rw.WriteHeader(http.StatusOK)
+
+	// The stream outlives the server's write timeout
+	controller := http.NewResponseController(rw)
+	controller.SetWriteDeadline(time.Time{})
+
+	ticker := time.NewTicker(liveStatsInterval)
+	defer ticker.Stop()
+	for {
+		data, err := json.Marshal(w.GetLiveStats())
+		if err != nil {
+			return
+		}
+		if _, err := fmt.Fprintf(rw, "event: stats\ndata: %s\n\n", data); err != nil {
+			return
+		}
+		if err := controller.Flush(); err != nil {
+			return
+		}
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
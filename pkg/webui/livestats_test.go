@@ -0,0 +1,112 @@
+package webui
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCountKeys(t *testing.T) {
+	tests := []struct {
+		input string
+		want  int
+	}{
+		{"", 0},
+		{"hjkl", 4},
+		{"\x1b[A\x1b[B", 2},
+		{"\x1bOP", 1},
+		{"\x1b[1;5C", 1},
+		{"\x1b", 1},
+		{"é\r", 2},
+	}
+	for _, tt := range tests {
+		if got := countKeys([]byte(tt.input)); got != tt.want {
+			t.Errorf("countKeys(%q) = %d, want %d", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestKeyCounter_PerMinute(t *testing.T) {
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	c := newKeyCounter()
+	c.now = func() time.Time { return now }
+
+	c.add([]byte("abc"))
+	now = now.Add(30 * time.Second)
+	c.add([]byte("de"))
+	if apm := c.perMinute(); apm != 5 {
+		t.Errorf("perMinute = %d, want 5", apm)
+	}
+
+	// Keys older than a minute drop out, even when their slot is reused
+	now = now.Add(40 * time.Second)
+	if apm := c.perMinute(); apm != 2 {
+		t.Errorf("perMinute after 70s = %d, want 2", apm)
+	}
+	now = now.Add(20 * time.Second)
+	c.add([]byte("f"))
+	if apm, total := c.perMinute(), c.count(); apm != 1 || total != 6 {
+		t.Errorf("perMinute, count = %d, %d; want 1, 6", apm, total)
+	}
+}
+
+func TestWebUI_handleLiveStats(t *testing.T) {
+	ui := newTestWebUI(t)
+	go func() {
+		for {
+			if _, err := ui.GetView().HandleInput(); err != nil {
+				return
+			}
+		}
+	}()
+	if err := ui.handleClientInput("c1", "jj\x1b[D"); err != nil {
+		t.Fatalf("handleClientInput failed: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	ui.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/session/live", nil))
+	var stats LiveStats
+	if err := json.Unmarshal(rec.Body.Bytes(), &stats); err != nil {
+		t.Fatalf("GET /session/live = %d %s", rec.Code, rec.Body.String())
+	}
+	if stats.Keys != 3 || stats.APM != 3 || stats.Started.IsZero() || stats.Watchers != 0 {
+		t.Errorf("stats = %+v", stats)
+	}
+
+	// EventSource clients receive a stream of events
+	server := httptest.NewServer(ui)
+	defer server.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/session/live", nil)
+	req.Header.Set("Accept", "text/event-stream")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("stream request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("Content-Type = %q", ct)
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	events := 0
+	for events < 2 && scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data: ")
+		if !ok {
+			continue
+		}
+		if err := json.Unmarshal([]byte(data), &stats); err != nil || stats.Keys != 3 {
+			t.Errorf("event %q: %+v, %v", data, stats, err)
+		}
+		events++
+	}
+	if events != 2 {
+		t.Errorf("received %d events, want 2", events)
+	}
+}
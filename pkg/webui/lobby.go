@@ -0,0 +1,176 @@
+// Package webui provides a lobby page listing servers, sessions and
+// recordings as an entry point for clients.
+package webui
+
+import (
+	"html/template"
+	"net/http"
+
+	"github.com/opd-ai/go-gamelaunch-www/pkg/recording"
+)
+
+// defaultLobbyRecordings is how many recent recordings the lobby lists by
+// default
+const defaultLobbyRecordings = 10
+
+// LobbyConfig enables the lobby at /lobby, an entry point listing the
+// configured game servers, active sessions with their watch counts, and
+// recent recordings, instead of booting straight into the terminal
+type LobbyConfig struct {
+	// Servers are the configured game servers
+	Servers []LobbyServer `yaml:"-"`
+
+	// Sessions lists the sessions of a multi-session deployment; nil lists
+	// only this WebUI's session
+	Sessions *SessionRouter `yaml:"-"`
+
+	// Recent recordings listed when recordings are enabled; default 10,
+	// negative disables the list
+	RecentRecordings int `yaml:"recent_recordings,omitempty"`
+}
+
+// LobbyServer is a game server listed in the lobby
+type LobbyServer struct {
+	Name string `json:"name"`
+	Host string `json:"host"`
+	Port int    `json:"port,omitempty"`
+	Game string `json:"game,omitempty"` // default game
+}
+
+// LobbySession is an active session listed in the lobby
+type LobbySession struct {
+	ID        string `json:"id"`
+	URL       string `json:"url"`
+	Player    string `json:"player,omitempty"`
+	Width     int    `json:"width"`
+	Height    int    `json:"height"`
+	Watchers  int    `json:"watchers"`            // connected clients
+	Thumbnail string `json:"thumbnail,omitempty"` // when thumbnails are enabled
+	Recorded  bool   `json:"recorded"`
+}
+
+// LobbyInfo is the content of the lobby
+type LobbyInfo struct {
+	Servers    []LobbyServer         `json:"servers"`
+	Sessions   []LobbySession        `json:"sessions"`
+	Recordings []recording.Recording `json:"recordings"`
+}
+
+// lobbySession describes the session w serves below url
+func (w *WebUI) lobbySession(id, url string) LobbySession {
+	info := w.GetSessionInfo()
+	session := LobbySession{
+		ID:       id,
+		URL:      url,
+		Player:   w.options.Player,
+		Width:    info.Width,
+		Height:   info.Height,
+		Watchers: info.Clients,
+		Recorded: w.recorder != nil && w.recorder.Enabled(),
+	}
+	if w.thumbnails != nil {
+		session.Thumbnail = url + "thumb.png"
+	}
+	return session
+}
+
+// LobbySessions describes the sessions served by the router
+func (sr *SessionRouter) LobbySessions() []LobbySession {
+	ids := sr.Sessions()
+	sessions := make([]LobbySession, 0, len(ids))
+	for _, id := range ids {
+		if ui, ok := sr.session(id); ok {
+			sessions = append(sessions, ui.lobbySession(id, "/s/"+id+"/"))
+		}
+	}
+	return sessions
+}
+
+// lobbyInfo gathers the content of the lobby for r
+func (w *WebUI) lobbyInfo(r *http.Request) LobbyInfo {
+	cfg := w.options.Lobby
+	info := LobbyInfo{
+		Servers:    append([]LobbyServer{}, cfg.Servers...),
+		Recordings: []recording.Recording{},
+	}
+
+	if cfg.Sessions != nil {
+		info.Sessions = cfg.Sessions.LobbySessions()
+	} else {
+		info.Sessions = []LobbySession{w.lobbySession(w.sessionName(), basePath(r.Context())+"/")}
+	}
+
+	limit := cfg.RecentRecordings
+	if limit == 0 {
+		limit = defaultLobbyRecordings
+	}
+	if store := w.options.Recordings; store != nil && limit > 0 {
+		recent, err := store.Query(r.Context(), recording.Filter{Limit: limit})
+		if err != nil {
+			w.logger(r.Context()).Warn("webui: failed to list recordings for the lobby", "error", err)
+		} else {
+			info.Recordings = recent
+		}
+	}
+	return info
+}
+
+// lobbyPage renders the lobby for browsers
+var lobbyPage = template.Must(template.New("lobby").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta http-equiv="refresh" content="30">
+<title>Lobby</title>
+</head>
+<body>
+<h1>Lobby</h1>
+<h2>Games in progress</h2>
+<ul>
+{{range .Sessions}}<li><a href="{{.URL}}">{{if .Thumbnail}}<img src="{{.Thumbnail}}" alt=""> {{end}}{{if .Player}}{{.Player}}{{else}}{{.ID}}{{end}}</a> ({{.Width}}x{{.Height}}, {{.Watchers}} watching{{if .Recorded}}, recorded{{end}})</li>
+{{else}}<li>No games in progress</li>
+{{end}}</ul>
+{{if .Servers}}<h2>Servers</h2>
+<table>
+<thead><tr><th>Name</th><th>Host</th><th>Game</th></tr></thead>
+<tbody>
+{{range .Servers}}<tr><td>{{.Name}}</td><td>{{.Host}}{{if .Port}}:{{.Port}}{{end}}</td><td>{{.Game}}</td></tr>
+{{end}}</tbody>
+</table>
+{{end}}{{if .Recordings}}<h2>Recent recordings</h2>
+<table>
+<thead><tr><th>Player</th><th>Game</th><th>Started</th><th>Result</th></tr></thead>
+<tbody>
+{{range .Recordings}}<tr><td><a href="{{$.Base}}/recordings/file?id={{.ID}}">{{.Player}}</a></td><td>{{.Game}}</td><td>{{.Started.Format "2006-01-02 15:04"}}</td><td>{{.Result}}</td></tr>
+{{end}}</tbody>
+</table>
+{{end}}</body>
+</html>
+`))
+
+// handleLobbyPage serves GET /lobby, a self-refreshing lobby page
+func (w *WebUI) handleLobbyPage(rw http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		rw.Header().Set("Allow", http.MethodGet)
+		http.Error(rw, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	rw.Header().Set("Content-Type", "text/html; charset=utf-8")
+	err := lobbyPage.Execute(rw, struct {
+		LobbyInfo
+		Base string
+	}{w.lobbyInfo(r), basePath(r.Context())})
+	if err != nil {
+		w.logger(r.Context()).Error("webui: render lobby failed", "error", err)
+	}
+}
+
+// handleLobbyInfo serves GET /lobby/info as JSON
+func (w *WebUI) handleLobbyInfo(rw http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		rw.Header().Set("Allow", http.MethodGet)
+		http.Error(rw, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	writeJSON(rw, http.StatusOK, w.lobbyInfo(r))
+}
@@ -0,0 +1,95 @@
+package webui
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// getLobbyInfo fetches /lobby/info from handler
+func getLobbyInfo(t *testing.T, handler http.Handler, path string) LobbyInfo {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET %s status = %d, want %d", path, rec.Code, http.StatusOK)
+	}
+	var info LobbyInfo
+	if err := json.NewDecoder(rec.Body).Decode(&info); err != nil {
+		t.Fatalf("Failed to decode lobby: %v", err)
+	}
+	return info
+}
+
+func TestWebUI_HandleLobbyInfo_SingleSession(t *testing.T) {
+	ui, err := NewWebUI(WebUIOptions{
+		View:       newTestView(t),
+		Player:     "alice",
+		Thumbnails: &ThumbnailConfig{},
+		Lobby: &LobbyConfig{Servers: []LobbyServer{
+			{Name: "nao", Host: "alt.org", Port: 22, Game: "nethack"},
+		}},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create WebUI: %v", err)
+	}
+
+	info := getLobbyInfo(t, ui, "/lobby/info")
+	if len(info.Servers) != 1 || info.Servers[0].Host != "alt.org" {
+		t.Errorf("Servers = %+v, want the configured server", info.Servers)
+	}
+	if len(info.Sessions) != 1 {
+		t.Fatalf("Sessions = %+v, want this session", info.Sessions)
+	}
+	session := info.Sessions[0]
+	if session.URL != "/" || session.Thumbnail != "/thumb.png" || session.Player != "alice" || session.Width != 80 {
+		t.Errorf("session = %+v", session)
+	}
+	if info.Recordings == nil {
+		t.Error("Recordings = null, want an empty list")
+	}
+}
+
+func TestWebUI_HandleLobbyInfo_ListsRouterSessions(t *testing.T) {
+	router := NewSessionRouter(nil)
+	for _, id := range []string{"bob", "alice"} {
+		if err := router.Add(id, newTestWebUI(t)); err != nil {
+			t.Fatalf("Add(%s) error = %v", id, err)
+		}
+	}
+	lobby, err := NewWebUI(WebUIOptions{View: newTestView(t), Lobby: &LobbyConfig{Sessions: router}})
+	if err != nil {
+		t.Fatalf("Failed to create WebUI: %v", err)
+	}
+
+	info := getLobbyInfo(t, lobby, "/lobby/info")
+	if len(info.Sessions) != 2 || info.Sessions[0].URL != "/s/alice/" || info.Sessions[1].URL != "/s/bob/" {
+		t.Errorf("Sessions = %+v, want alice and bob below /s/", info.Sessions)
+	}
+}
+
+func TestWebUI_HandleLobbyPage(t *testing.T) {
+	ui, err := NewWebUI(WebUIOptions{
+		View:   newTestView(t),
+		Player: "alice",
+		Lobby:  &LobbyConfig{Servers: []LobbyServer{{Name: "<script>", Host: "localhost"}}},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create WebUI: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	ui.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/lobby", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	body := rec.Body.String()
+	if strings.Contains(body, "<script>") || !strings.Contains(body, "&lt;script&gt;") {
+		t.Error("server name was not escaped")
+	}
+	if !strings.Contains(body, `<a href="/">alice</a>`) {
+		t.Errorf("page does not link the session:\n%s", body)
+	}
+}
@@ -0,0 +1,59 @@
+// Package webui provides the cell metadata channel filled by parser plugins.
+package webui
+
+import (
+	"fmt"
+	"maps"
+	"strings"
+)
+
+// CellAnnotator is a parser plugin that recognizes what the screen shows and
+// attaches semantic metadata to cells, e.g. monster=jackal on a 'd'. Clients
+// use it for tooltips, minimaps and similar features without the core cell
+// schema changing for each of them.
+type CellAnnotator interface {
+	// Namespace qualifies the keys the annotator sets, e.g. "nethack"
+	Namespace() string
+
+	// Annotate inspects state and calls annotate for each piece of
+	// metadata. state must not be modified.
+	Annotate(state *GameState, annotate func(x, y int, key, value string))
+}
+
+// AddAnnotator registers a parser plugin run on every new state. Its keys
+// appear in Cell.Meta as "<namespace>.<key>".
+func (v *WebView) AddAnnotator(annotator CellAnnotator) error {
+	namespace := annotator.Namespace()
+	if namespace == "" || strings.ContainsAny(namespace, ". ") {
+		return fmt.Errorf("annotator: invalid namespace %q", namespace)
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	for _, existing := range v.annotators {
+		if existing.Namespace() == namespace {
+			return fmt.Errorf("annotator: namespace %q already registered", namespace)
+		}
+	}
+	v.annotators = append(v.annotators, annotator)
+	return nil
+}
+
+// annotate runs the registered annotators on state. Cells get fresh Meta
+// maps, which are never modified afterwards, so copies of the state may
+// share them.
+func (v *WebView) annotate(state *GameState) {
+	for _, annotator := range v.annotators {
+		prefix := annotator.Namespace() + "."
+		annotator.Annotate(state, func(x, y int, key, value string) {
+			if y < 0 || y >= len(state.Buffer) || x < 0 || x >= len(state.Buffer[y]) || key == "" {
+				return
+			}
+			cell := &state.Buffer[y][x]
+			meta := make(map[string]string, len(cell.Meta)+1)
+			maps.Copy(meta, cell.Meta)
+			meta[prefix+key] = value
+			cell.Meta = meta
+		})
+	}
+}
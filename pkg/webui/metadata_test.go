@@ -0,0 +1,88 @@
+package webui
+
+import (
+	"testing"
+
+	"github.com/opd-ai/go-gamelaunch-www/pkg/transport"
+)
+
+// glyphAnnotator tags every cell showing glyph with key=value
+type glyphAnnotator struct {
+	namespace  string
+	glyph      rune
+	key, value string
+}
+
+func (a glyphAnnotator) Namespace() string { return a.namespace }
+
+func (a glyphAnnotator) Annotate(state *GameState, annotate func(x, y int, key, value string)) {
+	for y, row := range state.Buffer {
+		for x, cell := range row {
+			if cell.Char == a.glyph {
+				annotate(x, y, a.key, a.value)
+			}
+		}
+	}
+	annotate(-1, 0, a.key, "outside the screen")
+}
+
+func TestWebView_AddAnnotator_FillsCellMetadata(t *testing.T) {
+	view := newTestView(t)
+	for _, a := range []glyphAnnotator{
+		{"monsters", 'd', "kind", "jackal"},
+		{"items", 'd', "kind", "not an item"},
+	} {
+		if err := view.AddAnnotator(a); err != nil {
+			t.Fatalf("AddAnnotator(%s) failed: %v", a.namespace, err)
+		}
+	}
+
+	if err := view.Render([]byte("d.")); err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	state := view.GetCurrentState()
+	meta := state.Buffer[0][0].Meta
+	if len(meta) != 2 || meta["monsters.kind"] != "jackal" || meta["items.kind"] != "not an item" {
+		t.Errorf("Meta of 'd' = %v, want both namespaced keys", meta)
+	}
+	if state.Buffer[0][1].Meta != nil {
+		t.Errorf("Meta of '.' = %v, want none", state.Buffer[0][1].Meta)
+	}
+
+	payload := toStatePayloadFor(state, transport.ViewOptions{}, nil)
+	defer releaseStatePayload(payload)
+	if payload.Buffer[0][0].Meta["monsters.kind"] != "jackal" {
+		t.Errorf("wire cell Meta = %v, want the metadata", payload.Buffer[0][0].Meta)
+	}
+}
+
+func TestWebView_AddAnnotator_RejectsInvalidNamespaces(t *testing.T) {
+	view := newTestView(t)
+	if err := view.AddAnnotator(glyphAnnotator{namespace: "monsters"}); err != nil {
+		t.Fatalf("AddAnnotator failed: %v", err)
+	}
+	for _, namespace := range []string{"", "a.b", "monsters"} {
+		if err := view.AddAnnotator(glyphAnnotator{namespace: namespace}); err == nil {
+			t.Errorf("AddAnnotator(%q) succeeded, want error", namespace)
+		}
+	}
+}
+
+func TestStateManager_cellsDiffer_ComparesMetadata(t *testing.T) {
+	sm := NewStateManager()
+	a := Cell{Char: 'd', Meta: map[string]string{"monsters.kind": "jackal"}}
+	tests := []struct {
+		name string
+		b    Cell
+		want bool
+	}{
+		{"same metadata", Cell{Char: 'd', Meta: map[string]string{"monsters.kind": "jackal"}}, false},
+		{"other metadata", Cell{Char: 'd', Meta: map[string]string{"monsters.kind": "coyote"}}, true},
+		{"no metadata", Cell{Char: 'd'}, true},
+	}
+	for _, tt := range tests {
+		if got := sm.cellsDiffer(a, tt.b); got != tt.want {
+			t.Errorf("%s: cellsDiffer = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
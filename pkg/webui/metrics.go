@@ -0,0 +1,112 @@
+// Package webui provides a Prometheus text-format metrics endpoint.
+package webui
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// metricsContentType is the Prometheus text exposition format content type
+const metricsContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// handleMetrics serves server metrics in the Prometheus text format
+func (w *WebUI) handleMetrics(rw http.ResponseWriter, r *http.Request) {
+	rw.Header().Set("Content-Type", metricsContentType)
+	w.writeMetrics(rw)
+}
+
+// writeMetrics writes all metric families to out
+func (w *WebUI) writeMetrics(out io.Writer) {
+	info := GetBuildInfo()
+	writeMetric(out, "dgconnect_build_info", "gauge",
+		"Build information of the running server.",
+		map[string]string{
+			"version":    info.Version,
+			"commit":     info.Commit,
+			"date":       info.Date,
+			"go_version": info.GoVersion,
+		}, 1)
+
+	// Every session metric names the session's correlation ID
+	session := map[string]string{"session_id": w.correlationID}
+
+	writeMetric(out, "dgconnect_ws_clients", "gauge",
+		"Number of connected WebSocket clients.", session, float64(w.wsHandler.GetClientCount()))
+
+	var skipped uint64
+	slow := 0
+	for _, stats := range w.wsHandler.ClientStats() {
+		skipped += stats.Skipped
+		if stats.KeyframeOnly {
+			slow++
+		}
+	}
+	writeMetric(out, "dgconnect_ws_skipped_messages", "gauge",
+		"Messages dropped or superseded for connected WebSocket clients.", session, float64(skipped))
+	writeMetric(out, "dgconnect_ws_slow_clients", "gauge",
+		"Connected WebSocket clients in keyframe-only mode.", session, float64(slow))
+
+	webSent, webReceived := w.wsHandler.Traffic()
+	writeMetric(out, "dgconnect_ws_sent_bytes_total", "counter",
+		"Bytes written to WebSocket clients.", session, float64(webSent))
+	writeMetric(out, "dgconnect_ws_received_bytes_total", "counter",
+		"Bytes read from WebSocket clients.", session, float64(webReceived))
+	if w.bandwidth != nil {
+		writeMetric(out, "dgconnect_game_sent_bytes_total", "counter",
+			"Input bytes sent to the game.", session, float64(w.bandwidth.gameSent.Load()))
+		writeMetric(out, "dgconnect_game_received_bytes_total", "counter",
+			"Output bytes received from the game.", session, float64(w.bandwidth.gameReceived.Load()))
+		degraded := 0.0
+		if w.bandwidth.isDegraded() {
+			degraded = 1
+		}
+		writeMetric(out, "dgconnect_bandwidth_degraded", "gauge",
+			"Whether a bandwidth cap has been reached.", session, degraded)
+	}
+
+	if view := w.GetView(); view != nil {
+		writeMetric(out, "dgconnect_escape_violations_total", "counter",
+			"Oversized escape sequences discarded by the terminal parser.", session,
+			float64(view.EscapeStats().Violations))
+		writeMetric(out, "dgconnect_state_version", "counter",
+			"Current game state version.", session, float64(view.GetStateManager().GetCurrentVersion()))
+	}
+	if w.quota != nil {
+		usage := w.GetQuotaUsage()
+		writeMetric(out, "dgconnect_screen_memory_bytes", "gauge",
+			"Estimated memory of the screen buffer.", session, float64(usage.Memory))
+		writeMetric(out, "dgconnect_scrollback_bytes", "gauge",
+			"Output kept in memory for instant replay.", session, float64(usage.Scrollback))
+		writeMetric(out, "dgconnect_recording_bytes", "gauge",
+			"Size of the recording in progress.", session, float64(usage.Recording))
+	}
+}
+
+// writeMetric writes a single-sample metric family with HELP and TYPE lines
+func writeMetric(out io.Writer, name, kind, help string, labels map[string]string, value float64) {
+	fmt.Fprintf(out, "# HELP %s %s\n", name, help)
+	fmt.Fprintf(out, "# TYPE %s %s\n", name, kind)
+	fmt.Fprintf(out, "%s%s %g\n", name, formatLabels(labels), value)
+}
+
+// formatLabels renders a label set in sorted key order
+func formatLabels(labels map[string]string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		parts = append(parts, fmt.Sprintf("%s=%q", k, labels[k]))
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
@@ -0,0 +1,313 @@
+// Package webui provides a downsampled minimap of the explored map area.
+package webui
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// Minimap codes, one per class of map cell
+const (
+	minimapUnknown  = ' '
+	minimapFloor    = '.'
+	minimapCorridor = '#'
+	minimapWall     = '|'
+	minimapWater    = '~'
+	minimapDoor     = '+'
+	minimapStairs   = '>'
+	minimapItem     = 'i'
+	minimapMonster  = 'm'
+	minimapPlayer   = '@'
+)
+
+// minimapPriority orders the codes from most to least important; a minimap
+// cell covering several screen cells shows the most important one
+const minimapPriority = "@mi>+~|#. "
+
+// minimapClasses names the class of each code. Parser plugins classify a
+// cell by annotating it with one of these names under the "minimap" key
+// (e.g. "nethack.minimap" = "door"), overriding the glyph heuristics.
+var minimapClasses = map[byte]string{
+	minimapUnknown:  "unknown",
+	minimapFloor:    "floor",
+	minimapCorridor: "corridor",
+	minimapWall:     "wall",
+	minimapWater:    "water",
+	minimapDoor:     "door",
+	minimapStairs:   "stairs",
+	minimapItem:     "item",
+	minimapMonster:  "monster",
+	minimapPlayer:   "player",
+}
+
+// minimapCodes maps class names to codes
+var minimapCodes = func() map[string]byte {
+	codes := make(map[string]byte, len(minimapClasses))
+	for code, class := range minimapClasses {
+		codes[class] = code
+	}
+	return codes
+}()
+
+// MinimapConfig maintains an overview of the explored parts of the map,
+// served at /game/minimap. Terrain stays on the minimap once seen, until the
+// map area is cleared (e.g. on a new level) or the screen is resized, while
+// the player, monsters and items show where they are now.
+type MinimapConfig struct {
+	Top    *int `yaml:"top,omitempty"`     // first map row, default 1 (below the message line)
+	Bottom *int `yaml:"bottom,omitempty"`  // last map row; negative counts from the bottom, default -3
+	ScaleX int  `yaml:"scale_x,omitempty"` // screen columns per minimap cell, default 2
+	ScaleY int  `yaml:"scale_y,omitempty"` // screen rows per minimap cell, default 1
+}
+
+// Minimap is the response of GET /game/minimap. Each row has one code per
+// minimap cell, named by Legend.
+type Minimap struct {
+	Width   int               `json:"width"`
+	Height  int               `json:"height"`
+	ScaleX  int               `json:"scale_x"`
+	ScaleY  int               `json:"scale_y"`
+	Top     int               `json:"top"` // screen row of the first map row
+	Rows    []string          `json:"rows"`
+	Legend  map[string]string `json:"legend"`
+	Version uint64            `json:"version"`
+}
+
+// minimapper remembers the explored terrain at screen resolution
+type minimapper struct {
+	top    int
+	bottom int
+	scaleX int
+	scaleY int
+
+	mu       sync.Mutex
+	width    int
+	height   int
+	mapTop   int
+	terrain  []byte // remembered terrain codes, row-major
+	entities []byte // player, monster and item codes of the latest state
+	version  uint64
+}
+
+// newMinimapper validates cfg
+func newMinimapper(cfg MinimapConfig) (*minimapper, error) {
+	m := &minimapper{top: 1, bottom: -3, scaleX: 2, scaleY: 1}
+	if cfg.Top != nil {
+		if *cfg.Top < 0 {
+			return nil, fmt.Errorf("minimap: invalid top %d", *cfg.Top)
+		}
+		m.top = *cfg.Top
+	}
+	if cfg.Bottom != nil {
+		m.bottom = *cfg.Bottom
+	}
+	if cfg.Bottom != nil && *cfg.Bottom >= 0 && *cfg.Bottom < m.top {
+		return nil, fmt.Errorf("minimap: bottom %d above top %d", *cfg.Bottom, m.top)
+	}
+	if cfg.ScaleX < 0 || cfg.ScaleX > 16 {
+		return nil, fmt.Errorf("minimap: invalid scale_x %d", cfg.ScaleX)
+	}
+	if cfg.ScaleY < 0 || cfg.ScaleY > 16 {
+		return nil, fmt.Errorf("minimap: invalid scale_y %d", cfg.ScaleY)
+	}
+	if cfg.ScaleX > 0 {
+		m.scaleX = cfg.ScaleX
+	}
+	if cfg.ScaleY > 0 {
+		m.scaleY = cfg.ScaleY
+	}
+	return m, nil
+}
+
+// bounds returns the first and last map rows for a screen height
+func (m *minimapper) bounds(height int) (int, int) {
+	bottom := m.bottom
+	if bottom < 0 {
+		bottom += height
+	}
+	if bottom >= height {
+		bottom = height - 1
+	}
+	return m.top, bottom
+}
+
+// update records the map area of state unless it was already recorded
+func (m *minimapper) update(state *GameState) {
+	if state == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.terrain != nil && m.version == state.Version {
+		return
+	}
+
+	top, bottom := m.bounds(state.Height)
+	width, height := state.Width, bottom-top+1
+	if height < 0 {
+		height = 0
+	}
+	if width != m.width || height != m.height || top != m.mapTop {
+		m.width, m.height, m.mapTop = width, height, top
+		m.terrain = blankMinimap(width * height)
+	}
+
+	entities := blankMinimap(width * height)
+	blank := true
+	for y := 0; y < height && top+y < len(state.Buffer); y++ {
+		row := state.Buffer[top+y]
+		for x := 0; x < width && x < len(row); x++ {
+			code := classifyCell(row[x])
+			i := y*width + x
+			switch code {
+			case minimapUnknown:
+				continue
+			case minimapPlayer, minimapMonster, minimapItem:
+				entities[i] = code
+			default:
+				m.terrain[i] = code
+			}
+			blank = false
+		}
+	}
+	if blank {
+		m.terrain = blankMinimap(width * height)
+	}
+	m.entities = entities
+	m.version = state.Version
+}
+
+// snapshot downsamples the remembered map
+func (m *minimapper) snapshot() Minimap {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	cols := (m.width + m.scaleX - 1) / m.scaleX
+	rows := (m.height + m.scaleY - 1) / m.scaleY
+	minimap := Minimap{
+		Width:   cols,
+		Height:  rows,
+		ScaleX:  m.scaleX,
+		ScaleY:  m.scaleY,
+		Top:     m.mapTop,
+		Rows:    make([]string, rows),
+		Legend:  make(map[string]string, len(minimapClasses)),
+		Version: m.version,
+	}
+	for code, class := range minimapClasses {
+		minimap.Legend[string(rune(code))] = class
+	}
+
+	line := make([]byte, cols)
+	for row := 0; row < rows; row++ {
+		for col := 0; col < cols; col++ {
+			best := byte(minimapUnknown)
+			for y := row * m.scaleY; y < (row+1)*m.scaleY && y < m.height; y++ {
+				for x := col * m.scaleX; x < (col+1)*m.scaleX && x < m.width; x++ {
+					code := m.entities[y*m.width+x]
+					if code == minimapUnknown {
+						code = m.terrain[y*m.width+x]
+					}
+					if minimapRank(code) < minimapRank(best) {
+						best = code
+					}
+				}
+			}
+			line[col] = best
+		}
+		minimap.Rows[row] = string(line)
+	}
+	return minimap
+}
+
+// run records every state change of view, with the annotations of its
+// parser plugins, until ctx is done
+func (m *minimapper) run(ctx context.Context, view *WebView) {
+	sm := view.GetStateManager()
+	version := sm.GetCurrentVersion()
+	m.update(view.GetCurrentState())
+	for {
+		if _, err := sm.PollChangesWithContext(ctx, version); err != nil {
+			return
+		}
+		state := view.GetCurrentState()
+		if state == nil || state.Version <= version {
+			continue
+		}
+		m.update(state)
+		version = state.Version
+	}
+}
+
+// blankMinimap returns n unknown cells
+func blankMinimap(n int) []byte {
+	return []byte(strings.Repeat(string(rune(minimapUnknown)), n))
+}
+
+// minimapRank returns the priority of code, lower being more important
+func minimapRank(code byte) int {
+	return strings.IndexByte(minimapPriority, code)
+}
+
+// classifyCell returns the minimap code of a cell, preferring the most
+// important class annotated by a parser plugin over the glyph
+func classifyCell(cell Cell) byte {
+	if cell.Concealed {
+		return minimapUnknown
+	}
+	annotated := byte(minimapUnknown)
+	for key, value := range cell.Meta {
+		if !strings.HasSuffix(key, ".minimap") {
+			continue
+		}
+		if code, ok := minimapCodes[value]; ok && minimapRank(code) < minimapRank(annotated) {
+			annotated = code
+		}
+	}
+	if annotated != minimapUnknown {
+		return annotated
+	}
+	return classifyGlyph(cell.Char)
+}
+
+// classifyGlyph guesses the class of a roguelike map glyph
+func classifyGlyph(r rune) byte {
+	switch {
+	case r == '@':
+		return minimapPlayer
+	case r == '.' || r == '·':
+		return minimapFloor
+	case r == '#':
+		return minimapCorridor
+	case r == '-' || r == '|' || (r >= 0x2500 && r <= 0x257F):
+		return minimapWall
+	case r == '+':
+		return minimapDoor
+	case r == '<' || r == '>':
+		return minimapStairs
+	case r == '}' || r == '{' || r == '~' || r == '≈':
+		return minimapWater
+	case strings.ContainsRune(")[%?!/=*\"($", r):
+		return minimapItem
+	case r == '&' || (r < 0x80 && (r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z')):
+		return minimapMonster
+	}
+	return minimapUnknown
+}
+
+// handleMinimap serves GET /game/minimap, the overview of the explored map
+func (w *WebUI) handleMinimap(rw http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		rw.Header().Set("Allow", http.MethodGet)
+		http.Error(rw, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if view := w.GetView(); view != nil {
+		w.minimap.update(view.GetCurrentState())
+	}
+	writeJSON(rw, http.StatusOK, w.minimap.snapshot())
+}
@@ -0,0 +1,144 @@
+package webui
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// minimapState returns a state showing lines, cut or padded with blanks to
+// width
+func minimapState(version uint64, width int, lines ...string) *GameState {
+	state := &GameState{Width: width, Height: len(lines), Version: version, Buffer: make([][]Cell, len(lines))}
+	for y, line := range lines {
+		row := make([]Cell, width)
+		for x := range row {
+			row[x] = Cell{Char: ' '}
+		}
+		for x, r := range []rune(line) {
+			if x >= width {
+				break
+			}
+			row[x] = Cell{Char: r}
+		}
+		state.Buffer[y] = row
+	}
+	return state
+}
+
+func TestMinimapper_RemembersExploredTerrain(t *testing.T) {
+	zero := 0
+	last := -1
+	m, err := newMinimapper(MinimapConfig{Top: &zero, Bottom: &last, ScaleX: 1, ScaleY: 1})
+	if err != nil {
+		t.Fatalf("newMinimapper failed: %v", err)
+	}
+
+	m.update(minimapState(1, 6, "|.@d#+", "-<)}  "))
+	got := m.snapshot()
+	want := []string{"|.@m#+", "|>i~  "}
+	for y := range want {
+		if got.Rows[y] != want[y] {
+			t.Errorf("row %d = %q, want %q", y, got.Rows[y], want[y])
+		}
+	}
+
+	// Out of sight terrain stays; the player and monster moved away
+	m.update(minimapState(2, 6, "  .   ", "      "))
+	got = m.snapshot()
+	if got.Rows[0] != "|.. #+" || got.Rows[1] != "|> ~  " {
+		t.Errorf("rows after moving = %q, want remembered terrain without entities", got.Rows)
+	}
+
+	// A cleared map starts over, e.g. on a new level
+	m.update(minimapState(3, 6, "      ", "      "))
+	if got = m.snapshot(); got.Rows[0] != "      " {
+		t.Errorf("row 0 after clearing = %q, want blank", got.Rows[0])
+	}
+}
+
+func TestMinimapper_DownsamplesByPriority(t *testing.T) {
+	m, err := newMinimapper(MinimapConfig{ScaleX: 2, ScaleY: 2})
+	if err != nil {
+		t.Fatalf("newMinimapper failed: %v", err)
+	}
+
+	// Rows 0 (messages) and the last two (status) are not part of the map
+	m.update(minimapState(1, 5, "You see here a dagger.", "..@#.", "|.>.|", "..", "Dlvl:1", "HP:10(10)"))
+	got := m.snapshot()
+	if got.Width != 3 || got.Height != 2 || got.Top != 1 {
+		t.Fatalf("size = %dx%d at row %d, want 3x2 at row 1", got.Width, got.Height, got.Top)
+	}
+	if got.Rows[0] != "|@|" || got.Rows[1] != ".  " {
+		t.Errorf("rows = %q, want [\"|@|\" \".  \"]", got.Rows)
+	}
+	if got.Legend["@"] != "player" || got.Legend[" "] != "unknown" {
+		t.Errorf("legend = %v", got.Legend)
+	}
+}
+
+func TestClassifyCell_PrefersAnnotations(t *testing.T) {
+	tests := []struct {
+		name string
+		cell Cell
+		want byte
+	}{
+		{"glyph", Cell{Char: '+'}, minimapDoor},
+		{"annotated", Cell{Char: '+', Meta: map[string]string{"nethack.minimap": "item"}}, minimapItem},
+		{"most important annotation", Cell{Char: 'x', Meta: map[string]string{"a.minimap": "floor", "b.minimap": "monster"}}, minimapMonster},
+		{"unknown class", Cell{Char: '#', Meta: map[string]string{"nethack.minimap": "lava"}}, minimapCorridor},
+		{"other key", Cell{Char: '#', Meta: map[string]string{"nethack.kind": "door"}}, minimapCorridor},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := classifyCell(tt.cell); got != tt.want {
+				t.Errorf("classifyCell = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWebUI_HandleMinimap(t *testing.T) {
+	view := newTestView(t)
+	w, err := NewWebUI(WebUIOptions{View: view, Minimap: &MinimapConfig{}})
+	if err != nil {
+		t.Fatalf("NewWebUI failed: %v", err)
+	}
+	if err := view.Render([]byte("Hello\r\n|.@.|")); err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	w.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/game/minimap", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET /game/minimap = %d, want 200", rec.Code)
+	}
+	var minimap Minimap
+	if err := json.Unmarshal(rec.Body.Bytes(), &minimap); err != nil {
+		t.Fatalf("invalid response: %v", err)
+	}
+	if len(minimap.Rows) == 0 || minimap.Rows[0][:3] != "|@|" {
+		t.Errorf("rows = %q, want the map row first", minimap.Rows)
+	}
+
+	rec = httptest.NewRecorder()
+	w.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/game/minimap", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("POST /game/minimap = %d, want 405", rec.Code)
+	}
+}
+
+func TestNewMinimapper_RejectsInvalidConfig(t *testing.T) {
+	negative, five, two := -1, 5, 2
+	for _, cfg := range []MinimapConfig{
+		{Top: &negative},
+		{Top: &five, Bottom: &two},
+		{ScaleX: -1},
+		{ScaleY: 17},
+	} {
+		if _, err := newMinimapper(cfg); err == nil {
+			t.Errorf("newMinimapper(%+v) succeeded, want an error", cfg)
+		}
+	}
+}
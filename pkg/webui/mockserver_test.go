@@ -0,0 +1,82 @@
+package webui
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/opd-ai/go-gamelaunch-client/pkg/dgclient"
+	"github.com/opd-ai/go-gamelaunch-www/pkg/testutil"
+	"golang.org/x/crypto/ssh"
+)
+
+// waitForScreen waits until a line of the view's screen contains want
+func waitForScreen(t *testing.T, ctx context.Context, view *WebView, want string) {
+	t.Helper()
+	var version uint64
+	for {
+		state := view.GetStateManager().GetCurrentState()
+		if state != nil && strings.Contains(strings.Join(stateLines(state), "\n"), want) {
+			return
+		}
+		var err error
+		if version, err = view.WaitForUpdateContext(ctx, version); err != nil {
+			t.Fatalf("screen never showed %q: %v", want, err)
+		}
+	}
+}
+
+// TestWebUI_MockGameServer_EndToEnd drives a session from the SSH server
+// through the view to HTTP clients and back
+func TestWebUI_MockGameServer_EndToEnd(t *testing.T) {
+	server := testutil.StartGameServer(t, testutil.Script{
+		{Output: "\x1b[2J\x1b[H## dgamelaunch\r\n\r\n p) Play NetHack\r\n", Expect: "p"},
+		{Output: "\x1b[2J\x1b[HHello tester, welcome to NetHack!", Expect: "y"},
+		{Output: "\x1b[2J\x1b[HBe careful! New moon tonight."},
+	})
+
+	view := newTestView(t)
+	ui, err := NewWebUI(WebUIOptions{View: view})
+	if err != nil {
+		t.Fatalf("Failed to create WebUI: %v", err)
+	}
+
+	config := dgclient.DefaultClientConfig()
+	config.SSHConfig = &ssh.ClientConfig{User: "tester", HostKeyCallback: ssh.InsecureIgnoreHostKey()}
+	client := dgclient.NewClient(config)
+	defer client.Close()
+	if err := client.SetView(view); err != nil {
+		t.Fatalf("SetView failed: %v", err)
+	}
+	if err := client.Connect(server.Host(), server.Port(), dgclient.NewPasswordAuth("secret")); err != nil {
+		t.Fatalf("Connect failed: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	go client.Run(ctx)
+
+	waitForScreen(t, ctx, view, "p) Play NetHack")
+	if pty := server.PTY(); pty.Width != 80 || pty.Height != 24 {
+		t.Errorf("PTY = %+v, want 80x24", pty)
+	}
+
+	input := func(body string) {
+		rr := httptest.NewRecorder()
+		ui.ServeHTTP(rr, httptest.NewRequest("POST", "/input", strings.NewReader(body)))
+		if rr.Code != http.StatusOK {
+			t.Fatalf("input status = %d: %s", rr.Code, rr.Body.String())
+		}
+	}
+	input(`{"client":"c1","seq":1,"events":[{"type":"key","key":"p"}]}`)
+	waitForScreen(t, ctx, view, "welcome to NetHack!")
+	input(`{"client":"c1","seq":2,"events":[{"type":"text","data":"y"}]}`)
+	waitForScreen(t, ctx, view, "New moon tonight.")
+
+	if err := server.WaitForInput(ctx, "py"); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,74 @@
+// Package webui provides a broadcaster waking every listener on screen
+// updates.
+package webui
+
+import (
+	"context"
+	"sync"
+)
+
+// updateNotifier wakes each subscriber after screen updates. Every
+// subscriber has its own channel holding at most one pending wakeup, so slow
+// listeners see updates coalesced instead of stealing them from others.
+type updateNotifier struct {
+	mu     sync.Mutex
+	subs   map[chan struct{}]struct{}
+	closed bool
+}
+
+// newUpdateNotifier creates a notifier without subscribers
+func newUpdateNotifier() *updateNotifier {
+	return &updateNotifier{subs: make(map[chan struct{}]struct{})}
+}
+
+// subscribe returns a channel receiving a value after each update until
+// unsubscribe is called or ctx is done. The channel is closed when the
+// notifier is, and returned closed after that.
+func (n *updateNotifier) subscribe(ctx context.Context) (<-chan struct{}, func()) {
+	ch := make(chan struct{}, 1)
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.closed {
+		close(ch)
+		return ch, func() {}
+	}
+	n.subs[ch] = struct{}{}
+
+	unsubscribe := func() {
+		n.mu.Lock()
+		defer n.mu.Unlock()
+		delete(n.subs, ch)
+	}
+	stop := context.AfterFunc(ctx, unsubscribe)
+	return ch, func() {
+		stop()
+		unsubscribe()
+	}
+}
+
+// notify wakes every subscriber without blocking
+func (n *updateNotifier) notify() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	for ch := range n.subs {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// close closes the channels of all subscribers, current and future
+func (n *updateNotifier) close() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.closed {
+		return
+	}
+	n.closed = true
+	for ch := range n.subs {
+		close(ch)
+	}
+	clear(n.subs)
+}
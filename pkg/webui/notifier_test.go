@@ -0,0 +1,147 @@
+package webui
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestUpdateNotifier_WakesEverySubscriber(t *testing.T) {
+	n := newUpdateNotifier()
+	first, unsubscribeFirst := n.subscribe(context.Background())
+	second, unsubscribeSecond := n.subscribe(context.Background())
+	defer unsubscribeSecond()
+
+	// Updates coalesce into one pending wakeup per subscriber
+	n.notify()
+	n.notify()
+	for i, ch := range []<-chan struct{}{first, second} {
+		select {
+		case <-ch:
+		default:
+			t.Fatalf("subscriber %d was not woken", i)
+		}
+		select {
+		case <-ch:
+			t.Errorf("subscriber %d woken twice, want updates coalesced", i)
+		default:
+		}
+	}
+
+	// Unsubscribed listeners are no longer woken
+	unsubscribeFirst()
+	unsubscribeFirst()
+	n.notify()
+	select {
+	case <-first:
+		t.Error("unsubscribed listener was woken")
+	default:
+	}
+	if _, ok := <-second; !ok {
+		t.Error("remaining subscriber's channel closed")
+	}
+}
+
+func TestUpdateNotifier_ContextAndClose(t *testing.T) {
+	n := newUpdateNotifier()
+	ctx, cancel := context.WithCancel(context.Background())
+	_, unsubscribe := n.subscribe(ctx)
+	defer unsubscribe()
+	cancel()
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		n.mu.Lock()
+		remaining := len(n.subs)
+		n.mu.Unlock()
+		if remaining == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("subscription outlived its context")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	open, _ := n.subscribe(context.Background())
+	n.close()
+	n.close()
+	if _, ok := <-open; ok {
+		t.Error("subscriber's channel open after close")
+	}
+	late, _ := n.subscribe(context.Background())
+	if _, ok := <-late; ok {
+		t.Error("subscription after close returned an open channel")
+	}
+}
+
+func TestWebView_WaitForUpdate(t *testing.T) {
+	view := newTestView(t)
+
+	// Every waiter sees the same update
+	results := make(chan bool, 3)
+	for range 3 {
+		go func() { results <- view.WaitForUpdate(5 * time.Second) }()
+	}
+	deadline := time.Now().Add(time.Second)
+	for {
+		view.updates.mu.Lock()
+		waiting := len(view.updates.subs)
+		view.updates.mu.Unlock()
+		if waiting == 3 || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if err := view.Render([]byte("x")); err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	for range 3 {
+		if !<-results {
+			t.Error("WaitForUpdate = false, want every waiter woken by the update")
+		}
+	}
+
+	if view.WaitForUpdate(10 * time.Millisecond) {
+		t.Error("WaitForUpdate without an update = true, want false after the timeout")
+	}
+	view.Close()
+	if view.WaitForUpdate(time.Second) {
+		t.Error("WaitForUpdate on a closed view = true, want false")
+	}
+}
+
+func TestWebView_WaitForUpdateContext(t *testing.T) {
+	view := newTestView(t)
+	if err := view.Render([]byte("a")); err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	current := view.GetStateManager().GetCurrentVersion()
+
+	// A newer version returns at once
+	if version, err := view.WaitForUpdateContext(context.Background(), current-1); err != nil || version != current {
+		t.Errorf("WaitForUpdateContext(%d) = %d, %v; want %d, nil", current-1, version, err, current)
+	}
+
+	// Otherwise it waits for the next version, including resizes
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		view.SetSize(40, 10)
+	}()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if version, err := view.WaitForUpdateContext(ctx, current); err != nil || version != current+1 {
+		t.Errorf("WaitForUpdateContext(%d) = %d, %v; want %d, nil", current, version, err, current+1)
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := view.WaitForUpdateContext(ctx, current+1); err != context.DeadlineExceeded {
+		t.Errorf("WaitForUpdateContext without an update = %v, want the context's error", err)
+	}
+
+	view.Close()
+	if _, err := view.WaitForUpdateContext(context.Background(), current+1); err != ErrViewClosed {
+		t.Errorf("WaitForUpdateContext on a closed view = %v, want ErrViewClosed", err)
+	}
+}
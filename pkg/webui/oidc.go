@@ -0,0 +1,341 @@
+// Package webui provides OAuth2/OpenID Connect login for the web interface.
+package webui
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/opd-ai/go-gamelaunch-www/pkg/transport"
+)
+
+// oidcStateCookieName holds the anti-CSRF state during the login redirect
+const oidcStateCookieName = "dgconnect_oidc_state"
+
+// OIDCConfig configures delegated login through an OpenID Connect provider.
+// Providers without discovery support (e.g. GitHub OAuth apps) can be used
+// by setting AuthURL, TokenURL and UserInfoURL explicitly.
+type OIDCConfig struct {
+	Issuer       string   `yaml:"issuer,omitempty"`
+	ClientID     string   `yaml:"client_id"`
+	ClientSecret string   `yaml:"client_secret"`
+	RedirectURL  string   `yaml:"redirect_url"`
+	Scopes       []string `yaml:"scopes,omitempty"`
+
+	// Explicit endpoints, overriding discovery from Issuer
+	AuthURL     string `yaml:"auth_url,omitempty"`
+	TokenURL    string `yaml:"token_url,omitempty"`
+	UserInfoURL string `yaml:"userinfo_url,omitempty"`
+
+	// Authorization mapping
+	GroupsClaim string            `yaml:"groups_claim,omitempty"` // default "groups"
+	GroupRoles  map[string]string `yaml:"group_roles,omitempty"`  // group -> role
+	DefaultRole string            `yaml:"default_role,omitempty"` // role when no group matches; empty denies
+
+	SessionTTL string `yaml:"session_ttl,omitempty" yamlcheck:"duration"` // e.g. "24h"
+}
+
+// oidcEndpoints are the provider endpoints used by the login flow
+type oidcEndpoints struct {
+	AuthURL     string `json:"authorization_endpoint"`
+	TokenURL    string `json:"token_endpoint"`
+	UserInfoURL string `json:"userinfo_endpoint"`
+}
+
+// OIDCAuthenticator implements the OAuth2 authorization code flow and maps
+// the provider's group claims to session roles
+type OIDCAuthenticator struct {
+	RoleAuthorizer
+	config   OIDCConfig
+	client   *http.Client
+	sessions *authSessionStore
+
+	endpointsMu sync.Mutex
+	endpoints   *oidcEndpoints
+}
+
+// NewOIDCAuthenticator validates cfg and creates an authenticator
+func NewOIDCAuthenticator(cfg OIDCConfig) (*OIDCAuthenticator, error) {
+	if cfg.ClientID == "" {
+		return nil, fmt.Errorf("oidc: client_id is required")
+	}
+	if cfg.RedirectURL == "" {
+		return nil, fmt.Errorf("oidc: redirect_url is required")
+	}
+	if cfg.Issuer == "" && (cfg.AuthURL == "" || cfg.TokenURL == "" || cfg.UserInfoURL == "") {
+		return nil, fmt.Errorf("oidc: issuer or auth_url, token_url and userinfo_url are required")
+	}
+	if err := validateRoleMapping(cfg.GroupRoles, cfg.DefaultRole); err != nil {
+		return nil, fmt.Errorf("oidc: %w", err)
+	}
+	if cfg.GroupsClaim == "" {
+		cfg.GroupsClaim = "groups"
+	}
+	if len(cfg.Scopes) == 0 {
+		cfg.Scopes = []string{"openid", "profile", "email"}
+	}
+
+	ttl := 24 * time.Hour
+	if cfg.SessionTTL != "" {
+		parsed, err := time.ParseDuration(cfg.SessionTTL)
+		if err != nil || parsed <= 0 {
+			return nil, fmt.Errorf("oidc: invalid session_ttl %q", cfg.SessionTTL)
+		}
+		ttl = parsed
+	}
+
+	a := &OIDCAuthenticator{
+		config:   cfg,
+		client:   &http.Client{Timeout: 10 * time.Second},
+		sessions: newAuthSessionStore(ttl),
+	}
+	if cfg.AuthURL != "" && cfg.TokenURL != "" && cfg.UserInfoURL != "" {
+		a.endpoints = &oidcEndpoints{AuthURL: cfg.AuthURL, TokenURL: cfg.TokenURL, UserInfoURL: cfg.UserInfoURL}
+	}
+	return a, nil
+}
+
+// Authenticate returns the identity of the request's session, or nil
+func (a *OIDCAuthenticator) Authenticate(r *http.Request) *Identity {
+	return a.sessions.fromRequest(r)
+}
+
+// Routes implements AuthProvider
+func (a *OIDCAuthenticator) Routes() map[string]http.HandlerFunc {
+	return map[string]http.HandlerFunc{
+		"/auth/login":    a.HandleLogin,
+		"/auth/callback": a.HandleCallback,
+		"/auth/logout":   a.HandleLogout,
+	}
+}
+
+// HandleLogin redirects the browser to the provider's authorization endpoint
+func (a *OIDCAuthenticator) HandleLogin(rw http.ResponseWriter, r *http.Request) {
+	endpoints, err := a.getEndpoints()
+	if err != nil {
+		slog.Error("webui.oidc: discovery failed", "error", err)
+		http.Error(rw, "Login provider unavailable", http.StatusBadGateway)
+		return
+	}
+
+	state, err := randomToken(16)
+	if err != nil {
+		http.Error(rw, "Failed to start login", http.StatusInternalServerError)
+		return
+	}
+	http.SetCookie(rw, &http.Cookie{
+		Name:     oidcStateCookieName,
+		Value:    state,
+		Path:     cookiePath(r) + "auth/",
+		MaxAge:   600,
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	query := url.Values{
+		"response_type": {"code"},
+		"client_id":     {a.config.ClientID},
+		"redirect_uri":  {a.config.RedirectURL},
+		"scope":         {strings.Join(a.config.Scopes, " ")},
+		"state":         {state},
+	}
+	http.Redirect(rw, r, endpoints.AuthURL+"?"+query.Encode(), http.StatusFound)
+}
+
+// HandleCallback completes the login, creating a session for the user
+func (a *OIDCAuthenticator) HandleCallback(rw http.ResponseWriter, r *http.Request) {
+	stateCookie, err := r.Cookie(oidcStateCookieName)
+	if err != nil || stateCookie.Value == "" || stateCookie.Value != r.URL.Query().Get("state") {
+		http.Error(rw, "Invalid login state", http.StatusBadRequest)
+		return
+	}
+	clearCookie(rw, oidcStateCookieName, cookiePath(r)+"auth/")
+
+	if errCode := r.URL.Query().Get("error"); errCode != "" {
+		http.Error(rw, "Login failed: "+errCode, http.StatusUnauthorized)
+		return
+	}
+
+	identity, err := a.exchange(r.URL.Query().Get("code"))
+	if err != nil {
+		slog.Error("webui.oidc: login failed", "error", err, "correlation_id", transport.CorrelationID(r.Context()))
+		http.Error(rw, "Login failed", http.StatusUnauthorized)
+		return
+	}
+	if identity.Role == "" {
+		slog.Warn("webui.oidc: no role for user", "subject", identity.Subject, "groups", identity.Groups, "correlation_id", transport.CorrelationID(r.Context()))
+		http.Error(rw, "Access denied", http.StatusForbidden)
+		return
+	}
+
+	token, err := a.sessions.create(identity)
+	if err != nil {
+		http.Error(rw, "Failed to create session", http.StatusInternalServerError)
+		return
+	}
+	a.sessions.setCookie(rw, r, token)
+
+	slog.Info("webui.oidc: user logged in", "subject", identity.Subject, "role", identity.Role, "correlation_id", transport.CorrelationID(r.Context()))
+	http.Redirect(rw, r, cookiePath(r), http.StatusFound)
+}
+
+// HandleLogout ends the current session
+func (a *OIDCAuthenticator) HandleLogout(rw http.ResponseWriter, r *http.Request) {
+	if cookie, err := r.Cookie(sessionCookieName); err == nil {
+		a.sessions.remove(cookie.Value)
+	}
+	clearCookie(rw, sessionCookieName, cookiePath(r))
+	http.Redirect(rw, r, cookiePath(r), http.StatusFound)
+}
+
+// exchange trades an authorization code for the user's identity
+func (a *OIDCAuthenticator) exchange(code string) (*Identity, error) {
+	if code == "" {
+		return nil, fmt.Errorf("missing authorization code")
+	}
+
+	endpoints, err := a.getEndpoints()
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{
+		"grant_type":   {"authorization_code"},
+		"code":         {code},
+		"redirect_uri": {a.config.RedirectURL},
+	}
+	req, err := http.NewRequest(http.MethodPost, endpoints.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.SetBasicAuth(url.QueryEscape(a.config.ClientID), url.QueryEscape(a.config.ClientSecret))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	var token struct {
+		AccessToken string `json:"access_token"`
+	}
+	if err := a.doJSON(req, &token); err != nil {
+		return nil, fmt.Errorf("token exchange failed: %w", err)
+	}
+	if token.AccessToken == "" {
+		return nil, fmt.Errorf("token response has no access_token")
+	}
+
+	req, err = http.NewRequest(http.MethodGet, endpoints.UserInfoURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token.AccessToken)
+	req.Header.Set("Accept", "application/json")
+
+	var claims map[string]interface{}
+	if err := a.doJSON(req, &claims); err != nil {
+		return nil, fmt.Errorf("userinfo request failed: %w", err)
+	}
+
+	return a.identityFromClaims(claims)
+}
+
+// identityFromClaims builds an Identity from userinfo claims
+func (a *OIDCAuthenticator) identityFromClaims(claims map[string]interface{}) (*Identity, error) {
+	identity := &Identity{
+		Subject: claimString(claims, "sub", "id"),
+		Name:    claimString(claims, "preferred_username", "name", "login", "email"),
+		Groups:  claimStrings(claims, a.config.GroupsClaim),
+	}
+	if identity.Subject == "" {
+		return nil, fmt.Errorf("userinfo has no subject")
+	}
+
+	identity.Role = resolveRole(a.config.GroupRoles, a.config.DefaultRole, identity.Groups)
+	return identity, nil
+}
+
+// getEndpoints returns the provider endpoints, running discovery once
+func (a *OIDCAuthenticator) getEndpoints() (*oidcEndpoints, error) {
+	a.endpointsMu.Lock()
+	defer a.endpointsMu.Unlock()
+
+	if a.endpoints != nil {
+		return a.endpoints, nil
+	}
+
+	discoveryURL := strings.TrimSuffix(a.config.Issuer, "/") + "/.well-known/openid-configuration"
+	req, err := http.NewRequest(http.MethodGet, discoveryURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var endpoints oidcEndpoints
+	if err := a.doJSON(req, &endpoints); err != nil {
+		return nil, fmt.Errorf("discovery failed: %w", err)
+	}
+	if a.config.AuthURL != "" {
+		endpoints.AuthURL = a.config.AuthURL
+	}
+	if a.config.TokenURL != "" {
+		endpoints.TokenURL = a.config.TokenURL
+	}
+	if a.config.UserInfoURL != "" {
+		endpoints.UserInfoURL = a.config.UserInfoURL
+	}
+	if endpoints.AuthURL == "" || endpoints.TokenURL == "" || endpoints.UserInfoURL == "" {
+		return nil, fmt.Errorf("provider metadata is missing required endpoints")
+	}
+
+	a.endpoints = &endpoints
+	return a.endpoints, nil
+}
+
+// doJSON performs req and decodes a successful JSON response into v
+func (a *OIDCAuthenticator) doJSON(req *http.Request, v interface{}) error {
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %s from %s", resp.Status, req.URL.Host)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// claimString returns the first non-empty claim among keys as a string
+func claimString(claims map[string]interface{}, keys ...string) string {
+	for _, key := range keys {
+		switch value := claims[key].(type) {
+		case string:
+			if value != "" {
+				return value
+			}
+		case float64:
+			return fmt.Sprintf("%.0f", value)
+		}
+	}
+	return ""
+}
+
+// claimStrings returns a string-list claim, accepting a single string too
+func claimStrings(claims map[string]interface{}, key string) []string {
+	switch value := claims[key].(type) {
+	case string:
+		return []string{value}
+	case []interface{}:
+		result := make([]string, 0, len(value))
+		for _, item := range value {
+			if s, ok := item.(string); ok {
+				result = append(result, s)
+			}
+		}
+		return result
+	}
+	return nil
+}
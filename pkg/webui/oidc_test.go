@@ -0,0 +1,213 @@
+package webui
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/opd-ai/go-gamelaunch-client/pkg/dgclient"
+)
+
+// newFakeOIDCProvider serves discovery, token and userinfo endpoints that
+// authenticate every user as "alice" in the given groups
+func newFakeOIDCProvider(t *testing.T, groups []string) *httptest.Server {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	var server *httptest.Server
+	mux.HandleFunc("/.well-known/openid-configuration", func(rw http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(rw).Encode(map[string]string{
+			"authorization_endpoint": server.URL + "/authorize",
+			"token_endpoint":         server.URL + "/token",
+			"userinfo_endpoint":      server.URL + "/userinfo",
+		})
+	})
+	mux.HandleFunc("/token", func(rw http.ResponseWriter, r *http.Request) {
+		if id, secret, ok := r.BasicAuth(); !ok || id != "client" || secret != "secret" {
+			http.Error(rw, "bad client", http.StatusUnauthorized)
+			return
+		}
+		if r.FormValue("code") != "good-code" {
+			http.Error(rw, "bad code", http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(rw).Encode(map[string]string{"access_token": "token-123"})
+	})
+	mux.HandleFunc("/userinfo", func(rw http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token-123" {
+			http.Error(rw, "bad token", http.StatusUnauthorized)
+			return
+		}
+		json.NewEncoder(rw).Encode(map[string]interface{}{
+			"sub":                "alice-id",
+			"preferred_username": "alice",
+			"groups":             groups,
+		})
+	})
+	server = httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+// newOIDCWebUI creates a WebUI that delegates login to provider
+func newOIDCWebUI(t *testing.T, provider *httptest.Server, defaultRole string) *WebUI {
+	t.Helper()
+
+	view, err := NewWebView(dgclient.ViewOptions{InitialWidth: 80, InitialHeight: 24})
+	if err != nil {
+		t.Fatalf("Failed to create WebView: %v", err)
+	}
+	webUI, err := NewWebUI(WebUIOptions{
+		View: view,
+		OIDC: &OIDCConfig{
+			Issuer:       provider.URL,
+			ClientID:     "client",
+			ClientSecret: "secret",
+			RedirectURL:  "http://game.example/auth/callback",
+			GroupRoles:   map[string]string{"nethack-admins": "admin", "players": "player"},
+			DefaultRole:  defaultRole,
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create WebUI: %v", err)
+	}
+	return webUI
+}
+
+// loginViaOIDC runs the login redirect and callback, returning the callback response
+func loginViaOIDC(t *testing.T, webUI *WebUI, code string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	rec := httptest.NewRecorder()
+	webUI.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/auth/login", nil))
+	if rec.Code != http.StatusFound {
+		t.Fatalf("login status = %d, want %d", rec.Code, http.StatusFound)
+	}
+	location, _ := url.Parse(rec.Header().Get("Location"))
+	state := location.Query().Get("state")
+	if state == "" {
+		t.Fatal("login redirect has no state")
+	}
+
+	callback := httptest.NewRequest(http.MethodGet, "/auth/callback?code="+code+"&state="+state, nil)
+	for _, c := range rec.Result().Cookies() {
+		callback.AddCookie(c)
+	}
+	rec = httptest.NewRecorder()
+	webUI.ServeHTTP(rec, callback)
+	return rec
+}
+
+// sessionCookie returns the session cookie set on rec
+func sessionCookie(rec *httptest.ResponseRecorder) *http.Cookie {
+	for _, c := range rec.Result().Cookies() {
+		if c.Name == sessionCookieName {
+			return c
+		}
+	}
+	return nil
+}
+
+func TestNewOIDCAuthenticator_ValidatesConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  OIDCConfig
+		wantErr bool
+	}{
+		{"Valid", OIDCConfig{Issuer: "https://idp", ClientID: "c", RedirectURL: "https://x/cb"}, false},
+		{"ExplicitEndpoints", OIDCConfig{ClientID: "c", RedirectURL: "https://x/cb", AuthURL: "a", TokenURL: "t", UserInfoURL: "u"}, false},
+		{"MissingClientID", OIDCConfig{Issuer: "https://idp", RedirectURL: "https://x/cb"}, true},
+		{"MissingIssuer", OIDCConfig{ClientID: "c", RedirectURL: "https://x/cb"}, true},
+		{"UnknownRole", OIDCConfig{Issuer: "https://idp", ClientID: "c", RedirectURL: "https://x/cb", GroupRoles: map[string]string{"g": "wizard"}}, true},
+		{"BadTTL", OIDCConfig{Issuer: "https://idp", ClientID: "c", RedirectURL: "https://x/cb", SessionTTL: "soon"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewOIDCAuthenticator(tt.config)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewOIDCAuthenticator() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestWebUI_OIDC_LoginMapsGroupsToRole(t *testing.T) {
+	provider := newFakeOIDCProvider(t, []string{"players", "nethack-admins"})
+	webUI := newOIDCWebUI(t, provider, "")
+
+	rec := loginViaOIDC(t, webUI, "good-code")
+	if rec.Code != http.StatusFound {
+		t.Fatalf("callback status = %d, want %d (%s)", rec.Code, http.StatusFound, rec.Body.String())
+	}
+	cookie := sessionCookie(rec)
+	if cookie == nil {
+		t.Fatal("callback did not set a session cookie")
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/auth/whoami", nil)
+	req.AddCookie(cookie)
+	rec = httptest.NewRecorder()
+	webUI.ServeHTTP(rec, req)
+
+	var identity Identity
+	if err := json.Unmarshal(rec.Body.Bytes(), &identity); err != nil {
+		t.Fatalf("failed to decode identity: %v", err)
+	}
+	if identity.Name != "alice" || identity.Role != RoleAdmin {
+		t.Errorf("identity = %+v, want alice as admin", identity)
+	}
+}
+
+func TestWebUI_OIDC_DeniesUsersWithoutRole(t *testing.T) {
+	provider := newFakeOIDCProvider(t, []string{"strangers"})
+	webUI := newOIDCWebUI(t, provider, "")
+
+	rec := loginViaOIDC(t, webUI, "good-code")
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("callback status = %d, want %d", rec.Code, http.StatusForbidden)
+	}
+	if sessionCookie(rec) != nil {
+		t.Error("denied user should not receive a session cookie")
+	}
+}
+
+func TestWebUI_OIDC_RejectsBadCode(t *testing.T) {
+	provider := newFakeOIDCProvider(t, nil)
+	webUI := newOIDCWebUI(t, provider, "spectator")
+
+	rec := loginViaOIDC(t, webUI, "bad-code")
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("callback status = %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+}
+
+func TestWebUI_OIDC_RequiresLoginForProtectedRoutes(t *testing.T) {
+	provider := newFakeOIDCProvider(t, nil)
+	webUI := newOIDCWebUI(t, provider, "spectator")
+
+	rec := httptest.NewRecorder()
+	webUI.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/session/info", nil))
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("anonymous API status = %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept", "text/html")
+	rec = httptest.NewRecorder()
+	webUI.ServeHTTP(rec, req)
+	if rec.Code != http.StatusFound || rec.Header().Get("Location") != "/auth/login" {
+		t.Errorf("anonymous browser should be redirected to login, got %d %q", rec.Code, rec.Header().Get("Location"))
+	}
+
+	login := loginViaOIDC(t, webUI, "good-code")
+	req = httptest.NewRequest(http.MethodGet, "/session/info", nil)
+	req.AddCookie(sessionCookie(login))
+	rec = httptest.NewRecorder()
+	webUI.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Errorf("authenticated status = %d, want %d", rec.Code, http.StatusOK)
+	}
+}
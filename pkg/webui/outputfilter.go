@@ -0,0 +1,181 @@
+// Package webui provides output filter hooks that transform terminal output
+// before the view parses it.
+package webui
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"unicode"
+	"unicode/utf8"
+)
+
+// OutputFilter is a plugin that sees the game's terminal output before the
+// view parses it, and before it is recorded. It returns the output to
+// render, or nothing to drop it. Output arrives in chunks as read from the
+// SSH session, so an escape sequence may be split across calls. Filters run
+// with the view locked and must not call back into the view.
+type OutputFilter interface {
+	FilterOutput(data []byte) []byte
+}
+
+// OutputFilterFunc adapts a function to OutputFilter
+type OutputFilterFunc func(data []byte) []byte
+
+// FilterOutput implements OutputFilter
+func (f OutputFilterFunc) FilterOutput(data []byte) []byte {
+	return f(data)
+}
+
+// OutputFilterConfig configures the built-in output filters, applied in the
+// order strip, watermark
+type OutputFilterConfig struct {
+	// Strip lists regular expressions whose matches are removed from the
+	// output, e.g. sequences known to upset the parser. A match split
+	// across two chunks is not removed.
+	Strip []string `yaml:"strip,omitempty"`
+
+	// Watermark is text drawn over the screen after every chunk of output,
+	// e.g. for public streams
+	Watermark *WatermarkConfig `yaml:"watermark,omitempty"`
+}
+
+// WatermarkConfig places the watermark text. Row and Column are 1-based;
+// Row 0 is the bottom row and Column 0 the first column. Text must end
+// before the last column, as writing there wraps the line.
+type WatermarkConfig struct {
+	Text   string `yaml:"text"`
+	Row    int    `yaml:"row,omitempty"`
+	Column int    `yaml:"column,omitempty"`
+}
+
+// AddOutputFilter registers a filter that terminal output passes through
+// before it is parsed, after the filters already registered
+func (v *WebView) AddOutputFilter(filter OutputFilter) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.outputFilters = append(v.outputFilters, filter)
+}
+
+// AddOutputFilter registers a filter on the view's terminal output, see
+// WebView.AddOutputFilter. It stays registered when ReplaceView installs a
+// new view.
+func (w *WebUI) AddOutputFilter(filter OutputFilter) {
+	w.viewMu.Lock()
+	defer w.viewMu.Unlock()
+	w.outputFilters = append(w.outputFilters, filter)
+	w.GetView().AddOutputFilter(filter)
+}
+
+// NewOutputFilters validates cfg and returns its built-in filters in the
+// order they apply
+func NewOutputFilters(cfg OutputFilterConfig) ([]OutputFilter, error) {
+	var filters []OutputFilter
+	for _, expr := range cfg.Strip {
+		if expr == "" {
+			return nil, fmt.Errorf("output filter: empty strip pattern")
+		}
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("output filter: invalid strip pattern: %w", err)
+		}
+		filters = append(filters, stripFilter{re: re})
+	}
+	if cfg.Watermark != nil {
+		watermark, err := newWatermarkFilter(*cfg.Watermark)
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, watermark)
+	}
+	return filters, nil
+}
+
+// stripFilter removes the matches of a pattern
+type stripFilter struct {
+	re *regexp.Regexp
+}
+
+// FilterOutput implements OutputFilter
+func (f stripFilter) FilterOutput(data []byte) []byte {
+	return f.re.ReplaceAll(data, nil)
+}
+
+// watermarkFilter draws text over the screen after each chunk of output
+// that ends between escape sequences and characters, saving and restoring
+// the cursor around it
+type watermarkFilter struct {
+	overlay []byte
+	stream  outputBoundary
+}
+
+// newWatermarkFilter validates cfg and creates its filter
+func newWatermarkFilter(cfg WatermarkConfig) (*watermarkFilter, error) {
+	if cfg.Text == "" {
+		return nil, fmt.Errorf("output filter: watermark text is required")
+	}
+	for _, r := range cfg.Text {
+		if unicode.IsControl(r) || r == utf8.RuneError {
+			return nil, fmt.Errorf("output filter: watermark text %q contains a control character", cfg.Text)
+		}
+	}
+	if cfg.Row < 0 || cfg.Column < 0 {
+		return nil, fmt.Errorf("output filter: invalid watermark position %d,%d", cfg.Row, cfg.Column)
+	}
+
+	row, column := cfg.Row, max(cfg.Column, 1)
+	if row == 0 {
+		row = 9999 // clamped to the bottom row
+	}
+	overlay := "\x1b7\x1b[" + strconv.Itoa(row) + ";" + strconv.Itoa(column) + "H\x1b[0m" + cfg.Text + "\x1b8"
+	return &watermarkFilter{overlay: []byte(overlay)}, nil
+}
+
+// FilterOutput implements OutputFilter
+func (f *watermarkFilter) FilterOutput(data []byte) []byte {
+	f.stream.scan(data)
+	if !f.stream.atBoundary() {
+		return data
+	}
+	return append(data[:len(data):len(data)], f.overlay...)
+}
+
+// outputBoundary follows terminal output far enough to tell whether it
+// stopped inside an escape sequence or a multibyte character, where nothing
+// may be inserted
+type outputBoundary struct {
+	escape       bool // after ESC
+	csi          bool // after ESC [
+	continuation int  // bytes left of a multibyte character
+}
+
+// scan advances past data
+func (s *outputBoundary) scan(data []byte) {
+	for _, b := range data {
+		switch {
+		case s.csi:
+			s.csi = !(b >= 'A' && b <= 'Z' || b >= 'a' && b <= 'z')
+		case s.escape:
+			s.escape = false
+			s.csi = b == '['
+		case b == '\x1b':
+			s.escape, s.continuation = true, 0
+		case s.continuation > 0 && b&0xC0 == 0x80:
+			s.continuation--
+		case b&0xE0 == 0xC0:
+			s.continuation = 1
+		case b&0xF0 == 0xE0:
+			s.continuation = 2
+		case b&0xF8 == 0xF0:
+			s.continuation = 3
+		default:
+			s.continuation = 0
+		}
+	}
+}
+
+// atBoundary reports whether the output scanned so far ends between escape
+// sequences and characters
+func (s *outputBoundary) atBoundary() bool {
+	return !s.escape && !s.csi && s.continuation == 0
+}
@@ -0,0 +1,104 @@
+package webui
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestNewOutputFilters_Validation(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     OutputFilterConfig
+		want    int
+		wantErr bool
+	}{
+		{"Empty", OutputFilterConfig{}, 0, false},
+		{"All", OutputFilterConfig{Strip: []string{`\x1b\]`, `\x07`}, Watermark: &WatermarkConfig{Text: "LIVE"}}, 3, false},
+		{"EmptyPattern", OutputFilterConfig{Strip: []string{""}}, 0, true},
+		{"InvalidPattern", OutputFilterConfig{Strip: []string{`(`}}, 0, true},
+		{"EmptyWatermark", OutputFilterConfig{Watermark: &WatermarkConfig{}}, 0, true},
+		{"ControlInWatermark", OutputFilterConfig{Watermark: &WatermarkConfig{Text: "\x1b[2J"}}, 0, true},
+		{"NegativeRow", OutputFilterConfig{Watermark: &WatermarkConfig{Text: "LIVE", Row: -1}}, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filters, err := NewOutputFilters(tt.cfg)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewOutputFilters() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(filters) != tt.want {
+				t.Errorf("NewOutputFilters() returned %d filters, want %d", len(filters), tt.want)
+			}
+		})
+	}
+}
+
+func TestWebUI_OutputFilter_StripsAndWatermarks(t *testing.T) {
+	ui, err := NewWebUI(WebUIOptions{
+		View: newTestView(t),
+		OutputFilter: &OutputFilterConfig{
+			Strip:     []string{`\x1b\[\d+;\d+;\d+t`},
+			Watermark: &WatermarkConfig{Text: "LIVE", Column: 70},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create WebUI: %v", err)
+	}
+	view := ui.GetView()
+
+	if err := view.Render([]byte("\x1b[1;31mHello\x1b[8;24;80t")); err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	state := view.GetCurrentState()
+	lines := stateLines(state)
+	if !strings.HasPrefix(lines[0], "Hello") || !strings.HasSuffix(lines[23], "LIVE") {
+		t.Errorf("screen = %q ... %q, want Hello and the watermark on the bottom row", lines[0], lines[23])
+	}
+	if state.CursorX != 5 || state.CursorY != 0 {
+		t.Errorf("cursor = %d,%d, want 5,0 as before the watermark", state.CursorX, state.CursorY)
+	}
+
+	// Text after the watermark keeps the game's attributes, and output
+	// that stops inside an escape sequence is not interrupted
+	if err := view.Render([]byte("!\x1b[")); err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	if err := view.Render([]byte("2;1HX")); err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	state = view.GetCurrentState()
+	if cell := state.Buffer[0][5]; cell.Char != '!' || !cell.Bold {
+		t.Errorf("cell after watermark = %+v, want a bold !", cell)
+	}
+	if state.Buffer[1][0].Char != 'X' {
+		t.Errorf("split escape sequence was interrupted: %q", stateLines(state)[1])
+	}
+}
+
+func TestWebUI_AddOutputFilter(t *testing.T) {
+	ui := newTestWebUI(t)
+	var recorded []byte
+	ui.GetView().TapOutput(func(data []byte) { recorded = append(recorded, data...) })
+	// A plugin that drops bells and upper-cases the rest
+	ui.AddOutputFilter(OutputFilterFunc(func(data []byte) []byte {
+		return bytes.ToUpper(bytes.ReplaceAll(data, []byte("\a"), nil))
+	}))
+
+	version := ui.GetView().GetStateManager().GetCurrentVersion()
+	if err := ui.GetView().Render([]byte("\a")); err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	if got := ui.GetView().GetStateManager().GetCurrentVersion(); got != version {
+		t.Errorf("version = %d after dropped output, want %d", got, version)
+	}
+	if err := ui.GetView().Render([]byte("abc\a")); err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	if line := stateLines(ui.GetView().GetCurrentState())[0]; !strings.HasPrefix(line, "ABC") {
+		t.Errorf("screen = %q, want ABC", line)
+	}
+	if string(recorded) != "ABC" {
+		t.Errorf("tapped output = %q, want the filtered ABC", recorded)
+	}
+}
@@ -0,0 +1,363 @@
+package webui
+
+// Benchmarks for the render -> diff -> encode pipeline.
+//
+// Captures are replayed frame by frame through WebView.Render (which drives
+// StateManager diffing), through a WebUI streaming to a WebSocket client and
+// through diff encoding, using only the public API. The NetHack game bundled
+// with pkg/demo and a deterministic synthetic DCSS-like capture are always
+// available; more recordings can be added by placing .ttyrec files in
+// testdata/ttyrec or listing them, comma-separated, in
+// DGCONNECT_BENCH_TTYREC:
+//
+//	DGCONNECT_BENCH_TTYREC=~/crawl.ttyrec go test -run '^$' -bench Pipeline \
+//	    -cpuprofile cpu.out -memprofile mem.out ./pkg/webui
+//
+// Besides ns/op and allocations, each benchmark reports the number of changed
+// cells or encoded bytes per frame.
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/opd-ai/go-gamelaunch-client/pkg/dgclient"
+	"github.com/opd-ai/go-gamelaunch-www/pkg/demo"
+	"github.com/opd-ai/go-gamelaunch-www/pkg/transport"
+	"github.com/opd-ai/go-gamelaunch-www/pkg/ttyrec"
+	"nhooyr.io/websocket"
+	"nhooyr.io/websocket/wsjson"
+)
+
+// benchCapture is a named sequence of terminal output frames
+type benchCapture struct {
+	name   string
+	frames [][]byte
+}
+
+// loadBenchmarkCaptures returns the bundled NetHack game and the synthetic
+// DCSS capture plus any recordings found in testdata/ttyrec or
+// DGCONNECT_BENCH_TTYREC
+func loadBenchmarkCaptures(b *testing.B) []benchCapture {
+	b.Helper()
+
+	bundled, err := demo.Frames()
+	if err != nil {
+		b.Fatalf("Failed to read the bundled capture: %v", err)
+	}
+	nethack := benchCapture{name: "NetHack"}
+	for _, frame := range bundled {
+		nethack.frames = append(nethack.frames, frame.Data)
+	}
+	captures := []benchCapture{
+		nethack,
+		{name: "SyntheticDCSS", frames: syntheticDCSSFrames(500)},
+	}
+
+	paths, _ := filepath.Glob(filepath.Join("testdata", "ttyrec", "*.ttyrec"))
+	if env := os.Getenv("DGCONNECT_BENCH_TTYREC"); env != "" {
+		paths = append(paths, strings.Split(env, ",")...)
+	}
+	for _, path := range paths {
+		f, err := os.Open(path)
+		if err != nil {
+			b.Fatalf("Failed to open capture: %v", err)
+		}
+		frames, err := ttyrec.ReadAll(f)
+		f.Close()
+		if err != nil {
+			b.Fatalf("Failed to read capture %s: %v", path, err)
+		}
+
+		capture := benchCapture{name: strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))}
+		for _, frame := range frames {
+			capture.frames = append(capture.frames, frame.Data)
+		}
+		if len(capture.frames) > 0 {
+			captures = append(captures, capture)
+		}
+	}
+
+	return captures
+}
+
+func BenchmarkPipeline(b *testing.B) {
+	for _, capture := range loadBenchmarkCaptures(b) {
+		b.Run(capture.name+"/Render", func(b *testing.B) { benchmarkPipelineRender(b, capture.frames) })
+		b.Run(capture.name+"/Stream", func(b *testing.B) { benchmarkPipelineStream(b, capture.frames) })
+		b.Run(capture.name+"/EncodeDiff", func(b *testing.B) { benchmarkPipelineEncodeDiff(b, capture.frames) })
+	}
+}
+
+// newBenchmarkView creates an 80x24 view for replaying captures
+func newBenchmarkView(b *testing.B) *WebView {
+	b.Helper()
+
+	view, err := NewWebView(dgclient.ViewOptions{InitialWidth: 80, InitialHeight: 24})
+	if err != nil {
+		b.Fatalf("NewWebView failed: %v", err)
+	}
+	return view
+}
+
+// benchmarkPipelineRender measures parsing, state capture and diffing
+func benchmarkPipelineRender(b *testing.B, frames [][]byte) {
+	// Diff sizes come from a replay outside the timed loop
+	_, diffs := replayStates(b, frames)
+	cells := 0
+	for _, diff := range diffs {
+		cells += len(diff.Changes)
+	}
+	view := newBenchmarkView(b)
+
+	var input int
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		frame := frames[i%len(frames)]
+		if err := view.Render(frame); err != nil {
+			b.Fatalf("Render failed: %v", err)
+		}
+		input += len(frame)
+	}
+
+	b.ReportMetric(float64(cells)/float64(max(len(diffs), 1)), "cells/frame")
+	b.ReportMetric(float64(input)/float64(b.N), "input-B/frame")
+}
+
+// replayStates renders frames and returns the resulting states and diffs
+func replayStates(b *testing.B, frames [][]byte) ([]*GameState, []*StateDiff) {
+	b.Helper()
+
+	view := newBenchmarkView(b)
+	sm := view.GetStateManager()
+	states := make([]*GameState, 0, len(frames))
+	diffs := make([]*StateDiff, 0, len(frames))
+	for _, frame := range frames {
+		version := sm.GetCurrentVersion()
+		view.Render(frame)
+		states = append(states, sm.GetCurrentState())
+		if d, ok := sm.Store().DiffsSince(version); ok && len(d) == 1 {
+			diffs = append(diffs, d[0])
+		}
+	}
+	return states, diffs
+}
+
+// benchmarkPipelineStream measures the whole pipeline as a WebSocket client
+// sees it: each frame is rendered and its state pushed to the client
+func benchmarkPipelineStream(b *testing.B, frames [][]byte) {
+	view := newBenchmarkView(b)
+	ui, err := NewWebUI(WebUIOptions{View: view})
+	if err != nil {
+		b.Fatalf("NewWebUI failed: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go ui.Run(ctx)
+	server := httptest.NewServer(ui)
+	defer server.Close()
+
+	conn, _, err := websocket.Dial(ctx, "ws"+strings.TrimPrefix(server.URL, "http")+"/ws", nil)
+	if err != nil {
+		b.Fatalf("Dial failed: %v", err)
+	}
+	defer conn.Close(websocket.StatusNormalClosure, "")
+	conn.SetReadLimit(1 << 20)
+
+	// receive reads messages until the state of version arrives
+	receive := func(version uint64) int {
+		for {
+			var msg transport.Message
+			if err := wsjson.Read(ctx, conn, &msg); err != nil {
+				b.Fatalf("Failed to read message: %v", err)
+			}
+			if msg.Type != transport.MsgTypeState {
+				continue
+			}
+			var state struct {
+				Version uint64 `json:"version"`
+			}
+			if err := json.Unmarshal(msg.Payload, &state); err != nil {
+				b.Fatalf("Failed to decode state: %v", err)
+			}
+			if state.Version >= version {
+				return len(msg.Payload)
+			}
+		}
+	}
+	view.Render([]byte("\x1b[H\x1b[2J"))
+	receive(view.GetStateManager().GetCurrentVersion())
+
+	var encoded int
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := view.Render(frames[i%len(frames)]); err != nil {
+			b.Fatalf("Render failed: %v", err)
+		}
+		encoded += receive(view.GetStateManager().GetCurrentVersion())
+	}
+
+	b.ReportMetric(float64(encoded)/float64(b.N), "B/frame")
+}
+
+// benchmarkPipelineEncodeDiff measures diff encoding
+func benchmarkPipelineEncodeDiff(b *testing.B, frames [][]byte) {
+	_, diffs := replayStates(b, frames)
+	if len(diffs) == 0 {
+		b.Skip("capture produced no diffs")
+	}
+
+	var encoded int
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		data, err := json.Marshal(diffs[i%len(diffs)])
+		if err != nil {
+			b.Fatalf("Marshal failed: %v", err)
+		}
+		encoded += len(data)
+	}
+
+	b.ReportMetric(float64(encoded)/float64(b.N), "B/frame")
+}
+
+// syntheticNetHackFrames produces a NetHack-like session: a dungeon level is
+// drawn, then each turn moves the player and a pet, updates the message line
+// and the bottom status lines, with a full redraw on every new level
+func syntheticNetHackFrames(n int) [][]byte {
+	rng := rand.New(rand.NewSource(1))
+	messages := []string{"You see here a dagger.", "The jackal bites!", "You hit the newt.",
+		"You kill the grid bug!", "There is a staircase down here.", ""}
+
+	var frames [][]byte
+	px, py, dx, dy := 10, 5, 11, 5
+	for turn := 0; len(frames) < n; turn++ {
+		var buf bytes.Buffer
+		if turn%100 == 0 {
+			buf.WriteString("\x1b[H\x1b[2J")
+			for room := 0; room < 4; room++ {
+				x0, y0 := 2+room*19, 3+rng.Intn(8)
+				w, h := 10+rng.Intn(6), 4+rng.Intn(5)
+				for y := y0; y <= y0+h; y++ {
+					fmt.Fprintf(&buf, "\x1b[%d;%dH", y, x0)
+					for x := 0; x <= w; x++ {
+						switch {
+						case y == y0 || y == y0+h:
+							buf.WriteByte('-')
+						case x == 0 || x == w:
+							buf.WriteByte('|')
+						default:
+							buf.WriteByte('.')
+						}
+					}
+				}
+			}
+		}
+
+		// Move the player and pet
+		fmt.Fprintf(&buf, "\x1b[%d;%dH.", py, px)
+		fmt.Fprintf(&buf, "\x1b[%d;%dH.", dy, dx)
+		dx, dy = px, py
+		px = 4 + (px-4+1+rng.Intn(2))%70
+		py = 4 + (py-4+rng.Intn(3)+1)%14
+		fmt.Fprintf(&buf, "\x1b[%d;%dH\x1b[0;37;1m@\x1b[0m", py, px)
+		fmt.Fprintf(&buf, "\x1b[%d;%dH\x1b[0;37md\x1b[0m", dy, dx)
+
+		// Message line and status
+		fmt.Fprintf(&buf, "\x1b[1;1H%s\x1b[K", messages[rng.Intn(len(messages))])
+		fmt.Fprintf(&buf, "\x1b[23;1HAgent the Stripling   St:18/02 Dx:14 Co:17 In:8 Wi:10 Ch:7  Lawful\x1b[K")
+		fmt.Fprintf(&buf, "\x1b[24;1HDlvl:%d  $:%d  HP:%d(16) Pw:2(2) AC:6  Xp:1/%d T:%d\x1b[K",
+			1+turn/100, turn*3, 16-turn%7, turn/4, turn+1)
+		fmt.Fprintf(&buf, "\x1b[%d;%dH", py, px)
+
+		frames = append(frames, buf.Bytes())
+	}
+	return frames
+}
+
+// syntheticDCSSFrames produces a DCSS-like session: a colourful map view,
+// a sidebar with health bars and a scrolling message area redrawn each turn
+func syntheticDCSSFrames(n int) [][]byte {
+	rng := rand.New(rand.NewSource(2))
+	glyphs := []byte(".#.#..~%)[!?=\"$")
+	lines := []string{"You hit the goblin.", "The goblin misses you.", "You see here 12 gold pieces.",
+		"Found a stone staircase leading down.", "You feel a strange sense of loss.", "The kobold dies!"}
+
+	var frames [][]byte
+	var log []string
+	for turn := 0; len(frames) < n; turn++ {
+		var buf bytes.Buffer
+
+		// Map: redraw a band of the 33x17 view around the player
+		for y := 1; y <= 17; y++ {
+			if turn%20 != 0 && (y+turn)%3 != 0 {
+				continue
+			}
+			fmt.Fprintf(&buf, "\x1b[%d;1H", y)
+			for x := 0; x < 33; x++ {
+				fmt.Fprintf(&buf, "\x1b[%d;3%dm%c", rng.Intn(2), 1+rng.Intn(7), glyphs[rng.Intn(len(glyphs))])
+			}
+		}
+		buf.WriteString("\x1b[9;17H\x1b[1;37m@\x1b[0m")
+
+		// Sidebar
+		hp := 20 - turn%15
+		fmt.Fprintf(&buf, "\x1b[1;37HAgent the Gladiator\x1b[K")
+		fmt.Fprintf(&buf, "\x1b[3;37HHealth: %d/20 \x1b[32m%s\x1b[31m%s\x1b[0m\x1b[K", hp,
+			strings.Repeat("=", hp), strings.Repeat("-", 20-hp))
+		fmt.Fprintf(&buf, "\x1b[4;37HMagic:  3/3   \x1b[34m%s\x1b[0m\x1b[K", strings.Repeat("=", 20))
+		fmt.Fprintf(&buf, "\x1b[8;37HXL: %2d Next: %d%% Place: Dungeon:%d\x1b[K", 1+turn/50, turn%100, 1+turn/100)
+		fmt.Fprintf(&buf, "\x1b[9;37HTime: %d.0 (1.0)\x1b[K", turn)
+
+		// Message area scrolls one line per turn
+		log = append(log, lines[rng.Intn(len(lines))])
+		if len(log) > 6 {
+			log = log[1:]
+		}
+		for i, line := range log {
+			fmt.Fprintf(&buf, "\x1b[%d;1H%s\x1b[K", 19+i, line)
+		}
+
+		frames = append(frames, buf.Bytes())
+	}
+	return frames
+}
+
+func TestSyntheticCaptures_RoundTripThroughTtyrec(t *testing.T) {
+	var buf bytes.Buffer
+	w := ttyrec.NewWriter(&buf)
+	frames := syntheticNetHackFrames(20)
+	for i, data := range frames {
+		if err := w.WriteFrame(ttyrec.Frame{Time: time.Unix(int64(i), 0), Data: data}); err != nil {
+			t.Fatalf("WriteFrame failed: %v", err)
+		}
+	}
+
+	read, err := ttyrec.ReadAll(&buf)
+	if err != nil || len(read) != len(frames) {
+		t.Fatalf("ReadAll returned %d frames, err %v", len(read), err)
+	}
+
+	view, err := NewWebView(dgclient.ViewOptions{InitialWidth: 80, InitialHeight: 24})
+	if err != nil {
+		t.Fatalf("NewWebView failed: %v", err)
+	}
+	for _, frame := range read {
+		if err := view.Render(frame.Data); err != nil {
+			t.Fatalf("Render failed: %v", err)
+		}
+	}
+	if !strings.HasPrefix(view.rowText(23, 80), "Dlvl:1") {
+		t.Errorf("Unexpected status line %q", view.rowText(23, 80))
+	}
+}
@@ -0,0 +1,64 @@
+// Package webui provides buffer reuse for the render and broadcast path.
+package webui
+
+import (
+	"sync"
+
+	"github.com/opd-ai/go-gamelaunch-www/pkg/transport"
+)
+
+// wireRowPool recycles the backing arrays of wire-format screen buffers.
+// Payloads are marshalled synchronously when broadcast, so their cells can
+// be reused as soon as the send returns.
+var wireRowPool = sync.Pool{
+	New: func() interface{} { return new([]transport.Cell) },
+}
+
+// asciiStrings holds single-character strings for ASCII runes so that cell
+// conversion does not allocate for the common case
+var asciiStrings = func() (table [128]string) {
+	for i := range table {
+		table[i] = string(rune(i))
+	}
+	return table
+}()
+
+// runeString converts r to a string, avoiding allocation for ASCII
+func runeString(r rune) string {
+	if r >= 0 && r < 128 {
+		return asciiStrings[r]
+	}
+	return string(r)
+}
+
+// getWireCells returns a cell slice of length n from the pool
+func getWireCells(n int) []transport.Cell {
+	buf := wireRowPool.Get().(*[]transport.Cell)
+	if cap(*buf) < n {
+		*buf = make([]transport.Cell, n)
+	}
+	return (*buf)[:n]
+}
+
+// releaseStatePayload returns the buffer of a payload built by
+// toStatePayload to the pool. The payload must not be used afterwards.
+func releaseStatePayload(payload *transport.StatePayload) {
+	if len(payload.Buffer) == 0 || len(payload.Buffer[0]) == 0 {
+		return
+	}
+
+	// Rows share one backing array starting at the first row
+	cells := payload.Buffer[0][:cap(payload.Buffer[0])]
+	payload.Buffer = nil
+	wireRowPool.Put(&cells)
+}
+
+// newCellGrid allocates a width x height grid backed by a single array
+func newCellGrid(width, height int) [][]Cell {
+	cells := make([]Cell, width*height)
+	grid := make([][]Cell, height)
+	for y := range grid {
+		grid[y] = cells[y*width : (y+1)*width : (y+1)*width]
+	}
+	return grid
+}
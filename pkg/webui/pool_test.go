@@ -0,0 +1,99 @@
+package webui
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/opd-ai/go-gamelaunch-client/pkg/dgclient"
+)
+
+// benchmarkFrame returns a full-screen redraw similar to a roguelike map
+// refresh
+func benchmarkFrame(frame int) []byte {
+	data := []byte("\x1b[H")
+	for y := 1; y <= 21; y++ {
+		data = append(data, fmt.Sprintf("\x1b[%d;1H\x1b[3%dm", y, (y+frame)%8)...)
+		for x := 0; x < 80; x++ {
+			data = append(data, byte('!'+(x+y+frame)%90))
+		}
+	}
+	return data
+}
+
+func BenchmarkWebView_Render(b *testing.B) {
+	view, err := NewWebView(dgclient.ViewOptions{InitialWidth: 80, InitialHeight: 24})
+	if err != nil {
+		b.Fatalf("NewWebView failed: %v", err)
+	}
+	frames := [][]byte{benchmarkFrame(0), benchmarkFrame(1)}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		view.Render(frames[i%2])
+	}
+}
+
+func BenchmarkToStatePayload(b *testing.B) {
+	view, err := NewWebView(dgclient.ViewOptions{InitialWidth: 80, InitialHeight: 24})
+	if err != nil {
+		b.Fatalf("NewWebView failed: %v", err)
+	}
+	view.Render(benchmarkFrame(0))
+	state := view.GetStateManager().GetCurrentState()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		payload := toStatePayload(state)
+		releaseStatePayload(payload)
+	}
+}
+
+func TestToStatePayload_ReusesPooledCells(t *testing.T) {
+	state := &GameState{Width: 2, Height: 2, Buffer: newCellGrid(2, 2)}
+	state.Buffer[0][0] = Cell{Char: 'a'}
+	state.Buffer[1][1] = Cell{Char: '龍'}
+
+	for i := 0; i < 3; i++ {
+		payload := toStatePayload(state)
+		if len(payload.Buffer) != 2 || len(payload.Buffer[1]) != 2 {
+			t.Fatalf("iteration %d: unexpected shape %dx%d", i, len(payload.Buffer[0]), len(payload.Buffer))
+		}
+		if payload.Buffer[0][0].Char != "a" || payload.Buffer[1][1].Char != "龍" {
+			t.Errorf("iteration %d: unexpected cells %+v", i, payload.Buffer)
+		}
+		if payload.Buffer[0][1].Char != "" { // Char 0 shows nothing, see Cell.Wide
+			t.Errorf("iteration %d: stale cell content %q", i, payload.Buffer[0][1].Char)
+		}
+		releaseStatePayload(payload)
+		if payload.Buffer != nil {
+			t.Error("Expected released payload to drop its buffer")
+		}
+	}
+}
+
+func TestNewCellGrid_RowsDoNotOverlap(t *testing.T) {
+	grid := newCellGrid(3, 2)
+	grid[0] = append(grid[0], Cell{Char: 'x'})
+	if grid[1][0].Char == 'x' {
+		t.Error("Appending to a row overwrote the next row")
+	}
+}
+
+func TestStateManager_generateDiff_TrimsHintCapacity(t *testing.T) {
+	sm := NewStateManager()
+	blank := &GameState{Width: 40, Height: 10, Buffer: newCellGrid(40, 10)}
+	full := &GameState{Width: 40, Height: 10, Buffer: newCellGrid(40, 10)}
+	for y := range full.Buffer {
+		for x := range full.Buffer[y] {
+			full.Buffer[y][x].Char = '#'
+		}
+	}
+
+	sm.generateDiff(blank, full)
+	small := sm.generateDiff(full, full)
+	if cap(small.Changes) > 64 {
+		t.Errorf("Expected trimmed capacity for empty diff, got %d", cap(small.Changes))
+	}
+}
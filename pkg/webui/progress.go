@@ -0,0 +1,146 @@
+// Package webui provides the game clock and turn counter read from the
+// screen by parser plugins.
+package webui
+
+import (
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strconv"
+
+	"github.com/opd-ai/go-gamelaunch-www/pkg/transport"
+)
+
+// GameProgress is how far the game has come, as shown on its status lines
+type GameProgress struct {
+	Turn  int64  `json:"turn,omitempty"`  // e.g. NetHack's T:1234
+	Clock string `json:"clock,omitempty"` // in-game time as displayed, e.g. DCSS's 1234.5
+}
+
+// ProgressParser is a parser plugin that reads the game's turn counter or
+// clock from the screen
+type ProgressParser interface {
+	// Progress returns the progress state shows, or false when it shows
+	// none. state must not be modified.
+	Progress(state *GameState) (GameProgress, bool)
+}
+
+// ProgressConfig selects the patterns that find the turn counter and game
+// clock on the screen
+type ProgressConfig struct {
+	Game  string `yaml:"game,omitempty"`  // built-in patterns, see ProgressGames
+	Turn  string `yaml:"turn,omitempty"`  // regexp whose first group is the turn number
+	Clock string `yaml:"clock,omitempty"` // regexp whose first group is the game time
+}
+
+// progressGames holds the built-in turn and clock patterns by game
+var progressGames = map[string]ProgressConfig{
+	"nethack": {Turn: `\bT:(\d+)`},
+	"crawl":   {Turn: `\bTurn:\s*(\d+)`, Clock: `\bTime:\s*(\d+(?:\.\d+)?)`},
+	"angband": {Turn: `\bTurn[: ]\s*(\d+)`},
+}
+
+// ProgressGames returns the names of the games with built-in patterns
+func ProgressGames() []string {
+	return slices.Sorted(maps.Keys(progressGames))
+}
+
+// PatternProgressParser finds the turn counter and game clock with regular
+// expressions, searching the screen from the bottom up as status lines
+// usually are at the bottom
+type PatternProgressParser struct {
+	turn  *regexp.Regexp
+	clock *regexp.Regexp
+}
+
+// NewPatternProgressParser validates cfg and compiles its patterns, which
+// override those of its game
+func NewPatternProgressParser(cfg ProgressConfig) (*PatternProgressParser, error) {
+	if cfg.Game != "" {
+		builtin, ok := progressGames[cfg.Game]
+		if !ok {
+			return nil, fmt.Errorf("progress: unknown game %q", cfg.Game)
+		}
+		if cfg.Turn == "" {
+			cfg.Turn = builtin.Turn
+		}
+		if cfg.Clock == "" {
+			cfg.Clock = builtin.Clock
+		}
+	}
+	if cfg.Turn == "" && cfg.Clock == "" {
+		return nil, fmt.Errorf("progress: a game or a turn or clock pattern is required")
+	}
+
+	p := &PatternProgressParser{}
+	for _, pattern := range []struct {
+		name string
+		expr string
+		dst  **regexp.Regexp
+	}{
+		{"turn", cfg.Turn, &p.turn},
+		{"clock", cfg.Clock, &p.clock},
+	} {
+		if pattern.expr == "" {
+			continue
+		}
+		re, err := regexp.Compile(pattern.expr)
+		if err != nil {
+			return nil, fmt.Errorf("progress: invalid %s pattern: %w", pattern.name, err)
+		}
+		if re.NumSubexp() < 1 {
+			return nil, fmt.Errorf("progress: %s pattern %q has no group", pattern.name, pattern.expr)
+		}
+		*pattern.dst = re
+	}
+	return p, nil
+}
+
+// Progress implements ProgressParser
+func (p *PatternProgressParser) Progress(state *GameState) (GameProgress, bool) {
+	var progress GameProgress
+	foundTurn, foundClock := p.turn == nil, p.clock == nil
+	lines := stateLines(state)
+	for y := len(lines) - 1; y >= 0 && !(foundTurn && foundClock); y-- {
+		if !foundTurn {
+			if m := p.turn.FindStringSubmatch(lines[y]); m != nil {
+				if turn, err := strconv.ParseInt(m[1], 10, 64); err == nil {
+					progress.Turn, foundTurn = turn, true
+				}
+			}
+		}
+		if !foundClock {
+			if m := p.clock.FindStringSubmatch(lines[y]); m != nil {
+				progress.Clock, foundClock = m[1], true
+			}
+		}
+	}
+	return progress, progress != GameProgress{}
+}
+
+// AddProgressParser registers a parser plugin run on every new state. The
+// first parser that recognizes progress on a screen sets GameState.Progress.
+func (v *WebView) AddProgressParser(parser ProgressParser) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.progressParsers = append(v.progressParsers, parser)
+}
+
+// progress runs the registered progress parsers on state
+func (v *WebView) progress(state *GameState) *GameProgress {
+	for _, parser := range v.progressParsers {
+		if progress, ok := parser.Progress(state); ok {
+			return &progress
+		}
+	}
+	return nil
+}
+
+// toWireProgress converts progress to its wire representation
+func toWireProgress(progress *GameProgress) *transport.Progress {
+	if progress == nil {
+		return nil
+	}
+	return &transport.Progress{Turn: progress.Turn, Clock: progress.Clock}
+}
@@ -0,0 +1,96 @@
+package webui
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestNewPatternProgressParser_Validation(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     ProgressConfig
+		wantErr bool
+	}{
+		{"Game", ProgressConfig{Game: "nethack"}, false},
+		{"Patterns", ProgressConfig{Turn: `Moves: (\d+)`, Clock: `Day (\d+)`}, false},
+		{"OverrideGame", ProgressConfig{Game: "crawl", Clock: `Time (\S+)`}, false},
+		{"Empty", ProgressConfig{}, true},
+		{"UnknownGame", ProgressConfig{Game: "rogue"}, true},
+		{"InvalidPattern", ProgressConfig{Turn: `T:(\d+`}, true},
+		{"NoGroup", ProgressConfig{Turn: `T:\d+`}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewPatternProgressParser(tt.cfg)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewPatternProgressParser() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPatternProgressParser_Progress(t *testing.T) {
+	tests := []struct {
+		game   string
+		screen string
+		want   GameProgress
+		wantOK bool
+	}{
+		{"nethack", "You see here a jackal corpse.\r\n\r\nAgent the Stripling   St:16 Dx:13\r\nDlvl:1 $:0 HP:14(14) Pw:1(1) AC:6 Xp:1/0 T:1234", GameProgress{Turn: 1234}, true},
+		{"crawl", "Health: 12/12\r\nXL:  1 Next:  0%  Place: Dungeon:1\r\nTime: 345.6 (1.0)  Turn: 412", GameProgress{Turn: 412, Clock: "345.6"}, true},
+		{"nethack", "Welcome to NetHack!", GameProgress{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.game, func(t *testing.T) {
+			parser, err := NewPatternProgressParser(ProgressConfig{Game: tt.game})
+			if err != nil {
+				t.Fatalf("NewPatternProgressParser failed: %v", err)
+			}
+			view := newTestView(t)
+			if err := view.Render([]byte(tt.screen)); err != nil {
+				t.Fatalf("Render failed: %v", err)
+			}
+
+			got, ok := parser.Progress(view.GetCurrentState())
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("Progress() = %+v, %v, want %+v, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestWebUI_Progress_InStatsAndDiffs(t *testing.T) {
+	ui, err := NewWebUI(WebUIOptions{View: newTestView(t), Progress: &ProgressConfig{Game: "nethack"}})
+	if err != nil {
+		t.Fatalf("Failed to create WebUI: %v", err)
+	}
+	view := ui.GetView()
+	if err := view.Render([]byte("Dlvl:1 T:7")); err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	version := view.GetStateManager().GetCurrentState().Version
+	if err := view.Render([]byte("\rDlvl:1 T:8")); err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+
+	diff, err := view.GetStateManager().PollChanges(version, time.Second)
+	if err != nil {
+		t.Fatalf("PollChanges failed: %v", err)
+	}
+	if diff.Progress == nil || diff.Progress.Turn != 8 {
+		t.Errorf("diff progress = %+v, want turn 8", diff.Progress)
+	}
+
+	rec := httptest.NewRecorder()
+	ui.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/session/stats", nil))
+	var stats SessionStats
+	if err := json.Unmarshal(rec.Body.Bytes(), &stats); err != nil {
+		t.Fatalf("invalid response: %v", err)
+	}
+	if stats.Progress == nil || stats.Progress.Turn != 8 {
+		t.Errorf("session stats progress = %+v, want turn 8", stats.Progress)
+	}
+}
@@ -0,0 +1,155 @@
+// Package webui provides authentication through identity headers set by a
+// trusted reverse proxy such as oauth2-proxy or Authelia.
+package webui
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/opd-ai/go-gamelaunch-www/pkg/transport"
+)
+
+// ProxyAuthConfig configures trust in identity headers set by an
+// authenticating reverse proxy. Headers are only honoured on requests whose
+// remote address is one of TrustedProxies. They must be named explicitly:
+// a proxy only overwrites the headers it sets, so any other variant would
+// reach the UI as the client sent it.
+type ProxyAuthConfig struct {
+	TrustedProxies []string `yaml:"trusted_proxies"`         // IPs or CIDRs of the proxy
+	UserHeader     string   `yaml:"user_header"`             // e.g. Remote-User
+	GroupsHeader   string   `yaml:"groups_header,omitempty"` // comma-separated groups; none when unset
+
+	// Authorization mapping
+	UserRoles   map[string]string `yaml:"user_roles,omitempty"`   // user -> role, takes precedence
+	GroupRoles  map[string]string `yaml:"group_roles,omitempty"`  // group -> role
+	DefaultRole string            `yaml:"default_role,omitempty"` // role when nothing matches; empty denies
+}
+
+// ProxyAuthenticator maps reverse-proxy identity headers to identities
+type ProxyAuthenticator struct {
+	RoleAuthorizer
+	config  ProxyAuthConfig
+	trusted []*net.IPNet
+}
+
+// NewProxyAuthenticator validates cfg and creates an authenticator
+func NewProxyAuthenticator(cfg ProxyAuthConfig) (*ProxyAuthenticator, error) {
+	if len(cfg.TrustedProxies) == 0 {
+		return nil, fmt.Errorf("proxy auth: trusted_proxies is required")
+	}
+	if strings.TrimSpace(cfg.UserHeader) == "" {
+		return nil, fmt.Errorf("proxy auth: user_header is required, e.g. Remote-User for Authelia or X-Auth-Request-User for oauth2-proxy")
+	}
+
+	trusted, err := parseIPNets(cfg.TrustedProxies)
+	if err != nil {
+		return nil, fmt.Errorf("proxy auth: %w", err)
+	}
+	if err := validateRoleMapping(cfg.GroupRoles, cfg.DefaultRole); err != nil {
+		return nil, fmt.Errorf("proxy auth: %w", err)
+	}
+	for user, role := range cfg.UserRoles {
+		if _, ok := ParseRole(role); !ok {
+			return nil, fmt.Errorf("proxy auth: user %q maps to unknown role %q", user, role)
+		}
+	}
+
+	return &ProxyAuthenticator{config: cfg, trusted: trusted}, nil
+}
+
+// Authenticate returns the identity asserted by the proxy headers on r, or
+// nil when r does not come from a trusted proxy or carries no user header
+func (a *ProxyAuthenticator) Authenticate(r *http.Request) *Identity {
+	if !a.isTrusted(r) {
+		return nil
+	}
+
+	user := headerValue(r, a.config.UserHeader)
+	if user == "" {
+		return nil
+	}
+
+	identity := &Identity{
+		Subject: user,
+		Name:    user,
+		Groups:  splitGroups(headerValue(r, a.config.GroupsHeader)),
+	}
+	if role, ok := ParseRole(a.config.UserRoles[user]); ok {
+		identity.Role = role
+	} else {
+		identity.Role = resolveRole(a.config.GroupRoles, a.config.DefaultRole, identity.Groups)
+	}
+	return identity
+}
+
+// Routes implements AuthProvider; the proxy manages its own sessions
+func (a *ProxyAuthenticator) Routes() map[string]http.HandlerFunc {
+	return nil
+}
+
+// isTrusted reports whether r was received directly from a trusted proxy
+func (a *ProxyAuthenticator) isTrusted(r *http.Request) bool {
+	ip := net.ParseIP(transport.RemoteIP(r))
+	if ip == nil {
+		return false
+	}
+
+	for _, network := range a.trusted {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// headerValue returns the trimmed value of header, or "" when header is
+// unset
+func headerValue(r *http.Request, header string) string {
+	if header == "" {
+		return ""
+	}
+	return strings.TrimSpace(r.Header.Get(header))
+}
+
+// splitGroups parses a comma-separated group list
+func splitGroups(value string) []string {
+	if value == "" {
+		return nil
+	}
+
+	var groups []string
+	for _, group := range strings.Split(value, ",") {
+		if group = strings.TrimSpace(group); group != "" {
+			groups = append(groups, group)
+		}
+	}
+	return groups
+}
+
+// parseIPNets parses a list of IP addresses and CIDR ranges
+func parseIPNets(entries []string) ([]*net.IPNet, error) {
+	networks := make([]*net.IPNet, 0, len(entries))
+	for _, entry := range entries {
+		if !strings.Contains(entry, "/") {
+			ip := net.ParseIP(entry)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid IP address %q", entry)
+			}
+			bits := 128
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 32
+			}
+			networks = append(networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+
+		_, network, err := net.ParseCIDR(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CIDR %q", entry)
+		}
+		networks = append(networks, network)
+	}
+	return networks, nil
+}
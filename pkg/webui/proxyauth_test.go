@@ -0,0 +1,192 @@
+package webui
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/opd-ai/go-gamelaunch-client/pkg/dgclient"
+)
+
+// newProxyAuthWebUI creates a WebUI that trusts identity headers from
+// 10.0.0.0/8
+func newProxyAuthWebUI(t *testing.T) *WebUI {
+	t.Helper()
+
+	view, err := NewWebView(dgclient.ViewOptions{InitialWidth: 80, InitialHeight: 24})
+	if err != nil {
+		t.Fatalf("Failed to create WebView: %v", err)
+	}
+	ui, err := NewWebUI(WebUIOptions{
+		View: view,
+		ProxyAuth: &ProxyAuthConfig{
+			TrustedProxies: []string{"10.0.0.0/8"},
+			UserHeader:     "Remote-User",
+			GroupsHeader:   "Remote-Groups",
+			GroupRoles:     map[string]string{"admins": "admin", "players": "player"},
+			UserRoles:      map[string]string{"carol": "admin"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create WebUI: %v", err)
+	}
+	return ui
+}
+
+func TestNewProxyAuthenticator_InvalidConfig(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  ProxyAuthConfig
+	}{
+		{"no trusted proxies", ProxyAuthConfig{UserHeader: "Remote-User"}},
+		{"no user header", ProxyAuthConfig{TrustedProxies: []string{"127.0.0.1"}}},
+		{"blank user header", ProxyAuthConfig{TrustedProxies: []string{"127.0.0.1"}, UserHeader: " "}},
+		{"bad address", ProxyAuthConfig{TrustedProxies: []string{"not-an-ip"}, UserHeader: "Remote-User"}},
+		{"bad cidr", ProxyAuthConfig{TrustedProxies: []string{"10.0.0.0/99"}, UserHeader: "Remote-User"}},
+		{"bad group role", ProxyAuthConfig{TrustedProxies: []string{"127.0.0.1"}, UserHeader: "Remote-User", GroupRoles: map[string]string{"g": "root"}}},
+		{"bad user role", ProxyAuthConfig{TrustedProxies: []string{"127.0.0.1"}, UserHeader: "Remote-User", UserRoles: map[string]string{"u": "root"}}},
+		{"bad default role", ProxyAuthConfig{TrustedProxies: []string{"127.0.0.1"}, UserHeader: "Remote-User", DefaultRole: "root"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewProxyAuthenticator(tt.cfg); err == nil {
+				t.Error("Expected configuration error")
+			}
+		})
+	}
+}
+
+func TestProxyAuthenticator_Authenticate(t *testing.T) {
+	auth, err := NewProxyAuthenticator(ProxyAuthConfig{
+		TrustedProxies: []string{"10.0.0.0/8", "::1"},
+		UserHeader:     "Remote-User",
+		GroupsHeader:   "Remote-Groups",
+		GroupRoles:     map[string]string{"admins": "admin", "players": "player"},
+		UserRoles:      map[string]string{"carol": "admin"},
+		DefaultRole:    "spectator",
+	})
+	if err != nil {
+		t.Fatalf("NewProxyAuthenticator failed: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		headers    map[string]string
+		wantUser   string
+		wantRole   Role
+	}{
+		{"untrusted source", "192.168.1.5:1234", map[string]string{"Remote-User": "alice"}, "", ""},
+		{"missing user", "10.1.2.3:1234", nil, "", ""},
+		{"authelia headers", "10.1.2.3:1234", map[string]string{"Remote-User": "alice", "Remote-Groups": "players, admins"}, "alice", RoleAdmin},
+		{"ipv6 proxy", "[::1]:1234", map[string]string{"Remote-User": "bob", "Remote-Groups": "players"}, "bob", RolePlayer},
+		{"unconfigured headers", "10.1.2.3:1234", map[string]string{"X-Forwarded-User": "mallory", "X-Auth-Request-User": "mallory"}, "", ""},
+		{"unconfigured groups header", "10.1.2.3:1234", map[string]string{"Remote-User": "dave", "X-Forwarded-Groups": "admins"}, "dave", RoleSpectator},
+		{"user mapping wins", "10.1.2.3:1234", map[string]string{"Remote-User": "carol", "Remote-Groups": "players"}, "carol", RoleAdmin},
+		{"default role", "10.1.2.3:1234", map[string]string{"Remote-User": "dave"}, "dave", RoleSpectator},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/", nil)
+			req.RemoteAddr = tt.remoteAddr
+			for name, value := range tt.headers {
+				req.Header.Set(name, value)
+			}
+
+			identity := auth.Authenticate(req)
+			if tt.wantUser == "" {
+				if identity != nil {
+					t.Errorf("Expected no identity, got %+v", identity)
+				}
+				return
+			}
+			if identity == nil {
+				t.Fatal("Expected identity, got nil")
+			}
+			if identity.Subject != tt.wantUser || identity.Role != tt.wantRole {
+				t.Errorf("Got %s/%s, want %s/%s", identity.Subject, identity.Role, tt.wantUser, tt.wantRole)
+			}
+		})
+	}
+}
+
+func TestProxyAuthenticator_CustomHeader(t *testing.T) {
+	auth, err := NewProxyAuthenticator(ProxyAuthConfig{
+		TrustedProxies: []string{"127.0.0.1"},
+		UserHeader:     "X-Email",
+		DefaultRole:    "player",
+	})
+	if err != nil {
+		t.Fatalf("NewProxyAuthenticator failed: %v", err)
+	}
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.RemoteAddr = "127.0.0.1:5555"
+	req.Header.Set("Remote-User", "ignored")
+	req.Header.Set("Remote-Groups", "admins")
+	if identity := auth.Authenticate(req); identity != nil {
+		t.Errorf("Expected other identity headers to be ignored, got %+v", identity)
+	}
+
+	req.Header.Set("X-Email", "alice@example.com")
+	identity := auth.Authenticate(req)
+	if identity == nil || identity.Subject != "alice@example.com" || len(identity.Groups) != 0 {
+		t.Errorf("Expected identity from custom header without groups, got %+v", identity)
+	}
+}
+
+func TestWebUI_ProxyAuthGate(t *testing.T) {
+	ui := newProxyAuthWebUI(t)
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		user       string
+		groups     string
+		wantStatus int
+	}{
+		{"untrusted source", "203.0.113.9:1000", "alice", "admins", http.StatusUnauthorized},
+		{"no role", "10.0.0.2:1000", "eve", "", http.StatusForbidden},
+		{"player", "10.0.0.2:1000", "alice", "players", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/version", nil)
+			req.RemoteAddr = tt.remoteAddr
+			req.Header.Set("Accept", "text/html")
+			req.Header.Set("Remote-User", tt.user)
+			req.Header.Set("Remote-Groups", tt.groups)
+			rr := httptest.NewRecorder()
+			ui.ServeHTTP(rr, req)
+
+			if rr.Code != tt.wantStatus {
+				t.Errorf("Expected status %d, got %d", tt.wantStatus, rr.Code)
+			}
+		})
+	}
+}
+
+func TestWebUI_ProxyAuthWhoAmI(t *testing.T) {
+	ui := newProxyAuthWebUI(t)
+
+	req := httptest.NewRequest("GET", "/auth/whoami", nil)
+	req.RemoteAddr = "10.9.9.9:1000"
+	req.Header.Set("Remote-User", "carol")
+	rr := httptest.NewRecorder()
+	ui.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", rr.Code)
+	}
+	var identity Identity
+	if err := json.Unmarshal(rr.Body.Bytes(), &identity); err != nil {
+		t.Fatalf("Failed to decode identity: %v", err)
+	}
+	if identity.Subject != "carol" || identity.Role != RoleAdmin {
+		t.Errorf("Unexpected identity %+v", identity)
+	}
+}
@@ -0,0 +1,56 @@
+// Package webui provides color quantization for clients with a limited
+// palette.
+package webui
+
+import "github.com/opd-ai/go-gamelaunch-www/pkg/transport"
+
+// ansi16Palette lists the 16 standard ANSI colors in index order
+var ansi16Palette = [16]string{
+	"#000000", "#800000", "#008000", "#808000", "#000080", "#800080", "#008080", "#C0C0C0",
+	"#808080", "#FF0000", "#00FF00", "#FFFF00", "#0000FF", "#FF00FF", "#00FFFF", "#FFFFFF",
+}
+
+// QuantizeColors replaces a cell's foreground and background colors with the
+// nearest of the 16 standard ANSI colors. Colors that are not in #RRGGBB
+// form are returned unchanged.
+func (cc *ColorConverter) QuantizeColors(fg, bg string) (string, string) {
+	key := colorKey{fg: fg, bg: bg, mode: transport.PaletteANSI16}
+	cc.mu.Lock()
+	if pair, ok := cc.cache[key]; ok {
+		cc.mu.Unlock()
+		return pair.fg, pair.bg
+	}
+	cc.mu.Unlock()
+
+	pair := colorPair{fg: nearestANSI16(fg), bg: nearestANSI16(bg)}
+
+	cc.mu.Lock()
+	if cc.cache == nil || len(cc.cache) >= maxColorCacheEntries {
+		cc.cache = make(map[colorKey]colorPair)
+	}
+	cc.cache[key] = pair
+	cc.mu.Unlock()
+
+	return pair.fg, pair.bg
+}
+
+// nearestANSI16 returns the ANSI color closest to hex, weighting the
+// channels by how strongly they are perceived ("redmean" distance)
+func nearestANSI16(hex string) string {
+	r, g, b, ok := parseHexColor(hex)
+	if !ok {
+		return hex
+	}
+
+	best, bestDistance := hex, -1.0
+	for _, candidate := range ansi16Palette {
+		cr, cg, cb, _ := parseHexColor(candidate)
+		redMean := (r + cr) / 2
+		dr, dg, db := r-cr, g-cg, b-cb
+		distance := (2+redMean/256)*dr*dr + 4*dg*dg + (2+(255-redMean)/256)*db*db
+		if bestDistance < 0 || distance < bestDistance {
+			best, bestDistance = candidate, distance
+		}
+	}
+	return best
+}
@@ -0,0 +1,56 @@
+package webui
+
+import (
+	"testing"
+
+	"github.com/opd-ai/go-gamelaunch-www/pkg/transport"
+)
+
+func TestNearestANSI16(t *testing.T) {
+	tests := []struct {
+		hex  string
+		want string
+	}{
+		{"#FF0000", "#FF0000"},
+		{"#ff0000", "#FF0000"},
+		{"#E01010", "#FF0000"},
+		{"#700000", "#800000"},
+		{"#5F87AF", "#808080"},
+		{"#D7D7D7", "#C0C0C0"},
+		{"#FAFAFA", "#FFFFFF"},
+		{"#101010", "#000000"},
+		{"#00D7D7", "#00FFFF"},
+		{"default", "default"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := nearestANSI16(tt.hex); got != tt.want {
+			t.Errorf("nearestANSI16(%q) = %s, want %s", tt.hex, got, tt.want)
+		}
+	}
+}
+
+func TestToStatePayloadFor_QuantizesToANSI16(t *testing.T) {
+	state := &GameState{Buffer: [][]Cell{{{Char: 'd', FgColor: "#E01010", BgColor: "#101010"}}}, Width: 1, Height: 1}
+
+	tests := []struct {
+		name   string
+		opts   transport.ViewOptions
+		wantFg string
+		wantBg string
+	}{
+		{"FullPalette", transport.ViewOptions{}, "#E01010", "#101010"},
+		{"ANSI16", transport.ViewOptions{Palette: transport.PaletteANSI16}, "#FF0000", "#000000"},
+		// The color mode runs first, so its output is quantized
+		{"ANSI16Deuteranopia", transport.ViewOptions{Palette: transport.PaletteANSI16, ColorMode: transport.ColorModeDeuteranopia}, "#800080", "#000000"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			payload := toStatePayloadFor(state, tt.opts, NewColorConverter())
+			defer releaseStatePayload(payload)
+			if got := payload.Buffer[0][0]; got.FgColor != tt.wantFg || got.BgColor != tt.wantBg {
+				t.Errorf("colors = %s on %s, want %s on %s", got.FgColor, got.BgColor, tt.wantFg, tt.wantBg)
+			}
+		})
+	}
+}
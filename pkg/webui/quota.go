@@ -0,0 +1,143 @@
+// Package webui provides per-session resource quotas, so that a single
+// runaway session cannot exhaust the host.
+package webui
+
+import (
+	"fmt"
+	"math"
+	"unsafe"
+)
+
+// cellMemory estimates the bytes a screen cell takes, counting the view's
+// buffer and the copy in the current state
+const cellMemory = 2 * int(unsafe.Sizeof(Cell{}))
+
+// QuotaConfig bounds the resources of a session. A terminal resize that
+// would exceed the memory quota is shrunk to fit, the scrollback drops its
+// oldest lines to fit in what the screen leaves, instant replay keeps no
+// more output than the scrollback quota, and a recording stops at the
+// recording quota until the next game starts. Empty values set no limit.
+type QuotaConfig struct {
+	Memory     string `yaml:"memory,omitempty"`     // e.g. "16MB"; screen buffer and scrollback
+	Scrollback string `yaml:"scrollback,omitempty"` // e.g. "2MB"; output kept for instant replay
+	Recording  string `yaml:"recording,omitempty"`  // e.g. "100MB" per game
+}
+
+// QuotaUsage reports the resources of a session against its quotas
+type QuotaUsage struct {
+	Memory          uint64 `json:"memory"` // estimated bytes of the screen and scrollback
+	MemoryLimit     uint64 `json:"memory_limit,omitempty"`
+	Scrollback      uint64 `json:"scrollback"` // bytes kept for instant replay
+	ScrollbackLimit uint64 `json:"scrollback_limit,omitempty"`
+	Recording       uint64 `json:"recording"` // bytes of the recording in progress
+	RecordingLimit  uint64 `json:"recording_limit,omitempty"`
+
+	// Shrunk is set once a resize was shrunk to the memory quota, and
+	// Truncated while the current game is no longer recorded
+	Shrunk    bool `json:"shrunk,omitempty"`
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// sessionQuota holds the limits of a session in bytes, zero for none
+type sessionQuota struct {
+	memory     uint64
+	scrollback uint64
+	recording  uint64
+}
+
+// newSessionQuota validates cfg
+func newSessionQuota(cfg QuotaConfig) (*sessionQuota, error) {
+	q := &sessionQuota{}
+	var err error
+	if q.memory, err = parseByteSize(cfg.Memory); err != nil {
+		return nil, fmt.Errorf("quota: invalid memory %q", cfg.Memory)
+	}
+	if q.memory > 0 && q.memory < uint64(80*24*cellMemory) {
+		return nil, fmt.Errorf("quota: memory %q does not fit an 80x24 screen", cfg.Memory)
+	}
+	if q.scrollback, err = parseByteSize(cfg.Scrollback); err != nil {
+		return nil, fmt.Errorf("quota: invalid scrollback %q", cfg.Scrollback)
+	}
+	if q.recording, err = parseByteSize(cfg.Recording); err != nil {
+		return nil, fmt.Errorf("quota: invalid recording %q", cfg.Recording)
+	}
+	return q, nil
+}
+
+// SetMaxCells limits the screen to n cells, shrinking it right away when it
+// is larger; zero removes the limit
+func (v *WebView) SetMaxCells(n int) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	v.maxCells = n
+	if n > 0 && v.width*v.height > n {
+		v.resize(v.width, v.height)
+	}
+	if v.scrollback != nil {
+		v.scrollback.trim(v.scrollbackBudget())
+	}
+}
+
+// scrollbackBudget returns the cells the scrollback may hold in the memory
+// SetMaxCells leaves beside the screen, or -1 without a limit; v.mu must be
+// held
+func (v *WebView) scrollbackBudget() int {
+	if v.maxCells <= 0 {
+		return -1
+	}
+	screens := 1
+	if v.mainBuffer != nil {
+		screens = 2
+	}
+	free := (v.maxCells*cellMemory - screens*v.width*v.height*cellMemory) / int(unsafe.Sizeof(Cell{}))
+	return max(0, free)
+}
+
+// fitSize scales width and height down in proportion until the screen fits
+// the cell limit, reporting whether it had to
+func (v *WebView) fitSize(width, height int) (int, int, bool) {
+	if v.maxCells <= 0 || width*height <= v.maxCells {
+		return width, height, false
+	}
+	scale := math.Sqrt(float64(v.maxCells) / float64(width*height))
+	width = max(1, int(float64(width)*scale))
+	height = max(1, min(int(float64(height)*scale), v.maxCells/width))
+	return width, height, true
+}
+
+// ScreenMemory returns the estimated bytes of the screen and its scrollback,
+// and whether a resize was shrunk to fit SetMaxCells
+func (v *WebView) ScreenMemory() (bytes uint64, shrunk bool) {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	screens := 1
+	if v.mainBuffer != nil {
+		screens = 2 // the alternate screen and the saved normal one
+	}
+	bytes = uint64(screens * v.width * v.height * cellMemory)
+	if v.scrollback != nil {
+		bytes += uint64(v.scrollback.cells) * uint64(unsafe.Sizeof(Cell{}))
+	}
+	return bytes, v.shrunk
+}
+
+// GetQuotaUsage returns the resources of the session against its quotas
+func (w *WebUI) GetQuotaUsage() QuotaUsage {
+	usage := QuotaUsage{
+		MemoryLimit:     w.quota.memory,
+		ScrollbackLimit: w.quota.scrollback,
+		RecordingLimit:  w.quota.recording,
+	}
+	if view := w.GetView(); view != nil {
+		usage.Memory, usage.Shrunk = view.ScreenMemory()
+	}
+	if w.replay != nil {
+		usage.Scrollback = uint64(w.replay.Size())
+	}
+	if w.recorder != nil {
+		size, truncated := w.recorder.Size()
+		usage.Recording, usage.Truncated = uint64(size), truncated
+	}
+	return usage
+}
@@ -0,0 +1,108 @@
+package webui
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWebView_SetMaxCells_ShrinksResize(t *testing.T) {
+	view := newTestView(t)
+	view.SetMaxCells(80 * 24)
+
+	if err := view.SetSize(80, 24); err != nil {
+		t.Fatalf("SetSize failed: %v", err)
+	}
+	if _, shrunk := view.ScreenMemory(); shrunk {
+		t.Error("80x24 shrunk although it fits")
+	}
+
+	if err := view.SetSize(800, 240); err != nil {
+		t.Fatalf("SetSize failed: %v", err)
+	}
+	width, height := view.GetSize()
+	if width*height > 80*24 || width < 70 || height < 20 {
+		t.Errorf("size = %dx%d, want about 80x24", width, height)
+	}
+	memory, shrunk := view.ScreenMemory()
+	if !shrunk || memory != uint64(width*height*cellMemory) {
+		t.Errorf("ScreenMemory = %d, %v", memory, shrunk)
+	}
+
+	// A limit below the current size shrinks the screen right away
+	view.SetMaxCells(40 * 10)
+	if width, height := view.GetSize(); width*height > 40*10 {
+		t.Errorf("size = %dx%d after lowering the limit", width, height)
+	}
+	var recorded bool
+	for _, event := range view.Events().Events(EventQuota, 0) {
+		recorded = recorded || strings.Contains(event.Message, "800x240")
+	}
+	if !recorded {
+		t.Error("shrunk resize not recorded in the event log")
+	}
+}
+
+func TestWebUI_QuotaUsage(t *testing.T) {
+	view := newTestView(t)
+	ui, err := NewWebUI(WebUIOptions{
+		View:          view,
+		Quota:         &QuotaConfig{Memory: "1MB", Scrollback: "10B"},
+		InstantReplay: &InstantReplayConfig{},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create WebUI: %v", err)
+	}
+	if err := view.Render([]byte("You hear the footsteps of a guard on patrol.")); err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+
+	usage := ui.GetSessionStats().Quota
+	if usage.MemoryLimit != 1e6 || usage.Memory == 0 || usage.Memory > usage.MemoryLimit {
+		t.Errorf("memory usage = %d of %d", usage.Memory, usage.MemoryLimit)
+	}
+	if usage.ScrollbackLimit != 10 || usage.Scrollback > 10 {
+		t.Errorf("scrollback usage = %d of %d", usage.Scrollback, usage.ScrollbackLimit)
+	}
+}
+
+func TestNewSessionQuota_InvalidConfig(t *testing.T) {
+	for _, cfg := range []QuotaConfig{
+		{Memory: "lots"},
+		{Memory: "1KB"}, // smaller than an 80x24 screen
+		{Scrollback: "-1MB"},
+		{Recording: "big"},
+	} {
+		if _, err := newSessionQuota(cfg); err == nil {
+			t.Errorf("newSessionQuota(%+v) succeeded", cfg)
+		}
+	}
+}
+
+func TestWebView_SetMaxCells_BoundsScrollback(t *testing.T) {
+	view := newTestView(t)
+	view.SetScrollback(1000)
+	view.SetMaxCells(80*24 + 40)
+
+	// Full lines scroll off until only what the screen leaves is kept
+	line := strings.Repeat("x", 80) + "\r\n"
+	if err := view.Render([]byte(strings.Repeat(line, 100))); err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	memory, _ := view.ScreenMemory()
+	if limit := uint64((80*24 + 40) * cellMemory); memory > limit {
+		t.Errorf("ScreenMemory = %d, over the %d quota", memory, limit)
+	}
+	page := view.Scrollback(^uint64(0), 1000)
+	if len(page.Lines) == 0 || page.Total != 100-23 {
+		t.Fatalf("scrollback = %d lines of %d, want the newest kept", len(page.Lines), page.Total)
+	}
+	if page.First+uint64(len(page.Lines)) != page.Total {
+		t.Errorf("scrollback kept lines %d-%d of %d", page.First, page.First+uint64(len(page.Lines)), page.Total)
+	}
+
+	// A lower limit drops lines right away
+	view.SetMaxCells(80 * 24)
+	if page := view.Scrollback(^uint64(0), 1000); len(page.Lines) != 0 {
+		t.Errorf("scrollback = %d lines with no memory left", len(page.Lines))
+	}
+}
@@ -0,0 +1,219 @@
+// Package webui provides per-IP request rate limiting with temporary bans.
+package webui
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Rate limiter defaults
+const (
+	defaultRateBurst       = 20
+	defaultBanThreshold    = 50
+	defaultBanDuration     = 10 * time.Minute
+	rateLimiterPrunePeriod = time.Minute
+)
+
+// RateLimitConfig configures per-IP request rate limiting. Each address gets
+// a token bucket refilled at RequestsPerSecond; requests made while the
+// bucket is empty count as violations, and BanThreshold violations result
+// in a temporary ban.
+type RateLimitConfig struct {
+	RequestsPerSecond float64 `yaml:"requests_per_second"`
+	Burst             int     `yaml:"burst,omitempty"`                             // default 20
+	BanThreshold      int     `yaml:"ban_threshold,omitempty"`                     // default 50, negative disables bans
+	BanDuration       string  `yaml:"ban_duration,omitempty" yamlcheck:"duration"` // default 10m
+}
+
+// Ban describes a temporarily banned address
+type Ban struct {
+	IP      string    `json:"ip"`
+	Reason  string    `json:"reason"`
+	Expires time.Time `json:"expires"`
+}
+
+// bucket tracks the request budget of a single address
+type bucket struct {
+	tokens     float64
+	last       time.Time
+	violations int
+}
+
+// RateLimiter enforces per-IP request rates and a ban list
+type RateLimiter struct {
+	rate         float64
+	burst        float64
+	banThreshold int
+	banDuration  time.Duration
+
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	bans      map[string]Ban
+	lastPrune time.Time
+	now       func() time.Time
+}
+
+// NewRateLimiter validates cfg and creates a rate limiter
+func NewRateLimiter(cfg RateLimitConfig) (*RateLimiter, error) {
+	if cfg.RequestsPerSecond <= 0 {
+		return nil, fmt.Errorf("rate limit: requests_per_second must be positive")
+	}
+
+	burst := cfg.Burst
+	if burst <= 0 {
+		burst = defaultRateBurst
+	}
+	threshold := cfg.BanThreshold
+	if threshold == 0 {
+		threshold = defaultBanThreshold
+	}
+	duration := defaultBanDuration
+	if cfg.BanDuration != "" {
+		d, err := time.ParseDuration(cfg.BanDuration)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("rate limit: invalid ban_duration %q", cfg.BanDuration)
+		}
+		duration = d
+	}
+
+	return &RateLimiter{
+		rate:         cfg.RequestsPerSecond,
+		burst:        float64(burst),
+		banThreshold: threshold,
+		banDuration:  duration,
+		buckets:      make(map[string]*bucket),
+		bans:         make(map[string]Ban),
+		now:          time.Now,
+	}, nil
+}
+
+// Allow consumes one request from the budget of ip. It returns false and the
+// time to wait when the request must be rejected.
+func (l *RateLimiter) Allow(ip string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.pruneLocked(now)
+
+	if ban, ok := l.bans[ip]; ok {
+		if now.Before(ban.Expires) {
+			return false, ban.Expires.Sub(now)
+		}
+		delete(l.bans, ip)
+	}
+
+	b, ok := l.buckets[ip]
+	if !ok {
+		b = &bucket{tokens: l.burst, last: now}
+		l.buckets[ip] = b
+	}
+
+	b.tokens = math.Min(l.burst, b.tokens+now.Sub(b.last).Seconds()*l.rate)
+	b.last = now
+	if b.tokens >= 1 {
+		b.tokens--
+		if b.tokens >= l.burst-1 {
+			b.violations = 0
+		}
+		return true, 0
+	}
+
+	b.violations++
+	if l.banThreshold > 0 && b.violations >= l.banThreshold {
+		l.banLocked(ip, "rate limit exceeded", l.banDuration, now)
+		return false, l.banDuration
+	}
+	return false, time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+}
+
+// Ban blocks ip for duration, or for the configured ban duration when
+// duration is zero
+func (l *RateLimiter) Ban(ip, reason string, duration time.Duration) {
+	if duration <= 0 {
+		duration = l.banDuration
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.banLocked(ip, reason, duration, l.now())
+}
+
+// banLocked records a ban; l.mu must be held
+func (l *RateLimiter) banLocked(ip, reason string, duration time.Duration, now time.Time) {
+	l.bans[ip] = Ban{IP: ip, Reason: reason, Expires: now.Add(duration)}
+	delete(l.buckets, ip)
+	slog.Warn("webui.RateLimiter banned address", "ip", ip, "reason", reason, "duration", duration)
+}
+
+// Unban lifts the ban on ip and reports whether one existed
+func (l *RateLimiter) Unban(ip string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	_, ok := l.bans[ip]
+	delete(l.bans, ip)
+	return ok
+}
+
+// ClearBans lifts every ban and returns how many were removed
+func (l *RateLimiter) ClearBans() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	n := len(l.bans)
+	l.bans = make(map[string]Ban)
+	return n
+}
+
+// Bans returns the active bans ordered by address
+func (l *RateLimiter) Bans() []Ban {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	bans := make([]Ban, 0, len(l.bans))
+	for _, ban := range l.bans {
+		if now.Before(ban.Expires) {
+			bans = append(bans, ban)
+		}
+	}
+	sort.Slice(bans, func(i, j int) bool { return bans[i].IP < bans[j].IP })
+	return bans
+}
+
+// pruneLocked drops expired bans and buckets that have refilled; l.mu must
+// be held
+func (l *RateLimiter) pruneLocked(now time.Time) {
+	if now.Sub(l.lastPrune) < rateLimiterPrunePeriod {
+		return
+	}
+	l.lastPrune = now
+
+	for ip, ban := range l.bans {
+		if !now.Before(ban.Expires) {
+			delete(l.bans, ip)
+		}
+	}
+	for ip, b := range l.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*l.rate >= l.burst {
+			delete(l.buckets, ip)
+		}
+	}
+}
+
+// rejectRateLimited writes the response for a request refused by Allow
+func rejectRateLimited(rw http.ResponseWriter, retryAfter time.Duration) {
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+	rw.Header().Set("Retry-After", strconv.Itoa(seconds))
+	http.Error(rw, "Too many requests", http.StatusTooManyRequests)
+}
@@ -0,0 +1,185 @@
+package webui
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/opd-ai/go-gamelaunch-client/pkg/dgclient"
+)
+
+// newTestRateLimiter creates a limiter driven by a manual clock
+func newTestRateLimiter(t *testing.T, cfg RateLimitConfig) (*RateLimiter, *time.Time) {
+	t.Helper()
+
+	limiter, err := NewRateLimiter(cfg)
+	if err != nil {
+		t.Fatalf("NewRateLimiter failed: %v", err)
+	}
+	now := time.Unix(1000, 0)
+	limiter.now = func() time.Time { return now }
+	return limiter, &now
+}
+
+func TestNewRateLimiter_InvalidConfig(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  RateLimitConfig
+	}{
+		{"zero rate", RateLimitConfig{}},
+		{"bad duration", RateLimitConfig{RequestsPerSecond: 1, BanDuration: "soon"}},
+		{"negative duration", RateLimitConfig{RequestsPerSecond: 1, BanDuration: "-1m"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewRateLimiter(tt.cfg); err == nil {
+				t.Error("Expected configuration error")
+			}
+		})
+	}
+}
+
+func TestRateLimiter_Allow_TokenBucket(t *testing.T) {
+	limiter, now := newTestRateLimiter(t, RateLimitConfig{RequestsPerSecond: 2, Burst: 3, BanThreshold: -1})
+
+	for i := 0; i < 3; i++ {
+		if ok, _ := limiter.Allow("10.0.0.1"); !ok {
+			t.Fatalf("Request %d within burst was rejected", i)
+		}
+	}
+	ok, retryAfter := limiter.Allow("10.0.0.1")
+	if ok {
+		t.Fatal("Expected request over burst to be rejected")
+	}
+	if retryAfter <= 0 || retryAfter > time.Second {
+		t.Errorf("Unexpected retry-after %v", retryAfter)
+	}
+
+	// Other addresses have their own budget
+	if ok, _ := limiter.Allow("10.0.0.2"); !ok {
+		t.Error("Expected independent budget for another address")
+	}
+
+	// Half a second refills one token at 2 req/s
+	*now = now.Add(500 * time.Millisecond)
+	if ok, _ := limiter.Allow("10.0.0.1"); !ok {
+		t.Error("Expected request after refill to be allowed")
+	}
+}
+
+func TestRateLimiter_BansRepeatOffenders(t *testing.T) {
+	limiter, now := newTestRateLimiter(t, RateLimitConfig{RequestsPerSecond: 1, Burst: 1, BanThreshold: 3, BanDuration: "1m"})
+
+	limiter.Allow("10.0.0.1")
+	for i := 0; i < 3; i++ {
+		limiter.Allow("10.0.0.1")
+	}
+
+	bans := limiter.Bans()
+	if len(bans) != 1 || bans[0].IP != "10.0.0.1" {
+		t.Fatalf("Expected 10.0.0.1 to be banned, got %+v", bans)
+	}
+
+	// Banned even after the bucket would have refilled
+	*now = now.Add(30 * time.Second)
+	if ok, retryAfter := limiter.Allow("10.0.0.1"); ok || retryAfter != 30*time.Second {
+		t.Errorf("Expected ban with 30s remaining, got ok=%v retry=%v", ok, retryAfter)
+	}
+
+	// Ban expires
+	*now = now.Add(31 * time.Second)
+	if ok, _ := limiter.Allow("10.0.0.1"); !ok {
+		t.Error("Expected request after ban expiry to be allowed")
+	}
+	if len(limiter.Bans()) != 0 {
+		t.Error("Expected no active bans")
+	}
+}
+
+func TestRateLimiter_ManualBans(t *testing.T) {
+	limiter, _ := newTestRateLimiter(t, RateLimitConfig{RequestsPerSecond: 1})
+
+	limiter.Ban("10.0.0.1", "abuse", 0)
+	limiter.Ban("10.0.0.2", "abuse", time.Hour)
+	if ok, _ := limiter.Allow("10.0.0.1"); ok {
+		t.Error("Expected banned address to be rejected")
+	}
+
+	if !limiter.Unban("10.0.0.1") {
+		t.Error("Expected Unban to report an existing ban")
+	}
+	if limiter.Unban("10.0.0.1") {
+		t.Error("Expected second Unban to report no ban")
+	}
+	if n := limiter.ClearBans(); n != 1 {
+		t.Errorf("Expected ClearBans to remove 1 ban, removed %d", n)
+	}
+}
+
+func TestWebUI_RateLimit(t *testing.T) {
+	view, err := NewWebView(dgclient.ViewOptions{InitialWidth: 80, InitialHeight: 24})
+	if err != nil {
+		t.Fatalf("Failed to create WebView: %v", err)
+	}
+	ui, err := NewWebUI(WebUIOptions{
+		View:         view,
+		RateLimit:    &RateLimitConfig{RequestsPerSecond: 0.001, Burst: 2},
+		StaticTokens: adminTokens,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create WebUI: %v", err)
+	}
+
+	request := func(method, target, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.RemoteAddr = "192.0.2.1:4000"
+		rr := httptest.NewRecorder()
+		ui.ServeHTTP(rr, req)
+		return rr
+	}
+
+	request("GET", "/version", "")
+	request("GET", "/version", "")
+	rr := request("GET", "/version", "")
+	if rr.Code != http.StatusTooManyRequests {
+		t.Fatalf("Expected 429, got %d", rr.Code)
+	}
+	if rr.Header().Get("Retry-After") == "" {
+		t.Error("Expected Retry-After header")
+	}
+
+	// Administer bans from fresh addresses so the budget is not a factor
+	adminRequests := 0
+	admin := func(method, target, body string) *httptest.ResponseRecorder {
+		adminRequests++
+		req := newAdminRequest(method, target, strings.NewReader(body))
+		req.RemoteAddr = fmt.Sprintf("198.51.100.%d:4000", adminRequests)
+		rr := httptest.NewRecorder()
+		ui.ServeHTTP(rr, req)
+		return rr
+	}
+
+	if rr := admin("POST", "/admin/bans", `{"ip":"203.0.113.5","duration":"1h"}`); rr.Code != http.StatusOK {
+		t.Fatalf("Ban failed with %d: %s", rr.Code, rr.Body.String())
+	}
+	if rr := admin("POST", "/admin/bans", `{"ip":"nope"}`); rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for invalid ip, got %d", rr.Code)
+	}
+
+	rr = admin("DELETE", "/admin/bans?ip=203.0.113.5", "")
+	var result struct {
+		Removed int `json:"removed"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &result); err != nil || result.Removed != 1 {
+		t.Errorf("Expected one ban removed, got %s", rr.Body.String())
+	}
+
+	if rr := admin("PUT", "/admin/bans", ""); rr.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected 405, got %d", rr.Code)
+	}
+}
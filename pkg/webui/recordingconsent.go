@@ -0,0 +1,57 @@
+// Package webui provides per-session consent to recording.
+package webui
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/opd-ai/go-gamelaunch-www/pkg/i18n"
+)
+
+// RecordingConsent reports or changes whether the session is recorded
+type RecordingConsent struct {
+	Enabled bool `json:"enabled"`
+}
+
+// handleSessionRecording serves GET /session/recording, reporting whether
+// the session is recorded, and POST /session/recording with
+// {"enabled": bool}, opting in or out. Opting out deletes the recording of
+// the game in progress.
+func (w *WebUI) handleSessionRecording(rw http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(rw, http.StatusOK, RecordingConsent{Enabled: w.recorder.Enabled()})
+
+	case http.MethodPost:
+		var consent RecordingConsent
+		if err := json.NewDecoder(http.MaxBytesReader(rw, r.Body, 4096)).Decode(&consent); err != nil {
+			http.Error(rw, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		changed := w.recorder.Enabled() != consent.Enabled
+		if err := w.recorder.SetEnabled(consent.Enabled); err != nil {
+			w.logger(r.Context()).Error("webui: failed to change recording consent", "error", err)
+			http.Error(rw, "Failed to change recording", http.StatusInternalServerError)
+			return
+		}
+
+		if changed {
+			subject := ""
+			if identity := IdentityFromContext(r.Context()); identity != nil {
+				subject = identity.Subject
+			}
+			w.logger(r.Context()).Info("webui: recording consent changed", "session", w.sessionName(), "enabled", consent.Enabled, "subject", subject)
+
+			if consent.Enabled {
+				w.broadcastNotice("info", i18n.MsgRecordingStarted)
+			} else {
+				w.broadcastNotice("info", i18n.MsgRecordingStopped)
+			}
+		}
+		writeJSON(rw, http.StatusOK, consent)
+
+	default:
+		rw.Header().Set("Allow", "GET, POST")
+		http.Error(rw, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
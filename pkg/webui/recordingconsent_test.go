@@ -0,0 +1,122 @@
+package webui
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/opd-ai/go-gamelaunch-www/pkg/recording"
+)
+
+// newConsentWebUI creates a WebUI recording to an opt-in store
+func newConsentWebUI(t *testing.T, record *bool) (*WebUI, *recording.Store) {
+	t.Helper()
+	store, err := recording.Open(recording.Config{Dir: t.TempDir(), OptIn: true})
+	if err != nil {
+		t.Fatalf("recording.Open failed: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	webUI, err := NewWebUI(WebUIOptions{
+		View:          newTestView(t),
+		Recordings:    store,
+		Player:        "alice",
+		RecordSession: record,
+	})
+	if err != nil {
+		t.Fatalf("NewWebUI failed: %v", err)
+	}
+	return webUI, store
+}
+
+func TestWebUI_handleSessionRecording_OptsIn(t *testing.T) {
+	webUI, store := newConsentWebUI(t, nil)
+
+	rec := httptest.NewRecorder()
+	webUI.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/session/recording", nil))
+	if rec.Code != http.StatusOK || strings.TrimSpace(rec.Body.String()) != `{"enabled":false}` {
+		t.Fatalf("GET /session/recording = %d %s, want disabled", rec.Code, rec.Body.String())
+	}
+
+	webUI.GetView().Render([]byte("not recorded"))
+	rec = httptest.NewRecorder()
+	webUI.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/session/recording", strings.NewReader(`{"enabled":true}`)))
+	if rec.Code != http.StatusOK || !webUI.recorder.Enabled() {
+		t.Fatalf("POST /session/recording = %d %s, want recording enabled", rec.Code, rec.Body.String())
+	}
+	webUI.GetView().Render([]byte("recorded"))
+
+	recordings, err := store.Query(context.Background(), recording.Filter{Player: "alice"})
+	if err != nil || len(recordings) != 1 {
+		t.Errorf("recordings = %+v, %v; want one after opting in", recordings, err)
+	}
+}
+
+func TestWebUI_handleSessionRecording_RejectsInvalidRequests(t *testing.T) {
+	webUI, _ := newConsentWebUI(t, nil)
+	tests := []struct {
+		method, body string
+		want         int
+	}{
+		{http.MethodPost, "enabled", http.StatusBadRequest},
+		{http.MethodDelete, "", http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		webUI.ServeHTTP(rec, httptest.NewRequest(tt.method, "/session/recording", strings.NewReader(tt.body)))
+		if rec.Code != tt.want {
+			t.Errorf("%s /session/recording = %d, want %d", tt.method, rec.Code, tt.want)
+		}
+	}
+}
+
+func TestNewWebUI_RecordSession_OverridesOptIn(t *testing.T) {
+	record := true
+	if webUI, _ := newConsentWebUI(t, &record); !webUI.recorder.Enabled() {
+		t.Error("RecordSession = true did not enable recording")
+	}
+
+	policy, err := newAuthzPolicy(nil)
+	if err != nil {
+		t.Fatalf("newAuthzPolicy failed: %v", err)
+	}
+	if got := policy.required(routeMethod("/session/recording")); got != RolePlayer {
+		t.Errorf("session.recording requires %q, want player", got)
+	}
+}
+
+func TestWebUI_RecordingFile_WithholdConcealed(t *testing.T) {
+	store, err := recording.Open(recording.Config{Dir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("recording.Open failed: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	w, err := NewWebUI(WebUIOptions{
+		View:       newTestView(t),
+		Recordings: store,
+		StaticTokens: []StaticToken{
+			{Name: "watcher", Token: spectatorToken, Role: "spectator"},
+			{Name: "hero", Token: playerToken, Role: "player"},
+		},
+		WithholdConcealed: true,
+	})
+	if err != nil {
+		t.Fatalf("NewWebUI failed: %v", err)
+	}
+
+	// Raw recordings hold concealed text, so spectators cannot download them
+	for _, method := range []string{http.MethodGet, http.MethodHead} {
+		for token, forbidden := range map[string]bool{spectatorToken: true, playerToken: false} {
+			req := httptest.NewRequest(method, "/recordings/file?id=1", nil)
+			req.Header.Set("Authorization", "Bearer "+token)
+			req.Header.Set("Range", "bytes=0-10")
+			rec := httptest.NewRecorder()
+			w.ServeHTTP(rec, req)
+			if (rec.Code == http.StatusForbidden) != forbidden {
+				t.Errorf("%s /recordings/file with %s token = %d", method, token, rec.Code)
+			}
+		}
+	}
+}
@@ -0,0 +1,91 @@
+// Package webui provides the parser plugins that pause recordings on menus.
+package webui
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// PauseParser is a parser plugin that recognizes screens not worth
+// recording, such as the dgamelaunch menus a session idles in between games
+type PauseParser interface {
+	// Pause reports whether lines show such a screen
+	Pause(lines []string) bool
+}
+
+// PauseParserFunc adapts a function to PauseParser
+type PauseParserFunc func(lines []string) bool
+
+// Pause implements PauseParser
+func (f PauseParserFunc) Pause(lines []string) bool {
+	return f(lines)
+}
+
+var (
+	pauseParsersMu sync.RWMutex
+	pauseParsers   = map[string]PauseParser{
+		"dgamelaunch": PauseParserFunc(isDgamelaunchMenu),
+	}
+)
+
+// RegisterPauseParser makes a pause parser available to recordings under
+// name, replacing any parser registered with the same name
+func RegisterPauseParser(name string, parser PauseParser) {
+	pauseParsersMu.Lock()
+	defer pauseParsersMu.Unlock()
+	pauseParsers[name] = parser
+}
+
+// PauseParsers returns the names of the registered pause parsers
+func PauseParsers() []string {
+	pauseParsersMu.RLock()
+	defer pauseParsersMu.RUnlock()
+	return slices.Sorted(maps.Keys(pauseParsers))
+}
+
+// lookupPauseParsers returns the pause parsers registered under names
+func lookupPauseParsers(names []string) ([]PauseParser, error) {
+	pauseParsersMu.RLock()
+	defer pauseParsersMu.RUnlock()
+	parsers := make([]PauseParser, 0, len(names))
+	for _, name := range names {
+		parser, ok := pauseParsers[name]
+		if !ok {
+			return nil, fmt.Errorf("recordings: unknown pause parser %q", name)
+		}
+		parsers = append(parsers, parser)
+	}
+	return parsers, nil
+}
+
+// isDgamelaunchMenu recognizes the login, main and watch menus of
+// dgamelaunch
+func isDgamelaunchMenu(lines []string) bool {
+	for _, line := range lines {
+		if strings.Contains(strings.ToLower(line), "## dgamelaunch") {
+			return true
+		}
+	}
+	if _, ok := ParseWatchMenu(lines); ok {
+		return true
+	}
+	return len(ParseGameMenu(lines)) > 0
+}
+
+// recordScreen records the output data unless the screen it produced on
+// view is one that a pause parser recognizes. The first output recorded
+// after a skipped stretch is replaced by a redraw of view. It runs as a
+// screen tap, with view locked.
+func (w *WebUI) recordScreen(view *WebView, data []byte, state *GameState) {
+	lines := stateLines(state)
+	for _, parser := range w.pauseParsers {
+		if parser.Pause(lines) {
+			w.recorder.Skip(data)
+			return
+		}
+	}
+	w.recorder.WriteScreen(data, view.redraw)
+}
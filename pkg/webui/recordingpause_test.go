@@ -0,0 +1,129 @@
+package webui
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/opd-ai/go-gamelaunch-www/pkg/recording"
+	"github.com/opd-ai/go-gamelaunch-www/pkg/ttyrec"
+)
+
+func TestIsDgamelaunchMenu(t *testing.T) {
+	menu := strings.Split(strings.ReplaceAll(testGameMenu, "\r", ""), "\n")
+	if !isDgamelaunchMenu(menu) {
+		t.Error("main menu not recognized")
+	}
+	if !isDgamelaunchMenu([]string{" The following games are in progress:", " a) alice  NetHack"}) {
+		t.Error("watch menu not recognized")
+	}
+	if isDgamelaunchMenu([]string{"Hello alice, welcome to NetHack!", "", "Dlvl:1 $:0 HP:12(12) T:1"}) {
+		t.Error("game screen recognized as a menu")
+	}
+}
+
+func TestWebUI_RecordingPausesOnMenus(t *testing.T) {
+	store, err := recording.Open(recording.Config{Dir: t.TempDir(), PauseOn: []string{"dgamelaunch"}})
+	if err != nil {
+		t.Fatalf("recording.Open failed: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	view := newTestView(t)
+	webUI, err := NewWebUI(WebUIOptions{View: view, Recordings: store, Player: "alice"})
+	if err != nil {
+		t.Fatalf("NewWebUI failed: %v", err)
+	}
+
+	view.Render([]byte(testGameMenu))
+	view.Render([]byte("p"))
+	view.Render([]byte("\x1b[2J\x1b[HHello alice, welcome to NetHack!"))
+	view.Render([]byte("\r\nYou see here a scroll."))
+	view.Render([]byte(testGameMenu))
+	webUI.finishRecording()
+
+	recordings, err := store.Query(context.Background(), recording.Filter{Player: "alice"})
+	if err != nil || len(recordings) != 1 {
+		t.Fatalf("recordings = %+v, %v; want one", recordings, err)
+	}
+	data, err := os.ReadFile(store.File(&recordings[0]))
+	if err != nil {
+		t.Fatalf("ReadFile failed: %v", err)
+	}
+	frames, err := ttyrec.ReadAll(bytes.NewReader(data))
+	if err != nil || len(frames) != 2 || !strings.Contains(string(frames[0].Data), "welcome to NetHack") {
+		t.Errorf("frames = %q, %v; want only the game", frames, err)
+	}
+}
+
+func TestWebUI_RecordingRedrawsAfterPause(t *testing.T) {
+	store, err := recording.Open(recording.Config{Dir: t.TempDir(), PauseOn: []string{"dgamelaunch"}})
+	if err != nil {
+		t.Fatalf("recording.Open failed: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	view := newTestView(t)
+	webUI, err := NewWebUI(WebUIOptions{View: view, Recordings: store, Player: "alice"})
+	if err != nil {
+		t.Fatalf("NewWebUI failed: %v", err)
+	}
+
+	// The menu switches to the alternate screen and line drawing, which
+	// the game's output relies on
+	view.Render([]byte("\x1b[?1049h" + testGameMenu + "\x1b(0"))
+	view.Render([]byte("\x1b[2J\x1b[5;1H\x1b[32mqqqq\x1b[1;1H"))
+	view.Render([]byte("lqk"))
+	webUI.finishRecording()
+
+	recordings, err := store.Query(context.Background(), recording.Filter{Player: "alice"})
+	if err != nil || len(recordings) != 1 {
+		t.Fatalf("recordings = %+v, %v; want one", recordings, err)
+	}
+	data, err := os.ReadFile(store.File(&recordings[0]))
+	if err != nil {
+		t.Fatalf("ReadFile failed: %v", err)
+	}
+	frames, err := ttyrec.ReadAll(bytes.NewReader(data))
+	if err != nil || len(frames) != 2 {
+		t.Fatalf("frames = %q, %v; want a redraw and the game", frames, err)
+	}
+
+	// Played back, the recording ends on the live screen
+	replayed := newTestView(t)
+	for _, frame := range frames {
+		replayed.Render(frame.Data)
+	}
+	want, got := view.GetCurrentState(), replayed.GetCurrentState()
+	if gotLines, wantLines := stateLines(got), stateLines(want); !slices.Equal(gotLines, wantLines) {
+		t.Errorf("replayed screen = %q, want %q", gotLines, wantLines)
+	}
+	if got.Buffer[4][0].FgColor != want.Buffer[4][0].FgColor {
+		t.Errorf("replayed color = %s, want %s", got.Buffer[4][0].FgColor, want.Buffer[4][0].FgColor)
+	}
+	if !replayed.modes.altScreen {
+		t.Error("replay left the alternate screen the menu switched to")
+	}
+}
+
+func TestNewWebUI_UnknownPauseParser(t *testing.T) {
+	store, err := recording.Open(recording.Config{Dir: t.TempDir(), PauseOn: []string{"lobby"}})
+	if err != nil {
+		t.Fatalf("recording.Open failed: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	if _, err := NewWebUI(WebUIOptions{View: newTestView(t), Recordings: store}); err == nil {
+		t.Error("NewWebUI accepted an unknown pause parser")
+	}
+	RegisterPauseParser("lobby", PauseParserFunc(func(lines []string) bool { return false }))
+	if !slices.Contains(PauseParsers(), "lobby") {
+		t.Errorf("PauseParsers = %v, want lobby registered", PauseParsers())
+	}
+	if _, err := NewWebUI(WebUIOptions{View: newTestView(t), Recordings: store}); err != nil {
+		t.Errorf("NewWebUI failed with a registered pause parser: %v", err)
+	}
+}
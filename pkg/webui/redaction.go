@@ -0,0 +1,79 @@
+// Package webui provides detection of secret-entry prompts so that credentials
+// typed into the game never reach logs, audit trails or recordings.
+package webui
+
+import (
+	"fmt"
+	"strings"
+)
+
+// secretPromptKeywords are matched case-insensitively on the cursor line and
+// the line above it to recognise password entry prompts such as dgamelaunch's
+// "Please enter your password." followed by a "=>" prompt.
+var secretPromptKeywords = []string{"password", "passphrase", "passwd"}
+
+// IsSecretInput reports whether the game is currently waiting for secret
+// input such as a password, in which case typed input must be redacted.
+func (v *WebView) IsSecretInput() bool {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+
+	return v.secretPrompt
+}
+
+// RedactInput returns a loggable representation of input, hiding its
+// content when the game is at a secret prompt.
+func (v *WebView) RedactInput(data []byte) string {
+	if v.IsSecretInput() {
+		return redactedInput(data)
+	}
+	return fmt.Sprintf("%q", data)
+}
+
+// redactedInput describes input without revealing its content
+func redactedInput(data []byte) string {
+	return fmt.Sprintf("[redacted %d bytes]", len(data))
+}
+
+// detectSecretPrompt inspects the screen around the cursor for a password
+// prompt. The cursor line must end in a prompt character and either it or
+// the line above must mention a password keyword.
+func (v *WebView) detectSecretPrompt() bool {
+	if v.cursorY < 0 || v.cursorY >= v.height {
+		return false
+	}
+
+	promptLine := strings.TrimRight(v.rowText(v.cursorY, v.cursorX), " ")
+	if promptLine == "" {
+		return false
+	}
+	switch promptLine[len(promptLine)-1] {
+	case ':', '>', '?':
+	default:
+		return false
+	}
+
+	context := strings.ToLower(promptLine)
+	if v.cursorY > 0 {
+		context = strings.ToLower(v.rowText(v.cursorY-1, v.width)) + "\n" + context
+	}
+	for _, keyword := range secretPromptKeywords {
+		if strings.Contains(context, keyword) {
+			return true
+		}
+	}
+	return false
+}
+
+// rowText returns the characters of row y up to (but excluding) column end
+func (v *WebView) rowText(y, end int) string {
+	if end > v.width {
+		end = v.width
+	}
+
+	var sb strings.Builder
+	for x := 0; x < end; x++ {
+		sb.WriteString(v.buffer[y][x].Grapheme())
+	}
+	return sb.String()
+}
@@ -0,0 +1,59 @@
+package webui
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/opd-ai/go-gamelaunch-client/pkg/dgclient"
+)
+
+func TestWebView_DetectsSecretPrompts(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		secret bool
+	}{
+		{"PlainPasswordPrompt", "Password: ", true},
+		{"DgamelaunchPrompt", "Please enter your password. (blank entry aborts)\r\n => ", true},
+		{"PassphrasePrompt", "Enter passphrase for key:", true},
+		{"UsernamePrompt", "Please enter your username.\r\n => ", false},
+		{"GameOutput", "You see here a password scroll.", false},
+		{"MenuAfterLogin", "Please enter your password.\r\n\r\n\r\n l) Logout", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			view, err := NewWebView(dgclient.ViewOptions{InitialWidth: 80, InitialHeight: 24})
+			if err != nil {
+				t.Fatalf("Failed to create WebView: %v", err)
+			}
+			if err := view.Render([]byte(tt.output)); err != nil {
+				t.Fatalf("Render failed: %v", err)
+			}
+
+			if got := view.IsSecretInput(); got != tt.secret {
+				t.Errorf("IsSecretInput() = %v, want %v", got, tt.secret)
+			}
+		})
+	}
+}
+
+func TestWebView_RedactInput_HidesSecretInput(t *testing.T) {
+	view, err := NewWebView(dgclient.ViewOptions{InitialWidth: 80, InitialHeight: 24})
+	if err != nil {
+		t.Fatalf("Failed to create WebView: %v", err)
+	}
+
+	if got := view.RedactInput([]byte("hunter2")); !strings.Contains(got, "hunter2") {
+		t.Errorf("input outside a prompt should be visible, got %s", got)
+	}
+
+	view.Render([]byte("Password: "))
+	got := view.RedactInput([]byte("hunter2"))
+	if strings.Contains(got, "hunter2") {
+		t.Errorf("secret input leaked: %s", got)
+	}
+	if got != "[redacted 7 bytes]" {
+		t.Errorf("RedactInput() = %q, want %q", got, "[redacted 7 bytes]")
+	}
+}
@@ -0,0 +1,129 @@
+// Package webui provides redraws of the terminal: output that rebuilds the
+// screen and modes of a view on a terminal in any state, for replays and
+// recordings that do not start at the beginning of the session.
+package webui
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+)
+
+// redraw returns output that resets a terminal and draws the screen of v:
+// the alternate screen when shown, the cells, the modes, scroll region and
+// character sets, then the cursor and its attributes; v.mu must be held
+func (v *WebView) redraw() []byte {
+	var out bytes.Buffer
+	out.WriteString("\x1bc") // RIS
+	if v.modes.altScreen {
+		out.WriteString("\x1b[?1049h")
+	}
+	out.WriteString("\x1b[H\x1b[2J")
+
+	for y, row := range v.buffer {
+		fmt.Fprintf(&out, "\x1b[%dH", y+1)
+		pen := ""
+		for x := 0; x < len(row); x++ {
+			cell := row[x]
+			if sgr := cellSGR(cell); sgr != pen {
+				out.WriteString(sgr)
+				pen = sgr
+			}
+			switch {
+			case cell.Text != "":
+				out.WriteString(cell.Text)
+			case cell.Char == 0:
+				out.WriteByte(' ')
+			default:
+				out.WriteRune(cell.Char)
+			}
+			if cell.Wide {
+				x++ // the terminal fills the cell to its right
+			}
+		}
+	}
+	out.WriteString("\x1b[0m")
+
+	if v.modes.noAutowrap {
+		out.WriteString("\x1b[?7l")
+	}
+	if v.modes.appCursor {
+		out.WriteString("\x1b[?1h")
+	}
+	if v.modes.appKeypad {
+		out.WriteString("\x1b=")
+	}
+	if v.scrollTop != 0 || v.scrollBottom != v.height-1 {
+		fmt.Fprintf(&out, "\x1b[%d;%dr", v.scrollTop+1, v.scrollBottom+1)
+	}
+	for i, g := range v.charset.g {
+		if g != 0 {
+			out.WriteString("\x1b" + "()"[i:i+1] + string(g))
+		}
+	}
+	if v.charset.shifted {
+		out.WriteByte(ctrlSO)
+	}
+
+	fmt.Fprintf(&out, "\x1b[%d;%dH", v.cursorY+1, min(v.cursorX, v.width-1)+1)
+	out.WriteString(cellSGR(Cell{
+		FgColor:        v.currentFgColor,
+		BgColor:        v.currentBgColor,
+		Bold:           v.currentBold,
+		Inverse:        v.currentInverse,
+		Blink:          v.currentBlink,
+		Underline:      v.currentAttrs.underline,
+		UnderlineColor: v.currentAttrs.underlineColor,
+		Overline:       v.currentAttrs.overline,
+		Concealed:      v.currentAttrs.concealed,
+	}))
+	return out.Bytes()
+}
+
+// cellSGR returns the SGR sequence, starting from a reset, that draws text
+// with the colors and attributes of cell
+func cellSGR(cell Cell) string {
+	sgr := []byte("\x1b[0")
+	for _, attr := range []struct {
+		set  bool
+		code string
+	}{
+		{cell.Bold, "1"},
+		{cell.Blink, "5"},
+		{cell.Inverse, "7"},
+		{cell.Concealed, "8"},
+		{cell.Overline, "53"},
+	} {
+		if attr.set {
+			sgr = append(sgr, ';')
+			sgr = append(sgr, attr.code...)
+		}
+	}
+	switch cell.Underline {
+	case "":
+	case UnderlineSingle:
+		sgr = append(sgr, ";4"...)
+	default:
+		for style, name := range underlineStyles {
+			if name == cell.Underline {
+				sgr = append(sgr, ";4:"...)
+				sgr = strconv.AppendInt(sgr, int64(style), 10)
+			}
+		}
+	}
+	for _, color := range []struct {
+		code, hex, fallback string
+	}{
+		{"38", cell.FgColor, "#FFFFFF"},
+		{"48", cell.BgColor, "#000000"},
+		{"58", cell.UnderlineColor, ""},
+	} {
+		if color.hex == color.fallback {
+			continue
+		}
+		if r, g, b, ok := parseHexColor(color.hex); ok {
+			sgr = fmt.Appendf(sgr, ";%s;2;%d;%d;%d", color.code, int(r), int(g), int(b))
+		}
+	}
+	return string(append(sgr, 'm'))
+}
@@ -0,0 +1,167 @@
+// Package webui provides protected screen regions that never scroll.
+package webui
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// PinnedRegion declares a band of rows that scrolling never moves, such as
+// the status lines at the bottom of a roguelike screen
+type PinnedRegion struct {
+	Name  string `yaml:"name" json:"name"`
+	Start int    `yaml:"start" json:"start"`                   // first row; negative counts from the bottom (-2 = second to last)
+	Rows  int    `yaml:"rows,omitempty" json:"rows,omitempty"` // default 1
+}
+
+// ScreenRegion is a pinned region resolved to absolute rows for the current
+// screen size; Top and Bottom are inclusive
+type ScreenRegion struct {
+	Name   string `json:"name"`
+	Top    int    `json:"top"`
+	Bottom int    `json:"bottom"`
+}
+
+// Names of the regions reported for margins set by the game with DECSTBM
+const (
+	RegionMarginTop    = "margin_top"
+	RegionMarginBottom = "margin_bottom"
+)
+
+// resolve returns the absolute rows of the region for a screen height; ok is
+// false when the region lies entirely off screen
+func (r PinnedRegion) resolve(height int) (ScreenRegion, bool) {
+	rows := r.Rows
+	if rows <= 0 {
+		rows = 1
+	}
+	top := r.Start
+	if top < 0 {
+		top += height
+	}
+	bottom := top + rows - 1
+	if top < 0 {
+		top = 0
+	}
+	if bottom >= height {
+		bottom = height - 1
+	}
+	if top > bottom {
+		return ScreenRegion{}, false
+	}
+	return ScreenRegion{Name: r.Name, Top: top, Bottom: bottom}, true
+}
+
+// SetProtectedRegions replaces the regions that scrolling must leave in
+// place
+func (v *WebView) SetProtectedRegions(regions []PinnedRegion) error {
+	for _, region := range regions {
+		if region.Rows < 0 {
+			return fmt.Errorf("protected region %q: rows must not be negative", region.Name)
+		}
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	v.protected = append([]PinnedRegion(nil), regions...)
+	return nil
+}
+
+// pinnedRegions returns the configured regions and any margins outside the
+// game's scroll region, resolved for the current size
+func (v *WebView) pinnedRegions() []ScreenRegion {
+	var regions []ScreenRegion
+	for _, region := range v.protected {
+		if resolved, ok := region.resolve(v.height); ok {
+			regions = append(regions, resolved)
+		}
+	}
+	if v.scrollTop > 0 {
+		regions = append(regions, ScreenRegion{Name: RegionMarginTop, Top: 0, Bottom: v.scrollTop - 1})
+	}
+	if v.scrollBottom < v.height-1 {
+		regions = append(regions, ScreenRegion{Name: RegionMarginBottom, Top: v.scrollBottom + 1, Bottom: v.height - 1})
+	}
+	return regions
+}
+
+// scrollableRows returns the rows inside the scroll region that are not
+// protected, top to bottom
+func (v *WebView) scrollableRows() []int {
+	pinned := make([]bool, v.height)
+	for _, region := range v.protected {
+		if resolved, ok := region.resolve(v.height); ok {
+			for y := resolved.Top; y <= resolved.Bottom; y++ {
+				pinned[y] = true
+			}
+		}
+	}
+
+	rows := make([]int, 0, v.height)
+	for y := v.scrollTop; y <= v.scrollBottom && y < v.height; y++ {
+		if !pinned[y] {
+			rows = append(rows, y)
+		}
+	}
+	return rows
+}
+
+// resetScrollRegion makes the whole screen the scroll region
+func (v *WebView) resetScrollRegion() {
+	v.scrollTop = 0
+	v.scrollBottom = v.height - 1
+}
+
+// handleSetScrollRegion processes DECSTBM (ESC[top;bottom r), which games
+// use to keep status lines out of the scrolling area
+func (v *WebView) handleSetScrollRegion(seq string) {
+	params := strings.Split(seq[2:len(seq)-1], ";")
+	top, bottom := 1, v.height
+	if len(params) > 0 && params[0] != "" {
+		if n, err := strconv.Atoi(params[0]); err == nil && n > 0 {
+			top = n
+		}
+	}
+	if len(params) > 1 && params[1] != "" {
+		if n, err := strconv.Atoi(params[1]); err == nil && n > 0 {
+			bottom = n
+		}
+	}
+	if bottom > v.height {
+		bottom = v.height
+	}
+	if top >= bottom {
+		return // invalid regions are ignored, as in xterm
+	}
+
+	v.scrollTop = top - 1
+	v.scrollBottom = bottom - 1
+	v.cursorX = 0
+	v.cursorY = 0
+}
+
+// lineFeed moves the cursor down a line, scrolling when it is at the bottom
+// of the scroll region
+func (v *WebView) lineFeed() {
+	if v.cursorY == v.scrollBottom {
+		v.scrollUp()
+		return
+	}
+	if v.cursorY < v.height-1 {
+		v.cursorY++
+	}
+}
+
+// reverseLineFeed moves the cursor up a line, scrolling when it is at the
+// top of the scroll region
+func (v *WebView) reverseLineFeed() {
+	if v.cursorY == v.scrollTop {
+		v.scrollDown()
+		return
+	}
+	if v.cursorY > 0 {
+		v.cursorY--
+	}
+}
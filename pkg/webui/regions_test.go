@@ -0,0 +1,115 @@
+package webui
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/opd-ai/go-gamelaunch-client/pkg/dgclient"
+)
+
+// newRegionTestView creates a small view with rows labelled by their index
+func newRegionTestView(t *testing.T, height int) *WebView {
+	t.Helper()
+
+	view, err := NewWebView(dgclient.ViewOptions{InitialWidth: 4, InitialHeight: height})
+	if err != nil {
+		t.Fatalf("NewWebView failed: %v", err)
+	}
+	for y := 0; y < height; y++ {
+		view.buffer[y][0].Char = rune('0' + y)
+	}
+	return view
+}
+
+// rowLabels returns the first character of every row
+func rowLabels(view *WebView) string {
+	labels := make([]rune, view.height)
+	for y := range labels {
+		labels[y] = view.buffer[y][0].Char
+	}
+	return string(labels)
+}
+
+func TestPinnedRegion_Resolve(t *testing.T) {
+	tests := []struct {
+		name   string
+		region PinnedRegion
+		want   ScreenRegion
+		wantOK bool
+	}{
+		{"BottomTwoRows", PinnedRegion{Name: "status", Start: -2, Rows: 2}, ScreenRegion{Name: "status", Top: 22, Bottom: 23}, true},
+		{"TopRowDefaultSize", PinnedRegion{Name: "messages"}, ScreenRegion{Name: "messages", Top: 0, Bottom: 0}, true},
+		{"ClippedAtBottom", PinnedRegion{Start: 23, Rows: 5}, ScreenRegion{Top: 23, Bottom: 23}, true},
+		{"OffScreen", PinnedRegion{Start: 30}, ScreenRegion{}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := tt.region.resolve(24)
+			if ok != tt.wantOK || got != tt.want {
+				t.Errorf("resolve() = %+v, %v; want %+v, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestWebView_ScrollUp_KeepsProtectedRows(t *testing.T) {
+	view := newRegionTestView(t, 6)
+	if err := view.SetProtectedRegions([]PinnedRegion{{Name: "status", Start: -2, Rows: 2}}); err != nil {
+		t.Fatalf("SetProtectedRegions failed: %v", err)
+	}
+
+	view.cursorY = 5
+	view.processTerminalData([]byte("\n"))
+
+	if got := rowLabels(view); got != "123 45" {
+		t.Errorf("Rows after scroll = %q, want %q", got, "123 45")
+	}
+
+	state := view.getCurrentState()
+	want := []ScreenRegion{{Name: "status", Top: 4, Bottom: 5}}
+	if !reflect.DeepEqual(state.Pinned, want) {
+		t.Errorf("Pinned = %+v, want %+v", state.Pinned, want)
+	}
+}
+
+func TestWebView_SetScrollRegion_ScrollsOnlyInsideMargins(t *testing.T) {
+	view := newRegionTestView(t, 6)
+
+	// Rows 2-4 (1-based) scroll; the cursor homes after DECSTBM
+	view.processTerminalData([]byte("\x1b[2;4r"))
+	if view.cursorX != 0 || view.cursorY != 0 {
+		t.Errorf("Cursor = (%d,%d), want home", view.cursorX, view.cursorY)
+	}
+
+	view.processTerminalData([]byte("\x1b[4;1H\n"))
+	if got := rowLabels(view); got != "023 45" {
+		t.Errorf("Rows after line feed = %q, want %q", got, "023 45")
+	}
+
+	view.processTerminalData([]byte("\x1b[2;1H\x1bM"))
+	if got := rowLabels(view); got != "0 2345" {
+		t.Errorf("Rows after reverse line feed = %q, want %q", got, "0 2345")
+	}
+
+	want := []ScreenRegion{
+		{Name: RegionMarginTop, Top: 0, Bottom: 0},
+		{Name: RegionMarginBottom, Top: 4, Bottom: 5},
+	}
+	if got := view.getCurrentState().Pinned; !reflect.DeepEqual(got, want) {
+		t.Errorf("Pinned = %+v, want %+v", got, want)
+	}
+
+	// Reset restores full-screen scrolling
+	view.processTerminalData([]byte("\x1b[r"))
+	if view.scrollTop != 0 || view.scrollBottom != 5 {
+		t.Errorf("Scroll region = %d-%d, want 0-5", view.scrollTop, view.scrollBottom)
+	}
+}
+
+func TestWebView_SetProtectedRegions_RejectsNegativeRows(t *testing.T) {
+	view := newRegionTestView(t, 6)
+	if err := view.SetProtectedRegions([]PinnedRegion{{Name: "bad", Rows: -1}}); err == nil {
+		t.Error("Expected error for negative rows")
+	}
+}
@@ -0,0 +1,188 @@
+// Package webui provides hot-swapping of the view, so a reconnected SSH
+// session can be shown without restarting the web server.
+package webui
+
+import (
+	"context"
+	"fmt"
+)
+
+// attachView applies the WebUI's configuration to view: logger, tileset,
+// terminal options, quota, scrollback, parser plugins, output taps and
+// filters, and the pending expiry
+func (w *WebUI) attachView(view *WebView) error {
+	opts := w.options
+	if opts.EscapePolicy != nil {
+		if err := view.SetEscapePolicy(*opts.EscapePolicy); err != nil {
+			return fmt.Errorf("failed to configure escape policy: %w", err)
+		}
+	}
+	if opts.DiffBudget != nil {
+		if err := view.GetStateManager().SetDiffBudget(*opts.DiffBudget); err != nil {
+			return fmt.Errorf("failed to configure diff budget: %w", err)
+		}
+	}
+	if opts.Keyframes != nil {
+		if err := view.GetStateManager().SetKeyframes(*opts.Keyframes); err != nil {
+			return fmt.Errorf("failed to configure keyframes: %w", err)
+		}
+	}
+	if len(opts.ProtectedRegions) > 0 {
+		if err := view.SetProtectedRegions(opts.ProtectedRegions); err != nil {
+			return fmt.Errorf("failed to configure protected regions: %w", err)
+		}
+	}
+	if w.compaction != nil {
+		if _, ok := view.GetStateManager().Store().(HistoryCompactor); !ok {
+			return fmt.Errorf("failed to configure history compaction: state store cannot compact its history")
+		}
+	}
+	var progress ProgressParser
+	if opts.Progress != nil {
+		parser, err := NewPatternProgressParser(*opts.Progress)
+		if err != nil {
+			return fmt.Errorf("failed to configure progress: %w", err)
+		}
+		progress = parser
+	}
+
+	view.SetLogger(w.log)
+	if served := w.tileset.Load(); served != nil {
+		view.setTileset(served.source, served.subset)
+	}
+	view.SetMaxCells(int(w.quota.memory / uint64(cellMemory)))
+	view.SetScrollback(w.scrollback)
+	if w.damage != nil {
+		view.GetStateManager().setDamageMap(w.damage)
+	}
+	if progress != nil {
+		view.AddProgressParser(progress)
+	}
+	for _, parser := range w.landmarks {
+		view.AddLandmarkParser(parser)
+	}
+	for _, filter := range w.outputFilters {
+		view.AddOutputFilter(filter)
+	}
+	if w.recorder != nil && len(w.pauseParsers) > 0 {
+		view.TapScreen(func(data []byte, state *GameState) {
+			w.recordScreen(view, data, state)
+		})
+	} else if w.recorder != nil {
+		view.TapOutput(w.recorder.Write)
+	}
+	if w.replay != nil {
+		view.TapOutput(w.replay.Write)
+		view.TapScreen(w.replayScreen.follow)
+	}
+	view.TapOutput(func(data []byte) {
+		w.bandwidth.gameReceived.Add(uint64(len(data)))
+	})
+	if expiry := w.expiry.current(); expiry != nil {
+		view.SetExpiry(expiry)
+	}
+	if broadcast := w.currentBroadcast(); broadcast != nil {
+		view.SetBroadcast(broadcast)
+	}
+	return nil
+}
+
+// ReplaceView installs view in place of the current one, e.g. after the SSH
+// session reconnected, without restarting the web server. The new view gets
+// the WebUI's configuration and current tileset; when that fails the
+// current view stays in place. Once the state stream of the current view
+// has stopped, every connected client receives the new screen as a
+// keyframe, and the state epoch changes with the new view's
+// state manager so pollers resynchronize. Background work such as state
+// streaming, thumbnails and score scraping follows the new view.
+//
+// The previous view is left open; the caller closes it once its game
+// client is done with it.
+func (w *WebUI) ReplaceView(view *WebView) error {
+	if view == nil {
+		return fmt.Errorf("view is required")
+	}
+
+	w.viewMu.Lock()
+	defer w.viewMu.Unlock()
+	previous := w.GetView()
+	if view == previous {
+		return fmt.Errorf("view is already in use")
+	}
+	if previous != nil && view.GetStateManager() == previous.GetStateManager() {
+		return fmt.Errorf("view shares the state manager of the current view")
+	}
+	if err := w.attachView(view); err != nil {
+		return err
+	}
+	w.view.Store(view)
+
+	// Stop the stream of the previous view, so none of its updates reach
+	// clients after the keyframe
+	close(w.viewSwap)
+	w.viewSwap = make(chan struct{})
+	if w.streamDone != nil {
+		<-w.streamDone
+		w.streamDone = nil
+	}
+
+	// Keyframe every client with the new screen, before the stream of the
+	// new view picks up from there
+	state := view.GetStateManager().GetCurrentState()
+	if state == nil {
+		state = view.GetCurrentState()
+	}
+	w.broadcastState(state)
+	w.keyframed = state.Version
+
+	view.Events().Record(EventViewReplaced, "view replaced (epoch %s, %d clients)", view.GetStateManager().Epoch(), w.wsHandler.GetClientCount())
+	w.log.Info("webui: view replaced", "epoch", view.GetStateManager().Epoch())
+	return nil
+}
+
+// activeView returns the view, a channel closed when ReplaceView swaps it,
+// the version of the keyframe ReplaceView sent for it, and a channel to
+// close once the state stream of the view stops, which ReplaceView waits
+// for
+func (w *WebUI) activeView() (*WebView, <-chan struct{}, uint64, chan struct{}) {
+	w.viewMu.Lock()
+	defer w.viewMu.Unlock()
+	w.streamDone = make(chan struct{})
+	return w.GetView(), w.viewSwap, w.keyframed, w.streamDone
+}
+
+// runViewLoops runs the background work bound to the view until ctx is
+// canceled, restarting it on the new view whenever ReplaceView swaps it
+func (w *WebUI) runViewLoops(ctx context.Context) {
+	view, swapped, _, streamDone := w.activeView()
+	version := view.GetStateManager().GetCurrentVersion()
+	for {
+		viewCtx, cancel := context.WithCancel(ctx)
+		go func(view *WebView, version uint64, done chan struct{}) {
+			defer close(done)
+			w.streamState(viewCtx, view, version)
+		}(view, version, streamDone)
+		w.watchScores(viewCtx, view)
+		if w.thumbnails != nil {
+			go w.thumbnails.run(viewCtx, view.GetStateManager())
+		}
+		if w.minimap != nil {
+			go w.minimap.run(viewCtx, view)
+		}
+		if w.compaction != nil {
+			go w.compaction.run(viewCtx, view.GetStateManager().Store().(HistoryCompactor))
+		}
+		if w.snapshots != nil {
+			go w.snapshots.run(viewCtx, view)
+		}
+
+		select {
+		case <-ctx.Done():
+			cancel()
+			return
+		case <-swapped:
+			cancel()
+		}
+		view, swapped, version, streamDone = w.activeView()
+	}
+}
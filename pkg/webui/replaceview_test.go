@@ -0,0 +1,186 @@
+package webui
+
+import (
+	"bytes"
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/opd-ai/go-gamelaunch-client/pkg/dgclient"
+	"nhooyr.io/websocket"
+)
+
+func TestWebUI_ReplaceView(t *testing.T) {
+	ui := newTestWebUI(t)
+	old := ui.GetView()
+	if err := ui.UpdateTileset(&TilesetConfig{Name: "swap"}); err != nil {
+		t.Fatalf("UpdateTileset failed: %v", err)
+	}
+	ui.AddOutputFilter(OutputFilterFunc(func(data []byte) []byte {
+		return bytes.ReplaceAll(data, []byte("secret"), []byte("******"))
+	}))
+	if err := old.Render([]byte("old screen")); err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	go ui.runViewLoops(ctx)
+
+	server := httptest.NewServer(ui)
+	defer server.Close()
+	conn, _, err := websocket.Dial(ctx, "ws"+strings.TrimPrefix(server.URL, "http")+"/ws", nil)
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	defer conn.Close(websocket.StatusNormalClosure, "")
+	conn.SetReadLimit(1 << 20)
+	if got := readState(t, ctx, conn).Buffer[0][0].Char; got != "o" {
+		t.Fatalf("initial cell (0,0) = %q, want %q", got, "o")
+	}
+
+	// The new view shows the reconnected session
+	view := newTestView(t)
+	if err := view.Render([]byte("new")); err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	oldEpoch := ui.GetSessionInfo().Epoch
+	if err := ui.ReplaceView(view); err != nil {
+		t.Fatalf("ReplaceView failed: %v", err)
+	}
+	if ui.GetView() != view {
+		t.Fatal("GetView did not return the new view")
+	}
+	if epoch := ui.GetSessionInfo().Epoch; epoch == oldEpoch {
+		t.Errorf("epoch %s did not change", epoch)
+	}
+	view.mu.RLock()
+	tileset := view.tileset
+	view.mu.RUnlock()
+	if tileset == nil || tileset.Name != "swap" {
+		t.Errorf("tileset = %+v, want the WebUI's", tileset)
+	}
+
+	// Connected clients receive a keyframe of the new screen at once
+	keyframe := readState(t, ctx, conn)
+	if got := keyframe.Buffer[0][0].Char + keyframe.Buffer[0][1].Char + keyframe.Buffer[0][3].Char; got != "ne " {
+		t.Errorf("keyframe starts %q, want %q", got, "ne ")
+	}
+
+	// Updates now come from the new view, through the WebUI's filters
+	if err := old.Render([]byte("!")); err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	if err := view.Render([]byte(" secret")); err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	update := readState(t, ctx, conn)
+	if update.Version <= keyframe.Version {
+		t.Errorf("update version %d not newer than %d", update.Version, keyframe.Version)
+	}
+	if got := update.Buffer[0][4].Char; got != "*" {
+		t.Errorf("cell (4,0) = %q, want the filtered %q", got, "*")
+	}
+
+	if events := view.Events().Events(EventViewReplaced, 0); len(events) != 1 {
+		t.Errorf("%s events = %+v, want one", EventViewReplaced, events)
+	}
+}
+
+func TestWebUI_ReplaceView_StopsOldStreamBeforeKeyframe(t *testing.T) {
+	ui := newTestWebUI(t)
+	old := ui.GetView()
+	if err := old.Render([]byte("o")); err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	go ui.runViewLoops(ctx)
+
+	server := httptest.NewServer(ui)
+	defer server.Close()
+	conn, _, err := websocket.Dial(ctx, "ws"+strings.TrimPrefix(server.URL, "http")+"/ws", nil)
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	defer conn.Close(websocket.StatusNormalClosure, "")
+	conn.SetReadLimit(1 << 20)
+	readState(t, ctx, conn)
+
+	// The old session keeps drawing while the view is replaced
+	stop := make(chan struct{})
+	drawing := make(chan struct{})
+	go func() {
+		defer close(drawing)
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				old.Render([]byte("\ro"))
+			}
+		}
+	}()
+	time.Sleep(10 * time.Millisecond)
+
+	view := newTestView(t)
+	if err := view.Render([]byte("n")); err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	if err := ui.ReplaceView(view); err != nil {
+		t.Fatalf("ReplaceView failed: %v", err)
+	}
+	close(stop)
+	<-drawing
+	if err := view.Render([]byte("ew")); err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+
+	// Once the new screen arrived, nothing of the old one follows
+	keyframed := false
+	for {
+		state := readState(t, ctx, conn)
+		first := state.Buffer[0][0].Char
+		switch {
+		case first == "n":
+			keyframed = true
+		case keyframed:
+			t.Fatalf("state %d from the old view after the keyframe", state.Version)
+		}
+		if state.Buffer[0][2].Char == "w" {
+			return
+		}
+	}
+}
+
+func TestWebUI_ReplaceView_Rejected(t *testing.T) {
+	ui := newTestWebUI(t)
+	current := ui.GetView()
+
+	if err := ui.ReplaceView(nil); err == nil {
+		t.Error("ReplaceView accepted a nil view")
+	}
+	if err := ui.ReplaceView(current); err == nil {
+		t.Error("ReplaceView accepted the current view")
+	}
+
+	// A view the configuration does not apply to leaves the current one
+	strict, err := NewWebUI(WebUIOptions{View: newTestView(t), HistoryCompaction: &HistoryCompactionConfig{}})
+	if err != nil {
+		t.Fatalf("NewWebUI failed: %v", err)
+	}
+	current = strict.GetView()
+	view, err := NewWebViewWithStore(dgclient.ViewOptions{}, struct{ StateStore }{NewMemoryStateStore(0)})
+	if err != nil {
+		t.Fatalf("NewWebViewWithStore failed: %v", err)
+	}
+	if err := strict.ReplaceView(view); err == nil {
+		t.Error("ReplaceView accepted a view whose store cannot compact")
+	}
+	if strict.GetView() != current {
+		t.Error("failed ReplaceView changed the view")
+	}
+}
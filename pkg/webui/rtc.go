@@ -0,0 +1,70 @@
+// Package webui provides an experimental WebRTC data channel transport,
+// negotiated over HTTP and backed by a pluggable WebRTC stack.
+package webui
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/opd-ai/go-gamelaunch-www/pkg/transport"
+)
+
+// rtcNegotiationTimeout bounds how long answering an offer may take
+const rtcNegotiationTimeout = 10 * time.Second
+
+// RTCSessionDescription is an SDP offer or answer, in the JSON form of the
+// browser's RTCSessionDescription
+type RTCSessionDescription struct {
+	Type string `json:"type"` // "offer" or "answer"
+	SDP  string `json:"sdp"`
+}
+
+// RTCNegotiator answers WebRTC offers with a data channel to the client.
+// pkg/rtc implements it with pion/webrtc.
+type RTCNegotiator interface {
+	// Answer accepts an offer and returns the SDP answer together with the
+	// data channel the client will open. Reads on the connection block until
+	// the channel is open; it must fail reads once the peer connection
+	// fails or closes.
+	Answer(ctx context.Context, offer RTCSessionDescription) (RTCSessionDescription, transport.MessageConn, error)
+}
+
+// handleRTCOffer serves POST /rtc/offer. The body is the client's offer; the
+// response is the server's answer, after which the client is served over the
+// data channel exactly as over /ws, including the protocol and view option
+// query parameters.
+func (w *WebUI) handleRTCOffer(rw http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		rw.Header().Set("Allow", http.MethodPost)
+		http.Error(rw, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var offer RTCSessionDescription
+	if err := json.NewDecoder(http.MaxBytesReader(rw, r.Body, 64<<10)).Decode(&offer); err != nil || offer.Type != "offer" || offer.SDP == "" {
+		http.Error(rw, "Invalid offer", http.StatusBadRequest)
+		return
+	}
+
+	admission, refusal := w.wsHandler.Admit(r)
+	if refusal != nil {
+		refusal.WriteHTTP(rw)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), rtcNegotiationTimeout)
+	defer cancel()
+	answer, conn, err := w.options.RTC.Answer(ctx, offer)
+	if err != nil {
+		admission.Release()
+		w.logger(r.Context()).Warn("webui: WebRTC negotiation failed", "remote", transport.RemoteIP(r), "error", err)
+		http.Error(rw, "WebRTC negotiation failed", http.StatusBadRequest)
+		return
+	}
+
+	// The client outlives this request but keeps its identity
+	go admission.Serve(context.WithoutCancel(r.Context()), conn)
+	writeJSON(rw, http.StatusOK, answer)
+}
@@ -0,0 +1,112 @@
+package webui
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/opd-ai/go-gamelaunch-www/pkg/transport"
+)
+
+// rtcTestConn is a data channel that stays open until closed
+type rtcTestConn struct {
+	closed chan struct{}
+}
+
+func (c *rtcTestConn) Read(ctx context.Context) ([]byte, error) {
+	select {
+	case <-c.closed:
+		return nil, errors.New("closed")
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (c *rtcTestConn) Write(ctx context.Context, data []byte) error { return nil }
+
+func (c *rtcTestConn) Close() error {
+	select {
+	case <-c.closed:
+	default:
+		close(c.closed)
+	}
+	return nil
+}
+
+// rtcTestNegotiator answers offers whose SDP is "ok"
+type rtcTestNegotiator struct {
+	conn *rtcTestConn
+}
+
+func (n *rtcTestNegotiator) Answer(ctx context.Context, offer RTCSessionDescription) (RTCSessionDescription, transport.MessageConn, error) {
+	if offer.SDP != "ok" {
+		return RTCSessionDescription{}, nil, errors.New("unsupported offer")
+	}
+	return RTCSessionDescription{Type: "answer", SDP: "answer-sdp"}, n.conn, nil
+}
+
+func TestWebUI_handleRTCOffer_ServesClientOverDataChannel(t *testing.T) {
+	conn := &rtcTestConn{closed: make(chan struct{})}
+	webUI, err := NewWebUI(WebUIOptions{View: newTestView(t), RTC: &rtcTestNegotiator{conn: conn}, MaxClients: 1})
+	if err != nil {
+		t.Fatalf("NewWebUI failed: %v", err)
+	}
+	defer conn.Close()
+
+	rec := httptest.NewRecorder()
+	webUI.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/rtc/offer", strings.NewReader(`{"type":"offer","sdp":"ok"}`)))
+	var answer RTCSessionDescription
+	if rec.Code != http.StatusOK || json.Unmarshal(rec.Body.Bytes(), &answer) != nil || answer.SDP != "answer-sdp" {
+		t.Fatalf("POST /rtc/offer = %d %s, want the answer", rec.Code, rec.Body.String())
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for webUI.wsHandler.GetClientCount() != 1 {
+		if time.Now().After(deadline) {
+			t.Fatal("data channel client was not registered")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	// The data channel client holds the only slot
+	rec = httptest.NewRecorder()
+	webUI.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/rtc/offer", strings.NewReader(`{"type":"offer","sdp":"ok"}`)))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("offer over the client limit = %d, want 503", rec.Code)
+	}
+}
+
+func TestWebUI_handleRTCOffer_RejectsInvalidOffers(t *testing.T) {
+	webUI, err := NewWebUI(WebUIOptions{View: newTestView(t), RTC: &rtcTestNegotiator{}, MaxClients: 1})
+	if err != nil {
+		t.Fatalf("NewWebUI failed: %v", err)
+	}
+
+	tests := []struct {
+		method, body string
+		want         int
+	}{
+		{http.MethodGet, "", http.StatusMethodNotAllowed},
+		{http.MethodPost, `{"type":"answer","sdp":"ok"}`, http.StatusBadRequest},
+		{http.MethodPost, `{"type":"offer","sdp":"bad"}`, http.StatusBadRequest},
+		{http.MethodPost, `{"type":"offer","sdp":"bad"}`, http.StatusBadRequest}, // the failed negotiation released its slot
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		webUI.ServeHTTP(rec, httptest.NewRequest(tt.method, "/rtc/offer", strings.NewReader(tt.body)))
+		if rec.Code != tt.want {
+			t.Errorf("%s /rtc/offer %s = %d, want %d", tt.method, tt.body, rec.Code, tt.want)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	newTestWebUI(t).ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/rtc/offer", strings.NewReader(`{"type":"offer","sdp":"ok"}`)))
+	if rec.Code == http.StatusOK {
+		t.Error("/rtc/offer served without a negotiator")
+	}
+}
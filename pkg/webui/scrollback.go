@@ -0,0 +1,246 @@
+// Package webui provides the scrollback of the terminal: lines that scroll
+// off the top of the screen are kept so clients can scroll back through
+// past output.
+package webui
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/opd-ai/go-gamelaunch-www/pkg/transport"
+)
+
+const (
+	// defaultScrollbackLines is how many lines the scrollback keeps, and
+	// maxScrollbackLines how many it may be configured to keep
+	defaultScrollbackLines = 1000
+	maxScrollbackLines     = 100000
+
+	// defaultScrollbackPage and maxScrollbackPage bound the lines of one
+	// GET /game/scrollback
+	defaultScrollbackPage = 100
+	maxScrollbackPage     = 1000
+)
+
+// ScrollbackConfig keeps the last Lines lines that scrolled off the top of
+// the normal screen, served at /game/scrollback. As in xterm, lines leaving
+// a scroll region below the top row or the alternate screen are not kept,
+// and ED 3 (the "\e[3J" of clear) discards the scrollback. With a memory
+// quota, the oldest lines are dropped to keep the screen and scrollback
+// within it.
+type ScrollbackConfig struct {
+	Lines int `yaml:"lines,omitempty"` // default 1000, at most 100000
+}
+
+// ScrollbackInfo tells clients which lines the scrollback holds. Lines are
+// numbered from 0 in the order they scrolled off the screen, so a client
+// fetches the lines from the last Total it saw when Total grows.
+type ScrollbackInfo struct {
+	First uint64 `json:"first"` // oldest line kept
+	Total uint64 `json:"total"` // lines scrolled off so far
+}
+
+// ScrollbackPage is the response of GET /game/scrollback: Lines, oldest
+// first and without trailing blanks, are numbered from Start
+type ScrollbackPage struct {
+	ScrollbackInfo
+	Start uint64   `json:"start"`
+	Lines [][]Cell `json:"lines"`
+}
+
+// scrollbackRing holds the newest lines scrolled off the screen, oldest
+// first; the view's lock guards it
+type scrollbackRing struct {
+	lines [][]Cell
+	size  int
+	cells int             // cells held, for ScreenMemory
+	info  *ScrollbackInfo // replaced, never modified, on every change
+}
+
+// newScrollbackRing creates a scrollback of size lines
+func newScrollbackRing(size int) *scrollbackRing {
+	return &scrollbackRing{size: size, info: &ScrollbackInfo{}}
+}
+
+// push keeps a copy of row, then drops the oldest lines beyond the size or
+// maxCells cells, unless maxCells is negative
+func (r *scrollbackRing) push(row []Cell, maxCells int) {
+	n := len(row)
+	for n > 0 && isBlankCell(row[n-1]) {
+		n--
+	}
+	line := make([]Cell, n)
+	copy(line, row)
+	for i := range line {
+		line[i].Changed = false
+	}
+
+	r.lines = append(r.lines, line)
+	r.cells += n
+	r.info = &ScrollbackInfo{First: r.info.First, Total: r.info.Total + 1}
+	r.trim(maxCells)
+}
+
+// trim drops the oldest lines beyond the size or maxCells cells, unless
+// maxCells is negative
+func (r *scrollbackRing) trim(maxCells int) {
+	dropped := 0
+	for len(r.lines) > r.size || (maxCells >= 0 && r.cells > maxCells && len(r.lines) > 0) {
+		r.cells -= len(r.lines[0])
+		r.lines[0] = nil
+		r.lines = r.lines[1:]
+		dropped++
+	}
+	if dropped > 0 {
+		r.info = &ScrollbackInfo{First: r.info.First + uint64(dropped), Total: r.info.Total}
+	}
+}
+
+// line returns line number n, which must be kept
+func (r *scrollbackRing) line(n uint64) []Cell {
+	return r.lines[n-r.info.First]
+}
+
+// resize keeps at most size lines, dropping the oldest
+func (r *scrollbackRing) resize(size int) {
+	r.size = size
+	r.trim(-1)
+}
+
+// clear discards every line; the numbering goes on
+func (r *scrollbackRing) clear() {
+	r.lines, r.cells = nil, 0
+	r.info = &ScrollbackInfo{First: r.info.Total, Total: r.info.Total}
+}
+
+// isBlankCell reports whether cell shows nothing, on the default background
+func isBlankCell(cell Cell) bool {
+	return cell.Char == ' ' && cell.Text == "" && cell.BgColor == "#000000" &&
+		!cell.Inverse && cell.Underline == "" && !cell.Overline &&
+		cell.TileX == 0 && cell.TileY == 0 && cell.Meta == nil
+}
+
+// SetScrollback keeps up to lines lines scrolled off the screen, keeping
+// the newest ones already kept; zero or less discards the scrollback
+func (v *WebView) SetScrollback(lines int) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	switch {
+	case lines <= 0:
+		v.scrollback = nil
+	case v.scrollback == nil:
+		v.scrollback = newScrollbackRing(lines)
+	default:
+		v.scrollback.resize(lines)
+	}
+}
+
+// keepScrolledLines saves the first n of the scrolling rows to the
+// scrollback before they scroll off the top of the normal screen
+func (v *WebView) keepScrolledLines(rows []int, n int) {
+	if v.scrollback == nil || v.modes.altScreen || v.scrollTop != 0 {
+		return
+	}
+	budget := v.scrollbackBudget()
+	for _, y := range rows[:min(n, len(rows))] {
+		v.scrollback.push(v.buffer[y], budget)
+	}
+}
+
+// clearScrollback discards the scrollback (ED 3)
+func (v *WebView) clearScrollback() {
+	if v.scrollback != nil {
+		v.scrollback.clear()
+	}
+}
+
+// Scrollback returns up to limit lines of the scrollback that scrolled off
+// before line number before, the newest of them when there are more
+func (v *WebView) Scrollback(before uint64, limit int) ScrollbackPage {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+
+	if v.scrollback == nil {
+		return ScrollbackPage{Lines: [][]Cell{}}
+	}
+	info := *v.scrollback.info
+	end := min(before, info.Total)
+	start := max(info.First, end-min(end, uint64(limit)))
+	page := ScrollbackPage{ScrollbackInfo: info, Start: start, Lines: make([][]Cell, 0, max(end, start)-start)}
+	for n := start; n < end; n++ {
+		line := v.scrollback.line(n)
+		page.Lines = append(page.Lines, append([]Cell(nil), line...))
+	}
+	return page
+}
+
+// toWireScrollback converts scrollback info to its wire representation
+func toWireScrollback(info *ScrollbackInfo) *transport.Scrollback {
+	if info == nil {
+		return nil
+	}
+	return &transport.Scrollback{First: info.First, Total: info.Total}
+}
+
+// validateScrollback checks cfg and returns the lines to keep
+func validateScrollback(cfg ScrollbackConfig) (int, error) {
+	if cfg.Lines < 0 || cfg.Lines > maxScrollbackLines {
+		return 0, fmt.Errorf("scrollback: invalid lines %d", cfg.Lines)
+	}
+	if cfg.Lines == 0 {
+		return defaultScrollbackLines, nil
+	}
+	return cfg.Lines, nil
+}
+
+// handleScrollback serves GET /game/scrollback, the lines that scrolled off
+// the screen: the newest limit (default 100) of them, or those before line
+// number before for paging further back
+func (w *WebUI) handleScrollback(rw http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		rw.Header().Set("Allow", http.MethodGet)
+		http.Error(rw, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	q := r.URL.Query()
+	before := uint64(1<<64 - 1)
+	if v := q.Get("before"); v != "" {
+		parsed, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			http.Error(rw, "Invalid before", http.StatusBadRequest)
+			return
+		}
+		before = parsed
+	}
+	limit := defaultScrollbackPage
+	if v := q.Get("limit"); v != "" {
+		parsed, err := strconv.Atoi(v)
+		if err != nil || parsed <= 0 || parsed > maxScrollbackPage {
+			http.Error(rw, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = parsed
+	}
+
+	view := w.GetView()
+	if view == nil {
+		http.Error(rw, "No active session", http.StatusNotFound)
+		return
+	}
+	page := view.Scrollback(before, limit)
+
+	// Like the screen, concealed text only reaches players
+	if w.options.WithholdConcealed && !w.authorize(r.Context(), MethodInput) {
+		for _, line := range page.Lines {
+			for x, cell := range line {
+				if cell.Concealed {
+					line[x] = Cell{Char: ' ', FgColor: cell.FgColor, BgColor: cell.BgColor, Concealed: true}
+				}
+			}
+		}
+	}
+	writeJSON(rw, http.StatusOK, page)
+}
@@ -0,0 +1,127 @@
+package webui
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/opd-ai/go-gamelaunch-client/pkg/dgclient"
+)
+
+// scrollbackLines returns the text of the lines of page
+func scrollbackLines(page ScrollbackPage) []string {
+	return stateLines(&GameState{Buffer: page.Lines})
+}
+
+func TestWebView_Scrollback(t *testing.T) {
+	view, err := NewWebView(dgclient.ViewOptions{InitialWidth: 6, InitialHeight: 3})
+	if err != nil {
+		t.Fatalf("NewWebView failed: %v", err)
+	}
+	view.SetScrollback(3)
+	render := func(data string) {
+		t.Helper()
+		if err := view.Render([]byte(data)); err != nil {
+			t.Fatalf("Render failed: %v", err)
+		}
+	}
+
+	render("1\r\n2\r\n3\r\n4\r\n5")
+	page := view.Scrollback(^uint64(0), 10)
+	if got := scrollbackLines(page); !slices.Equal(got, []string{"1", "2"}) || page.Start != 0 {
+		t.Fatalf("scrollback = %q from %d, want the two lines scrolled off", got, page.Start)
+	}
+	if len(page.Lines[0]) != 1 {
+		t.Errorf("line = %d cells, want trailing blanks trimmed", len(page.Lines[0]))
+	}
+
+	// The oldest line makes room, and clients learn of the new ones
+	render("\r\n6\r\n7")
+	if info := view.GetCurrentState().Scrollback; info == nil || *info != (ScrollbackInfo{First: 1, Total: 4}) {
+		t.Fatalf("state scrollback = %+v, want lines 1 to 3 of 4", info)
+	}
+	page = view.Scrollback(3, 1)
+	if got := scrollbackLines(page); !slices.Equal(got, []string{"3"}) || page.Start != 2 {
+		t.Errorf("page before 3 = %q from %d, want line 2", got, page.Start)
+	}
+
+	// Lines leaving a lower scroll region or the alternate screen are not kept
+	render("\x1b[2;3r\x1b[3;1H\r\nx\x1b[r\x1b[?1049h\x1b[3;1H\r\n\r\n\x1b[?1049l")
+	if total := view.GetCurrentState().Scrollback.Total; total != 4 {
+		t.Errorf("Total = %d after scrolling a region and the alternate screen, want 4", total)
+	}
+
+	// SU keeps every line it scrolls off; ED 3 discards the scrollback
+	render("\x1b[2S")
+	if info := view.GetCurrentState().Scrollback; info.Total != 6 {
+		t.Errorf("Total = %d after SU, want 6", info.Total)
+	}
+	render("\x1b[3J")
+	if info := view.GetCurrentState().Scrollback; *info != (ScrollbackInfo{First: 6, Total: 6}) {
+		t.Errorf("scrollback = %+v after ED 3, want empty", info)
+	}
+
+	view.SetScrollback(0)
+	if view.GetCurrentState().Scrollback != nil {
+		t.Error("scrollback still reported once disabled")
+	}
+}
+
+func TestWebUI_HandleScrollback(t *testing.T) {
+	view := newTestView(t)
+	w, err := NewWebUI(WebUIOptions{
+		View:       view,
+		Scrollback: &ScrollbackConfig{Lines: 50},
+		StaticTokens: []StaticToken{
+			{Name: "watcher", Token: spectatorToken, Role: "spectator"},
+			{Name: "hero", Token: playerToken, Role: "player"},
+		},
+		WithholdConcealed: true,
+	})
+	if err != nil {
+		t.Fatalf("NewWebUI failed: %v", err)
+	}
+	if err := view.Render([]byte("\x1b[8msecret\x1b[0m" + strings.Repeat("\r\n", 30) + "line")); err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+
+	// Like the screen, the concealed text only reaches players
+	for token, want := range map[string]string{playerToken: "secret", spectatorToken: ""} {
+		req := httptest.NewRequest(http.MethodGet, "/game/scrollback?limit=2&before=1", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		w.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("GET /game/scrollback = %d: %s", rec.Code, rec.Body.String())
+		}
+		var page ScrollbackPage
+		if err := json.Unmarshal(rec.Body.Bytes(), &page); err != nil {
+			t.Fatalf("invalid response: %v", err)
+		}
+		if page.Start != 0 || len(page.Lines) != 1 || page.Total == 0 {
+			t.Fatalf("page = %+v, want the first line", page)
+		}
+		if got := scrollbackLines(page)[0]; got != want {
+			t.Errorf("first line = %q, want %q", got, want)
+		}
+	}
+
+	for _, query := range []string{"limit=0", "limit=5000", "before=x"} {
+		req := httptest.NewRequest(http.MethodGet, "/game/scrollback?"+query, nil)
+		req.Header.Set("Authorization", "Bearer "+playerToken)
+		rec := httptest.NewRecorder()
+		w.ServeHTTP(rec, req)
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("GET /game/scrollback?%s = %d, want 400", query, rec.Code)
+		}
+	}
+
+	for _, lines := range []int{-1, maxScrollbackLines + 1} {
+		if _, err := NewWebUI(WebUIOptions{View: newTestView(t), Scrollback: &ScrollbackConfig{Lines: lines}}); err == nil {
+			t.Errorf("NewWebUI accepted %d scrollback lines", lines)
+		}
+	}
+}
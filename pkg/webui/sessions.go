@@ -0,0 +1,126 @@
+// Package webui provides routing of several sessions under /s/{id}/ so
+// individual games can be bookmarked and shared.
+package webui
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// sessionIDPattern restricts session IDs to characters that need no escaping
+// in a URL path
+var sessionIDPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.@-]{0,63}$`)
+
+// basePathContextKey is the request context key for the path prefix a
+// request was routed through
+type basePathContextKey struct{}
+
+// basePath returns the path prefix, e.g. "/s/alice" or "/club/s/alice", that
+// the request with ctx was routed through, or "" when it was served directly
+func basePath(ctx context.Context) string {
+	prefix, _ := ctx.Value(basePathContextKey{}).(string)
+	return prefix
+}
+
+// SessionRouter serves several sessions from one listener. Each session is a
+// WebUI reachable below /s/{id}/, so the UI it serves is bound to that game;
+// all other paths go to Home. Requests are routed with the /s/{id} prefix
+// removed, so every session authenticates and authorizes them with its own
+// configuration, and the routes are named in its AuthorizationPolicy as if
+// the WebUI were served directly. Run each WebUI with Run.
+type SessionRouter struct {
+	home http.Handler
+
+	mu       sync.RWMutex
+	sessions map[string]*WebUI
+}
+
+// NewSessionRouter creates a router serving paths outside /s/ with home, or
+// answering them with 404 when home is nil
+func NewSessionRouter(home http.Handler) *SessionRouter {
+	if home == nil {
+		home = http.NotFoundHandler()
+	}
+	return &SessionRouter{home: home, sessions: make(map[string]*WebUI)}
+}
+
+// Add serves ui below /s/{id}/
+func (sr *SessionRouter) Add(id string, ui *WebUI) error {
+	if !sessionIDPattern.MatchString(id) {
+		return fmt.Errorf("sessions: invalid id %q", id)
+	}
+
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+	if _, ok := sr.sessions[id]; ok {
+		return fmt.Errorf("sessions: id %q is already in use", id)
+	}
+	sr.sessions[id] = ui
+	return nil
+}
+
+// Remove stops serving the session id
+func (sr *SessionRouter) Remove(id string) {
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+	delete(sr.sessions, id)
+}
+
+// Sessions returns the IDs of the sessions served, sorted
+func (sr *SessionRouter) Sessions() []string {
+	sr.mu.RLock()
+	defer sr.mu.RUnlock()
+
+	ids := make([]string, 0, len(sr.sessions))
+	for id := range sr.sessions {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// session returns the WebUI serving id
+func (sr *SessionRouter) session(id string) (*WebUI, bool) {
+	sr.mu.RLock()
+	defer sr.mu.RUnlock()
+	ui, ok := sr.sessions[id]
+	return ui, ok
+}
+
+// ServeHTTP implements http.Handler
+func (sr *SessionRouter) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	rest, ok := strings.CutPrefix(r.URL.Path, "/s/")
+	if !ok {
+		sr.home.ServeHTTP(rw, r)
+		return
+	}
+
+	id, path, hasSlash := strings.Cut(rest, "/")
+	ui, ok := sr.session(id)
+	if !ok {
+		http.Error(rw, "Session not found", http.StatusNotFound)
+		return
+	}
+
+	// Relative URLs of the UI resolve below the session only with a
+	// trailing slash
+	prefix := basePath(r.Context()) + "/s/" + id
+	if !hasSlash {
+		target := prefix + "/"
+		if r.URL.RawQuery != "" {
+			target += "?" + r.URL.RawQuery
+		}
+		http.Redirect(rw, r, target, http.StatusMovedPermanently)
+		return
+	}
+
+	routed := r.Clone(context.WithValue(r.Context(), basePathContextKey{}, prefix))
+	routed.URL.Path = "/" + path
+	routed.URL.RawPath = ""
+	ui.ServeHTTP(rw, routed)
+}
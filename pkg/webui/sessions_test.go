@@ -0,0 +1,82 @@
+package webui
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSessionRouter_ServeHTTP(t *testing.T) {
+	home := http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		rw.WriteHeader(http.StatusTeapot)
+	})
+	router := NewSessionRouter(home)
+	if err := router.Add("alice", newRBACWebUI(t, nil, nil)); err != nil {
+		t.Fatalf("Add(alice) error = %v", err)
+	}
+	bob, err := NewWebUI(WebUIOptions{
+		View:         newTestView(t),
+		StaticTokens: []StaticToken{{Name: "bob", Token: "bob-token-000000001", Role: "admin"}},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create WebUI: %v", err)
+	}
+	if err := router.Add("bob", bob); err != nil {
+		t.Fatalf("Add(bob) error = %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		path       string
+		token      string
+		wantStatus int
+		wantHeader string
+	}{
+		{"session route", "/s/alice/session/info", spectatorToken, http.StatusOK, ""},
+		{"policy of the session", "/s/alice/admin/events", spectatorToken, http.StatusForbidden, ""},
+		{"token of another session", "/s/bob/session/info", spectatorToken, http.StatusUnauthorized, ""},
+		{"unauthenticated", "/s/alice/session/info", "", http.StatusUnauthorized, ""},
+		{"unknown session", "/s/carol/session/info", spectatorToken, http.StatusNotFound, ""},
+		{"missing trailing slash", "/s/alice?theme=dark", "", http.StatusMovedPermanently, "/s/alice/?theme=dark"},
+		{"outside /s/", "/version", "", http.StatusTeapot, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if tt.wantHeader != "" && rec.Header().Get("Location") != tt.wantHeader {
+				t.Errorf("Location = %q, want %q", rec.Header().Get("Location"), tt.wantHeader)
+			}
+		})
+	}
+}
+
+func TestSessionRouter_Add(t *testing.T) {
+	router := NewSessionRouter(nil)
+	ui := newTestWebUI(t)
+
+	for _, id := range []string{"", "../admin", "a/b", "with space"} {
+		if err := router.Add(id, ui); err == nil {
+			t.Errorf("Add(%q) succeeded, want error", id)
+		}
+	}
+	if err := router.Add("alice@nethack", ui); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	if err := router.Add("alice@nethack", ui); err == nil {
+		t.Error("Add() of a duplicate id succeeded, want error")
+	}
+
+	router.Remove("alice@nethack")
+	if ids := router.Sessions(); len(ids) != 0 {
+		t.Errorf("Sessions() = %v after Remove, want none", ids)
+	}
+}
@@ -0,0 +1,324 @@
+// Package webui provides compressed snapshots of the screen written to disk
+// periodically and when the game goes idle, for crash recovery and for
+// seeing what was on screen when something went wrong.
+package webui
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Reasons a snapshot was taken
+const (
+	SnapshotPeriodic = "periodic"
+	SnapshotIdle     = "idle"
+	SnapshotShutdown = "shutdown"
+)
+
+// snapshotPrefix and snapshotSuffix enclose the name of snapshot files
+const (
+	snapshotPrefix = "snapshot-"
+	snapshotSuffix = ".json.gz"
+)
+
+// snapshotTimeFormat names snapshot files so that they sort by time
+const snapshotTimeFormat = "20060102T150405.000Z"
+
+// SnapshotConfig writes a gzipped JSON snapshot of the screen to Dir every
+// Interval and once the game has been idle for Idle, unless the screen has
+// not changed since the last one. Only the newest Keep snapshots, none older
+// than MaxAge, are retained.
+type SnapshotConfig struct {
+	Dir      string `yaml:"dir"`
+	Interval string `yaml:"interval,omitempty" yamlcheck:"duration"` // default "5m"
+	Idle     string `yaml:"idle,omitempty" yamlcheck:"duration"`     // default "30s"
+	Keep     int    `yaml:"keep,omitempty"`                          // default 20
+	MaxAge   string `yaml:"max_age,omitempty" yamlcheck:"duration"`  // e.g. "168h"; default unlimited
+}
+
+// Snapshot is the content of a snapshot file
+type Snapshot struct {
+	Time      time.Time  `json:"time"`
+	Reason    string     `json:"reason"` // periodic, idle or shutdown
+	SessionID string     `json:"session_id,omitempty"`
+	State     *GameState `json:"state"`
+}
+
+// SnapshotInfo describes a snapshot file
+type SnapshotInfo struct {
+	Name    string    `json:"name"`
+	Time    time.Time `json:"time"`
+	Version uint64    `json:"version"`
+	Size    int64     `json:"size"`
+}
+
+// snapshotter writes snapshots of a view's screen
+type snapshotter struct {
+	dir       string
+	interval  time.Duration
+	idle      time.Duration
+	keep      int
+	maxAge    time.Duration
+	sessionID string
+
+	mu      sync.Mutex
+	version uint64 // of the last snapshot
+	written bool
+	now     func() time.Time
+}
+
+// newSnapshotter validates cfg and creates its directory
+func newSnapshotter(cfg SnapshotConfig) (*snapshotter, error) {
+	s := &snapshotter{dir: cfg.Dir, interval: 5 * time.Minute, idle: 30 * time.Second, keep: 20, now: time.Now}
+	if cfg.Dir == "" {
+		return nil, fmt.Errorf("snapshots: dir is required")
+	}
+	for _, d := range []struct {
+		name  string
+		value string
+		dst   *time.Duration
+	}{
+		{"interval", cfg.Interval, &s.interval},
+		{"idle", cfg.Idle, &s.idle},
+		{"max_age", cfg.MaxAge, &s.maxAge},
+	} {
+		if d.value == "" {
+			continue
+		}
+		parsed, err := time.ParseDuration(d.value)
+		if err != nil || parsed <= 0 {
+			return nil, fmt.Errorf("snapshots: invalid %s %q", d.name, d.value)
+		}
+		*d.dst = parsed
+	}
+	if cfg.Keep < 0 {
+		return nil, fmt.Errorf("snapshots: invalid keep %d", cfg.Keep)
+	}
+	if cfg.Keep > 0 {
+		s.keep = cfg.Keep
+	}
+	if err := os.MkdirAll(cfg.Dir, 0o750); err != nil {
+		return nil, fmt.Errorf("snapshots: %w", err)
+	}
+	return s, nil
+}
+
+// run takes snapshots of view every interval and after it has been idle,
+// and a last one when ctx is done
+func (s *snapshotter) run(ctx context.Context, view *WebView) {
+	updates, unsubscribe := view.SubscribeUpdates(ctx)
+	defer unsubscribe()
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	idle := time.NewTimer(s.idle)
+	defer idle.Stop()
+
+	take := func(reason string) {
+		if err := s.take(view.GetStateManager().GetCurrentState(), reason); err != nil {
+			view.logger.Warn("webui: failed to write snapshot", "reason", reason, "error", err)
+		}
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			take(SnapshotShutdown)
+			return
+		case _, ok := <-updates:
+			if !ok {
+				return
+			}
+			idle.Reset(s.idle)
+		case <-idle.C:
+			take(SnapshotIdle)
+		case <-ticker.C:
+			take(SnapshotPeriodic)
+		}
+	}
+}
+
+// take writes a snapshot of state unless the last one shows the same
+// version, then applies the retention limits
+func (s *snapshotter) take(state *GameState, reason string) error {
+	if state == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.written && s.version == state.Version {
+		return nil
+	}
+
+	now := s.now().UTC()
+	name := snapshotPrefix + now.Format(snapshotTimeFormat) + "-v" + strconv.FormatUint(state.Version, 10) + snapshotSuffix
+	snapshot := Snapshot{Time: now, Reason: reason, SessionID: s.sessionID, State: state}
+	if err := writeSnapshot(filepath.Join(s.dir, name), &snapshot); err != nil {
+		return err
+	}
+	s.version, s.written = state.Version, true
+	return s.prune(now)
+}
+
+// writeSnapshot writes snapshot to path through a temporary file, so that
+// readers never see a partial snapshot
+func writeSnapshot(path string, snapshot *Snapshot) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".snapshot-*")
+	if err != nil {
+		return fmt.Errorf("snapshots: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	zw := gzip.NewWriter(tmp)
+	if err := json.NewEncoder(zw).Encode(snapshot); err != nil {
+		tmp.Close()
+		return fmt.Errorf("snapshots: %w", err)
+	}
+	if err := zw.Close(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("snapshots: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("snapshots: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("snapshots: %w", err)
+	}
+	return nil
+}
+
+// prune removes the snapshots beyond the newest keep and those older than
+// maxAge
+func (s *snapshotter) prune(now time.Time) error {
+	snapshots, err := ListSnapshots(s.dir)
+	if err != nil {
+		return err
+	}
+	for i, info := range snapshots {
+		if i < len(snapshots)-s.keep || (s.maxAge > 0 && now.Sub(info.Time) > s.maxAge) {
+			if err := os.Remove(filepath.Join(s.dir, info.Name)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+				return fmt.Errorf("snapshots: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+// ListSnapshots returns the snapshots in dir, oldest first
+func ListSnapshots(dir string) ([]SnapshotInfo, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("snapshots: %w", err)
+	}
+
+	var snapshots []SnapshotInfo
+	for _, entry := range entries {
+		info, ok := parseSnapshotName(entry.Name())
+		if !ok || !entry.Type().IsRegular() {
+			continue
+		}
+		if fi, err := entry.Info(); err == nil {
+			info.Size = fi.Size()
+		}
+		snapshots = append(snapshots, info)
+	}
+	slices.SortFunc(snapshots, func(a, b SnapshotInfo) int { return strings.Compare(a.Name, b.Name) })
+	return snapshots, nil
+}
+
+// parseSnapshotName reads the time and version from a snapshot file name
+func parseSnapshotName(name string) (SnapshotInfo, bool) {
+	rest, ok := strings.CutPrefix(name, snapshotPrefix)
+	if !ok {
+		return SnapshotInfo{}, false
+	}
+	rest, ok = strings.CutSuffix(rest, snapshotSuffix)
+	if !ok {
+		return SnapshotInfo{}, false
+	}
+	stamp, version, ok := strings.Cut(rest, "-v")
+	if !ok {
+		return SnapshotInfo{}, false
+	}
+	t, err := time.Parse(snapshotTimeFormat, stamp)
+	if err != nil {
+		return SnapshotInfo{}, false
+	}
+	v, err := strconv.ParseUint(version, 10, 64)
+	if err != nil {
+		return SnapshotInfo{}, false
+	}
+	return SnapshotInfo{Name: name, Time: t, Version: v}, true
+}
+
+// LoadSnapshot reads a snapshot file
+func LoadSnapshot(path string) (*Snapshot, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("snapshots: %w", err)
+	}
+	defer file.Close()
+
+	zr, err := gzip.NewReader(file)
+	if err != nil {
+		return nil, fmt.Errorf("snapshots: %s: %w", path, err)
+	}
+	defer zr.Close()
+
+	var snapshot Snapshot
+	if err := json.NewDecoder(zr).Decode(&snapshot); err != nil {
+		return nil, fmt.Errorf("snapshots: %s: %w", path, err)
+	}
+	return &snapshot, nil
+}
+
+// handleAdminSnapshots handles GET /admin/snapshots, listing the snapshots
+// on disk, and GET /admin/snapshots?name=..., returning one
+func (w *WebUI) handleAdminSnapshots(rw http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		rw.Header().Set("Allow", http.MethodGet)
+		http.Error(rw, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	name := r.URL.Query().Get("name")
+	if name == "" {
+		snapshots, err := ListSnapshots(w.snapshots.dir)
+		if err != nil {
+			w.logger(r.Context()).Error("webui: failed to list snapshots", "error", err)
+			http.Error(rw, "Failed to list snapshots", http.StatusInternalServerError)
+			return
+		}
+		if snapshots == nil {
+			snapshots = []SnapshotInfo{}
+		}
+		writeJSON(rw, http.StatusOK, map[string]interface{}{"snapshots": snapshots})
+		return
+	}
+
+	if _, ok := parseSnapshotName(name); !ok || filepath.Base(name) != name {
+		http.Error(rw, "Invalid snapshot name", http.StatusBadRequest)
+		return
+	}
+	snapshot, err := LoadSnapshot(filepath.Join(w.snapshots.dir, name))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			http.NotFound(rw, r)
+			return
+		}
+		w.logger(r.Context()).Error("webui: failed to read snapshot", "name", name, "error", err)
+		http.Error(rw, "Failed to read snapshot", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(rw, http.StatusOK, snapshot)
+}
@@ -0,0 +1,168 @@
+package webui
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestNewSnapshotter_Validation(t *testing.T) {
+	dir := t.TempDir()
+
+	tests := []struct {
+		name    string
+		cfg     SnapshotConfig
+		wantErr bool
+	}{
+		{"Defaults", SnapshotConfig{Dir: dir}, false},
+		{"Custom", SnapshotConfig{Dir: filepath.Join(dir, "nested"), Interval: "1m", Idle: "5s", Keep: 3, MaxAge: "24h"}, false},
+		{"MissingDir", SnapshotConfig{}, true},
+		{"InvalidInterval", SnapshotConfig{Dir: dir, Interval: "often"}, true},
+		{"ZeroIdle", SnapshotConfig{Dir: dir, Idle: "0s"}, true},
+		{"InvalidMaxAge", SnapshotConfig{Dir: dir, MaxAge: "-1h"}, true},
+		{"NegativeKeep", SnapshotConfig{Dir: dir, Keep: -1}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newSnapshotter(tt.cfg)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("newSnapshotter() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSnapshotter_Take_WritesAndPrunes(t *testing.T) {
+	dir := t.TempDir()
+	s, err := newSnapshotter(SnapshotConfig{Dir: dir, Keep: 2, MaxAge: "1h"})
+	if err != nil {
+		t.Fatalf("newSnapshotter failed: %v", err)
+	}
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+
+	view := newTestView(t)
+	for i, text := range []string{"a", "b", "c"} {
+		if err := view.Render([]byte(text)); err != nil {
+			t.Fatalf("Render failed: %v", err)
+		}
+		now = now.Add(time.Duration(i+1) * time.Second)
+		if err := s.take(view.GetStateManager().GetCurrentState(), SnapshotPeriodic); err != nil {
+			t.Fatalf("take failed: %v", err)
+		}
+	}
+	// An unchanged screen is not written again
+	now = now.Add(time.Second)
+	if err := s.take(view.GetStateManager().GetCurrentState(), SnapshotIdle); err != nil {
+		t.Fatalf("take failed: %v", err)
+	}
+
+	snapshots, err := ListSnapshots(dir)
+	if err != nil {
+		t.Fatalf("ListSnapshots failed: %v", err)
+	}
+	if len(snapshots) != 2 {
+		t.Fatalf("kept %d snapshots, want 2: %+v", len(snapshots), snapshots)
+	}
+	latest := snapshots[1]
+	if latest.Version != view.GetStateManager().GetCurrentVersion() || latest.Size == 0 {
+		t.Errorf("latest snapshot = %+v", latest)
+	}
+
+	snapshot, err := LoadSnapshot(filepath.Join(dir, latest.Name))
+	if err != nil {
+		t.Fatalf("LoadSnapshot failed: %v", err)
+	}
+	if snapshot.Reason != SnapshotPeriodic || snapshot.State.Buffer[0][2].Char != 'c' {
+		t.Errorf("snapshot = %s with %q, want periodic with the last screen", snapshot.Reason, snapshot.State.Buffer[0][2].Char)
+	}
+
+	// Snapshots older than MaxAge are removed
+	now = now.Add(2 * time.Hour)
+	if err := view.Render([]byte("d")); err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	if err := s.take(view.GetStateManager().GetCurrentState(), SnapshotIdle); err != nil {
+		t.Fatalf("take failed: %v", err)
+	}
+	if snapshots, _ := ListSnapshots(dir); len(snapshots) != 1 {
+		t.Errorf("kept %d snapshots after max_age, want 1", len(snapshots))
+	}
+}
+
+func TestSnapshotter_Run_SnapshotsWhenIdle(t *testing.T) {
+	dir := t.TempDir()
+	s, err := newSnapshotter(SnapshotConfig{Dir: dir, Idle: "20ms", Interval: "1h"})
+	if err != nil {
+		t.Fatalf("newSnapshotter failed: %v", err)
+	}
+	view := newTestView(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		s.run(ctx, view)
+		close(done)
+	}()
+
+	if err := view.Render([]byte("idle")); err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		snapshots, _ := ListSnapshots(dir)
+		if len(snapshots) > 0 && snapshots[len(snapshots)-1].Version == view.GetStateManager().GetCurrentVersion() {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("no idle snapshot of the rendered screen: %+v", snapshots)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	cancel()
+	<-done
+}
+
+func TestWebUI_HandleAdminSnapshots(t *testing.T) {
+	dir := t.TempDir()
+	ui := newAdminWebUI(t, WebUIOptions{Snapshots: &SnapshotConfig{Dir: dir}})
+	if err := ui.GetView().Render([]byte("crash")); err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	if err := ui.snapshots.take(ui.GetView().GetStateManager().GetCurrentState(), SnapshotIdle); err != nil {
+		t.Fatalf("take failed: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	ui.ServeHTTP(rec, newAdminRequest(http.MethodGet, "/admin/snapshots", nil))
+	var list struct {
+		Snapshots []SnapshotInfo `json:"snapshots"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &list); err != nil || len(list.Snapshots) != 1 {
+		t.Fatalf("GET /admin/snapshots = %s", rec.Body.String())
+	}
+
+	tests := []struct {
+		name       string
+		query      string
+		wantStatus int
+	}{
+		{"Existing", "?name=" + list.Snapshots[0].Name, http.StatusOK},
+		{"Missing", "?name=snapshot-20240501T120000.000Z-v9.json.gz", http.StatusNotFound},
+		{"Traversal", "?name=../snapshot-20240501T120000.000Z-v9.json.gz", http.StatusBadRequest},
+		{"NotSnapshot", "?name=passwd", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			ui.ServeHTTP(rec, newAdminRequest(http.MethodGet, "/admin/snapshots"+tt.query, nil))
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d (body: %s)", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+		})
+	}
+}
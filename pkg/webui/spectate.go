@@ -0,0 +1,90 @@
+// Package webui provides spectator mode, in which browser clients watch the
+// shared session without sending input.
+package webui
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+)
+
+// SpectateParams is the request body of POST /session/spectate
+type SpectateParams struct {
+	Client   string `json:"client"`   // WebSocket client ID from the connect message
+	Spectate bool   `json:"spectate"` // false to leave spectator mode
+}
+
+// SpectateResult is the response to POST /session/spectate
+type SpectateResult struct {
+	Client     string `json:"client"`
+	Spectating bool   `json:"spectating"`
+	Spectators int    `json:"spectators"` // see SessionInfo.Spectators
+}
+
+// spectatorCount returns the number of connected clients that watch without
+// sending input: those in spectator mode and those whose role does not
+// allow input
+func (w *WebUI) spectatorCount() int {
+	count := 0
+	for _, client := range w.wsHandler.ClientStats() {
+		if client.Spectating {
+			count++
+			continue
+		}
+		if ctx, ok := w.wsHandler.ClientContext(client.ID); ok && !w.authorize(ctx, MethodInput) {
+			count++
+		}
+	}
+	return count
+}
+
+// mayControlClient reports whether the caller of ctx may change the mode of
+// the client connected with clientCtx: with logins required, only the same
+// account or an admin may
+func (w *WebUI) mayControlClient(ctx, clientCtx context.Context) bool {
+	if !w.authRequired() {
+		return true
+	}
+	caller, owner := IdentityFromContext(ctx), IdentityFromContext(clientCtx)
+	if caller == nil {
+		return false
+	}
+	return caller.Role.Allows(RoleAdmin) || (owner != nil && owner.Subject == caller.Subject)
+}
+
+// handleSpectate handles POST /session/spectate, switching a WebSocket
+// client in or out of spectator mode. Spectators keep receiving the game
+// state, but their input is rejected.
+func (w *WebUI) handleSpectate(rw http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		rw.Header().Set("Allow", http.MethodPost)
+		http.Error(rw, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var params SpectateParams
+	if err := json.NewDecoder(http.MaxBytesReader(rw, r.Body, 4096)).Decode(&params); err != nil || params.Client == "" {
+		http.Error(rw, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	clientCtx, ok := w.wsHandler.ClientContext(params.Client)
+	if !ok {
+		http.Error(rw, "Unknown client", http.StatusNotFound)
+		return
+	}
+	if !w.mayControlClient(r.Context(), clientCtx) {
+		http.Error(rw, "Client belongs to another account", http.StatusForbidden)
+		return
+	}
+	if err := w.wsHandler.SetSpectating(params.Client, params.Spectate); err != nil {
+		http.Error(rw, "Unknown client", http.StatusNotFound)
+		return
+	}
+
+	w.logger(r.Context()).Info("webui.handleSpectate", "client", params.Client, "spectate", params.Spectate)
+	writeJSON(rw, http.StatusOK, SpectateResult{
+		Client:     params.Client,
+		Spectating: params.Spectate,
+		Spectators: w.spectatorCount(),
+	})
+}
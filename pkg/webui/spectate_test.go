@@ -0,0 +1,99 @@
+package webui
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/opd-ai/go-gamelaunch-www/pkg/transport"
+	"nhooyr.io/websocket"
+	"nhooyr.io/websocket/wsjson"
+)
+
+func TestWebUI_Spectate(t *testing.T) {
+	received := make(chan string, 4)
+	ui := newRBACWebUI(t, nil, func(data []byte) error {
+		received <- string(data)
+		return nil
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	server := httptest.NewServer(ui)
+	defer server.Close()
+	base := "ws" + strings.TrimPrefix(server.URL, "http") + "/ws?access_token="
+
+	// dial connects a client and returns its ID from the connect message
+	dial := func(query string) (*websocket.Conn, string) {
+		conn, _, err := websocket.Dial(ctx, base+query, nil)
+		if err != nil {
+			t.Fatalf("Dial failed: %v", err)
+		}
+		conn.SetReadLimit(1 << 20)
+		t.Cleanup(func() { conn.Close(websocket.StatusNormalClosure, "") })
+		var msg transport.Message
+		var connect transport.ConnectPayload
+		if err := wsjson.Read(ctx, conn, &msg); err != nil || json.Unmarshal(msg.Payload, &connect) != nil {
+			t.Fatalf("connect message = %+v, %v", msg, err)
+		}
+		return conn, connect.ClientID
+	}
+	spectate := func(token, client string, on bool) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(SpectateParams{Client: client, Spectate: on})
+		req := httptest.NewRequest(http.MethodPost, "/session/spectate", strings.NewReader(string(body)))
+		req.Header.Set("Authorization", "Bearer "+token)
+		rr := httptest.NewRecorder()
+		ui.ServeHTTP(rr, req)
+		return rr
+	}
+
+	// A player's second tab watches, and so does a spectator by role
+	player, playerID := dial(playerToken)
+	dial(playerToken + "&spectate=1")
+	dial(spectatorToken)
+	for ui.wsHandler.GetClientCount() < 3 {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if info := ui.GetSessionInfo(); info.Clients != 3 || info.Spectators != 2 {
+		t.Errorf("session info counts %d clients, %d spectators; want 3, 2", info.Clients, info.Spectators)
+	}
+
+	if rr := spectate(spectatorToken, playerID, true); rr.Code != http.StatusForbidden {
+		t.Errorf("switching another account's client: status = %d, want 403", rr.Code)
+	}
+	if rr := spectate(playerToken, "client-0", true); rr.Code != http.StatusNotFound {
+		t.Errorf("unknown client: status = %d, want 404", rr.Code)
+	}
+	rr := spectate(playerToken, playerID, true)
+	var result SpectateResult
+	if err := json.Unmarshal(rr.Body.Bytes(), &result); err != nil || !result.Spectating || result.Spectators != 3 {
+		t.Fatalf("POST /session/spectate = %d %s", rr.Code, rr.Body.String())
+	}
+
+	payload, _ := json.Marshal(transport.InputPayload{Input: "q"})
+	if err := wsjson.Write(ctx, player, transport.Message{Type: transport.MsgTypeInput, Payload: payload}); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	for {
+		var msg transport.Message
+		if err := wsjson.Read(ctx, player, &msg); err != nil {
+			t.Fatalf("Read failed: %v", err)
+		}
+		if msg.Type == transport.MsgTypeError {
+			break
+		}
+	}
+	select {
+	case got := <-received:
+		t.Errorf("spectator input %q reached the game", got)
+	default:
+	}
+
+	if rr := spectate(playerToken, playerID, false); rr.Code != http.StatusOK || ui.GetSessionInfo().Spectators != 2 {
+		t.Errorf("leaving spectator mode: status = %d, spectators = %d", rr.Code, ui.GetSessionInfo().Spectators)
+	}
+}
@@ -4,117 +4,215 @@ package webui
 
 import (
 	"context"
-	"fmt"
-	"strconv"
-	"strings"
+	"log/slog"
+	"maps"
+	"slices"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
 // StateManager manages game state versions and change tracking
 // Moved from: state.go
 type StateManager struct {
-	mu           sync.RWMutex
-	currentState *GameState
-	version      uint64
-	waiters      map[string]chan *StateDiff
-	waitersMu    sync.Mutex
+	mu        sync.Mutex // serializes updates, from diffing to delivery
+	store     StateStore
+	epoch     string // identifies this instance to pollers
+	diffHint  int    // size of the last diff, used to pre-size the next one
+	budget    atomic.Pointer[DiffBudget]
+	keyframes *keyframeSchedule // nil without periodic keyframes
+	events    *EventLog
+	logger    *slog.Logger
+	damage    *damageMap           // nil without the damage heatmap
+	waiters   map[uint64][]*waiter // by the version each poller has
+	waitersMu sync.Mutex
 }
 
-// NewStateManager creates a new state manager
+// NewStateManager creates a new state manager backed by an in-memory store
 // Moved from: state.go
 func NewStateManager() *StateManager {
+	return NewStateManagerWithStore(NewMemoryStateStore(0))
+}
+
+// NewStateManagerWithStore creates a state manager backed by store
+func NewStateManagerWithStore(store StateStore) *StateManager {
 	return &StateManager{
-		waiters: make(map[string]chan *StateDiff),
+		store:   store,
+		epoch:   newEpoch(),
+		logger:  slog.Default(),
+		waiters: make(map[uint64][]*waiter),
 	}
 }
 
-// UpdateState updates the current state and notifies waiters
+// UpdateState updates the current state and notifies waiters. The state
+// manager takes ownership of state; callers must not modify it afterwards.
 // Moved from: state.go
 func (sm *StateManager) UpdateState(state *GameState) {
+	sm.UpdateStateContext(context.Background(), state)
+}
+
+// UpdateStateContext is UpdateState that gives up with ctx.Err() if ctx is
+// done before the state is saved, passing ctx to stores implementing
+// ContextSaver. A state given up on is neither current nor delivered.
+func (sm *StateManager) UpdateStateContext(ctx context.Context, state *GameState) error {
 	sm.mu.Lock()
+	defer sm.mu.Unlock()
 
 	// Increment version
-	sm.version++
-	state.Version = sm.version
+	previous := sm.store.Current()
+	state.Version = sm.store.Version() + 1
+
+	// Send a keyframe when one is due; saving it without a diff restarts
+	// the history
+	now := time.Now()
+	keyframe := previous != nil && sm.keyframes != nil && sm.keyframes.due(state.Version, now)
 
 	// Generate diff if we have a previous state
 	var diff *StateDiff
-	if sm.currentState != nil {
-		diff = sm.generateDiff(sm.currentState, state)
+	if previous != nil && !keyframe {
+		diff = sm.generateDiff(previous, state)
+	}
+
+	// Pollers stay at the last saved state, which the next update diffs
+	// against
+	if err := saveState(ctx, sm.store, state, diff); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		sm.logger.Error("webui.StateManager failed to save state", "version", state.Version, "error", err)
+		return nil
 	}
 
-	sm.currentState = state
-	sm.mu.Unlock()
+	if sm.damage != nil && previous != nil {
+		changed := diff
+		if changed == nil {
+			changed = sm.generateDiff(previous, state)
+		}
+		sm.damage.record(now, state.Width, state.Height, changed.Changes)
+	}
+	delivered := sm.applyBudget(diff, state)
+	// The first state has nothing to diff against, but pollers waiting
+	// for it must still wake
+	if keyframe || previous == nil {
+		delivered = keyframeDiff(state)
+	}
+	if keyframe {
+		sm.keyframes.mark(state.Version, now)
+	}
 
-	// Notify waiters
-	if diff != nil {
-		sm.notifyWaiters(diff)
+	// Notify waiters before the next update, so that they see versions in
+	// order
+	if delivered != nil {
+		sm.notifyWaiters(sm.stamp(delivered))
 	}
+	return nil
 }
 
-// GetCurrentState returns the current state
+// GetCurrentState returns a copy of the current state, or nil before the
+// first update. The copy is never modified by later updates; the caller may
+// change it as GameState.Clone allows.
 // Moved from: state.go
 func (sm *StateManager) GetCurrentState() *GameState {
-	sm.mu.RLock()
-	defer sm.mu.RUnlock()
-
-	if sm.currentState == nil {
-		return nil
-	}
-
-	// Return a copy
-	stateCopy := *sm.currentState
-	return &stateCopy
+	return sm.store.Current().Clone()
 }
 
 // GetCurrentVersion returns the current version number
 // Moved from: state.go
 func (sm *StateManager) GetCurrentVersion() uint64 {
-	sm.mu.RLock()
-	defer sm.mu.RUnlock()
+	return sm.store.Version()
+}
 
-	return sm.version
+// SetEventLog sets the log that records skipped waiter notifications
+func (sm *StateManager) SetEventLog(events *EventLog) {
+	sm.events = events
+}
+
+// SetLogger sets the logger of failures, slog.Default() until then
+func (sm *StateManager) SetLogger(logger *slog.Logger) {
+	sm.logger = logger
+}
+
+// Store returns the backing state store
+func (sm *StateManager) Store() StateStore {
+	return sm.store
+}
+
+// waiter is a poller waiting for a version newer than version
+type waiter struct {
+	version uint64
+	ch      chan *StateDiff // receives at most one diff
 }
 
 // waiterRegistration holds the state needed for change polling
 type waiterRegistration struct {
-	waiterCh  chan *StateDiff
-	uniqueKey string
-	cleanup   func()
+	waiterCh chan *StateDiff
+	cleanup  func()
 }
 
-// registerWaiter creates and registers a waiter channel, returning nil if client is already behind
+// registerWaiter creates and registers a waiter channel, returning nil and
+// an immediate diff if the client is not at the current version
 func (sm *StateManager) registerWaiter(clientVersion uint64) (*waiterRegistration, *StateDiff) {
-	sm.mu.RLock()
-	currentVersion := sm.version
-	sm.mu.RUnlock()
-
-	// If client is behind, return immediate diff
-	if clientVersion < currentVersion {
-		diff, _ := sm.generateDiffFromVersion(clientVersion)
+	if diff := sm.immediateDiff(clientVersion); diff != nil {
 		return nil, diff
 	}
 
 	// Create and register waiter
-	waiterCh := make(chan *StateDiff, 1)
-	uniqueKey := fmt.Sprintf("%d-%d", clientVersion, time.Now().UnixNano())
-
+	w := &waiter{version: clientVersion, ch: make(chan *StateDiff, 1)}
 	sm.waitersMu.Lock()
-	sm.waiters[uniqueKey] = waiterCh
+	sm.waiters[clientVersion] = append(sm.waiters[clientVersion], w)
 	sm.waitersMu.Unlock()
 
 	cleanup := func() {
 		sm.waitersMu.Lock()
-		delete(sm.waiters, uniqueKey)
-		sm.waitersMu.Unlock()
+		defer sm.waitersMu.Unlock()
+		sm.removeWaiter(w)
+	}
+
+	// An update between the version check and the registration would not
+	// have been delivered to the new waiter
+	if diff := sm.immediateDiff(clientVersion); diff != nil {
+		cleanup()
+		return nil, diff
+	}
+
+	return &waiterRegistration{waiterCh: w.ch, cleanup: cleanup}, nil
+}
+
+// immediateDiff returns the diff for a client at clientVersion if it need
+// not wait, or nil
+func (sm *StateManager) immediateDiff(clientVersion uint64) *StateDiff {
+	currentVersion := sm.store.Version()
+
+	// If client is behind, return immediate diff
+	if clientVersion < currentVersion {
+		diff, _ := sm.generateDiffFromVersion(clientVersion)
+		return sm.stamp(diff)
 	}
 
-	return &waiterRegistration{
-		waiterCh:  waiterCh,
-		uniqueKey: uniqueKey,
-		cleanup:   cleanup,
-	}, nil
+	// A client ahead of the current version polled a previous instance
+	// before a restart; send the whole screen rather than wait for it
+	if clientVersion > currentVersion {
+		if current := sm.store.Current(); current != nil {
+			return sm.stamp(keyframeDiff(current))
+		}
+	}
+	return nil
+}
+
+// removeWaiter unregisters w if it is still waiting; waitersMu must be held
+func (sm *StateManager) removeWaiter(w *waiter) {
+	waiters := sm.waiters[w.version]
+	for i, registered := range waiters {
+		if registered == w {
+			waiters = append(waiters[:i], waiters[i+1:]...)
+			break
+		}
+	}
+	if len(waiters) == 0 {
+		delete(sm.waiters, w.version)
+		return
+	}
+	sm.waiters[w.version] = waiters
 }
 
 // PollChanges waits for changes since the given client version
@@ -134,35 +232,50 @@ func (sm *StateManager) PollChanges(clientVersion uint64, timeout time.Duration)
 	}
 }
 
-// notifyWaiters notifies all waiting clients of state changes
+// NotifyExternalUpdate wakes pollers after another process advanced the
+// backing store from fromVersion, as happens on fan-out replicas
+func (sm *StateManager) NotifyExternalUpdate(fromVersion uint64) {
+	diff, _ := sm.generateDiffFromVersion(fromVersion)
+	if diff != nil && diff.Version > fromVersion {
+		sm.notifyWaiters(sm.stamp(diff))
+	}
+}
+
+// notifyWaiters delivers diff to every poller at an older version, the
+// furthest behind first and in order of registration within a version. Each
+// notified poller is unregistered, so it receives at most one diff.
 // Moved from: state.go
 func (sm *StateManager) notifyWaiters(diff *StateDiff) {
 	sm.waitersMu.Lock()
 	defer sm.waitersMu.Unlock()
 
-	for key, waiterCh := range sm.waiters {
-		if version, ok := parseWaiterVersion(key); ok && version < diff.Version {
-			sendToWaiter(waiterCh, diff)
+	versions := make([]uint64, 0, len(sm.waiters))
+	for version := range sm.waiters {
+		if version < diff.Version {
+			versions = append(versions, version)
 		}
 	}
-}
+	slices.Sort(versions)
 
-// parseWaiterVersion extracts the version number from a waiter key.
-func parseWaiterVersion(key string) (uint64, bool) {
-	parts := strings.Split(key, "-")
-	if len(parts) < 1 {
-		return 0, false
+	for _, version := range versions {
+		for _, w := range sm.waiters[version] {
+			if !sendToWaiter(w.ch, diff) {
+				sm.events.Record(EventSlowPoll, "poller at version %d missed update to %d", version, diff.Version)
+			}
+		}
+		delete(sm.waiters, version)
 	}
-	version, err := strconv.ParseUint(parts[0], 10, 64)
-	return version, err == nil
 }
 
-// sendToWaiter delivers a diff to a waiter channel without blocking.
-func sendToWaiter(ch chan *StateDiff, diff *StateDiff) {
+// sendToWaiter delivers a diff to a waiter channel without blocking and
+// reports whether it was delivered.
+func sendToWaiter(ch chan *StateDiff, diff *StateDiff) bool {
 	select {
 	case ch <- diff:
+		return true
 	default:
 		// Channel full, skip
+		return false
 	}
 }
 
@@ -188,11 +301,18 @@ func (sm *StateManager) PollChangesWithContext(pollCtx context.Context, version
 // Moved from: state.go
 func (sm *StateManager) generateDiff(oldState, newState *GameState) *StateDiff {
 	diff := &StateDiff{
-		Version:   newState.Version,
-		CursorX:   newState.CursorX,
-		CursorY:   newState.CursorY,
-		Timestamp: newState.Timestamp,
-		Changes:   make([]CellDiff, 0),
+		Version:    newState.Version,
+		CursorX:    newState.CursorX,
+		CursorY:    newState.CursorY,
+		Echo:       newState.Echo,
+		Progress:   newState.Progress,
+		Landmarks:  newState.Landmarks,
+		Expiry:     newState.Expiry,
+		Stall:      newState.Stall,
+		Broadcast:  newState.Broadcast,
+		Scrollback: newState.Scrollback,
+		Timestamp:  newState.Timestamp,
+		Changes:    make([]CellDiff, 0, min(sm.diffHint, newState.Width*newState.Height)),
 	}
 
 	// Compare cells in the overlapping region.
@@ -208,6 +328,12 @@ func (sm *StateManager) generateDiff(oldState, newState *GameState) *StateDiff {
 
 	// Append cells from any expanded region.
 	appendExpandedCells(diff, oldState, newState)
+	sm.diffHint = len(diff.Changes)
+
+	// Diffs are kept in history; do not retain a mostly unused hint capacity
+	if cap(diff.Changes) > 2*len(diff.Changes)+64 {
+		diff.Changes = append([]CellDiff(nil), diff.Changes...)
+	}
 
 	return diff
 }
@@ -229,46 +355,108 @@ func appendExpandedCells(diff *StateDiff, oldState, newState *GameState) {
 // generateDiffFromVersion generates diff from a specific version to current
 // Moved from: state.go
 func (sm *StateManager) generateDiffFromVersion(fromVersion uint64) (*StateDiff, error) {
-	sm.mu.RLock()
-	defer sm.mu.RUnlock()
-
-	if sm.currentState == nil {
+	current := sm.store.Current()
+	if current == nil {
 		return nil, nil
 	}
 
-	// For simplicity, return full state as diff if version is old
-	// In production, you'd want to store historical states or deltas
+	// Replay stored history when it reaches back far enough
+	if fromVersion < current.Version {
+		if diffs, ok := sm.store.DiffsSince(fromVersion); ok && len(diffs) > 0 &&
+			diffs[len(diffs)-1].Version == current.Version {
+			return sm.applyBudget(mergeDiffs(diffs, current), current), nil
+		}
+	}
+
+	// Otherwise send the full state
 	diff := &StateDiff{
-		Version:   sm.currentState.Version,
-		CursorX:   sm.currentState.CursorX,
-		CursorY:   sm.currentState.CursorY,
-		Timestamp: sm.currentState.Timestamp,
-		Changes:   make([]CellDiff, 0),
+		Version:    current.Version,
+		CursorX:    current.CursorX,
+		CursorY:    current.CursorY,
+		Echo:       current.Echo,
+		Progress:   current.Progress,
+		Landmarks:  current.Landmarks,
+		Expiry:     current.Expiry,
+		Stall:      current.Stall,
+		Broadcast:  current.Broadcast,
+		Scrollback: current.Scrollback,
+		Timestamp:  current.Timestamp,
+		Changes:    make([]CellDiff, 0),
 	}
 
 	// Add all cells as changes
-	for y := 0; y < sm.currentState.Height; y++ {
-		for x := 0; x < sm.currentState.Width; x++ {
+	for y := 0; y < current.Height; y++ {
+		for x := 0; x < current.Width; x++ {
 			diff.Changes = append(diff.Changes, CellDiff{
 				X:    x,
 				Y:    y,
-				Cell: sm.currentState.Buffer[y][x],
+				Cell: current.Buffer[y][x],
 			})
 		}
 	}
 
-	return diff, nil
+	return sm.applyBudget(diff, current), nil
+}
+
+// mergeDiffs collapses consecutive diffs into one, keeping the latest value
+// of each cell that lies within the current screen
+func mergeDiffs(diffs []*StateDiff, current *GameState) *StateDiff {
+	merged := &StateDiff{
+		Version:    current.Version,
+		CursorX:    current.CursorX,
+		CursorY:    current.CursorY,
+		Echo:       current.Echo,
+		Progress:   current.Progress,
+		Landmarks:  current.Landmarks,
+		Expiry:     current.Expiry,
+		Stall:      current.Stall,
+		Broadcast:  current.Broadcast,
+		Scrollback: current.Scrollback,
+		Timestamp:  current.Timestamp,
+		Changes:    mergeChanges(diffs, current.Width, current.Height),
+	}
+
+	return merged
+}
+
+// mergeChanges returns the latest change of each cell within width x height
+// across diffs, in the order the cells first changed
+func mergeChanges(diffs []*StateDiff, width, height int) []CellDiff {
+	changes := make([]CellDiff, 0)
+	index := make(map[[2]int]int)
+	for _, diff := range diffs {
+		for _, change := range diff.Changes {
+			if change.X >= width || change.Y >= height {
+				continue
+			}
+			key := [2]int{change.X, change.Y}
+			if i, ok := index[key]; ok {
+				changes[i] = change
+				continue
+			}
+			index[key] = len(changes)
+			changes = append(changes, change)
+		}
+	}
+	return changes
 }
 
 // cellsDiffer compares two cells for differences
 // Moved from: state.go
 func (sm *StateManager) cellsDiffer(a, b Cell) bool {
 	return a.Char != b.Char ||
+		a.Text != b.Text ||
+		a.Wide != b.Wide ||
 		a.FgColor != b.FgColor ||
 		a.BgColor != b.BgColor ||
 		a.Bold != b.Bold ||
 		a.Inverse != b.Inverse ||
 		a.Blink != b.Blink ||
+		a.Underline != b.Underline ||
+		a.UnderlineColor != b.UnderlineColor ||
+		a.Overline != b.Overline ||
+		a.Concealed != b.Concealed ||
 		a.TileX != b.TileX ||
-		a.TileY != b.TileY
+		a.TileY != b.TileY ||
+		!maps.Equal(a.Meta, b.Meta)
 }
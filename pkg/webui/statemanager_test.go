@@ -5,9 +5,12 @@ package webui
 import (
 	"context"
 	"fmt"
+	"reflect"
 	"sync"
 	"testing"
 	"time"
+
+	"github.com/opd-ai/go-gamelaunch-client/pkg/dgclient"
 )
 
 // TestNewStateManager tests the constructor function
@@ -463,13 +466,13 @@ func TestStateManager_notifyWaiters_NotifiesCorrectWaiters(t *testing.T) {
 	// Create a waiter for version 0 (should be notified)
 	waiterCh := make(chan *StateDiff, 1)
 	sm.waitersMu.Lock()
-	sm.waiters["0-123456"] = waiterCh // version 0
+	sm.waiters[0] = []*waiter{{version: 0, ch: waiterCh}}
 	sm.waitersMu.Unlock()
 
 	// Create a waiter for version 2 (should not be notified)
 	waiterCh2 := make(chan *StateDiff, 1)
 	sm.waitersMu.Lock()
-	sm.waiters["2-123457"] = waiterCh2 // version 2
+	sm.waiters[2] = []*waiter{{version: 2, ch: waiterCh2}}
 	sm.waitersMu.Unlock()
 
 	// Create diff for version 2
@@ -504,8 +507,8 @@ func TestStateManager_notifyWaiters_NotifiesCorrectWaiters(t *testing.T) {
 
 	// Cleanup
 	sm.waitersMu.Lock()
-	delete(sm.waiters, "0-123456")
-	delete(sm.waiters, "2-123457")
+	delete(sm.waiters, 0)
+	delete(sm.waiters, 2)
 	sm.waitersMu.Unlock()
 }
 
@@ -693,3 +696,218 @@ func createTestGameState(version uint64) *GameState {
 		Buffer:    createTestBuffer(24, 80),
 	}
 }
+
+// TestStateManager_GetCurrentState_ReturnsDeepCopy verifies that modifying a
+// returned state does not affect the stored state
+func TestStateManager_GetCurrentState_ReturnsDeepCopy(t *testing.T) {
+	sm := NewStateManager()
+	state := &GameState{Buffer: newCellGrid(3, 2), Width: 3, Height: 2}
+	state.Buffer[1][2].Char = 'x'
+	sm.UpdateState(state)
+
+	first := sm.GetCurrentState()
+	first.Buffer[1][2].Char = 'y'
+	first.Buffer[0] = nil
+
+	second := sm.GetCurrentState()
+	if second.Buffer[1][2].Char != 'x' {
+		t.Errorf("Stored cell = %q, want 'x'", second.Buffer[1][2].Char)
+	}
+	if len(second.Buffer[0]) != 3 {
+		t.Errorf("Stored row length = %d, want 3", len(second.Buffer[0]))
+	}
+	if &first.Buffer[1][0] == &second.Buffer[1][0] {
+		t.Error("Copies share a buffer")
+	}
+}
+
+// TestGameState_Clone_PreservesRaggedRows verifies row lengths and capacity
+// limits are kept so appending to one row cannot overwrite the next
+func TestGameState_Clone_PreservesRaggedRows(t *testing.T) {
+	state := &GameState{Buffer: [][]Cell{{{Char: 'a'}}, {}, {{Char: 'b'}, {Char: 'c'}}}, Version: 7}
+
+	clone := state.Clone()
+	if clone.Version != 7 || len(clone.Buffer) != 3 {
+		t.Fatalf("Clone = %+v", clone)
+	}
+	for y, row := range state.Buffer {
+		if len(clone.Buffer[y]) != len(row) {
+			t.Errorf("Row %d length = %d, want %d", y, len(clone.Buffer[y]), len(row))
+		}
+	}
+
+	clone.Buffer[0] = append(clone.Buffer[0], Cell{Char: 'z'})
+	if clone.Buffer[2][0].Char != 'b' {
+		t.Error("Appending to a cloned row overwrote the next row")
+	}
+	if (*GameState)(nil).Clone() != nil {
+		t.Error("Clone of nil state should be nil")
+	}
+}
+
+// TestWebView_GetCurrentState_ConcurrentRender_SnapshotsAreStable renders
+// while readers hold snapshots and checks that a snapshot never changes
+// after it was taken. Run with -race to detect aliasing.
+func TestWebView_GetCurrentState_ConcurrentRender_SnapshotsAreStable(t *testing.T) {
+	view, err := NewWebView(dgclient.ViewOptions{InitialWidth: 20, InitialHeight: 5})
+	if err != nil {
+		t.Fatalf("NewWebView failed: %v", err)
+	}
+	sm := view.GetStateManager()
+	view.Render([]byte("start"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; ctx.Err() == nil; i++ {
+			view.Render([]byte(fmt.Sprintf("\x1b[H%08d", i)))
+		}
+	}()
+
+	for i := 0; i < 200; i++ {
+		for _, state := range []*GameState{view.GetCurrentState(), sm.GetCurrentState()} {
+			before := state.Clone()
+			time.Sleep(10 * time.Microsecond)
+			for y := range before.Buffer {
+				for x := range before.Buffer[y] {
+					if !reflect.DeepEqual(state.Buffer[y][x], before.Buffer[y][x]) {
+						t.Fatalf("Snapshot cell (%d,%d) changed after it was taken", x, y)
+					}
+				}
+			}
+			state.Buffer[0][0].Char = '#'
+		}
+	}
+
+	cancel()
+	wg.Wait()
+
+	if sm.GetCurrentState().Buffer[0][0].Char == '#' {
+		t.Error("Modifying a snapshot changed the stored state")
+	}
+}
+
+func TestStateManager_notifyWaiters_DeliversAtMostOnce(t *testing.T) {
+	sm := NewStateManager()
+
+	register := func(version uint64) *waiter {
+		w := &waiter{version: version, ch: make(chan *StateDiff, 1)}
+		sm.waiters[version] = append(sm.waiters[version], w)
+		return w
+	}
+	behind := []*waiter{register(3), register(1), register(1)}
+	ahead := register(5)
+
+	sm.notifyWaiters(&StateDiff{Version: 4})
+	for i, w := range behind {
+		if len(w.ch) != 1 {
+			t.Errorf("poller %d at version %d was not notified", i, w.version)
+		}
+	}
+	if len(ahead.ch) != 0 {
+		t.Error("poller at a newer version was notified")
+	}
+
+	// Notified pollers are unregistered and never receive a second diff
+	sm.notifyWaiters(&StateDiff{Version: 6})
+	for i, w := range behind {
+		if len(w.ch) != 1 {
+			t.Errorf("poller %d holds %d diffs, want 1", i, len(w.ch))
+		}
+	}
+	if len(ahead.ch) != 1 {
+		t.Error("poller at version 5 was not notified of version 6")
+	}
+	if len(sm.waiters) != 0 {
+		t.Errorf("%d versions still have pollers, want none", len(sm.waiters))
+	}
+}
+
+func TestStateManager_PollChanges_DeliversVersionsInOrder(t *testing.T) {
+	sm := NewStateManager()
+	const writers, updates, pollers = 4, 50, 8
+	final := uint64(writers * updates)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// Each poller applies the diffs it receives to a screen of its own,
+	// which only ends up as the final screen when they come in order
+	errs := make(chan error, pollers)
+	screens := make(chan []rune, pollers)
+	for p := 0; p < pollers; p++ {
+		go func() {
+			screen := make([]rune, 4)
+			var version uint64
+			for version < final {
+				diff, err := sm.PollChangesWithContext(ctx, version)
+				if err != nil {
+					errs <- fmt.Errorf("poll at version %d: %w", version, err)
+					return
+				}
+				if diff.Version <= version {
+					errs <- fmt.Errorf("received version %d after %d", diff.Version, version)
+					return
+				}
+				for _, change := range diff.Changes {
+					screen[change.X] = change.Cell.Char
+				}
+				version = diff.Version
+			}
+			errs <- nil
+			screens <- screen
+		}()
+	}
+
+	var wg sync.WaitGroup
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < updates; i++ {
+				sm.UpdateState(lineState(fmt.Sprintf("%d%03d", w, i)))
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	var want []rune
+	for _, cell := range sm.GetCurrentState().Buffer[0] {
+		want = append(want, cell.Char)
+	}
+	for p := 0; p < pollers; p++ {
+		if err := <-errs; err != nil {
+			t.Fatal(err)
+		}
+	}
+	for p := 0; p < pollers; p++ {
+		if screen := <-screens; string(screen) != string(want) {
+			t.Errorf("poller screen = %q, want %q", string(screen), string(want))
+		}
+	}
+}
+
+func TestStateManager_registerWaiter_CleanupKeepsOrder(t *testing.T) {
+	sm := NewStateManager()
+	sm.UpdateState(createTestGameState(1))
+
+	first, _ := sm.registerWaiter(1)
+	second, _ := sm.registerWaiter(1)
+	third, _ := sm.registerWaiter(1)
+	second.cleanup()
+
+	sm.waitersMu.Lock()
+	waiters := sm.waiters[1]
+	sm.waitersMu.Unlock()
+	if len(waiters) != 2 || waiters[0].ch != first.waiterCh || waiters[1].ch != third.waiterCh {
+		t.Fatalf("waiters after cleanup = %v, want the first and third in order", waiters)
+	}
+
+	first.cleanup()
+	third.cleanup()
+	if len(sm.waiters) != 0 {
+		t.Errorf("%d versions still have pollers after cleanup, want none", len(sm.waiters))
+	}
+}
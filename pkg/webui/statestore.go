@@ -0,0 +1,195 @@
+// Package webui provides pluggable storage for game state and diff history.
+package webui
+
+import (
+	"context"
+	"math"
+	"sync"
+)
+
+// defaultDiffHistory is the number of diffs kept by MemoryStateStore
+const defaultDiffHistory = 64
+
+// StateStore persists the current game state, its version and a bounded
+// history of diffs. Implementations must be safe for concurrent use; the
+// StateManager serializes calls to Save.
+//
+// Alternative backends (for example Redis for multi-process fan-out or disk
+// persistence) can be supplied through NewStateManagerWithStore. Backends
+// that cannot reach their storage should return their last known values from
+// the read methods.
+type StateStore interface {
+	// Save records state as the current state. diff holds the changes from
+	// the previous state and is nil for the first state. After an error the
+	// previous state must remain current.
+	Save(state *GameState, diff *StateDiff) error
+
+	// Current returns the current state, or nil before the first Save
+	Current() *GameState
+
+	// Version returns the version of the current state
+	Version() uint64
+
+	// DiffsSince returns the diffs that lead from version to the current
+	// state in order. ok is false when the history no longer reaches back to
+	// version.
+	DiffsSince(version uint64) (diffs []*StateDiff, ok bool)
+}
+
+// ContextSaver is implemented by state stores whose Save may block, e.g. on
+// the network, so that a cancelled update stops waiting for it
+type ContextSaver interface {
+	// SaveContext is Save that gives up when ctx is done
+	SaveContext(ctx context.Context, state *GameState, diff *StateDiff) error
+}
+
+// saveState saves state to store, with ctx when the store takes one
+func saveState(ctx context.Context, store StateStore, state *GameState, diff *StateDiff) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if saver, ok := store.(ContextSaver); ok {
+		return saver.SaveContext(ctx, state, diff)
+	}
+	return store.Save(state, diff)
+}
+
+// HistoryCompactor is implemented by stores that can merge their older
+// diffs into deltas spanning several versions. Clients within the range of a
+// delta receive all of it, which also brings them up to date.
+type HistoryCompactor interface {
+	// Compact merges runs of consecutive diffs, except the newest recent
+	// ones, into deltas covering up to span versions each
+	Compact(recent, span int)
+}
+
+// MemoryStateStore is the default in-process StateStore
+type MemoryStateStore struct {
+	mu         sync.RWMutex
+	current    *GameState
+	history    []historyEntry
+	maxHistory int
+}
+
+// historyEntry is a diff leading from version from to diff.Version, which is
+// more than one version apart after compaction
+type historyEntry struct {
+	from uint64
+	diff *StateDiff
+}
+
+// NewMemoryStateStore creates an in-memory store keeping up to maxHistory
+// diffs; zero or negative uses the default
+func NewMemoryStateStore(maxHistory int) *MemoryStateStore {
+	if maxHistory <= 0 {
+		maxHistory = defaultDiffHistory
+	}
+	return &MemoryStateStore{maxHistory: maxHistory}
+}
+
+// Save implements StateStore
+func (s *MemoryStateStore) Save(state *GameState, diff *StateDiff) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.current = state
+	if diff == nil {
+		s.history = s.history[:0]
+		return nil
+	}
+
+	s.history = append(s.history, historyEntry{from: diff.Version - 1, diff: diff})
+	if len(s.history) > s.maxHistory {
+		s.history = append(s.history[:0], s.history[len(s.history)-s.maxHistory:]...)
+	}
+	return nil
+}
+
+// Current implements StateStore
+func (s *MemoryStateStore) Current() *GameState {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.current
+}
+
+// Version implements StateStore
+func (s *MemoryStateStore) Version() uint64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.current == nil {
+		return 0
+	}
+	return s.current.Version
+}
+
+// DiffsSince implements StateStore
+func (s *MemoryStateStore) DiffsSince(version uint64) ([]*StateDiff, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for i, entry := range s.history {
+		if entry.from <= version && version < entry.diff.Version {
+			diffs := make([]*StateDiff, 0, len(s.history)-i)
+			for _, entry := range s.history[i:] {
+				diffs = append(diffs, entry.diff)
+			}
+			return diffs, true
+		}
+	}
+	return nil, false
+}
+
+// Compact implements HistoryCompactor
+func (s *MemoryStateStore) Compact(recent, span int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	old := len(s.history) - recent
+	if old < 2 {
+		return
+	}
+	compacted := make([]historyEntry, 0, old)
+	var run []historyEntry
+	flush := func() {
+		if len(run) == 1 {
+			compacted = append(compacted, run[0])
+		} else if len(run) > 1 {
+			compacted = append(compacted, mergeEntries(run))
+		}
+		run = run[:0]
+	}
+	for _, entry := range s.history[:old] {
+		if len(run) > 0 && entry.diff.Version-run[0].from > uint64(span) {
+			flush()
+		}
+		run = append(run, entry)
+	}
+	flush()
+	s.history = append(compacted, s.history[old:]...)
+}
+
+// mergeEntries returns one entry covering the consecutive entries of run.
+// The diffs may still be held by readers and are not modified.
+func mergeEntries(run []historyEntry) historyEntry {
+	diffs := make([]*StateDiff, len(run))
+	for i, entry := range run {
+		diffs[i] = entry.diff
+	}
+	last := diffs[len(diffs)-1]
+	return historyEntry{from: run[0].from, diff: &StateDiff{
+		Version:    last.Version,
+		CursorX:    last.CursorX,
+		CursorY:    last.CursorY,
+		Echo:       last.Echo,
+		Progress:   last.Progress,
+		Landmarks:  last.Landmarks,
+		Expiry:     last.Expiry,
+		Stall:      last.Stall,
+		Broadcast:  last.Broadcast,
+		Scrollback: last.Scrollback,
+		Timestamp:  last.Timestamp,
+		Changes:    mergeChanges(diffs, math.MaxInt, math.MaxInt),
+	}}
+}
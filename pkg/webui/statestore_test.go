@@ -0,0 +1,271 @@
+package webui
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/opd-ai/go-gamelaunch-client/pkg/dgclient"
+)
+
+func TestMemoryStateStore_DiffsSince(t *testing.T) {
+	store := NewMemoryStateStore(3)
+
+	if _, ok := store.DiffsSince(0); ok {
+		t.Error("Expected empty store to have no history")
+	}
+
+	store.Save(&GameState{Version: 1}, nil)
+	for v := uint64(2); v <= 5; v++ {
+		store.Save(&GameState{Version: v}, &StateDiff{Version: v})
+	}
+
+	if store.Version() != 5 {
+		t.Errorf("Version() = %d, want 5", store.Version())
+	}
+
+	tests := []struct {
+		since    uint64
+		wantOK   bool
+		wantDiff int
+	}{
+		{1, false, 0}, // trimmed from history
+		{2, true, 3},
+		{4, true, 1},
+		{5, false, 0},
+	}
+	for _, tt := range tests {
+		diffs, ok := store.DiffsSince(tt.since)
+		if ok != tt.wantOK || len(diffs) != tt.wantDiff {
+			t.Errorf("DiffsSince(%d) = %d diffs, ok=%v; want %d, ok=%v", tt.since, len(diffs), ok, tt.wantDiff, tt.wantOK)
+		}
+	}
+}
+
+func TestStateManager_generateDiffFromVersion_MergesHistory(t *testing.T) {
+	sm := NewStateManager()
+
+	for i, char := range []rune{'a', 'b', 'c'} {
+		state := &GameState{Width: 2, Height: 2, Buffer: createTestBuffer(2, 2)}
+		state.Buffer[0][0] = Cell{Char: char}
+		if i == 2 {
+			state.Buffer[1][1] = Cell{Char: 'z'}
+		}
+		sm.UpdateState(state)
+	}
+
+	diff, err := sm.generateDiffFromVersion(1)
+	if err != nil {
+		t.Fatalf("generateDiffFromVersion() error = %v", err)
+	}
+	if diff.Version != 3 {
+		t.Errorf("Diff version = %d, want 3", diff.Version)
+	}
+	if len(diff.Changes) != 2 {
+		t.Fatalf("Diff has %d changes, want 2 merged changes", len(diff.Changes))
+	}
+	if diff.Changes[0].Cell.Char != 'c' {
+		t.Errorf("Merged cell (0,0) = %q, want latest value 'c'", diff.Changes[0].Cell.Char)
+	}
+}
+
+func TestMemoryStateStore_Compact(t *testing.T) {
+	store := NewMemoryStateStore(0)
+	sm := NewStateManagerWithStore(store)
+
+	// Every version changes one of three cells; keep each state to catch up from
+	states := make(map[uint64]*GameState)
+	for v := uint64(1); v <= 21; v++ {
+		state := &GameState{Width: 3, Height: 1, Buffer: createTestBuffer(1, 3)}
+		if previous := sm.GetCurrentState(); previous != nil {
+			state.Buffer = previous.Buffer
+		}
+		state.Buffer[0][v%3] = Cell{Char: rune('a' + v)}
+		sm.UpdateState(state)
+		states[v] = sm.GetCurrentState()
+	}
+
+	store.Compact(4, 8)
+	if diffs, _ := store.DiffsSince(1); len(diffs) != 6 {
+		t.Fatalf("history has %d entries after compaction, want 2 deltas and 4 diffs", len(diffs))
+	}
+	store.Compact(4, 8)
+	if diffs, _ := store.DiffsSince(1); len(diffs) != 6 {
+		t.Errorf("history has %d entries after compacting again, want 6", len(diffs))
+	}
+
+	tests := []struct {
+		since   uint64
+		wantOK  bool
+		entries int
+	}{
+		{0, false, 0},
+		{1, true, 6},  // start of the first delta
+		{5, true, 6},  // within the first delta
+		{12, true, 5}, // within the second delta
+		{17, true, 4}, // first diff kept as it is
+		{21, false, 0},
+	}
+	for _, tt := range tests {
+		diffs, ok := store.DiffsSince(tt.since)
+		if ok != tt.wantOK || len(diffs) != tt.entries {
+			t.Errorf("DiffsSince(%d) = %d entries, ok=%v; want %d, ok=%v", tt.since, len(diffs), ok, tt.entries, tt.wantOK)
+			continue
+		}
+		if !ok {
+			continue
+		}
+
+		// Applying the catch-up diff to the client's state yields the current state
+		diff, err := sm.generateDiffFromVersion(tt.since)
+		if err != nil {
+			t.Fatalf("generateDiffFromVersion(%d) error = %v", tt.since, err)
+		}
+		caughtUp := states[tt.since].Clone()
+		for _, change := range diff.Changes {
+			caughtUp.Buffer[change.Y][change.X] = change.Cell
+		}
+		for x, cell := range caughtUp.Buffer[0] {
+			if want := states[21].Buffer[0][x]; cell.Char != want.Char {
+				t.Errorf("catching up from %d: cell %d = %q, want %q", tt.since, x, cell.Char, want.Char)
+			}
+		}
+	}
+}
+
+// recordingStore wraps MemoryStateStore to observe saves
+type recordingStore struct {
+	*MemoryStateStore
+	saves int
+}
+
+func (s *recordingStore) Save(state *GameState, diff *StateDiff) error {
+	s.saves++
+	return s.MemoryStateStore.Save(state, diff)
+}
+
+func TestNewWebViewWithStore_UsesCustomStore(t *testing.T) {
+	store := &recordingStore{MemoryStateStore: NewMemoryStateStore(0)}
+	view, err := NewWebViewWithStore(dgclient.ViewOptions{InitialWidth: 10, InitialHeight: 2}, store)
+	if err != nil {
+		t.Fatalf("NewWebViewWithStore failed: %v", err)
+	}
+
+	if err := view.Render([]byte("hi")); err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	if store.saves == 0 {
+		t.Error("Expected the view to save state through the custom store")
+	}
+	if view.GetStateManager().Store() != store {
+		t.Error("Expected state manager to expose the custom store")
+	}
+}
+
+// failingStore wraps MemoryStateStore, failing saves while fail is set
+type failingStore struct {
+	*MemoryStateStore
+	fail bool
+}
+
+func (s *failingStore) Save(state *GameState, diff *StateDiff) error {
+	if s.fail {
+		return errors.New("store unavailable")
+	}
+	return s.MemoryStateStore.Save(state, diff)
+}
+
+// lineState returns a one-row state showing text
+func lineState(text string) *GameState {
+	state := &GameState{Width: len(text), Height: 1, Buffer: newCellGrid(len(text), 1)}
+	for x, char := range text {
+		state.Buffer[0][x].Char = char
+	}
+	return state
+}
+
+func TestStateManager_UpdateState_FailedSave(t *testing.T) {
+	store := &failingStore{MemoryStateStore: NewMemoryStateStore(0)}
+	sm := NewStateManagerWithStore(store)
+	sm.UpdateState(lineState("ab"))
+
+	// A state the store refused is neither current nor delivered
+	store.fail = true
+	sm.UpdateState(lineState("xb"))
+	if got := sm.GetCurrentVersion(); got != 1 {
+		t.Errorf("version = %d after a failed save, want 1", got)
+	}
+	if diff, _ := sm.PollChanges(1, 50*time.Millisecond); diff != nil {
+		t.Errorf("pollers received version %d the store refused", diff.Version)
+	}
+
+	// The next state follows the last saved one, also for pollers
+	store.fail = false
+	polled := make(chan *StateDiff, 1)
+	go func() {
+		diff, _ := sm.PollChanges(1, 5*time.Second)
+		polled <- diff
+	}()
+	for {
+		sm.waitersMu.Lock()
+		waiting := len(sm.waiters[1])
+		sm.waitersMu.Unlock()
+		if waiting > 0 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	sm.UpdateState(lineState("xy"))
+	if diff := <-polled; diff == nil || diff.Version != 2 || len(diff.Changes) != 2 {
+		t.Errorf("poller received %+v, want version 2 changing both cells", diff)
+	}
+	diffs, ok := store.DiffsSince(1)
+	if !ok || len(diffs) != 1 || diffs[0].Version != 2 || len(diffs[0].Changes) != 2 {
+		t.Errorf("history after recovery = %+v, %v, want one diff to version 2 changing both cells", diffs, ok)
+	}
+}
+
+func TestStateManager_UpdateState_Concurrent(t *testing.T) {
+	store := NewMemoryStateStore(100)
+	sm := NewStateManagerWithStore(store)
+	sm.UpdateState(lineState("...."))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 10; j++ {
+				text := []byte("....")
+				text[i] = byte('0' + j)
+				sm.UpdateState(lineState(string(text)))
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	// Every update got its own version, diffed against the one before
+	if got := sm.GetCurrentVersion(); got != 41 {
+		t.Fatalf("version = %d after 41 updates", got)
+	}
+	diffs, ok := store.DiffsSince(1)
+	if !ok || len(diffs) != 40 {
+		t.Fatalf("history = %d diffs, %v, want 40", len(diffs), ok)
+	}
+	replayed := lineState("....")
+	for i, diff := range diffs {
+		if diff.Version != uint64(i+2) {
+			t.Fatalf("diff %d has version %d", i, diff.Version)
+		}
+		for _, change := range diff.Changes {
+			replayed.Buffer[change.Y][change.X] = change.Cell
+		}
+	}
+	current := sm.GetCurrentState()
+	for x := range current.Buffer[0] {
+		if replayed.Buffer[0][x].Char != current.Buffer[0][x].Char {
+			t.Errorf("replaying the history gives %q at %d, want %q", replayed.Buffer[0][x].Char, x, current.Buffer[0][x].Char)
+		}
+	}
+}
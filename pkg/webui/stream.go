@@ -0,0 +1,211 @@
+// Package webui provides streaming of game state to WebSocket clients.
+package webui
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/opd-ai/go-gamelaunch-www/pkg/transport"
+)
+
+// streamState pushes each state of view newer than version to all WebSocket
+// clients until ctx is cancelled
+func (w *WebUI) streamState(ctx context.Context, view *WebView, version uint64) {
+	sm := view.GetStateManager()
+	for {
+		if _, err := sm.PollChangesWithContext(ctx, version); err != nil {
+			return
+		}
+
+		state := sm.GetCurrentState()
+		if state == nil || state.Version <= version {
+			continue
+		}
+		w.broadcastState(state)
+		version = state.Version
+
+		// Over a bandwidth cap, coalesce updates into one per interval
+		if w.bandwidth != nil && w.bandwidth.isDegraded() {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(w.bandwidth.interval):
+			}
+		}
+	}
+}
+
+// broadcastState sends state to every WebSocket client, rendered for its
+// view options
+func (w *WebUI) broadcastState(state *GameState) {
+	var payloads []*transport.StatePayload
+	w.wsHandler.BroadcastStateFunc(func(opts transport.ViewOptions) *transport.StatePayload {
+		payload := toStatePayloadFor(state, opts, w.colors)
+		payloads = append(payloads, payload)
+		return payload
+	})
+	for _, payload := range payloads {
+		releaseStatePayload(payload)
+	}
+}
+
+// handleClientConnect sends the current screen to a newly connected client
+func (w *WebUI) handleClientConnect(clientID string) {
+	view := w.GetView()
+	if view == nil {
+		return
+	}
+	view.Events().Record(EventClientConnect, "%s connected (%d clients)", clientID, w.wsHandler.GetClientCount())
+	w.attach()
+	if w.teardown != nil {
+		if ctx, ok := w.wsHandler.ClientContext(clientID); ok && w.authorize(ctx, MethodInput) {
+			w.teardown.connect(clientID)
+		}
+	}
+	w.sendCurrentState(clientID)
+}
+
+// sendCurrentState sends the current screen to a single client, rendered
+// for its view options
+func (w *WebUI) sendCurrentState(clientID string) {
+	view := w.GetView()
+	if view == nil {
+		return
+	}
+	state := view.GetStateManager().GetCurrentState()
+	if state == nil {
+		return
+	}
+
+	opts, _ := w.wsHandler.ViewOptions(clientID)
+	statePayload := toStatePayloadFor(state, opts, w.colors)
+	payload, err := json.Marshal(statePayload)
+	releaseStatePayload(statePayload)
+	if err != nil {
+		return
+	}
+	err = w.wsHandler.SendToClient(clientID, transport.Message{
+		Type:      transport.MsgTypeState,
+		Payload:   payload,
+		Timestamp: time.Now().UnixMilli(),
+	})
+	if err != nil {
+		w.clientLogger(clientID).Debug("webui.sendCurrentState: state not sent", "client", clientID, "error", err)
+	}
+}
+
+// handleClientDisconnect records a client leaving the session, detaching it
+// once no client is left and starting the teardown once no player is
+func (w *WebUI) handleClientDisconnect(clientID string) {
+	if view := w.GetView(); view != nil {
+		view.Events().Record(EventClientDisconnect, "%s disconnected (%d clients)", clientID, w.wsHandler.GetClientCount())
+	}
+	w.detached()
+	if w.teardown != nil {
+		w.teardown.disconnect(clientID, w.tearDown)
+	}
+}
+
+// toStatePayload converts a game state to its wire representation. The
+// cells come from a pool; callers release them with releaseStatePayload once
+// the payload has been marshalled.
+func toStatePayload(state *GameState) *transport.StatePayload {
+	return toStatePayloadFor(state, transport.ViewOptions{}, nil)
+}
+
+// toStatePayloadFor converts a game state to its wire representation with a
+// client's view options applied. colors may be nil when no color mode is
+// requested.
+func toStatePayloadFor(state *GameState, opts transport.ViewOptions, colors *ColorConverter) *transport.StatePayload {
+	payload := &transport.StatePayload{
+		Buffer:     make([][]transport.Cell, len(state.Buffer)),
+		Width:      state.Width,
+		Height:     state.Height,
+		CursorX:    state.CursorX,
+		CursorY:    state.CursorY,
+		Echo:       state.Echo,
+		Pinned:     toWireRegions(state.Pinned),
+		Progress:   toWireProgress(state.Progress),
+		Landmarks:  toWireLandmarks(state.Landmarks),
+		Expiry:     toWireExpiry(state.Expiry),
+		Stall:      toWireStall(state.Stall),
+		Broadcast:  toWireBroadcast(state.Broadcast),
+		Scrollback: toWireScrollback(state.Scrollback),
+		Version:    state.Version,
+		Timestamp:  state.Timestamp,
+	}
+
+	total := 0
+	for _, row := range state.Buffer {
+		total += len(row)
+	}
+	cells := getWireCells(total)
+
+	offset := 0
+	for y, row := range state.Buffer {
+		payload.Buffer[y] = cells[offset : offset+len(row)]
+		offset += len(row)
+		for x, cell := range row {
+			fg, bg, inverse := cell.FgColor, cell.BgColor, cell.Inverse
+			if inverse && opts.ResolveInverse {
+				fg, bg, inverse = bg, fg, false
+			}
+			underlineColor := cell.UnderlineColor
+			if opts.ColorMode != transport.ColorModeNormal && colors != nil {
+				fg, bg = colors.TransformColors(fg, bg, opts.ColorMode)
+				if underlineColor != "" {
+					underlineColor, _ = colors.TransformColors(underlineColor, cell.BgColor, opts.ColorMode)
+				}
+			}
+			if opts.Palette == transport.PaletteANSI16 && colors != nil {
+				fg, bg = colors.QuantizeColors(fg, bg)
+				if underlineColor != "" {
+					underlineColor, _ = colors.QuantizeColors(underlineColor, cell.BgColor)
+				}
+			}
+			char, tileX, tileY, meta, concealed := cell.Grapheme(), cell.TileX, cell.TileY, cell.Meta, cell.Concealed
+			switch {
+			case !concealed:
+			case opts.WithholdConcealed:
+				if char != "" {
+					char = " "
+				}
+				tileX, tileY, meta = 0, 0, nil
+			case opts.RevealConcealed:
+				concealed = false
+			}
+			payload.Buffer[y][x] = transport.Cell{
+				Char:    char,
+				FgColor: fg,
+				BgColor: bg,
+				Bold:    cell.Bold,
+				Inverse: inverse,
+				Blink:   cell.Blink,
+				TileX:   tileX,
+				TileY:   tileY,
+				Wide:    cell.Wide,
+				Meta:    meta,
+
+				Underline:      cell.Underline,
+				UnderlineColor: underlineColor,
+				Overline:       cell.Overline,
+				Concealed:      concealed,
+			}
+		}
+	}
+
+	return payload
+}
+
+// toWireRegions converts pinned regions to their wire representation
+func toWireRegions(regions []ScreenRegion) []transport.Region {
+	if len(regions) == 0 {
+		return nil
+	}
+	wire := make([]transport.Region, len(regions))
+	for i, region := range regions {
+		wire[i] = transport.Region{Name: region.Name, Top: region.Top, Bottom: region.Bottom}
+	}
+	return wire
+}
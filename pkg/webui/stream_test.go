@@ -0,0 +1,108 @@
+package webui
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/opd-ai/go-gamelaunch-www/pkg/transport"
+	"nhooyr.io/websocket"
+	"nhooyr.io/websocket/wsjson"
+)
+
+// readState reads messages from conn until a state message arrives
+func readState(t *testing.T, ctx context.Context, conn *websocket.Conn) *transport.StatePayload {
+	t.Helper()
+
+	for {
+		var msg transport.Message
+		if err := wsjson.Read(ctx, conn, &msg); err != nil {
+			t.Fatalf("Failed to read message: %v", err)
+		}
+		if msg.Type != transport.MsgTypeState {
+			continue
+		}
+
+		var state transport.StatePayload
+		if err := json.Unmarshal(msg.Payload, &state); err != nil {
+			t.Fatalf("Failed to decode state: %v", err)
+		}
+		return &state
+	}
+}
+
+func TestWebUI_StreamState_PushesUpdates(t *testing.T) {
+	ui := newTestWebUI(t)
+	if err := ui.GetView().Render([]byte("hello")); err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	go ui.streamState(ctx, ui.GetView(), ui.GetView().GetStateManager().GetCurrentVersion())
+
+	server := httptest.NewServer(ui)
+	defer server.Close()
+
+	conn, _, err := websocket.Dial(ctx, "ws"+strings.TrimPrefix(server.URL, "http")+"/ws", nil)
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	defer conn.Close(websocket.StatusNormalClosure, "")
+	conn.SetReadLimit(1 << 20)
+
+	// New clients receive the current screen immediately
+	initial := readState(t, ctx, conn)
+	if initial.Buffer[0][0].Char != "h" {
+		t.Errorf("Initial state cell (0,0) = %q, want %q", initial.Buffer[0][0].Char, "h")
+	}
+
+	// Later renders are pushed
+	if err := ui.GetView().Render([]byte("!")); err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	update := readState(t, ctx, conn)
+	if update.Version <= initial.Version {
+		t.Errorf("Update version %d not newer than %d", update.Version, initial.Version)
+	}
+	if update.Buffer[0][5].Char != "!" {
+		t.Errorf("Updated cell (5,0) = %q, want %q", update.Buffer[0][5].Char, "!")
+	}
+}
+
+func TestWebUI_StreamState_AppliesClientViewOptions(t *testing.T) {
+	ui := newTestWebUI(t)
+	if err := ui.GetView().Render([]byte("\x1b[91mD")); err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	go ui.streamState(ctx, ui.GetView(), ui.GetView().GetStateManager().GetCurrentVersion())
+
+	server := httptest.NewServer(ui)
+	defer server.Close()
+
+	conn, _, err := websocket.Dial(ctx, "ws"+strings.TrimPrefix(server.URL, "http")+"/ws?color_mode=deuteranopia", nil)
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	defer conn.Close(websocket.StatusNormalClosure, "")
+	conn.SetReadLimit(1 << 20)
+
+	if got := readState(t, ctx, conn).Buffer[0][0].FgColor; got != "#D55E00" {
+		t.Errorf("Initial FgColor = %s, want #D55E00", got)
+	}
+
+	// Switching modes resends the screen with the new palette
+	payload, _ := json.Marshal(transport.ViewOptions{ColorMode: transport.ColorModeNormal})
+	if err := wsjson.Write(ctx, conn, transport.Message{Type: transport.MsgTypeViewOptions, Payload: payload}); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	if got := readState(t, ctx, conn).Buffer[0][0].FgColor; got != "#FF0000" {
+		t.Errorf("FgColor after switching to normal = %s, want #FF0000", got)
+	}
+}
@@ -0,0 +1,123 @@
+// Package webui provides graceful teardown of the game session once the
+// last player leaves: after a grace period the game is sent a key sequence,
+// such as save and quit, before the session is closed.
+package webui
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// TeardownConfig ends the session once no WebSocket client allowed to send
+// input has been connected for Grace. Keys, e.g. "Sy" to save in NetHack,
+// are then sent to the game, and the session expires Wait later. A player
+// connecting during Grace keeps the session.
+type TeardownConfig struct {
+	Grace string `yaml:"grace,omitempty" yamlcheck:"duration"` // default "5m"
+	Keys  string `yaml:"keys,omitempty"`                       // sent as they are; YAML "\e" is Escape
+	Wait  string `yaml:"wait,omitempty" yamlcheck:"duration"`  // default "10s"
+}
+
+// teardownWatch counts the connected clients that control the game and
+// tears the session down once they have all been gone for the grace period
+type teardownWatch struct {
+	grace time.Duration
+	keys  []byte
+	wait  time.Duration
+
+	mu          sync.Mutex
+	controllers map[string]bool // client IDs
+	timer       *time.Timer     // pending teardown, or nil
+	done        bool
+}
+
+// newTeardownWatch validates cfg
+func newTeardownWatch(cfg TeardownConfig) (*teardownWatch, error) {
+	t := &teardownWatch{
+		grace:       5 * time.Minute,
+		keys:        []byte(cfg.Keys),
+		wait:        10 * time.Second,
+		controllers: make(map[string]bool),
+	}
+	for _, d := range []struct {
+		name  string
+		value string
+		dst   *time.Duration
+	}{
+		{"grace", cfg.Grace, &t.grace},
+		{"wait", cfg.Wait, &t.wait},
+	} {
+		if d.value == "" {
+			continue
+		}
+		parsed, err := time.ParseDuration(d.value)
+		if err != nil || parsed < 0 {
+			return nil, fmt.Errorf("teardown: invalid %s %q", d.name, d.value)
+		}
+		*d.dst = parsed
+	}
+	return t, nil
+}
+
+// connect records a client that controls the game, calling off a pending
+// teardown
+func (t *teardownWatch) connect(clientID string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.controllers[clientID] = true
+	if t.timer != nil {
+		t.timer.Stop()
+		t.timer = nil
+	}
+}
+
+// disconnect forgets clientID, and calls teardown after the grace period
+// once no controller is left
+func (t *teardownWatch) disconnect(clientID string, teardown func()) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.controllers[clientID] {
+		return
+	}
+	delete(t.controllers, clientID)
+	if len(t.controllers) > 0 || t.done || t.timer != nil {
+		return
+	}
+	var timer *time.Timer
+	timer = time.AfterFunc(t.grace, func() {
+		t.mu.Lock()
+		if t.timer != timer {
+			t.mu.Unlock()
+			return
+		}
+		t.timer, t.done = nil, true
+		t.mu.Unlock()
+		teardown()
+	})
+	t.timer = timer
+}
+
+// pending reports whether a teardown waits for the grace period to pass
+func (t *teardownWatch) pending() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.timer != nil
+}
+
+// tearDown sends the teardown keys to the game and expires the session
+// once the game has had time to act on them
+func (w *WebUI) tearDown() {
+	w.log.Info("webui: last player left, tearing down the session", "keys", len(w.teardown.keys))
+	if view := w.GetView(); view != nil {
+		view.Events().Record(EventTeardown, "last player left; sending %d teardown bytes", len(w.teardown.keys))
+	}
+	if len(w.teardown.keys) > 0 {
+		if err := w.sendGameInput(w.teardown.keys); err != nil {
+			w.log.Warn("webui: failed to send teardown keys", "error", err)
+		}
+	}
+	if _, err := w.scheduleExpiry(ExpiryDeparted, w.teardown.wait, "", false); err != nil {
+		w.log.Warn("webui: failed to schedule teardown", "error", err)
+	}
+}
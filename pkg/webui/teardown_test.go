@@ -0,0 +1,76 @@
+package webui
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestTeardownWatch_LastControllerLeaves(t *testing.T) {
+	watch, err := newTeardownWatch(TeardownConfig{Grace: "20ms"})
+	if err != nil {
+		t.Fatalf("newTeardownWatch failed: %v", err)
+	}
+	var calls atomic.Int32
+	teardown := func() { calls.Add(1) }
+
+	// Spectators and the remaining players keep the session
+	watch.connect("p1")
+	watch.connect("p2")
+	watch.disconnect("spectator", teardown)
+	watch.disconnect("p1", teardown)
+	if watch.pending() {
+		t.Fatal("teardown pending with a player connected")
+	}
+
+	// A player returning within the grace period calls it off
+	watch.disconnect("p2", teardown)
+	if !watch.pending() {
+		t.Fatal("no teardown pending after the last player left")
+	}
+	watch.connect("p2")
+	time.Sleep(50 * time.Millisecond)
+	if calls.Load() != 0 || watch.pending() {
+		t.Fatalf("teardown ran although the player returned")
+	}
+
+	watch.disconnect("p2", teardown)
+	deadline := time.Now().Add(5 * time.Second)
+	for calls.Load() == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	watch.connect("p3")
+	watch.disconnect("p3", teardown)
+	time.Sleep(50 * time.Millisecond)
+	if calls.Load() != 1 {
+		t.Errorf("teardown ran %d times, want once", calls.Load())
+	}
+
+	for _, cfg := range []TeardownConfig{{Grace: "soon"}, {Wait: "-1s"}} {
+		if _, err := newTeardownWatch(cfg); err == nil {
+			t.Errorf("newTeardownWatch(%+v) accepted an invalid config", cfg)
+		}
+	}
+}
+
+func TestWebUI_TearDown(t *testing.T) {
+	view := newTestView(t)
+	var sent []byte
+	ui, err := NewWebUI(WebUIOptions{
+		View:      view,
+		Teardown:  &TeardownConfig{Keys: "Sy", Wait: "30s"},
+		InputSink: func(data []byte) error { sent = append(sent, data...); return nil },
+	})
+	if err != nil {
+		t.Fatalf("Failed to create WebUI: %v", err)
+	}
+
+	ui.tearDown()
+	if string(sent) != "Sy" {
+		t.Errorf("game input = %q, want the teardown keys", sent)
+	}
+	expiry := view.GetStateManager().GetCurrentState().Expiry
+	if expiry == nil || expiry.Reason != ExpiryDeparted || expiry.Cancelable {
+		t.Errorf("expiry = %+v, want a departed expiry no keystroke cancels", expiry)
+	}
+}
@@ -0,0 +1,129 @@
+// Package webui provides routing of isolated tenants under their own URL
+// prefixes, so one process can host web access for several communities.
+package webui
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// TenantRouter serves several tenants from one listener, each below its own
+// URL prefix such as /club; all other paths go to Home. A tenant is usually
+// a SessionRouter or a WebUI configured with the tenant's own login, so
+// requests are routed with the prefix removed and every tenant
+// authenticates and authorizes them on its own. Cookies set by the tenant
+// are scoped to its prefix.
+type TenantRouter struct {
+	home http.Handler
+
+	mu      sync.RWMutex
+	tenants map[string]http.Handler // by prefix
+}
+
+// NewTenantRouter creates a router serving paths outside the tenants with
+// home, or answering them with 404 when home is nil
+func NewTenantRouter(home http.Handler) *TenantRouter {
+	if home == nil {
+		home = http.NotFoundHandler()
+	}
+	return &TenantRouter{home: home, tenants: make(map[string]http.Handler)}
+}
+
+// Add serves handler below prefix, one or more path segments such as
+// "/club" or "/hosting/club"
+func (tr *TenantRouter) Add(prefix string, handler http.Handler) error {
+	if err := validateTenantPrefix(prefix); err != nil {
+		return err
+	}
+
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	if _, ok := tr.tenants[prefix]; ok {
+		return fmt.Errorf("tenants: prefix %q is already in use", prefix)
+	}
+	tr.tenants[prefix] = handler
+	return nil
+}
+
+// validateTenantPrefix accepts absolute paths of segments that need no
+// escaping and cannot be mistaken for a route of the WebUI
+func validateTenantPrefix(prefix string) error {
+	rest, ok := strings.CutPrefix(prefix, "/")
+	if !ok {
+		return fmt.Errorf("tenants: prefix %q must start with /", prefix)
+	}
+	for _, segment := range strings.Split(rest, "/") {
+		if !sessionIDPattern.MatchString(segment) || segment == "." || segment == ".." {
+			return fmt.Errorf("tenants: invalid prefix %q", prefix)
+		}
+	}
+	return nil
+}
+
+// Remove stops serving the tenant at prefix
+func (tr *TenantRouter) Remove(prefix string) {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	delete(tr.tenants, prefix)
+}
+
+// Tenants returns the prefixes served, sorted
+func (tr *TenantRouter) Tenants() []string {
+	tr.mu.RLock()
+	defer tr.mu.RUnlock()
+
+	prefixes := make([]string, 0, len(tr.tenants))
+	for prefix := range tr.tenants {
+		prefixes = append(prefixes, prefix)
+	}
+	sort.Strings(prefixes)
+	return prefixes
+}
+
+// tenant returns the tenant whose prefix is the longest to contain urlPath
+func (tr *TenantRouter) tenant(urlPath string) (string, http.Handler, bool) {
+	tr.mu.RLock()
+	defer tr.mu.RUnlock()
+
+	best := ""
+	for prefix := range tr.tenants {
+		if (urlPath == prefix || strings.HasPrefix(urlPath, prefix+"/")) && len(prefix) > len(best) {
+			best = prefix
+		}
+	}
+	if best == "" {
+		return "", nil, false
+	}
+	return best, tr.tenants[best], true
+}
+
+// ServeHTTP implements http.Handler
+func (tr *TenantRouter) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	prefix, handler, ok := tr.tenant(r.URL.Path)
+	if !ok {
+		tr.home.ServeHTTP(rw, r)
+		return
+	}
+
+	// Relative URLs of the UI resolve below the tenant only with a
+	// trailing slash
+	path := strings.TrimPrefix(r.URL.Path, prefix)
+	prefix = basePath(r.Context()) + prefix
+	if path == "" {
+		target := prefix + "/"
+		if r.URL.RawQuery != "" {
+			target += "?" + r.URL.RawQuery
+		}
+		http.Redirect(rw, r, target, http.StatusMovedPermanently)
+		return
+	}
+
+	routed := r.Clone(context.WithValue(r.Context(), basePathContextKey{}, prefix))
+	routed.URL.Path = path
+	routed.URL.RawPath = ""
+	handler.ServeHTTP(rw, routed)
+}
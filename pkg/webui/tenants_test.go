@@ -0,0 +1,76 @@
+package webui
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestTenantRouter_ServeHTTP(t *testing.T) {
+	home := http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		rw.WriteHeader(http.StatusTeapot)
+	})
+	router := NewTenantRouter(home)
+
+	club := NewSessionRouter(nil)
+	if err := club.Add("alice", newRBACWebUI(t, nil, nil)); err != nil {
+		t.Fatalf("Add(alice) error = %v", err)
+	}
+	if err := router.Add("/club", club); err != nil {
+		t.Fatalf("Add(/club) error = %v", err)
+	}
+	if err := router.Add("/open", newTestWebUI(t)); err != nil {
+		t.Fatalf("Add(/open) error = %v", err)
+	}
+	for _, prefix := range []string{"club", "/", "/club", "/a//b", "/a/../b", "/a b"} {
+		if err := router.Add(prefix, home); err == nil {
+			t.Errorf("Add(%q) accepted an invalid or duplicate prefix", prefix)
+		}
+	}
+	if got := strings.Join(router.Tenants(), ","); got != "/club,/open" {
+		t.Errorf("Tenants() = %s", got)
+	}
+
+	tests := []struct {
+		name       string
+		path       string
+		token      string
+		wantStatus int
+		wantHeader string
+	}{
+		{"session of the tenant", "/club/s/alice/session/info", spectatorToken, http.StatusOK, ""},
+		{"login of the tenant", "/club/s/alice/session/info", "", http.StatusUnauthorized, ""},
+		{"tenant without login", "/open/session/info", "", http.StatusOK, ""},
+		{"unknown session", "/club/s/bob/session/info", spectatorToken, http.StatusNotFound, ""},
+		{"missing trailing slash", "/club?x=1", "", http.StatusMovedPermanently, "/club/?x=1"},
+		{"nested session redirect", "/club/s/alice", "", http.StatusMovedPermanently, "/club/s/alice/"},
+		{"prefix is a whole segment", "/clubs/session/info", "", http.StatusTeapot, ""},
+		{"outside the tenants", "/version", "", http.StatusTeapot, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if tt.wantHeader != "" && rec.Header().Get("Location") != tt.wantHeader {
+				t.Errorf("Location = %q, want %q", rec.Header().Get("Location"), tt.wantHeader)
+			}
+		})
+	}
+
+	// Cookies of a tenant do not reach the others
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/open/theme", strings.NewReader(`{"name":"light"}`)))
+	cookies := rec.Result().Cookies()
+	if rec.Code != http.StatusOK || len(cookies) != 1 || cookies[0].Path != "/open/" {
+		t.Errorf("POST /open/theme = %d with cookies %v, want one scoped to /open/", rec.Code, cookies)
+	}
+}
@@ -0,0 +1,213 @@
+// Package webui provides color themes served to web clients as CSS variables,
+// so operators control the appearance of every client from the server.
+package webui
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// themeCookieName is the cookie holding a client's chosen theme
+const themeCookieName = "dgconnect_theme"
+
+// themeCookieTTL is how long a client's theme choice is remembered
+const themeCookieTTL = 365 * 24 * time.Hour
+
+// prefersColorSchemeHeader is the client hint carrying the browser's
+// preferred color scheme
+const prefersColorSchemeHeader = "Sec-CH-Prefers-Color-Scheme"
+
+// ThemeConfig lists color themes offered to web clients besides the built-in
+// dark and light themes, which a theme of the same name replaces
+type ThemeConfig struct {
+	Themes  []Theme `yaml:"themes,omitempty"`
+	Default string  `yaml:"default,omitempty"` // theme of clients without a preference, default per their color scheme
+}
+
+// Theme is a named set of terminal colors in #RRGGBB form
+type Theme struct {
+	Name       string `yaml:"name" json:"name"`
+	Scheme     string `yaml:"scheme,omitempty" json:"scheme"` // dark (default) or light
+	Background string `yaml:"background" json:"background"`
+	Foreground string `yaml:"foreground" json:"foreground"`
+	Cursor     string `yaml:"cursor" json:"cursor"`
+	Selection  string `yaml:"selection" json:"selection"`
+}
+
+// builtinThemes are offered by every server
+var builtinThemes = []Theme{
+	{Name: "dark", Scheme: "dark", Background: "#000000", Foreground: "#C0C0C0", Cursor: "#FFFFFF", Selection: "#264F78"},
+	{Name: "light", Scheme: "light", Background: "#FFFFFF", Foreground: "#1E1E1E", Cursor: "#000000", Selection: "#ADD6FF"},
+}
+
+// ThemeList is the response of GET /theme
+type ThemeList struct {
+	Current string  `json:"current"`
+	Themes  []Theme `json:"themes"`
+}
+
+// themeRequest is the request body of POST /theme
+type themeRequest struct {
+	Name string `json:"name"`
+}
+
+// themeSet holds the validated themes of a WebUI
+type themeSet struct {
+	themes      []Theme
+	byName      map[string]Theme
+	defaultName string // or "" to follow the client's color scheme
+}
+
+// newThemeSet validates cfg and merges its themes with the built-in ones
+func newThemeSet(cfg ThemeConfig) (*themeSet, error) {
+	s := &themeSet{byName: make(map[string]Theme)}
+	for _, theme := range builtinThemes {
+		s.byName[theme.Name] = theme
+	}
+
+	configured := make(map[string]bool, len(cfg.Themes))
+	for _, theme := range cfg.Themes {
+		if theme.Name == "" {
+			return nil, fmt.Errorf("theme: theme without a name")
+		}
+		if configured[theme.Name] {
+			return nil, fmt.Errorf("theme: duplicate theme %q", theme.Name)
+		}
+		configured[theme.Name] = true
+
+		switch theme.Scheme {
+		case "":
+			theme.Scheme = "dark"
+		case "dark", "light":
+		default:
+			return nil, fmt.Errorf("theme: %q has unknown scheme %q", theme.Name, theme.Scheme)
+		}
+		for field, color := range map[string]string{
+			"background": theme.Background,
+			"foreground": theme.Foreground,
+			"cursor":     theme.Cursor,
+			"selection":  theme.Selection,
+		} {
+			if _, _, _, ok := parseHexColor(color); !ok {
+				return nil, fmt.Errorf("theme: %q has invalid %s %q, want #RRGGBB", theme.Name, field, color)
+			}
+		}
+		s.byName[theme.Name] = theme
+	}
+
+	if cfg.Default != "" {
+		if _, ok := s.byName[cfg.Default]; !ok {
+			return nil, fmt.Errorf("theme: unknown default theme %q", cfg.Default)
+		}
+		s.defaultName = cfg.Default
+	}
+
+	for _, theme := range builtinThemes {
+		s.themes = append(s.themes, s.byName[theme.Name])
+	}
+	for _, theme := range cfg.Themes {
+		if theme.Name != "dark" && theme.Name != "light" {
+			s.themes = append(s.themes, s.byName[theme.Name])
+		}
+	}
+	return s, nil
+}
+
+// forRequest returns the theme chosen by the client of r: the theme query
+// parameter, the theme cookie, the configured default or the theme named
+// after the client's preferred color scheme
+func (s *themeSet) forRequest(r *http.Request) Theme {
+	if theme, ok := s.byName[r.URL.Query().Get("theme")]; ok {
+		return theme
+	}
+	if cookie, err := r.Cookie(themeCookieName); err == nil {
+		if theme, ok := s.byName[cookie.Value]; ok {
+			return theme
+		}
+	}
+	return s.fallback(r)
+}
+
+// fallback returns the theme of the client of r when it chose none
+func (s *themeSet) fallback(r *http.Request) Theme {
+	if s.defaultName != "" {
+		return s.byName[s.defaultName]
+	}
+	if strings.EqualFold(strings.Trim(r.Header.Get(prefersColorSchemeHeader), `"`), "light") {
+		return s.byName["light"]
+	}
+	return s.byName["dark"]
+}
+
+// css renders theme as CSS custom properties
+func (t Theme) css() string {
+	return fmt.Sprintf(":root {\n"+
+		"\tcolor-scheme: %s;\n"+
+		"\t--background: %s;\n"+
+		"\t--foreground: %s;\n"+
+		"\t--cursor: %s;\n"+
+		"\t--selection: %s;\n"+
+		"}\n", t.Scheme, t.Background, t.Foreground, t.Cursor, t.Selection)
+}
+
+// handleTheme serves GET /theme, listing the themes and the client's
+// current one, and POST /theme, remembering the client's choice
+// ({"name": "light"}) in a cookie; an empty name forgets it
+func (w *WebUI) handleTheme(rw http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		rw.Header().Set("Accept-CH", prefersColorSchemeHeader)
+		rw.Header().Set("Vary", "Cookie, "+prefersColorSchemeHeader)
+		writeJSON(rw, http.StatusOK, ThemeList{Current: w.themes.forRequest(r).Name, Themes: w.themes.themes})
+
+	case http.MethodPost:
+		var req themeRequest
+		if err := json.NewDecoder(http.MaxBytesReader(rw, r.Body, 4096)).Decode(&req); err != nil {
+			http.Error(rw, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if req.Name == "" {
+			clearCookie(rw, themeCookieName, cookiePath(r))
+			writeJSON(rw, http.StatusOK, ThemeList{Current: w.themes.fallback(r).Name, Themes: w.themes.themes})
+			return
+		}
+		if _, ok := w.themes.byName[req.Name]; !ok {
+			http.Error(rw, fmt.Sprintf("unknown theme %q", req.Name), http.StatusBadRequest)
+			return
+		}
+
+		http.SetCookie(rw, &http.Cookie{
+			Name:     themeCookieName,
+			Value:    req.Name,
+			Path:     cookiePath(r),
+			MaxAge:   int(themeCookieTTL.Seconds()),
+			HttpOnly: true,
+			Secure:   r.TLS != nil,
+			SameSite: http.SameSiteLaxMode,
+		})
+		writeJSON(rw, http.StatusOK, ThemeList{Current: req.Name, Themes: w.themes.themes})
+
+	default:
+		rw.Header().Set("Allow", "GET, POST")
+		http.Error(rw, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleThemeCSS serves GET /theme.css, the client's theme as CSS custom
+// properties (--background, --foreground, --cursor and --selection)
+func (w *WebUI) handleThemeCSS(rw http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		rw.Header().Set("Allow", http.MethodGet)
+		http.Error(rw, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	rw.Header().Set("Content-Type", "text/css; charset=utf-8")
+	w.setCacheControl(rw, r, "no-cache")
+	rw.Header().Set("Accept-CH", prefersColorSchemeHeader)
+	rw.Header().Set("Vary", "Cookie, "+prefersColorSchemeHeader)
+	fmt.Fprint(rw, w.themes.forRequest(r).css())
+}
@@ -0,0 +1,121 @@
+package webui
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNewThemeSet_Validation(t *testing.T) {
+	solarized := Theme{Name: "solarized", Background: "#002B36", Foreground: "#839496", Cursor: "#93A1A1", Selection: "#073642"}
+
+	tests := []struct {
+		name    string
+		cfg     ThemeConfig
+		wantErr bool
+	}{
+		{"Empty", ThemeConfig{}, false},
+		{"Custom", ThemeConfig{Themes: []Theme{solarized}, Default: "solarized"}, false},
+		{"DefaultBuiltin", ThemeConfig{Default: "light"}, false},
+		{"UnknownDefault", ThemeConfig{Default: "sepia"}, true},
+		{"MissingName", ThemeConfig{Themes: []Theme{{Background: "#000000"}}}, true},
+		{"Duplicate", ThemeConfig{Themes: []Theme{solarized, solarized}}, true},
+		{"UnknownScheme", ThemeConfig{Themes: []Theme{{Name: "x", Scheme: "dim", Background: "#000000", Foreground: "#FFFFFF", Cursor: "#FFFFFF", Selection: "#333333"}}}, true},
+		{"InvalidColor", ThemeConfig{Themes: []Theme{{Name: "x", Background: "black", Foreground: "#FFFFFF", Cursor: "#FFFFFF", Selection: "#333333"}}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newThemeSet(tt.cfg)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("newThemeSet() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestWebUI_HandleThemeCSS_NegotiatesTheme(t *testing.T) {
+	ui, err := NewWebUI(WebUIOptions{View: newTestView(t), Themes: &ThemeConfig{Themes: []Theme{
+		{Name: "dark", Background: "#101010", Foreground: "#E0E0E0", Cursor: "#FFFFFF", Selection: "#333333"},
+	}}})
+	if err != nil {
+		t.Fatalf("Failed to create WebUI: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		target string
+		cookie string
+		scheme string
+		want   string
+	}{
+		{"Default", "/theme.css", "", "", "--background: #101010;"},
+		{"ClientHint", "/theme.css", "", `"light"`, "--background: #FFFFFF;"},
+		{"Cookie", "/theme.css", "light", `"dark"`, "--background: #FFFFFF;"},
+		{"Query", "/theme.css?theme=dark", "light", "", "--background: #101010;"},
+		{"UnknownCookie", "/theme.css", "sepia", "", "--background: #101010;"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			if tt.cookie != "" {
+				req.AddCookie(&http.Cookie{Name: themeCookieName, Value: tt.cookie})
+			}
+			if tt.scheme != "" {
+				req.Header.Set(prefersColorSchemeHeader, tt.scheme)
+			}
+			rec := httptest.NewRecorder()
+			ui.ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+			}
+			if !strings.Contains(rec.Body.String(), tt.want) {
+				t.Errorf("body = %q, want it to contain %q", rec.Body.String(), tt.want)
+			}
+			if got := rec.Header().Get("Content-Type"); !strings.HasPrefix(got, "text/css") {
+				t.Errorf("Content-Type = %q, want text/css", got)
+			}
+		})
+	}
+}
+
+func TestWebUI_HandleTheme_PersistsChoice(t *testing.T) {
+	ui := newTestWebUI(t)
+
+	rec := httptest.NewRecorder()
+	ui.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/theme", strings.NewReader(`{"name":"light"}`)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("POST /theme: status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	cookies := rec.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != themeCookieName || cookies[0].Value != "light" || cookies[0].MaxAge <= 0 {
+		t.Fatalf("POST /theme cookies = %v, want a persistent %s=light", cookies, themeCookieName)
+	}
+
+	// The cookie selects the theme on later requests
+	req := httptest.NewRequest(http.MethodGet, "/theme", nil)
+	req.AddCookie(cookies[0])
+	rec = httptest.NewRecorder()
+	ui.ServeHTTP(rec, req)
+	var list ThemeList
+	if err := json.Unmarshal(rec.Body.Bytes(), &list); err != nil {
+		t.Fatalf("invalid response: %v", err)
+	}
+	if list.Current != "light" || len(list.Themes) != 2 {
+		t.Errorf("GET /theme = %+v, want current light of 2 themes", list)
+	}
+
+	// Unknown themes are rejected and an empty name forgets the choice
+	rec = httptest.NewRecorder()
+	ui.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/theme", strings.NewReader(`{"name":"sepia"}`)))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("POST unknown theme: status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+	rec = httptest.NewRecorder()
+	ui.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/theme", strings.NewReader(`{"name":""}`)))
+	if cookies := rec.Result().Cookies(); len(cookies) != 1 || cookies[0].MaxAge >= 0 {
+		t.Errorf("POST empty theme cookies = %v, want the cookie expired", cookies)
+	}
+}
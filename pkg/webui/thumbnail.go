@@ -0,0 +1,183 @@
+// Package webui provides small PNG previews of the screen for lobby pages.
+package webui
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Colors of thumbnail cells without a #RRGGBB color
+var (
+	thumbnailDefaultFg = color.RGBA{0xC0, 0xC0, 0xC0, 0xFF}
+	thumbnailDefaultBg = color.RGBA{0x00, 0x00, 0x00, 0xFF}
+)
+
+// ThumbnailConfig renders a small PNG preview of the screen every Interval,
+// served at /thumb.png (/s/{id}/thumb.png through a SessionRouter) so lobby
+// pages can show live previews. Each cell becomes a block of CellWidth x
+// CellHeight pixels in its background color, with the glyph drawn as a bar
+// in its foreground color.
+type ThumbnailConfig struct {
+	Interval   string `yaml:"interval,omitempty" yamlcheck:"duration"` // default "10s"
+	CellWidth  int    `yaml:"cell_width,omitempty"`                    // default 2
+	CellHeight int    `yaml:"cell_height,omitempty"`                   // default 4
+}
+
+// thumbnailer keeps the latest rendered thumbnail
+type thumbnailer struct {
+	interval time.Duration
+	cellW    int
+	cellH    int
+
+	mu       sync.RWMutex
+	png      []byte
+	version  uint64
+	rendered time.Time
+}
+
+// newThumbnailer validates cfg
+func newThumbnailer(cfg ThumbnailConfig) (*thumbnailer, error) {
+	t := &thumbnailer{interval: 10 * time.Second, cellW: 2, cellH: 4}
+	if cfg.Interval != "" {
+		parsed, err := time.ParseDuration(cfg.Interval)
+		if err != nil || parsed <= 0 {
+			return nil, fmt.Errorf("thumbnails: invalid interval %q", cfg.Interval)
+		}
+		t.interval = parsed
+	}
+	if cfg.CellWidth < 0 || cfg.CellWidth > 16 {
+		return nil, fmt.Errorf("thumbnails: invalid cell_width %d", cfg.CellWidth)
+	}
+	if cfg.CellHeight < 0 || cfg.CellHeight > 32 {
+		return nil, fmt.Errorf("thumbnails: invalid cell_height %d", cfg.CellHeight)
+	}
+	if cfg.CellWidth > 0 {
+		t.cellW = cfg.CellWidth
+	}
+	if cfg.CellHeight > 0 {
+		t.cellH = cfg.CellHeight
+	}
+	return t, nil
+}
+
+// refresh renders state unless the cached thumbnail already shows it
+func (t *thumbnailer) refresh(state *GameState) error {
+	if state == nil {
+		return nil
+	}
+	t.mu.RLock()
+	current := t.png != nil && t.version == state.Version
+	t.mu.RUnlock()
+	if current {
+		return nil
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, renderThumbnail(state, t.cellW, t.cellH)); err != nil {
+		return fmt.Errorf("thumbnails: %w", err)
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.png, t.version, t.rendered = buf.Bytes(), state.Version, time.Now()
+	return nil
+}
+
+// refreshIfEmpty renders state when nothing has been rendered yet
+func (t *thumbnailer) refreshIfEmpty(state *GameState) error {
+	t.mu.RLock()
+	empty := t.png == nil
+	t.mu.RUnlock()
+	if !empty {
+		return nil
+	}
+	return t.refresh(state)
+}
+
+// run re-renders the thumbnail from sm every interval until ctx is canceled
+func (t *thumbnailer) run(ctx context.Context, sm *StateManager) {
+	ticker := time.NewTicker(t.interval)
+	defer ticker.Stop()
+	for {
+		if err := t.refresh(sm.GetCurrentState()); err != nil {
+			slog.Warn("webui: failed to render thumbnail", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// renderThumbnail draws state with cellW x cellH pixels per cell
+func renderThumbnail(state *GameState, cellW, cellH int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, state.Width*cellW, state.Height*cellH))
+	glyphTop := cellH / 4
+	for y, row := range state.Buffer {
+		for x, cell := range row {
+			fg := thumbnailColor(cell.FgColor, thumbnailDefaultFg)
+			bg := thumbnailColor(cell.BgColor, thumbnailDefaultBg)
+			if cell.Inverse {
+				fg, bg = bg, fg
+			}
+
+			block := image.Rect(x*cellW, y*cellH, (x+1)*cellW, (y+1)*cellH)
+			draw.Draw(img, block, &image.Uniform{C: bg}, image.Point{}, draw.Src)
+			if cell.Char != ' ' && cell.Char != 0 && !cell.Concealed {
+				glyph := image.Rect(block.Min.X, block.Min.Y+glyphTop, block.Max.X, block.Max.Y)
+				draw.Draw(img, glyph, &image.Uniform{C: fg}, image.Point{}, draw.Src)
+			}
+		}
+	}
+	return img
+}
+
+// thumbnailColor parses a #RRGGBB color, returning fallback for anything else
+func thumbnailColor(hex string, fallback color.RGBA) color.RGBA {
+	r, g, b, ok := parseHexColor(hex)
+	if !ok {
+		return fallback
+	}
+	return color.RGBA{uint8(r), uint8(g), uint8(b), 0xFF}
+}
+
+// handleThumbnail serves the latest thumbnail of the screen. Clients may
+// cache it for one render interval and revalidate it with its ETag, which
+// changes with the state version.
+func (w *WebUI) handleThumbnail(rw http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		rw.Header().Set("Allow", "GET, HEAD")
+		http.Error(rw, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Render on demand until the background renderer has run
+	if err := w.thumbnails.refreshIfEmpty(w.GetView().GetCurrentState()); err != nil {
+		http.Error(rw, "Failed to render thumbnail", http.StatusInternalServerError)
+		return
+	}
+
+	w.thumbnails.mu.RLock()
+	data, version, rendered := w.thumbnails.png, w.thumbnails.version, w.thumbnails.rendered
+	w.thumbnails.mu.RUnlock()
+	if data == nil {
+		http.Error(rw, "No screen yet", http.StatusServiceUnavailable)
+		return
+	}
+
+	rw.Header().Set("Content-Type", "image/png")
+	w.setCacheControl(rw, r, w.cacheVisibility()+", max-age="+strconv.Itoa(int(w.thumbnails.interval.Seconds())))
+	rw.Header().Set("ETag", `"v`+strconv.FormatUint(version, 10)+`"`)
+	http.ServeContent(rw, r, "thumb.png", rendered, bytes.NewReader(data))
+}
@@ -0,0 +1,79 @@
+package webui
+
+import (
+	"image/color"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRenderThumbnail(t *testing.T) {
+	state := &GameState{
+		Width:  2,
+		Height: 1,
+		Buffer: [][]Cell{{
+			{Char: '@', FgColor: "#FF0000", BgColor: "#000080"},
+			{Char: ' ', FgColor: "#00FF00", BgColor: "default", Inverse: true},
+		}},
+	}
+	img := renderThumbnail(state, 2, 4)
+
+	if got := img.Bounds().Size(); got.X != 4 || got.Y != 4 {
+		t.Fatalf("size = %v, want 4x4", got)
+	}
+	tests := []struct {
+		name string
+		x, y int
+		want color.RGBA
+	}{
+		{"background above the glyph", 0, 0, color.RGBA{0x00, 0x00, 0x80, 0xFF}},
+		{"glyph", 1, 3, color.RGBA{0xFF, 0x00, 0x00, 0xFF}},
+		{"inverse blank", 2, 3, color.RGBA{0x00, 0xFF, 0x00, 0xFF}},
+	}
+	for _, tt := range tests {
+		if got := img.RGBAAt(tt.x, tt.y); got != tt.want {
+			t.Errorf("%s: pixel (%d,%d) = %v, want %v", tt.name, tt.x, tt.y, got, tt.want)
+		}
+	}
+}
+
+func TestWebUI_HandleThumbnail(t *testing.T) {
+	ui, err := NewWebUI(WebUIOptions{View: newTestView(t), Thumbnails: &ThumbnailConfig{Interval: "30s"}})
+	if err != nil {
+		t.Fatalf("Failed to create WebUI: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	ui.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/thumb.png", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	if got := rec.Header().Get("Cache-Control"); got != "public, max-age=30" {
+		t.Errorf("Cache-Control = %q, want %q", got, "public, max-age=30")
+	}
+	img, err := png.Decode(rec.Body)
+	if err != nil {
+		t.Fatalf("response is not a PNG: %v", err)
+	}
+	if got := img.Bounds().Size(); got.X != 160 || got.Y != 96 {
+		t.Errorf("size = %v, want 160x96 for an 80x24 screen", got)
+	}
+
+	etag := rec.Header().Get("ETag")
+	req := httptest.NewRequest(http.MethodGet, "/thumb.png", nil)
+	req.Header.Set("If-None-Match", etag)
+	rec = httptest.NewRecorder()
+	ui.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNotModified {
+		t.Errorf("revalidation status = %d, want %d", rec.Code, http.StatusNotModified)
+	}
+}
+
+func TestNewThumbnailer_RejectsInvalidConfig(t *testing.T) {
+	for _, cfg := range []ThumbnailConfig{{Interval: "often"}, {Interval: "0s"}, {CellWidth: -1}, {CellHeight: 64}} {
+		if _, err := newThumbnailer(cfg); err == nil {
+			t.Errorf("newThumbnailer(%+v) succeeded, want error", cfg)
+		}
+	}
+}
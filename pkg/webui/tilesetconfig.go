@@ -3,6 +3,7 @@
 package webui
 
 import (
+	"encoding/json"
 	"fmt"
 	"image"
 	_ "image/gif"  // Import for GIF support
@@ -11,6 +12,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 
 	"gopkg.in/yaml.v3"
 )
@@ -30,6 +32,12 @@ type TilesetConfig struct {
 	mappingIndex map[rune]*TileMapping
 	imageData    image.Image
 	basePath     string // Base path for resolving relative image paths
+
+	// Serialized ToJSON output, valid while jsonRevision equals revision
+	jsonMu       sync.Mutex
+	revision     uint64
+	jsonRevision uint64
+	jsonCache    []byte
 }
 
 // LoadTilesetConfig loads a tileset from a YAML file
@@ -216,6 +224,7 @@ func (tc *TilesetConfig) validateSpecialTiles() error {
 // buildIndex creates the character-to-mapping lookup table
 // Moved from: tileset.go
 func (tc *TilesetConfig) buildIndex() error {
+	tc.Invalidate()
 	tc.mappingIndex = make(map[rune]*TileMapping)
 
 	for i := range tc.Mappings {
@@ -320,6 +329,7 @@ func (tc *TilesetConfig) GetImageData() image.Image {
 // SetImageData replaces the tileset's image data with the provided image.
 func (tc *TilesetConfig) SetImageData(img image.Image) {
 	tc.imageData = img
+	tc.Invalidate()
 }
 
 // GetTileCount returns the number of tiles in the tileset
@@ -366,6 +376,44 @@ func (tc *TilesetConfig) ToJSON() map[string]interface{} {
 	return result
 }
 
+// ToJSONBytes returns the encoded ToJSON representation. The encoding is
+// cached until the tileset is modified through SetImageData or Invalidate,
+// so repeated fetches of large tilesets do not rebuild the mapping list.
+func (tc *TilesetConfig) ToJSONBytes() ([]byte, error) {
+	tc.jsonMu.Lock()
+	defer tc.jsonMu.Unlock()
+
+	if tc.jsonCache != nil && tc.jsonRevision == tc.revision {
+		return tc.jsonCache, nil
+	}
+
+	data, err := json.Marshal(tc.ToJSON())
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode tileset: %w", err)
+	}
+	tc.jsonCache = data
+	tc.jsonRevision = tc.revision
+	return data, nil
+}
+
+// Invalidate discards the cached ToJSONBytes output. Callers that modify
+// exported fields such as Mappings directly must call it afterwards.
+func (tc *TilesetConfig) Invalidate() {
+	tc.jsonMu.Lock()
+	defer tc.jsonMu.Unlock()
+
+	tc.revision++
+	tc.jsonCache = nil
+}
+
+// Revision returns a counter that changes whenever the tileset is modified
+func (tc *TilesetConfig) Revision() uint64 {
+	tc.jsonMu.Lock()
+	defer tc.jsonMu.Unlock()
+
+	return tc.revision
+}
+
 // Clone creates a deep copy of the tileset configuration
 // Moved from: tileset.go
 func (tc *TilesetConfig) Clone() *TilesetConfig {
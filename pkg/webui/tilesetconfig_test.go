@@ -3,6 +3,7 @@
 package webui
 
 import (
+	"encoding/json"
 	"fmt"
 	"image"
 	"image/color"
@@ -10,6 +11,7 @@ import (
 	"os"
 	"path/filepath"
 	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -688,3 +690,60 @@ func containsSubstring(s, substr string) bool {
 	}
 	return false
 }
+
+// TestTilesetConfig_ToJSONBytes_CachesUntilInvalidated tests that encoded
+// output is reused and refreshed after modifications
+func TestTilesetConfig_ToJSONBytes_CachesUntilInvalidated(t *testing.T) {
+	config := DefaultTilesetConfig()
+
+	first, err := config.ToJSONBytes()
+	if err != nil {
+		t.Fatalf("ToJSONBytes failed: %v", err)
+	}
+	second, _ := config.ToJSONBytes()
+	if &first[0] != &second[0] {
+		t.Error("Expected cached encoding to be reused")
+	}
+
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(first, &decoded); err != nil {
+		t.Fatalf("Cached encoding is not valid JSON: %v", err)
+	}
+	if decoded["name"] != config.Name {
+		t.Errorf("Encoded name = %v, want %q", decoded["name"], config.Name)
+	}
+
+	revision := config.Revision()
+	config.Name = "Renamed"
+	config.Invalidate()
+	if config.Revision() == revision {
+		t.Error("Expected Invalidate to change the revision")
+	}
+	third, _ := config.ToJSONBytes()
+	if !strings.Contains(string(third), `"Renamed"`) {
+		t.Errorf("Expected refreshed encoding after Invalidate, got %s", third)
+	}
+
+	config.SetImageData(image.NewRGBA(image.Rect(0, 0, 16, 32)))
+	fourth, _ := config.ToJSONBytes()
+	if !strings.Contains(string(fourth), `"tiles_x":2`) {
+		t.Errorf("Expected refreshed encoding after SetImageData, got %s", fourth)
+	}
+}
+
+// BenchmarkTilesetConfig_ToJSONBytes measures cached encoding of a large
+// tileset
+func BenchmarkTilesetConfig_ToJSONBytes(b *testing.B) {
+	config := &TilesetConfig{Name: "Large", TileWidth: 16, TileHeight: 16}
+	for i := 0; i < 5000; i++ {
+		config.Mappings = append(config.Mappings, TileMapping{Char: string(rune(0x4E00 + i)), X: i % 64, Y: i / 64})
+	}
+	config.buildIndex()
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := config.ToJSONBytes(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
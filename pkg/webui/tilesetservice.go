@@ -129,11 +129,8 @@ func (ts *TilesetService) Fetch(r *http.Request, params *struct{}, result *map[s
 	ts.mu.RLock()
 	defer ts.mu.RUnlock()
 
-	log.Printf("[TilesetService] Fetch: Enhanced tileset fetch requested")
-
 	tileset := ts.webui.GetTileset()
 	if tileset == nil {
-		log.Printf("[TilesetService] Fetch: No tileset available")
 		*result = map[string]interface{}{
 			"tileset":         nil,
 			"image_available": false,
@@ -142,8 +139,6 @@ func (ts *TilesetService) Fetch(r *http.Request, params *struct{}, result *map[s
 		return nil
 	}
 
-	log.Printf("[TilesetService] Fetch: Processing tileset %s v%s", tileset.Name, tileset.Version)
-
 	// Get enhanced tileset metadata
 	metadata := ts.getTilesetMetadata(tileset)
 
@@ -153,15 +148,19 @@ func (ts *TilesetService) Fetch(r *http.Request, params *struct{}, result *map[s
 
 	imageAvailable := tileset.GetImageData() != nil || processedImage != nil
 
+	tilesetJSON, err := tileset.ToJSONBytes()
+	if err != nil {
+		return err
+	}
+
 	*result = map[string]interface{}{
-		"tileset":         tileset.ToJSON(),
+		"tileset":         json.RawMessage(tilesetJSON),
 		"image_available": imageAvailable,
+		"image_url":       ts.webui.TilesetImageURL(),
 		"metadata":        metadata,
 		"capabilities":    ts.getServiceCapabilities(),
 		"cache_status":    ts.getCacheStatus(),
 	}
-
-	log.Printf("[TilesetService] Fetch: Enhanced response prepared with metadata")
 	return nil
 }
 
@@ -170,8 +169,6 @@ func (ts *TilesetService) Update(r *http.Request, params *TilesetUpdateParams, r
 	ts.mu.Lock()
 	defer ts.mu.Unlock()
 
-	log.Printf("[TilesetService] Update: Processing tileset update request")
-
 	var tileset *TilesetConfig
 	var err error
 
@@ -216,10 +213,15 @@ func (ts *TilesetService) Update(r *http.Request, params *TilesetUpdateParams, r
 	cacheKey := fmt.Sprintf("%s-%s", tileset.Name, tileset.Version)
 	ts.cacheProcessedImage(cacheKey, tileset.GetImageData())
 
+	tilesetJSON, err := tileset.ToJSONBytes()
+	if err != nil {
+		return err
+	}
+
 	// Prepare response
 	*result = map[string]interface{}{
 		"success":  true,
-		"tileset":  tileset.ToJSON(),
+		"tileset":  json.RawMessage(tilesetJSON),
 		"metadata": ts.getTilesetMetadata(tileset),
 		"message":  fmt.Sprintf("Tileset '%s' updated successfully", tileset.Name),
 	}
@@ -230,8 +232,6 @@ func (ts *TilesetService) Update(r *http.Request, params *TilesetUpdateParams, r
 
 // List returns available tilesets in configured directories
 func (ts *TilesetService) List(r *http.Request, params *struct{}, result *TilesetListResponse) error {
-	log.Printf("[TilesetService] List: Scanning for available tilesets")
-
 	tilesets := []TilesetInfo{}
 
 	// Scan common tileset directories
@@ -259,8 +259,6 @@ func (ts *TilesetService) List(r *http.Request, params *struct{}, result *Tilese
 		Tilesets: tilesets,
 		Default:  defaultTileset,
 	}
-
-	log.Printf("[TilesetService] List: Found %d tilesets", len(tilesets))
 	return nil
 }
 
@@ -272,8 +270,6 @@ func (ts *TilesetService) ProcessImage(r *http.Request, params *struct {
 	ts.mu.Lock()
 	defer ts.mu.Unlock()
 
-	log.Printf("[TilesetService] ProcessImage: Applying image processing")
-
 	tileset := ts.webui.GetTileset()
 	if tileset == nil {
 		return fmt.Errorf("no tileset loaded")
@@ -297,8 +293,6 @@ func (ts *TilesetService) ProcessImage(r *http.Request, params *struct {
 		"message":  "Image processing completed",
 		"metadata": ts.getTilesetMetadata(tileset),
 	}
-
-	log.Printf("[TilesetService] ProcessImage: Processing completed successfully")
 	return nil
 }
 
@@ -52,7 +52,8 @@ func TestTilesetService_Fetch(t *testing.T) {
 	tileset := DefaultTilesetConfig()
 
 	// Create mock WebUI with tileset
-	webui := &WebUI{tileset: tileset}
+	webui := &WebUI{}
+	webui.tileset.Store(&servedTileset{source: tileset})
 	
 	service := NewTilesetService(webui)
 
@@ -0,0 +1,190 @@
+// Package webui provides tileset subsetting: large sheets are cut down to
+// the tiles the session actually drew, so clients on slow links do not
+// download art the game never shows.
+package webui
+
+import (
+	"fmt"
+	"image"
+	"image/draw"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/opd-ai/go-gamelaunch-www/pkg/transport"
+)
+
+// tilesetGrowthDelay gathers the glyphs first drawn within it into one new
+// subset sheet for clients to download
+const tilesetGrowthDelay = 100 * time.Millisecond
+
+// mappedTiles returns the distinct tiles of tc's mappings and special tiles,
+// in the order they are first referenced, failing for tiles outside the
+// sheet
+func (tc *TilesetConfig) mappedTiles() ([]TileRef, error) {
+	tilesX, tilesY := tc.GetTileCount()
+	seen := make(map[TileRef]bool)
+	var tiles []TileRef
+	ref := func(tile TileRef) error {
+		if tile.X < 0 || tile.Y < 0 || tile.X >= tilesX || tile.Y >= tilesY {
+			return fmt.Errorf("tileset subset: tile (%d, %d) outside the %dx%d sheet", tile.X, tile.Y, tilesX, tilesY)
+		}
+		if !seen[tile] {
+			seen[tile] = true
+			tiles = append(tiles, tile)
+		}
+		return nil
+	}
+	for _, mapping := range tc.Mappings {
+		if err := ref(TileRef{X: mapping.X, Y: mapping.Y}); err != nil {
+			return nil, err
+		}
+	}
+	for _, special := range tc.SpecialTiles {
+		for _, tile := range special.Tiles {
+			if err := ref(tile); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return tiles, nil
+}
+
+// Subset returns a copy of tc whose image holds only tiles, in that order,
+// in rows of cols tiles. Mappings and special tiles drawing them are
+// rewritten to match; other mappings are dropped.
+func (tc *TilesetConfig) Subset(tiles []TileRef, cols int) *TilesetConfig {
+	cols = max(1, cols)
+	rows := max(1, (len(tiles)+cols-1)/cols)
+	slots := make(map[TileRef]TileRef, len(tiles))
+	for slot, tile := range tiles {
+		slots[tile] = TileRef{X: slot % cols, Y: slot / cols}
+	}
+
+	sheet := image.NewRGBA(image.Rect(0, 0, cols*tc.TileWidth, rows*tc.TileHeight))
+	if src := tc.GetImageData(); src != nil {
+		origin := src.Bounds().Min
+		for tile, dst := range slots {
+			rect := image.Rect(dst.X*tc.TileWidth, dst.Y*tc.TileHeight, (dst.X+1)*tc.TileWidth, (dst.Y+1)*tc.TileHeight)
+			draw.Draw(sheet, rect, src, origin.Add(image.Pt(tile.X*tc.TileWidth, tile.Y*tc.TileHeight)), draw.Src)
+		}
+	}
+
+	subset := tc.Clone()
+	subset.Mappings = subset.Mappings[:0]
+	for _, mapping := range tc.Mappings {
+		if moved, ok := slots[TileRef{X: mapping.X, Y: mapping.Y}]; ok {
+			mapping.X, mapping.Y = moved.X, moved.Y
+			subset.Mappings = append(subset.Mappings, mapping)
+		}
+	}
+	subset.buildIndex()
+	for _, special := range subset.SpecialTiles {
+		for i, tile := range special.Tiles {
+			special.Tiles[i] = slots[tile]
+		}
+	}
+	subset.SetImageData(sheet)
+	return subset
+}
+
+// tileSubset tracks the tiles of a tileset the session drew, for serving a
+// sheet of only those and the special tiles clients draw on their own. A
+// tile keeps the slot it got when first drawn, in rows as wide as a
+// near-square sheet of every mapped tile, so cells already sent keep
+// pointing at their art as the sheet grows.
+type tileSubset struct {
+	source *TilesetConfig
+	cols   int
+	grown  func() // called, without mu held, when a tile was added
+
+	mu    sync.Mutex
+	slots map[TileRef]int
+	tiles []TileRef
+	sheet *TilesetConfig // of tiles; nil when outdated
+}
+
+// newTileSubset starts the subset of source with its special tiles
+func newTileSubset(source *TilesetConfig, grown func()) (*tileSubset, error) {
+	mapped, err := source.mappedTiles()
+	if err != nil {
+		return nil, err
+	}
+	s := &tileSubset{
+		source: source,
+		cols:   max(1, int(math.Ceil(math.Sqrt(float64(len(mapped)))))),
+		grown:  grown,
+		slots:  make(map[TileRef]int),
+	}
+	for _, special := range source.SpecialTiles {
+		for _, tile := range special.Tiles {
+			s.add(tile)
+		}
+	}
+	return s, nil
+}
+
+// add gives tile the next slot unless it has one; s.mu must be held or s
+// not yet shared
+func (s *tileSubset) add(tile TileRef) (int, bool) {
+	if slot, ok := s.slots[tile]; ok {
+		return slot, false
+	}
+	slot := len(s.tiles)
+	s.slots[tile] = slot
+	s.tiles = append(s.tiles, tile)
+	s.sheet = nil
+	return slot, true
+}
+
+// slot returns where tile, of the source sheet, is found in the subset
+// sheet, adding it when it was not drawn before
+func (s *tileSubset) slot(tile TileRef) TileRef {
+	s.mu.Lock()
+	slot, added := s.add(tile)
+	s.mu.Unlock()
+
+	if added && s.grown != nil {
+		s.grown()
+	}
+	return TileRef{X: slot % s.cols, Y: slot / s.cols}
+}
+
+// Tileset returns the tileset of the tiles drawn so far
+func (s *tileSubset) Tileset() *TilesetConfig {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.sheet == nil {
+		s.sheet = s.source.Subset(s.tiles, s.cols)
+	}
+	return s.sheet
+}
+
+// subsetTileset returns the subset of tileset when the TilesetSubset option
+// is set, or nil
+func (w *WebUI) subsetTileset(tileset *TilesetConfig) (*tileSubset, error) {
+	if tileset == nil || !w.options.TilesetSubset {
+		return nil, nil
+	}
+	subset, err := newTileSubset(tileset, w.tilesetGrown)
+	if err != nil {
+		return nil, err
+	}
+	tilesX, tilesY := tileset.GetTileCount()
+	w.log.Info("webui: serving tileset subset", "tileset", tileset.Name, "sheet_tiles", tilesX*tilesY, "subset_columns", subset.cols)
+	return subset, nil
+}
+
+// tilesetGrown tells clients to download the grown subset sheet, at most
+// once per tilesetGrowthDelay
+func (w *WebUI) tilesetGrown() {
+	if !w.tilesetGrowing.CompareAndSwap(false, true) {
+		return
+	}
+	time.AfterFunc(tilesetGrowthDelay, func() {
+		w.tilesetGrowing.Store(false)
+		if image := w.TilesetImageURL(); image != "" {
+			w.wsHandler.BroadcastTileset(&transport.TilesetPayload{Image: image})
+		}
+	})
+}
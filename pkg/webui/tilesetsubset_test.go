@@ -0,0 +1,153 @@
+package webui
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+// newSheetTileset returns a tileset of 2x2 pixel tiles on a tilesX by
+// tilesY sheet, each tile filled with the color of its coordinates
+func newSheetTileset(tilesX, tilesY int, mappings []TileMapping) *TilesetConfig {
+	sheet := image.NewRGBA(image.Rect(0, 0, tilesX*2, tilesY*2))
+	for y := 0; y < tilesY*2; y++ {
+		for x := 0; x < tilesX*2; x++ {
+			sheet.Set(x, y, color.RGBA{R: uint8(x / 2), G: uint8(y / 2), A: 255})
+		}
+	}
+	tileset := &TilesetConfig{Name: "sheet", TileWidth: 2, TileHeight: 2, Mappings: mappings}
+	tileset.buildIndex()
+	tileset.SetImageData(sheet)
+	return tileset
+}
+
+// tileColor returns the color of the top-left pixel of tile (x, y)
+func tileColor(tileset *TilesetConfig, x, y int) color.RGBA {
+	return tileset.GetImageData().(*image.RGBA).RGBAAt(x*tileset.TileWidth, y*tileset.TileHeight)
+}
+
+func TestTilesetConfig_Subset(t *testing.T) {
+	tileset := newSheetTileset(10, 10, []TileMapping{
+		{Char: "@", X: 7, Y: 3},
+		{Char: ".", X: 0, Y: 9},
+		{Char: ",", X: 7, Y: 3}, // shares the tile of @
+		{Char: "#", X: 4, Y: 4}, // not in the subset
+	})
+	tileset.SpecialTiles = []SpecialTile{{ID: "statue", Tiles: []TileRef{{X: 5, Y: 5}, {X: 0, Y: 9}}}}
+
+	subset := tileset.Subset([]TileRef{{X: 5, Y: 5}, {X: 7, Y: 3}, {X: 0, Y: 9}}, 2)
+	if tilesX, tilesY := subset.GetTileCount(); tilesX != 2 || tilesY != 2 {
+		t.Fatalf("subset sheet = %dx%d tiles, want 2x2 for 3 tiles in rows of 2", tilesX, tilesY)
+	}
+
+	// Tiles keep their order, and mappings draw the same art as before
+	if at := subset.GetMapping('@'); at.X != 1 || at.Y != 0 {
+		t.Errorf("@ moved to (%d, %d), want (1, 0)", at.X, at.Y)
+	}
+	for _, mapping := range tileset.Mappings[:3] {
+		moved := subset.GetMapping([]rune(mapping.Char)[0])
+		if got, want := tileColor(subset, moved.X, moved.Y), tileColor(tileset, mapping.X, mapping.Y); got != want {
+			t.Errorf("%q draws %v from (%d, %d), want %v", mapping.Char, got, moved.X, moved.Y, want)
+		}
+	}
+	if subset.GetMapping('#') != nil {
+		t.Error("Subset kept the mapping of a tile it left out")
+	}
+	statue := subset.SpecialTiles[0].Tiles
+	if statue[0] != (TileRef{X: 0, Y: 0}) || statue[1] != (TileRef{X: 0, Y: 1}) {
+		t.Errorf("special tiles moved to %+v", statue)
+	}
+	if tileset.Mappings[0].X != 7 || tileset.SpecialTiles[0].Tiles[0].X != 5 {
+		t.Error("Subset modified the original tileset")
+	}
+
+	outside := newSheetTileset(2, 2, []TileMapping{{Char: "a", X: 2, Y: 0}})
+	if _, err := outside.mappedTiles(); err == nil {
+		t.Error("mappedTiles accepted a tile outside the sheet")
+	}
+}
+
+func TestWebUI_TilesetSubset(t *testing.T) {
+	tileset := newSheetTileset(8, 8, []TileMapping{
+		{Char: "@", X: 6, Y: 6},
+		{Char: "#", X: 3, Y: 3},
+		{Char: ".", X: 1, Y: 1},
+	})
+	tileset.SpecialTiles = []SpecialTile{{ID: "door", Tiles: []TileRef{{X: 5, Y: 5}}}}
+	w, err := NewWebUI(WebUIOptions{View: newTestView(t), Tileset: tileset, TilesetSubset: true})
+	if err != nil {
+		t.Fatalf("NewWebUI failed: %v", err)
+	}
+
+	// Before anything is drawn only the special tiles are served
+	served := w.GetTileset()
+	if served == tileset || len(served.Mappings) != 0 {
+		t.Fatalf("NewWebUI served %d mappings, want none", len(served.Mappings))
+	}
+	if tilesX, tilesY := served.GetTileCount(); tilesX != 2 || tilesY != 1 {
+		t.Errorf("initial sheet = %dx%d tiles, want 2x1", tilesX, tilesY)
+	}
+	url := w.TilesetImageURL()
+
+	// Drawn glyphs are added in rows as wide as the sheet of every tile
+	if err := w.GetView().Render([]byte("#@#")); err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	row := w.GetView().GetCurrentState().Buffer[0]
+	if row[0].TileX != 1 || row[0].TileY != 0 || row[1].TileX != 0 || row[1].TileY != 1 || row[2].TileX != 1 || row[2].TileY != 0 {
+		t.Errorf("cell tiles = (%d, %d) (%d, %d), want (1, 0) (0, 1)", row[0].TileX, row[0].TileY, row[1].TileX, row[1].TileY)
+	}
+	served = w.GetTileset()
+	if served.GetMapping('.') != nil {
+		t.Error("Subset serves a glyph that was never drawn")
+	}
+	if got, want := tileColor(served, row[1].TileX, row[1].TileY), tileColor(tileset, 6, 6); got != want {
+		t.Errorf("@ draws %v, want %v", got, want)
+	}
+	if w.TilesetImageURL() == url {
+		t.Error("Image URL did not change as the sheet grew")
+	}
+
+	// Tiles keep their place as more are drawn
+	if err := w.GetView().Render([]byte(".")); err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	if at := w.GetTileset().GetMapping('@'); at.X != 0 || at.Y != 1 {
+		t.Errorf("@ moved to (%d, %d)", at.X, at.Y)
+	}
+	if usage := w.TilesetUsage(); len(usage.Unused) != 0 {
+		t.Errorf("usage reports %+v unused, want every source tile used", usage.Unused)
+	}
+
+	// Reloaded tilesets are cut down as well, keeping what is on screen
+	if err := w.UpdateTileset(newSheetTileset(8, 8, []TileMapping{{Char: "@", X: 1, Y: 1}, {Char: "#", X: 2, Y: 2}})); err != nil {
+		t.Fatalf("UpdateTileset failed: %v", err)
+	}
+	if got := len(w.GetTileset().Mappings); got != 2 {
+		t.Errorf("reloaded subset has %d mappings, want the 2 on screen", got)
+	}
+	if err := w.UpdateTileset(newSheetTileset(2, 2, []TileMapping{{Char: "@", X: 5, Y: 5}})); err == nil {
+		t.Error("UpdateTileset accepted a tile outside the sheet")
+	}
+}
+
+func TestWebUI_GetTileset_ConcurrentUpdate(t *testing.T) {
+	w, err := NewWebUI(WebUIOptions{View: newTestView(t), Tileset: DefaultTilesetConfig()})
+	if err != nil {
+		t.Fatalf("NewWebUI failed: %v", err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			w.UpdateTileset(DefaultTilesetConfig())
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		if w.GetTileset() == nil {
+			t.Fatal("GetTileset returned nil during an update")
+		}
+	}
+	<-done
+}
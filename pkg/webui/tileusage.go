@@ -0,0 +1,101 @@
+// Package webui provides tileset usage analytics: how often each tile is
+// drawn, so tileset authors know which art matters and operators can trim
+// tiles that are never used.
+package webui
+
+import (
+	"net/http"
+	"sort"
+	"time"
+)
+
+// TileUsage is how often a tile was drawn
+type TileUsage struct {
+	Char  string `json:"char"`
+	X     int    `json:"x"`
+	Y     int    `json:"y"`
+	Draws uint64 `json:"draws"`
+}
+
+// TilesetUsage reports the tiles drawn in the session, most drawn first,
+// and the tiles of the current tileset that were never drawn
+type TilesetUsage struct {
+	Tileset string      `json:"tileset,omitempty"`
+	Since   time.Time   `json:"since"`
+	Draws   uint64      `json:"draws"`
+	Tiles   []TileUsage `json:"tiles"`
+	Unused  []TileUsage `json:"unused"`
+}
+
+// tileKey identifies a tile by the character it draws and its position in
+// the sheet, so counts survive tileset reloads that keep the mapping
+type tileKey struct {
+	char string
+	x, y int
+}
+
+// countTileDraw records that mapping was drawn; v.mu must be held for
+// writing
+func (v *WebView) countTileDraw(mapping *TileMapping) {
+	if v.tileDraws == nil {
+		v.tileDraws = make(map[tileKey]uint64)
+	}
+	v.tileDraws[tileKey{mapping.Char, mapping.X, mapping.Y}]++
+}
+
+// TileUsage returns how often each tile was drawn since the view was
+// created, most drawn first
+func (v *WebView) TileUsage() []TileUsage {
+	v.mu.RLock()
+	usage := make([]TileUsage, 0, len(v.tileDraws))
+	for key, draws := range v.tileDraws {
+		usage = append(usage, TileUsage{Char: key.char, X: key.x, Y: key.y, Draws: draws})
+	}
+	v.mu.RUnlock()
+
+	sort.Slice(usage, func(i, j int) bool {
+		if usage[i].Draws != usage[j].Draws {
+			return usage[i].Draws > usage[j].Draws
+		}
+		return usage[i].Char < usage[j].Char
+	})
+	return usage
+}
+
+// TilesetUsage reports the tile usage of the session against the current
+// tileset
+func (w *WebUI) TilesetUsage() TilesetUsage {
+	view := w.GetView()
+	report := TilesetUsage{Since: view.created, Tiles: view.TileUsage(), Unused: []TileUsage{}}
+
+	drawn := make(map[tileKey]bool, len(report.Tiles))
+	for _, tile := range report.Tiles {
+		report.Draws += tile.Draws
+		drawn[tileKey{tile.Char, tile.X, tile.Y}] = true
+	}
+	if served := w.tileset.Load(); served != nil {
+		report.Tileset = served.source.Name
+		for _, mapping := range served.source.Mappings {
+			if !drawn[tileKey{mapping.Char, mapping.X, mapping.Y}] {
+				report.Unused = append(report.Unused, TileUsage{Char: mapping.Char, X: mapping.X, Y: mapping.Y})
+			}
+		}
+	}
+	return report
+}
+
+// Usage reports which tiles were drawn in the session and which were not
+func (ts *TilesetService) Usage(r *http.Request, params *struct{}, result *TilesetUsage) error {
+	*result = ts.webui.TilesetUsage()
+	return nil
+}
+
+// handleTilesetUsage serves GET /tileset/usage
+func (w *WebUI) handleTilesetUsage(rw http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		rw.Header().Set("Allow", http.MethodGet)
+		http.Error(rw, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	writeJSON(rw, http.StatusOK, w.TilesetUsage())
+}
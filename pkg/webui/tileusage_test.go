@@ -0,0 +1,47 @@
+package webui
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWebUI_TilesetUsage(t *testing.T) {
+	ui := newTestWebUI(t)
+	if err := ui.UpdateTileset(DefaultTilesetConfig()); err != nil {
+		t.Fatalf("UpdateTileset failed: %v", err)
+	}
+	if err := ui.GetView().Render([]byte("@..#x\r\n.")); err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	ui.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/tileset/usage", nil))
+	var usage TilesetUsage
+	if err := json.Unmarshal(rec.Body.Bytes(), &usage); err != nil {
+		t.Fatalf("invalid response: %v (%s)", err, rec.Body.String())
+	}
+
+	want := []TileUsage{{".", 1, 0, 3}, {"#", 2, 0, 1}, {"@", 0, 0, 1}}
+	if len(usage.Tiles) != len(want) {
+		t.Fatalf("tiles = %+v, want %+v", usage.Tiles, want)
+	}
+	for i := range want {
+		if usage.Tiles[i] != want[i] {
+			t.Errorf("tiles[%d] = %+v, want %+v", i, usage.Tiles[i], want[i])
+		}
+	}
+	if usage.Tileset != "ASCII Default" || usage.Draws != 5 || len(usage.Unused) != 5 || usage.Since.IsZero() {
+		t.Errorf("usage = %+v, want 5 draws of ASCII Default with 5 unused tiles", usage)
+	}
+
+	// The tileset service reports the same through RPC
+	var result TilesetUsage
+	if err := ui.getTilesetService().Usage(httptest.NewRequest(http.MethodPost, "/rpc", nil), &struct{}{}, &result); err != nil {
+		t.Fatalf("Usage failed: %v", err)
+	}
+	if result.Draws != usage.Draws || len(result.Unused) != len(usage.Unused) {
+		t.Errorf("Usage() = %+v, want %+v", result, usage)
+	}
+}
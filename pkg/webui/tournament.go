@@ -0,0 +1,393 @@
+// Package webui provides tournament score tracking with a live leaderboard.
+package webui
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Tournament defaults
+const (
+	defaultLeaderboardSize = 100
+	webhookTimeout         = 5 * time.Second
+)
+
+// TournamentConfig configures tournament mode. Sessions sharing a Tournament
+// feed one leaderboard.
+type TournamentConfig struct {
+	Name       string   `yaml:"name"`
+	Parsers    []string `yaml:"parsers,omitempty"`     // registered score parsers; default all
+	Webhooks   []string `yaml:"webhooks,omitempty"`    // URLs notified of new high scores
+	MaxEntries int      `yaml:"max_entries,omitempty"` // leaderboard size, default 100
+}
+
+// ScoreEntry is a finished game on the leaderboard
+type ScoreEntry struct {
+	Session string    `json:"session"`
+	Player  string    `json:"player"`
+	Game    string    `json:"game,omitempty"`
+	Score   int64     `json:"score"`
+	Outcome string    `json:"outcome,omitempty"` // e.g. "killed by a jackal"
+	Time    time.Time `json:"time"`
+}
+
+// ScoreParser recognises the end-of-game screen of a game and extracts the
+// final score. Player is left empty when the screen does not name one.
+type ScoreParser interface {
+	ParseScore(lines []string) (ScoreEntry, bool)
+}
+
+// ScoreParserFunc adapts a function to ScoreParser
+type ScoreParserFunc func(lines []string) (ScoreEntry, bool)
+
+// ParseScore implements ScoreParser
+func (f ScoreParserFunc) ParseScore(lines []string) (ScoreEntry, bool) {
+	return f(lines)
+}
+
+var (
+	scoreParsersMu sync.RWMutex
+	scoreParsers   = map[string]ScoreParser{
+		"nethack": ScoreParserFunc(parseNetHackScore),
+		"crawl":   ScoreParserFunc(parseCrawlScore),
+	}
+)
+
+// RegisterScoreParser makes a score parser available to tournaments under
+// name, replacing any parser registered with the same name
+func RegisterScoreParser(name string, parser ScoreParser) {
+	scoreParsersMu.Lock()
+	defer scoreParsersMu.Unlock()
+	scoreParsers[name] = parser
+}
+
+// Tournament aggregates final scores from any number of sessions into a live
+// leaderboard
+type Tournament struct {
+	name       string
+	parsers    []ScoreParser
+	webhooks   []string
+	maxEntries int
+	client     *http.Client
+
+	mu        sync.Mutex
+	entries   []ScoreEntry      // sorted by descending score
+	lastSeen  map[string]string // session -> last scraped end screen
+	listeners []func(entry ScoreEntry, highScore bool)
+}
+
+// NewTournament validates cfg and creates a tournament
+func NewTournament(cfg TournamentConfig) (*Tournament, error) {
+	t := &Tournament{
+		name:       cfg.Name,
+		maxEntries: cfg.MaxEntries,
+		client:     &http.Client{Timeout: webhookTimeout},
+		lastSeen:   make(map[string]string),
+	}
+	if t.maxEntries <= 0 {
+		t.maxEntries = defaultLeaderboardSize
+	}
+
+	scoreParsersMu.RLock()
+	names := cfg.Parsers
+	if len(names) == 0 {
+		for name := range scoreParsers {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+	}
+	for _, name := range names {
+		parser, ok := scoreParsers[name]
+		if !ok {
+			scoreParsersMu.RUnlock()
+			return nil, fmt.Errorf("tournament: unknown score parser %q", name)
+		}
+		t.parsers = append(t.parsers, parser)
+	}
+	scoreParsersMu.RUnlock()
+
+	for _, hook := range cfg.Webhooks {
+		u, err := url.Parse(hook)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("tournament: invalid webhook URL %q", hook)
+		}
+		t.webhooks = append(t.webhooks, hook)
+	}
+
+	return t, nil
+}
+
+// Observe scrapes a screen of session for a final score and records it. The
+// same end screen is only recorded once, however often it is redrawn.
+func (t *Tournament) Observe(session string, lines []string) (ScoreEntry, bool) {
+	for _, parser := range t.parsers {
+		entry, ok := parser.ParseScore(lines)
+		if !ok {
+			continue
+		}
+		if entry.Player == "" {
+			entry.Player = session
+		}
+		key := fmt.Sprintf("%s\x00%d\x00%s", entry.Player, entry.Score, entry.Outcome)
+
+		t.mu.Lock()
+		seen := t.lastSeen[session] == key
+		t.lastSeen[session] = key
+		t.mu.Unlock()
+		if seen {
+			return entry, false
+		}
+
+		entry.Session = session
+		t.Record(entry)
+		return entry, true
+	}
+
+	// Off the end screen: the next one is a new game
+	t.mu.Lock()
+	delete(t.lastSeen, session)
+	t.mu.Unlock()
+	return ScoreEntry{}, false
+}
+
+// Record adds a score to the leaderboard and reports whether it is the new
+// high score, notifying the webhooks if so
+func (t *Tournament) Record(entry ScoreEntry) bool {
+	if entry.Time.IsZero() {
+		entry.Time = time.Now()
+	}
+
+	t.mu.Lock()
+	var previous *ScoreEntry
+	if len(t.entries) > 0 {
+		top := t.entries[0]
+		previous = &top
+	}
+	i := sort.Search(len(t.entries), func(i int) bool {
+		return t.entries[i].Score < entry.Score
+	})
+	t.entries = append(t.entries, ScoreEntry{})
+	copy(t.entries[i+1:], t.entries[i:])
+	t.entries[i] = entry
+	if len(t.entries) > t.maxEntries {
+		t.entries = t.entries[:t.maxEntries]
+	}
+
+	listeners := t.listeners
+	t.mu.Unlock()
+
+	highScore := i == 0 && (previous == nil || entry.Score > previous.Score)
+	for _, fn := range listeners {
+		fn(entry, highScore)
+	}
+	if highScore {
+		slog.Info("tournament: new high score", "tournament", t.name,
+			"player", entry.Player, "score", entry.Score)
+		go t.notifyWebhooks(entry, previous)
+	}
+	return highScore
+}
+
+// OnScore registers fn to be called with every recorded score
+func (t *Tournament) OnScore(fn func(entry ScoreEntry, highScore bool)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.listeners = append(t.listeners, fn)
+}
+
+// Name returns the configured tournament name
+func (t *Tournament) Name() string {
+	return t.name
+}
+
+// Leaderboard returns a copy of the current leaderboard, best score first
+func (t *Tournament) Leaderboard() []ScoreEntry {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]ScoreEntry(nil), t.entries...)
+}
+
+// Watch scrapes the current and every later state of a session until ctx
+// is cancelled
+func (t *Tournament) Watch(ctx context.Context, session string, sm *StateManager) {
+	var version uint64
+	for {
+		if _, err := sm.PollChangesWithContext(ctx, version); err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			continue
+		}
+		state := sm.GetCurrentState()
+		if state == nil || state.Version <= version {
+			continue
+		}
+		version = state.Version
+		t.Observe(session, stateLines(state))
+	}
+}
+
+// highScoreEvent is the webhook payload for a new high score
+type highScoreEvent struct {
+	Event      string      `json:"event"`
+	Tournament string      `json:"tournament,omitempty"`
+	Entry      ScoreEntry  `json:"entry"`
+	Previous   *ScoreEntry `json:"previous,omitempty"`
+}
+
+// notifyWebhooks posts a new high score to every configured webhook
+func (t *Tournament) notifyWebhooks(entry ScoreEntry, previous *ScoreEntry) {
+	if len(t.webhooks) == 0 {
+		return
+	}
+	body, err := json.Marshal(highScoreEvent{
+		Event:      "high_score",
+		Tournament: t.name,
+		Entry:      entry,
+		Previous:   previous,
+	})
+	if err != nil {
+		return
+	}
+
+	for _, hook := range t.webhooks {
+		resp, err := t.client.Post(hook, "application/json", bytes.NewReader(body))
+		if err != nil {
+			slog.Warn("tournament: webhook failed", "url", hook, "error", err)
+			continue
+		}
+		resp.Body.Close()
+		if resp.StatusCode >= 300 {
+			slog.Warn("tournament: webhook rejected", "url", hook, "status", resp.StatusCode)
+		}
+	}
+}
+
+// leaderboardPage renders the leaderboard for browsers
+var leaderboardPage = template.Must(template.New("leaderboard").Funcs(template.FuncMap{
+	"inc": func(i int) int { return i + 1 },
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta http-equiv="refresh" content="30">
+<title>{{if .Name}}{{.Name}}{{else}}Tournament{{end}} leaderboard</title>
+</head>
+<body>
+<h1>{{if .Name}}{{.Name}}{{else}}Tournament{{end}}</h1>
+<table>
+<thead><tr><th>#</th><th>Player</th><th>Game</th><th>Score</th><th>Outcome</th><th>Time</th></tr></thead>
+<tbody>
+{{range $i, $e := .Entries}}<tr><td>{{inc $i}}</td><td>{{$e.Player}}</td><td>{{$e.Game}}</td><td>{{$e.Score}}</td><td>{{$e.Outcome}}</td><td>{{$e.Time.Format "2006-01-02 15:04"}}</td></tr>
+{{else}}<tr><td colspan="6">No finished games yet</td></tr>
+{{end}}</tbody>
+</table>
+</body>
+</html>
+`))
+
+// HandleLeaderboard serves GET /tournament/leaderboard as JSON
+func (t *Tournament) HandleLeaderboard(rw http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		rw.Header().Set("Allow", http.MethodGet)
+		http.Error(rw, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	writeJSON(rw, http.StatusOK, map[string]interface{}{
+		"name":    t.name,
+		"entries": t.Leaderboard(),
+	})
+}
+
+// HandlePage serves GET /tournament, a self-refreshing leaderboard page
+func (t *Tournament) HandlePage(rw http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		rw.Header().Set("Allow", http.MethodGet)
+		http.Error(rw, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	rw.Header().Set("Content-Type", "text/html; charset=utf-8")
+	err := leaderboardPage.Execute(rw, struct {
+		Name    string
+		Entries []ScoreEntry
+	}{t.name, t.Leaderboard()})
+	if err != nil {
+		slog.Error("tournament: render leaderboard failed", "error", err)
+	}
+}
+
+// NetHack end-of-game screens
+var (
+	nethackPointsPattern  = regexp.MustCompile(`with (\d+) points?`)
+	nethackGoodbyePattern = regexp.MustCompile(`Goodbye (\S+) the`)
+	nethackOutcomePattern = regexp.MustCompile(`^\s*You (died|quit|escaped|ascended|were|went|turned)\b`)
+	nethackCausePattern   = regexp.MustCompile(`(?i)\b((?:killed|choked|poisoned|petrified|drowned|burned|dissolved|crushed|starved|strangled|zapped|turned to slime)\b.*?)[.,]?$`)
+)
+
+// parseNetHackScore recognises the NetHack "Goodbye ..." end screen
+func parseNetHackScore(lines []string) (ScoreEntry, bool) {
+	var entry ScoreEntry
+	found := false
+	for _, line := range lines {
+		if m := nethackGoodbyePattern.FindStringSubmatch(line); m != nil {
+			entry.Player = strings.TrimSuffix(m[1], ",")
+		}
+		if nethackOutcomePattern.MatchString(line) {
+			if m := nethackPointsPattern.FindStringSubmatch(line); m != nil {
+				entry.Score, _ = strconv.ParseInt(m[1], 10, 64)
+				found = true
+				if entry.Outcome == "" {
+					entry.Outcome = strings.Fields(line)[1]
+				}
+			}
+		}
+		if m := nethackCausePattern.FindStringSubmatch(line); m != nil && found {
+			entry.Outcome = strings.ToLower(strings.TrimSpace(m[1]))
+		}
+	}
+	if !found {
+		return ScoreEntry{}, false
+	}
+	entry.Game = "nethack"
+	return entry, true
+}
+
+// Dungeon Crawl Stone Soup end-of-game screens
+var (
+	crawlScorePattern = regexp.MustCompile(`^\s*(\d+) (\S+) the .+\(level \d+`)
+	crawlCausePattern = regexp.MustCompile(`^\s*((?:Slain|Killed|Mangled|Blown up|Drowned|Shot|Burnt|Poisoned|Starved|Succumbed|Rotted|Quit|Escaped|Asphyxiated|Engulfed|Petrified|Froze)\b.*?)\s*$`)
+)
+
+// parseCrawlScore recognises the DCSS death screen summary line
+func parseCrawlScore(lines []string) (ScoreEntry, bool) {
+	var entry ScoreEntry
+	found := false
+	for _, line := range lines {
+		if m := crawlScorePattern.FindStringSubmatch(line); m != nil && !found {
+			entry.Score, _ = strconv.ParseInt(m[1], 10, 64)
+			entry.Player = m[2]
+			found = true
+			continue
+		}
+		if m := crawlCausePattern.FindStringSubmatch(line); m != nil && found && entry.Outcome == "" {
+			entry.Outcome = m[1]
+		}
+	}
+	if !found {
+		return ScoreEntry{}, false
+	}
+	entry.Game = "crawl"
+	return entry, true
+}
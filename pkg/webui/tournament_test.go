@@ -0,0 +1,305 @@
+package webui
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/opd-ai/go-gamelaunch-www/pkg/announce"
+	"github.com/opd-ai/go-gamelaunch-www/pkg/recording"
+)
+
+var nethackEndScreen = []string{
+	"Goodbye alice the Valkyrie...",
+	"",
+	"You died in The Dungeons of Doom on dungeon level 5 with 1234 points,",
+	"and 56 pieces of gold, after 2345 moves.",
+	"You were level 6 with a maximum of 52 hit points when you died.",
+}
+
+var crawlEndScreen = []string{
+	"Goodbye, bob.",
+	"",
+	" 5678 bob the Fighter (level 9, -3/75 HPs)",
+	"             Began as a Minotaur Berserker on Oct 1, 2026.",
+	"             Slain by an orc warrior (12 damage)",
+	"             ... on level 4 of the Dungeon.",
+}
+
+func TestScoreParsers(t *testing.T) {
+	tests := []struct {
+		name   string
+		parse  func([]string) (ScoreEntry, bool)
+		lines  []string
+		want   ScoreEntry
+		wantOK bool
+	}{
+		{
+			name:   "nethack death",
+			parse:  parseNetHackScore,
+			lines:  nethackEndScreen,
+			want:   ScoreEntry{Player: "alice", Game: "nethack", Score: 1234, Outcome: "died"},
+			wantOK: true,
+		},
+		{
+			name:   "crawl death",
+			parse:  parseCrawlScore,
+			lines:  crawlEndScreen,
+			want:   ScoreEntry{Player: "bob", Game: "crawl", Score: 5678, Outcome: "Slain by an orc warrior (12 damage)"},
+			wantOK: true,
+		},
+		{
+			name:  "nethack in play",
+			parse: parseNetHackScore,
+			lines: []string{"You hit the jackal!", "Dlvl:1 $:0 HP:14(14)"},
+		},
+		{
+			name:  "crawl parser on nethack screen",
+			parse: parseCrawlScore,
+			lines: nethackEndScreen,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := tt.parse(tt.lines)
+			if ok != tt.wantOK {
+				t.Fatalf("ok = %v, want %v", ok, tt.wantOK)
+			}
+			if got != tt.want {
+				t.Errorf("entry = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewTournament_RejectsBadConfig(t *testing.T) {
+	if _, err := NewTournament(TournamentConfig{Parsers: []string{"angband"}}); err == nil {
+		t.Error("expected error for unknown parser")
+	}
+	if _, err := NewTournament(TournamentConfig{Webhooks: []string{"ftp://example.com"}}); err == nil {
+		t.Error("expected error for non-HTTP webhook")
+	}
+}
+
+func TestTournament_Observe_RecordsEachGameOnce(t *testing.T) {
+	tournament, err := NewTournament(TournamentConfig{})
+	if err != nil {
+		t.Fatalf("NewTournament failed: %v", err)
+	}
+
+	// The end screen is redrawn several times before the player moves on
+	for i := 0; i < 3; i++ {
+		tournament.Observe("alice@nao", nethackEndScreen)
+	}
+	tournament.Observe("bob@cao", crawlEndScreen)
+
+	board := tournament.Leaderboard()
+	if len(board) != 2 {
+		t.Fatalf("leaderboard has %d entries, want 2: %+v", len(board), board)
+	}
+	if board[0].Player != "bob" || board[1].Player != "alice" {
+		t.Errorf("leaderboard order = %s, %s; want bob, alice", board[0].Player, board[1].Player)
+	}
+	if board[1].Session != "alice@nao" {
+		t.Errorf("Session = %q, want alice@nao", board[1].Session)
+	}
+
+	// A second game with the same result after returning to the menu counts
+	tournament.Observe("alice@nao", []string{" ## dgamelaunch", "  p) Play"})
+	tournament.Observe("alice@nao", nethackEndScreen)
+	if got := len(tournament.Leaderboard()); got != 3 {
+		t.Errorf("leaderboard has %d entries, want 3", got)
+	}
+}
+
+func TestTournament_Record_TrimsAndReportsHighScores(t *testing.T) {
+	tournament, err := NewTournament(TournamentConfig{MaxEntries: 2})
+	if err != nil {
+		t.Fatalf("NewTournament failed: %v", err)
+	}
+
+	for _, tc := range []struct {
+		score int64
+		want  bool
+	}{{100, true}, {50, false}, {100, false}, {200, true}} {
+		if got := tournament.Record(ScoreEntry{Player: "p", Score: tc.score}); got != tc.want {
+			t.Errorf("Record(%d) = %v, want %v", tc.score, got, tc.want)
+		}
+	}
+
+	board := tournament.Leaderboard()
+	if len(board) != 2 || board[0].Score != 200 || board[1].Score != 100 {
+		t.Errorf("leaderboard = %+v, want scores 200, 100", board)
+	}
+}
+
+func TestTournament_HighScore_NotifiesWebhook(t *testing.T) {
+	received := make(chan highScoreEvent, 1)
+	hook := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var event highScoreEvent
+		if err := json.Unmarshal(body, &event); err == nil {
+			received <- event
+		}
+	}))
+	defer hook.Close()
+
+	tournament, err := NewTournament(TournamentConfig{Name: "cup", Webhooks: []string{hook.URL}})
+	if err != nil {
+		t.Fatalf("NewTournament failed: %v", err)
+	}
+	tournament.Record(ScoreEntry{Player: "alice", Score: 1234})
+
+	select {
+	case event := <-received:
+		if event.Event != "high_score" || event.Tournament != "cup" || event.Entry.Score != 1234 {
+			t.Errorf("event = %+v", event)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("webhook not called")
+	}
+}
+
+func TestWebUI_Tournament_ServesLeaderboard(t *testing.T) {
+	tournament, err := NewTournament(TournamentConfig{Name: "cup"})
+	if err != nil {
+		t.Fatalf("NewTournament failed: %v", err)
+	}
+	tournament.Record(ScoreEntry{Player: "alice", Score: 1234, Outcome: "died"})
+
+	view := newTestView(t)
+	webUI, err := NewWebUI(WebUIOptions{View: view, Tournament: tournament})
+	if err != nil {
+		t.Fatalf("NewWebUI failed: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	webUI.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/tournament/leaderboard", nil))
+	var resp struct {
+		Name    string       `json:"name"`
+		Entries []ScoreEntry `json:"entries"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if resp.Name != "cup" || len(resp.Entries) != 1 || resp.Entries[0].Player != "alice" {
+		t.Errorf("leaderboard = %+v", resp)
+	}
+
+	rec = httptest.NewRecorder()
+	webUI.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/tournament", nil))
+	if !strings.Contains(rec.Body.String(), "<td>alice</td>") {
+		t.Errorf("page does not list alice:\n%s", rec.Body.String())
+	}
+}
+
+func TestWebUI_Announcer_AnnouncesDeathsAndRecords(t *testing.T) {
+	messages := make(chan string, 10)
+	discord := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Content string `json:"content"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		messages <- body.Content
+	}))
+	defer discord.Close()
+
+	announcer, err := announce.New(announce.Config{Discord: &announce.DiscordConfig{WebhookURL: discord.URL}})
+	if err != nil {
+		t.Fatalf("announce.New failed: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go announcer.Run(ctx)
+
+	tournament, err := NewTournament(TournamentConfig{Name: "cup"})
+	if err != nil {
+		t.Fatalf("NewTournament failed: %v", err)
+	}
+	_, err = NewWebUI(WebUIOptions{
+		View:              newTestView(t),
+		Tournament:        tournament,
+		TournamentSession: "alice@nao",
+		Announcer:         announcer,
+	})
+	if err != nil {
+		t.Fatalf("NewWebUI failed: %v", err)
+	}
+
+	// Scores from other sessions of a shared tournament are not ours to announce
+	tournament.Observe("bob@cao", crawlEndScreen)
+	tournament.Observe("alice@nao", nethackEndScreen)
+
+	want := []string{"alice [nethack]: died, 1234 points"}
+	for _, w := range want {
+		select {
+		case got := <-messages:
+			if got != w {
+				t.Errorf("announced %q, want %q", got, w)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("%q not announced", w)
+		}
+	}
+
+	tournament.Observe("alice@nao", []string{"new game"})
+	tournament.Record(ScoreEntry{Session: "alice@nao", Player: "alice", Game: "nethack", Score: 9999, Outcome: "ascended"})
+	for _, w := range []string{"alice [nethack]: ascended, 9999 points", "New record in cup! alice scored 9999 points"} {
+		select {
+		case got := <-messages:
+			if got != w {
+				t.Errorf("announced %q, want %q", got, w)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("%q not announced", w)
+		}
+	}
+}
+
+func TestWebUI_Recordings_EndRecordingAtGameEnd(t *testing.T) {
+	store, err := recording.Open(recording.Config{Dir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("recording.Open failed: %v", err)
+	}
+	defer store.Close()
+
+	view := newTestView(t)
+	webUI, err := NewWebUI(WebUIOptions{
+		View:              view,
+		Recordings:        store,
+		TournamentSession: "alice@nao",
+		Player:            "alice",
+	})
+	if err != nil {
+		t.Fatalf("NewWebUI failed: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	webUI.watchScores(ctx, view)
+
+	view.Render([]byte("\x1b[2J\x1b[H" + strings.Join(nethackEndScreen, "\r\n")))
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		recordings, err := store.Query(context.Background(), recording.Filter{Player: "alice"})
+		if err != nil {
+			t.Fatalf("Query failed: %v", err)
+		}
+		if len(recordings) == 1 && recordings[0].Ended != nil {
+			if recordings[0].Game != "nethack" || recordings[0].Score != 1234 {
+				t.Errorf("recording = %+v", recordings[0])
+			}
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("recording not finished: %+v", recordings)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
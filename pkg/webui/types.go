@@ -34,7 +34,8 @@ type ViewRenderer interface {
 // StateProvider defines the interface for game state management
 // This interface abstracts state tracking and change notification
 type StateProvider interface {
-	// GetCurrentState returns the current game state
+	// GetCurrentState returns a copy of the current game state that is
+	// not affected by later updates; see GameState.Clone
 	GetCurrentState() *GameState
 
 	// GetCurrentVersion returns the current state version
@@ -3,6 +3,9 @@
 package webui
 
 import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
 	"strings"
 
 	"github.com/fatih/color"
@@ -85,3 +88,12 @@ func standardColor16ToRGB(index uint8) (r, g, b int) {
 	}
 	return 0, 0, 0 // Default to black for invalid indices
 }
+
+// writeJSON encodes v as the JSON response body with the given status code
+func writeJSON(rw http.ResponseWriter, status int, v interface{}) {
+	rw.Header().Set("Content-Type", "application/json")
+	rw.WriteHeader(status)
+	if err := json.NewEncoder(rw).Encode(v); err != nil {
+		slog.Error("webui.writeJSON: encode failed", "error", err)
+	}
+}
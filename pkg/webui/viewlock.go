@@ -0,0 +1,80 @@
+// Package webui provides the lock of a web view: a reader/writer lock made
+// of channels, so that a writer can give up waiting for it when its context
+// is done.
+package webui
+
+import "context"
+
+// viewLock is a reader/writer lock like sync.RWMutex whose write lock can
+// also be acquired with a context. A waiting writer holds the turnstile, so
+// readers arriving after it wait too and cannot starve it.
+type viewLock struct {
+	turnstile chan struct{} // held by a writer from waiting until it locks
+	write     chan struct{} // held by the writer, or on behalf of all readers
+	readersMu chan struct{} // guards readers
+	readers   int
+}
+
+// newViewLock creates an unlocked lock
+func newViewLock() *viewLock {
+	return &viewLock{
+		turnstile: make(chan struct{}, 1),
+		write:     make(chan struct{}, 1),
+		readersMu: make(chan struct{}, 1),
+	}
+}
+
+// Lock locks l for writing
+func (l *viewLock) Lock() {
+	l.turnstile <- struct{}{}
+	l.write <- struct{}{}
+	<-l.turnstile
+}
+
+// LockContext locks l for writing or returns ctx.Err() if ctx is done first
+func (l *viewLock) LockContext(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	select {
+	case l.turnstile <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	defer func() { <-l.turnstile }()
+
+	select {
+	case l.write <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Unlock unlocks l for writing
+func (l *viewLock) Unlock() {
+	<-l.write
+}
+
+// RLock locks l for reading
+func (l *viewLock) RLock() {
+	l.turnstile <- struct{}{}
+	<-l.turnstile
+
+	l.readersMu <- struct{}{}
+	l.readers++
+	if l.readers == 1 {
+		l.write <- struct{}{}
+	}
+	<-l.readersMu
+}
+
+// RUnlock undoes one RLock
+func (l *viewLock) RUnlock() {
+	l.readersMu <- struct{}{}
+	l.readers--
+	if l.readers == 0 {
+		<-l.write
+	}
+	<-l.readersMu
+}
@@ -0,0 +1,204 @@
+// Package webui provides the editing functions of the terminal emulator:
+// inserting and deleting characters and lines, scrolling by more than a
+// line and switching to the alternate screen.
+package webui
+
+// blankCell returns an empty cell in the current colors, as erasing leaves
+func (v *WebView) blankCell() Cell {
+	return Cell{
+		Char:    ' ',
+		FgColor: v.currentFgColor,
+		BgColor: v.currentBgColor,
+		Changed: true,
+	}
+}
+
+// blankGrid returns an empty screen of the current size
+func (v *WebView) blankGrid() [][]Cell {
+	grid := newCellGrid(v.width, v.height)
+	blank := v.blankCell()
+	for _, row := range grid {
+		for x := range row {
+			row[x] = blank
+		}
+	}
+	return grid
+}
+
+// cursorColumn returns the column of the cursor, which is past the last
+// one while a wrap is pending
+func (v *WebView) cursorColumn() int {
+	return min(v.cursorX, v.width-1)
+}
+
+// insertChars inserts n blanks at the cursor, shifting the rest of the line
+// right and off the screen (ICH)
+func (v *WebView) insertChars(n int) {
+	v.breakGrapheme()
+	x, row := v.cursorColumn(), v.buffer[v.cursorY]
+	n = min(n, v.width-x)
+	copy(row[x+n:], row[x:v.width-n])
+	for i := x; i < v.width; i++ {
+		if i < x+n {
+			row[i] = v.blankCell()
+		}
+		row[i].Changed = true
+	}
+	v.repairWide(row)
+}
+
+// deleteChars deletes n characters at the cursor, shifting the rest of the
+// line left and blanking its end (DCH)
+func (v *WebView) deleteChars(n int) {
+	v.breakGrapheme()
+	x, row := v.cursorColumn(), v.buffer[v.cursorY]
+	n = min(n, v.width-x)
+	copy(row[x:], row[x+n:])
+	for i := x; i < v.width; i++ {
+		if i >= v.width-n {
+			row[i] = v.blankCell()
+		}
+		row[i].Changed = true
+	}
+	v.repairWide(row)
+}
+
+// eraseChars blanks n characters from the cursor without moving the rest
+// of the line (ECH)
+func (v *WebView) eraseChars(n int) {
+	v.breakGrapheme()
+	x, row := v.cursorColumn(), v.buffer[v.cursorY]
+	for i := x; i < min(x+n, v.width); i++ {
+		row[i] = v.blankCell()
+	}
+	v.repairWide(row)
+}
+
+// repairWide blanks the halves of wide characters in row whose other half
+// was shifted away or erased
+func (v *WebView) repairWide(row []Cell) {
+	for x := 0; x < len(row); x++ {
+		switch {
+		case row[x].Wide && x+1 < len(row) && row[x+1].Char == 0:
+			x++ // intact, skip the right half
+		case row[x].Wide, row[x].Char == 0:
+			row[x] = v.blankCell()
+		}
+	}
+}
+
+// insertLines inserts n blank lines at the cursor, pushing the lines below
+// towards the bottom margin (IL). Outside the scroll region it does nothing.
+func (v *WebView) insertLines(n int) {
+	if v.cursorY < v.scrollTop || v.cursorY > v.scrollBottom {
+		return
+	}
+	v.shiftRows(v.rowsFrom(v.cursorY), n)
+	v.cursorX = 0
+}
+
+// deleteLines deletes n lines at the cursor, pulling up the lines below and
+// blanking those at the bottom margin (DL). Outside the scroll region it
+// does nothing.
+func (v *WebView) deleteLines(n int) {
+	if v.cursorY < v.scrollTop || v.cursorY > v.scrollBottom {
+		return
+	}
+	v.shiftRows(v.rowsFrom(v.cursorY), -n)
+	v.cursorX = 0
+}
+
+// rowsFrom returns the scrollable rows from y down
+func (v *WebView) rowsFrom(y int) []int {
+	rows := v.scrollableRows()
+	for i, row := range rows {
+		if row >= y {
+			return rows[i:]
+		}
+	}
+	return nil
+}
+
+// shiftRows moves the contents of rows n places down, or up for negative
+// n, blanking the rows left behind, as scrolling by n lines does
+func (v *WebView) shiftRows(rows []int, n int) {
+	v.breakGrapheme()
+	n = max(-len(rows), min(n, len(rows)))
+	if n < 0 {
+		n = -n
+		for i := 0; i+n < len(rows); i++ {
+			copy(v.buffer[rows[i]], v.buffer[rows[i+n]])
+		}
+		rows = rows[len(rows)-n:]
+	} else {
+		for i := len(rows) - 1; i >= n; i-- {
+			copy(v.buffer[rows[i]], v.buffer[rows[i-n]])
+		}
+		rows = rows[:n]
+	}
+
+	blank := v.blankCell()
+	for _, y := range rows {
+		for x := range v.buffer[y] {
+			v.buffer[y][x] = blank
+		}
+	}
+}
+
+// switchScreen switches between the normal screen and the alternate one
+// full-screen applications draw on (DECSET 47, 1047 and 1049). The
+// alternate screen starts out blank, and the normal screen comes back as it
+// was left; with saveCursor the cursor does too, as for 1049.
+func (v *WebView) switchScreen(alternate, saveCursor bool) {
+	if alternate == v.modes.altScreen {
+		return
+	}
+	v.breakGrapheme()
+	v.modes.altScreen = alternate
+	if alternate {
+		if saveCursor {
+			v.saveCursor()
+		}
+		v.mainBuffer, v.buffer = v.buffer, v.blankGrid()
+		return
+	}
+
+	main := v.mainBuffer
+	v.mainBuffer = nil
+	if len(main) != v.height || len(main[0]) != v.width {
+		main = v.blankGrid() // resized meanwhile
+	}
+	for _, row := range main {
+		for x := range row {
+			row[x].Changed = true
+		}
+	}
+	v.buffer = main
+	if saveCursor {
+		v.restoreCursor()
+	}
+}
+
+// alignmentTest fills the screen with E and homes the cursor (DECALN)
+func (v *WebView) alignmentTest() {
+	v.resetScrollRegion()
+	v.breakGrapheme()
+	cell := v.blankCell()
+	cell.Char = 'E'
+	for _, row := range v.buffer {
+		for x := range row {
+			row[x] = cell
+		}
+	}
+	v.cursorX, v.cursorY = 0, 0
+}
+
+// softReset restores the default modes, margins, attributes and character
+// sets without clearing the screen (DECSTR)
+func (v *WebView) softReset() {
+	v.resetAttributes()
+	v.resetScrollRegion()
+	v.charset = charsetState{}
+	v.savedCursor = savedCursor{}
+	v.modes.appCursor, v.modes.appKeypad, v.modes.noAutowrap = false, false, false
+}
@@ -0,0 +1,236 @@
+// Package webui provides the escape sequence parser of the terminal
+// emulator, a state machine after the DEC VT500 model
+// (https://vt100.net/emu/dec_ansi_parser) that splits game output into text,
+// control characters and complete escape sequences.
+package webui
+
+import (
+	"strconv"
+	"strings"
+)
+
+// vtState is the state of the escape sequence parser
+type vtState uint8
+
+const (
+	vtGround       vtState = iota // text and control characters
+	vtEscape                      // after ESC
+	vtEscapeInter                 // ESC and intermediate bytes, until the final byte
+	vtCSI                         // control sequence, until its final byte
+	vtCSIIgnore                   // malformed or oversized control sequence, until its final byte
+	vtString                      // OSC, DCS, SOS, PM or APC string, until ST
+	vtStringEscape                // ESC inside a string, which ends it
+)
+
+// Control characters with a meaning inside escape sequences
+const (
+	ctrlBEL = 0x07
+	ctrlSO  = 0x0e // shift out: use the G1 character set
+	ctrlSI  = 0x0f // shift in: use the G0 character set
+	ctrlCAN = 0x18
+	ctrlSUB = 0x1a
+	ctrlESC = 0x1b
+	ctrlDEL = 0x7f
+)
+
+// processEscapeByte advances the escape sequence parser by b. Control
+// characters inside a sequence are executed as if they came before it, CAN
+// and SUB abandon the sequence and ESC starts a new one. Strings such as
+// window titles are skipped without being buffered.
+func (v *WebView) processEscapeByte(b byte) {
+	switch v.vtState {
+	case vtString:
+		switch b {
+		case ctrlESC:
+			v.vtState = vtStringEscape
+		case ctrlBEL, ctrlCAN, ctrlSUB: // xterm also ends OSC strings with BEL
+			v.endEscape()
+		}
+		return
+	case vtStringEscape:
+		v.endEscape()
+		if b != '\\' { // not ST, so ESC began a new sequence
+			v.startEscapeSequence()
+			v.processEscapeByte(b)
+		}
+		return
+	}
+
+	switch {
+	case b == ctrlCAN || b == ctrlSUB:
+		v.endEscape()
+		return
+	case b == ctrlESC:
+		v.startEscapeSequence()
+		return
+	case b < 0x20:
+		v.processControlChar(b)
+		return
+	case b == ctrlDEL:
+		return
+	case v.vtState == vtCSIIgnore:
+		if b >= 0x40 {
+			v.endEscape()
+		}
+		return
+	}
+
+	if len(v.escapeBuffer) >= v.escapePolicy.maxLength {
+		v.escapeOverflow()
+		inCSI := v.vtState == vtCSI
+		v.endEscape()
+		if inCSI && b < 0x40 {
+			v.vtState = vtCSIIgnore // skip the rest of the sequence
+		}
+		return
+	}
+	v.escapeBuffer = append(v.escapeBuffer, b)
+
+	switch v.vtState {
+	case vtEscape:
+		switch {
+		case b == '[':
+			v.vtState = vtCSI
+		case b == ']' || b == 'P' || b == 'X' || b == '^' || b == '_':
+			v.vtState = vtString
+			v.escapeBuffer = v.escapeBuffer[:0]
+		case isIntermediate(rune(b)):
+			v.vtState = vtEscapeInter
+		default:
+			v.dispatchEscape()
+			v.endEscape()
+		}
+	case vtEscapeInter:
+		if !isIntermediate(rune(b)) {
+			v.dispatchEscape()
+			v.endEscape()
+		}
+	case vtCSI:
+		switch {
+		case b >= 0x40:
+			v.handleCSISequence(string(v.escapeBuffer))
+			v.endEscape()
+		case b < 0x30:
+			// intermediate byte
+		case strings.ContainsFunc(string(v.escapeBuffer[2:len(v.escapeBuffer)-1]), isIntermediate):
+			v.vtState = vtCSIIgnore // parameter after an intermediate
+		}
+	}
+}
+
+// endEscape returns the parser to text
+func (v *WebView) endEscape() {
+	v.vtState = vtGround
+	v.escapeBuffer = v.escapeBuffer[:0]
+}
+
+// isIntermediate reports whether r is an intermediate byte of an escape
+// sequence, such as the ( of ESC ( 0
+func isIntermediate(r rune) bool {
+	return r >= 0x20 && r <= 0x2f
+}
+
+// dispatchEscape runs the escape sequence in escapeBuffer: ESC, any
+// intermediate bytes and the final byte. Unknown sequences are ignored.
+func (v *WebView) dispatchEscape() {
+	seq := v.escapeBuffer
+	final := seq[len(seq)-1]
+	switch string(seq[1 : len(seq)-1]) {
+	case "":
+		switch final {
+		case 'c': // Reset terminal (RIS)
+			v.resetTerminalState()
+		case 'D': // Line feed (IND)
+			v.lineFeed()
+		case 'E': // Next line (NEL)
+			v.cursorX = 0
+			v.lineFeed()
+		case 'M': // Reverse line feed (RI)
+			v.reverseLineFeed()
+		case '7': // Save cursor (DECSC)
+			v.saveCursor()
+		case '8': // Restore cursor (DECRC)
+			v.restoreCursor()
+		case '=': // Application keypad (DECKPAM)
+			v.modes.appKeypad = true
+		case '>': // Normal keypad (DECKPNM)
+			v.modes.appKeypad = false
+		}
+	case "(": // Designate G0 (SCS)
+		v.charset.g[0] = final
+	case ")": // Designate G1 (SCS)
+		v.charset.g[1] = final
+	case "#":
+		if final == '8' { // Screen alignment test (DECALN)
+			v.alignmentTest()
+		}
+	}
+}
+
+// handleCSIIntermediate processes control sequences with intermediate
+// bytes, of which only the soft reset is supported
+func (v *WebView) handleCSIIntermediate(params string, final byte) {
+	if params == "!" && final == 'p' { // DECSTR
+		v.softReset()
+	}
+}
+
+// handlePrivateCSI processes control sequences with a private parameter
+// prefix: the DEC private modes and selective erase, which is treated as
+// plain erase. The rest, such as xterm's key modifier options, are ignored.
+func (v *WebView) handlePrivateCSI(seq string, final byte) {
+	if seq[2] != '?' {
+		return
+	}
+	switch final {
+	case 'h':
+		v.handlePrivateMode(seq, true)
+	case 'l':
+		v.handlePrivateMode(seq, false)
+	case 'J':
+		v.handleEraseDisplay("\x1b[" + seq[3:])
+	case 'K':
+		v.handleEraseLine("\x1b[" + seq[3:])
+	}
+}
+
+// csiParam returns parameter i of the control sequence seq, or def when it
+// is missing, zero or invalid
+func csiParam(seq string, i, def int) int {
+	params := strings.Split(seq[2:len(seq)-1], ";")
+	if i >= len(params) {
+		return def
+	}
+	n, err := strconv.Atoi(params[i])
+	if err != nil || n <= 0 {
+		return def
+	}
+	return n
+}
+
+// charsetState holds the character sets designated with SCS and the one
+// selected with SO and SI
+type charsetState struct {
+	g       [2]byte // final byte designating G0 and G1; '0' is DEC special graphics, anything else ASCII
+	shifted bool    // SO selected G1
+}
+
+// decSpecialGraphics maps 0x5f to 0x7e to the line drawing and symbol
+// characters of the DEC special graphics set, used for menus and maps
+var decSpecialGraphics = [...]rune{
+	' ', '◆', '▒', '␉', '␌', '␍', '␊', '°', '±', '␤', '␋', '┘', '┐', '┌', '└', '┼',
+	'⎺', '⎻', '─', '⎼', '⎽', '├', '┤', '┴', '┬', '│', '≤', '≥', 'π', '≠', '£', '·',
+}
+
+// translate returns the character that printable ASCII b stands for in the
+// selected character set
+func (c charsetState) translate(b byte) rune {
+	set := c.g[0]
+	if c.shifted {
+		set = c.g[1]
+	}
+	if set == '0' && b >= 0x5f && b <= 0x7e {
+		return decSpecialGraphics[b-0x5f]
+	}
+	return rune(b)
+}
@@ -0,0 +1,119 @@
+package webui
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/opd-ai/go-gamelaunch-client/pkg/dgclient"
+)
+
+func TestWebView_EscapeSequences(t *testing.T) {
+	// Four rows of six columns, filled before each case
+	const fill = "\x1b[Haaaaaa\x1b[2;1Hbbbbbb\x1b[3;1Hcccccc\x1b[4;1Hdddddd"
+
+	tests := []struct {
+		name       string
+		input      string
+		want       []string
+		wantCursor [2]int
+	}{
+		{"InsertLines", "\x1b[2;3H\x1b[2L", []string{"aaaaaa", "", "", "bbbbbb"}, [2]int{0, 1}},
+		{"DeleteLines", "\x1b[2;3H\x1b[M", []string{"aaaaaa", "cccccc", "dddddd", ""}, [2]int{0, 1}},
+		{"InsertLinesInsideMargins", "\x1b[1;3r\x1b[2;1H\x1b[L", []string{"aaaaaa", "", "bbbbbb", "dddddd"}, [2]int{0, 1}},
+		{"InsertChars", "\x1b[1;2H\x1b[2@xy", []string{"axyaaa", "bbbbbb", "cccccc", "dddddd"}, [2]int{3, 0}},
+		{"DeleteChars", "\x1b[1;2H\x1bP\x1b[3P", []string{"aaa", "bbbbbb", "cccccc", "dddddd"}, [2]int{1, 0}},
+		{"EraseChars", "\x1b[2;2H\x1b[3X", []string{"aaaaaa", "b   bb", "cccccc", "dddddd"}, [2]int{1, 1}},
+		{"ScrollUpAndDown", "\x1b[2S\x1b[T", []string{"", "cccccc", "dddddd", ""}, [2]int{5, 3}},
+		{"AbsoluteColumnAndRow", "\x1b[H\x1b[3G1\x1b[2d2\x1b[5`3", []string{"aa1aaa", "bbb23b", "cccccc", "dddddd"}, [2]int{5, 1}},
+		{"NextAndPreviousLine", "\x1b[1;4H\x1b[2E1\x1b[F2", []string{"aaaaaa", "2bbbbb", "1ccccc", "dddddd"}, [2]int{1, 1}},
+		{"SaveAndRestoreCursor", "\x1b[2;3H\x1b[s\x1b[4;6H\x1b[u!", []string{"aaaaaa", "bb!bbb", "cccccc", "dddddd"}, [2]int{3, 1}},
+		{"BottomRightDoesNotScroll", "\x1b[4;6H!", []string{"aaaaaa", "bbbbbb", "cccccc", "ddddd!"}, [2]int{5, 3}},
+		{"DeferredWrap", "\x1b[3;6H!?", []string{"aaaaaa", "bbbbbb", "ccccc!", "?ddddd"}, [2]int{1, 3}},
+		{"NoAutowrap", "\x1b[?7l\x1b[1;5Hxyz", []string{"aaaaxz", "bbbbbb", "cccccc", "dddddd"}, [2]int{5, 0}},
+		{"TabStopsAtLastColumn", "\x1b[1;1H\t\t!", []string{"aaaaa!", "bbbbbb", "cccccc", "dddddd"}, [2]int{5, 0}},
+		{"DECSpecialGraphics", "\x1b[H\x1b(0lqk\x1b(Bq", []string{"┌─┐qaa", "bbbbbb", "cccccc", "dddddd"}, [2]int{4, 0}},
+		{"ShiftOutToG1", "\x1b)0\x1b[Hx\x0ex\x0fx", []string{"x│xaaa", "bbbbbb", "cccccc", "dddddd"}, [2]int{3, 0}},
+		{"WindowTitleSkipped", "\x1b]0;NetHack: long title\x07\x1b]2;x\x1b\\\x1b[H!", []string{"!aaaaa", "bbbbbb", "cccccc", "dddddd"}, [2]int{1, 0}},
+		{"DeviceControlStringSkipped", "\x1bPq#0;2;0;0;0\x1b\\\x1b[H!", []string{"!aaaaa", "bbbbbb", "cccccc", "dddddd"}, [2]int{1, 0}},
+		{"ControlInsideSequence", "\x1b[1\r;3H!", []string{"aa!aaa", "bbbbbb", "cccccc", "dddddd"}, [2]int{3, 0}},
+		{"CancelledSequence", "\x1b[H\x1b[3\x18X", []string{"Xaaaaa", "bbbbbb", "cccccc", "dddddd"}, [2]int{1, 0}},
+		{"UnknownFinalBytes", "\x1b[H\x1b[1~\x1b[?1;2c\x1b[>4;1m\x1b[0 q!", []string{"!aaaaa", "bbbbbb", "cccccc", "dddddd"}, [2]int{1, 0}},
+		{"PrivateRestoreIsNotMargins", "\x1b[3;3H\x1b[?1049r!", []string{"aaaaaa", "bbbbbb", "cc!ccc", "dddddd"}, [2]int{3, 2}},
+		{"SelectiveErase", "\x1b[2;3H\x1b[?K\x1b[?1J", []string{"", "", "cccccc", "dddddd"}, [2]int{2, 1}},
+		{"AlignmentTest", "\x1b#8", []string{"EEEEEE", "EEEEEE", "EEEEEE", "EEEEEE"}, [2]int{0, 0}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			view, err := NewWebView(dgclient.ViewOptions{InitialWidth: 6, InitialHeight: 4})
+			if err != nil {
+				t.Fatalf("NewWebView failed: %v", err)
+			}
+			if err := view.Render([]byte(fill + tt.input)); err != nil {
+				t.Fatalf("Render failed: %v", err)
+			}
+			state := view.GetCurrentState()
+			if got := stateLines(state); !slices.Equal(got, tt.want) {
+				t.Errorf("screen = %q, want %q", got, tt.want)
+			}
+			if got := [2]int{state.CursorX, state.CursorY}; got != tt.wantCursor {
+				t.Errorf("cursor = %v, want %v", got, tt.wantCursor)
+			}
+		})
+	}
+}
+
+func TestWebView_AlternateScreen(t *testing.T) {
+	view, err := NewWebView(dgclient.ViewOptions{InitialWidth: 6, InitialHeight: 4})
+	if err != nil {
+		t.Fatalf("NewWebView failed: %v", err)
+	}
+	render := func(data string) []string {
+		t.Helper()
+		if err := view.Render([]byte(data)); err != nil {
+			t.Fatalf("Render failed: %v", err)
+		}
+		return stateLines(view.GetCurrentState())
+	}
+
+	render("$ nh\x1b[2;3H")
+	if got := render("\x1b[?1049h\x1b[Hmenu"); !slices.Equal(got, []string{"menu", "", "", ""}) {
+		t.Errorf("alternate screen = %q, want only the menu", got)
+	}
+	if memory, _ := view.ScreenMemory(); memory != uint64(2*6*4*cellMemory) {
+		t.Errorf("ScreenMemory = %d, want both screens counted", memory)
+	}
+	if got := render("\x1b[?1049l!"); !slices.Equal(got, []string{"$ nh", "  !", "", ""}) {
+		t.Errorf("normal screen = %q, want it restored with the cursor", got)
+	}
+
+	// Resizing on the alternate screen leaves a blank normal screen
+	render("\x1b[?47h")
+	if err := view.SetSize(8, 3); err != nil {
+		t.Fatalf("SetSize failed: %v", err)
+	}
+	if got := render("\x1b[?47lx"); !slices.Equal(got, []string{"x", "", ""}) {
+		t.Errorf("normal screen after resize = %q, want blank", got)
+	}
+}
+
+func TestWebView_WideCharsAroundEdits(t *testing.T) {
+	view, err := NewWebView(dgclient.ViewOptions{InitialWidth: 6, InitialHeight: 1})
+	if err != nil {
+		t.Fatalf("NewWebView failed: %v", err)
+	}
+	// Inserting pushes the wide character off the edge, deleting the right
+	// half of one blanks its left half
+	if err := view.Render([]byte("ab日本\x1b[1;2H\x1b[@\x1b[1;4H\x1b[P")); err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	state := view.GetCurrentState()
+	if got := stateLines(state)[0]; got != "a b" {
+		t.Errorf("line = %q, want %q", got, "a b")
+	}
+	for x, cell := range state.Buffer[0] {
+		if cell.Wide || cell.Char == 0 {
+			t.Errorf("cell %d = %+v, want no broken wide character", x, cell)
+		}
+	}
+}
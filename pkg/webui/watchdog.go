@@ -0,0 +1,147 @@
+// Package webui provides a watchdog for stuck game streams: when the SSH
+// connection is up but the game stops answering, clients are told the
+// session may be stalled instead of being left with a frozen screen.
+package webui
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/opd-ai/go-gamelaunch-www/pkg/transport"
+)
+
+// WatchdogConfig flags the session as possibly stalled once input has gone
+// unanswered by the game for Timeout. With Probe set, the watchdog also
+// sends it to the game whenever the screen has been quiet for Timeout, so
+// a dead stream is noticed even while nobody types; a harmless key such as
+// Ctrl-R, which makes NetHack and DCSS redraw the screen, or a newline at a
+// shell prompt.
+type WatchdogConfig struct {
+	Timeout string `yaml:"timeout" yamlcheck:"duration"` // e.g. "90s"
+	Probe   string `yaml:"probe,omitempty"`              // input sent when quiet; none by default
+}
+
+// StreamStall announces that the game has not answered input since Since
+// although the connection claims to be alive
+type StreamStall struct {
+	Since  int64 `json:"since"`            // Unix milliseconds of the last output, like GameState.Timestamp
+	Probes int   `json:"probes,omitempty"` // probes sent since
+}
+
+// StreamWatchdog watches the output of game sessions for stalls; see
+// WatchdogConfig. One watchdog may watch any number of sessions.
+type StreamWatchdog struct {
+	timeout time.Duration
+	probe   []byte
+	now     func() time.Time
+}
+
+// NewStreamWatchdog validates cfg
+func NewStreamWatchdog(cfg WatchdogConfig) (*StreamWatchdog, error) {
+	timeout, err := time.ParseDuration(cfg.Timeout)
+	if err != nil || timeout < time.Second {
+		return nil, fmt.Errorf("watchdog: invalid timeout %q, must be at least 1s", cfg.Timeout)
+	}
+	return &StreamWatchdog{timeout: timeout, probe: []byte(cfg.Probe), now: time.Now}, nil
+}
+
+// watchState is what the watchdog knows about one session
+type watchState struct {
+	output time.Time // last output seen
+	probed time.Time // last probe sent
+	probes int       // probes sent since output
+	stall  *StreamStall
+}
+
+// Run watches view until ctx is done, typically for as long as the game
+// client is connected, and clears any stall it flagged when it returns.
+// connected reports whether the connection claims to be alive; nothing is
+// flagged while it does not, e.g. while the client reconnects.
+func (d *StreamWatchdog) Run(ctx context.Context, view *WebView, connected func() bool) {
+	var st watchState
+	defer view.SetStall(nil)
+
+	ticker := time.NewTicker(d.timeout / 4)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			d.check(view, connected(), &st)
+		}
+	}
+}
+
+// check flags or clears the stall of view and sends probes as they are due
+func (d *StreamWatchdog) check(view *WebView, connected bool, st *watchState) {
+	output, input := view.streamActivity()
+	now := d.now()
+
+	if !output.Equal(st.output) {
+		st.output, st.probes = output, 0
+		if st.stall != nil {
+			st.stall = nil
+			view.Events().Record(EventStreamResumed, "game output resumed")
+			view.logger.Info("webui: game output resumed")
+			view.SetStall(nil)
+		}
+	}
+	if !connected {
+		return
+	}
+
+	stall := st.stall
+	if stall == nil && input.After(output) && now.Sub(input) >= d.timeout {
+		stall = &StreamStall{Since: output.UnixMilli(), Probes: st.probes}
+		quiet := now.Sub(output).Round(time.Second)
+		view.Events().Record(EventStreamStalled, "no output for %s despite input", quiet)
+		view.logger.Warn("webui: game stream possibly stalled", "quiet", quiet, "probes", st.probes)
+	}
+	if len(d.probe) > 0 && now.Sub(output) >= d.timeout && now.Sub(st.probed) >= d.timeout {
+		view.SendInput(d.probe)
+		st.probed = now
+		st.probes++
+		if stall != nil {
+			stall = &StreamStall{Since: stall.Since, Probes: st.probes}
+		}
+	}
+	if stall != st.stall {
+		st.stall = stall
+		view.SetStall(stall)
+	}
+}
+
+// streamActivity returns when the game last sent output and when input was
+// last queued for it
+func (v *WebView) streamActivity() (output, input time.Time) {
+	output = v.created
+	if ns := v.lastOutput.Load(); ns != 0 {
+		output = time.Unix(0, ns)
+	}
+	if ns := v.lastInput.Load(); ns != 0 {
+		input = time.Unix(0, ns)
+	}
+	return output, input
+}
+
+// SetStall shows stall to clients with the next state, or stops showing it
+// when nil
+func (v *WebView) SetStall(stall *StreamStall) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.closed || v.stall == stall {
+		return
+	}
+	v.stall = stall
+	v.publishState()
+}
+
+// toWireStall converts a stall to its wire representation
+func toWireStall(stall *StreamStall) *transport.Stall {
+	if stall == nil {
+		return nil
+	}
+	return &transport.Stall{Since: stall.Since, Probes: stall.Probes}
+}
@@ -0,0 +1,124 @@
+package webui
+
+import (
+	"testing"
+	"time"
+
+	"github.com/opd-ai/go-gamelaunch-client/pkg/dgclient"
+)
+
+// newWatchedView returns a view whose last output was at start, and a
+// watchdog checking it at the time *now
+func newWatchedView(t *testing.T, cfg WatchdogConfig, start time.Time, now *time.Time) (*WebView, *StreamWatchdog) {
+	t.Helper()
+	view, err := NewWebView(dgclient.ViewOptions{InitialWidth: 10, InitialHeight: 2})
+	if err != nil {
+		t.Fatalf("NewWebView failed: %v", err)
+	}
+	t.Cleanup(func() { view.Close() })
+	view.lastOutput.Store(start.UnixNano())
+
+	d, err := NewStreamWatchdog(cfg)
+	if err != nil {
+		t.Fatalf("NewStreamWatchdog failed: %v", err)
+	}
+	d.now = func() time.Time { return *now }
+	return view, d
+}
+
+func TestStreamWatchdog_FlagsUnansweredInput(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	now := start
+	view, d := newWatchedView(t, WatchdogConfig{Timeout: "1m"}, start, &now)
+	var st watchState
+	stall := func() *StreamStall { return view.GetCurrentState().Stall }
+
+	// A quiet game nobody typed into is not stalled
+	now = start.Add(10 * time.Minute)
+	d.check(view, true, &st)
+	if stall() != nil {
+		t.Fatal("Quiet session without input flagged as stalled")
+	}
+
+	view.lastInput.Store(start.Add(10 * time.Minute).UnixNano())
+	now = start.Add(10*time.Minute + 59*time.Second)
+	d.check(view, true, &st)
+	if stall() != nil {
+		t.Fatal("Stall flagged before the timeout")
+	}
+	now = start.Add(11 * time.Minute)
+	d.check(view, false, &st)
+	if stall() != nil {
+		t.Fatal("Stall flagged while disconnected")
+	}
+	d.check(view, true, &st)
+	if got := stall(); got == nil || got.Since != start.UnixMilli() {
+		t.Fatalf("Stall = %+v, want one since the last output", got)
+	}
+
+	// Output clears the stall
+	view.lastOutput.Store(start.Add(12 * time.Minute).UnixNano())
+	now = start.Add(12 * time.Minute)
+	d.check(view, true, &st)
+	if got := stall(); got != nil {
+		t.Fatalf("Stall = %+v after output, want none", got)
+	}
+
+	var types []string
+	for _, event := range view.Events().Events("", 0) {
+		if event.Type == EventStreamStalled || event.Type == EventStreamResumed {
+			types = append(types, event.Type)
+		}
+	}
+	if len(types) != 2 || types[0] != EventStreamStalled || types[1] != EventStreamResumed {
+		t.Errorf("Events = %v, want stalled then resumed", types)
+	}
+}
+
+func TestStreamWatchdog_Probes(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	now := start
+	view, d := newWatchedView(t, WatchdogConfig{Timeout: "1m", Probe: "\x12"}, start, &now)
+	var st watchState
+	// Probes are queued at the real time, put them on the test's clock
+	probed := func() bool {
+		select {
+		case input := <-view.inputChan:
+			if string(input) != "\x12" {
+				t.Errorf("Probe = %q, want Ctrl-R", input)
+			}
+			view.lastInput.Store(now.UnixNano())
+			return true
+		default:
+			return false
+		}
+	}
+
+	now = start.Add(30 * time.Second)
+	if d.check(view, true, &st); probed() {
+		t.Error("Probe sent before the screen was quiet for the timeout")
+	}
+	now = start.Add(time.Minute)
+	if d.check(view, true, &st); !probed() {
+		t.Fatal("No probe sent once the screen was quiet")
+	}
+	now = start.Add(90 * time.Second)
+	if d.check(view, true, &st); probed() {
+		t.Error("Probe repeated before the timeout")
+	}
+
+	// The unanswered probe flags the stall, and probing goes on
+	now = start.Add(2 * time.Minute)
+	if d.check(view, true, &st); !probed() {
+		t.Error("Probing stopped while stalled")
+	}
+	if got := view.GetCurrentState().Stall; got == nil || got.Probes != 2 {
+		t.Fatalf("Stall = %+v, want one counting 2 probes", got)
+	}
+
+	for _, timeout := range []string{"", "x", "500ms"} {
+		if _, err := NewStreamWatchdog(WatchdogConfig{Timeout: timeout}); err == nil {
+			t.Errorf("NewStreamWatchdog accepted timeout %q", timeout)
+		}
+	}
+}
@@ -0,0 +1,322 @@
+// Package webui provides navigation of the dgamelaunch "watch games in progress" menu.
+package webui
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// watchMenuTimeout bounds how long a request waits for the game server to
+// draw the watch menu
+const watchMenuTimeout = 5 * time.Second
+
+// watchMenuSettle is how long the screen must stay unchanged before a newly
+// drawn menu is parsed, so that partially drawn pages are not returned
+const watchMenuSettle = 150 * time.Millisecond
+
+// dgamelaunch key bindings for the watch menu
+const (
+	watchMenuKey  = "w"
+	watchNextPage = ">"
+	watchPrevPage = "<"
+)
+
+var (
+	watchEntryPattern = regexp.MustCompile(`^\s*([A-Za-z])\)\s+\S`)
+	watchPagePattern  = regexp.MustCompile(`\((\d+)-(\d+) of (\d+)\)`)
+)
+
+// errNotWatchMenu is returned when the screen does not show the watch menu
+var errNotWatchMenu = errors.New("watch menu not shown")
+
+// WatchableGame is a game in progress listed in the dgamelaunch watch menu
+type WatchableGame struct {
+	Key      string `json:"key"` // menu key that selects the game
+	Username string `json:"username"`
+	Game     string `json:"game,omitempty"`
+	Size     string `json:"size,omitempty"`
+	Started  string `json:"started,omitempty"`
+	Idle     string `json:"idle,omitempty"`
+	Watchers int    `json:"watchers"`
+}
+
+// WatchMenu is the parsed content of one page of the watch menu
+type WatchMenu struct {
+	Games []WatchableGame `json:"games"`
+	First int             `json:"first,omitempty"` // position of the first game on this page
+	Last  int             `json:"last,omitempty"`  // position of the last game on this page
+	Total int             `json:"total"`           // games in progress on all pages
+}
+
+// watchColumn is a column of the watch menu table
+type watchColumn struct {
+	name  string
+	start int
+}
+
+// ParseWatchMenu parses a screen showing the dgamelaunch watch menu. ok is
+// false if the screen shows something else.
+func ParseWatchMenu(lines []string) (menu *WatchMenu, ok bool) {
+	var columns []watchColumn
+	menu = &WatchMenu{Games: []WatchableGame{}}
+	for _, line := range lines {
+		lower := strings.ToLower(line)
+		switch {
+		case strings.Contains(lower, "games are in progress"),
+			strings.Contains(lower, "watch which game"):
+			ok = true
+		case strings.Contains(lower, "no games available"):
+			return menu, true
+		case columns == nil && strings.Contains(lower, "username"):
+			columns = parseWatchHeader(line)
+		case watchEntryPattern.MatchString(line):
+			menu.Games = append(menu.Games, parseWatchEntry(line, columns))
+		}
+
+		if m := watchPagePattern.FindStringSubmatch(line); m != nil {
+			menu.First, _ = strconv.Atoi(m[1])
+			menu.Last, _ = strconv.Atoi(m[2])
+			menu.Total, _ = strconv.Atoi(m[3])
+		}
+	}
+	if !ok {
+		return nil, false
+	}
+	if menu.Total == 0 {
+		menu.Total = len(menu.Games)
+	}
+	return menu, true
+}
+
+// parseWatchHeader locates the table columns from the header line
+func parseWatchHeader(line string) []watchColumn {
+	lower := strings.ToLower(line)
+	var columns []watchColumn
+	for _, name := range []string{"username", "game", "size", "start", "idle", "watchers"} {
+		if i := strings.Index(lower, name); i >= 0 {
+			columns = append(columns, watchColumn{name: name, start: i})
+		}
+	}
+	// Columns are matched in header order, which dgamelaunch keeps fixed
+	for i := 1; i < len(columns); i++ {
+		if columns[i].start < columns[i-1].start {
+			return nil
+		}
+	}
+	return columns
+}
+
+// parseWatchEntry parses a single game line, using the header columns when
+// they are known and whitespace-separated fields otherwise
+func parseWatchEntry(line string, columns []watchColumn) WatchableGame {
+	m := watchEntryPattern.FindStringSubmatch(line)
+	game := WatchableGame{Key: m[1]}
+
+	fields := map[string]string{}
+	if len(columns) > 0 {
+		for i, col := range columns {
+			end := len(line)
+			if i+1 < len(columns) {
+				end = columns[i+1].start
+			}
+			if col.start < len(line) {
+				if end > len(line) {
+					end = len(line)
+				}
+				fields[col.name] = strings.TrimSpace(line[col.start:end])
+			}
+		}
+	} else {
+		parts := strings.Fields(line[strings.Index(line, ")")+1:])
+		for i, name := range []string{"username", "game", "size"} {
+			if i < len(parts) {
+				fields[name] = parts[i]
+			}
+		}
+	}
+
+	game.Username = fields["username"]
+	game.Game = fields["game"]
+	game.Size = fields["size"]
+	game.Started = fields["start"]
+	game.Idle = fields["idle"]
+	game.Watchers, _ = strconv.Atoi(fields["watchers"])
+	return game
+}
+
+// stateLines returns the rows of a state as text
+func stateLines(state *GameState) []string {
+	lines := make([]string, len(state.Buffer))
+	for y, row := range state.Buffer {
+		var sb strings.Builder
+		for _, cell := range row {
+			sb.WriteString(cell.Grapheme())
+		}
+		lines[y] = strings.TrimRight(sb.String(), " ")
+	}
+	return lines
+}
+
+// currentWatchMenu parses the current screen and returns it with its version
+func (w *WebUI) currentWatchMenu() (*WatchMenu, uint64, error) {
+	view := w.GetView()
+	if view == nil {
+		return nil, 0, fmt.Errorf("no view attached")
+	}
+	state := view.GetStateManager().GetCurrentState()
+	if state == nil {
+		return nil, 0, errNotWatchMenu
+	}
+	menu, ok := ParseWatchMenu(stateLines(state))
+	if !ok {
+		return nil, state.Version, errNotWatchMenu
+	}
+	return menu, state.Version, nil
+}
+
+// navigateWatchMenu sends keys to the game and waits until the screen shows
+// the watch menu again
+func (w *WebUI) navigateWatchMenu(ctx context.Context, keys string) (*WatchMenu, error) {
+	_, version, err := w.currentWatchMenu()
+	if err != nil && !errors.Is(err, errNotWatchMenu) {
+		return nil, err
+	}
+	if err := w.sendGameInput([]byte(keys)); err != nil {
+		return nil, err
+	}
+
+	sm := w.GetView().GetStateManager()
+	for {
+		if _, err := sm.PollChangesWithContext(ctx, version); err != nil {
+			return nil, fmt.Errorf("watch menu did not appear: %w", err)
+		}
+		_, current, err := w.currentWatchMenu()
+		if err == nil && current > version {
+			return w.settledWatchMenu(ctx, current)
+		}
+		version = current
+	}
+}
+
+// settledWatchMenu waits until the screen stops changing and parses it
+func (w *WebUI) settledWatchMenu(ctx context.Context, version uint64) (*WatchMenu, error) {
+	sm := w.GetView().GetStateManager()
+	for {
+		settleCtx, cancel := context.WithTimeout(ctx, watchMenuSettle)
+		_, err := sm.PollChangesWithContext(settleCtx, version)
+		cancel()
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("watch menu did not appear: %w", ctx.Err())
+		}
+		if err != nil {
+			break // quiet for watchMenuSettle
+		}
+		version = sm.GetCurrentVersion()
+	}
+
+	menu, _, err := w.currentWatchMenu()
+	return menu, err
+}
+
+// openWatchMenu returns the watch menu, opening it from the main menu if it
+// is not already shown
+func (w *WebUI) openWatchMenu(ctx context.Context) (*WatchMenu, error) {
+	menu, _, err := w.currentWatchMenu()
+	if err == nil {
+		return menu, nil
+	}
+	if !errors.Is(err, errNotWatchMenu) {
+		return nil, err
+	}
+	return w.navigateWatchMenu(ctx, watchMenuKey)
+}
+
+// handleWatchableGames handles GET /games/watchable[?page=next|prev],
+// opening the watch menu if needed and listing the games on the current page
+func (w *WebUI) handleWatchableGames(rw http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		rw.Header().Set("Allow", http.MethodGet)
+		http.Error(rw, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var pageKey string
+	switch r.URL.Query().Get("page") {
+	case "":
+	case "next":
+		pageKey = watchNextPage
+	case "prev":
+		pageKey = watchPrevPage
+	default:
+		http.Error(rw, "Invalid page", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), watchMenuTimeout)
+	defer cancel()
+
+	menu, err := w.openWatchMenu(ctx)
+	if err == nil && pageKey != "" {
+		menu, err = w.navigateWatchMenu(ctx, pageKey)
+	}
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusGatewayTimeout)
+		return
+	}
+	writeJSON(rw, http.StatusOK, menu)
+}
+
+// WatchParams selects a game from the watch menu by player name or menu key
+type WatchParams struct {
+	Username string `json:"username,omitempty"`
+	Key      string `json:"key,omitempty"`
+}
+
+// handleWatchGame handles POST /games/watch, starting to spectate a game
+// listed on the current page of the watch menu
+func (w *WebUI) handleWatchGame(rw http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		rw.Header().Set("Allow", http.MethodPost)
+		http.Error(rw, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var params WatchParams
+	if err := json.NewDecoder(http.MaxBytesReader(rw, r.Body, 4096)).Decode(&params); err != nil {
+		http.Error(rw, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if params.Username == "" && params.Key == "" {
+		http.Error(rw, "username or key is required", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), watchMenuTimeout)
+	defer cancel()
+
+	menu, err := w.openWatchMenu(ctx)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusGatewayTimeout)
+		return
+	}
+
+	for _, game := range menu.Games {
+		if (params.Username != "" && strings.EqualFold(game.Username, params.Username)) ||
+			(params.Username == "" && game.Key == params.Key) {
+			if err := w.sendGameInput([]byte(game.Key)); err != nil {
+				http.Error(rw, err.Error(), http.StatusBadGateway)
+				return
+			}
+			writeJSON(rw, http.StatusOK, map[string]interface{}{"watching": game})
+			return
+		}
+	}
+	http.Error(rw, "Game not found on the current page", http.StatusNotFound)
+}
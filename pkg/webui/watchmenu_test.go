@@ -0,0 +1,169 @@
+package webui
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testWatchMenu is a dgamelaunch watch menu as drawn by a typical server
+var testWatchMenu = []string{
+	" The following games are in progress: (use uppercase to try spectating)",
+	"",
+	"     Username         Game   Size     Start date & time     Idle time  Watchers",
+	"  a) alice            NH370  80x24    2026-10-01 12:00:00   1m 2s      3",
+	"  b) bob              DCSS   80x24    2026-10-01 13:30:00   10s        0",
+	"",
+	" (1-2 of 2)",
+	"",
+	" Watch which game? ('?' for help) =>",
+}
+
+func TestParseWatchMenu(t *testing.T) {
+	tests := []struct {
+		name      string
+		lines     []string
+		wantOK    bool
+		wantGames []WatchableGame
+		wantTotal int
+	}{
+		{
+			name:   "header columns",
+			lines:  testWatchMenu,
+			wantOK: true,
+			wantGames: []WatchableGame{
+				{Key: "a", Username: "alice", Game: "NH370", Size: "80x24", Started: "2026-10-01 12:00:00", Idle: "1m 2s", Watchers: 3},
+				{Key: "b", Username: "bob", Game: "DCSS", Size: "80x24", Started: "2026-10-01 13:30:00", Idle: "10s", Watchers: 0},
+			},
+			wantTotal: 2,
+		},
+		{
+			name: "no header",
+			lines: []string{
+				" The following games are in progress:",
+				"  a) carol NH370 80x24",
+			},
+			wantOK:    true,
+			wantGames: []WatchableGame{{Key: "a", Username: "carol", Game: "NH370", Size: "80x24"}},
+			wantTotal: 1,
+		},
+		{
+			name: "pagination footer",
+			lines: []string{
+				" The following games are in progress:",
+				"  a) dave NH370 80x24",
+				" (15-15 of 15)",
+			},
+			wantOK:    true,
+			wantGames: []WatchableGame{{Key: "a", Username: "dave", Game: "NH370", Size: "80x24"}},
+			wantTotal: 15,
+		},
+		{
+			name:      "no games",
+			lines:     []string{" Sorry, no games available for viewing."},
+			wantOK:    true,
+			wantGames: []WatchableGame{},
+		},
+		{
+			name:   "main menu",
+			lines:  []string{" ## dgamelaunch", "  l) Login", "  w) Watch games in progress", "  q) Quit"},
+			wantOK: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			menu, ok := ParseWatchMenu(tt.lines)
+			if ok != tt.wantOK {
+				t.Fatalf("ok = %v, want %v", ok, tt.wantOK)
+			}
+			if !ok {
+				return
+			}
+			if len(menu.Games) != len(tt.wantGames) {
+				t.Fatalf("got %d games, want %d: %+v", len(menu.Games), len(tt.wantGames), menu.Games)
+			}
+			for i, want := range tt.wantGames {
+				if menu.Games[i] != want {
+					t.Errorf("game %d = %+v, want %+v", i, menu.Games[i], want)
+				}
+			}
+			if menu.Total != tt.wantTotal {
+				t.Errorf("Total = %d, want %d", menu.Total, tt.wantTotal)
+			}
+		})
+	}
+}
+
+// drawScreen renders lines onto a cleared screen
+func drawScreen(t *testing.T, view *WebView, lines []string) {
+	t.Helper()
+	if err := view.Render([]byte("\x1b[2J\x1b[H" + strings.Join(lines, "\r\n"))); err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+}
+
+// readInput returns the next input the game would read
+func readInput(t *testing.T, view *WebView) string {
+	t.Helper()
+	select {
+	case data := <-view.inputChan:
+		return string(data)
+	case <-time.After(time.Second):
+		t.Fatal("no input sent to the game")
+		return ""
+	}
+}
+
+func TestWebUI_WatchableGames_OpensMenuFromMainMenu(t *testing.T) {
+	webUI := newTestWebUI(t)
+	view := webUI.GetView()
+	drawScreen(t, view, []string{" ## dgamelaunch", "  w) Watch games in progress"})
+
+	// Play the game server: draw the watch menu once "w" arrives
+	go func() {
+		if key := <-view.inputChan; string(key) == watchMenuKey {
+			_ = view.Render([]byte("\x1b[2J\x1b[H" + strings.Join(testWatchMenu, "\r\n")))
+		}
+	}()
+
+	rec := httptest.NewRecorder()
+	webUI.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/games/watchable", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", rec.Code, rec.Body.String())
+	}
+
+	var menu WatchMenu
+	if err := json.Unmarshal(rec.Body.Bytes(), &menu); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if len(menu.Games) != 2 || menu.Games[1].Username != "bob" {
+		t.Errorf("games = %+v, want alice and bob", menu.Games)
+	}
+}
+
+func TestWebUI_WatchGame_SendsMenuKey(t *testing.T) {
+	webUI := newTestWebUI(t)
+	view := webUI.GetView()
+	drawScreen(t, view, testWatchMenu)
+
+	rec := httptest.NewRecorder()
+	body := strings.NewReader(`{"username": "BOB"}`)
+	webUI.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/games/watch", body))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", rec.Code, rec.Body.String())
+	}
+	if key := readInput(t, view); key != "b" {
+		t.Errorf("sent %q, want %q", key, "b")
+	}
+
+	rec = httptest.NewRecorder()
+	body = strings.NewReader(`{"username": "mallory"}`)
+	webUI.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/games/watch", body))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("unknown player status = %d, want 404", rec.Code)
+	}
+}
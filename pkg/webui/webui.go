@@ -1,15 +1,25 @@
 package webui
 
 import (
+	"bytes"
 	"context"
+	"crypto/tls"
+	"errors"
 	"fmt"
-	"image/png"
 	"log/slog"
 	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/opd-ai/go-gamelaunch-client/pkg/dgclient"
+	"github.com/opd-ai/go-gamelaunch-www/pkg/announce"
+	"github.com/opd-ai/go-gamelaunch-www/pkg/chardump"
+	"github.com/opd-ai/go-gamelaunch-www/pkg/i18n"
+	"github.com/opd-ai/go-gamelaunch-www/pkg/recording"
 	"github.com/opd-ai/go-gamelaunch-www/pkg/transport"
+	"github.com/opd-ai/go-gamelaunch-www/pkg/ttyrec"
 )
 
 // WebUIOptions contains configuration for WebUI
@@ -22,6 +32,11 @@ type WebUIOptions struct {
 	TilesetPath string
 	Tileset     *TilesetConfig
 
+	// TilesetSubset serves a sheet of only the tiles the session drew
+	// instead of the tileset's whole source image, growing as new tiles
+	// are drawn
+	TilesetSubset bool
+
 	// Server configuration
 	ListenAddr  string
 	PollTimeout time.Duration
@@ -29,18 +44,245 @@ type WebUIOptions struct {
 	// CORS settings
 	AllowOrigins []string
 
+	// Connection limits (0 = unlimited)
+	MaxClients      int // Concurrent WebSocket clients attached to the session
+	MaxClientsPerIP int // Concurrent WebSocket clients and POST /input requests from a single IP
+
 	// Static file serving
 	StaticPath string // Optional: override embedded files
+
+	// Authentication: when any method is set, all routes except /auth/*
+	// require a login through one of them
+	OIDC *OIDCConfig
+
+	// Trusted reverse-proxy identity headers; may be combined with OIDC
+	ProxyAuth *ProxyAuthConfig
+
+	// Bearer tokens for scripts and bots
+	StaticTokens []StaticToken
+
+	// Password login against an htpasswd file
+	Htpasswd *HtpasswdConfig
+
+	// AuthProviders are additional identity systems, consulted after the
+	// built-in ones
+	AuthProviders []AuthProvider
+
+	// Authorization overrides entries of DefaultAuthorizationPolicy; it
+	// only applies when authentication is configured
+	Authorization AuthorizationPolicy
+
+	// RTC negotiates WebRTC data channels as an experimental alternative
+	// to WebSockets; nil disables the transport
+	RTC RTCNegotiator
+
+	// HTTP/3 listener alongside TCP; nil serves plain HTTP over TCP only
+	HTTP3 *HTTP3Config
+
+	// Per-IP request rate limiting; nil disables it
+	RateLimit *RateLimitConfig
+
+	// Structured access log of HTTP requests; nil disables it
+	AccessLog *AccessLogConfig
+
+	// Daily and monthly traffic caps; traffic is counted either way
+	Bandwidth *BandwidthConfig
+
+	// Size limit of diffs sent to pollers before falling back to row or
+	// keyframe encoding; nil sends every change individually
+	DiffBudget *DiffBudget
+
+	// Periodic full-state keyframes for pollers; nil sends only diffs
+	Keyframes *KeyframeConfig
+
+	// Escape-sequence security policy applied to View; nil keeps the
+	// default 32-byte limit without strict mode
+	EscapePolicy *EscapePolicyConfig
+
+	// Rows of View that scrolling never moves, e.g. status lines
+	ProtectedRegions []PinnedRegion
+
+	// WithholdConcealed never sends concealed (SGR 8) text to clients whose
+	// role does not allow input, and limits instant replay to those that
+	// do; players may still reveal it with the reveal_concealed view option
+	WithholdConcealed bool
+
+	// Tournament collects the final scores of this session, which appears
+	// on its leaderboard as TournamentSession
+	Tournament        *Tournament
+	TournamentSession string
+
+	// Announcer posts deaths and new tournament records of this session
+	Announcer *announce.Announcer
+
+	// Dumps archives the character dump of each game this session finishes
+	Dumps *chardump.Archive
+
+	// Recordings records each game of this session as a ttyrec file,
+	// indexed under Player until the end screen names the player
+	Recordings *recording.Store
+	Player     string
+
+	// RecordSession overrides the store's opt-in default for this session;
+	// nil keeps it
+	RecordSession *bool
+
+	// Recent game output kept in memory for instant replay; nil disables it
+	InstantReplay *InstantReplayConfig
+
+	// Periodic PNG previews of the screen at /thumb.png; nil disables them
+	Thumbnails *ThumbnailConfig
+
+	// Overview of the explored map at /game/minimap; nil disables it
+	Minimap *MinimapConfig
+
+	// Lines scrolled off the screen, at /game/scrollback; nil disables it
+	Scrollback *ScrollbackConfig
+
+	// Background merging of older diffs in the state history; nil disables
+	// it. The view's StateStore must implement HistoryCompactor.
+	HistoryCompaction *HistoryCompactionConfig
+
+	// Heatmap of the cells changed most often at /debug/damage; nil
+	// disables it
+	DamageMap *DamageMapConfig
+
+	// Screen snapshots written to disk periodically and when the game goes
+	// idle, listed at /admin/snapshots; nil disables them
+	Snapshots *SnapshotConfig
+
+	// Cache-Control overrides by route; nil keeps the defaults
+	Cache *CacheConfig
+
+	// Idle timeout after which the session expires, with a countdown sent
+	// to clients beforehand; nil disables it. Admins may also end the
+	// session at /admin/expiry; see WebUI.Expired.
+	Expiry *ExpiryConfig
+
+	// Key sequence sent to the game some time after the last player left,
+	// before the session is closed; nil leaves the game running
+	Teardown *TeardownConfig
+
+	// How long the game keeps running with no client connected before the
+	// session expires; nil keeps it until the game ends
+	Detach *DetachConfig
+
+	// Memory, scrollback and recording limits of the session, reported in
+	// session stats; nil sets none
+	Quota *QuotaConfig
+
+	// Built-in filters that remap, mask or refuse client input before it
+	// reaches the game; nil adds none. Filter plugins may also be added
+	// with WebUI.AddInputFilter.
+	InputFilter *InputFilterConfig
+
+	// Built-in filters that strip sequences from or draw a watermark over
+	// the game's output before it is parsed; nil adds none. Filter plugins
+	// may also be added with WebUI.AddOutputFilter.
+	OutputFilter *OutputFilterConfig
+
+	// Turn counter and game clock read from the screen into session stats
+	// and diffs; nil disables it. Parser plugins may also be added with
+	// WebView.AddProgressParser.
+	Progress *ProgressConfig
+
+	// Names of the landmark parsers that find the map, status and message
+	// areas of the screen for keyboard navigation and ARIA labels, e.g.
+	// "nethack"; see LandmarkParsers. Parser plugins may also be added with
+	// WebView.AddLandmarkParser.
+	Landmarks []string
+
+	// Lobby page listing servers, sessions and recordings; nil disables it
+	Lobby *LobbyConfig
+
+	// Name of the input preset selected at startup, e.g. "nethack"; see
+	// InputPresets
+	InputPreset string
+
+	// On-screen keyboard layouts served at /keyboard; nil disables them
+	Keyboards *KeyboardConfig
+
+	// Color themes served at /theme besides the built-in dark and light
+	// ones; nil offers only those
+	Themes *ThemeConfig
+
+	// Webfont served at /font and the cell size of text mode; nil bundles no
+	// font and uses the tileset's, or the default, cell size
+	Font *FontConfig
+
+	// SSHChallenges relays keyboard-interactive prompts of the SSH login to
+	// web clients through /ssh/challenge; nil disables it
+	SSHChallenges *ChallengeRelay
+
+	// HostKeys verifies the game server's host key, asking web clients about
+	// unknown and changed keys through /ssh/hostkey when authentication is
+	// configured; nil disables it
+	HostKeys *HostKeyStore
+
+	// InputSink receives client input instead of the view when set, for
+	// instances that do not own the SSH session
+	InputSink func(data []byte) error
+
+	// Translations of the server's messages by locale and then message key,
+	// see i18n.English; nil serves English
+	Messages map[string]map[string]string
 }
 
 // WebUI provides a web-based interface for dgclient
 type WebUI struct {
-	view           *WebView
-	tileset        *TilesetConfig
+	view           atomic.Pointer[WebView]       // see GetView and ReplaceView
+	viewMu         sync.Mutex                    // serializes ReplaceView
+	viewSwap       chan struct{}                 // closed when ReplaceView swaps the view
+	streamDone     chan struct{}                 // closed when the view's state stream stops, or nil
+	keyframed      uint64                        // version of the keyframe sent by ReplaceView
+	outputFilters  []OutputFilter                // applied to every view, see AddOutputFilter
+	tileset        atomic.Pointer[servedTileset] // see GetTileset and UpdateTileset
+	tilesetGrowing atomic.Bool                   // see tilesetGrown
 	tilesetService *TilesetService
 	wsHandler      *transport.Handler
+	authProviders  []AuthProvider
+	policy         *authzPolicy
+	rateLimiter    *RateLimiter
+	accessLog      *accessLogger
+	bandwidth      *bandwidthMeter
+	colors         *ColorConverter // transforms colors for client color modes
+	scores         *Tournament     // scrapes final scores; the tournament or a private one
+	recorder       *recording.Recorder
+	pauseParsers   []PauseParser    // screens left out of recordings
+	replay         *ttyrec.Ring     // recent output for instant replay, or nil
+	replayScreen   *replayKeyframer // screen of the output replay dropped
+	http3TLS       *tls.Config      // certificate of the HTTP/3 listeners, or nil
+	thumbnails     *thumbnailer     // screen previews, or nil
+	minimap        *minimapper      // explored map overview, or nil
+	scrollback     int              // lines kept, or 0
+	damage         *damageMap       // cell change counts, or nil
+	challenges     *ChallengeRelay  // SSH login prompts, or nil
+	hostKeys       *HostKeyStore    // host key decisions, or nil
+	inputSeqs      *inputSequencer
+	landmarks      []LandmarkParser // finds the semantic regions of the screen
+	keys           *keyCounter      // keys pressed by players, see LiveStats
+	started        time.Time
+	inputFilters   *inputFilterChain
+	compaction     *historyCompaction
+	messages       *i18n.Catalog
+	themes         *themeSet
+	snapshots      *snapshotter
+	expiry         *expiryTimer
+	broadcastMu    sync.Mutex
+	broadcast      *SessionBroadcast // shown to clients, see BroadcastMessage
+	teardown       *teardownWatch    // controlling clients, or nil
+	detach         *detachWatch
+	quota          *sessionQuota
+	font           *bundledFont
+	cache          *cachePolicy
+	static         *staticAssets
+	tilesetImages  tilesetImageCache
+	inputPreset    atomic.Pointer[InputPreset] // selected preset, or nil
+	keyboards      []KeyboardLayout            // on-screen keyboards, or nil
 	mux            *http.ServeMux
 	options        WebUIOptions
+	correlationID  string       // see CorrelationID
+	log            *slog.Logger // tagged with correlationID
 }
 
 // NewWebUI creates a new WebUI instance
@@ -56,25 +298,255 @@ func NewWebUI(opts WebUIOptions) (*WebUI, error) {
 	}
 
 	webui := &WebUI{
-		view:    opts.View,
-		options: opts,
-		mux:     http.NewServeMux(),
+		viewSwap:     make(chan struct{}),
+		options:      opts,
+		mux:          http.NewServeMux(),
+		colors:       NewColorConverter(),
+		inputSeqs:    newInputSequencer(),
+		keys:         newKeyCounter(),
+		started:      time.Now(),
+		inputFilters: &inputFilterChain{},
 	}
+	webui.correlationID = transport.NewCorrelationID("s-")
+	webui.log = slog.Default().With("session_id", webui.correlationID)
 
 	// Load tileset if specified
-	if opts.Tileset != nil {
-		webui.tileset = opts.Tileset
-	} else if opts.TilesetPath != "" {
-		tileset, err := LoadTilesetConfig(opts.TilesetPath)
+	tileset := opts.Tileset
+	if tileset == nil && opts.TilesetPath != "" {
+		loaded, err := LoadTilesetConfig(opts.TilesetPath)
 		if err != nil {
 			return nil, fmt.Errorf("failed to load tileset: %w", err)
 		}
-		webui.tileset = tileset
+		tileset = loaded
+	}
+	if tileset != nil {
+		subset, err := webui.subsetTileset(tileset)
+		if err != nil {
+			return nil, fmt.Errorf("failed to subset tileset: %w", err)
+		}
+		webui.tileset.Store(&servedTileset{source: tileset, subset: subset})
+	}
+
+	if opts.HTTP3 != nil {
+		tlsConfig, err := newHTTP3TLSConfig(*opts.HTTP3)
+		if err != nil {
+			return nil, fmt.Errorf("failed to configure HTTP/3: %w", err)
+		}
+		webui.http3TLS = tlsConfig
+	}
+
+	// Configure authentication if requested
+	providers, err := newAuthProviders(opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure authentication: %w", err)
+	}
+	webui.authProviders = providers
+	policy, err := newAuthzPolicy(opts.Authorization)
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure authorization: %w", err)
+	}
+	webui.policy = policy
+
+	// Configure abuse protection if requested
+	if opts.RateLimit != nil {
+		limiter, err := NewRateLimiter(*opts.RateLimit)
+		if err != nil {
+			return nil, fmt.Errorf("failed to configure rate limiting: %w", err)
+		}
+		webui.rateLimiter = limiter
+	}
+
+	if opts.AccessLog != nil {
+		accessLog, err := newAccessLogger(*opts.AccessLog)
+		if err != nil {
+			return nil, fmt.Errorf("failed to configure access log: %w", err)
+		}
+		accessLog.logger = webui.log
+		webui.accessLog = accessLog
+	}
+
+	var quotaConfig QuotaConfig
+	if opts.Quota != nil {
+		quotaConfig = *opts.Quota
+	}
+	quota, err := newSessionQuota(quotaConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure quota: %w", err)
+	}
+	webui.quota = quota
+
+	// Scrape final scores for the leaderboard, the announcer and the dump
+	// archive
+	webui.scores = opts.Tournament
+	if webui.scores == nil && (opts.Announcer != nil || opts.Dumps != nil || opts.Recordings != nil) {
+		webui.scores, _ = NewTournament(TournamentConfig{})
+	}
+	if opts.Announcer != nil {
+		webui.scores.OnScore(webui.announceScore)
+	}
+	if opts.Dumps != nil {
+		webui.scores.OnScore(webui.archiveDump)
 	}
 
-	// Set tileset on view if available
-	if webui.view != nil && webui.tileset != nil {
-		webui.view.SetTileset(webui.tileset)
+	landmarks, err := lookupLandmarkParsers(opts.Landmarks)
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure landmarks: %w", err)
+	}
+	webui.landmarks = landmarks
+
+	// Record the session's output, one recording per game
+	if opts.Recordings != nil {
+		webui.recorder = opts.Recordings.NewRecorder(webui.sessionName(), opts.Player)
+		if opts.RecordSession != nil {
+			webui.recorder.SetEnabled(*opts.RecordSession)
+		}
+		webui.recorder.SetMaxSize(int64(quota.recording))
+		parsers, err := lookupPauseParsers(opts.Recordings.PauseOn())
+		if err != nil {
+			return nil, fmt.Errorf("failed to configure recordings: %w", err)
+		}
+		webui.pauseParsers = parsers
+		webui.scores.OnScore(webui.endRecording)
+	}
+
+	// Keep recent output for instant replay
+	if opts.InstantReplay != nil {
+		ring, keyframer, err := newReplayRing(*opts.InstantReplay, quota.scrollback)
+		if err != nil {
+			return nil, fmt.Errorf("failed to configure instant replay: %w", err)
+		}
+		webui.replay, webui.replayScreen = ring, keyframer
+	}
+
+	if err := webui.SetInputPreset(opts.InputPreset); err != nil {
+		return nil, fmt.Errorf("failed to configure input preset: %w", err)
+	}
+
+	if opts.Keyboards != nil {
+		keyboards, err := newKeyboardLayouts(*opts.Keyboards)
+		if err != nil {
+			return nil, fmt.Errorf("failed to configure keyboards: %w", err)
+		}
+		webui.keyboards = keyboards
+	}
+
+	if opts.Thumbnails != nil {
+		thumbnails, err := newThumbnailer(*opts.Thumbnails)
+		if err != nil {
+			return nil, fmt.Errorf("failed to configure thumbnails: %w", err)
+		}
+		webui.thumbnails = thumbnails
+	}
+
+	if opts.Minimap != nil {
+		minimap, err := newMinimapper(*opts.Minimap)
+		if err != nil {
+			return nil, fmt.Errorf("failed to configure minimap: %w", err)
+		}
+		webui.minimap = minimap
+	}
+
+	if opts.Scrollback != nil {
+		lines, err := validateScrollback(*opts.Scrollback)
+		if err != nil {
+			return nil, fmt.Errorf("failed to configure scrollback: %w", err)
+		}
+		webui.scrollback = lines
+	}
+
+	if opts.HistoryCompaction != nil {
+		compaction, err := newHistoryCompaction(*opts.HistoryCompaction)
+		if err != nil {
+			return nil, fmt.Errorf("failed to configure history compaction: %w", err)
+		}
+		webui.compaction = compaction
+	}
+
+	if opts.Snapshots != nil {
+		snapshots, err := newSnapshotter(*opts.Snapshots)
+		if err != nil {
+			return nil, fmt.Errorf("failed to configure snapshots: %w", err)
+		}
+		snapshots.sessionID = webui.correlationID
+		webui.snapshots = snapshots
+	}
+
+	if opts.DamageMap != nil {
+		damage, err := newDamageMap(*opts.DamageMap)
+		if err != nil {
+			return nil, fmt.Errorf("failed to configure damage map: %w", err)
+		}
+		webui.damage = damage
+	}
+
+	messages, err := i18n.NewCatalog(opts.Messages)
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure messages: %w", err)
+	}
+	webui.messages = messages
+
+	var themeConfig ThemeConfig
+	if opts.Themes != nil {
+		themeConfig = *opts.Themes
+	}
+	themes, err := newThemeSet(themeConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure themes: %w", err)
+	}
+	webui.themes = themes
+
+	var fontConfig FontConfig
+	if opts.Font != nil {
+		fontConfig = *opts.Font
+	}
+	font, err := newBundledFont(fontConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure font: %w", err)
+	}
+	webui.font = font
+
+	cache, err := newCachePolicy(opts.Cache)
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure cache: %w", err)
+	}
+	webui.cache = cache
+
+	if opts.InputFilter != nil {
+		filters, err := NewInputFilters(*opts.InputFilter)
+		if err != nil {
+			return nil, fmt.Errorf("failed to configure input filter: %w", err)
+		}
+		for _, filter := range filters {
+			webui.AddInputFilter(filter)
+		}
+	}
+
+	if opts.OutputFilter != nil {
+		filters, err := NewOutputFilters(*opts.OutputFilter)
+		if err != nil {
+			return nil, fmt.Errorf("failed to configure output filter: %w", err)
+		}
+		webui.outputFilters = filters
+	}
+
+	expiry, err := newExpiryTimer(opts.Expiry)
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure expiry: %w", err)
+	}
+	webui.expiry = expiry
+
+	detach, err := newDetachWatch(opts.Detach)
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure detach: %w", err)
+	}
+	webui.detach = detach
+
+	if opts.Teardown != nil {
+		teardown, err := newTeardownWatch(*opts.Teardown)
+		if err != nil {
+			return nil, fmt.Errorf("failed to configure teardown: %w", err)
+		}
+		webui.teardown = teardown
 	}
 
 	// Create tileset service for hot-reload support
@@ -82,29 +554,199 @@ func NewWebUI(opts WebUIOptions) (*WebUI, error) {
 
 	// Create WebSocket handler
 	webui.wsHandler = transport.NewHandler()
+	webui.wsHandler.SetLimits(opts.MaxClients, opts.MaxClientsPerIP)
+	webui.wsHandler.SetCatalog(webui.messages)
+	webui.wsHandler.SetInputHandler(webui.handleClientInput)
+	webui.wsHandler.SetConnectHandler(webui.handleClientConnect)
+	webui.wsHandler.SetDisconnectHandler(webui.handleClientDisconnect)
+	webui.wsHandler.SetViewOptionsHandler(webui.sendCurrentState)
+	if opts.WithholdConcealed {
+		webui.wsHandler.SetViewOptionsPolicy(webui.concealmentPolicy)
+	}
+	if opts.SSHChallenges != nil {
+		webui.challenges = opts.SSHChallenges
+		webui.challenges.setNotifier(webui.wsHandler.BroadcastAuthChallenge)
+	}
+	// Anonymous visitors must not approve host keys, which would let anyone
+	// accept a man in the middle
+	if opts.HostKeys != nil && webui.authRequired() {
+		webui.hostKeys = opts.HostKeys
+		webui.hostKeys.setNotifier(webui.wsHandler.BroadcastHostKey)
+	}
+
+	// Account the session's traffic
+	var bandwidthConfig BandwidthConfig
+	if opts.Bandwidth != nil {
+		bandwidthConfig = *opts.Bandwidth
+	}
+	bandwidth, err := newBandwidthMeter(bandwidthConfig, webui.wsHandler.Traffic)
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure bandwidth caps: %w", err)
+	}
+	webui.bandwidth = bandwidth
+
+	if err := webui.attachView(opts.View); err != nil {
+		return nil, err
+	}
+	webui.view.Store(opts.View)
 
 	// Set up routes
-	webui.setupRoutes()
+	if err := webui.setupRoutes(); err != nil {
+		return nil, err
+	}
 
 	return webui, nil
 }
 
 // setupRoutes configures HTTP routes
-func (w *WebUI) setupRoutes() {
+func (w *WebUI) setupRoutes() error {
 	// Tileset image endpoint
 	w.mux.HandleFunc("/tileset/image", w.handleTilesetImage)
+	w.mux.HandleFunc("/tileset/usage", w.handleTilesetUsage)
 
 	// WebSocket endpoint for real-time state updates
 	w.mux.HandleFunc("/ws", w.wsHandler.ServeHTTP)
 
+	// Input over plain HTTP, safe to retry
+	w.mux.HandleFunc("/input", w.handleInput)
+	w.mux.HandleFunc("/input/preset", w.handleInputPreset)
+	if w.options.Keyboards != nil {
+		w.mux.HandleFunc("/keyboard", w.handleKeyboard)
+	}
+
+	// Appearance chosen by each client
+	w.mux.HandleFunc("/theme", w.handleTheme)
+	w.mux.HandleFunc("/theme.css", w.handleThemeCSS)
+	w.mux.HandleFunc("/font/metrics", w.handleFontMetrics)
+	if w.font.data != nil {
+		w.mux.HandleFunc("/font", w.handleFont)
+	}
+	if w.challenges != nil {
+		w.mux.HandleFunc("/ssh/challenge", w.handleSSHChallenge)
+	}
+	if w.hostKeys != nil {
+		w.mux.HandleFunc("/ssh/hostkey", w.handleHostKey)
+	}
+
+	// Experimental WebRTC data channel transport
+	if w.options.RTC != nil {
+		w.mux.HandleFunc("/rtc/offer", w.handleRTCOffer)
+	}
+
+	// Deployment and session introspection endpoints
+	w.mux.HandleFunc("/version", w.handleVersion)
+	w.mux.HandleFunc("/session/info", w.handleSessionInfo)
+	w.mux.HandleFunc("/session/stats", w.handleSessionStats)
+	w.mux.HandleFunc("/session/live", w.handleLiveStats)
+	w.mux.HandleFunc("/session/spectate", w.handleSpectate)
+	w.mux.HandleFunc("/metrics", w.handleMetrics)
+
+	// Entry point listing servers, sessions and recordings
+	if w.options.Lobby != nil {
+		w.mux.HandleFunc("/lobby", w.handleLobbyPage)
+		w.mux.HandleFunc("/lobby/info", w.handleLobbyInfo)
+	}
+
+	// Spectating through the dgamelaunch watch menu
+	w.mux.HandleFunc("/games/watchable", w.handleWatchableGames)
+	w.mux.HandleFunc("/games/watch", w.handleWatchGame)
+
+	// Tournament leaderboard
+	if t := w.options.Tournament; t != nil {
+		w.mux.HandleFunc("/tournament", t.HandlePage)
+		w.mux.HandleFunc("/tournament/leaderboard", t.HandleLeaderboard)
+	}
+
+	// Character dump archive
+	if d := w.options.Dumps; d != nil {
+		w.mux.HandleFunc("/dumps", d.HandleList)
+		w.mux.HandleFunc("/dumps/file", d.HandleFile)
+	}
+
+	// Recorded games
+	if s := w.options.Recordings; s != nil {
+		w.mux.HandleFunc("/recordings", s.HandleList)
+		w.mux.HandleFunc("/recordings/file", w.handleRecordingFile)
+		w.mux.HandleFunc("/session/recording", w.handleSessionRecording)
+	}
+	if w.replay != nil {
+		w.mux.HandleFunc("/replay/instant", w.handleInstantReplay)
+	}
+	if w.thumbnails != nil {
+		w.mux.HandleFunc("/thumb.png", w.handleThumbnail)
+	}
+	if w.minimap != nil {
+		w.mux.HandleFunc("/game/minimap", w.handleMinimap)
+	}
+	if w.scrollback > 0 {
+		w.mux.HandleFunc("/game/scrollback", w.handleScrollback)
+	}
+
+	// Administrative endpoints, only when authentication can tell admins
+	// from anyone else
+	if w.authRequired() {
+		w.mux.HandleFunc("/admin/broadcast", w.handleAdminBroadcast)
+		w.mux.HandleFunc("/admin/events", w.handleAdminEvents)
+		w.mux.HandleFunc("/admin/expiry", w.handleAdminExpiry)
+		w.mux.HandleFunc("/admin/clients", w.handleAdminClients)
+		w.mux.HandleFunc("/admin/security", w.handleAdminSecurity)
+		if w.rateLimiter != nil {
+			w.mux.HandleFunc("/admin/bans", w.handleAdminBans)
+		}
+		if w.snapshots != nil {
+			w.mux.HandleFunc("/admin/snapshots", w.handleAdminSnapshots)
+		}
+		if w.damage != nil {
+			w.mux.HandleFunc("/debug/damage", w.handleDamage)
+		}
+	}
+
+	// Login endpoints
+	if w.authRequired() {
+		routes := map[string]bool{"/auth/whoami": true}
+		w.mux.HandleFunc("/auth/whoami", w.handleWhoAmI)
+		for _, provider := range w.authProviders {
+			for path, handler := range provider.Routes() {
+				if !strings.HasPrefix(path, "/auth/") {
+					return fmt.Errorf("auth provider route %q is not below /auth/", path)
+				}
+				if routes[path] {
+					return fmt.Errorf("auth provider route %q is registered twice", path)
+				}
+				routes[path] = true
+				w.mux.HandleFunc(path, handler)
+			}
+		}
+	}
+
 	// Static files served from filesystem when StaticPath is configured
 	if w.options.StaticPath != "" {
-		w.mux.Handle("/", http.FileServer(http.Dir(w.options.StaticPath)))
+		w.static = newStaticAssets(w, w.options.StaticPath)
+		w.mux.Handle("/", w.static)
 	}
+	return nil
 }
 
 // ServeHTTP implements http.Handler
 func (w *WebUI) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	r = withCorrelation(rw, r)
+	if w.accessLog != nil {
+		w.accessLog.serve(rw, r, w.serve)
+		return
+	}
+	w.serve(rw, r)
+}
+
+// serve applies rate limiting, CORS and authentication, then routes r
+func (w *WebUI) serve(rw http.ResponseWriter, r *http.Request) {
+	// Refuse clients over their request budget before doing any work
+	if w.rateLimiter != nil {
+		if ok, retryAfter := w.rateLimiter.Allow(transport.RemoteIP(r)); !ok {
+			rejectRateLimited(rw, retryAfter)
+			return
+		}
+	}
+
 	// Add CORS headers
 	w.addCORSHeaders(rw, r)
 
@@ -114,10 +756,68 @@ func (w *WebUI) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Require a login when authentication is configured
+	if w.authRequired() && !strings.HasPrefix(r.URL.Path, "/auth/") {
+		identity, provider := w.authenticate(r)
+		if identity == nil {
+			w.rejectUnauthenticated(rw, r)
+			return
+		}
+		setAccessLogSubject(rw, identity.Subject)
+		r = r.WithContext(withAuthProvider(WithIdentity(r.Context(), identity), provider))
+		if !w.authorize(r.Context(), routeMethod(r.URL.Path)) {
+			rejectForbidden(rw, identity)
+			return
+		}
+	}
+
 	// Route request
 	w.mux.ServeHTTP(rw, r)
 }
 
+// authRequired reports whether any authentication method is configured
+func (w *WebUI) authRequired() bool {
+	return len(w.authProviders) > 0
+}
+
+// authenticate resolves the identity of r with the first provider that
+// recognises its credentials
+func (w *WebUI) authenticate(r *http.Request) (*Identity, AuthProvider) {
+	for _, provider := range w.authProviders {
+		if identity := provider.Authenticate(r); identity != nil {
+			return identity, provider
+		}
+	}
+	return nil, nil
+}
+
+// rejectUnauthenticated sends browsers to the login page when one exists and
+// API clients a 401 with the challenges of the configured providers
+func (w *WebUI) rejectUnauthenticated(rw http.ResponseWriter, r *http.Request) {
+	for _, provider := range w.authProviders {
+		if _, ok := provider.Routes()["/auth/login"]; ok && r.Method == http.MethodGet && strings.Contains(r.Header.Get("Accept"), "text/html") {
+			http.Redirect(rw, r, basePath(r.Context())+"/auth/login", http.StatusFound)
+			return
+		}
+	}
+	for _, provider := range w.authProviders {
+		if challenger, ok := provider.(AuthChallenger); ok {
+			rw.Header().Add("WWW-Authenticate", challenger.Challenge())
+		}
+	}
+	http.Error(rw, "Authentication required", http.StatusUnauthorized)
+}
+
+// handleWhoAmI returns the identity of the logged-in user
+func (w *WebUI) handleWhoAmI(rw http.ResponseWriter, r *http.Request) {
+	identity, _ := w.authenticate(r)
+	if identity == nil {
+		http.Error(rw, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+	writeJSON(rw, http.StatusOK, identity)
+}
+
 // addCORSHeaders adds CORS headers to response
 func (w *WebUI) addCORSHeaders(rw http.ResponseWriter, r *http.Request) {
 	origin := r.Header.Get("Origin")
@@ -130,14 +830,17 @@ func (w *WebUI) addCORSHeaders(rw http.ResponseWriter, r *http.Request) {
 		rw.Header().Set("Access-Control-Allow-Origin", "*")
 	}
 
-	rw.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
-	rw.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+	rw.Header().Set("Access-Control-Allow-Methods", "GET, HEAD, POST, OPTIONS")
+	rw.Header().Set("Access-Control-Allow-Headers", "Content-Type, Range, If-None-Match, If-Modified-Since, If-Range, "+transport.CorrelationHeader)
+	rw.Header().Set("Access-Control-Expose-Headers", "Accept-Ranges, Content-Range, ETag, "+transport.CorrelationHeader)
 	rw.Header().Set("Access-Control-Max-Age", "86400")
 
-	// Prevent caching of dynamic content
-	rw.Header().Set("Cache-Control", "no-cache, no-store, must-revalidate")
-	rw.Header().Set("Pragma", "no-cache")
-	rw.Header().Set("Expires", "0")
+	// Prevent caching of dynamic content unless its route is configured
+	w.setCacheControl(rw, r, "no-cache, no-store, must-revalidate")
+	if _, ok := w.cache.lookup(r.URL.Path); !ok {
+		rw.Header().Set("Pragma", "no-cache")
+		rw.Header().Set("Expires", "0")
+	}
 }
 
 // isOriginAllowed checks if an origin is in the allowed list
@@ -152,61 +855,190 @@ func (w *WebUI) isOriginAllowed(origin string) bool {
 
 // handleTilesetImage serves the tileset image
 func (w *WebUI) handleTilesetImage(rw http.ResponseWriter, r *http.Request) {
-	slog.Debug("webui.handleTilesetImage", "remote", r.RemoteAddr)
-
-	if w.tileset == nil || w.tileset.GetImageData() == nil {
-		http.NotFound(rw, r)
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		rw.Header().Set("Allow", "GET, HEAD")
+		http.Error(rw, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
-
-	// Check for If-None-Match header for caching
-	etag := fmt.Sprintf(`"%s-%s"`, w.tileset.Name, w.tileset.Version)
-	if r.Header.Get("If-None-Match") == etag {
-		rw.WriteHeader(http.StatusNotModified)
+	image, err := w.tilesetImages.get(w.GetTileset())
+	if err != nil {
+		w.logger(r.Context()).Error("webui.handleTilesetImage: encode failed", "error", err)
+		http.Error(rw, "Failed to encode image", http.StatusInternalServerError)
+		return
+	}
+	if image == nil {
+		http.NotFound(rw, r)
 		return
 	}
 
-	// Set caching headers
-	rw.Header().Set("ETag", etag)
-	rw.Header().Set("Cache-Control", "public, max-age=3600")
+	// Versioned URLs (see TilesetImageURL) may be cached forever; ServeContent
+	// answers conditional and range requests against the content hash
+	if r.URL.Query().Get("v") == image.hash {
+		w.setCacheControl(rw, r, w.cacheVisibility()+", "+immutableMaxAge)
+	} else {
+		w.setCacheControl(rw, r, w.cacheVisibility()+", max-age=3600")
+	}
+	rw.Header().Set("ETag", `"`+image.hash+`"`)
 	rw.Header().Set("Content-Type", "image/png")
+	http.ServeContent(rw, r, "", image.modTime, bytes.NewReader(image.data))
+}
 
-	// Encode image as PNG
-	if err := png.Encode(rw, w.tileset.GetImageData()); err != nil {
-		slog.Error("webui.handleTilesetImage: encode failed", "error", err)
-		http.Error(rw, "Failed to encode image", http.StatusInternalServerError)
-		return
+// handleClientInput forwards input received from a WebSocket client to the
+// view. Input typed at a password prompt is redacted from the log.
+func (w *WebUI) handleClientInput(clientID, input string) error {
+	view := w.GetView()
+	if view == nil {
+		return fmt.Errorf("no view attached")
+	}
+
+	ctx, ok := w.wsHandler.ClientContext(clientID)
+	if !ok {
+		ctx = context.Background()
+	}
+	if !w.authorize(ctx, MethodInput) {
+		return errors.New(w.messages.Message(i18n.Locale(ctx), i18n.MsgInputForbidden))
+	}
+
+	data, err := w.filterInput(ctx, []byte(w.inputPreset.Load().translateRaw(input)))
+	if err != nil {
+		w.logger(ctx).Debug("webui.handleClientInput: input refused by filter", "client", clientID, "error", err)
+		return err
+	}
+	if len(data) == 0 {
+		return nil
+	}
+	w.logger(ctx).Debug("webui.handleClientInput", "client", clientID, "input", view.RedactInput(data))
+	if err := w.sendGameInput(data); err != nil {
+		return err
+	}
+	w.keys.add(data)
+	return nil
+}
+
+// sendGameInput delivers input to the game, through InputSink when this
+// instance does not own the SSH session
+func (w *WebUI) sendGameInput(data []byte) error {
+	if w.options.InputSink != nil {
+		if err := w.options.InputSink(data); err != nil {
+			return err
+		}
+		w.bandwidth.gameSent.Add(uint64(len(data)))
+		w.noteInput()
+		return nil
+	}
+	view := w.GetView()
+	if view == nil {
+		return fmt.Errorf("no view attached")
+	}
+	view.SendInput(data)
+	w.bandwidth.gameSent.Add(uint64(len(data)))
+	w.noteInput()
+	return nil
+}
+
+// SessionInfo describes the current game session
+type SessionInfo struct {
+	Width        int       `json:"width"`
+	Height       int       `json:"height"`
+	StateVersion uint64    `json:"state_version"`
+	Epoch        string    `json:"epoch"` // changes when the server restarts
+	Clients      int       `json:"clients"`
+	Spectators   int       `json:"spectators"` // clients watching without input, see /session/spectate
+	Tileset      string    `json:"tileset,omitempty"`
+	TilesetImage string    `json:"tileset_image,omitempty"` // content-hashed URL, see TilesetImageURL
+	InputPreset  string    `json:"input_preset,omitempty"`
+	Build        BuildInfo `json:"build"`
+
+	DetachedSince *time.Time `json:"detached_since,omitempty"` // when the last client left, see DetachConfig
+}
+
+// handleVersion serves the build information of the running server
+func (w *WebUI) handleVersion(rw http.ResponseWriter, r *http.Request) {
+	writeJSON(rw, http.StatusOK, GetBuildInfo())
+}
+
+// handleSessionInfo serves information about the current session
+func (w *WebUI) handleSessionInfo(rw http.ResponseWriter, r *http.Request) {
+	writeJSON(rw, http.StatusOK, w.GetSessionInfo())
+}
+
+// GetSessionInfo returns information about the current session
+func (w *WebUI) GetSessionInfo() SessionInfo {
+	info := SessionInfo{
+		Clients:     w.wsHandler.GetClientCount(),
+		Spectators:  w.spectatorCount(),
+		InputPreset: w.InputPreset(),
+		Build:       GetBuildInfo(),
+
+		DetachedSince: w.detachedSince(),
 	}
+
+	if view := w.GetView(); view != nil {
+		info.Width, info.Height = view.GetSize()
+		info.StateVersion = view.GetStateManager().GetCurrentVersion()
+		info.Epoch = view.GetStateManager().Epoch()
+	}
+	if served := w.tileset.Load(); served != nil {
+		info.Tileset = served.source.Name
+		info.TilesetImage = w.TilesetImageURL()
+	}
+
+	return info
+}
+
+// servedTileset is the tileset of a WebUI and, with the TilesetSubset
+// option, the subset of it clients are served
+type servedTileset struct {
+	source *TilesetConfig
+	subset *tileSubset
 }
 
-// GetTileset returns the current tileset configuration
+// GetTileset returns the current tileset configuration, as served to
+// clients
 func (w *WebUI) GetTileset() *TilesetConfig {
-	return w.tileset
+	served := w.tileset.Load()
+	if served == nil {
+		return nil
+	}
+	if served.subset != nil {
+		return served.subset.Tileset()
+	}
+	return served.source
 }
 
 // UpdateTileset updates the tileset configuration
 func (w *WebUI) UpdateTileset(tileset *TilesetConfig) error {
-	w.tileset = tileset
+	subset, err := w.subsetTileset(tileset)
+	if err != nil {
+		return fmt.Errorf("failed to subset tileset: %w", err)
+	}
+
+	w.viewMu.Lock()
+	defer w.viewMu.Unlock()
+	if tileset == nil {
+		w.tileset.Store(nil)
+	} else {
+		w.tileset.Store(&servedTileset{source: tileset, subset: subset})
+	}
 
-	if w.view != nil {
-		w.view.SetTileset(tileset)
+	if view := w.GetView(); view != nil {
+		view.setTileset(tileset, subset)
 	}
 
 	return nil
 }
 
-// SetView sets the view for the WebUI
+// SetView sets the view for the WebUI. It is ReplaceView without the error,
+// which is logged and leaves the previous view in place.
 func (w *WebUI) SetView(view *WebView) {
-	w.view = view
-
-	if w.tileset != nil {
-		view.SetTileset(w.tileset)
+	if err := w.ReplaceView(view); err != nil {
+		w.log.Error("webui: failed to set view", "error", err)
 	}
 }
 
 // GetView returns the current view
 func (w *WebUI) GetView() *WebView {
-	return w.view
+	return w.view.Load()
 }
 
 // Start starts the WebUI server
@@ -223,8 +1055,13 @@ func (w *WebUI) Start(addr string) error {
 		IdleTimeout:  120 * time.Second,
 	}
 
+	go w.runViewLoops(context.Background())
+	go w.watchBandwidth(context.Background())
+	go w.expiry.run(context.Background(), w)
+
 	fmt.Printf("WebUI server starting on %s\n", addr)
-	return server.ListenAndServe()
+	errs, _ := w.listen(server)
+	return <-errs
 }
 
 // StartWithContext starts the WebUI server with context for graceful shutdown
@@ -241,34 +1078,161 @@ func (w *WebUI) StartWithContext(ctx context.Context, addr string) error {
 		IdleTimeout:  120 * time.Second,
 	}
 
-	// Start tileset hot-reload monitoring if we have a tileset service
-	if tilesetService := w.getTilesetService(); tilesetService != nil {
-		go func() {
-			if err := tilesetService.StartHotReload(ctx); err != nil && err != context.Canceled {
-				slog.Error("webui: tileset hot-reload stopped", "error", err)
-			}
-		}()
-	}
+	w.startBackground(ctx)
 
-	// Start server in goroutine
-	errCh := make(chan error, 1)
-	go func() {
-		fmt.Printf("WebUI server starting on %s\n", addr)
-		errCh <- server.ListenAndServe()
-	}()
+	// Start servers in the background
+	fmt.Printf("WebUI server starting on %s\n", addr)
+	errCh, shutdown := w.listen(server)
 
 	// Wait for context cancellation or server error
 	select {
 	case <-ctx.Done():
+		w.finishRecording()
+
 		// Graceful shutdown
 		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 		defer cancel()
-		return server.Shutdown(shutdownCtx)
+		return shutdown(shutdownCtx)
 	case err := <-errCh:
 		return err
 	}
 }
 
+// Run performs the background work of the WebUI, such as pushing screen
+// updates to WebSocket clients, until ctx is canceled. StartWithContext calls
+// it; embedders serving the WebUI from their own server, e.g. through a
+// SessionRouter, call Run instead.
+func (w *WebUI) Run(ctx context.Context) {
+	w.startBackground(ctx)
+	<-ctx.Done()
+	w.finishRecording()
+}
+
+// startBackground starts the background work of the WebUI until ctx is
+// canceled
+func (w *WebUI) startBackground(ctx context.Context) {
+	// Start tileset hot-reload monitoring if we have a tileset service
+	if tilesetService := w.getTilesetService(); tilesetService != nil {
+		go func() {
+			if err := tilesetService.StartHotReload(ctx); err != nil && err != context.Canceled {
+				w.log.Error("webui: tileset hot-reload stopped", "error", err)
+			}
+		}()
+	}
+
+	// Push screen updates to WebSocket clients
+	go w.runViewLoops(ctx)
+	go w.watchBandwidth(ctx)
+	go w.expiry.run(ctx, w)
+}
+
+// finishRecording completes the recording in progress, if any
+func (w *WebUI) finishRecording() {
+	if w.recorder == nil {
+		return
+	}
+	if err := w.recorder.Close(); err != nil {
+		w.log.Error("webui: failed to finish recording", "error", err)
+	}
+}
+
+// watchScores starts scraping final scores of view for tournament mode or
+// the announcer
+func (w *WebUI) watchScores(ctx context.Context, view *WebView) {
+	if w.scores == nil || view == nil {
+		return
+	}
+	go w.scores.Watch(ctx, w.sessionName(), view.GetStateManager())
+}
+
+// sessionName identifies this session on the leaderboard and in
+// announcements
+func (w *WebUI) sessionName() string {
+	if w.options.TournamentSession != "" {
+		return w.options.TournamentSession
+	}
+	return w.options.ListenAddr
+}
+
+// announceScore announces a finished game, and a new record when the
+// session plays in a tournament
+func (w *WebUI) announceScore(entry ScoreEntry, highScore bool) {
+	if entry.Session != w.sessionName() {
+		return
+	}
+	event := announce.Event{
+		Type:    announce.EventDeath,
+		Player:  entry.Player,
+		Session: entry.Session,
+		Game:    entry.Game,
+		Outcome: entry.Outcome,
+		Score:   entry.Score,
+	}
+	w.options.Announcer.Announce(event)
+
+	if highScore && w.options.Tournament != nil {
+		event.Type = announce.EventRecord
+		event.Tournament = w.options.Tournament.Name()
+		w.options.Announcer.Announce(event)
+	}
+}
+
+// endRecording completes the recording of a game this session finished
+func (w *WebUI) endRecording(entry ScoreEntry, _ bool) {
+	if entry.Session != w.sessionName() {
+		return
+	}
+	player := entry.Player
+	if player == entry.Session {
+		player = "" // not named by the game
+	}
+	err := w.recorder.EndGame(recording.Result{
+		Player:  player,
+		Game:    entry.Game,
+		Outcome: entry.Outcome,
+		Score:   entry.Score,
+	})
+	if err != nil {
+		w.log.Error("webui: failed to finish recording", "error", err)
+	}
+}
+
+// handleRecordingFile serves the ttyrec files of recordings, which hold
+// concealed text verbatim, so only to players when it is withheld
+func (w *WebUI) handleRecordingFile(rw http.ResponseWriter, r *http.Request) {
+	if w.options.WithholdConcealed && !w.authorize(r.Context(), MethodInput) {
+		rejectForbidden(rw, IdentityFromContext(r.Context()))
+		return
+	}
+	w.options.Recordings.HandleFile(rw, r)
+}
+
+// dumpFetchTimeout bounds fetching a character dump from the game server
+const dumpFetchTimeout = 2 * time.Minute
+
+// archiveDump fetches the character dump of a game this session finished
+func (w *WebUI) archiveDump(entry ScoreEntry, _ bool) {
+	// Dumps are found by the player name the game showed; the session name
+	// stands in for it otherwise
+	if entry.Session != w.sessionName() || entry.Player == entry.Session {
+		return
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), dumpFetchTimeout)
+		defer cancel()
+		_, err := w.options.Dumps.Collect(ctx, chardump.Game{
+			Player:  entry.Player,
+			Game:    entry.Game,
+			Outcome: entry.Outcome,
+			Score:   entry.Score,
+			Ended:   entry.Time,
+		})
+		if err != nil {
+			w.log.Warn("webui: character dump not archived", "player", entry.Player, "error", err)
+		}
+	}()
+}
+
 // getTilesetService returns the tileset service for hot-reload monitoring.
 func (w *WebUI) getTilesetService() *TilesetService {
 	return w.tilesetService
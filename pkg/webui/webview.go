@@ -3,30 +3,73 @@
 package webui
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
+	"unicode/utf8"
 
 	"github.com/opd-ai/go-gamelaunch-client/pkg/dgclient"
 )
 
+// ErrViewClosed is returned when rendering to or sending input to a view
+// after Close
+var ErrViewClosed = errors.New("view closed")
+
 // WebView implements dgclient.View for web browser rendering
 // Moved from: view.go
 type WebView struct {
-	mu           sync.RWMutex
+	mu           *viewLock
 	buffer       [][]Cell
 	width        int
 	height       int
 	cursorX      int
 	cursorY      int
 	inputChan    chan []byte
-	updateNotify chan struct{}
+	inputMu      sync.RWMutex  // held by senders; Close takes it before closing inputChan
+	done         chan struct{} // closed by Close to release blocked senders
+	updates      *updateNotifier
 	stateManager *StateManager
+	events       *EventLog
+	logger       *slog.Logger
 	tileset      *TilesetConfig
-	closed       bool // Track if view has been closed to prevent race conditions
+	tileSubset   *tileSubset // where tileset's tiles are served, if subset
+	closed       bool        // Track if view has been closed to prevent race conditions
+	created      time.Time
+
+	// Scrolling: rows scrollTop..scrollBottom scroll, except protected ones
+	scrollTop    int
+	scrollBottom int
+	protected    []PinnedRegion
+
+	secretPrompt bool // Game is waiting for a password; input must be redacted
+	modes        terminalModes
+
+	outputTaps []func(data []byte)                   // see TapOutput
+	screenTaps []func(data []byte, state *GameState) // see TapScreen
+	annotators []CellAnnotator                       // see AddAnnotator
+
+	progressParsers []ProgressParser // see AddProgressParser
+	landmarkParsers []LandmarkParser // see AddLandmarkParser
+	outputFilters   []OutputFilter   // see AddOutputFilter
+
+	tileDraws map[tileKey]uint64 // see TileUsage
+	expiry    *SessionExpiry     // see SetExpiry
+	stall     *StreamStall       // see SetStall
+	broadcast *SessionBroadcast  // see SetBroadcast
+
+	// Unix nanoseconds of the last output and input; see streamActivity
+	lastOutput atomic.Int64
+	lastInput  atomic.Int64
+
+	maxCells int  // see SetMaxCells
+	shrunk   bool // a resize was shrunk to maxCells
 
 	// ANSI parsing state - simplified with library integration
 	currentFgColor string
@@ -34,8 +77,20 @@ type WebView struct {
 	currentBold    bool
 	currentInverse bool
 	currentBlink   bool
+	currentAttrs   textAttributes
 	escapeBuffer   []byte
-	inEscapeSeq    bool
+	vtState        vtState      // see processEscapeByte
+	charset        charsetState // see charsetState.translate
+	savedCursor    savedCursor  // see saveCursor
+	grapheme       graphemeState
+	utf8Partial    []byte          // incomplete UTF-8 sequence, see decodeUTF8
+	mainBuffer     [][]Cell        // the normal screen while the alternate one is shown
+	scrollback     *scrollbackRing // see SetScrollback
+
+	// Escape-sequence security policy; escapeTerminated is set once a strict
+	// policy trips and makes every later Render fail
+	escapePolicy     *escapePolicy
+	escapeTerminated bool
 
 	// Color converter using fatih/color library
 	colorConverter *ColorConverter
@@ -44,6 +99,16 @@ type WebView struct {
 // NewWebView creates a new web-based view
 // Moved from: view.go
 func NewWebView(opts dgclient.ViewOptions) (*WebView, error) {
+	return NewWebViewWithStore(opts, nil)
+}
+
+// NewWebViewWithStore creates a web view whose state history is kept in
+// store; a nil store uses the in-memory default
+func NewWebViewWithStore(opts dgclient.ViewOptions, store StateStore) (*WebView, error) {
+	if store == nil {
+		store = NewMemoryStateStore(0)
+	}
+
 	width := opts.InitialWidth
 	height := opts.InitialHeight
 
@@ -54,13 +119,27 @@ func NewWebView(opts dgclient.ViewOptions) (*WebView, error) {
 		height = 24
 	}
 
+	policy, err := newEscapePolicy(EscapePolicyConfig{})
+	if err != nil {
+		return nil, err
+	}
+
+	events := NewEventLog(0)
+	stateManager := NewStateManagerWithStore(store)
+	stateManager.SetEventLog(events)
+
 	view := &WebView{
+		mu:           newViewLock(),
 		width:        width,
 		height:       height,
 		inputChan:    make(chan []byte, 100),
-		updateNotify: make(chan struct{}, 10),
-		stateManager: NewStateManager(),
+		done:         make(chan struct{}),
+		updates:      newUpdateNotifier(),
+		stateManager: stateManager,
+		events:       events,
+		logger:       slog.Default(),
 		closed:       false, // Initialize closed state
+		created:      time.Now(),
 
 		// Initialize color state
 		currentFgColor: "#FFFFFF",
@@ -68,8 +147,8 @@ func NewWebView(opts dgclient.ViewOptions) (*WebView, error) {
 		currentBold:    false,
 		currentInverse: false,
 		currentBlink:   false,
-		escapeBuffer:   make([]byte, 0, 32),
-		inEscapeSeq:    false,
+		escapeBuffer:   make([]byte, 0, defaultMaxEscapeLength),
+		escapePolicy:   policy,
 
 		// Initialize color converter
 		colorConverter: NewColorConverter(),
@@ -92,6 +171,8 @@ func (v *WebView) Init() error {
 // initBuffer initializes the screen buffer
 // Moved from: view.go
 func (v *WebView) initBuffer() {
+	v.resetScrollRegion()
+	v.breakGrapheme()
 	v.buffer = make([][]Cell, v.height)
 	for y := 0; y < v.height; y++ {
 		v.buffer[y] = make([]Cell, v.width)
@@ -118,25 +199,71 @@ func (v *WebView) Render(data []byte) error {
 	v.mu.Lock()
 	defer v.mu.Unlock()
 
+	return v.render(context.Background(), data)
+}
+
+// RenderContext is like Render but gives up with ctx.Err() if ctx is done
+// while waiting for the view lock or for the state store to save the new
+// screen. The screen itself is always updated in full once rendering
+// starts; one the store did not save is published with the next update.
+func (v *WebView) RenderContext(ctx context.Context, data []byte) error {
+	if err := v.mu.LockContext(ctx); err != nil {
+		return err
+	}
+	defer v.mu.Unlock()
+
+	return v.render(ctx, data)
+}
+
+// render updates the buffer and publishes the new state; v.mu must be held
+func (v *WebView) render(ctx context.Context, data []byte) error {
 	// Check if view is closed to prevent race condition
 	if v.closed {
-		return fmt.Errorf("cannot render to closed view")
+		return fmt.Errorf("cannot render to closed view: %w", ErrViewClosed)
+	}
+	if v.escapeTerminated {
+		return ErrEscapePolicyViolation
+	}
+	v.lastOutput.Store(time.Now().UnixNano())
+
+	for _, filter := range v.outputFilters {
+		if data = filter.FilterOutput(data); len(data) == 0 {
+			return nil
+		}
+	}
+	for _, tap := range v.outputTaps {
+		tap(data)
 	}
 
 	// Process the terminal data to update buffer
+	v.events.RecordRender(len(data))
 	v.processTerminalData(data)
+	v.secretPrompt = v.detectSecretPrompt()
+	if v.escapeTerminated {
+		return ErrEscapePolicyViolation
+	}
 
 	// Update state manager with new version
-	state := v.getCurrentState()
-	v.stateManager.UpdateState(state)
+	return v.publishRender(ctx, data)
+}
 
-	// Notify polling clients of updates - safe channel send
-	select {
-	case v.updateNotify <- struct{}{}:
-	default:
-	}
+// TapOutput registers fn to receive the raw terminal output of every Render,
+// e.g. for recording. fn runs with the view locked and must not call back
+// into the view.
+func (v *WebView) TapOutput(fn func(data []byte)) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.outputTaps = append(v.outputTaps, fn)
+}
 
-	return nil
+// TapScreen registers fn to receive the terminal output of every Render
+// together with the screen it produced, e.g. for recording only some
+// screens. fn runs with the view locked and must neither call back into the
+// view nor modify or keep state.
+func (v *WebView) TapScreen(fn func(data []byte, state *GameState)) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.screenTaps = append(v.screenTaps, fn)
 }
 
 // Clear clears the display
@@ -145,13 +272,13 @@ func (v *WebView) Clear() error {
 	v.mu.Lock()
 	defer v.mu.Unlock()
 
+	v.events.Record(EventClear, "screen cleared")
 	v.clearScreen()
 	v.cursorX = 0
 	v.cursorY = 0
 
 	// Update state manager
-	state := v.getCurrentState()
-	v.stateManager.UpdateState(state)
+	v.publishState()
 
 	return nil
 }
@@ -162,15 +289,30 @@ func (v *WebView) SetSize(width, height int) error {
 	v.mu.Lock()
 	defer v.mu.Unlock()
 
+	v.resize(width, height)
+	return nil
+}
+
+// resize reinitializes the screen at the given size, shrunk to the cell
+// limit
+func (v *WebView) resize(width, height int) {
+	if fitWidth, fitHeight, shrunk := v.fitSize(width, height); shrunk {
+		v.events.Record(EventQuota, "%dx%d exceeds the memory quota, shrunk to %dx%d", width, height, fitWidth, fitHeight)
+		v.logger.Warn("webui.WebView shrunk terminal to the memory quota",
+			"requested", fmt.Sprintf("%dx%d", width, height), "size", fmt.Sprintf("%dx%d", fitWidth, fitHeight))
+		width, height, v.shrunk = fitWidth, fitHeight, true
+	}
+
+	v.events.Record(EventResize, "%dx%d -> %dx%d", v.width, v.height, width, height)
 	v.width = width
 	v.height = height
 	v.initBuffer()
+	if v.scrollback != nil {
+		v.scrollback.trim(v.scrollbackBudget())
+	}
 
 	// Update state manager
-	state := v.getCurrentState()
-	v.stateManager.UpdateState(state)
-
-	return nil
+	v.publishState()
 }
 
 // GetSize returns current dimensions
@@ -182,13 +324,18 @@ func (v *WebView) GetSize() (int, int) {
 	return v.width, v.height
 }
 
-// HandleInput reads and returns user input
+// HandleInput waits for input from web clients. It returns io.EOF once the
+// view is closed, as dgclient.View requires, rather than whenever no input
+// is queued, which made dgclient stop reading input altogether.
 // Moved from: view.go
 func (v *WebView) HandleInput() ([]byte, error) {
 	select {
-	case input := <-v.inputChan:
+	case input, ok := <-v.inputChan:
+		if !ok {
+			return nil, io.EOF
+		}
 		return input, nil
-	default:
+	case <-v.done:
 		return nil, io.EOF
 	}
 }
@@ -204,30 +351,65 @@ func (v *WebView) Close() error {
 		return nil
 	}
 
+	v.events.Record(EventClose, "view closed")
 	v.closed = true
+	close(v.done)
+	v.updates.close()
+
+	// Wait for in-flight senders, which return once done is closed
+	v.inputMu.Lock()
 	close(v.inputChan)
-	close(v.updateNotify)
+	v.inputMu.Unlock()
 	return nil
 }
 
 // SendInput queues input from web client
 // Moved from: view.go
 func (v *WebView) SendInput(data []byte) {
-	v.mu.RLock()
-	if v.closed {
-		v.mu.RUnlock()
+	v.inputMu.RLock()
+	defer v.inputMu.RUnlock()
+	v.lastInput.Store(time.Now().UnixNano())
+
+	select {
+	case <-v.done:
 		return // Silently ignore input to closed view
+	default:
 	}
-	v.mu.RUnlock()
 
 	select {
 	case v.inputChan <- data:
 	default:
 		// Input buffer full, drop input
+		v.events.Record(EventInputDropped, "input buffer full, dropped %d bytes", len(data))
+	}
+}
+
+// SendInputContext queues input, waiting for room in the input buffer
+// instead of dropping it. It returns ctx.Err() if ctx is done first and
+// ErrViewClosed if the view is or becomes closed.
+func (v *WebView) SendInputContext(ctx context.Context, data []byte) error {
+	v.inputMu.RLock()
+	defer v.inputMu.RUnlock()
+	v.lastInput.Store(time.Now().UnixNano())
+
+	select {
+	case <-v.done:
+		return ErrViewClosed
+	default:
+	}
+
+	select {
+	case v.inputChan <- data:
+		return nil
+	case <-v.done:
+		return ErrViewClosed
+	case <-ctx.Done():
+		return ctx.Err()
 	}
 }
 
-// GetCurrentState returns the current game state
+// GetCurrentState returns a snapshot of the current game state. The
+// snapshot owns its buffer and is unaffected by later rendering.
 // Moved from: view.go
 func (v *WebView) GetCurrentState() *GameState {
 	v.mu.RLock()
@@ -239,10 +421,16 @@ func (v *WebView) GetCurrentState() *GameState {
 // SetTileset updates the tileset configuration
 // Moved from: view.go
 func (v *WebView) SetTileset(tileset *TilesetConfig) {
+	v.setTileset(tileset, nil)
+}
+
+// setTileset is SetTileset for a tileset served as subset, which may be nil
+func (v *WebView) setTileset(tileset *TilesetConfig, subset *tileSubset) {
 	v.mu.Lock()
 	defer v.mu.Unlock()
 
 	v.tileset = tileset
+	v.tileSubset = subset
 
 	// Re-apply tileset mappings to current buffer
 	if tileset != nil {
@@ -250,53 +438,131 @@ func (v *WebView) SetTileset(tileset *TilesetConfig) {
 			for x := 0; x < v.width; x++ {
 				cell := &v.buffer[y][x]
 				if mapping := tileset.GetMapping(cell.Char); mapping != nil {
-					cell.TileX = mapping.X
-					cell.TileY = mapping.Y
+					cell.TileX, cell.TileY = v.tileCoords(mapping)
 					cell.Changed = true
 				}
 			}
 		}
 
 		// Update state manager
-		state := v.getCurrentState()
-		v.stateManager.UpdateState(state)
+		v.publishState()
 	}
 }
 
+// SetLogger sets the logger of the view and its state manager, for example
+// one tagged with the session's correlation ID
+func (v *WebView) SetLogger(logger *slog.Logger) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.logger = logger
+	v.stateManager.SetLogger(logger)
+}
+
+// Events returns the debugging event log for this view's session
+func (v *WebView) Events() *EventLog {
+	return v.events
+}
+
 // GetStateManager returns the state manager for this view
 // Moved from: view.go
 func (v *WebView) GetStateManager() *StateManager {
 	return v.stateManager
 }
 
-// WaitForUpdate waits for the next screen update
+// WaitForUpdate waits for the next screen update. It returns false after
+// timeout or when the view is closed.
+//
+// Deprecated: use WaitForUpdateContext, which cannot miss an update made
+// between two calls.
 // Moved from: view.go
 func (v *WebView) WaitForUpdate(timeout time.Duration) bool {
-	select {
-	case <-v.updateNotify:
-		return true
-	case <-time.After(timeout):
-		return false
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	_, err := v.WaitForUpdateContext(ctx, v.stateManager.GetCurrentVersion())
+	return err == nil
+}
+
+// WaitForUpdateContext waits until the screen version exceeds sinceVersion
+// and returns the current version. It returns ctx.Err() when ctx ends first
+// and ErrViewClosed when the view is closed without a newer version.
+func (v *WebView) WaitForUpdateContext(ctx context.Context, sinceVersion uint64) (uint64, error) {
+	// Subscribe before checking the version so no update is missed
+	updates, unsubscribe := v.SubscribeUpdates(ctx)
+	defer unsubscribe()
+
+	for {
+		if version := v.stateManager.GetCurrentVersion(); version > sinceVersion {
+			return version, nil
+		}
+		select {
+		case _, ok := <-updates:
+			if !ok {
+				if version := v.stateManager.GetCurrentVersion(); version > sinceVersion {
+					return version, nil
+				}
+				return 0, ErrViewClosed
+			}
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		}
 	}
 }
 
+// publishState saves the screen as a new version and wakes subscribers. The
+// caller must hold v.mu.
+func (v *WebView) publishState() {
+	v.publishRender(context.Background(), nil)
+}
+
+// publishRender is publishState for a screen produced by the output data,
+// which it passes to the screen taps. It returns ctx.Err() if ctx is done
+// before the screen is saved. The caller must hold v.mu.
+func (v *WebView) publishRender(ctx context.Context, data []byte) error {
+	state := v.getCurrentState()
+	if data != nil {
+		for _, tap := range v.screenTaps {
+			tap(data, state)
+		}
+	}
+	err := v.stateManager.UpdateStateContext(ctx, state)
+	v.updates.notify()
+	return err
+}
+
+// SubscribeUpdates returns a channel receiving a value after screen updates
+// until unsubscribe is called or ctx is done. Updates arriving while a value
+// is pending are coalesced into it. The channel is closed when the view is.
+func (v *WebView) SubscribeUpdates(ctx context.Context) (updates <-chan struct{}, unsubscribe func()) {
+	return v.updates.subscribe(ctx)
+}
+
 // getCurrentState returns current state without locking (internal use)
 // Moved from: view.go
 func (v *WebView) getCurrentState() *GameState {
 	state := &GameState{
-		Buffer:    make([][]Cell, v.height),
+		Buffer:    newCellGrid(v.width, v.height),
 		Width:     v.width,
 		Height:    v.height,
-		CursorX:   v.cursorX,
+		CursorX:   min(v.cursorX, v.width-1),
 		CursorY:   v.cursorY,
+		Echo:      v.localEcho(),
+		Pinned:    v.pinnedRegions(),
 		Timestamp: time.Now().UnixMilli(),
 	}
 
 	// Copy buffer
 	for y := 0; y < v.height; y++ {
-		state.Buffer[y] = make([]Cell, v.width)
 		copy(state.Buffer[y], v.buffer[y])
 	}
+	v.annotate(state)
+	state.Progress = v.progress(state)
+	state.Landmarks = v.landmarks(state)
+	state.Expiry = v.expiry
+	state.Stall = v.stall
+	state.Broadcast = v.broadcast
+	if v.scrollback != nil {
+		state.Scrollback = v.scrollback.info
+	}
 
 	return state
 }
@@ -307,11 +573,14 @@ func (v *WebView) processTerminalData(data []byte) {
 	for i := 0; i < len(data); i++ {
 		b := data[i]
 
-		if v.inEscapeSeq {
-			if !v.processEscapeByte(b) {
-				continue
-			}
-			i-- // Reprocess byte if escape overflow
+		if v.vtState != vtGround {
+			v.processEscapeByte(b)
+			continue
+		}
+
+		// Multibyte characters are written whole so that graphemes can be
+		// assembled, even when a Render ends partway through one
+		if (b >= utf8.RuneSelf || len(v.utf8Partial) > 0) && v.decodeUTF8(b) {
 			continue
 		}
 
@@ -319,26 +588,36 @@ func (v *WebView) processTerminalData(data []byte) {
 	}
 }
 
-// processEscapeByte handles a byte during escape sequence processing
-// Returns true if escape sequence was reset due to overflow
-func (v *WebView) processEscapeByte(b byte) bool {
-	// Check for buffer overflow protection
-	if len(v.escapeBuffer) >= 32 {
-		fmt.Printf("SECURITY WARNING: Escape sequence buffer overflow attempt detected, resetting\n")
-		v.escapeBuffer = v.escapeBuffer[:0]
-		v.inEscapeSeq = false
+// decodeUTF8 adds b to the pending UTF-8 sequence and writes its character
+// once the sequence is complete. Invalid bytes are written as U+FFFD, as in
+// xterm; it returns false when b ended an incomplete sequence that way and
+// is ASCII, to be processed on its own.
+func (v *WebView) decodeUTF8(b byte) bool {
+	v.utf8Partial = append(v.utf8Partial, b)
+	if !utf8.FullRune(v.utf8Partial) {
+		return true
+	}
+	r, size := utf8.DecodeRune(v.utf8Partial)
+	if size == len(v.utf8Partial) {
+		v.utf8Partial = v.utf8Partial[:0]
+		v.writeCharacter(r)
 		return true
 	}
-	v.escapeBuffer = append(v.escapeBuffer, b)
-	if v.processEscapeSequence(b) {
-		v.inEscapeSeq = false
-		v.escapeBuffer = v.escapeBuffer[:0]
+
+	// b does not continue the sequence, which is cut short
+	v.utf8Partial = v.utf8Partial[:0]
+	v.writeCharacter(utf8.RuneError)
+	if b < utf8.RuneSelf {
+		return false
 	}
-	return false
+	return v.decodeUTF8(b)
 }
 
 // processControlChar handles control characters and printable characters
 func (v *WebView) processControlChar(b byte) {
+	if b < 32 || b == 127 {
+		v.breakGrapheme()
+	}
 	switch b {
 	case '\x1b': // ESC
 		v.startEscapeSequence()
@@ -350,6 +629,10 @@ func (v *WebView) processControlChar(b byte) {
 		v.handleBackspace()
 	case '\t':
 		v.handleTab()
+	case ctrlSO:
+		v.charset.shifted = true
+	case ctrlSI:
+		v.charset.shifted = false
 	default:
 		v.handlePrintableChar(b)
 	}
@@ -357,113 +640,55 @@ func (v *WebView) processControlChar(b byte) {
 
 // startEscapeSequence begins an escape sequence
 func (v *WebView) startEscapeSequence() {
-	v.inEscapeSeq = true
+	v.vtState = vtEscape
 	v.escapeBuffer = append(v.escapeBuffer[:0], '\x1b')
 }
 
 // handleNewline processes newline character
 func (v *WebView) handleNewline() {
-	v.cursorY++
 	v.cursorX = 0
-	if v.cursorY >= v.height {
-		v.scrollUp()
-		v.cursorY = v.height - 1
-	}
+	v.lineFeed()
 }
 
 // handleBackspace processes backspace character
 func (v *WebView) handleBackspace() {
+	v.cursorX = min(v.cursorX, v.width-1)
 	if v.cursorX > 0 {
 		v.cursorX--
 	}
 }
 
-// handleTab processes tab character
+// handleTab moves the cursor to the next tab stop, every eight columns,
+// stopping at the last column as xterm does
 func (v *WebView) handleTab() {
-	v.cursorX = ((v.cursorX / 8) + 1) * 8
-	if v.cursorX >= v.width {
-		v.cursorX = 0
-		v.cursorY++
-		if v.cursorY >= v.height {
-			v.scrollUp()
-			v.cursorY = v.height - 1
-		}
-	}
+	v.cursorX = min(((v.cursorX/8)+1)*8, v.width-1)
 }
 
 // handlePrintableChar processes printable characters
 func (v *WebView) handlePrintableChar(b byte) {
 	if b >= 32 && b < 127 { // Printable ASCII
-		v.writeCharacter(rune(b))
-	} else if b >= 128 { // UTF-8 continuation or start
-		v.writeCharacter(rune(b))
+		v.writeCharacter(v.charset.translate(b))
 	}
 }
 
-// processEscapeSequence processes individual bytes of escape sequences
+// handleCSISequence processes complete CSI escape sequences
 // Moved from: view.go
-func (v *WebView) processEscapeSequence(b byte) bool {
-	// Safety check is now handled in processTerminalData before this function is called
-	// This function assumes the byte has already been added to the buffer safely
-	escSeq := string(v.escapeBuffer)
-
-	// Handle CSI sequences (ESC[...)
-	if len(escSeq) >= 2 && escSeq[1] == '[' {
-		// Check if sequence is complete
-		if (b >= 'A' && b <= 'Z') || (b >= 'a' && b <= 'z') || b == 'm' || b == 'H' || b == 'J' || b == 'K' {
-			// Sequence is complete, process it
-			v.handleCSISequence(escSeq)
-			// Reset buffer after processing
-			v.escapeBuffer = v.escapeBuffer[:0]
-			v.inEscapeSeq = false
-			return true
-		}
-		// Continue building sequence if not complete
-		return false
+func (v *WebView) handleCSISequence(seq string) {
+	// The handlers slice the parameters out of ESC [ ... final
+	if len(seq) < 3 || !strings.HasPrefix(seq, "\x1b[") {
+		return
 	}
 
-	// Handle other escape sequences
-	if len(escSeq) >= 2 {
-		switch escSeq[1] {
-		case 'c': // Reset terminal
-			v.resetTerminalState()
-		case 'D': // Line feed
-			v.cursorY++
-			if v.cursorY >= v.height {
-				v.scrollUp()
-				v.cursorY = v.height - 1
-			}
-		case 'M': // Reverse line feed
-			v.cursorY--
-			if v.cursorY < 0 {
-				v.scrollDown()
-				v.cursorY = 0
-			}
-		default:
-			// Unknown sequence, terminate
-			v.escapeBuffer = v.escapeBuffer[:0]
-			v.inEscapeSeq = false
-			return true
-		}
-
-		// Reset buffer after processing
-		v.escapeBuffer = v.escapeBuffer[:0]
-		v.inEscapeSeq = false
-		return true
+	params, lastChar := seq[2:len(seq)-1], seq[len(seq)-1]
+	if strings.ContainsFunc(params, isIntermediate) {
+		v.handleCSIIntermediate(params, lastChar)
+		return
 	}
-
-	// Continue building sequence
-	return false
-}
-
-// handleCSISequence processes complete CSI escape sequences
-// Moved from: view.go
-func (v *WebView) handleCSISequence(seq string) {
-	if len(seq) == 0 {
+	if params != "" && strings.IndexByte("<=>?", params[0]) >= 0 {
+		v.handlePrivateCSI(seq, lastChar)
 		return
 	}
 
-	lastChar := seq[len(seq)-1]
 	switch lastChar {
 	case 'm':
 		v.handleSGRSequence(seq)
@@ -481,6 +706,44 @@ func (v *WebView) handleCSISequence(seq string) {
 		v.handleCursorMove(seq, 1, 0)
 	case 'D':
 		v.handleCursorMove(seq, -1, 0)
+	case 'E':
+		v.handleCursorMove(seq, 0, 1)
+		v.cursorX = 0
+	case 'F':
+		v.handleCursorMove(seq, 0, -1)
+		v.cursorX = 0
+	case 'a':
+		v.handleCursorMove(seq, 1, 0)
+	case 'e':
+		v.handleCursorMove(seq, 0, 1)
+	case 'G', '`':
+		v.cursorX = min(csiParam(seq, 0, 1), v.width) - 1
+	case 'd':
+		v.cursorY = min(csiParam(seq, 0, 1), v.height) - 1
+	case 'r':
+		v.handleSetScrollRegion(seq)
+	case '@':
+		v.insertChars(csiParam(seq, 0, 1))
+	case 'P':
+		v.deleteChars(csiParam(seq, 0, 1))
+	case 'X':
+		v.eraseChars(csiParam(seq, 0, 1))
+	case 'L':
+		v.insertLines(csiParam(seq, 0, 1))
+	case 'M':
+		v.deleteLines(csiParam(seq, 0, 1))
+	case 'S':
+		rows, n := v.scrollableRows(), csiParam(seq, 0, 1)
+		v.keepScrolledLines(rows, n)
+		v.shiftRows(rows, -n)
+	case 'T':
+		v.shiftRows(v.scrollableRows(), csiParam(seq, 0, 1))
+	case 's':
+		if params == "" {
+			v.saveCursor()
+		}
+	case 'u':
+		v.restoreCursor()
 	}
 }
 
@@ -504,6 +767,7 @@ func (v *WebView) handleSGRSequence(seq string) {
 	v.currentBold = bold
 	v.currentInverse = inverse
 	v.currentBlink = blink
+	v.applyTextAttributes(params)
 }
 
 // handleCursorPosition processes cursor positioning sequences
@@ -560,6 +824,8 @@ func (v *WebView) handleEraseDisplay(seq string) {
 		v.clearToCursor()
 	case 2: // Clear entire screen
 		v.clearScreen()
+	case 3: // Clear scrollback
+		v.clearScrollback()
 	}
 }
 
@@ -620,35 +886,133 @@ func (v *WebView) resetAttributes() {
 	v.currentBold = false
 	v.currentInverse = false
 	v.currentBlink = false
+	v.currentAttrs = textAttributes{}
 }
 
 // resetTerminalState resets terminal state to defaults
 // Moved from: view.go
 func (v *WebView) resetTerminalState() {
+	v.switchScreen(false, false)
 	v.resetAttributes()
+	v.resetScrollRegion()
+	v.modes = terminalModes{}
+	v.charset = charsetState{}
 	v.cursorX = 0
 	v.cursorY = 0
 }
 
-// writeCharacter writes a character to the current cursor position
+// savedCursor is the cursor position and text attributes saved by DECSC
+type savedCursor struct {
+	set              bool
+	x, y             int
+	fgColor, bgColor string
+	bold, inverse    bool
+	blink            bool
+	attrs            textAttributes
+	charset          charsetState
+}
+
+// saveCursor saves the cursor position and text attributes
+func (v *WebView) saveCursor() {
+	v.savedCursor = savedCursor{
+		set:     true,
+		x:       v.cursorX,
+		y:       v.cursorY,
+		fgColor: v.currentFgColor,
+		bgColor: v.currentBgColor,
+		bold:    v.currentBold,
+		inverse: v.currentInverse,
+		blink:   v.currentBlink,
+		attrs:   v.currentAttrs,
+		charset: v.charset,
+	}
+}
+
+// restoreCursor restores what saveCursor saved, or moves the cursor home
+// with default attributes when nothing was saved
+func (v *WebView) restoreCursor() {
+	s := v.savedCursor
+	if !s.set {
+		v.resetAttributes()
+		v.charset = charsetState{}
+		v.cursorX, v.cursorY = 0, 0
+		return
+	}
+	v.cursorX, v.cursorY = min(s.x, v.width-1), min(s.y, v.height-1)
+	v.currentFgColor, v.currentBgColor = s.fgColor, s.bgColor
+	v.currentBold, v.currentInverse, v.currentBlink = s.bold, s.inverse, s.blink
+	v.currentAttrs = s.attrs
+	v.charset = s.charset
+}
+
+// writeCharacter writes a character to the current cursor position. Runes
+// that extend the grapheme cluster last written join its cell, and wide
+// characters take two cells, wrapping early when only one is left.
 // Moved from: view.go
 func (v *WebView) writeCharacter(char rune) {
-	if v.cursorX < v.width && v.cursorY < v.height {
-		v.setCellChar(v.cursorX, v.cursorY, char)
+	if g := v.grapheme; g.ok && extendsCluster(g.last, g.regional, char) {
+		v.extendGrapheme(char)
+		return
 	}
 
+	cells := runeWidth(char)
+	if cells > v.width {
+		cells = 1
+	}
+	if v.cursorX >= v.width {
+		// Wrap deferred from writing the last column
+		v.cursorX = 0
+		v.lineFeed()
+	}
+	if cells == 2 && v.cursorX == v.width-1 {
+		if v.modes.noAutowrap {
+			cells = 1
+		} else {
+			v.cursorX = 0
+			v.lineFeed()
+		}
+	}
+
+	x, y := v.cursorX, v.cursorY
+	v.setCellChar(x, y, char)
+	v.startGrapheme(x, y, char)
+	if cells == 2 {
+		v.splitWide(x+1, y)
+		lead := &v.buffer[y][x]
+		lead.Wide = true
+		v.buffer[y][x+1] = Cell{
+			FgColor:        lead.FgColor,
+			BgColor:        lead.BgColor,
+			Bold:           lead.Bold,
+			Inverse:        lead.Inverse,
+			Blink:          lead.Blink,
+			Underline:      lead.Underline,
+			UnderlineColor: lead.UnderlineColor,
+			Overline:       lead.Overline,
+			Concealed:      lead.Concealed,
+			Changed:        true,
+		}
+		v.advanceCursor()
+	}
 	v.advanceCursor()
 }
 
 // setCellChar sets a character at the given position with current attributes
 func (v *WebView) setCellChar(x, y int, char rune) {
+	v.splitWide(x, y)
 	cell := &v.buffer[y][x]
 	cell.Char = char
+	cell.Text = ""
+	cell.Wide = false
 	cell.FgColor = v.currentFgColor
 	cell.BgColor = v.currentBgColor
 	cell.Bold = v.currentBold
 	cell.Inverse = v.currentInverse
 	cell.Blink = v.currentBlink
+	cell.Underline = v.currentAttrs.underline
+	cell.UnderlineColor = v.currentAttrs.underlineColor
+	cell.Overline = v.currentAttrs.overline
+	cell.Concealed = v.currentAttrs.concealed
 	cell.Changed = true
 
 	v.applyTilesetMapping(cell, char)
@@ -665,8 +1029,8 @@ func (v *WebView) applyTilesetMapping(cell *Cell, char rune) {
 		return
 	}
 
-	cell.TileX = mapping.X
-	cell.TileY = mapping.Y
+	cell.TileX, cell.TileY = v.tileCoords(mapping)
+	v.countTileDraw(mapping)
 	if mapping.FgColor != "" {
 		cell.FgColor = mapping.FgColor
 	}
@@ -675,30 +1039,44 @@ func (v *WebView) applyTilesetMapping(cell *Cell, char rune) {
 	}
 }
 
-// advanceCursor moves the cursor forward, wrapping as needed
+// tileCoords returns the position of mapping's tile in the sheet clients
+// are served; v.mu must be held
+func (v *WebView) tileCoords(mapping *TileMapping) (int, int) {
+	if v.tileSubset == nil {
+		return mapping.X, mapping.Y
+	}
+	tile := v.tileSubset.slot(TileRef{X: mapping.X, Y: mapping.Y})
+	return tile.X, tile.Y
+}
+
+// advanceCursor moves the cursor forward. Past the last column it waits
+// there until the next character wraps it, as in xterm, so that writing the
+// bottom right cell does not scroll; without autowrap it stays put.
 func (v *WebView) advanceCursor() {
-	v.cursorX++
-	if v.cursorX >= v.width {
-		v.cursorX = 0
-		v.cursorY++
-		if v.cursorY >= v.height {
-			v.scrollUp()
-			v.cursorY = v.height - 1
-		}
+	if v.cursorX < v.width-1 || !v.modes.noAutowrap {
+		v.cursorX++
 	}
 }
 
 // scrollUp scrolls the buffer up by one line
 // Moved from: view.go
 func (v *WebView) scrollUp() {
-	// Move all lines up
-	for y := 0; y < v.height-1; y++ {
-		copy(v.buffer[y], v.buffer[y+1])
+	v.breakGrapheme()
+	rows := v.scrollableRows()
+	if len(rows) == 0 {
+		return
+	}
+	v.keepScrolledLines(rows, 1)
+
+	// Move scrollable lines up, leaving protected rows in place
+	for i := 0; i < len(rows)-1; i++ {
+		copy(v.buffer[rows[i]], v.buffer[rows[i+1]])
 	}
 
 	// Clear last line
+	last := rows[len(rows)-1]
 	for x := 0; x < v.width; x++ {
-		v.buffer[v.height-1][x] = Cell{
+		v.buffer[last][x] = Cell{
 			Char:    ' ',
 			FgColor: v.currentFgColor,
 			BgColor: v.currentBgColor,
@@ -713,14 +1091,21 @@ func (v *WebView) scrollUp() {
 // scrollDown scrolls the buffer down by one line
 // Moved from: view.go
 func (v *WebView) scrollDown() {
-	// Move all lines down
-	for y := v.height - 1; y > 0; y-- {
-		copy(v.buffer[y], v.buffer[y-1])
+	v.breakGrapheme()
+	rows := v.scrollableRows()
+	if len(rows) == 0 {
+		return
+	}
+
+	// Move scrollable lines down, leaving protected rows in place
+	for i := len(rows) - 1; i > 0; i-- {
+		copy(v.buffer[rows[i]], v.buffer[rows[i-1]])
 	}
 
 	// Clear first line
+	first := rows[0]
 	for x := 0; x < v.width; x++ {
-		v.buffer[0][x] = Cell{
+		v.buffer[first][x] = Cell{
 			Char:    ' ',
 			FgColor: v.currentFgColor,
 			BgColor: v.currentBgColor,
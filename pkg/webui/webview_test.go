@@ -0,0 +1,227 @@
+package webui
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/opd-ai/go-gamelaunch-client/pkg/dgclient"
+)
+
+func newTestView(t *testing.T) *WebView {
+	t.Helper()
+
+	view, err := NewWebView(dgclient.ViewOptions{InitialWidth: 80, InitialHeight: 24})
+	if err != nil {
+		t.Fatalf("NewWebView failed: %v", err)
+	}
+	return view
+}
+
+// TestWebView_SendInputContext_FullBuffer_WaitsForRoom tests that input
+// blocks until the game reads instead of being dropped
+func TestWebView_SendInputContext_FullBuffer_WaitsForRoom(t *testing.T) {
+	view := newTestView(t)
+	for i := 0; i < cap(view.inputChan); i++ {
+		view.SendInput([]byte{'a'})
+	}
+
+	result := make(chan error, 1)
+	go func() {
+		result <- view.SendInputContext(context.Background(), []byte{'b'})
+	}()
+
+	select {
+	case err := <-result:
+		t.Fatalf("SendInputContext returned %v before room was available", err)
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	if _, err := view.HandleInput(); err != nil {
+		t.Fatalf("HandleInput failed: %v", err)
+	}
+	if err := <-result; err != nil {
+		t.Errorf("SendInputContext = %v, want nil", err)
+	}
+}
+
+// TestWebView_HandleInput_WaitsUntilClosed tests that an empty input
+// buffer is not mistaken for the end of input
+func TestWebView_HandleInput_WaitsUntilClosed(t *testing.T) {
+	view := newTestView(t)
+	result := make(chan error, 1)
+	go func() {
+		_, err := view.HandleInput()
+		result <- err
+	}()
+
+	select {
+	case err := <-result:
+		t.Fatalf("HandleInput returned %v without input", err)
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	view.Close()
+	if err := <-result; err != io.EOF {
+		t.Errorf("HandleInput after Close = %v, want io.EOF", err)
+	}
+}
+
+// TestWebView_SendInputContext_Cancelled tests cancellation and close while
+// blocked on a full input buffer
+func TestWebView_SendInputContext_Cancelled(t *testing.T) {
+	tests := []struct {
+		name    string
+		release func(view *WebView, cancel context.CancelFunc)
+		want    error
+	}{
+		{"ContextCancelled", func(_ *WebView, cancel context.CancelFunc) { cancel() }, context.Canceled},
+		{"ViewClosed", func(view *WebView, _ context.CancelFunc) { view.Close() }, ErrViewClosed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			view := newTestView(t)
+			for i := 0; i < cap(view.inputChan); i++ {
+				view.SendInput([]byte{'a'})
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			result := make(chan error, 1)
+			go func() {
+				result <- view.SendInputContext(ctx, []byte{'b'})
+			}()
+
+			time.Sleep(10 * time.Millisecond)
+			tt.release(view, cancel)
+
+			select {
+			case err := <-result:
+				if !errors.Is(err, tt.want) {
+					t.Errorf("SendInputContext = %v, want %v", err, tt.want)
+				}
+			case <-time.After(time.Second):
+				t.Fatal("SendInputContext did not return")
+			}
+		})
+	}
+}
+
+// TestWebView_SendInput_AfterClose_DoesNotPanic tests that input racing with
+// Close is ignored
+func TestWebView_SendInput_AfterClose_DoesNotPanic(t *testing.T) {
+	view := newTestView(t)
+	view.Close()
+
+	view.SendInput([]byte{'a'})
+	if err := view.SendInputContext(context.Background(), []byte{'a'}); !errors.Is(err, ErrViewClosed) {
+		t.Errorf("SendInputContext after Close = %v, want ErrViewClosed", err)
+	}
+}
+
+// TestWebView_RenderContext tests rendering with a live, cancelled and
+// blocked context
+func TestWebView_RenderContext(t *testing.T) {
+	view := newTestView(t)
+
+	if err := view.RenderContext(context.Background(), []byte("hello")); err != nil {
+		t.Fatalf("RenderContext failed: %v", err)
+	}
+	if got := view.rowText(0, 5); got != "hello" {
+		t.Errorf("Row 0 = %q, want %q", got, "hello")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := view.RenderContext(ctx, []byte("x")); !errors.Is(err, context.Canceled) {
+		t.Errorf("RenderContext with cancelled context = %v, want context.Canceled", err)
+	}
+
+	// A lock held elsewhere must not block past the deadline
+	view.mu.Lock()
+	ctx, cancel = context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	err := view.RenderContext(ctx, []byte("x"))
+	view.mu.Unlock()
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("RenderContext while locked = %v, want context.DeadlineExceeded", err)
+	}
+
+	view.Close()
+	if err := view.RenderContext(context.Background(), []byte("x")); !errors.Is(err, ErrViewClosed) {
+		t.Errorf("RenderContext after Close = %v, want ErrViewClosed", err)
+	}
+}
+
+// blockingStore is a MemoryStateStore whose saves wait for release or the
+// context of the update
+type blockingStore struct {
+	*MemoryStateStore
+	release chan struct{}
+}
+
+func (s *blockingStore) SaveContext(ctx context.Context, state *GameState, diff *StateDiff) error {
+	select {
+	case <-s.release:
+		return s.MemoryStateStore.Save(state, diff)
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func TestWebView_RenderContext_CancelsSlowSave(t *testing.T) {
+	store := &blockingStore{MemoryStateStore: NewMemoryStateStore(0), release: make(chan struct{})}
+	view, err := NewWebViewWithStore(dgclient.ViewOptions{InitialWidth: 10, InitialHeight: 2}, store)
+	if err != nil {
+		t.Fatalf("NewWebViewWithStore failed: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := view.RenderContext(ctx, []byte("hi")); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("RenderContext during a slow save = %v, want context.DeadlineExceeded", err)
+	}
+	if version := view.GetStateManager().GetCurrentVersion(); version != 0 {
+		t.Errorf("version = %d after the save was given up, want 0", version)
+	}
+
+	// The screen is complete and published with the next update
+	close(store.release)
+	if err := view.RenderContext(context.Background(), []byte("!")); err != nil {
+		t.Fatalf("RenderContext failed: %v", err)
+	}
+	if got := view.GetStateManager().GetCurrentState(); got == nil || got.Buffer[0][0].Char != 'h' || got.Buffer[0][2].Char != '!' {
+		t.Errorf("state after the next render = %+v, want \"hi!\"", got)
+	}
+}
+
+func TestWebView_RenderContext_WaitingWriterIsNotStarved(t *testing.T) {
+	view := newTestView(t)
+
+	// Readers arriving after a waiting writer queue behind it
+	view.mu.RLock()
+	rendered := make(chan error, 1)
+	go func() { rendered <- view.RenderContext(context.Background(), []byte("x")) }()
+	for len(view.mu.turnstile) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	read := make(chan struct{})
+	go func() {
+		view.GetCurrentState()
+		close(read)
+	}()
+	select {
+	case <-read:
+		t.Fatal("reader went ahead of the waiting writer")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	view.mu.RUnlock()
+	if err := <-rendered; err != nil {
+		t.Fatalf("RenderContext failed: %v", err)
+	}
+	<-read
+}
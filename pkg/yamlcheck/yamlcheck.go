@@ -0,0 +1,344 @@
+// Package yamlcheck validates YAML documents against the Go structs they are
+// decoded into. Unlike decoding, which silently drops unknown keys and stops
+// at the first problem, Check reports every unknown key, mistyped value and
+// malformed duration together with its line number.
+//
+// Keys are matched the way gopkg.in/yaml.v3 matches them: the name of the
+// yaml struct tag, or the lower-cased field name without one. A string field
+// tagged `yamlcheck:"duration"` must hold a time.ParseDuration duration.
+package yamlcheck
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Diagnostic is one problem found in a document
+type Diagnostic struct {
+	Line    int
+	Column  int
+	Path    string // dotted path of the key, e.g. preferences.reconnect_delay
+	Message string
+}
+
+// String formats d as "line 3: preferences.x: message"
+func (d Diagnostic) String() string {
+	if d.Path == "" {
+		return fmt.Sprintf("line %d: %s", d.Line, d.Message)
+	}
+	return fmt.Sprintf("line %d: %s: %s", d.Line, d.Path, d.Message)
+}
+
+// Error lists the problems of a document
+type Error struct {
+	File        string
+	Diagnostics []Diagnostic
+}
+
+func (e *Error) Error() string {
+	var b strings.Builder
+	if len(e.Diagnostics) == 1 {
+		fmt.Fprintf(&b, "%s: %s", e.File, e.Diagnostics[0])
+		return b.String()
+	}
+	fmt.Fprintf(&b, "%s: %d problems:", e.File, len(e.Diagnostics))
+	for _, d := range e.Diagnostics {
+		fmt.Fprintf(&b, "\n  %s", d)
+	}
+	return b.String()
+}
+
+var (
+	unmarshalerType     = reflect.TypeOf((*yaml.Unmarshaler)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// Check parses data and compares it with the type of v, a struct or a
+// pointer to one, returning the problems in document order. A document that
+// is not valid YAML yields a single diagnostic.
+func Check(data []byte, v interface{}) []Diagnostic {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return []Diagnostic{syntaxDiagnostic(err)}
+	}
+	if len(doc.Content) == 0 {
+		return nil
+	}
+
+	c := &checker{}
+	c.check(doc.Content[0], reflect.TypeOf(v), "", "")
+	sort.SliceStable(c.diags, func(i, j int) bool {
+		if c.diags[i].Line != c.diags[j].Line {
+			return c.diags[i].Line < c.diags[j].Line
+		}
+		return c.diags[i].Column < c.diags[j].Column
+	})
+	return c.diags
+}
+
+// CheckFile is Check returning an *Error naming file, or nil
+func CheckFile(file string, data []byte, v interface{}) error {
+	if diags := Check(data, v); len(diags) > 0 {
+		return &Error{File: file, Diagnostics: diags}
+	}
+	return nil
+}
+
+// syntaxDiagnostic turns a parse error of yaml.v3 into a diagnostic
+func syntaxDiagnostic(err error) Diagnostic {
+	msg := strings.TrimPrefix(err.Error(), "yaml: ")
+	var line int
+	if _, scanErr := fmt.Sscanf(msg, "line %d:", &line); scanErr == nil {
+		msg = strings.TrimSpace(msg[strings.Index(msg, ":")+1:])
+	}
+	return Diagnostic{Line: line, Message: msg}
+}
+
+// checker collects the diagnostics of one document
+type checker struct {
+	diags []Diagnostic
+}
+
+// report records a problem at node
+func (c *checker) report(node *yaml.Node, path, format string, args ...interface{}) {
+	c.diags = append(c.diags, Diagnostic{Line: node.Line, Column: node.Column, Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+// check compares node with t; tag is the yamlcheck tag of the field
+func (c *checker) check(node *yaml.Node, t reflect.Type, path, tag string) {
+	if node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+	if node.Kind == yaml.ScalarNode && node.Tag == "!!null" {
+		return
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	// Types that decode themselves are checked by decoding
+	if reflect.PtrTo(t).Implements(unmarshalerType) || reflect.PtrTo(t).Implements(textUnmarshalerType) {
+		if err := node.Decode(reflect.New(t).Interface()); err != nil {
+			c.report(node, path, "%s", decodeMessage(err))
+		}
+		return
+	}
+
+	switch t.Kind() {
+	case reflect.Interface:
+		return
+	case reflect.Struct:
+		if node.Kind != yaml.MappingNode {
+			c.report(node, path, "expected a mapping, got %s", describe(node))
+			return
+		}
+		c.checkStruct(node, t, path)
+	case reflect.Map:
+		if node.Kind != yaml.MappingNode {
+			c.report(node, path, "expected a mapping, got %s", describe(node))
+			return
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			if err := key.Decode(reflect.New(t.Key()).Interface()); err != nil {
+				c.report(key, path, "invalid key %q: %s", key.Value, decodeMessage(err))
+				continue
+			}
+			c.check(value, t.Elem(), join(path, key.Value), "")
+		}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 && node.Kind == yaml.ScalarNode {
+			break // []byte from a string
+		}
+		if node.Kind != yaml.SequenceNode {
+			c.report(node, path, "expected a list, got %s", describe(node))
+			return
+		}
+		for i, item := range node.Content {
+			c.check(item, t.Elem(), fmt.Sprintf("%s[%d]", path, i), "")
+		}
+	default:
+		if node.Kind != yaml.ScalarNode {
+			c.report(node, path, "expected %s, got %s", kindName(t), describe(node))
+			return
+		}
+		if err := node.Decode(reflect.New(t).Interface()); err != nil {
+			c.report(node, path, "expected %s, got %q", kindName(t), node.Value)
+			return
+		}
+		if tag == "duration" && t.Kind() == reflect.String && node.Value != "" {
+			if _, err := time.ParseDuration(node.Value); err != nil {
+				c.report(node, path, "invalid duration %q, expected e.g. 30s, 5m or 1h30m", node.Value)
+			}
+		}
+	}
+}
+
+// checkStruct checks the keys of a mapping against the fields of t
+func (c *checker) checkStruct(node *yaml.Node, t reflect.Type, path string) {
+	fields, rest := structFields(t)
+	seen := make(map[string]bool)
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		keyPath := join(path, key.Value)
+		if seen[key.Value] {
+			c.report(key, keyPath, "duplicate key")
+		}
+		seen[key.Value] = true
+
+		field, ok := fields[key.Value]
+		switch {
+		case ok:
+			c.check(value, field.Type, keyPath, field.Tag.Get("yamlcheck"))
+		case rest != nil:
+			c.check(value, rest.Elem(), keyPath, "")
+		default:
+			c.report(key, keyPath, "unknown key%s", suggest(key.Value, fields))
+		}
+	}
+}
+
+// structFields returns the fields of t by yaml key, including those of
+// inlined structs, and the type of an inlined map collecting other keys
+func structFields(t reflect.Type) (map[string]reflect.StructField, reflect.Type) {
+	fields := make(map[string]reflect.StructField)
+	var rest reflect.Type
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" && !field.Anonymous {
+			continue // unexported
+		}
+		tag := field.Tag.Get("yaml")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if strings.Contains(","+opts+",", ",inline,") {
+			ft := field.Type
+			for ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			switch ft.Kind() {
+			case reflect.Struct:
+				inner, innerRest := structFields(ft)
+				for key, f := range inner {
+					fields[key] = f
+				}
+				if innerRest != nil {
+					rest = innerRest
+				}
+			case reflect.Map:
+				rest = ft
+			}
+			continue
+		}
+		if field.PkgPath != "" {
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(field.Name)
+		}
+		fields[name] = field
+	}
+	return fields, rest
+}
+
+// suggest returns ", did you mean x?" for the known key closest to key, or
+// "" when none is close
+func suggest(key string, fields map[string]reflect.StructField) string {
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	best, bestDist := "", len(key)/3+1
+	for _, name := range names {
+		if d := distance(key, name); d < bestDist {
+			best, bestDist = name, d
+		}
+	}
+	if best == "" {
+		return ""
+	}
+	return fmt.Sprintf(", did you mean %q?", best)
+}
+
+// distance is the Levenshtein distance of a and b
+func distance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+// decodeMessage strips the position yaml.v3 prefixes to decode errors
+func decodeMessage(err error) string {
+	msg := err.Error()
+	if typeErr, ok := err.(*yaml.TypeError); ok && len(typeErr.Errors) > 0 {
+		msg = typeErr.Errors[0]
+	}
+	msg = strings.TrimPrefix(msg, "yaml: ")
+	if strings.HasPrefix(msg, "line ") {
+		if i := strings.Index(msg, ": "); i >= 0 {
+			msg = msg[i+2:]
+		}
+	}
+	return msg
+}
+
+// describe names the kind of node for messages
+func describe(node *yaml.Node) string {
+	switch node.Kind {
+	case yaml.MappingNode:
+		return "a mapping"
+	case yaml.SequenceNode:
+		return "a list"
+	default:
+		return fmt.Sprintf("%q", node.Value)
+	}
+}
+
+// kindName names the scalar type t for messages
+func kindName(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Bool:
+		return "true or false"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return "an integer"
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "a non-negative integer"
+	case reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.String:
+		return "a string"
+	}
+	return t.String()
+}
+
+// join appends key to a dotted path
+func join(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
@@ -0,0 +1,117 @@
+package yamlcheck
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+type testAuth struct {
+	Method  string `yaml:"method"`
+	KeyPath string `yaml:"key_path,omitempty"`
+}
+
+type testServer struct {
+	Host string            `yaml:"host"`
+	Port int               `yaml:"port,omitempty"`
+	Auth testAuth          `yaml:"auth"`
+	Env  map[string]string `yaml:"env,omitempty"`
+}
+
+type testTiming struct {
+	Delay string `yaml:"delay,omitempty" yamlcheck:"duration"`
+}
+
+type testConfig struct {
+	Servers     map[string]testServer `yaml:"servers"`
+	Preferences struct {
+		ReconnectDelay string `yaml:"reconnect_delay" yamlcheck:"duration"`
+		Color          bool   `yaml:"color_enabled"`
+	} `yaml:"preferences"`
+	Timing   testTiming    `yaml:",inline"`
+	Timeout  time.Duration `yaml:"timeout"`
+	Tags     []string      `yaml:"tags"`
+	Extra    interface{}   `yaml:"extra"`
+	Untagged int
+	Ignored  string `yaml:"-"`
+}
+
+func TestCheck_Valid(t *testing.T) {
+	doc := `
+servers:
+  nethack:
+    host: nethack.example.com
+    port: 2022
+    auth: {method: key, key_path: ~/.ssh/id}
+    env: {LANG: C.UTF-8}
+preferences:
+  reconnect_delay: 5s
+  color_enabled: true
+delay: 1m30s
+timeout: 10s
+tags: [a, b]
+extra: {anything: [goes]}
+untagged: 3
+empty:
+`
+	diags := Check([]byte(doc), &testConfig{})
+	if len(diags) != 1 || diags[0].Path != "empty" {
+		t.Errorf("diagnostics = %v, want only the unknown key empty", diags)
+	}
+}
+
+func TestCheck_Problems(t *testing.T) {
+	doc := `servers:
+  nethack:
+    host: nethack.example.com
+    port: twenty
+    auth:
+      metod: key
+preferences:
+  recconnect_delay: 5s
+  color_enabled: maybe
+delay: soon
+tags: single
+servers: {}
+ignored: x
+`
+	diags := Check([]byte(doc), &testConfig{})
+	want := []string{
+		`line 4: servers.nethack.port: expected an integer, got "twenty"`,
+		`line 6: servers.nethack.auth.metod: unknown key, did you mean "method"?`,
+		`line 8: preferences.recconnect_delay: unknown key, did you mean "reconnect_delay"?`,
+		`line 9: preferences.color_enabled: expected true or false, got "maybe"`,
+		`line 10: delay: invalid duration "soon", expected e.g. 30s, 5m or 1h30m`,
+		`line 11: tags: expected a list, got "single"`,
+		`line 12: servers: duplicate key`,
+		`line 13: ignored: unknown key`,
+	}
+	var got []string
+	for _, d := range diags {
+		got = append(got, d.String())
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("diagnostics:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestCheck_Syntax(t *testing.T) {
+	diags := Check([]byte("servers:\n  a: [1, 2\n"), &testConfig{})
+	if len(diags) != 1 || diags[0].Line == 0 {
+		t.Errorf("diagnostics = %v, want one with a line", diags)
+	}
+}
+
+func TestCheckFile(t *testing.T) {
+	if err := CheckFile("ok.yaml", []byte("tags: [a]\n"), &testConfig{}); err != nil {
+		t.Errorf("CheckFile = %v", err)
+	}
+	err := CheckFile("bad.yaml", []byte("tags: [a]\nbogus: 1\ndelay: x\n"), &testConfig{})
+	if err == nil {
+		t.Fatal("CheckFile accepted problems")
+	}
+	want := "bad.yaml: 2 problems:\n  line 2: bogus: unknown key\n  line 3: delay: invalid duration \"x\", expected e.g. 30s, 5m or 1h30m"
+	if err.Error() != want {
+		t.Errorf("error = %q, want %q", err, want)
+	}
+}